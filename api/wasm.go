@@ -4,6 +4,7 @@ package api
 import (
 	"context"
 	"fmt"
+	"io"
 	"math"
 )
 
@@ -150,6 +151,31 @@ type Module interface {
 	// ExportedGlobal a global exported from this module or nil if it wasn't.
 	ExportedGlobal(name string) Global
 
+	// Snapshot captures the memory and the global values of this module, so that they can be restored later. The
+	// memory captured is the one returned by Memory, unless it is imported.
+	//
+	// Ex. to undo the side effects of a call:
+	//
+	//	snap := mod.Snapshot()
+	//	_, err := mod.ExportedFunction("handle").Call(ctx)
+	//	err = snap.Restore(ctx)
+	//
+	// Note: Imported memory and globals are not captured, as they are shared with the module that exports them, ex. a
+	// memory created with Namespace.NewSharedMemory. Restoring them would overwrite the state of every module using
+	// them.
+	Snapshot() Snapshot
+
+	// DataSegments returns the memory ranges the active data segments of this module were copied to, in the order of
+	// its data section. Passive data segments are not included, as they are only copied by "memory.init".
+	//
+	// Ex. to check the first active data segment was applied, without calling into Wasm:
+	//
+	//	seg := mod.DataSegments()[0]
+	//	buf, ok := mod.Memory().Read(ctx, seg.Offset, seg.Size)
+	//
+	// Note: Ranges are relative to the memory returned by Memory, which may be imported from another module.
+	DataSegments() []DataSegment
+
 	// CloseWithExitCode releases resources allocated for this Module. Use a non-zero exitCode parameter to indicate a
 	// failure to ExportedFunction callers. When the context is nil, it defaults to context.Background.
 	//
@@ -165,6 +191,18 @@ type Module interface {
 	Closer
 }
 
+// Snapshot is the memory and global values of a Module at the time Module.Snapshot was called.
+//
+// Note: This is an interface for decoupling, not third-party implementations. All implementations are in wazero.
+type Snapshot interface {
+	// Restore sets the memory and global values of the module back to the ones captured. The memory is shrunk back to
+	// its size at the time of the snapshot, if it grew since. This returns a sys.ExitError if the module was closed.
+	//
+	// A Snapshot can be restored any number of times. This must not be called while a function of the module is
+	// executing.
+	Restore(context.Context) error
+}
+
 // Closer closes a resource.
 //
 // Note: This is an interface for decoupling, not third-party implementations. All implementations are in wazero.
@@ -173,6 +211,93 @@ type Closer interface {
 	Close(context.Context) error
 }
 
+// TrapReason is the cause of a trap, which is a runtime error that aborts the execution of a WebAssembly function.
+//
+// See TrapError and https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#trap
+type TrapReason uint32
+
+const (
+	// TrapReasonOutOfBoundsMemory means a memory access was outside the linear memory.
+	TrapReasonOutOfBoundsMemory TrapReason = iota + 1
+	// TrapReasonOutOfBoundsTable means a table access was outside the table.
+	TrapReasonOutOfBoundsTable
+	// TrapReasonIntegerDivideByZero means an integer div or rem instruction had a zero divisor.
+	TrapReasonIntegerDivideByZero
+	// TrapReasonIntegerOverflow means an integer result was unrepresentable, ex. a float truncated into an integer
+	// too small to hold it.
+	TrapReasonIntegerOverflow
+	// TrapReasonInvalidConversion means a NaN was truncated into an integer.
+	TrapReasonInvalidConversion
+	// TrapReasonUnreachable means an "unreachable" instruction was executed.
+	TrapReasonUnreachable
+	// TrapReasonIndirectCallTypeMismatch means call_indirect used an element whose type differs from the one expected.
+	TrapReasonIndirectCallTypeMismatch
+	// TrapReasonCallStackExhausted means there were too many nested function calls, ex. due to infinite recursion.
+	TrapReasonCallStackExhausted
+	// TrapReasonIndirectCallToNull means call_indirect used an element which is a null funcref, ex. uninitialized.
+	TrapReasonIndirectCallToNull
+)
+
+// String returns the same text as TrapError.Error for the reason.
+func (r TrapReason) String() string {
+	switch r {
+	case TrapReasonOutOfBoundsMemory:
+		return "out of bounds memory access"
+	case TrapReasonOutOfBoundsTable:
+		return "invalid table access"
+	case TrapReasonIntegerDivideByZero:
+		return "integer divide by zero"
+	case TrapReasonIntegerOverflow:
+		return "integer overflow"
+	case TrapReasonInvalidConversion:
+		return "invalid conversion to integer"
+	case TrapReasonUnreachable:
+		return "unreachable"
+	case TrapReasonIndirectCallTypeMismatch:
+		return "indirect call type mismatch"
+	case TrapReasonCallStackExhausted:
+		return "callstack overflow"
+	case TrapReasonIndirectCallToNull:
+		return "uninitialized element"
+	}
+	return fmt.Sprintf("trap(%d)", r)
+}
+
+// TrapError is implemented by errors that mean a WebAssembly function trapped, and is wrapped by the error returned
+// from Function.Call.
+//
+// Ex. To handle an out-of-bounds memory access differently from other errors:
+//	var trap api.TrapError
+//	if errors.As(err, &trap) && trap.TrapReason() == api.TrapReasonOutOfBoundsMemory {
+//		// handle it
+//	}
+type TrapError interface {
+	error
+
+	// TrapReason returns the cause of the trap.
+	TrapReason() TrapReason
+}
+
+// ErrUnimplementedImport is the error wrapped by Function.Call when the WebAssembly function called an import that
+// no module provides, stubbed by wazero.RuntimeConfig WithStubMissingImports.
+//
+// Ex. To find which import a program actually needs:
+//	var unimplemented *api.ErrUnimplementedImport
+//	if errors.As(err, &unimplemented) {
+//		log.Printf("implement %s.%s", unimplemented.Module, unimplemented.Name)
+//	}
+type ErrUnimplementedImport struct {
+	// Module is the module name of the import, ex. "env".
+	Module string
+	// Name is the name of the function in that module, ex. "abort".
+	Name string
+}
+
+// Error implements error
+func (e *ErrUnimplementedImport) Error() string {
+	return fmt.Sprintf("unimplemented import: %s.%s", e.Module, e.Name)
+}
+
 // Function is a WebAssembly 1.0 (20191205) function exported from an instantiated module (wazero.Runtime InstantiateModule).
 //
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#syntax-func
@@ -196,7 +321,43 @@ type Function interface {
 	// If Module.Close or Module.CloseWithExitCode were invoked during this call, the error returned may be a
 	// sys.ExitError. Interpreting this is specific to the module. For example, some "main" functions always call a
 	// function that exits.
+	//
+	// If a host function panics during this call, the panic is recovered and returned as an error instead of crashing
+	// the caller. When the panic value is an error, the error returned wraps it, so errors.Is and errors.As work.
+	//
+	// If the WebAssembly function traps, ex. on "unreachable", the error returned wraps a TrapError.
 	Call(ctx context.Context, params ...uint64) ([]uint64, error)
+
+	// CallWithStack is an optimized variation of Call that uses the stack for both params and results, avoiding
+	// allocations when the same stack is reused across calls. When the context is nil, it defaults to
+	// context.Background.
+	//
+	// The stack must be at least as long as the larger of the count of ParamTypes and ResultTypes. Params are read
+	// from the beginning of the stack, encoded according to ParamTypes. On success, results are written to the
+	// beginning of the stack, encoded according to ResultTypes, overwriting params.
+	//
+	// Ex.
+	//	stack := make([]uint64, 2) // add has two params and one result
+	//	for _, x := range inputs {
+	//		stack[0], stack[1] = x, 1
+	//		if err := add.CallWithStack(ctx, stack); err != nil {
+	//			return err
+	//		}
+	//		sum := stack[0]
+	//	}
+	//
+	// Note: The stack is only valid for the duration of the call: do not retain references to it in host functions.
+	CallWithStack(ctx context.Context, stack []uint64) error
+}
+
+// DataSegment is the memory range an active data segment was copied to when its module was instantiated.
+//
+// See Module.DataSegments
+type DataSegment struct {
+	// Offset is the offset in memory of the first byte of the segment.
+	Offset uint32
+	// Size is the count of bytes in the segment.
+	Size uint32
 }
 
 // Global is a WebAssembly 1.0 (20191205) global exported from an instantiated module (wazero.Runtime InstantiateModule).
@@ -254,6 +415,11 @@ type Memory interface {
 	// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#-hrefsyntax-instr-memorymathsfmemorysize%E2%91%A0
 	Size(context.Context) uint32
 
+	// Pages returns the current size in pages (65536 bytes per page). Ex. If the underlying memory has 1 page: 1
+	//
+	// This is the same as the "memory.size" instruction defined in the WebAssembly Core Specification.
+	Pages(context.Context) uint32
+
 	// Grow increases memory by the delta in pages (65536 bytes per page).
 	// The return val is the previous memory size in pages, or false if the
 	// delta was ignored as it exceeds max memory.
@@ -262,7 +428,7 @@ type Memory interface {
 	//
 	//	* This is the same as the "memory.grow" instruction defined in the
 	//	  WebAssembly Core Specification, except returns false instead of -1.
-	//	* When this returns true, any shared views via Read must be refreshed.
+	//	* When this returns true, any shared views via Read or ReadSlice must be refreshed.
 	//
 	// See MemorySizer Read and https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#grow-mem
 	Grow(ctx context.Context, deltaPages uint32) (previousPages uint32, ok bool)
@@ -326,6 +492,27 @@ type Memory interface {
 	// allocated.
 	Read(ctx context.Context, offset, byteCount uint32) ([]byte, bool)
 
+	// ReadSlice is like Read, except the view of the underlying buffer is leased until the release function is called,
+	// or returns false if out of range. This is for hosts that process large regions without copying, ex. for I/O.
+	//
+	// For example:
+	//	buf, release, ok := memory.ReadSlice(ctx, offset, byteCount)
+	//	if !ok {
+	//		// Out of range!
+	//	}
+	//	defer release()
+	//	n, err := conn.Write(buf) // no copy
+	//
+	// Lifetime
+	//
+	// The returned slice is invalid after the memory grows, ex. via "memory.grow" or Grow, even if it isn't released,
+	// and must be read again. Unlike Read, this doesn't depend on memory capacity: while any slice is leased, growth
+	// always moves the underlying buffer, so the old slice no longer shares writes with Wasm in either direction.
+	//
+	// The release function is safe to call more than once. Not calling it only affects performance, as growth
+	// copies the underlying buffer while any slice is leased.
+	ReadSlice(ctx context.Context, offset, byteCount uint32) (buf []byte, release func(), ok bool)
+
 	// WriteByte writes a single byte to the underlying buffer at the offset in or returns false if out of range.
 	WriteByte(ctx context.Context, offset uint32, v byte) bool
 
@@ -355,6 +542,32 @@ type Memory interface {
 
 	// Write writes the slice to the underlying buffer at the offset or returns false if out of range.
 	Write(ctx context.Context, offset uint32, v []byte) bool
+
+	// ReaderAt returns an io.Reader of the byteCount bytes at the offset, or returns false if out of range. Reads
+	// return io.EOF at the end of the region.
+	//
+	// For example, to copy a guest buffer to a host stream without an intermediate buffer:
+	//	r, ok := memory.ReaderAt(ctx, offset, byteCount)
+	//	if !ok {
+	//		// Out of range!
+	//	}
+	//	n, err := io.Copy(conn, r)
+	//
+	// Note: Unlike Read, the result remains valid after the memory grows, ex. via "memory.grow" or Grow.
+	ReaderAt(ctx context.Context, offset, byteCount uint32) (io.Reader, bool)
+
+	// WriterAt returns an io.Writer of the byteCount bytes at the offset, or returns false if out of range. Writes
+	// past the end of the region are short, and return io.ErrShortWrite.
+	//
+	// For example, to copy a host stream into a guest buffer without an intermediate buffer:
+	//	w, ok := memory.WriterAt(ctx, offset, byteCount)
+	//	if !ok {
+	//		// Out of range!
+	//	}
+	//	n, err := io.Copy(w, conn)
+	//
+	// Note: Unlike Read, the result remains valid after the memory grows, ex. via "memory.grow" or Grow.
+	WriterAt(ctx context.Context, offset, byteCount uint32) (io.Writer, bool)
 }
 
 // EncodeExternref encodes the input as a ValueTypeExternref.
@@ -449,3 +662,20 @@ type ImportRenamer func(externType ExternType, oldModule, oldName string) (newMo
 //
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#grow-mem
 type MemorySizer func(minPages uint32, maxPages *uint32) (min, capacity, max uint32)
+
+// CustomSectionHandler applies during compilation after a module has been decoded from wasm, but before it is
+// validated. This is called for each custom section, in the order they appear in the binary, except the "name" section
+// which wazero decodes itself. A non-nil error aborts compilation.
+//
+// Ex. Here's how to reject modules compiled to use atomics:
+//
+//	rejectAtomics := func(name string, data []byte) error {
+//		if name == "target_features" && bytes.Contains(data, []byte("atomics")) {
+//			return errors.New("atomics are not supported")
+//		}
+//		return nil
+//	}
+//
+// Note: data is only valid during the call. Copy it to retain it, ex. to keep build metadata from "producers".
+// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#custom-section%E2%91%A0
+type CustomSectionHandler func(name string, data []byte) error
@@ -54,6 +54,33 @@ func TestValueTypeName(t *testing.T) {
 	}
 }
 
+func TestTrapReason_String(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    TrapReason
+		expected string
+	}{
+		{"out of bounds memory", TrapReasonOutOfBoundsMemory, "out of bounds memory access"},
+		{"out of bounds table", TrapReasonOutOfBoundsTable, "invalid table access"},
+		{"integer divide by zero", TrapReasonIntegerDivideByZero, "integer divide by zero"},
+		{"integer overflow", TrapReasonIntegerOverflow, "integer overflow"},
+		{"invalid conversion", TrapReasonInvalidConversion, "invalid conversion to integer"},
+		{"unreachable", TrapReasonUnreachable, "unreachable"},
+		{"indirect call type mismatch", TrapReasonIndirectCallTypeMismatch, "indirect call type mismatch"},
+		{"call stack exhausted", TrapReasonCallStackExhausted, "callstack overflow"},
+		{"indirect call to null", TrapReasonIndirectCallToNull, "uninitialized element"},
+		{"unknown", 100, "trap(100)"},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, tc.input.String())
+		})
+	}
+}
+
 func TestEncodeDecodeExternRef(t *testing.T) {
 	for _, v := range []uintptr{
 		0, uintptr(unsafe.Pointer(t)),
@@ -18,15 +18,16 @@ package assemblyscript
 
 import (
 	"context"
+	"encoding/binary"
 	"fmt"
 	"io"
+	"math"
 	"strconv"
 	"strings"
 	"unicode/utf16"
 
 	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/api"
-	"github.com/tetratelabs/wazero/internal/ieee754"
 	internalsys "github.com/tetratelabs/wazero/internal/sys"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
@@ -234,12 +235,11 @@ func formatFloat(f float64) string {
 //
 // See https://github.com/AssemblyScript/assemblyscript/blob/fa14b3b03bd4607efa52aaff3132bea0c03a7989/std/assembly/wasi/index.ts#L111
 func (a *assemblyscript) seed(mod api.Module) float64 {
-	randSource := getSysCtx(mod).RandSource()
-	v, err := ieee754.DecodeFloat64(randSource)
-	if err != nil {
+	var b [8]byte
+	if err := getSysCtx(mod).ReadRand(b[:]); err != nil {
 		panic(fmt.Errorf("error reading Module.RandSource: %w", err))
 	}
-	return v
+	return math.Float64frombits(binary.LittleEndian.Uint64(b[:]))
 }
 
 // readAssemblyScriptString reads a UTF-16 string created by AssemblyScript.
@@ -108,16 +108,32 @@ func TestSeed(t *testing.T) {
 	code, err := r.CompileModule(testCtx, seedWasm, wazero.NewCompileConfig())
 	require.NoError(t, err)
 
-	mod, err := r.InstantiateModule(testCtx, code, wazero.NewModuleConfig().WithRandSource(bytes.NewReader(seed)))
-	require.NoError(t, err)
+	tests := []struct {
+		name       string
+		randSource io.Reader
+	}{
+		{name: "whole", randSource: bytes.NewReader(seed)},
+		{name: "one byte at a time", randSource: iotest.OneByteReader(bytes.NewReader(seed))},
+	}
 
-	seedFn := mod.ExportedFunction("seed")
+	for _, tt := range tests {
+		tc := tt
 
-	res, err := seedFn.Call(testCtx)
-	require.NoError(t, err)
+		t.Run(tc.name, func(t *testing.T) {
+			mod, err := r.InstantiateModule(testCtx, code, wazero.NewModuleConfig().
+				WithName(t.Name()).WithRandSource(tc.randSource))
+			require.NoError(t, err)
+			defer mod.Close(testCtx)
 
-	// If this test doesn't break, the seed is deterministic.
-	require.Equal(t, uint64(506097522914230528), res[0])
+			seedFn := mod.ExportedFunction("seed")
+
+			res, err := seedFn.Call(testCtx)
+			require.NoError(t, err)
+
+			// If this test doesn't break, the seed is deterministic.
+			require.Equal(t, uint64(506097522914230528), res[0])
+		})
+	}
 }
 
 func TestTrace(t *testing.T) {
@@ -78,6 +78,9 @@ type ModuleBuilder interface {
 	//		return x + y
 	//	}
 	//
+	// The context.Context passed to a host function also carries the calling module. So, helpers that only accept ctx
+	// can use ModuleFromContext instead of threading the api.Module through.
+	//
 	// If both parameters exist, they must be in order at positions zero and one.
 	//
 	// Ex. This uses propagates context properly when calling other functions exported in the api.Module:
@@ -129,6 +132,8 @@ type ModuleBuilder interface {
 	//	builder.ExportGlobalI32("canvas_width", 1024)
 	//
 	// Note: The maximum value of v is math.MaxInt32 to match constraints of initialization in binary format.
+	// Note: Guests import this as an immutable global of the same type, ex. `(import "env" "__memory_base" (global i32))`.
+	// Instantiation fails if the imported type or mutability doesn't match.
 	//
 	// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#value-types%E2%91%A0 and
 	// https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#syntax-globaltype
@@ -167,6 +172,22 @@ type ModuleBuilder interface {
 	// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#syntax-globaltype
 	ExportGlobalF64(name string, v float64) ModuleBuilder
 
+	// ExportMutableGlobalI32 is like ExportGlobalI32, except the global is a variable initialized to v.
+	//
+	// For example, the WebAssembly 1.0 Text Format below is the equivalent of this builder method:
+	//	// (global (export "__stack_pointer") (mut i32) (i32.const 65536))
+	//	builder.ExportMutableGlobalI32("__stack_pointer", 65536)
+	//
+	// Guests import this as a mutable global of the same type, ex. `(import "env" "__stack_pointer" (global (mut i32)))`.
+	// A "global.set" by any importer is visible to other importers, and to the host via api.Module ExportedGlobal,
+	// which returns an api.MutableGlobal.
+	ExportMutableGlobalI32(name string, v int32) ModuleBuilder
+
+	// ExportMutableGlobalI64 is like ExportGlobalI64, except the global is a variable initialized to v.
+	//
+	// See ExportMutableGlobalI32 for more details.
+	ExportMutableGlobalI64(name string, v int64) ModuleBuilder
+
 	// Compile returns a CompiledModule that can instantiated in any namespace (Namespace).
 	//
 	// Note: Closing the Namespace has the same effect as closing the result.
@@ -182,6 +203,24 @@ type ModuleBuilder interface {
 	Instantiate(context.Context, Namespace) (api.Module, error)
 }
 
+// ModuleFromContext returns the module calling the host function that ctx was passed to, or nil if ctx wasn't passed
+// to a host function. The result is the same api.Module a host function would receive as a parameter.
+//
+// Ex. This lets a helper read memory without an api.Module parameter:
+//
+//	readUint32 := func(ctx context.Context, offset uint32) uint32 {
+//		v, _ := wazero.ModuleFromContext(ctx).Memory().ReadUint32Le(ctx, offset)
+//		return v
+//	}
+//
+// Note: The module is only present in a ctx passed to a host function, or derived from one.
+func ModuleFromContext(ctx context.Context) api.Module {
+	if m := wasm.ModuleFromContext(ctx); m != nil {
+		return m
+	}
+	return nil // don't return a typed nil
+}
+
 // moduleBuilder implements ModuleBuilder
 type moduleBuilder struct {
 	r            *runtime
@@ -266,6 +305,20 @@ func (b *moduleBuilder) ExportGlobalF64(name string, v float64) ModuleBuilder {
 	return b
 }
 
+// ExportMutableGlobalI32 implements ModuleBuilder.ExportMutableGlobalI32
+func (b *moduleBuilder) ExportMutableGlobalI32(name string, v int32) ModuleBuilder {
+	b.ExportGlobalI32(name, v)
+	b.nameToGlobal[name].Type.Mutable = true
+	return b
+}
+
+// ExportMutableGlobalI64 implements ModuleBuilder.ExportMutableGlobalI64
+func (b *moduleBuilder) ExportMutableGlobalI64(name string, v int64) ModuleBuilder {
+	b.ExportGlobalI64(name, v)
+	b.nameToGlobal[name].Type.Mutable = true
+	return b
+}
+
 // Compile implements ModuleBuilder.Compile
 func (b *moduleBuilder) Compile(ctx context.Context, cConfig CompileConfig) (CompiledModule, error) {
 	config, ok := cConfig.(*compileConfig)
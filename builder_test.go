@@ -337,6 +337,57 @@ func TestNewModuleBuilder_Compile(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "ExportMutableGlobalI32",
+			input: func(r Runtime) ModuleBuilder {
+				return r.NewModuleBuilder("").ExportMutableGlobalI32("__stack_pointer", 65536)
+			},
+			expected: &wasm.Module{
+				GlobalSection: []*wasm.Global{
+					{
+						Type: &wasm.GlobalType{ValType: wasm.ValueTypeI32, Mutable: true},
+						Init: &wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: leb128.EncodeInt32(65536)},
+					},
+				},
+				ExportSection: []*wasm.Export{
+					{Name: "__stack_pointer", Type: wasm.ExternTypeGlobal, Index: 0},
+				},
+			},
+		},
+		{
+			name: "ExportMutableGlobalI32 overwrites constant",
+			input: func(r Runtime) ModuleBuilder {
+				return r.NewModuleBuilder("").ExportGlobalI32("__stack_pointer", 1).ExportMutableGlobalI32("__stack_pointer", 65536)
+			},
+			expected: &wasm.Module{
+				GlobalSection: []*wasm.Global{
+					{
+						Type: &wasm.GlobalType{ValType: wasm.ValueTypeI32, Mutable: true},
+						Init: &wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: leb128.EncodeInt32(65536)},
+					},
+				},
+				ExportSection: []*wasm.Export{
+					{Name: "__stack_pointer", Type: wasm.ExternTypeGlobal, Index: 0},
+				},
+			},
+		},
+		{
+			name: "ExportMutableGlobalI64",
+			input: func(r Runtime) ModuleBuilder {
+				return r.NewModuleBuilder("").ExportMutableGlobalI64("__stack_pointer", 65536)
+			},
+			expected: &wasm.Module{
+				GlobalSection: []*wasm.Global{
+					{
+						Type: &wasm.GlobalType{ValType: wasm.ValueTypeI64, Mutable: true},
+						Init: &wasm.ConstantExpression{Opcode: wasm.OpcodeI64Const, Data: leb128.EncodeInt64(65536)},
+					},
+				},
+				ExportSection: []*wasm.Export{
+					{Name: "__stack_pointer", Type: wasm.ExternTypeGlobal, Index: 0},
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
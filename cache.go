@@ -0,0 +1,89 @@
+package wazero
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	goruntime "runtime"
+	"runtime/debug"
+)
+
+// wazeroModulePath is the path of the wazero Go module, used to find its version in the build info.
+const wazeroModulePath = "github.com/tetratelabs/wazero"
+
+// cacheKeyVersion identifies the compiled code wazero generates, so that upgrading wazero invalidates ModuleCacheKey.
+// This is a variable so that tests can simulate an upgrade.
+var cacheKeyVersion = wazeroVersion(debug.ReadBuildInfo())
+
+// wazeroVersion returns the version of wazero the program was built with, ex. "v1.0.0", or "dev" when unknown, such as
+// when wazero is built from a local directory.
+//
+// Note: The build info is a parameter, so that tests can simulate it.
+func wazeroVersion(info *debug.BuildInfo, ok bool) string {
+	if !ok {
+		return "dev"
+	}
+	mod := &info.Main
+	for _, dep := range info.Deps {
+		if dep.Path == wazeroModulePath {
+			mod = dep
+			break
+		}
+	}
+	if mod.Path != wazeroModulePath {
+		return "dev"
+	} else if mod.Replace != nil {
+		mod = mod.Replace
+	}
+	if mod.Version == "" || mod.Version == "(devel)" {
+		return "dev"
+	}
+	return mod.Version
+}
+
+// ModuleCacheKey returns a stable identifier of compiling the given wasm binary with the given features, RuntimeConfig
+// and CompileConfig, for use as the key of a cache outside the Runtime, such as on disk.
+//
+// The key is a hex-encoded sha256 hash of the binary, the features, the engine (ex. compiler or interpreter), the
+// settings which change the generated code, runtime.GOARCH and the version of wazero. The settings are
+// RuntimeConfig.WithDeterministicNaN, CompileConfig.WithOptimizationLevel and CompileConfig.WithTrustedMemory, so for
+// example, code compiled without bounds checks is never served to a compilation which expects them. Entries keyed with
+// this also invalidate when wazero is upgraded, or when the cache directory is shared by hosts of different
+// architectures.
+//
+// Note: The binary isn't decoded, so the key of an invalid binary is as stable as any other.
+// Note: The version of wazero is read from the build info of the program, see debug.ReadBuildInfo. When it is unknown,
+// ex. wazero is replaced with a local directory, the key doesn't change until the cache is cleared.
+func ModuleCacheKey(wasm []byte, features Features, config RuntimeConfig, cConfig CompileConfig) string {
+	c, ok := config.(*runtimeConfig)
+	if !ok {
+		panic(fmt.Errorf("unsupported wazero.RuntimeConfig implementation: %#v", config))
+	}
+	cc, ok := cConfig.(*compileConfig)
+	if !ok {
+		panic(fmt.Errorf("unsupported wazero.CompileConfig implementation: %#v", cConfig))
+	}
+
+	h := sha256.New()
+	// Length-prefix variable length values, so that no two different inputs write the same bytes.
+	var buf [8]byte
+	for _, v := range []string{cacheKeyVersion, c.engineKind, goruntime.GOARCH} {
+		binary.LittleEndian.PutUint64(buf[:], uint64(len(v)))
+		h.Write(buf[:])
+		h.Write([]byte(v))
+	}
+	binary.LittleEndian.PutUint64(buf[:], uint64(features))
+	h.Write(buf[:])
+	// These are the settings runtime.compileModule varies the ID of compiled code by, in addition to the features.
+	settings := [3]byte{byte(cc.optimizationLevel)}
+	if cc.trustedMemory {
+		settings[1] = 1
+	}
+	if c.deterministicNaN {
+		settings[2] = 1
+	}
+	h.Write(settings[:])
+	h.Write(wasm)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,103 @@
+package wazero
+
+import (
+	"runtime/debug"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestModuleCacheKey(t *testing.T) {
+	wasm1, wasm2 := []byte{0x00, 0x61, 0x73, 0x6d, 0x01}, []byte{0x00, 0x61, 0x73, 0x6d, 0x02}
+	config, cConfig := NewRuntimeConfigInterpreter(), NewCompileConfig()
+	key := ModuleCacheKey(wasm1, FeaturesWasmCore1, config, cConfig)
+
+	t.Run("stable", func(t *testing.T) {
+		require.Equal(t, key, ModuleCacheKey(wasm1, FeaturesWasmCore1, config, cConfig))
+		// A copy of the binary has the same key.
+		require.Equal(t, key, ModuleCacheKey(append([]byte{}, wasm1...), FeaturesWasmCore1, config, cConfig))
+		// Settings which don't change the generated code don't affect the key.
+		require.Equal(t, key, ModuleCacheKey(wasm1, FeaturesWasmCore1, NewRuntimeConfigInterpreter().WithWasmCore2(), cConfig))
+		require.Equal(t, 64, len(key)) // hex-encoded sha256
+	})
+
+	t.Run("wasm changes", func(t *testing.T) {
+		require.NotEqual(t, key, ModuleCacheKey(wasm2, FeaturesWasmCore1, config, cConfig))
+		require.NotEqual(t, key, ModuleCacheKey(nil, FeaturesWasmCore1, config, cConfig))
+	})
+
+	t.Run("features change", func(t *testing.T) {
+		require.NotEqual(t, key, ModuleCacheKey(wasm1, FeaturesWasmCore2, config, cConfig))
+		require.NotEqual(t, key, ModuleCacheKey(wasm1, FeaturesWasmCore1.Set(FeatureSIMD, true), config, cConfig))
+	})
+
+	t.Run("engine changes", func(t *testing.T) {
+		require.NotEqual(t, key, ModuleCacheKey(wasm1, FeaturesWasmCore1, NewRuntimeConfigCompiler(), cConfig))
+	})
+
+	t.Run("code settings change", func(t *testing.T) {
+		trusted := ModuleCacheKey(wasm1, FeaturesWasmCore1, config, cConfig.WithTrustedMemory(true))
+		minimal := ModuleCacheKey(wasm1, FeaturesWasmCore1, config, cConfig.WithOptimizationLevel(OptimizationLevelMinimal))
+		nan := ModuleCacheKey(wasm1, FeaturesWasmCore1, config.WithDeterministicNaN(true), cConfig)
+		for _, k := range []string{trusted, minimal, nan} {
+			require.NotEqual(t, key, k)
+		}
+		require.NotEqual(t, trusted, minimal)
+		require.NotEqual(t, trusted, nan)
+		require.NotEqual(t, minimal, nan)
+	})
+
+	t.Run("version changes", func(t *testing.T) {
+		defer func(v string) { cacheKeyVersion = v }(cacheKeyVersion)
+		cacheKeyVersion = "v1.0.1"
+		require.NotEqual(t, key, ModuleCacheKey(wasm1, FeaturesWasmCore1, config, cConfig))
+	})
+}
+
+func TestWazeroVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		info     *debug.BuildInfo
+		ok       bool
+		expected string
+	}{
+		{name: "no build info", expected: "dev"},
+		{
+			name:     "dependency",
+			info:     &debug.BuildInfo{Deps: []*debug.Module{{Path: "example.com/other", Version: "v2.0.0"}, {Path: wazeroModulePath, Version: "v1.0.0"}}},
+			ok:       true,
+			expected: "v1.0.0",
+		},
+		{
+			name:     "replaced dependency",
+			info:     &debug.BuildInfo{Deps: []*debug.Module{{Path: wazeroModulePath, Version: "v1.0.0", Replace: &debug.Module{Path: "example.com/fork", Version: "v1.0.1"}}}},
+			ok:       true,
+			expected: "v1.0.1",
+		},
+		{
+			name:     "replaced with a directory",
+			info:     &debug.BuildInfo{Deps: []*debug.Module{{Path: wazeroModulePath, Version: "v1.0.0", Replace: &debug.Module{Path: "../wazero"}}}},
+			ok:       true,
+			expected: "dev",
+		},
+		{
+			name:     "main module",
+			info:     &debug.BuildInfo{Main: debug.Module{Path: wazeroModulePath, Version: "(devel)"}},
+			ok:       true,
+			expected: "dev",
+		},
+		{
+			name:     "not a dependency",
+			info:     &debug.BuildInfo{Main: debug.Module{Path: "example.com/other", Version: "v2.0.0"}},
+			ok:       true,
+			expected: "dev",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, wazeroVersion(tc.info, tc.ok))
+		})
+	}
+}
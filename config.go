@@ -3,9 +3,13 @@ package wazero
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"io/fs"
 	"math"
+	"net"
+	"os"
+	goruntime "runtime"
 	"time"
 
 	"github.com/tetratelabs/wazero/api"
@@ -25,6 +29,22 @@ import (
 // Note: RuntimeConfig is immutable. Each WithXXX function returns a new instance including the corresponding change.
 type RuntimeConfig interface {
 
+	// WithDeterministicNaN when true replaces any NaN result of floating-point arithmetic with the canonical NaN, so
+	// that the same module produces the same bits regardless of the engine or platform. This defaults to false.
+	//
+	// The WebAssembly Core Specification allows arithmetic, such as `f32.add`, to return a NaN with an arbitrary
+	// payload when an operand is NaN. Hence, output such as memory snapshots of floats can differ between the
+	// interpreter and the compiler. This costs a comparison per arithmetic instruction to make them identical.
+	//
+	// Here are the notable effects:
+	//	* `add`, `sub`, `mul`, `div`, `sqrt`, `min`, `max`, `ceil`, `floor`, `trunc` and `nearest` of `f32` and `f64`,
+	//	  as well as `f32.demote_f64` and `f64.promote_f32` return the canonical NaN: 0x7fc00000 or 0x7ff8000000000000.
+	//	* `abs`, `neg`, `copysign`, loads, stores and `reinterpret` are unchanged, as they only move bits.
+	//
+	// Note: Vector (SIMD) instructions are not canonicalized.
+	// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#nan-propagation%E2%91%A0
+	WithDeterministicNaN(bool) RuntimeConfig
+
 	// WithFeatureBulkMemoryOperations adds instructions modify ranges of memory or table entries
 	// ("bulk-memory-operations"). This defaults to false as the feature was not finished in WebAssembly 1.0.
 	//
@@ -116,6 +136,107 @@ type RuntimeConfig interface {
 	// See https://github.com/WebAssembly/spec/blob/main/proposals/simd/SIMD.md
 	WithFeatureSIMD(bool) RuntimeConfig
 
+	// WithFeatureThreads enables the atomic memory instructions of the threads proposal ("threads"). This defaults to
+	// false as the feature is not yet finished.
+	//
+	// Here are the notable effects:
+	//	* Adds instructions such as `i32.atomic.load`, `i64.atomic.store8` and `i32.atomic.rmw.add`
+	//	* Adds instruction `atomic.fence`, which is a no-op
+	//
+	// Note: wazero has no shared memory, so atomic instructions run with single-threaded semantics: they behave like
+	// their non-atomic counterparts. `memory.atomic.notify` always returns zero, and `memory.atomic.wait32` and
+	// `memory.atomic.wait64` fail compilation.
+	//
+	// See https://github.com/WebAssembly/threads/blob/main/proposals/threads/Overview.md
+	WithFeatureThreads(bool) RuntimeConfig
+
+	// WithGuestProfiler writes a pprof CPU profile of the guest call stacks to w when the runtime closes. This defaults
+	// to nil, which disables profiling without any overhead.
+	//
+	// Ex. To see which guest functions took the most time:
+	//
+	//	f, _ := os.Create("guest.pprof")
+	//	r := wazero.NewRuntimeWithConfig(wazero.NewRuntimeConfigInterpreter().WithGuestProfiler(f))
+	//	// Instantiate and call functions, then:
+	//	_ = r.Close(ctx)
+	//	_ = f.Close()
+	//	// $ go tool pprof -top guest.pprof
+	//
+	// Notes
+	//
+	//	* Function names are those in the custom name section, if present, prefixed by the module name.
+	//	* The interpreter samples at each call boundary, so the profile includes the elapsed time of every call.
+	//	* This has no effect when the runtime uses the compiler, ex. NewRuntimeConfigCompiler, as only the interpreter
+	//	  can sample guest call stacks. In that case, no profile is written.
+	WithGuestProfiler(w io.Writer) RuntimeConfig
+
+	// WithInterpreterMetrics counts each operation the interpreter executes into the given InterpreterMetrics. This
+	// defaults to nil, which disables counting without any overhead.
+	//
+	// Ex. To see how often each kind of operation ran:
+	//	metrics := wazero.NewInterpreterMetrics()
+	//	r := wazero.NewRuntimeWithConfig(wazero.NewRuntimeConfigInterpreter().WithInterpreterMetrics(metrics))
+	//	// Instantiate and call functions, then:
+	//	fmt.Println(metrics.Counts()) // ex. map[Add:5 Call:1 ...]
+	//
+	// Note: This is for observability, so it does not limit execution.
+	// Note: This has no effect when the runtime uses the compiler, ex. NewRuntimeConfigCompiler.
+	WithInterpreterMetrics(InterpreterMetrics) RuntimeConfig
+
+	// WithStepper pauses the interpreter before each operation it executes, until the given InterpreterStepper allows
+	// it to continue. This defaults to nil, which executes without pausing or any overhead.
+	//
+	// Ex. To single-step a function in a REPL-style debugger:
+	//	stepper := wazero.NewInterpreterStepper()
+	//	r := wazero.NewRuntimeWithConfig(wazero.NewRuntimeConfigInterpreter().WithStepper(stepper))
+	//	// Instantiate a module without a start function, then call a function in another goroutine, as it blocks
+	//	// until stepped:
+	//	go fn.Call(ctx)
+	//	for stepper.Step(1) {
+	//		fmt.Println(stepper.FunctionIndex(), stepper.PC(), stepper.Stack())
+	//	}
+	//
+	// Notes
+	//
+	//	* This has no effect when the runtime uses the compiler, ex. NewRuntimeConfigCompiler.
+	//	* Every call in the runtime pauses, including start functions. Ex. Runtime.InstantiateModule of a module that
+	//	  exports "_start" blocks until Step is called from another goroutine, same as the call in the example above.
+	//	* The stepper isn't safe for concurrent use, so only call one function at a time in the runtime, including
+	//	  instantiation. Calls on two goroutines at once race on the stepper's state.
+	WithStepper(InterpreterStepper) RuntimeConfig
+
+	// WithStubMissingImports when true allows instantiating a module that imports functions no module provides. Each
+	// missing function is stubbed with one that fails the call with api.ErrUnimplementedImport. This defaults to
+	// false, which fails instantiation instead.
+	//
+	// This helps bring up a program incrementally, as it only fails on the imports it actually calls. Ex.
+	//	r := wazero.NewRuntimeWithConfig(wazero.NewRuntimeConfig().WithStubMissingImports(true))
+	//	// Instantiate and call functions, then:
+	//	var unimplemented *api.ErrUnimplementedImport
+	//	if errors.As(err, &unimplemented) {
+	//		log.Printf("implement %s.%s", unimplemented.Module, unimplemented.Name)
+	//	}
+	//
+	// Note: Only function imports are stubbed. Other missing imports, such as memory, still fail instantiation.
+	// Note: An import that exists with the wrong type is not stubbed, so still fails instantiation.
+	WithStubMissingImports(bool) RuntimeConfig
+
+	// WithTrapHandler calls the handler each time a call to a guest function traps, with diagnostic state captured
+	// before the call returns its error. This defaults to nil, which disables it without any overhead.
+	//
+	// Ex. To log where a long-running guest trapped:
+	//
+	//	r := wazero.NewRuntimeWithConfig(wazero.NewRuntimeConfig().WithTrapHandler(func(info wazero.TrapInfo) {
+	//		log.Printf("%s in %s (memory: %d bytes)\n%s", info.Reason, info.Function, info.MemorySize, info.Stderr)
+	//	}))
+	//
+	// Notes
+	//
+	//	* This is for observability only: the handler cannot change the error the call returns.
+	//	* The handler is called on the goroutine of the call, so it should return quickly.
+	//	* The operand stack isn't captured, as the compiler keeps operands in registers, not memory.
+	WithTrapHandler(func(TrapInfo)) RuntimeConfig
+
 	// WithWasmCore1 enables features included in the WebAssembly Core Specification 1.0. Selecting this
 	// overwrites any currently accumulated features with only those included in this W3C recommendation.
 	//
@@ -147,10 +268,26 @@ func NewRuntimeConfig() RuntimeConfig {
 }
 
 type runtimeConfig struct {
-	enabledFeatures wasm.Features
-	newEngine       func(wasm.Features) wasm.Engine
+	enabledFeatures    wasm.Features
+	newEngine          func(*runtimeConfig) wasm.Engine
+	engineKind         string
+	interpreterMetrics *interpreter.Metrics
+	interpreterStepper *interpreter.Stepper
+	guestProfile       io.Writer
+	stubMissingImports bool
+	deterministicNaN   bool
+	trapHandler        func(TrapInfo)
+
+	// interpreterProfiler is set by NewRuntimeWithConfig when guestProfile is non-nil, so each runtime has its own.
+	interpreterProfiler *interpreter.Profiler
 }
 
+// engineKind values identify the engine a runtimeConfig creates, ex. for ModuleCacheKey.
+const (
+	engineKindCompiler    = "compiler"
+	engineKindInterpreter = "interpreter"
+)
+
 // engineLessConfig helps avoid copy/pasting the wrong defaults.
 var engineLessConfig = &runtimeConfig{
 	enabledFeatures: wasm.Features20191205,
@@ -167,19 +304,45 @@ var engineLessConfig = &runtimeConfig{
 // part. wazero automatically performs ahead-of-time compilation as needed when
 // Runtime.CompileModule is invoked.
 //
-// Warning: This panics at runtime if the runtime.GOOS or runtime.GOARCH does not
-// support Compiler. Use NewRuntimeConfig to safely detect and fallback to
-// NewRuntimeConfigInterpreter if needed.
+// Warning: NewRuntimeWithConfig panics if the runtime.GOOS or runtime.GOARCH
+// does not support Compiler. Use NewRuntimeConfig to safely detect and fallback
+// to NewRuntimeConfigInterpreter if needed.
 func NewRuntimeConfigCompiler() RuntimeConfig {
 	ret := *engineLessConfig // copy
-	ret.newEngine = compiler.NewEngine
+	ret.newEngine, ret.engineKind = newCompilerEngine, engineKindCompiler
 	return &ret
 }
 
+// compilerSupported is a variable so that tests can simulate a platform that doesn't support the compiler.
+var compilerSupported = platform.CompilerSupported
+
+func newCompilerEngine(c *runtimeConfig) wasm.Engine {
+	if !compilerSupported() {
+		panic(fmt.Errorf("compiler is not supported on %s/%s: use NewRuntimeConfigInterpreter instead",
+			goruntime.GOOS, goruntime.GOARCH))
+	}
+	return compiler.NewEngine(c.enabledFeatures)
+}
+
 // NewRuntimeConfigInterpreter interprets WebAssembly modules instead of compiling them into assembly.
 func NewRuntimeConfigInterpreter() RuntimeConfig {
 	ret := *engineLessConfig // copy
-	ret.newEngine = interpreter.NewEngine
+	ret.newEngine, ret.engineKind = newInterpreterEngine, engineKindInterpreter
+	return &ret
+}
+
+func newInterpreterEngine(c *runtimeConfig) wasm.Engine {
+	return interpreter.NewEngineWithOptions(c.enabledFeatures, interpreter.EngineOptions{
+		Metrics:  c.interpreterMetrics,
+		Stepper:  c.interpreterStepper,
+		Profiler: c.interpreterProfiler,
+	})
+}
+
+// WithDeterministicNaN implements RuntimeConfig.WithDeterministicNaN
+func (c *runtimeConfig) WithDeterministicNaN(enabled bool) RuntimeConfig {
+	ret := *c // copy
+	ret.deterministicNaN = enabled
 	return &ret
 }
 
@@ -236,6 +399,62 @@ func (c *runtimeConfig) WithFeatureSIMD(enabled bool) RuntimeConfig {
 	return &ret
 }
 
+// WithFeatureThreads implements RuntimeConfig.WithFeatureThreads
+func (c *runtimeConfig) WithFeatureThreads(enabled bool) RuntimeConfig {
+	ret := *c // copy
+	ret.enabledFeatures = ret.enabledFeatures.Set(wasm.FeatureThreads, enabled)
+	return &ret
+}
+
+// WithGuestProfiler implements RuntimeConfig.WithGuestProfiler
+func (c *runtimeConfig) WithGuestProfiler(w io.Writer) RuntimeConfig {
+	ret := *c // copy
+	if ret.engineKind != engineKindCompiler { // Only the interpreter can sample guest call stacks.
+		ret.guestProfile = w
+	}
+	return &ret
+}
+
+// WithInterpreterMetrics implements RuntimeConfig.WithInterpreterMetrics
+func (c *runtimeConfig) WithInterpreterMetrics(metrics InterpreterMetrics) RuntimeConfig {
+	ret := *c // copy
+	if metrics == nil {
+		ret.interpreterMetrics = nil
+	} else if m, ok := metrics.(*interpreter.Metrics); ok {
+		ret.interpreterMetrics = m
+	} else {
+		panic(fmt.Errorf("unsupported wazero.InterpreterMetrics implementation: %#v", metrics))
+	}
+	return &ret
+}
+
+// WithStepper implements RuntimeConfig.WithStepper
+func (c *runtimeConfig) WithStepper(stepper InterpreterStepper) RuntimeConfig {
+	ret := *c // copy
+	if stepper == nil {
+		ret.interpreterStepper = nil
+	} else if s, ok := stepper.(*interpreter.Stepper); ok {
+		ret.interpreterStepper = s
+	} else {
+		panic(fmt.Errorf("unsupported wazero.InterpreterStepper implementation: %#v", stepper))
+	}
+	return &ret
+}
+
+// WithStubMissingImports implements RuntimeConfig.WithStubMissingImports
+func (c *runtimeConfig) WithStubMissingImports(enabled bool) RuntimeConfig {
+	ret := *c // copy
+	ret.stubMissingImports = enabled
+	return &ret
+}
+
+// WithTrapHandler implements RuntimeConfig.WithTrapHandler
+func (c *runtimeConfig) WithTrapHandler(handler func(TrapInfo)) RuntimeConfig {
+	ret := *c // copy
+	ret.trapHandler = handler
+	return &ret
+}
+
 // WithWasmCore1 implements RuntimeConfig.WithWasmCore1
 func (c *runtimeConfig) WithWasmCore1() RuntimeConfig {
 	ret := *c // copy
@@ -250,6 +469,56 @@ func (c *runtimeConfig) WithWasmCore2() RuntimeConfig {
 	return &ret
 }
 
+// InterpreterMetrics counts the operations executed by the interpreter, by kind. Use NewInterpreterMetrics to create
+// one and RuntimeConfig.WithInterpreterMetrics to enable it.
+//
+// Note: Kinds are those of the interpreter's intermediate representation, which are named in CamelCase and are not
+// specific to a value type. Ex. "Add" counts both `i32.add` and `f64.add`, and "Load" counts `i32.load`.
+// Note: This is goroutine-safe, so the same InterpreterMetrics can be shared by concurrent calls.
+type InterpreterMetrics interface {
+	// Counts returns the number of times each kind of operation was executed, keyed by name. Ex. "Call" or "Add".
+	// Kinds that were never executed are not included.
+	Counts() map[string]uint64
+
+	// Reset sets all counts back to zero.
+	Reset()
+}
+
+// NewInterpreterMetrics returns InterpreterMetrics with all counts at zero.
+func NewInterpreterMetrics() InterpreterMetrics {
+	return interpreter.NewMetrics()
+}
+
+// InterpreterStepper single-steps the operations executed by the interpreter. Use NewInterpreterStepper to create one
+// and RuntimeConfig.WithStepper to enable it.
+//
+// While enabled, each call blocks before its first operation until Step is called from another goroutine. This includes
+// start functions, so instantiating a module blocks, too, if it has one.
+//
+// Note: Operations are those of the interpreter's intermediate representation, so the PC doesn't correspond to a
+// byte offset in the WebAssembly binary. Ex. `local.get` is lowered to a "Pick" operation.
+// Note: This isn't safe for concurrent calls, which race on its state, so only debug one call at a time.
+type InterpreterStepper interface {
+	// Step allows the interpreter to execute n operations, then blocks until it has. This returns false if the call
+	// returned before that, or true when it is paused before the next operation.
+	Step(n uint64) bool
+
+	// FunctionIndex returns the module-scoped index of the function of the next operation, when Step returned true.
+	FunctionIndex() uint32
+
+	// PC returns the index of the next operation in the function body, when Step returned true.
+	PC() uint64
+
+	// Stack returns the value stack, when Step returned true. This includes the locals of each function in the call
+	// stack, and the last value is the top of the stack.
+	Stack() []uint64
+}
+
+// NewInterpreterStepper returns an InterpreterStepper which pauses before the first operation of the next call.
+func NewInterpreterStepper() InterpreterStepper {
+	return interpreter.NewStepper()
+}
+
 // CompiledModule is a WebAssembly 1.0 module ready to be instantiated (Runtime.InstantiateModule) as an api.Module.
 //
 // In WebAssembly terminology, this is a decoded, validated, and possibly also compiled module. wazero avoids using
@@ -262,6 +531,39 @@ type CompiledModule interface {
 	//
 	// Note: It is safe to call Close while having outstanding calls from an api.Module instantiated from this.
 	Close(context.Context) error
+
+	// CustomSections returns the custom sections in the order they were decoded from the binary format.
+	//
+	// Note: This excludes the "name" section, which wazero decodes for function names in stack traces.
+	CustomSections() []CustomSection
+
+	// DisassembleFunction returns a human-readable listing of the WebAssembly instructions of the function at the
+	// given index, or an error if it is out of range or imported. This is the decoded binary format, not the machine
+	// code of any compiler, and is intended for debugging.
+	//
+	// Each line has the offset of the instruction in the function body, its name and immediates, indented by block
+	// nesting. For example:
+	//
+	//	0x0000: local.get 0
+	//	0x0002: local.get 1
+	//	0x0004: i32.add
+	//	0x0005: end
+	//
+	// Note: The index is in the function index namespace, which begins with any imported functions.
+	DisassembleFunction(index uint32) (string, error)
+}
+
+// CustomSection is a custom section, such as "producers" or DWARF debug info, retained from the binary format.
+//
+// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#custom-section%E2%91%A0
+type CustomSection interface {
+	// Name is the name of the custom section, ex. "producers".
+	Name() string
+
+	// Data is the raw content of the section, after the name.
+	//
+	// Note: The caller must not modify the result.
+	Data() []byte
 }
 
 type compiledModule struct {
@@ -282,6 +584,38 @@ func (c *compiledModule) Close(_ context.Context) error {
 	return nil
 }
 
+// DisassembleFunction implements CompiledModule.DisassembleFunction
+func (c *compiledModule) DisassembleFunction(index uint32) (string, error) {
+	return c.module.DisassembleFunction(index)
+}
+
+// CustomSections implements CompiledModule.CustomSections
+func (c *compiledModule) CustomSections() []CustomSection {
+	if len(c.module.CustomSections) == 0 {
+		return nil
+	}
+	ret := make([]CustomSection, len(c.module.CustomSections))
+	for i, s := range c.module.CustomSections {
+		ret[i] = &customSection{s}
+	}
+	return ret
+}
+
+// customSection implements CustomSection
+type customSection struct {
+	s *wasm.CustomSection
+}
+
+// Name implements CustomSection.Name
+func (c *customSection) Name() string {
+	return c.s.Name
+}
+
+// Data implements CustomSection.Data
+func (c *customSection) Data() []byte {
+	return c.s.Data
+}
+
 // CompileConfig allows you to override what was decoded from wasm, prior to compilation (ModuleBuilder.Compile or
 // Runtime.CompileModule).
 //
@@ -293,27 +627,128 @@ type CompileConfig interface {
 	// WithImportRenamer can rename imports or break them into different modules. No default.
 	// A nil function is invalid and ignored.
 	//
+	// Ex. To satisfy imports from an older WASI ABI with the "wasi_snapshot_preview1" host module:
+	//	config := wazero.NewCompileConfig().WithImportRenamer(func(_ api.ExternType, oldModule, oldName string) (string, string) {
+	//		if oldModule == "wasi_unstable" {
+	//			return "wasi_snapshot_preview1", oldName
+	//		}
+	//		return oldModule, oldName
+	//	})
+	//
+	// Note: Runtime.CompileModule errs if two imports that had different names are renamed to the same module and name.
+	//
 	// Note: This is currently not relevant for ModuleBuilder as it has no means to define imports.
 	WithImportRenamer(api.ImportRenamer) CompileConfig
 
 	// WithMemorySizer are the allocation parameters used for a Wasm memory.
 	// The default is to set cap=min and max=65536 if unset. A nil function is invalid and ignored.
 	WithMemorySizer(api.MemorySizer) CompileConfig
+
+	// WithFeatures overrides the features RuntimeConfig enabled, such as FeatureSIMD, when decoding and validating
+	// the module. Defaults to those of the RuntimeConfig.
+	//
+	// This allows a restricted mode for untrusted modules, or enabling a proposal for only some modules. Ex. To reject
+	// modules that use SIMD, even if the runtime allows it:
+	//	config := wazero.NewCompileConfig().WithFeatures(wazero.FeaturesWasmCore2.Set(wazero.FeatureSIMD, false))
+	//
+	// Note: This is currently not relevant for ModuleBuilder as it has no means to define instructions.
+	WithFeatures(Features) CompileConfig
+
+	// WithOptimizationLevel trades the time to compile the module for the speed of its code. Defaults to
+	// OptimizationLevelFull.
+	//
+	// Long-running hosts, such as servers, amortize compilation over many calls, so are best served by the default.
+	// Short-lived ones, such as a CLI running a module once, can spend more time compiling than running, so can choose
+	// OptimizationLevelMinimal to start faster.
+	//
+	// Notes
+	//
+	//	* This only affects NewRuntimeConfigCompiler on amd64. The interpreter and arm64 have no optional passes.
+	//	* A binary already compiled by the Runtime is not compiled again, so keeps the level it was first compiled at.
+	//	* The results of functions are the same at any level.
+	WithOptimizationLevel(OptimizationLevel) CompileConfig
+
+	// WithCustomSectionHandler is called with each custom section of the module, such as "producers" or
+	// "target_features", before it is compiled. No default. A nil function is invalid and ignored.
+	//
+	// If the handler returns an error, compilation fails with it. This allows rejecting modules built for unsupported
+	// targets, or extracting build metadata. Ex. To log the toolchain that built the module:
+	//	config := wazero.NewCompileConfig().WithCustomSectionHandler(func(name string, data []byte) error {
+	//		if name == "producers" {
+	//			log.Printf("producers: %x", data)
+	//		}
+	//		return nil
+	//	})
+	//
+	// Note: This is currently not relevant for ModuleBuilder as it has no means to define custom sections.
+	WithCustomSectionHandler(api.CustomSectionHandler) CompileConfig
+
+	// WithTrustedMemory when true omits the bounds checks the compiler inserts before each memory load and store.
+	// Defaults to false.
+	//
+	// WARNING: This is unsafe! Memory is not backed by guard pages, so an out-of-bounds access isn't trapped: it reads
+	// or writes host memory adjacent to the module's memory, which can corrupt or crash the host. Only enable this for
+	// modules you fully control, which are known to never access memory out of bounds.
+	//
+	// This is a performance option for load and store heavy code on a hot path. Results of in-bounds accesses are the
+	// same as when checked.
+	//
+	// Notes
+	//
+	//	* This only affects NewRuntimeConfigCompiler. The interpreter always checks bounds.
+	//	* This includes SIMD loads and stores. Atomic and bulk memory instructions are still checked.
+	//	* Code compiled this way isn't shared with compilations of the same binary that keep bounds checks.
+	WithTrustedMemory(bool) CompileConfig
 }
 
+// OptimizationLevel is the amount of work done to make the code of a compiled module faster.
+// See CompileConfig.WithOptimizationLevel
+type OptimizationLevel uint8
+
+const (
+	// OptimizationLevelMinimal skips any compilation pass which only makes the code faster, for the shortest compile
+	// time.
+	OptimizationLevelMinimal OptimizationLevel = iota
+
+	// OptimizationLevelFull runs all compilation passes, for the fastest code. This is the default.
+	OptimizationLevelFull
+)
+
 type compileConfig struct {
-	importRenamer api.ImportRenamer
-	memorySizer   api.MemorySizer
+	importRenamer        api.ImportRenamer
+	memorySizer          api.MemorySizer
+	enabledFeatures      *wasm.Features
+	optimizationLevel    OptimizationLevel
+	customSectionHandler api.CustomSectionHandler
+	trustedMemory        bool
 }
 
 // NewCompileConfig returns a CompileConfig that can be used for configuring module compilation.
 func NewCompileConfig() CompileConfig {
 	return &compileConfig{
-		importRenamer: nil,
-		memorySizer:   wasm.MemorySizer,
+		importRenamer:     nil,
+		memorySizer:       wasm.MemorySizer,
+		optimizationLevel: OptimizationLevelFull,
 	}
 }
 
+// WithFeatures implements CompileConfig.WithFeatures
+func (c *compileConfig) WithFeatures(features Features) CompileConfig {
+	ret := *c // copy
+	ret.enabledFeatures = &features
+	return &ret
+}
+
+// WithOptimizationLevel implements CompileConfig.WithOptimizationLevel
+func (c *compileConfig) WithOptimizationLevel(level OptimizationLevel) CompileConfig {
+	if level > OptimizationLevelFull {
+		level = OptimizationLevelFull
+	}
+	ret := *c // copy
+	ret.optimizationLevel = level
+	return &ret
+}
+
 // WithImportRenamer implements CompileConfig.WithImportRenamer
 func (c *compileConfig) WithImportRenamer(importRenamer api.ImportRenamer) CompileConfig {
 	if importRenamer == nil {
@@ -334,6 +769,23 @@ func (c *compileConfig) WithMemorySizer(memorySizer api.MemorySizer) CompileConf
 	return &ret
 }
 
+// WithCustomSectionHandler implements CompileConfig.WithCustomSectionHandler
+func (c *compileConfig) WithCustomSectionHandler(customSectionHandler api.CustomSectionHandler) CompileConfig {
+	if customSectionHandler == nil {
+		return c
+	}
+	ret := *c // copy
+	ret.customSectionHandler = customSectionHandler
+	return &ret
+}
+
+// WithTrustedMemory implements CompileConfig.WithTrustedMemory
+func (c *compileConfig) WithTrustedMemory(enabled bool) CompileConfig {
+	ret := *c // copy
+	ret.trustedMemory = enabled
+	return &ret
+}
+
 // ModuleConfig configures resources needed by functions that have low-level interactions with the host operating
 // system. Using this, resources such as STDIN can be isolated, so that the same module can be safely instantiated
 // multiple times.
@@ -352,7 +804,8 @@ func (c *compileConfig) WithMemorySizer(memorySizer api.MemorySizer) CompileConf
 type ModuleConfig interface {
 
 	// WithArgs assigns command-line arguments visible to an imported function that reads an arg vector (argv). Defaults to
-	// none. Runtime.InstantiateModule errs if any arg is empty.
+	// none. Runtime.InstantiateModule errs if any arg contains a NUL(0) character, as that would corrupt the
+	// null-terminated encoding. Empty args are allowed.
 	//
 	// These values are commonly read by the functions like "args_get" in "wasi_snapshot_preview1" although they could be
 	// read by functions imported from other modules.
@@ -366,11 +819,38 @@ type ModuleConfig interface {
 	// See https://linux.die.net/man/3/argv and https://en.wikipedia.org/wiki/Null-terminated_string
 	WithArgs(...string) ModuleConfig
 
-	// WithEnv sets an environment variable visible to a Module that imports functions. Defaults to none.
-	// Runtime.InstantiateModule errs if the key is empty or contains a NULL(0) or equals("") character.
+	// WithCloseNotifier configures a function called once after the module closes, with its exit code. Defaults to
+	// none.
+	//
+	// This is called on any path that closes the module: api.Module Close or CloseWithExitCode, including via
+	// "proc_exit" in "wasi_snapshot_preview1", and closing the Namespace or Runtime that contains it. Use this to
+	// release host resources associated with the module, such as connections.
+	//
+	// Ex. To release a connection opened for the module:
 	//
-	// Validation is the same as os.Setenv on Linux and replaces any existing value. Unlike exec.Cmd Env, this does not
-	// default to the current process environment as that would violate sandboxing. This also does not preserve order.
+	//	config := wazero.NewModuleConfig().WithCloseNotifier(func(ctx context.Context, exitCode uint32) {
+	//		conn.Close()
+	//	})
+	//
+	// Notes
+	//
+	//	* The function is called after system resources, such as stdout, are closed.
+	//	* A trap does not close the module, so doesn't call this function: the module can still be called, and is only
+	//	  notified when the host closes it. To close a module when it traps, call api.Module Close when an
+	//	  api.Function call returns an error.
+	//	* This is not called if the WebAssembly start function, if the module defines one, closes the module, as
+	//	  that is part of instantiation. See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#start-function%E2%91%A0
+	WithCloseNotifier(func(ctx context.Context, exitCode uint32)) ModuleConfig
+
+	// WithEnv adds an environment variable visible to a Module that imports functions. Defaults to none.
+	// Runtime.InstantiateModule errs if the key is empty or contains a NULL(0) or equals("=") character, or if the
+	// value contains a NULL(0) character.
+	//
+	// Validation is the same as os.Setenv on Linux. Unlike exec.Cmd Env, this does not default to the current process
+	// environment as that would violate sandboxing.
+	//
+	// Entries are passed to the module in the order they were added, as "key=value". Like os.Environ, keys are not
+	// de-duplicated: calling this twice with the same key results in two entries, and the module decides which wins.
 	//
 	// Environment variables are commonly read by the functions like "environ_get" in "wasi_snapshot_preview1" although
 	// they could be read by functions imported from other modules.
@@ -396,14 +876,124 @@ type ModuleConfig interface {
 	//	// "index.html" is accessible as both "/index.html" and "./index.html" because we didn't use WithWorkDirFS.
 	//	config := wazero.NewModuleConfig().WithFS(rooted)
 	//
+	// Note: The file system is read-only unless it also implements methods to write, such as NewWritableDirFS does:
+	//	* `OpenFile(name string, flag int, perm fs.FileMode) (fs.File, error)` allows creating and writing files.
+	//	* `Mkdir(name string, perm fs.FileMode) error` allows creating directories.
 	WithFS(fs.FS) ModuleConfig
 
-	// WithName configures the module name. Defaults to what was decoded or overridden via CompileConfig.WithModuleName.
+	// WithOsWorkdir assigns the current directory of the host process, which can be read and written like
+	// NewWritableDirFS, as the file system to use for any paths beginning at "/". This is the common case of a CLI tool,
+	// where a guest reading "./foo.txt" reads the file "foo.txt" in the directory the host was started from.
+	//
+	// Ex. This is the same as the following:
+	//
+	//	wd, _ := os.Getwd()
+	//	config := wazero.NewModuleConfig().WithFS(wazero.NewWritableDirFS(wd))
+	//
+	// Note: This sets WithWorkDirFS to the same file-system unless already set, like WithFS.
+	// Note: The current directory is resolved when this is called, so a host calling os.Chdir later doesn't change
+	// what the guest sees. If it cannot be resolved, instantiation fails with the error of os.Getwd.
+	WithOsWorkdir() ModuleConfig
+
+	// WithMemoryInit configures a function to initialize memory before any start functions run. Defaults to none.
+	//
+	// The function is called after the memory is created and data segments are applied, but before any functions
+	// configured by WithStartFunctions, such as "_start". If it returns an error, the module is closed and
+	// Runtime.InstantiateModule fails with that error.
+	//
+	// Ex. To seed a configuration blob the guest reads during "_start":
+	//
+	//	config := wazero.NewModuleConfig().WithMemoryInit(func(ctx context.Context, mem api.Memory) error {
+	//		if !mem.Write(ctx, configOffset, configBlob) {
+	//			return errors.New("config blob out of range")
+	//		}
+	//		return nil
+	//	})
+	//
+	// Notes
+	//
+	//	* The function is not called if the module has no memory, either defined or imported.
+	//	* This is called after the WebAssembly start function, if the module defines one, as that is part of
+	//	  instantiation. See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#start-function%E2%91%A0
+	WithMemoryInit(func(context.Context, api.Memory) error) ModuleConfig
+
+	// WithMemoryGrowHook configures a function consulted each time the module's memory grows, ex. by the
+	// "memory.grow" instruction. Defaults to none, which allows growth up to the maximum size of the memory.
+	//
+	// The function is called with the current size and the requested delta, both in pages (65536 bytes). When it
+	// returns false, memory is not grown, so "memory.grow" returns -1. This allows quotas that change at runtime,
+	// unlike the static limit of CompileConfig.WithMemorySizer, as well as logging growth.
+	//
+	// Ex. To deny growth beyond a quota that can be lowered while the module runs:
+	//
+	//	var quotaPages uint32 = 16 // updated atomically elsewhere
+	//	config := wazero.NewModuleConfig().WithMemoryGrowHook(func(ctx context.Context, currentPages, delta uint32) bool {
+	//		return currentPages+delta <= atomic.LoadUint32(&quotaPages)
+	//	})
+	//
+	// Notes
+	//
+	//	* The function is only used when the module defines its memory, as opposed to importing it.
+	//	* The function is also consulted by api.Memory Grow, ex. when called by a host function.
+	//	* The function must not grow the memory itself, as it is called while growth is in progress.
+	//	* This is not consulted during the WebAssembly start function, if the module defines one, as that is part of
+	//	  instantiation. See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#start-function%E2%91%A0
+	WithMemoryGrowHook(func(ctx context.Context, currentPages, delta uint32) (allow bool)) ModuleConfig
+
+	// WithMaxTableElements limits the count of elements each table defined by the module can grow to, ex. via the
+	// "table.grow" instruction. Defaults to none, which allows growth up to the maximum size of the table.
+	//
+	// This bounds the host memory a guest can allocate for tables, similar to WithMemoryGrowHook for memory. When
+	// growing would exceed maxElements, the table is not grown, so "table.grow" returns -1.
+	//
+	// Ex. To allow tables to grow up to 1024 elements:
+	//
+	//	config := wazero.NewModuleConfig().WithMaxTableElements(1024)
+	//
+	// Notes
+	//
+	//	* This is only used for tables the module defines, as opposed to imports.
+	//	* This doesn't fail instantiation of a module whose tables are initially larger than maxElements.
+	//	* This is not consulted during the WebAssembly start function, if the module defines one, as that is part of
+	//	  instantiation. See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#start-function%E2%91%A0
+	WithMaxTableElements(maxElements uint32) ModuleConfig
+
+	// WithName configures the module name, which must be unique in the Namespace. Defaults to what was decoded from the
+	// name section, so use this to instantiate the same CompiledModule more than once in the same Namespace.
+	//
+	// Note: An empty name falls back to the default.
 	WithName(string) ModuleConfig
 
-	// WithStartFunctions configures the functions to call after the module is instantiated. Defaults to "_start".
+	// WithSocket opens the connection at the file descriptor fd, which must be at least 3 and not used by WithFS or
+	// WithWorkDirFS. Defaults to none.
+	//
+	// The connection is most commonly used by the functions "sock_recv", "sock_send" and "sock_shutdown" in
+	// "wasi_snapshot_preview1", which return ENOTSOCK for file descriptors not opened this way. For example, a host can
+	// accept connections itself and hand each one to a new module:
+	//
+	//	conn, err := listener.Accept()
+	//	// handle error
+	//	mod, err := r.InstantiateModule(ctx, compiled, wazero.NewModuleConfig().WithSocket(3, conn))
+	//
+	// Notes
 	//
-	// Note: If any function doesn't exist, it is skipped. However, all functions that do exist are called in order.
+	//	* The connection is closed on api.Module Close, so it can't be shared: Don't use the same config for more than
+	//	  one module. Hence, InstancePool.Get errs if the config has a socket.
+	//	* "sock_shutdown" requires the connection to implement CloseRead or CloseWrite, as net.TCPConn does.
+	//	* Runtime.InstantiateModule errs if fd conflicts with a file system or conn is nil.
+	WithSocket(fd uint32, conn net.Conn) ModuleConfig
+
+	// WithStartFunctions configures the functions to call after the module is instantiated. Defaults to the WASI
+	// application ABI: "_start" for a command, or "_initialize" for a reactor, which exports "_initialize", but not
+	// "_start".
+	//
+	// Notes
+	//
+	//	* If any function doesn't exist, it is skipped. However, all functions that do exist are called in order.
+	//	* Calling this with no functions disables the default, so no function is called.
+	//	* Exports of a reactor fail until its "_initialize" returns, even if overridden to not call it.
+	//
+	// See https://github.com/WebAssembly/WASI/blob/snapshot-01/design/application-abi.md#current-unstable-abi
 	WithStartFunctions(...string) ModuleConfig
 
 	// WithStderr configures where standard error (file descriptor 2) is written. Defaults to io.Discard.
@@ -413,8 +1003,10 @@ type ModuleConfig interface {
 	//
 	// Notes
 	//
-	//	* The caller is responsible to close any io.Writer they supply: It is not closed on api.Module Close.
+	//	* The caller is responsible to close any io.Writer they supply, unless WithCloseStdio is set.
 	//	* This does not default to os.Stderr as that both violates sandboxing and prevents concurrent modules.
+	//	* When an api.Function call fails after the guest wrote to stderr, ex. a panic message, the error includes what
+	//	  it wrote. See sys.GuestStderrError
 	//
 	// See https://linux.die.net/man/3/stderr
 	WithStderr(io.Writer) ModuleConfig
@@ -439,12 +1031,46 @@ type ModuleConfig interface {
 	//
 	// Notes
 	//
-	//	* The caller is responsible to close any io.Writer they supply: It is not closed on api.Module Close.
+	//	* The caller is responsible to close any io.Writer they supply, unless WithCloseStdio is set.
 	//	* This does not default to os.Stdout as that both violates sandboxing and prevents concurrent modules.
 	//
 	// See https://linux.die.net/man/3/stdout
 	WithStdout(io.Writer) ModuleConfig
 
+	// WithCloseStdio closes the writers configured by WithStdout and WithStderr, which implement io.Closer, on
+	// api.Module Close. Defaults to not closing them, so the caller is responsible for doing so.
+	//
+	// Notes
+	//
+	//	* Only set this when the ModuleConfig instantiates a single module. Otherwise, the first module to close
+	//	  closes the writers of all the others still running. Hence, NewInstancePool ignores this.
+	//	* os.Stdout and os.Stderr are never closed, as they belong to the host process. When the same writer is used
+	//	  for both stdout and stderr, it is only closed once.
+	WithCloseStdio() ModuleConfig
+
+	// WithStdioWriteBoundaries delivers each guest write to stdout or stderr as a single io.Writer.Write call. Defaults
+	// to one call per buffer.
+	//
+	// For example, "fd_write" in "wasi_snapshot_preview1" accepts a vector of buffers. By default, each buffer is written
+	// separately, so a writer sees fragments of what the guest considered one write. When enabled, the buffers are
+	// gathered first, which lets a writer treat each Write as a record, such as a log line.
+	//
+	// Note: This does not affect files opened by the guest.
+	WithStdioWriteBoundaries() ModuleConfig
+
+	// WithBufferedStdout buffers up to size bytes written to stdout before writing them to the writer configured by
+	// WithStdout. Defaults to no buffering, where each guest write reaches the writer immediately.
+	//
+	// The buffer is written when full, when an api.Function call returns an error such as a trap, and when the module is
+	// closed, by any means including "proc_exit" in "wasi_snapshot_preview1", api.Module Close or Runtime Close. Hence,
+	// output written before a trap is not held back.
+	//
+	// Notes
+	//
+	//	* A size of zero or less disables buffering.
+	//	* Buffered output is lost if the module is never closed.
+	WithBufferedStdout(size int) ModuleConfig
+
 	// WithWalltime configures the wall clock, sometimes referred to as the
 	// real time clock. Defaults to a constant fake result.
 	//
@@ -482,6 +1108,28 @@ type ModuleConfig interface {
 	// See WithNanotime
 	WithSysNanotime() ModuleConfig
 
+	// WithNanosleep configures how functions that block on a timeout, such as "poll_oneoff" in
+	// "wasi_snapshot_preview1", sleep. Defaults to returning immediately.
+	//
+	// Ex. To control time in tests, advance the clock configured by WithNanotime instead of sleeping:
+	//	var now int64
+	//	moduleConfig = moduleConfig.
+	//		WithNanotime(func(context.Context) int64 {
+	//			return now
+	//		}, sys.ClockResolution(1)).
+	//		WithNanosleep(func(ctx context.Context, ns int64) {
+	//			now += ns
+	//		})
+	//
+	// Note: This does not default to time.Sleep as that would block on the fake clock of WithNanotime. Use
+	// WithSysNanosleep for a usable implementation.
+	WithNanosleep(sys.Nanosleep) ModuleConfig
+
+	// WithSysNanosleep uses time.Timer for sys.Nanosleep, returning early when the context is done.
+	//
+	// See WithNanosleep
+	WithSysNanosleep() ModuleConfig
+
 	// WithRandSource configures a source of random bytes. Defaults to crypto/rand.Reader.
 	//
 	// This reader is most commonly used by the functions like "random_get" in "wasi_snapshot_preview1" or "seed" in
@@ -508,6 +1156,10 @@ type ModuleConfig interface {
 type moduleConfig struct {
 	name               string
 	startFunctions     []string
+	memoryInit         func(context.Context, api.Memory) error
+	memoryGrowHook     func(context.Context, uint32, uint32) bool
+	maxTableElements   *uint32
+	closeNotifier      func(context.Context, uint32)
 	stdin              io.Reader
 	stdout             io.Writer
 	stderr             io.Writer
@@ -516,20 +1168,41 @@ type moduleConfig struct {
 	walltimeResolution sys.ClockResolution
 	nanotimeTime       *sys.Nanotime
 	nanotimeResolution sys.ClockResolution
+	nanosleep          *sys.Nanosleep
 	args               []string
 	// environ is pair-indexed to retain order similar to os.Environ.
 	environ []string
-	// environKeys allow overwriting of existing values.
-	environKeys map[string]int
-	fs          *internalsys.FSConfig
+	fs      *internalsys.FSConfig
+	// sockets are keyed on file descriptor and copied on write.
+	sockets map[uint32]net.Conn
+	// stdioWriteBoundaries gathers each write to stdout or stderr into one io.Writer.Write call.
+	stdioWriteBoundaries bool
+	// stdoutBufferSize is the size of the buffer in front of stdout, or zero when unbuffered.
+	stdoutBufferSize int
+	// closeStdio closes stdout and stderr on module close. See WithCloseStdio
+	closeStdio bool
+	// osWorkdirErr is the error resolving the current directory in WithOsWorkdir, returned on instantiation.
+	osWorkdirErr error
+}
+
+// NewWritableDirFS returns a file system rooted at the host directory dir, for use in ModuleConfig.WithFS or
+// ModuleConfig.WithWorkDirFS. Unlike os.DirFS, functions such as WASI "path_open" can create and write files in it,
+// "path_create_directory" can create directories, "path_readlink" can read symbolic links and
+// "path_filestat_set_times" can change file times.
+//
+// Ex. To allow a guest to read and write files in "/work/appA" via the paths "/" and ".":
+//
+//	config := wazero.NewModuleConfig().WithFS(wazero.NewWritableDirFS("/work/appA"))
+//
+// Note: Paths with ".." elements, which could escape dir, are rejected. However, symbolic links inside dir are followed,
+// even if they point outside it.
+func NewWritableDirFS(dir string) fs.FS {
+	return internalsys.NewDirFS(dir)
 }
 
 // NewModuleConfig returns a ModuleConfig that can be used for configuring module instantiation.
 func NewModuleConfig() ModuleConfig {
 	return &moduleConfig{
-		startFunctions: []string{"_start"},
-		environKeys:    map[string]int{},
-
 		fs: internalsys.NewFSConfig(),
 	}
 }
@@ -541,16 +1214,19 @@ func (c *moduleConfig) WithArgs(args ...string) ModuleConfig {
 	return &ret
 }
 
+// WithCloseNotifier implements ModuleConfig.WithCloseNotifier
+func (c *moduleConfig) WithCloseNotifier(closeNotifier func(ctx context.Context, exitCode uint32)) ModuleConfig {
+	ret := *c // copy
+	ret.closeNotifier = closeNotifier
+	return &ret
+}
+
 // WithEnv implements ModuleConfig.WithEnv
 func (c *moduleConfig) WithEnv(key, value string) ModuleConfig {
 	ret := *c // copy
-	// Check to see if this key already exists and update it.
-	if i, ok := ret.environKeys[key]; ok {
-		ret.environ[i+1] = value // environ is pair-indexed, so the value is 1 after the key.
-	} else {
-		ret.environKeys[key] = len(ret.environ)
-		ret.environ = append(ret.environ, key, value)
-	}
+	// Copy on write, so that appending doesn't share the underlying array with the receiver.
+	ret.environ = make([]string, 0, len(c.environ)+2)
+	ret.environ = append(append(ret.environ, c.environ...), key, value)
 	return &ret
 }
 
@@ -558,6 +1234,28 @@ func (c *moduleConfig) WithEnv(key, value string) ModuleConfig {
 func (c *moduleConfig) WithFS(fs fs.FS) ModuleConfig {
 	ret := *c // copy
 	ret.fs = ret.fs.WithFS(fs)
+	ret.osWorkdirErr = nil // overwrites the file system of WithOsWorkdir
+	return &ret
+}
+
+// WithMemoryGrowHook implements ModuleConfig.WithMemoryGrowHook
+func (c *moduleConfig) WithMemoryGrowHook(memoryGrowHook func(ctx context.Context, currentPages, delta uint32) (allow bool)) ModuleConfig {
+	ret := *c // copy
+	ret.memoryGrowHook = memoryGrowHook
+	return &ret
+}
+
+// WithMemoryInit implements ModuleConfig.WithMemoryInit
+func (c *moduleConfig) WithMemoryInit(memoryInit func(context.Context, api.Memory) error) ModuleConfig {
+	ret := *c // copy
+	ret.memoryInit = memoryInit
+	return &ret
+}
+
+// WithMaxTableElements implements ModuleConfig.WithMaxTableElements
+func (c *moduleConfig) WithMaxTableElements(maxElements uint32) ModuleConfig {
+	ret := *c // copy
+	ret.maxTableElements = &maxElements
 	return &ret
 }
 
@@ -568,10 +1266,22 @@ func (c *moduleConfig) WithName(name string) ModuleConfig {
 	return &ret
 }
 
+// WithSocket implements ModuleConfig.WithSocket
+func (c *moduleConfig) WithSocket(fd uint32, conn net.Conn) ModuleConfig {
+	ret := *c // copy
+	ret.sockets = make(map[uint32]net.Conn, len(c.sockets)+1)
+	for k, v := range c.sockets {
+		ret.sockets[k] = v
+	}
+	ret.sockets[fd] = conn
+	return &ret
+}
+
 // WithStartFunctions implements ModuleConfig.WithStartFunctions
 func (c *moduleConfig) WithStartFunctions(startFunctions ...string) ModuleConfig {
 	ret := *c // copy
-	ret.startFunctions = startFunctions
+	// Copy to a non-nil slice, so that even no functions override the default.
+	ret.startFunctions = append([]string{}, startFunctions...)
 	return &ret
 }
 
@@ -596,6 +1306,30 @@ func (c *moduleConfig) WithStdout(stdout io.Writer) ModuleConfig {
 	return &ret
 }
 
+// WithBufferedStdout implements ModuleConfig.WithBufferedStdout
+func (c *moduleConfig) WithBufferedStdout(size int) ModuleConfig {
+	if size < 0 {
+		size = 0
+	}
+	ret := *c // copy
+	ret.stdoutBufferSize = size
+	return &ret
+}
+
+// WithCloseStdio implements ModuleConfig.WithCloseStdio
+func (c *moduleConfig) WithCloseStdio() ModuleConfig {
+	ret := *c // copy
+	ret.closeStdio = true
+	return &ret
+}
+
+// WithStdioWriteBoundaries implements ModuleConfig.WithStdioWriteBoundaries
+func (c *moduleConfig) WithStdioWriteBoundaries() ModuleConfig {
+	ret := *c // copy
+	ret.stdioWriteBoundaries = true
+	return &ret
+}
+
 // WithWalltime implements ModuleConfig.WithWalltime
 func (c *moduleConfig) WithWalltime(walltime sys.Walltime, resolution sys.ClockResolution) ModuleConfig {
 	ret := *c // copy
@@ -626,6 +1360,18 @@ func (c *moduleConfig) WithSysNanotime() ModuleConfig {
 	return c.WithNanotime(platform.Nanotime, sys.ClockResolution(1))
 }
 
+// WithNanosleep implements ModuleConfig.WithNanosleep
+func (c *moduleConfig) WithNanosleep(nanosleep sys.Nanosleep) ModuleConfig {
+	ret := *c // copy
+	ret.nanosleep = &nanosleep
+	return &ret
+}
+
+// WithSysNanosleep implements ModuleConfig.WithSysNanosleep
+func (c *moduleConfig) WithSysNanosleep() ModuleConfig {
+	return c.WithNanosleep(platform.Nanosleep)
+}
+
 // WithRandSource implements ModuleConfig.WithRandSource
 func (c *moduleConfig) WithRandSource(source io.Reader) ModuleConfig {
 	ret := *c // copy
@@ -633,6 +1379,20 @@ func (c *moduleConfig) WithRandSource(source io.Reader) ModuleConfig {
 	return &ret
 }
 
+// getwd is a variable so that tests can simulate a current directory that cannot be resolved.
+var getwd = os.Getwd
+
+// WithOsWorkdir implements ModuleConfig.WithOsWorkdir
+func (c *moduleConfig) WithOsWorkdir() ModuleConfig {
+	wd, err := getwd()
+	if err != nil {
+		ret := *c // copy
+		ret.osWorkdirErr = fmt.Errorf("failed to resolve the current directory: %w", err)
+		return &ret
+	}
+	return c.WithFS(internalsys.NewDirFS(wd))
+}
+
 // WithWorkDirFS implements ModuleConfig.WithWorkDirFS
 func (c *moduleConfig) WithWorkDirFS(fs fs.FS) ModuleConfig {
 	ret := *c // copy
@@ -642,6 +1402,10 @@ func (c *moduleConfig) WithWorkDirFS(fs fs.FS) ModuleConfig {
 
 // toSysContext creates a baseline wasm.Context configured by ModuleConfig.
 func (c *moduleConfig) toSysContext() (sysCtx *internalsys.Context, err error) {
+	if c.osWorkdirErr != nil {
+		return nil, c.osWorkdirErr
+	}
+
 	var environ []string // Intentionally doesn't pre-allocate to reduce logic to default to nil.
 	// Same validation as syscall.Setenv for Linux
 	for i := 0; i < len(c.environ); i += 2 {
@@ -664,16 +1428,37 @@ func (c *moduleConfig) toSysContext() (sysCtx *internalsys.Context, err error) {
 		return nil, err
 	}
 
+	// Preopens returns a new map, so sockets aren't shared with other modules using the same file systems.
+	for fd, conn := range c.sockets {
+		if conn == nil {
+			return nil, fmt.Errorf("socket %d is nil", fd)
+		} else if fd < 3 {
+			return nil, fmt.Errorf("socket %d conflicts with stdio", fd)
+		} else if _, ok := preopens[fd]; ok {
+			return nil, fmt.Errorf("socket %d conflicts with a file system", fd)
+		}
+		preopens[fd] = &internalsys.FileEntry{File: &internalsys.Socket{Conn: conn}}
+	}
+
+	stdout := c.stdout
+	if stdout != nil && c.stdoutBufferSize > 0 {
+		// Each module gets its own buffer, so that one module can't flush another's output.
+		stdout = internalsys.NewBufferedWriter(stdout, c.stdoutBufferSize)
+	}
+
 	return internalsys.NewContext(
 		math.MaxUint32,
 		c.args,
 		environ,
 		c.stdin,
-		c.stdout,
+		stdout,
 		c.stderr,
+		c.stdioWriteBoundaries,
+		c.closeStdio,
 		c.randSource,
 		c.walltimeTime, c.walltimeResolution,
 		c.nanotimeTime, c.nanotimeResolution,
+		c.nanosleep,
 		preopens,
 	)
 }
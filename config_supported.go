@@ -1,7 +1,7 @@
 // Note: The build constraints here are about the compiler, which is more
 // narrow than the architectures supported by the assembler.
 //
-// Constraints here must match platform.IsSupported.
+// Constraints here must match platform.CompilerSupported.
 //
 // Meanwhile, users who know their runtime.GOOS can operate with the compiler
 // may choose to use NewRuntimeConfigCompiler explicitly.
@@ -1,14 +1,19 @@
 package wazero
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"io"
 	"math"
+	"net"
+	"os"
 	"reflect"
 	"testing"
 	"testing/fstest"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/engine/interpreter"
 	internalsys "github.com/tetratelabs/wazero/internal/sys"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
@@ -16,6 +21,8 @@ import (
 )
 
 func TestRuntimeConfig(t *testing.T) {
+	metrics := NewInterpreterMetrics()
+	stepper := NewInterpreterStepper()
 	tests := []struct {
 		name     string
 		with     func(RuntimeConfig) RuntimeConfig
@@ -102,6 +109,60 @@ func TestRuntimeConfig(t *testing.T) {
 				enabledFeatures: wasm.FeatureSIMD,
 			},
 		},
+		{
+			name: "threads",
+			with: func(c RuntimeConfig) RuntimeConfig {
+				return c.WithFeatureThreads(true)
+			},
+			expected: &runtimeConfig{
+				enabledFeatures: wasm.FeatureThreads,
+			},
+		},
+		{
+			name: "interpreter-metrics",
+			with: func(c RuntimeConfig) RuntimeConfig {
+				return c.WithInterpreterMetrics(metrics)
+			},
+			expected: &runtimeConfig{
+				interpreterMetrics: metrics.(*interpreter.Metrics),
+			},
+		},
+		{
+			name: "interpreter-stepper",
+			with: func(c RuntimeConfig) RuntimeConfig {
+				return c.WithStepper(stepper)
+			},
+			expected: &runtimeConfig{
+				interpreterStepper: stepper.(*interpreter.Stepper),
+			},
+		},
+		{
+			name: "guest-profiler",
+			with: func(c RuntimeConfig) RuntimeConfig {
+				return c.WithGuestProfiler(io.Discard)
+			},
+			expected: &runtimeConfig{
+				guestProfile: io.Discard,
+			},
+		},
+		{
+			name: "stub-missing-imports",
+			with: func(c RuntimeConfig) RuntimeConfig {
+				return c.WithStubMissingImports(true)
+			},
+			expected: &runtimeConfig{
+				stubMissingImports: true,
+			},
+		},
+		{
+			name: "deterministic-nan",
+			with: func(c RuntimeConfig) RuntimeConfig {
+				return c.WithDeterministicNaN(true)
+			},
+			expected: &runtimeConfig{
+				deterministicNaN: true,
+			},
+		},
 	}
 	for _, tt := range tests {
 		tc := tt
@@ -197,6 +258,10 @@ func TestCompileConfig(t *testing.T) {
 	mp := func(minPages uint32, maxPages *uint32) (min, capacity, max uint32) {
 		return 0, 1, 1
 	}
+	csh := func(name string, data []byte) error {
+		return nil
+	}
+	core2, none := FeaturesWasmCore2, Features(0)
 	tests := []struct {
 		name     string
 		with     func(CompileConfig) CompileConfig
@@ -230,6 +295,62 @@ func TestCompileConfig(t *testing.T) {
 			},
 			expected: &compileConfig{memorySizer: mp},
 		},
+		{
+			name: "WithFeatures",
+			with: func(c CompileConfig) CompileConfig {
+				return c.WithFeatures(FeaturesWasmCore2)
+			},
+			expected: &compileConfig{enabledFeatures: &core2},
+		},
+		{
+			name: "WithFeatures zero",
+			with: func(c CompileConfig) CompileConfig {
+				return c.WithFeatures(FeaturesWasmCore2).WithFeatures(0)
+			},
+			expected: &compileConfig{enabledFeatures: &none},
+		},
+		{
+			name: "WithOptimizationLevel",
+			with: func(c CompileConfig) CompileConfig {
+				return c.WithOptimizationLevel(OptimizationLevelFull)
+			},
+			expected: &compileConfig{optimizationLevel: OptimizationLevelFull},
+		},
+		{
+			name: "WithOptimizationLevel beyond full",
+			with: func(c CompileConfig) CompileConfig {
+				return c.WithOptimizationLevel(OptimizationLevelFull + 1)
+			},
+			expected: &compileConfig{optimizationLevel: OptimizationLevelFull},
+		},
+		{
+			name: "WithCustomSectionHandler",
+			with: func(c CompileConfig) CompileConfig {
+				return c.WithCustomSectionHandler(csh)
+			},
+			expected: &compileConfig{customSectionHandler: csh},
+		},
+		{
+			name: "WithCustomSectionHandler nil",
+			with: func(c CompileConfig) CompileConfig {
+				return c.WithCustomSectionHandler(csh).WithCustomSectionHandler(nil)
+			},
+			expected: &compileConfig{customSectionHandler: csh},
+		},
+		{
+			name: "WithTrustedMemory",
+			with: func(c CompileConfig) CompileConfig {
+				return c.WithTrustedMemory(true)
+			},
+			expected: &compileConfig{trustedMemory: true},
+		},
+		{
+			name: "WithTrustedMemory false",
+			with: func(c CompileConfig) CompileConfig {
+				return c.WithTrustedMemory(true).WithTrustedMemory(false)
+			},
+			expected: &compileConfig{},
+		},
 	}
 	for _, tt := range tests {
 		tc := tt
@@ -242,6 +363,10 @@ func TestCompileConfig(t *testing.T) {
 			// See https://go.dev/ref/spec#Comparison_operators
 			require.Equal(t, reflect.ValueOf(tc.expected.importRenamer), reflect.ValueOf(rc.importRenamer))
 			require.Equal(t, reflect.ValueOf(tc.expected.memorySizer), reflect.ValueOf(rc.memorySizer))
+			require.Equal(t, reflect.ValueOf(tc.expected.customSectionHandler), reflect.ValueOf(rc.customSectionHandler))
+			require.Equal(t, tc.expected.enabledFeatures, rc.enabledFeatures)
+			require.Equal(t, tc.expected.optimizationLevel, rc.optimizationLevel)
+			require.Equal(t, tc.expected.trustedMemory, rc.trustedMemory)
 			// The source wasn't modified
 			require.Equal(t, &compileConfig{}, input)
 		})
@@ -249,6 +374,9 @@ func TestCompileConfig(t *testing.T) {
 }
 
 func TestModuleConfig(t *testing.T) {
+	testConn := &net.TCPConn{}
+	maxTableElements := uint32(10)
+
 	tests := []struct {
 		name     string
 		with     func(ModuleConfig) ModuleConfig
@@ -279,6 +407,24 @@ func TestModuleConfig(t *testing.T) {
 				name: "wa0",
 			},
 		},
+		{
+			name: "WithMaxTableElements",
+			with: func(c ModuleConfig) ModuleConfig {
+				return c.WithMaxTableElements(maxTableElements)
+			},
+			expected: &moduleConfig{
+				maxTableElements: &maxTableElements,
+			},
+		},
+		{
+			name: "WithSocket twice",
+			with: func(c ModuleConfig) ModuleConfig {
+				return c.WithSocket(3, testConn).WithSocket(4, testConn)
+			},
+			expected: &moduleConfig{
+				sockets: map[uint32]net.Conn{3: testConn, 4: testConn},
+			},
+		},
 	}
 	for _, tt := range tests {
 		tc := tt
@@ -296,6 +442,9 @@ func TestModuleConfig(t *testing.T) {
 func TestModuleConfig_toSysContext(t *testing.T) {
 	testFS := fstest.MapFS{}
 	testFS2 := fstest.MapFS{}
+	conn, peer := net.Pipe()
+	defer conn.Close()
+	defer peer.Close()
 
 	tests := []struct {
 		name     string
@@ -415,17 +564,33 @@ func TestModuleConfig_toSysContext(t *testing.T) {
 			),
 		},
 		{
-			name:  "WithEnv overwrites",
+			name:  "WithEnv duplicate keys",
 			input: NewModuleConfig().WithEnv("a", "bc").WithEnv("c", "de").WithEnv("a", "de"),
 			expected: requireSysContext(t,
-				math.MaxUint32,           // max
-				nil,                      // args
-				[]string{"a=de", "c=de"}, // environ
-				nil,                      // stdin
-				nil,                      // stdout
-				nil,                      // stderr
-				nil,                      // randSource
-				nil, 0,                   // walltime, walltimeResolution
+				math.MaxUint32,                   // max
+				nil,                              // args
+				[]string{"a=bc", "c=de", "a=de"}, // environ
+				nil,                              // stdin
+				nil,                              // stdout
+				nil,                              // stderr
+				nil,                              // randSource
+				nil, 0,                           // walltime, walltimeResolution
+				nil, 0, // nanotime, nanotimeResolution
+				nil, // openedFiles
+			),
+		},
+		{
+			name:  "WithEnv preserves order and case",
+			input: NewModuleConfig().WithEnv("b", "1").WithEnv("A", "2").WithEnv("a", "3"),
+			expected: requireSysContext(t,
+				math.MaxUint32,                // max
+				nil,                           // args
+				[]string{"b=1", "A=2", "a=3"}, // environ
+				nil,                           // stdin
+				nil,                           // stdout
+				nil,                           // stderr
+				nil,                           // randSource
+				nil, 0,                        // walltime, walltimeResolution
 				nil, 0, // nanotime, nanotimeResolution
 				nil, // openedFiles
 			),
@@ -540,6 +705,26 @@ func TestModuleConfig_toSysContext(t *testing.T) {
 				},
 			),
 		},
+		{
+			name:  "WithFS and WithSocket",
+			input: NewModuleConfig().WithFS(testFS).WithSocket(5, conn),
+			expected: requireSysContext(t,
+				math.MaxUint32, // max
+				nil,            // args
+				nil,            // environ
+				nil,            // stdin
+				nil,            // stdout
+				nil,            // stderr
+				nil,            // randSource
+				nil, 0,         // walltime, walltimeResolution
+				nil, 0, // nanotime, nanotimeResolution
+				map[uint32]*internalsys.FileEntry{ // openedFiles
+					3: {Path: "/", FS: testFS},
+					4: {Path: ".", FS: testFS},
+					5: {File: &internalsys.Socket{Conn: conn}},
+				},
+			),
+		},
 	}
 
 	for _, tt := range tests {
@@ -697,6 +882,130 @@ func TestModuleConfig_toSysContext_WithNanotime(t *testing.T) {
 	})
 }
 
+// TestModuleConfig_toSysContext_WithNanosleep has to test differently because we can't
+// compare function pointers when functions are passed by value.
+func TestModuleConfig_toSysContext_WithNanosleep(t *testing.T) {
+	var slept int64
+	sysCtx, err := NewModuleConfig().
+		WithNanosleep(func(ctx context.Context, ns int64) {
+			require.Equal(t, testCtx, ctx)
+			slept += ns
+		}).(*moduleConfig).toSysContext()
+	require.NoError(t, err)
+
+	sysCtx.Nanosleep(testCtx, 2)
+	sysCtx.Nanosleep(testCtx, 3)
+	require.Equal(t, int64(5), slept)
+}
+
+// TestModuleConfig_toSysContext_WithEnv_Base ensures configs derived from the same base don't share entries.
+func TestModuleConfig_toSysContext_WithEnv_Base(t *testing.T) {
+	base := NewModuleConfig().WithEnv("a", "b")
+	c1, c2 := base.WithEnv("c", "d"), base.WithEnv("e", "f")
+
+	sysCtx, err := c1.(*moduleConfig).toSysContext()
+	require.NoError(t, err)
+	require.Equal(t, []string{"a=b", "c=d"}, sysCtx.Environ())
+
+	sysCtx, err = c2.(*moduleConfig).toSysContext()
+	require.NoError(t, err)
+	require.Equal(t, []string{"a=b", "e=f"}, sysCtx.Environ())
+
+	sysCtx, err = base.(*moduleConfig).toSysContext()
+	require.NoError(t, err)
+	require.Equal(t, []string{"a=b"}, sysCtx.Environ())
+}
+
+func TestModuleConfig_toSysContext_WithStdioWriteBoundaries(t *testing.T) {
+	sysCtx, err := NewModuleConfig().(*moduleConfig).toSysContext()
+	require.NoError(t, err)
+	require.False(t, sysCtx.StdioWriteBoundaries())
+
+	base := NewModuleConfig().WithStdout(io.Discard)
+	sysCtx, err = base.WithStdioWriteBoundaries().(*moduleConfig).toSysContext()
+	require.NoError(t, err)
+	require.True(t, sysCtx.StdioWriteBoundaries())
+
+	// Ensure the base config wasn't mutated.
+	require.False(t, base.(*moduleConfig).stdioWriteBoundaries)
+}
+
+func TestModuleConfig_toSysContext_WithCloseStdio(t *testing.T) {
+	out := &closeTrackingWriter{}
+	base := NewModuleConfig().WithStdout(out).WithStderr(out)
+
+	// By default, the caller owns the writers, as the config can instantiate many modules.
+	sysCtx, err := base.(*moduleConfig).toSysContext()
+	require.NoError(t, err)
+	require.NoError(t, sysCtx.Close(testCtx))
+	require.Zero(t, out.closed)
+
+	sysCtx, err = base.WithCloseStdio().(*moduleConfig).toSysContext()
+	require.NoError(t, err)
+	require.NoError(t, sysCtx.Close(testCtx))
+	require.Equal(t, 1, out.closed)
+
+	// Ensure the base config wasn't mutated.
+	require.False(t, base.(*moduleConfig).closeStdio)
+}
+
+func TestModuleConfig_toSysContext_WithBufferedStdout(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	base := NewModuleConfig().WithStdout(stdout)
+	sysCtx, err := base.WithBufferedStdout(4).(*moduleConfig).toSysContext()
+	require.NoError(t, err)
+
+	// Writes are held until the buffer is full.
+	_, err = sysCtx.Stdout().Write([]byte("wa"))
+	require.NoError(t, err)
+	require.Zero(t, stdout.Len())
+	_, err = sysCtx.Stdout().Write([]byte("zero"))
+	require.NoError(t, err)
+	require.Equal(t, "waze", stdout.String())
+
+	// Closing flushes the remainder.
+	require.NoError(t, sysCtx.Close(testCtx))
+	require.Equal(t, "wazero", stdout.String())
+
+	// Ensure the base config wasn't mutated.
+	require.Zero(t, base.(*moduleConfig).stdoutBufferSize)
+
+	// Zero or less disables buffering.
+	sysCtx, err = base.WithBufferedStdout(-1).(*moduleConfig).toSysContext()
+	require.NoError(t, err)
+	require.Equal(t, stdout, sysCtx.Stdout())
+}
+
+func TestModuleConfig_toSysContext_WithOsWorkdir(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	config := NewModuleConfig().WithOsWorkdir()
+
+	// Changing the directory after the config is built doesn't change the pre-opened directory.
+	require.NoError(t, os.Chdir(t.TempDir()))
+	defer os.Chdir(wd) //nolint
+
+	sysCtx, err := config.(*moduleConfig).toSysContext()
+	require.NoError(t, err)
+	root, ok := sysCtx.FS().OpenedFile(3)
+	require.True(t, ok)
+	require.Equal(t, internalsys.NewDirFS(wd), root.FS)
+
+	t.Run("getwd error", func(t *testing.T) {
+		defer func(f func() (string, error)) { getwd = f }(getwd)
+		getwd = func() (string, error) { return "", errors.New("deleted") }
+
+		config := NewModuleConfig().WithOsWorkdir()
+		_, err := config.(*moduleConfig).toSysContext()
+		require.EqualError(t, err, "failed to resolve the current directory: deleted")
+
+		// WithFS overwrites the file system, so the error no longer applies.
+		_, err = config.WithFS(fstest.MapFS{}).(*moduleConfig).toSysContext()
+		require.NoError(t, err)
+	})
+}
+
 func TestModuleConfig_toSysContext_Errors(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -708,6 +1017,11 @@ func TestModuleConfig_toSysContext_Errors(t *testing.T) {
 			input:       NewModuleConfig().WithArgs("", string([]byte{'a', 0})),
 			expectedErr: "args invalid: contains NUL character",
 		},
+		{
+			name:        "WithArgs multibyte arg contains NUL",
+			input:       NewModuleConfig().WithArgs("日本", string([]byte{0xe6, 0x97, 0xa5, 0})),
+			expectedErr: "args invalid: contains NUL character",
+		},
 		{
 			name:        "WithEnv key contains NUL",
 			input:       NewModuleConfig().WithEnv(string([]byte{'a', 0}), "a"),
@@ -738,6 +1052,21 @@ func TestModuleConfig_toSysContext_Errors(t *testing.T) {
 			input:       NewModuleConfig().WithWorkDirFS(nil),
 			expectedErr: "FS for . is nil",
 		},
+		{
+			name:        "WithSocket nil",
+			input:       NewModuleConfig().WithSocket(3, nil),
+			expectedErr: "socket 3 is nil",
+		},
+		{
+			name:        "WithSocket stdio",
+			input:       NewModuleConfig().WithSocket(1, &net.TCPConn{}),
+			expectedErr: "socket 1 conflicts with stdio",
+		},
+		{
+			name:        "WithSocket conflicts with WithFS",
+			input:       NewModuleConfig().WithFS(fstest.MapFS{}).WithSocket(4, &net.TCPConn{}),
+			expectedErr: "socket 4 conflicts with a file system",
+		},
 	}
 	for _, tt := range tests {
 		tc := tt
@@ -768,9 +1097,12 @@ func requireSysContext(
 		stdin,
 		stdout,
 		stderr,
+		false,
+		false,
 		randSource,
 		walltime, walltimeResolution,
 		nanotime, nanotimeResolution,
+		nil,
 		openedFiles,
 	)
 	require.NoError(t, err)
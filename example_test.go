@@ -5,6 +5,8 @@ import (
 	_ "embed"
 	"fmt"
 	"log"
+
+	"github.com/tetratelabs/wazero/api"
 )
 
 // addWasm was generated by the following:
@@ -44,3 +46,30 @@ func Example() {
 	// Output:
 	// wasm/math: 1 + 2 = 3
 }
+
+// This is an example of how to unit test a host function, by calling it directly with a module that has memory.
+func ExampleNewFakeModule() {
+	ctx := context.Background()
+
+	// printString is the host function under test, which prints a string the guest wrote to memory.
+	printString := func(ctx context.Context, m api.Module, offset, byteCount uint32) {
+		buf, ok := m.Memory().Read(ctx, offset, byteCount)
+		if !ok {
+			log.Panicf("Memory.Read(%d, %d) out of range", offset, byteCount)
+		}
+		fmt.Println(string(buf))
+	}
+
+	// Create a module with one page of memory, then write the string as the guest would.
+	mod, err := NewFakeModule(NewModuleConfig(), 1)
+	if err != nil {
+		log.Panicln(err)
+	}
+	defer mod.Close(ctx)
+	mod.Memory().Write(ctx, 8, []byte("hello"))
+
+	printString(ctx, mod, 8, 5)
+
+	// Output:
+	// hello
+}
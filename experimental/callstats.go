@@ -0,0 +1,45 @@
+package experimental
+
+import (
+	"context"
+	"time"
+)
+
+// CallStatsKey is a context.Context Value key. Its associated value should be a *CallStats.
+//
+// See WithCallStats
+type CallStatsKey struct{}
+
+// CallStats accumulates the elapsed time of api.Function calls made with a context from WithCallStats.
+//
+// Notes
+//
+//	* Times are measured with the monotonic clock, so they include any time the goroutine was descheduled.
+//	* Nested calls, such as a host function calling back into the guest, are counted once, as part of the outermost
+//	  call. Likewise, guest time within a host function counts as Host.
+//	* This is not goroutine-safe. Use one per goroutine, or read it after calls complete.
+type CallStats struct {
+	// Total is the elapsed time of all calls, including host functions they invoked.
+	Total time.Duration
+
+	// Host is the portion of Total spent in host functions, ex. "fd_write" in "wasi_snapshot_preview1".
+	Host time.Duration
+}
+
+// Guest is the portion of Total spent executing WebAssembly.
+func (s *CallStats) Guest() time.Duration {
+	return s.Total - s.Host
+}
+
+// WithCallStats returns a context that accumulates the elapsed time of api.Function calls made with it into the
+// returned CallStats.
+//
+// Ex. To bill the time a guest function took:
+//
+//	ctx, stats := experimental.WithCallStats(ctx)
+//	_, err := fn.Call(ctx)
+//	fmt.Println(stats.Guest(), stats.Host)
+func WithCallStats(ctx context.Context) (context.Context, *CallStats) {
+	stats := &CallStats{}
+	return context.WithValue(ctx, CallStatsKey{}, stats), stats
+}
@@ -0,0 +1,101 @@
+package experimental_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	binaryformat "github.com/tetratelabs/wazero/internal/wasm/binary"
+)
+
+// spinWasm exports "spin", which busy-loops until "env.nanotime" reaches its parameter.
+var spinWasm = binaryformat.EncodeModule(&wasm.Module{
+	TypeSection: []*wasm.FunctionType{
+		{Results: []wasm.ValueType{wasm.ValueTypeI64}},
+		{Params: []wasm.ValueType{wasm.ValueTypeI64}},
+	},
+	ImportSection:   []*wasm.Import{{Module: "env", Name: "nanotime", Type: wasm.ExternTypeFunc, DescFunc: 0}},
+	FunctionSection: []wasm.Index{1},
+	CodeSection: []*wasm.Code{{Body: []byte{
+		wasm.OpcodeLoop, 0x40, // empty block type
+		wasm.OpcodeCall, 0,
+		wasm.OpcodeLocalGet, 0,
+		wasm.OpcodeI64LtU,
+		wasm.OpcodeBrIf, 0,
+		wasm.OpcodeEnd,
+		wasm.OpcodeEnd,
+	}}},
+	ExportSection: []*wasm.Export{{Name: "spin", Type: wasm.ExternTypeFunc, Index: 1}},
+})
+
+// instantiateSpin instantiates spinWasm, importing the given "nanotime" implementation.
+func instantiateSpin(t *testing.T, r wazero.Runtime, nanotime func(context.Context) int64) api.Module {
+	_, err := r.NewModuleBuilder("env").ExportFunction("nanotime", nanotime).Instantiate(context.Background(), r)
+	require.NoError(t, err)
+
+	mod, err := r.InstantiateModuleFromBinary(context.Background(), spinWasm)
+	require.NoError(t, err)
+	return mod
+}
+
+func TestWithCallStats(t *testing.T) {
+	r := wazero.NewRuntime()
+	defer r.Close(context.Background())
+
+	epoch := time.Now()
+	nanotime := func(context.Context) int64 { return time.Since(epoch).Nanoseconds() }
+	spin := instantiateSpin(t, r, nanotime).ExportedFunction("spin")
+
+	ctx, stats := experimental.WithCallStats(context.Background())
+	// The deadline is read before the call begins, so spin for longer than the duration asserted.
+	duration := time.Millisecond
+	deadline := func() uint64 { return uint64(nanotime(ctx) + 2*duration.Nanoseconds()) }
+	_, err := spin.Call(ctx, deadline())
+	require.NoError(t, err)
+
+	// The guest loops calling the host, so time is spent in both.
+	require.True(t, stats.Total >= duration, "expected Total >= %s, but was %s", duration, stats.Total)
+	require.True(t, stats.Host > 0, "expected Host > 0, but was %s", stats.Host)
+	require.True(t, stats.Guest() > 0, "expected Guest > 0, but was %s", stats.Guest())
+
+	// Another call accumulates.
+	total := stats.Total
+	_, err = spin.Call(ctx, deadline())
+	require.NoError(t, err)
+	require.True(t, stats.Total >= total+duration, "expected Total >= %s, but was %s", total+duration, stats.Total)
+}
+
+func TestWithCallStats_Nested(t *testing.T) {
+	r := wazero.NewRuntime()
+	defer r.Close(context.Background())
+
+	// The first time "env.nanotime" is called, it calls back into the guest, which returns after one host call.
+	var spin api.Function
+	epoch := time.Now()
+	var nested bool
+	nanotime := func(ctx context.Context) int64 {
+		if !nested {
+			nested = true
+			_, err := spin.Call(ctx, 0)
+			require.NoError(t, err)
+		}
+		return time.Since(epoch).Nanoseconds()
+	}
+	spin = instantiateSpin(t, r, nanotime).ExportedFunction("spin")
+
+	ctx, stats := experimental.WithCallStats(context.Background())
+	start := time.Now()
+	_, err := spin.Call(ctx, uint64(time.Since(epoch)+time.Millisecond))
+	elapsed := time.Since(start)
+	require.NoError(t, err)
+	require.True(t, nested)
+
+	// Had the nested call been counted again, Total could exceed the elapsed time of the outer call.
+	require.True(t, stats.Total <= elapsed, "expected Total <= %s, but was %s", elapsed, stats.Total)
+	require.True(t, stats.Host <= stats.Total, "expected Host <= %s, but was %s", stats.Total, stats.Host)
+}
@@ -0,0 +1,37 @@
+package wazero
+
+import (
+	"fmt"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// NewFakeModule returns a module with the system state of the config, such as stdout, and a memory of memoryPages
+// exported as "memory". It defines no functions.
+//
+// This is for testing only! It allows calling a host function directly, with the module parameter it expects,
+// without instantiating WebAssembly. Ex. to test a host function that reads a string from memory:
+//
+//	mod, _ := wazero.NewFakeModule(wazero.NewModuleConfig().WithStdout(stdout), 1)
+//	mod.Memory().Write(ctx, 0, []byte("hello"))
+//	log(ctx, mod, 0, 5)
+//
+// Note: The module isn't in any Runtime or Namespace, so it can't be imported and isn't closed with them.
+func NewFakeModule(config ModuleConfig, memoryPages uint32) (api.Module, error) {
+	mConfig, ok := config.(*moduleConfig)
+	if !ok {
+		return nil, fmt.Errorf("unsupported wazero.ModuleConfig implementation: %#v", config)
+	}
+	if memoryPages > wasm.MemoryLimitPages {
+		return nil, fmt.Errorf("memoryPages %d over limit of %d", memoryPages, wasm.MemoryLimitPages)
+	}
+
+	sysCtx, err := mConfig.toSysContext()
+	if err != nil {
+		return nil, err
+	}
+
+	memory := wasm.NewMemoryInstance(&wasm.Memory{Min: memoryPages, Cap: memoryPages, Max: wasm.MemoryLimitPages})
+	return wasm.NewFakeCallContext(mConfig.name, memory, sysCtx), nil
+}
@@ -0,0 +1,39 @@
+package wazero
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+func TestNewFakeModule(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	mod, err := NewFakeModule(NewModuleConfig().WithName("fake").WithStdout(stdout).WithArgs("a", "b"), 2)
+	require.NoError(t, err)
+
+	require.Equal(t, "fake", mod.Name())
+	require.Equal(t, uint32(2*wasm.MemoryPageSize), mod.Memory().Size(testCtx))
+	require.Equal(t, mod.Memory(), mod.ExportedMemory("memory"))
+	require.Nil(t, mod.ExportedFunction("memory"))
+
+	// The system state is from the config, as used by host functions such as WASI.
+	sysCtx := mod.(*wasm.CallContext).Sys
+	require.Equal(t, stdout, sysCtx.Stdout())
+	require.Equal(t, []string{"a", "b"}, sysCtx.Args())
+
+	// The memory can grow, as it would in a module that doesn't define a maximum.
+	_, ok := mod.Memory().Grow(testCtx, 1)
+	require.True(t, ok)
+
+	require.NoError(t, mod.Close(testCtx))
+}
+
+func TestNewFakeModule_Errors(t *testing.T) {
+	_, err := NewFakeModule(NewModuleConfig(), wasm.MemoryLimitPages+1)
+	require.EqualError(t, err, "memoryPages 65537 over limit of 65536")
+
+	_, err = NewFakeModule(NewModuleConfig().WithEnv("", "value"), 0)
+	require.EqualError(t, err, "environ invalid: empty key")
+}
@@ -0,0 +1,39 @@
+package wazero
+
+import "github.com/tetratelabs/wazero/internal/wasm"
+
+// Features is a bit set of WebAssembly feature proposals, such as FeatureSIMD. Use it with
+// CompileConfig.WithFeatures to choose which proposals a module may use.
+//
+// Features is a value, so Set returns a copy with the feature changed. Ex. To allow everything in WebAssembly 1.0
+// plus SIMD:
+//
+//	features := wazero.FeaturesWasmCore1.Set(wazero.FeatureSIMD, true)
+//
+// Note: The bits are an implementation detail. Only combine them with Set or bitwise operators, such as
+// `FeatureMultiValue | FeatureSignExtensionOps`, as their values may change between releases.
+type Features = wasm.Features
+
+const (
+	// FeaturesWasmCore1 are the features finished in WebAssembly 1.0 (20191205), which RuntimeConfig defaults to.
+	//
+	// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205
+	FeaturesWasmCore1 = wasm.Features20191205
+
+	// FeaturesWasmCore2 are the features finished in WebAssembly 2.0 (20220419), as RuntimeConfig.WithWasmCore2.
+	//
+	// See https://www.w3.org/TR/2022/WD-wasm-core-2-20220419/
+	FeaturesWasmCore2 = wasm.Features20220419
+)
+
+// These are the features that can be set individually. See RuntimeConfig WithFeatureXXX methods for their effects.
+const (
+	FeatureBulkMemoryOperations            = wasm.FeatureBulkMemoryOperations
+	FeatureMultiValue                      = wasm.FeatureMultiValue
+	FeatureMutableGlobal                   = wasm.FeatureMutableGlobal
+	FeatureNonTrappingFloatToIntConversion = wasm.FeatureNonTrappingFloatToIntConversion
+	FeatureReferenceTypes                  = wasm.FeatureReferenceTypes
+	FeatureSignExtensionOps                = wasm.FeatureSignExtensionOps
+	FeatureSIMD                            = wasm.FeatureSIMD
+	FeatureThreads                         = wasm.FeatureThreads
+)
@@ -222,6 +222,9 @@ type AssemblerImpl struct {
 	// MaxDisplacementForConstantPool is fixed to defaultMaxDisplacementForConstantPool
 	// but have it as a field here for testability.
 	MaxDisplacementForConstantPool int
+	// EnableShortForwardJumps starts encoding forward jumps with an 8-bit displacement, re-assembling when one doesn't
+	// fit. When false, they are encoded with a 32-bit displacement, which is larger but never needs re-assembly.
+	EnableShortForwardJumps bool
 
 	pool constPool
 }
@@ -230,8 +233,8 @@ type AssemblerImpl struct {
 var _ Assembler = &AssemblerImpl{}
 
 func NewAssemblerImpl() *AssemblerImpl {
-	return &AssemblerImpl{Buf: bytes.NewBuffer(nil), EnablePadding: true, pool: newConstPool(),
-		MaxDisplacementForConstantPool: defaultMaxDisplacementForConstantPool}
+	return &AssemblerImpl{Buf: bytes.NewBuffer(nil), EnablePadding: true, EnableShortForwardJumps: true,
+		pool: newConstPool(), MaxDisplacementForConstantPool: defaultMaxDisplacementForConstantPool}
 }
 
 // newNode creates a new Node and appends it into the linked list.
@@ -342,7 +345,7 @@ func (a *AssemblerImpl) InitializeNodesForEncoding() {
 			if target.isInitializedForEncoding() {
 				// This means the target exists behind.
 				n.Flag |= NodeFlagBackwardJump
-			} else {
+			} else if a.EnableShortForwardJumps {
 				// Otherwise, this is forward jump.
 				// We start with assuming that the jump can be short (8-bit displacement).
 				// If it doens't fit, we change this Flag in resolveRelativeForwardJump.
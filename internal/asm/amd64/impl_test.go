@@ -180,6 +180,42 @@ func TestAssemblerImpl_padNOP(t *testing.T) {
 	}
 }
 
+func TestAssemblerImpl_Assemble_EnableShortForwardJumps(t *testing.T) {
+	tests := []struct {
+		name                    string
+		enableShortForwardJumps bool
+		expected                []byte
+	}{
+		{
+			name:                    "short",
+			enableShortForwardJumps: true,
+			expected:                []byte{0xeb, 0x01, 0xc3, 0xc3}, // JMP rel8 over the first RET.
+		},
+		{
+			name:     "long",
+			expected: []byte{0xe9, 0x01, 0x00, 0x00, 0x00, 0xc3, 0xc3}, // JMP rel32 over the first RET.
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			a := NewAssemblerImpl()
+			a.EnablePadding = false
+			a.EnableShortForwardJumps = tc.enableShortForwardJumps
+
+			jmp := a.CompileJump(JMP)
+			a.CompileStandAlone(RET)
+			a.CompileStandAlone(RET)
+			jmp.AssignJumpTarget(a.Current)
+
+			actual, err := a.Assemble()
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
 func TestAssemblerImpl_CompileStandAlone(t *testing.T) {
 	a := NewAssemblerImpl()
 	a.CompileStandAlone(RET)
@@ -12,6 +12,10 @@ type compiler interface {
 	// compilePreamble is called before compiling any wazeroir operation.
 	// This is used, for example, to initialize the reserved registers, etc.
 	compilePreamble() error
+	// compileCheckInjectedTrap adds instructions to call the builtin function which panics with any trap injected into
+	// the function by wasm.CallContext.InjectTrap. This is only called after compilePreamble, when built with the tag
+	// "inject_trap". See wasm.InjectTrapEnabled
+	compileCheckInjectedTrap() error
 	// compile generates the byte slice of native code.
 	// stackPointerCeil is the max stack pointer that the target function would reach.
 	// staticData is codeStaticData for the resulting native code.
@@ -19,6 +23,14 @@ type compiler interface {
 	// compileHostFunction emits the trampoline code from which native code can jump into the host function.
 	// TODO: maybe we wouldn't need to have trampoline for host functions.
 	compileHostFunction() error
+	// disableOptimizations skips optional passes which make the native code faster, in exchange for compile time.
+	// This must be called before compilePreamble.
+	// See wasm.Module FastCompile
+	disableOptimizations()
+	// disableMemoryBoundsChecks omits the checks that the accessed range is within the memory before loads and stores.
+	// This must be called before compilePreamble.
+	// See wasm.Module TrustedMemory
+	disableMemoryBoundsChecks()
 	// compileLabel notify compilers of the beginning of a label.
 	// Return true if the compiler decided to skip the entire label.
 	// See wazeroir.OperationLabel
@@ -64,9 +76,10 @@ type compiler interface {
 	//
 	// Note: This is called indirect function call in the sense that the target function is indirectly
 	// determined by the current state (top value) of the stack.
-	// Therefore, two checks are performed at runtime before entering the target function:
+	// Therefore, three checks are performed at runtime before entering the target function:
 	// 1) If "offset" exceeds the length of table, the function exits with nativeCallStatusCodeInvalidTableAccess.
-	// 2) If the type of the function table[offset] doesn't match the specified function type, the function exits with nativeCallStatusCodeTypeMismatchOnIndirectCall.
+	// 2) If table[offset] is null, the function exits with nativeCallStatusCodeIndirectCallToNull.
+	// 3) If the type of the function table[offset] doesn't match the specified function type, the function exits with nativeCallStatusCodeTypeMismatchOnIndirectCall.
 	// Otherwise, we successfully enter the target function.
 	//
 	// See wasm.CallIndirect
@@ -594,7 +594,7 @@ func TestCompiler_compileCallIndirect(t *testing.T) {
 		require.NoError(t, err)
 		env.exec(code)
 
-		require.Equal(t, nativeCallStatusCodeInvalidTableAccess, env.compilerStatus())
+		require.Equal(t, nativeCallStatusCodeIndirectCallToNull, env.compilerStatus())
 	})
 
 	t.Run("type not match", func(t *testing.T) {
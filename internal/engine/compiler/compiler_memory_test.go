@@ -419,6 +419,51 @@ func TestCompiler_compileStore(t *testing.T) {
 	}
 }
 
+func TestCompiler_disableMemoryBoundsChecks(t *testing.T) {
+	storeTargetValue := uint64(0x12_34_56_78_9a_bc_ef_fe)
+	baseOffset := uint32(100)
+
+	// compileStoreThenLoad stores storeTargetValue at baseOffset+offsetArg, then loads it back onto the stack.
+	compileStoreThenLoad := func(t *testing.T, env *compilerEnv, trusted bool, offsetArg uint32) []byte {
+		compiler := env.requireNewCompiler(t, newCompiler, &wazeroir.CompilationResult{HasMemory: true, Signature: &wasm.FunctionType{}})
+		if trusted {
+			compiler.disableMemoryBoundsChecks()
+		}
+		require.NoError(t, compiler.compilePreamble())
+
+		arg := &wazeroir.MemoryArg{Offset: offsetArg}
+		require.NoError(t, compiler.compileConstI32(&wazeroir.OperationConstI32{Value: baseOffset}))
+		require.NoError(t, compiler.compileConstI64(&wazeroir.OperationConstI64{Value: storeTargetValue}))
+		require.NoError(t, compiler.compileStore(&wazeroir.OperationStore{Arg: arg, Type: wazeroir.UnsignedTypeI64}))
+		require.NoError(t, compiler.compileConstI32(&wazeroir.OperationConstI32{Value: baseOffset}))
+		require.NoError(t, compiler.compileLoad32(&wazeroir.OperationLoad32{Arg: arg}))
+		require.NoError(t, compiler.compileReturnFunction())
+
+		code, _, _, err := compiler.compile()
+		require.NoError(t, err)
+		return code
+	}
+
+	for _, offsetArg := range []uint32{0, 361} {
+		offsetArg := offsetArg
+		t.Run(fmt.Sprintf("offset=%d", offsetArg), func(t *testing.T) {
+			env := newCompilerEnvironment()
+			checked := compileStoreThenLoad(t, env, false, offsetArg)
+
+			env = newCompilerEnvironment()
+			trusted := compileStoreThenLoad(t, env, true, offsetArg)
+			require.True(t, len(trusted) < len(checked), "expected bounds checks to be omitted")
+
+			// In-bounds accesses have the same results as when checked.
+			env.exec(trusted)
+			require.Equal(t, nativeCallStatusCodeReturned, env.compilerStatus())
+			require.Equal(t, uint64(1), env.stackPointer())
+			require.Equal(t, uint64(uint32(storeTargetValue)), env.stackTopAsUint64())
+			require.Equal(t, storeTargetValue, binary.LittleEndian.Uint64(env.memory()[baseOffset+offsetArg:]))
+		})
+	}
+}
+
 func TestCompiler_MemoryOutOfBounds(t *testing.T) {
 	bases := []uint32{0, 1 << 5, 1 << 9, 1 << 10, 1 << 15, math.MaxUint32 - 1, math.MaxUint32}
 	offsets := []uint32{0,
@@ -839,12 +839,8 @@ func TestCompiler_compile_Min_Max_Copysign(t *testing.T) {
 			},
 			verifyFunc: func(t *testing.T, x1, x2 float64, raw uint64) {
 				exp := float32(moremath.WasmCompatMin(float64(float32(x1)), float64(float32(x2))))
-				actual := math.Float32frombits(uint32(raw))
-				if math.IsNaN(float64(exp)) { // NaN cannot be compared with themselves, so we have to use IsNaN
-					require.True(t, math.IsNaN(float64(actual)))
-				} else {
-					require.Equal(t, exp, actual)
-				}
+				// Compare the bits, as -0 == +0. NaN operands are always canonical after the conversion to float32.
+				require.Equal(t, math.Float32bits(exp), uint32(raw))
 			},
 		},
 		{
@@ -855,13 +851,7 @@ func TestCompiler_compile_Min_Max_Copysign(t *testing.T) {
 				require.NoError(t, err)
 			},
 			verifyFunc: func(t *testing.T, x1, x2 float64, raw uint64) {
-				exp := moremath.WasmCompatMin(x1, x2)
-				actual := math.Float64frombits(raw)
-				if math.IsNaN(exp) { // NaN cannot be compared with themselves, so we have to use IsNaN
-					require.True(t, math.IsNaN(actual))
-				} else {
-					require.Equal(t, exp, actual)
-				}
+				requireMinOrMaxBits(t, moremath.WasmCompatMin(x1, x2), x1, x2, raw)
 			},
 		},
 		{
@@ -873,12 +863,8 @@ func TestCompiler_compile_Min_Max_Copysign(t *testing.T) {
 			},
 			verifyFunc: func(t *testing.T, x1, x2 float64, raw uint64) {
 				exp := float32(moremath.WasmCompatMax(float64(float32(x1)), float64(float32(x2))))
-				actual := math.Float32frombits(uint32(raw))
-				if math.IsNaN(float64(exp)) { // NaN cannot be compared with themselves, so we have to use IsNaN
-					require.True(t, math.IsNaN(float64(actual)))
-				} else {
-					require.Equal(t, exp, actual)
-				}
+				// Compare the bits, as -0 == +0. NaN operands are always canonical after the conversion to float32.
+				require.Equal(t, math.Float32bits(exp), uint32(raw))
 			},
 		},
 		{
@@ -889,13 +875,7 @@ func TestCompiler_compile_Min_Max_Copysign(t *testing.T) {
 				require.NoError(t, err)
 			},
 			verifyFunc: func(t *testing.T, x1, x2 float64, raw uint64) {
-				exp := moremath.WasmCompatMax(x1, x2)
-				actual := math.Float64frombits(raw)
-				if math.IsNaN(exp) { // NaN cannot be compared with themselves, so we have to use IsNaN
-					require.True(t, math.IsNaN(actual))
-				} else {
-					require.Equal(t, exp, actual)
-				}
+				requireMinOrMaxBits(t, moremath.WasmCompatMax(x1, x2), x1, x2, raw)
 			},
 		},
 		{
@@ -934,6 +914,12 @@ func TestCompiler_compile_Min_Max_Copysign(t *testing.T) {
 		},
 	}
 
+	// Prevent constant folding by using two variables. -float64(0) is not actually negative.
+	// https://github.com/golang/go/issues/2196
+	zero := float64(0)
+	negZero := -zero
+	canonicalNaN := math.Float64frombits(moremath.F64CanonicalNaNBits)
+
 	for _, tt := range tests {
 		tc := tt
 		t.Run(tc.name, func(t *testing.T) {
@@ -952,6 +938,8 @@ func TestCompiler_compile_Min_Max_Copysign(t *testing.T) {
 				{math.NaN(), 0}, {0, math.NaN()},
 				{math.NaN(), 12321}, {12313, math.NaN()},
 				{math.NaN(), math.NaN()},
+				{negZero, 0}, {0, negZero}, {negZero, negZero},
+				{canonicalNaN, 1}, {1, canonicalNaN}, {canonicalNaN, negZero},
 			} {
 				x1, x2 := vs[0], vs[1]
 				t.Run(fmt.Sprintf("x1=%f_x2=%f", x1, x2), func(t *testing.T) {
@@ -1001,6 +989,20 @@ func TestCompiler_compile_Min_Max_Copysign(t *testing.T) {
 	}
 }
 
+// requireMinOrMaxBits requires the raw result of a 64-bit min or max of x1 and x2 to have the bits of exp. As native
+// instructions propagate a NaN operand, the result is only required to be NaN if an operand is a NaN with a payload,
+// such as math.NaN.
+func requireMinOrMaxBits(t *testing.T, exp, x1, x2 float64, raw uint64) {
+	isPayloadNaN := func(v float64) bool {
+		return math.IsNaN(v) && math.Float64bits(v) != moremath.F64CanonicalNaNBits
+	}
+	if isPayloadNaN(x1) || isPayloadNaN(x2) {
+		require.True(t, math.IsNaN(math.Float64frombits(raw)))
+	} else {
+		require.Equal(t, math.Float64bits(exp), raw)
+	}
+}
+
 func TestCompiler_compile_Abs_Neg_Ceil_Floor_Trunc_Nearest_Sqrt(t *testing.T) {
 	tests := []struct {
 		name       string
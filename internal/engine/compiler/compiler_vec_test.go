@@ -2,6 +2,7 @@ package compiler
 
 import (
 	"encoding/binary"
+	"fmt"
 	"math"
 	"runtime"
 	"testing"
@@ -431,6 +432,85 @@ func TestCompiler_compileV128Load(t *testing.T) {
 	}
 }
 
+// TestCompiler_compileV128Load_bounds ensures partial vector loads only trap when the bytes they read exceed memory,
+// not the size of a whole vector.
+func TestCompiler_compileV128Load_bounds(t *testing.T) {
+	tests := []struct {
+		name            string
+		compile         func(compiler compilerImpl) error
+		targetSizeBytes uint32
+	}{
+		{name: "128", targetSizeBytes: 16, compile: v128LoadFn(wazeroir.LoadV128Type128)},
+		{name: "8x8s", targetSizeBytes: 8, compile: v128LoadFn(wazeroir.LoadV128Type8x8s)},
+		{name: "16x4u", targetSizeBytes: 8, compile: v128LoadFn(wazeroir.LoadV128Type16x4u)},
+		{name: "32x2s", targetSizeBytes: 8, compile: v128LoadFn(wazeroir.LoadV128Type32x2s)},
+		{name: "8splat", targetSizeBytes: 1, compile: v128LoadFn(wazeroir.LoadV128Type8Splat)},
+		{name: "16splat", targetSizeBytes: 2, compile: v128LoadFn(wazeroir.LoadV128Type16Splat)},
+		{name: "32splat", targetSizeBytes: 4, compile: v128LoadFn(wazeroir.LoadV128Type32Splat)},
+		{name: "64splat", targetSizeBytes: 8, compile: v128LoadFn(wazeroir.LoadV128Type64Splat)},
+		{name: "32zero", targetSizeBytes: 4, compile: v128LoadFn(wazeroir.LoadV128Type32zero)},
+		{name: "64zero", targetSizeBytes: 8, compile: v128LoadFn(wazeroir.LoadV128Type64zero)},
+		{name: "8_lane", targetSizeBytes: 1, compile: v128LoadLaneFn(8)},
+		{name: "16_lane", targetSizeBytes: 2, compile: v128LoadLaneFn(16)},
+		{name: "32_lane", targetSizeBytes: 4, compile: v128LoadLaneFn(32)},
+		{name: "64_lane", targetSizeBytes: 8, compile: v128LoadLaneFn(64)},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		for _, outOfBounds := range []bool{false, true} {
+			outOfBounds := outOfBounds
+			t.Run(fmt.Sprintf("%s outOfBounds=%v", tc.name, outOfBounds), func(t *testing.T) {
+				env := newCompilerEnvironment()
+				compiler := env.requireNewCompiler(t, newCompiler,
+					&wazeroir.CompilationResult{HasMemory: true, Signature: &wasm.FunctionType{}})
+
+				err := compiler.compilePreamble()
+				require.NoError(t, err)
+
+				// Read the last bytes of memory, or one past them.
+				offset := uint32(len(env.memory())) - tc.targetSizeBytes
+				if outOfBounds {
+					offset++
+				}
+				err = compiler.compileConstI32(&wazeroir.OperationConstI32{Value: offset})
+				require.NoError(t, err)
+
+				require.NoError(t, tc.compile(compiler))
+				require.NoError(t, compiler.compileReturnFunction())
+
+				// Generate and run the code under test.
+				code, _, _, err := compiler.compile()
+				require.NoError(t, err)
+				env.exec(code)
+
+				if outOfBounds {
+					require.Equal(t, nativeCallStatusCodeMemoryOutOfBounds, env.compilerStatus())
+				} else {
+					require.Equal(t, nativeCallStatusCodeReturned, env.compilerStatus())
+				}
+			})
+		}
+	}
+}
+
+// v128LoadFn returns a function to compile wazeroir.OperationV128Load of the given type.
+func v128LoadFn(loadType wazeroir.LoadV128Type) func(compilerImpl) error {
+	return func(compiler compilerImpl) error {
+		return compiler.compileV128Load(&wazeroir.OperationV128Load{Type: loadType, Arg: &wazeroir.MemoryArg{}})
+	}
+}
+
+// v128LoadLaneFn returns a function to compile wazeroir.OperationV128LoadLane into lane zero of a zero vector.
+func v128LoadLaneFn(laneSize byte) func(compilerImpl) error {
+	return func(compiler compilerImpl) error {
+		if err := compiler.compileV128Const(&wazeroir.OperationV128Const{}); err != nil {
+			return err
+		}
+		return compiler.compileV128LoadLane(&wazeroir.OperationV128LoadLane{LaneSize: laneSize, Arg: &wazeroir.MemoryArg{}})
+	}
+}
+
 func TestCompiler_compileV128LoadLane(t *testing.T) {
 	originalVecLo, originalVecHi := uint64(0), uint64(0)
 	tests := []struct {
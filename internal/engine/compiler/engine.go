@@ -321,13 +321,14 @@ const (
 	nativeCallStatusCodeInvalidFloatToIntConversion
 	// nativeCallStatusCodeMemoryOutOfBounds means an out-of-bounds memory access happened.
 	nativeCallStatusCodeMemoryOutOfBounds
-	// nativeCallStatusCodeInvalidTableAccess means either offset to the table was out of bounds of table, or
-	// the target element in the table was uninitialized during call_indirect instruction.
+	// nativeCallStatusCodeInvalidTableAccess means the offset to the table was out of bounds of table.
 	nativeCallStatusCodeInvalidTableAccess
 	// nativeCallStatusCodeTypeMismatchOnIndirectCall means the type check failed during call_indirect.
 	nativeCallStatusCodeTypeMismatchOnIndirectCall
 	nativeCallStatusIntegerOverflow
 	nativeCallStatusIntegerDivisionByZero
+	// nativeCallStatusCodeIndirectCallToNull means the target element in the table was null during call_indirect.
+	nativeCallStatusCodeIndirectCallToNull
 )
 
 // causePanic causes a panic with the corresponding error to the status code.
@@ -348,6 +349,8 @@ func (s nativeCallStatusCode) causePanic() {
 		err = wasmruntime.ErrRuntimeInvalidTableAccess
 	case nativeCallStatusCodeTypeMismatchOnIndirectCall:
 		err = wasmruntime.ErrRuntimeIndirectCallTypeMismatch
+	case nativeCallStatusCodeIndirectCallToNull:
+		err = wasmruntime.ErrRuntimeIndirectCallToNull
 	}
 	panic(err)
 }
@@ -374,6 +377,8 @@ func (s nativeCallStatusCode) String() (ret string) {
 		ret = "integer overflow"
 	case nativeCallStatusIntegerDivisionByZero:
 		ret = "integer division by zero"
+	case nativeCallStatusCodeIndirectCallToNull:
+		ret = "indirect call to null"
 	default:
 		panic("BUG")
 	}
@@ -440,13 +445,14 @@ func (e *engine) CompileModule(ctx context.Context, module *wasm.Module) error {
 			funcs = append(funcs, compiled)
 		}
 	} else {
-		irs, err := wazeroir.CompileFunctions(ctx, e.enabledFeatures, module)
+		enabledFeatures := module.FeaturesOr(e.enabledFeatures)
+		irs, err := wazeroir.CompileFunctions(ctx, enabledFeatures, module)
 		if err != nil {
 			return err
 		}
 
 		for funcIndex := range module.FunctionSection {
-			compiled, err := compileWasmFunction(e.enabledFeatures, irs[funcIndex])
+			compiled, err := compileWasmFunction(enabledFeatures, irs[funcIndex], module.FastCompile, module.TrustedMemory)
 			if err != nil {
 				return fmt.Errorf("function[%d/%d] %w", funcIndex, len(module.FunctionSection)-1, err)
 			}
@@ -558,6 +564,34 @@ func (e *moduleEngine) InitializeFuncrefGlobals(globals []*wasm.GlobalInstance)
 
 // Call implements the same method as documented on wasm.ModuleEngine.
 func (e *moduleEngine) Call(ctx context.Context, callCtx *wasm.CallContext, f *wasm.FunctionInstance, params ...uint64) (results []uint64, err error) {
+	paramCount := len(params)
+	if f.Type.ParamNumInUint64 != paramCount {
+		return nil, fmt.Errorf("expected %d params, but passed %d", f.Type.ParamNumInUint64, paramCount)
+	}
+
+	// Copy the params, as the stack is overwritten with results.
+	stack := make([]uint64, f.Type.StackLen())
+	copy(stack, params)
+	if err = e.call(ctx, callCtx, f, stack); err == nil && f.Type.ResultNumInUint64 > 0 {
+		results = stack[:f.Type.ResultNumInUint64]
+	}
+	return
+}
+
+// CallWithStack implements the same method as documented on wasm.ModuleEngine.
+func (e *moduleEngine) CallWithStack(ctx context.Context, callCtx *wasm.CallContext, f *wasm.FunctionInstance, stack []uint64) error {
+	if stackLen := f.Type.StackLen(); len(stack) < stackLen {
+		return fmt.Errorf("need %d stack slots, but passed %d", stackLen, len(stack))
+	}
+	return e.call(ctx, callCtx, f, stack)
+}
+
+func (e *moduleEngine) call(ctx context.Context, callCtx *wasm.CallContext, f *wasm.FunctionInstance, stack []uint64) (err error) {
+	// Don't run any code if the module was already closed, ex. by the host while this call was pending.
+	if err = callCtx.FailIfClosed(); err != nil {
+		return
+	}
+
 	// Note: The input parameters are pre-validated, so a compiled function is only absent on close. Updates to
 	// code on close aren't locked, neither is this read.
 	compiled := e.functions[f.Idx]
@@ -568,11 +602,6 @@ func (e *moduleEngine) Call(ctx context.Context, callCtx *wasm.CallContext, f *w
 		return
 	}
 
-	paramCount := len(params)
-	if f.Type.ParamNumInUint64 != paramCount {
-		return nil, fmt.Errorf("expected %d params, but passed %d", f.Type.ParamNumInUint64, paramCount)
-	}
-
 	ce := e.newCallEngine()
 
 	// We ensure that this Call method never panics as
@@ -601,14 +630,18 @@ func (e *moduleEngine) Call(ctx context.Context, callCtx *wasm.CallContext, f *w
 		}
 	}()
 
+	params := stack[:f.Type.ParamNumInUint64]
+	results := stack[:f.Type.ResultNumInUint64]
 	if f.Kind == wasm.FunctionKindWasm {
 		for _, v := range params {
 			ce.pushValue(v)
 		}
 		ce.execWasmFunction(ctx, callCtx, compiled)
-		results = wasm.PopValues(f.Type.ResultNumInUint64, ce.popValue)
+		for i := len(results) - 1; i >= 0; i-- {
+			results[i] = ce.popValue()
+		}
 	} else {
-		results = wasm.CallGoFunc(ctx, callCtx, compiled.source, params)
+		wasm.CallGoFunc(ctx, callCtx, compiled.source, stack)
 	}
 	return
 }
@@ -687,6 +720,19 @@ func (ce *callEngine) pushValue(v uint64) {
 	ce.valueStackContext.stackPointer++
 }
 
+// callGoFuncWithStack calls the host function with the params on top of the value stack, replacing them with its
+// results in place.
+func (ce *callEngine) callGoFuncWithStack(ctx context.Context, callCtx *wasm.CallContext, f *wasm.FunctionInstance) {
+	paramLen, resultLen := uint64(f.Type.ParamNumInUint64), uint64(f.Type.ResultNumInUint64)
+	base := ce.valueStackTopIndex() - paramLen
+	stackLen := paramLen
+	if resultLen > stackLen {
+		stackLen = resultLen
+	}
+	wasm.CallGoFunc(ctx, callCtx, f, ce.valueStack[base:base+stackLen])
+	ce.valueStackContext.stackPointer = ce.valueStackContext.stackPointer - paramLen + resultLen
+}
+
 func (ce *callEngine) callFrameTop() *callFrame {
 	return &ce.callFrameStack[ce.globalContext.callFrameStackPointer-1]
 }
@@ -705,6 +751,8 @@ const (
 	builtinFunctionIndexGrowValueStack
 	builtinFunctionIndexGrowCallFrameStack
 	builtinFunctionIndexTableGrow
+	// builtinFunctionIndexCheckInjectedTrap is only called when built with the tag "inject_trap".
+	builtinFunctionIndexCheckInjectedTrap
 	// builtinFunctionIndexBreakPoint is internal (only for wazero developers). Disabled by default.
 	builtinFunctionIndexBreakPoint
 )
@@ -734,17 +782,17 @@ entry:
 			// Not "callFrameTop" but take the below of peek with "callFrameAt(1)" as the top frame is for host function,
 			// but when making host function calls, we need to pass the memory instance of host function caller.
 			callerFunction := ce.callFrameAt(1).function
-			params := wasm.PopGoFuncParams(calleeHostFunction.source, ce.popValue)
-			results := wasm.CallGoFunc(
+			if wasm.InjectTrapEnabled { // Host functions have no preamble to call builtinFunctionIndexCheckInjectedTrap.
+				if trap := calleeHostFunction.source.TakeInjectedTrap(); trap != nil {
+					panic(trap)
+				}
+			}
+			ce.callGoFuncWithStack(
 				ctx,
 				// Use the caller's memory, which might be different from the defining module on an imported function.
 				callCtx.WithMemory(callerFunction.source.Module.Memory),
 				calleeHostFunction.source,
-				params,
 			)
-			for _, v := range results {
-				ce.pushValue(v)
-			}
 			goto entry
 		case nativeCallStatusCodeCallBuiltInFunction:
 			switch ce.exitContext.builtinFunctionCallIndex {
@@ -759,6 +807,10 @@ entry:
 			case builtinFunctionIndexTableGrow:
 				caller := ce.callFrameTop().function
 				ce.builtinFunctionTableGrow(ctx, caller.source.Module.Tables)
+			case builtinFunctionIndexCheckInjectedTrap:
+				if trap := ce.callFrameTop().function.source.TakeInjectedTrap(); trap != nil {
+					panic(trap)
+				}
 			}
 			if buildoptions.IsDebugMode {
 				if ce.exitContext.builtinFunctionCallIndex == builtinFunctionIndexBreakPoint {
@@ -845,15 +897,27 @@ func compileHostFunction(sig *wasm.FunctionType) (*code, error) {
 	return &code{codeSegment: c}, nil
 }
 
-func compileWasmFunction(_ wasm.Features, ir *wazeroir.CompilationResult) (*code, error) {
+func compileWasmFunction(_ wasm.Features, ir *wazeroir.CompilationResult, fastCompile, trustedMemory bool) (*code, error) {
 	compiler, err := newCompiler(ir)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize assembly builder: %w", err)
 	}
 
+	if fastCompile {
+		compiler.disableOptimizations()
+	}
+	if trustedMemory {
+		compiler.disableMemoryBoundsChecks()
+	}
+
 	if err := compiler.compilePreamble(); err != nil {
 		return nil, fmt.Errorf("failed to emit preamble: %w", err)
 	}
+	if wasm.InjectTrapEnabled { // Guest calls jump directly to the function, so check on entry.
+		if err := compiler.compileCheckInjectedTrap(); err != nil {
+			return nil, fmt.Errorf("failed to emit injected trap check: %w", err)
+		}
+	}
 
 	var skip bool
 	for _, op := range ir.Operations {
@@ -15,6 +15,7 @@ import (
 	"github.com/tetratelabs/wazero/internal/asm"
 	"github.com/tetratelabs/wazero/internal/asm/amd64"
 	"github.com/tetratelabs/wazero/internal/buildoptions"
+	"github.com/tetratelabs/wazero/internal/moremath"
 	"github.com/tetratelabs/wazero/internal/platform"
 	"github.com/tetratelabs/wazero/internal/wasm"
 	"github.com/tetratelabs/wazero/internal/wazeroir"
@@ -141,6 +142,8 @@ type amd64Compiler struct {
 	// onStackPointerCeilDeterminedCallBack hold a callback which are called when the max stack pointer is determined BEFORE generating native code.
 	onStackPointerCeilDeterminedCallBack func(stackPointerCeil uint64)
 	staticData                           codeStaticData
+	// skipMemoryBoundsChecks is set by disableMemoryBoundsChecks.
+	skipMemoryBoundsChecks bool
 }
 
 func newAmd64Compiler(ir *wazeroir.CompilationResult) (compiler, error) {
@@ -154,6 +157,22 @@ func newAmd64Compiler(ir *wazeroir.CompilationResult) (compiler, error) {
 	return c, nil
 }
 
+// disableOptimizations implements compiler.disableOptimizations for amd64.
+//
+// This encodes forward jumps with 32-bit displacements, so that the function is assembled in one pass, and skips the
+// NOP padding that avoids Intel's jump conditional code erratum.
+func (c *amd64Compiler) disableOptimizations() {
+	if a, ok := c.assembler.(*amd64.AssemblerImpl); ok {
+		a.EnablePadding = false
+		a.EnableShortForwardJumps = false
+	}
+}
+
+// disableMemoryBoundsChecks implements compiler.disableMemoryBoundsChecks for amd64.
+func (c *amd64Compiler) disableMemoryBoundsChecks() {
+	c.skipMemoryBoundsChecks = true
+}
+
 // setLocationStack sets the given runtimeValueLocationStack to .locationStack field,
 // while allowing us to track runtimeValueLocationStack.stackPointerCeil across multiple stacks.
 // This is called when we branch into different block.
@@ -809,8 +828,8 @@ func (c *amd64Compiler) compileCallIndirect(o *wazeroir.OperationCallIndirect) e
 	// Jump if the target is initialized element.
 	jumpIfInitialized := c.assembler.CompileJump(amd64.JNE)
 
-	// If not initialized, we return the function with nativeCallStatusCodeInvalidTableAccess.
-	c.compileExitFromNativeCode(nativeCallStatusCodeInvalidTableAccess)
+	// If not initialized, we return the function with nativeCallStatusCodeIndirectCallToNull.
+	c.compileExitFromNativeCode(nativeCallStatusCodeIndirectCallToNull)
 
 	c.assembler.SetJumpTargetOnNext(jumpIfInitialized)
 
@@ -1219,7 +1238,12 @@ func (c *amd64Compiler) compileClz(o *wazeroir.OperationClz) error {
 
 		// First, we have to check if the target is non-zero as BSR is undefined
 		// on zero. See https://www.felixcloutier.com/x86/bsr.
-		c.assembler.CompileRegisterToConst(amd64.CMPQ, target.register, 0)
+		// Note: 32-bit values are compared with CMPL, as the upper bits of the register may not be cleared.
+		if o.Type == wazeroir.UnsignedInt32 {
+			c.assembler.CompileRegisterToConst(amd64.CMPL, target.register, 0)
+		} else {
+			c.assembler.CompileRegisterToConst(amd64.CMPQ, target.register, 0)
+		}
 		jmpIfNonZero := c.assembler.CompileJump(amd64.JNE)
 
 		// If the value is zero, we just push the const value.
@@ -1278,8 +1302,12 @@ func (c *amd64Compiler) compileCtz(o *wazeroir.OperationCtz) error {
 		// Meanwhile, we need branches for non-zero and zero cases on macos.
 		// TODO: find the reference to this behavior and put the link here.
 
-		// First we compare the target with zero.
-		c.assembler.CompileRegisterToConst(amd64.CMPQ, target.register, 0)
+		// First we compare the target with zero, only in the lower 32 bits for 32-bit values.
+		if o.Type == wazeroir.UnsignedInt32 {
+			c.assembler.CompileRegisterToConst(amd64.CMPL, target.register, 0)
+		} else {
+			c.assembler.CompileRegisterToConst(amd64.CMPQ, target.register, 0)
+		}
 		jmpIfNonZero := c.assembler.CompileJump(amd64.JNE)
 
 		// If the value is zero, we just push the const value.
@@ -1862,9 +1890,9 @@ func (c *amd64Compiler) compileRoundInstruction(is32Bit bool, mode int64) error
 func (c *amd64Compiler) compileMin(o *wazeroir.OperationMin) error {
 	is32Bit := o.Type == wazeroir.Float32
 	if is32Bit {
-		return c.compileMinOrMax(is32Bit, amd64.MINSS)
+		return c.compileMinOrMax(is32Bit, amd64.MINSS, amd64.ORPS)
 	} else {
-		return c.compileMinOrMax(is32Bit, amd64.MINSD)
+		return c.compileMinOrMax(is32Bit, amd64.MINSD, amd64.ORPD)
 	}
 }
 
@@ -1872,9 +1900,9 @@ func (c *amd64Compiler) compileMin(o *wazeroir.OperationMin) error {
 func (c *amd64Compiler) compileMax(o *wazeroir.OperationMax) error {
 	is32Bit := o.Type == wazeroir.Float32
 	if is32Bit {
-		return c.compileMinOrMax(is32Bit, amd64.MAXSS)
+		return c.compileMinOrMax(is32Bit, amd64.MAXSS, amd64.ANDPS)
 	} else {
-		return c.compileMinOrMax(is32Bit, amd64.MAXSD)
+		return c.compileMinOrMax(is32Bit, amd64.MAXSD, amd64.ANDPD)
 	}
 }
 
@@ -1889,8 +1917,13 @@ func (c *amd64Compiler) compileMax(o *wazeroir.OperationMax) error {
 // Therefore in this function, we have to add conditional jumps to check if one of values is NaN before
 // the native min/max, which is why we cannot simply emit a native min/max instruction here.
 //
+// Native min/max instructions also return the second operand when the values are equal, so the result of
+// native_min(+0.0, -0.0) is +0.0, where WebAssembly specifies -0.0. Therefore, equal values are combined with
+// equalInstruction instead: ORPS or ORPD for min, which keeps the sign bit if either has it, or ANDPS or ANDPD for
+// max, which keeps the sign bit only if both have it.
+//
 // For the semantics, see wazeroir.Min and wazeroir.Max for detail.
-func (c *amd64Compiler) compileMinOrMax(is32Bit bool, minOrMaxInstruction asm.Instruction) error {
+func (c *amd64Compiler) compileMinOrMax(is32Bit bool, minOrMaxInstruction, equalInstruction asm.Instruction) error {
 	x2 := c.locationStack.pop()
 	if err := c.compileEnsureOnGeneralPurposeRegister(x2); err != nil {
 		return err
@@ -1900,6 +1933,12 @@ func (c *amd64Compiler) compileMinOrMax(is32Bit bool, minOrMaxInstruction asm.In
 		return err
 	}
 
+	// Allocate the register to load the canonical NaN before branching, as allocating can spill other values.
+	tmpReg, err := c.allocateRegister(registerTypeGeneralPurpose)
+	if err != nil {
+		return err
+	}
+
 	// Check if this is (either x1 or x2 is NaN) or (x1 equals x2) case
 	if is32Bit {
 		c.assembler.CompileRegisterToRegister(amd64.UCOMISS, x2.register, x1.register)
@@ -1920,18 +1959,30 @@ func (c *amd64Compiler) compileMinOrMax(is32Bit bool, minOrMaxInstruction asm.In
 
 	// Start handling 2) and 3).
 
-	// Jump if two values are equal and NaN-free by checking the parity flag (PF).
-	// Here we use JPC to do the conditional jump when the parity flag is NOT set,
-	// and that is of 2).
-	equalExitJmp := c.assembler.CompileJump(amd64.JPC)
+	// Jump if one of two values is NaN by checking the parity flag (PF).
+	// Here we use JPS to do the conditional jump when the parity flag is set,
+	// and that is of 3).
+	nanJump := c.assembler.CompileJump(amd64.JPS)
+
+	// Start handling 2).
+
+	// Equal values only differ in their sign bit when they are zeros, so combine the bits to choose the sign.
+	c.assembler.CompileRegisterToRegister(equalInstruction, x2.register, x1.register)
+
+	// Exit from the equal case branch.
+	equalExitJmp := c.assembler.CompileJump(amd64.JMP)
 
 	// Start handling 3).
+	c.assembler.SetJumpTargetOnNext(nanJump)
 
-	// We emit the ADD instruction to produce the NaN in x1.
+	// Load the canonical NaN into x1, as opposed to propagating the payload of the NaN operand, so that the result is
+	// the same as the interpreter. We cannot directly load the value to float regs, so we move it to int reg first.
 	if is32Bit {
-		c.assembler.CompileRegisterToRegister(amd64.ADDSS, x2.register, x1.register)
+		c.assembler.CompileConstToRegister(amd64.MOVL, int64(moremath.F32CanonicalNaNBits), tmpReg)
+		c.assembler.CompileRegisterToRegister(amd64.MOVL, tmpReg, x1.register)
 	} else {
-		c.assembler.CompileRegisterToRegister(amd64.ADDSD, x2.register, x1.register)
+		c.assembler.CompileConstToRegister(amd64.MOVQ, int64(moremath.F64CanonicalNaNBits), tmpReg)
+		c.assembler.CompileRegisterToRegister(amd64.MOVQ, tmpReg, x1.register)
 	}
 
 	// Exit from the NaN case branch.
@@ -3266,8 +3317,9 @@ func (c *amd64Compiler) compileLoad32(o *wazeroir.OperationLoad32) error {
 // into a register, and returns the stored register. We call the result "ceil" because we access the memory
 // as memory.Buffer[ceil-targetSizeInBytes: ceil].
 //
-// Note: this also emits the instructions to check the out of bounds memory access.
-// In other words, if the ceil exceeds the memory size, the code exits with nativeCallStatusCodeMemoryOutOfBounds status.
+// Note: this also emits the instructions to check the out of bounds memory access, unless disableMemoryBoundsChecks was
+// called. In other words, if the ceil exceeds the memory size, the code exits with nativeCallStatusCodeMemoryOutOfBounds
+// status.
 func (c *amd64Compiler) compileMemoryAccessCeilSetup(offsetArg uint32, targetSizeInBytes int64) (asm.Register, error) {
 	base := c.locationStack.pop()
 	if err := c.compileEnsureOnGeneralPurposeRegister(base); err != nil {
@@ -3283,6 +3335,11 @@ func (c *amd64Compiler) compileMemoryAccessCeilSetup(offsetArg uint32, targetSiz
 		return result, nil
 	}
 
+	if c.skipMemoryBoundsChecks {
+		c.locationStack.markRegisterUnused(result)
+		return result, nil
+	}
+
 	// Now we compare the value with the memory length which is held by callEngine.
 	c.assembler.CompileMemoryToRegister(amd64.CMPQ,
 		amd64ReservedRegisterForCallEngine, callEngineModuleContextMemorySliceLenOffset, result)
@@ -3366,6 +3423,18 @@ func (c *amd64Compiler) compileMemoryGrow() error {
 	return nil
 }
 
+// compileCheckInjectedTrap implements compiler.compileCheckInjectedTrap for the amd64 architecture.
+func (c *amd64Compiler) compileCheckInjectedTrap() error {
+	if err := c.compileCallBuiltinFunction(builtinFunctionIndexCheckInjectedTrap); err != nil {
+		return err
+	}
+
+	// After the function call, we have to initialize the stack base pointer and memory reserved registers.
+	c.compileReservedStackBasePointerInitialization()
+	c.compileReservedMemoryPointerInitialization()
+	return nil
+}
+
 // compileMemorySize implements compiler.compileMemorySize for the amd64 architecture.
 func (c *amd64Compiler) compileMemorySize() error {
 	c.maybeCompileMoveTopConditionalToFreeGeneralPurposeRegister()
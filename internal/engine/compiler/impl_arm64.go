@@ -17,6 +17,7 @@ import (
 
 	"github.com/tetratelabs/wazero/internal/asm"
 	"github.com/tetratelabs/wazero/internal/asm/arm64"
+	"github.com/tetratelabs/wazero/internal/moremath"
 	"github.com/tetratelabs/wazero/internal/platform"
 	"github.com/tetratelabs/wazero/internal/wasm"
 	"github.com/tetratelabs/wazero/internal/wazeroir"
@@ -37,6 +38,8 @@ type arm64Compiler struct {
 	// codeStaticData holds br_table offset tables.
 	// See codeStaticData and arm64Compiler.compileBrTable.
 	staticData codeStaticData
+	// skipMemoryBoundsChecks is set by disableMemoryBoundsChecks.
+	skipMemoryBoundsChecks bool
 }
 
 func newArm64Compiler(ir *wazeroir.CompilationResult) (compiler, error) {
@@ -48,6 +51,17 @@ func newArm64Compiler(ir *wazeroir.CompilationResult) (compiler, error) {
 	}, nil
 }
 
+// disableOptimizations implements compiler.disableOptimizations for arm64.
+//
+// This is a no-op: instructions have a fixed length, so there are no jumps to shorten, and there are no other optional
+// passes.
+func (c *arm64Compiler) disableOptimizations() {}
+
+// disableMemoryBoundsChecks implements compiler.disableMemoryBoundsChecks for arm64.
+func (c *arm64Compiler) disableMemoryBoundsChecks() {
+	c.skipMemoryBoundsChecks = true
+}
+
 var (
 	arm64UnreservedVectorRegisters = []asm.Register{
 		arm64.RegV0, arm64.RegV1, arm64.RegV2, arm64.RegV3,
@@ -1221,7 +1235,7 @@ func (c *arm64Compiler) compileCallIndirect(o *wazeroir.OperationCallIndirect) e
 	// Check if the value of table[offset] equals zero, meaning that the target element is uninitialized.
 	c.assembler.CompileTwoRegistersToNone(arm64.CMP, arm64.RegRZR, offset.register)
 	brIfInitialized := c.assembler.CompileJump(arm64.BNE)
-	c.compileExitFromNativeCode(nativeCallStatusCodeInvalidTableAccess)
+	c.compileExitFromNativeCode(nativeCallStatusCodeIndirectCallToNull)
 
 	c.assembler.SetJumpTargetOnNext(brIfInitialized)
 	// Next we check the type matches, i.e. table[offset].source.TypeID == targetFunctionType.
@@ -2119,21 +2133,51 @@ func (c *arm64Compiler) compileSqrt(o *wazeroir.OperationSqrt) error {
 // compileMin implements compiler.compileMin for the arm64 architecture.
 func (c *arm64Compiler) compileMin(o *wazeroir.OperationMin) error {
 	if o.Type == wazeroir.Float32 {
-		return c.compileSimpleFloatBinop(arm64.FMINS)
+		return c.compileMinOrMax(true, arm64.FMINS)
 	} else {
-		return c.compileSimpleFloatBinop(arm64.FMIND)
+		return c.compileMinOrMax(false, arm64.FMIND)
 	}
 }
 
 // compileMax implements compiler.compileMax for the arm64 architecture.
 func (c *arm64Compiler) compileMax(o *wazeroir.OperationMax) error {
 	if o.Type == wazeroir.Float32 {
-		return c.compileSimpleFloatBinop(arm64.FMAXS)
+		return c.compileMinOrMax(true, arm64.FMAXS)
 	} else {
-		return c.compileSimpleFloatBinop(arm64.FMAXD)
+		return c.compileMinOrMax(false, arm64.FMAXD)
 	}
 }
 
+// compileMinOrMax adds instructions to perform min or max with the given instruction, which propagates the payload of
+// a NaN operand. So, a NaN result is replaced with the canonical NaN, to match the interpreter.
+func (c *arm64Compiler) compileMinOrMax(is32Bit bool, minOrMaxInstruction asm.Instruction) error {
+	x1, x2, err := c.popTwoValuesOnRegisters()
+	if err != nil {
+		return err
+	}
+	c.assembler.CompileRegisterToRegister(minOrMaxInstruction, x2.register, x1.register)
+
+	// VS flag is set if the result is NaN, as it then doesn't compare equal to itself.
+	var fcmp, mov, fmov asm.Instruction
+	var canonicalNaN uint64
+	if is32Bit {
+		fcmp, mov, fmov, canonicalNaN = arm64.FCMPS, arm64.MOVW, arm64.FMOVS, uint64(moremath.F32CanonicalNaNBits)
+	} else {
+		fcmp, mov, fmov, canonicalNaN = arm64.FCMPD, arm64.MOVD, arm64.FMOVD, moremath.F64CanonicalNaNBits
+	}
+	c.assembler.CompileTwoRegistersToNone(fcmp, x1.register, x1.register)
+	brIfNaN := c.assembler.CompileJump(arm64.BVS)
+	brExit := c.assembler.CompileJump(arm64.B)
+
+	c.assembler.SetJumpTargetOnNext(brIfNaN)
+	c.assembler.CompileConstToRegister(mov, int64(canonicalNaN), arm64ReservedRegisterForTemporary)
+	c.assembler.CompileRegisterToRegister(fmov, arm64ReservedRegisterForTemporary, x1.register)
+
+	c.assembler.SetJumpTargetOnNext(brExit)
+	c.pushRuntimeValueLocationOnRegister(x1.register, x1.valueType)
+	return nil
+}
+
 func (c *arm64Compiler) compileSimpleFloatBinop(inst asm.Instruction) error {
 	x1, x2, err := c.popTwoValuesOnRegisters()
 	if err != nil {
@@ -2814,8 +2858,9 @@ func (c *arm64Compiler) compileStoreImpl(offsetArg uint32, storeInst asm.Instruc
 // into a register, and returns the stored register. We call the result "offset" because we access the memory
 // as memory.Buffer[offset: offset+targetSizeInBytes].
 //
-// Note: this also emits the instructions to check the out of bounds memory access.
-// In other words, if the offset+targetSizeInBytes exceeds the memory size, the code exits with nativeCallStatusCodeMemoryOutOfBounds status.
+// Note: this also emits the instructions to check the out of bounds memory access, unless disableMemoryBoundsChecks was
+// called. In other words, if the offset+targetSizeInBytes exceeds the memory size, the code exits with
+// nativeCallStatusCodeMemoryOutOfBounds status.
 func (c *arm64Compiler) compileMemoryAccessOffsetSetup(offsetArg uint32, targetSizeInBytes int64) (offsetRegister asm.Register, err error) {
 	base, err := c.popValueOnRegister()
 	if err != nil {
@@ -2831,13 +2876,19 @@ func (c *arm64Compiler) compileMemoryAccessOffsetSetup(offsetArg uint32, targetS
 		c.assembler.CompileRegisterToRegister(arm64.MOVD, arm64.RegRZR, offsetRegister)
 	}
 
-	if offsetConst := int64(offsetArg) + targetSizeInBytes; offsetConst <= math.MaxUint32 {
-		// "offsetRegister = base + offsetArg + targetSizeInBytes"
-		c.assembler.CompileConstToRegister(arm64.ADD, offsetConst, offsetRegister)
-	} else {
+	if offsetConst := int64(offsetArg) + targetSizeInBytes; offsetConst > math.MaxUint32 {
 		// If the offset const is too large, we exit with nativeCallStatusCodeMemoryOutOfBounds.
 		c.compileExitFromNativeCode(nativeCallStatusCodeMemoryOutOfBounds)
 		return
+	} else if c.skipMemoryBoundsChecks {
+		if offsetArg != 0 {
+			// "offsetRegister = base + offsetArg"
+			c.assembler.CompileConstToRegister(arm64.ADD, int64(offsetArg), offsetRegister)
+		}
+		return offsetRegister, nil
+	} else {
+		// "offsetRegister = base + offsetArg + targetSizeInBytes"
+		c.assembler.CompileConstToRegister(arm64.ADD, offsetConst, offsetRegister)
 	}
 
 	// "arm64ReservedRegisterForTemporary = len(memory.Buffer)"
@@ -2873,6 +2924,18 @@ func (c *arm64Compiler) compileMemoryGrow() error {
 	return nil
 }
 
+// compileCheckInjectedTrap implements compiler.compileCheckInjectedTrap for the arm64 architecture.
+func (c *arm64Compiler) compileCheckInjectedTrap() error {
+	if err := c.compileCallGoFunction(nativeCallStatusCodeCallBuiltInFunction, builtinFunctionIndexCheckInjectedTrap); err != nil {
+		return err
+	}
+
+	// After return, we re-initialize reserved registers just like preamble of functions.
+	c.compileReservedStackBasePointerRegisterInitialization()
+	c.compileReservedMemoryRegisterInitialization()
+	return nil
+}
+
 // compileMemorySize implements compileMemorySize variants for arm64 architecture.
 func (c *arm64Compiler) compileMemorySize() error {
 	c.maybeCompileMoveTopConditionalToFreeGeneralPurposeRegister()
@@ -9,6 +9,7 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 	"unsafe"
 
 	"github.com/tetratelabs/wazero/experimental"
@@ -27,12 +28,44 @@ type engine struct {
 	enabledFeatures wasm.Features
 	codes           map[wasm.ModuleID][]*code // guarded by mutex.
 	mux             sync.RWMutex
+
+	// metrics when non-nil counts each operation executed. This is nil unless set in EngineOptions.
+	metrics *Metrics
+
+	// stepper when non-nil pauses before each operation executed. This is nil unless set in EngineOptions.
+	stepper *Stepper
+
+	// profiler when non-nil samples the call stack at each call boundary. This is nil unless set in EngineOptions.
+	profiler *Profiler
+}
+
+// EngineOptions are optional instrumentation of the interpreter, where each nil field is disabled.
+//
+// Note: When both Metrics and Stepper are nil, functions are compiled without any instrumentation, so there is no
+// per-operation overhead.
+type EngineOptions struct {
+	// Metrics counts each operation executed.
+	Metrics *Metrics
+
+	// Stepper pauses before each operation executed until it allows the interpreter to continue.
+	Stepper *Stepper
+
+	// Profiler samples the call stack at each call boundary.
+	Profiler *Profiler
 }
 
 func NewEngine(enabledFeatures wasm.Features) wasm.Engine {
+	return NewEngineWithOptions(enabledFeatures, EngineOptions{})
+}
+
+// NewEngineWithOptions is like NewEngine, except it enables the instrumentation in the given EngineOptions.
+func NewEngineWithOptions(enabledFeatures wasm.Features, options EngineOptions) wasm.Engine {
 	return &engine{
 		enabledFeatures: enabledFeatures,
 		codes:           map[wasm.ModuleID][]*code{},
+		metrics:         options.Metrics,
+		stepper:         options.Stepper,
+		profiler:        options.Profiler,
 	}
 }
 
@@ -88,10 +121,23 @@ type callEngine struct {
 
 	// frames are the function call stack.
 	frames []*callFrame
+
+	// metrics is the same as engine.metrics.
+	metrics *Metrics
+
+	// stepper is the same as engine.stepper.
+	stepper *Stepper
+
+	// profiler is the same as engine.profiler.
+	profiler *Profiler
+
+	// lastSample is when profiler last sampled this call, or zero before the first sample.
+	lastSample time.Time
 }
 
 func (me *moduleEngine) newCallEngine() *callEngine {
-	return &callEngine{}
+	e := me.parentEngine
+	return &callEngine{metrics: e.metrics, stepper: e.stepper, profiler: e.profiler}
 }
 
 func (ce *callEngine) pushValue(v uint64) {
@@ -111,6 +157,18 @@ func (ce *callEngine) popValue() (v uint64) {
 	return
 }
 
+// topValues returns up to n values at the top of the stack, beginning with the top.
+func (ce *callEngine) topValues(n int) []uint64 {
+	if n > len(ce.stack) {
+		n = len(ce.stack)
+	}
+	ret := make([]uint64, n)
+	for i := range ret {
+		ret[i] = ce.stack[len(ce.stack)-1-i]
+	}
+	return ret
+}
+
 // peekValues peeks api.ValueType values from the stack and returns them in reverse order.
 func (ce *callEngine) peekValues(count int) []uint64 {
 	if count == 0 {
@@ -147,18 +205,35 @@ func (ce *callEngine) pushFrame(frame *callFrame) {
 	if callStackCeiling <= len(ce.frames) {
 		panic(wasmruntime.ErrRuntimeCallStackOverflow)
 	}
+	if ce.profiler != nil {
+		ce.profiler.sample(ce)
+	}
 	ce.frames = append(ce.frames, frame)
 }
 
 func (ce *callEngine) popFrame() (frame *callFrame) {
 	// No need to check stack bound as we can assume that all the operations are valid thanks to validateFunction at
 	// module validation phase and wazeroir translation before compilation.
+	if ce.profiler != nil {
+		ce.profiler.sample(ce)
+	}
 	oneLess := len(ce.frames) - 1
 	frame = ce.frames[oneLess]
 	ce.frames = ce.frames[:oneLess]
 	return
 }
 
+// instrument runs the Stepper and Metrics, if any, for the operation after the operationKindInstrument at frame.pc.
+func (ce *callEngine) instrument(frame *callFrame) {
+	if ce.stepper != nil {
+		// Report the pc as if lowerIR didn't insert instrumentOp before each operation.
+		ce.stepper.before(ce, frame.f.source.Idx, frame.pc/2)
+	}
+	if ce.metrics != nil {
+		ce.metrics.add(frame.f.body[frame.pc+1].kind)
+	}
+}
+
 type callFrame struct {
 	// pc is the program counter representing the current position in code.body.
 	pc uint64
@@ -213,6 +288,13 @@ type interpreterOp struct {
 	rs     []*wazeroir.InclusiveRange
 }
 
+// operationKindInstrument is a pseudo-operation lowerIR inserts before each operation when the engine has Metrics or
+// a Stepper. This keeps the dispatch loop free of any per-operation checks when neither is in use.
+const operationKindInstrument = wazeroir.OperationKindEnd
+
+// instrumentOp is shared by all instrumented functions as it has no operands.
+var instrumentOp = &interpreterOp{kind: operationKindInstrument}
+
 // CompileModule implements the same method as documented on wasm.Engine.
 func (e *engine) CompileModule(ctx context.Context, module *wasm.Module) error {
 	if _, ok := e.getCodes(module); ok { // cache hit!
@@ -228,7 +310,7 @@ func (e *engine) CompileModule(ctx context.Context, module *wasm.Module) error {
 			funcs = append(funcs, &code{hostFn: hf})
 		}
 	} else {
-		irs, err := wazeroir.CompileFunctions(ctx, e.enabledFeatures, module)
+		irs, err := wazeroir.CompileFunctions(ctx, module.FeaturesOr(e.enabledFeatures), module)
 		if err != nil {
 			return err
 		}
@@ -289,6 +371,7 @@ func (e *engine) NewModuleEngine(name string, module *wasm.Module, importedFunct
 func (e *engine) lowerIR(ir *wazeroir.CompilationResult) (*code, error) {
 	ops := ir.Operations
 	ret := &code{}
+	instrument := e.metrics != nil || e.stepper != nil
 	labelAddress := map[string]uint64{}
 	onLabelAddressResolved := map[string][]func(addr uint64){}
 	for _, original := range ops {
@@ -648,6 +731,10 @@ func (e *engine) lowerIR(ir *wazeroir.CompilationResult) (*code, error) {
 		default:
 			panic(fmt.Errorf("BUG: unimplemented operation %s", op.kind.String()))
 		}
+		if instrument {
+			// Labels resolve to the address of instrumentOp, so it also runs before branch targets.
+			ret.body = append(ret.body, instrumentOp)
+		}
 		ret.body = append(ret.body, op)
 	}
 
@@ -696,6 +783,35 @@ func (me *moduleEngine) InitializeFuncrefGlobals(globals []*wasm.GlobalInstance)
 
 // Call implements the same method as documented on wasm.ModuleEngine.
 func (me *moduleEngine) Call(ctx context.Context, m *wasm.CallContext, f *wasm.FunctionInstance, params ...uint64) (results []uint64, err error) {
+	paramSignature := f.Type.ParamNumInUint64
+	paramCount := len(params)
+	if paramSignature != paramCount {
+		return nil, fmt.Errorf("expected %d params, but passed %d", paramSignature, paramCount)
+	}
+
+	// Copy the params, as the stack is overwritten with results.
+	stack := make([]uint64, f.Type.StackLen())
+	copy(stack, params)
+	if err = me.call(ctx, m, f, stack); err == nil && f.Type.ResultNumInUint64 > 0 {
+		results = stack[:f.Type.ResultNumInUint64]
+	}
+	return
+}
+
+// CallWithStack implements the same method as documented on wasm.ModuleEngine.
+func (me *moduleEngine) CallWithStack(ctx context.Context, m *wasm.CallContext, f *wasm.FunctionInstance, stack []uint64) error {
+	if stackLen := f.Type.StackLen(); len(stack) < stackLen {
+		return fmt.Errorf("need %d stack slots, but passed %d", stackLen, len(stack))
+	}
+	return me.call(ctx, m, f, stack)
+}
+
+func (me *moduleEngine) call(ctx context.Context, m *wasm.CallContext, f *wasm.FunctionInstance, stack []uint64) (err error) {
+	// Don't run any code if the module was already closed, ex. by the host while this call was pending.
+	if err = m.FailIfClosed(); err != nil {
+		return
+	}
+
 	// Note: The input parameters are pre-validated, so a compiled function is only absent on close. Updates to
 	// code on close aren't locked, neither is this read.
 	compiled := me.functions[f.Idx]
@@ -706,13 +822,11 @@ func (me *moduleEngine) Call(ctx context.Context, m *wasm.CallContext, f *wasm.F
 		return
 	}
 
-	paramSignature := f.Type.ParamNumInUint64
-	paramCount := len(params)
-	if paramSignature != paramCount {
-		return nil, fmt.Errorf("expected %d params, but passed %d", paramSignature, paramCount)
-	}
-
 	ce := me.newCallEngine()
+	if ce.stepper != nil {
+		ce.stepper.enter()
+		defer ce.stepper.exit()
+	}
 	defer func() {
 		// If the module closed during the call, and the call didn't err for another reason, set an ExitError.
 		if err == nil {
@@ -722,6 +836,7 @@ func (me *moduleEngine) Call(ctx context.Context, m *wasm.CallContext, f *wasm.F
 
 		if v := recover(); v != nil {
 			builder := wasmdebug.NewErrorBuilder()
+			builder.SetStackValues(ce.topValues(wasmdebug.MaxStackValues))
 			frameCount := len(ce.frames)
 			for i := 0; i < frameCount; i++ {
 				frame := ce.popFrame()
@@ -732,6 +847,8 @@ func (me *moduleEngine) Call(ctx context.Context, m *wasm.CallContext, f *wasm.F
 		}
 	}()
 
+	params := stack[:f.Type.ParamNumInUint64]
+	results := stack[:f.Type.ResultNumInUint64]
 	if f.Kind == wasm.FunctionKindWasm {
 		if f.FunctionListener != nil {
 			ctx = f.FunctionListener.Before(ctx, params)
@@ -740,34 +857,49 @@ func (me *moduleEngine) Call(ctx context.Context, m *wasm.CallContext, f *wasm.F
 			ce.pushValue(param)
 		}
 		ce.callNativeFunc(ctx, m, compiled)
-		results = wasm.PopValues(f.Type.ResultNumInUint64, ce.popValue)
+		for i := len(results) - 1; i >= 0; i-- {
+			results[i] = ce.popValue()
+		}
 		if f.FunctionListener != nil {
 			// TODO: This doesn't get the error due to use of panic to propagate them.
 			f.FunctionListener.After(ctx, nil, results)
 		}
 	} else {
-		results = ce.callGoFunc(ctx, m, compiled, params)
+		ce.callGoFunc(ctx, m, compiled, stack)
 	}
 	return
 }
 
-func (ce *callEngine) callGoFunc(ctx context.Context, callCtx *wasm.CallContext, f *function, params []uint64) (results []uint64) {
+// callGoFunc calls the host function with the params at the beginning of the stack, which are overwritten with its
+// results. The stack must be at least wasm.FunctionType StackLen.
+func (ce *callEngine) callGoFunc(ctx context.Context, callCtx *wasm.CallContext, f *function, stack []uint64) {
 	if len(ce.frames) > 0 {
 		// Use the caller's memory, which might be different from the defining module on an imported function.
 		callCtx = callCtx.WithMemory(ce.frames[len(ce.frames)-1].f.source.Module.Memory)
 	}
 	if f.source.FunctionListener != nil {
-		ctx = f.source.FunctionListener.Before(ctx, params)
+		ctx = f.source.FunctionListener.Before(ctx, stack[:f.source.Type.ParamNumInUint64])
 	}
 	frame := &callFrame{f: f}
 	ce.pushFrame(frame)
-	results = wasm.CallGoFunc(ctx, callCtx, f.source, params)
+	ce.checkInjectedTrap(f)
+	wasm.CallGoFunc(ctx, callCtx, f.source, stack)
 	ce.popFrame()
 	if f.source.FunctionListener != nil {
 		// TODO: This doesn't get the error due to use of panic to propagate them.
-		f.source.FunctionListener.After(ctx, nil, results)
+		f.source.FunctionListener.After(ctx, nil, stack[:f.source.Type.ResultNumInUint64])
+	}
+}
+
+// checkInjectedTrap panics with any trap injected into the function by wasm.CallContext.InjectTrap, as calls from the
+// guest don't pass through it. This is called after pushing the frame of the function, so that it is in the stack
+// trace. This is a no-op unless built with the tag "inject_trap".
+func (ce *callEngine) checkInjectedTrap(f *function) {
+	if wasm.InjectTrapEnabled {
+		if trap := f.source.TakeInjectedTrap(); trap != nil {
+			panic(trap)
+		}
 	}
-	return
 }
 
 func (ce *callEngine) callNativeFunc(ctx context.Context, callCtx *wasm.CallContext, f *function) {
@@ -782,6 +914,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, callCtx *wasm.CallCont
 	elementInstances := f.source.Module.ElementInstances
 	listener := f.source.FunctionListener
 	ce.pushFrame(frame)
+	ce.checkInjectedTrap(f)
 	bodyLen := uint64(len(frame.f.body))
 	for frame.pc < bodyLen {
 		op := frame.f.body[frame.pc]
@@ -789,6 +922,9 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, callCtx *wasm.CallCont
 		// on, for example, how many args are used,
 		// how the stack is modified, etc.
 		switch op.kind {
+		case operationKindInstrument:
+			ce.instrument(frame)
+			frame.pc++
 		case wazeroir.OperationKindUnreachable:
 			panic(wasmruntime.ErrRuntimeUnreachable)
 		case wazeroir.OperationKindBr:
@@ -828,7 +964,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, callCtx *wasm.CallCont
 			}
 			rawPtr := table.References[offset]
 			if rawPtr == 0 {
-				panic(wasmruntime.ErrRuntimeInvalidTableAccess)
+				panic(wasmruntime.ErrRuntimeIndirectCallToNull)
 			}
 
 			tf := functionFromUintptr(rawPtr)
@@ -1208,8 +1344,13 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, callCtx *wasm.CallCont
 			// If an integer, check we won't divide by zero.
 			t := wazeroir.SignedType(op.b1)
 			v2, v1 := ce.popValue(), ce.popValue()
+			// Only the lower 32 bits of a 32-bit integer are significant, as the upper ones may be garbage.
 			switch t {
 			case wazeroir.SignedTypeFloat32, wazeroir.SignedTypeFloat64: // not integers
+			case wazeroir.SignedTypeInt32, wazeroir.SignedTypeUint32:
+				if uint32(v2) == 0 {
+					panic(wasmruntime.ErrRuntimeIntegerDivideByZero)
+				}
 			default:
 				if v2 == 0 {
 					panic(wasmruntime.ErrRuntimeIntegerDivideByZero)
@@ -1253,7 +1394,12 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, callCtx *wasm.CallCont
 			frame.pc++
 		case wazeroir.OperationKindRem:
 			v2, v1 := ce.popValue(), ce.popValue()
-			if v2 == 0 {
+			// Like OperationKindDiv, only the lower 32 bits of a 32-bit integer are significant.
+			divisor := v2
+			if s := wazeroir.SignedInt(op.b1); s == wazeroir.SignedInt32 || s == wazeroir.SignedUint32 {
+				divisor = uint64(uint32(v2))
+			}
+			if divisor == 0 {
 				panic(wasmruntime.ErrRuntimeIntegerDivideByZero)
 			}
 			switch wazeroir.SignedInt(op.b1) {
@@ -2948,10 +3094,14 @@ func (ce *callEngine) popMemoryOffset(op *interpreterOp) uint32 {
 	return uint32(offset)
 }
 
+// callGoFuncWithStack calls the host function with the params on top of the value stack, replacing them with its
+// results in place.
 func (ce *callEngine) callGoFuncWithStack(ctx context.Context, callCtx *wasm.CallContext, f *function) {
-	params := wasm.PopGoFuncParams(f.source, ce.popValue)
-	results := ce.callGoFunc(ctx, callCtx, f, params)
-	for _, v := range results {
-		ce.pushValue(v)
+	paramLen, resultLen := f.source.Type.ParamNumInUint64, f.source.Type.ResultNumInUint64
+	base := len(ce.stack) - paramLen
+	for i := paramLen; i < resultLen; i++ { // Ensure there's room for the results.
+		ce.pushValue(0)
 	}
+	ce.callGoFunc(ctx, callCtx, f, ce.stack[base:])
+	ce.stack = ce.stack[:base+resultLen]
 }
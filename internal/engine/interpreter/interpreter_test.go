@@ -2,6 +2,7 @@ package interpreter
 
 import (
 	"context"
+	"encoding/binary"
 	"fmt"
 	"math"
 	"strconv"
@@ -424,6 +425,58 @@ func TestInterpreter_CallEngine_callNativeFunc_signExtend(t *testing.T) {
 	})
 }
 
+func TestInterpreter_CallEngine_callNativeFunc_v128(t *testing.T) {
+	// v128 returns the operation pushing a vector of the given bytes, where b[0] is the lowest lane.
+	v128 := func(b [16]byte) *interpreterOp {
+		return &interpreterOp{kind: wazeroir.OperationKindV128Const, us: []uint64{
+			binary.LittleEndian.Uint64(b[:8]), binary.LittleEndian.Uint64(b[8:]),
+		}}
+	}
+
+	// callV128 runs the body, and returns the resulting vector as bytes, where the lowest lane is first.
+	callV128 := func(body ...*interpreterOp) (ret [16]byte) {
+		ce := &callEngine{}
+		f := &function{
+			source: &wasm.FunctionInstance{Module: &wasm.ModuleInstance{Engine: &moduleEngine{}}},
+			body:   append(body, &interpreterOp{kind: wazeroir.OperationKindBr, us: []uint64{math.MaxUint64}}),
+		}
+		ce.callNativeFunc(testCtx, &wasm.CallContext{}, f)
+		hi, lo := ce.popValue(), ce.popValue()
+		binary.LittleEndian.PutUint64(ret[:8], lo)
+		binary.LittleEndian.PutUint64(ret[8:], hi)
+		return
+	}
+
+	t.Run("bitselect", func(t *testing.T) {
+		v1 := [16]byte{0xff, 0xff, 0xff, 0xff, 0x0f, 0x0f, 0x0f, 0x0f, 1, 2, 3, 4, 5, 6, 7, 8}
+		v2 := [16]byte{0x00, 0x00, 0x00, 0x00, 0xf0, 0xf0, 0xf0, 0xf0, 9, 10, 11, 12, 13, 14, 15, 16}
+		// Bits set in c select from v1, and bits clear select from v2.
+		c := [16]byte{0xaa, 0x55, 0xff, 0x00, 0xff, 0x00, 0x0f, 0xf0, 0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0}
+
+		actual := callV128(v128(v1), v128(v2), v128(c), &interpreterOp{kind: wazeroir.OperationKindV128Bitselect})
+		require.Equal(t, [16]byte{0xaa, 0x55, 0xff, 0x00, 0x0f, 0xf0, 0xff, 0x00, 1, 2, 3, 4, 13, 14, 15, 16}, actual)
+	})
+
+	t.Run("shuffle", func(t *testing.T) {
+		v1 := [16]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+		v2 := [16]byte{16, 17, 18, 19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31}
+		// Lanes 0-15 select from v1 and lanes 16-31 select from v2. This mask interleaves the low halves, reversed.
+		lanes := []uint64{7, 23, 6, 22, 5, 21, 4, 20, 3, 19, 2, 18, 1, 17, 0, 16}
+
+		actual := callV128(v128(v1), v128(v2), &interpreterOp{kind: wazeroir.OperationKindV128Shuffle, us: lanes})
+		require.Equal(t, [16]byte{7, 23, 6, 22, 5, 21, 4, 20, 3, 19, 2, 18, 1, 17, 0, 16}, actual)
+	})
+
+	t.Run("swizzle", func(t *testing.T) {
+		v := [16]byte{0xa0, 0xa1, 0xa2, 0xa3, 0xa4, 0xa5, 0xa6, 0xa7, 0xa8, 0xa9, 0xaa, 0xab, 0xac, 0xad, 0xae, 0xaf}
+		// Indexes out of range, ex. 16 or 0xff, result in zero.
+		idx := [16]byte{15, 14, 13, 12, 0, 1, 2, 3, 16, 0xff, 8, 8, 8, 8, 7, 7}
+
+		actual := callV128(v128(v), v128(idx), &interpreterOp{kind: wazeroir.OperationKindV128Swizzle})
+		require.Equal(t, [16]byte{0xaf, 0xae, 0xad, 0xac, 0xa0, 0xa1, 0xa2, 0xa3, 0, 0, 0xa8, 0xa8, 0xa8, 0xa8, 0xa7, 0xa7}, actual)
+	})
+}
+
 func TestInterpreter_Compile(t *testing.T) {
 	t.Run("uncompiled", func(t *testing.T) {
 		e := et.NewEngine(wasm.Features20191205).(*engine)
@@ -504,3 +557,35 @@ func TestEngine_CachedcodesPerModule(t *testing.T) {
 	_, ok = e.getCodes(m)
 	require.False(t, ok)
 }
+
+func TestInterpreter_lowerIR_Instrument(t *testing.T) {
+	label := &wazeroir.Label{Kind: wazeroir.LabelKindHeader}
+	ir := &wazeroir.CompilationResult{Operations: []wazeroir.Operation{
+		&wazeroir.OperationUnreachable{},
+		&wazeroir.OperationLabel{Label: label},
+		&wazeroir.OperationBr{Target: &wazeroir.BranchTarget{Label: label}},
+	}}
+
+	t.Run("none", func(t *testing.T) {
+		e := NewEngine(wasm.Features20191205).(*engine)
+		c, err := e.lowerIR(ir)
+		require.NoError(t, err)
+		require.Equal(t, 2, len(c.body))
+		require.Equal(t, wazeroir.OperationKindUnreachable, c.body[0].kind)
+		require.Equal(t, wazeroir.OperationKindBr, c.body[1].kind)
+		require.Equal(t, uint64(1), c.body[1].us[0])
+	})
+
+	t.Run("metrics", func(t *testing.T) {
+		e := NewEngineWithOptions(wasm.Features20191205, EngineOptions{Metrics: NewMetrics()}).(*engine)
+		c, err := e.lowerIR(ir)
+		require.NoError(t, err)
+		require.Equal(t, 4, len(c.body))
+		require.Equal(t, operationKindInstrument, c.body[0].kind)
+		require.Equal(t, wazeroir.OperationKindUnreachable, c.body[1].kind)
+		require.Equal(t, operationKindInstrument, c.body[2].kind)
+		require.Equal(t, wazeroir.OperationKindBr, c.body[3].kind)
+		// The branch target is the instrumentation before the operation at the label.
+		require.Equal(t, uint64(2), c.body[3].us[0])
+	})
+}
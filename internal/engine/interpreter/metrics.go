@@ -0,0 +1,43 @@
+package interpreter
+
+import (
+	"sync/atomic"
+
+	"github.com/tetratelabs/wazero/internal/wazeroir"
+)
+
+// Metrics counts the operations executed by the interpreter, by kind.
+//
+// Note: This is goroutine-safe, so the same Metrics can be shared by concurrent calls.
+type Metrics struct {
+	counts [wazeroir.OperationKindEnd]uint64
+}
+
+// NewMetrics returns Metrics with all counts at zero.
+func NewMetrics() *Metrics {
+	return &Metrics{}
+}
+
+// add increments the count of the given kind.
+func (m *Metrics) add(kind wazeroir.OperationKind) {
+	atomic.AddUint64(&m.counts[kind], 1)
+}
+
+// Counts returns the number of times each kind of operation was executed, keyed by wazeroir.OperationKind String.
+// Kinds that were never executed are not included.
+func (m *Metrics) Counts() map[string]uint64 {
+	ret := map[string]uint64{}
+	for kind := range m.counts {
+		if count := atomic.LoadUint64(&m.counts[kind]); count > 0 {
+			ret[wazeroir.OperationKind(kind).String()] = count
+		}
+	}
+	return ret
+}
+
+// Reset sets all counts back to zero.
+func (m *Metrics) Reset() {
+	for kind := range m.counts {
+		atomic.StoreUint64(&m.counts[kind], 0)
+	}
+}
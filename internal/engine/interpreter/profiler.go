@@ -0,0 +1,189 @@
+package interpreter
+
+import (
+	"compress/gzip"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/leb128"
+)
+
+// Profiler accumulates the time the interpreter spends in each call stack, and writes it as a pprof profile.
+//
+// The interpreter samples at call boundaries: each time a function is called or returns, the time since the previous
+// boundary is attributed to the call stack that was running. This is exact, as opposed to periodic sampling, but only
+// measures elapsed time, so includes any time the goroutine was descheduled.
+//
+// Note: This is goroutine-safe, so the same Profiler can be shared by concurrent calls.
+// Note: A host function that calls back into the interpreter starts a new call stack, so the stack of the guest
+// function that called the host function is not included in the samples of that nested call.
+type Profiler struct {
+	mux     sync.Mutex
+	start   time.Time
+	samples map[string]*profileSample // keyed by the function names of the stack, joined by newline.
+	order   []*profileSample          // samples in the order they were first seen, for deterministic output.
+}
+
+// profileSample is the time attributed to a call stack.
+type profileSample struct {
+	// stack are the function names in the call stack, leaf first, per pprof.
+	stack []string
+	count int64
+	nanos int64
+}
+
+// NewProfiler returns a Profiler with no samples.
+func NewProfiler() *Profiler {
+	return &Profiler{start: time.Now(), samples: map[string]*profileSample{}}
+}
+
+// sample attributes the time since the previous call boundary of ce to its current call stack. This is called before
+// each frame is pushed or popped.
+func (p *Profiler) sample(ce *callEngine) {
+	now := time.Now()
+	if len(ce.frames) > 0 && !ce.lastSample.IsZero() {
+		p.add(ce.frames, now.Sub(ce.lastSample))
+	}
+	ce.lastSample = now
+}
+
+// add attributes the elapsed time to the call stack of the given frames.
+func (p *Profiler) add(frames []*callFrame, elapsed time.Duration) {
+	stack := make([]string, len(frames))
+	for i, frame := range frames {
+		stack[len(frames)-1-i] = frame.f.source.DebugName
+	}
+	key := strings.Join(stack, "\n")
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	s, ok := p.samples[key]
+	if !ok {
+		s = &profileSample{stack: stack}
+		p.samples[key] = s
+		p.order = append(p.order, s)
+	}
+	s.count++
+	s.nanos += int64(elapsed)
+}
+
+// WriteProfile writes the samples to w as a gzipped pprof protobuf, readable by `go tool pprof`.
+//
+// See https://github.com/google/pprof/blob/main/proto/profile.proto
+func (p *Profiler) WriteProfile(w io.Writer) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	var b profileBuilder
+	b.strings = map[string]int64{"": 0}
+	b.stringTable = []string{""}
+	b.functions = map[string]uint64{}
+
+	// Profile.sample_type: the count of samples and the time attributed to them.
+	b.message(1, b.valueType("samples", "count"))
+	b.message(1, b.valueType("cpu", "nanoseconds"))
+
+	for _, s := range p.order {
+		var locationIDs, values []byte
+		for _, name := range s.stack {
+			locationIDs = append(locationIDs, leb128.EncodeUint64(b.function(name))...)
+		}
+		values = append(values, leb128.EncodeUint64(uint64(s.count))...)
+		values = append(values, leb128.EncodeUint64(uint64(s.nanos))...)
+
+		var sample protoBuffer
+		sample.bytes(1, locationIDs) // Sample.location_id, packed
+		sample.bytes(2, values)      // Sample.value, packed
+		b.message(2, sample)
+	}
+
+	// Each function has a location of the same ID, as there are no addresses or line numbers.
+	for id := uint64(1); id <= uint64(len(b.functionNames)); id++ {
+		var line, location, function protoBuffer
+		line.varint(1, id) // Line.function_id
+		location.varint(1, id)
+		location.message(4, line) // Location.line
+		b.message(4, location)
+
+		function.varint(1, id)
+		function.varint(2, uint64(b.str(b.functionNames[id-1]))) // Function.name
+		function.varint(3, uint64(b.str(b.functionNames[id-1]))) // Function.system_name
+		b.message(5, function)
+	}
+
+	// Now that all strings are known, write the string table.
+	for _, s := range b.stringTable {
+		b.bytes(6, []byte(s))
+	}
+	b.varint(9, uint64(p.start.UnixNano()))                 // Profile.time_nanos
+	b.varint(10, uint64(time.Since(p.start).Nanoseconds())) // Profile.duration_nanos
+
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(b.buf); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// profileBuilder builds a pprof Profile message, tracking the IDs of strings and functions.
+type profileBuilder struct {
+	protoBuffer
+	strings       map[string]int64
+	stringTable   []string
+	functions     map[string]uint64
+	functionNames []string
+}
+
+// str returns the index of s in the string table, adding it if needed.
+func (b *profileBuilder) str(s string) int64 {
+	if i, ok := b.strings[s]; ok {
+		return i
+	}
+	i := int64(len(b.stringTable))
+	b.strings[s] = i
+	b.stringTable = append(b.stringTable, s)
+	return i
+}
+
+// function returns the ID of the function with the given name, adding it if needed.
+func (b *profileBuilder) function(name string) uint64 {
+	if id, ok := b.functions[name]; ok {
+		return id
+	}
+	b.functionNames = append(b.functionNames, name)
+	id := uint64(len(b.functionNames))
+	b.functions[name] = id
+	return id
+}
+
+// valueType returns a ValueType message.
+func (b *profileBuilder) valueType(typ, unit string) (ret protoBuffer) {
+	ret.varint(1, uint64(b.str(typ)))
+	ret.varint(2, uint64(b.str(unit)))
+	return
+}
+
+// protoBuffer encodes the protobuf fields needed for a pprof profile.
+type protoBuffer struct {
+	buf []byte
+}
+
+// varint encodes a field of wire type 0 (varint).
+func (p *protoBuffer) varint(field int, v uint64) {
+	p.buf = append(p.buf, leb128.EncodeUint64(uint64(field)<<3)...)
+	p.buf = append(p.buf, leb128.EncodeUint64(v)...)
+}
+
+// bytes encodes a field of wire type 2 (length-delimited).
+func (p *protoBuffer) bytes(field int, b []byte) {
+	p.buf = append(p.buf, leb128.EncodeUint64(uint64(field)<<3|2)...)
+	p.buf = append(p.buf, leb128.EncodeUint64(uint64(len(b)))...)
+	p.buf = append(p.buf, b...)
+}
+
+// message encodes an embedded message field.
+func (p *protoBuffer) message(field int, m protoBuffer) {
+	p.bytes(field, m.buf)
+}
@@ -0,0 +1,89 @@
+package interpreter
+
+// Stepper pauses the interpreter before each operation until Step allows it to continue, for interactive debugging.
+//
+// While a Stepper is in use, a call blocks before its first operation until Step is called from another goroutine.
+// This includes start functions called during instantiation. Each Step then returns when the interpreter paused again,
+// with the position of the next operation to execute.
+//
+// Note: This is meant to debug one call at a time. The fields below aren't synchronized, so concurrent calls race.
+type Stepper struct {
+	// grant receives the count of operations Step allows the interpreter to execute.
+	grant chan uint64
+	// paused receives true when the interpreter executed the operations of Step, or false when the call returned.
+	paused chan bool
+
+	// The fields below are only written by the goroutine of the call, and read by Step after paused receives.
+
+	// remaining is the count of operations to execute before pausing.
+	remaining uint64
+	// depth is the count of calls in progress, as a host function can call back into the interpreter.
+	depth int
+	// stepping is true when Step is waiting to receive from paused.
+	stepping bool
+
+	functionIndex uint32
+	pc            uint64
+	stack         []uint64
+}
+
+// NewStepper returns a Stepper which pauses before the first operation of the next call.
+func NewStepper() *Stepper {
+	return &Stepper{grant: make(chan uint64), paused: make(chan bool)}
+}
+
+// Step allows the interpreter to execute n operations, then blocks until it has. This returns false if the call
+// returned before that. Step blocks until a call is in progress.
+func (s *Stepper) Step(n uint64) bool {
+	s.grant <- n
+	return <-s.paused
+}
+
+// FunctionIndex returns the module-scoped index of the function of the next operation to execute.
+func (s *Stepper) FunctionIndex() uint32 {
+	return s.functionIndex
+}
+
+// PC returns the index of the next operation to execute in the function body, after translation to wazeroir.
+func (s *Stepper) PC() uint64 {
+	return s.pc
+}
+
+// Stack returns a copy of the value stack, which includes the locals of each function in the call stack. The last
+// value is the top of the stack.
+func (s *Stepper) Stack() []uint64 {
+	return s.stack
+}
+
+// enter is called when a call begins.
+func (s *Stepper) enter() {
+	s.depth++
+}
+
+// exit is called when a call returns, even if it failed.
+func (s *Stepper) exit() {
+	s.depth--
+	if s.depth > 0 {
+		return // still in the outermost call.
+	}
+	s.remaining = 0 // Don't carry over steps to the next call.
+	if s.stepping {
+		s.stepping = false
+		s.paused <- false
+	}
+}
+
+// before is called before the interpreter executes the operation at pc of the function at functionIndex.
+func (s *Stepper) before(ce *callEngine, functionIndex uint32, pc uint64) {
+	for s.remaining == 0 {
+		if s.stepping {
+			s.functionIndex, s.pc = functionIndex, pc
+			s.stack = append(make([]uint64, 0, len(ce.stack)), ce.stack...)
+			s.stepping = false
+			s.paused <- true
+		}
+		s.remaining = <-s.grant
+		s.stepping = true
+	}
+	s.remaining--
+}
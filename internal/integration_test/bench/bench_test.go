@@ -35,6 +35,46 @@ func BenchmarkInvocation(b *testing.B) {
 	}
 }
 
+// BenchmarkCallWithStack compares allocations of api.Function Call with CallWithStack, reusing the same stack.
+func BenchmarkCallWithStack(b *testing.B) {
+	b.Run("interpreter", func(b *testing.B) {
+		m := instantiateHostFunctionModuleWithEngine(b, wazero.NewRuntimeConfigInterpreter())
+		defer m.Close(testCtx)
+		runCallWithStackBenches(b, m)
+	})
+	if runtime.GOARCH == "amd64" || runtime.GOARCH == "arm64" {
+		b.Run("compiler", func(b *testing.B) {
+			m := instantiateHostFunctionModuleWithEngine(b, wazero.NewRuntimeConfigCompiler())
+			defer m.Close(testCtx)
+			runCallWithStackBenches(b, m)
+		})
+	}
+}
+
+func runCallWithStackBenches(b *testing.B, m api.Module) {
+	fibonacci := m.ExportedFunction("fibonacci")
+
+	b.Run("Call", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := fibonacci.Call(testCtx, 5); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("CallWithStack", func(b *testing.B) {
+		stack := make([]uint64, 1)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			stack[0] = 5
+			if err := fibonacci.CallWithStack(testCtx, stack); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
 func BenchmarkInitialization(b *testing.B) {
 	b.Run("interpreter", func(b *testing.B) {
 		r := createRuntime(b, wazero.NewRuntimeConfigInterpreter())
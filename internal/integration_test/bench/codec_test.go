@@ -90,6 +90,9 @@ func TestExampleUpToDate(t *testing.T) {
 	t.Run("binary.DecodeModule", func(t *testing.T) {
 		m, err := binary.DecodeModule(exampleWasm, wasm.Features20220419, wasm.MemorySizer)
 		require.NoError(t, err)
+		for _, c := range m.CodeSection {
+			c.BodyOffset = 0 // the example isn't decoded, so has no offsets.
+		}
 		require.Equal(t, example, m)
 	})
 
@@ -0,0 +1,98 @@
+package bench
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	binaryformat "github.com/tetratelabs/wazero/internal/wasm/binary"
+)
+
+// hostCallWasm exports functions which only call the host function of the same name imported from "env":
+//   - "nanotime" returns an i64, like a clock.
+//   - "add" returns the sum of its two i32 parameters, and also accepts the context and module.
+var hostCallWasm = func() []byte {
+	i32, i64 := wasm.ValueTypeI32, wasm.ValueTypeI64
+	return binaryformat.EncodeModule(&wasm.Module{
+		TypeSection: []*wasm.FunctionType{
+			{Results: []wasm.ValueType{i64}},
+			{Params: []wasm.ValueType{i32, i32}, Results: []wasm.ValueType{i32}},
+		},
+		ImportSection: []*wasm.Import{
+			{Module: "env", Name: "nanotime", Type: wasm.ExternTypeFunc, DescFunc: 0},
+			{Module: "env", Name: "add", Type: wasm.ExternTypeFunc, DescFunc: 1},
+		},
+		FunctionSection: []wasm.Index{0, 1},
+		CodeSection: []*wasm.Code{
+			{Body: []byte{wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeCall, 1, wasm.OpcodeEnd}},
+		},
+		ExportSection: []*wasm.Export{
+			{Name: "nanotime", Type: wasm.ExternTypeFunc, Index: 2},
+			{Name: "add", Type: wasm.ExternTypeFunc, Index: 3},
+		},
+	})
+}()
+
+// BenchmarkHostCall measures the allocations of calling host functions from Wasm, using CallWithStack to exclude those
+// of api.Function Call.
+func BenchmarkHostCall(b *testing.B) {
+	b.Run("interpreter", func(b *testing.B) {
+		m := instantiateHostCallModule(b, wazero.NewRuntimeConfigInterpreter())
+		defer m.Close(testCtx)
+		runHostCallBenches(b, m)
+	})
+	if runtime.GOARCH == "amd64" || runtime.GOARCH == "arm64" {
+		b.Run("compiler", func(b *testing.B) {
+			m := instantiateHostCallModule(b, wazero.NewRuntimeConfigCompiler())
+			defer m.Close(testCtx)
+			runHostCallBenches(b, m)
+		})
+	}
+}
+
+func runHostCallBenches(b *testing.B, m api.Module) {
+	nanotime := m.ExportedFunction("nanotime")
+	b.Run("nanotime", func(b *testing.B) {
+		stack := make([]uint64, 1)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := nanotime.CallWithStack(testCtx, stack); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	add := m.ExportedFunction("add")
+	b.Run("add", func(b *testing.B) {
+		stack := make([]uint64, 2)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			stack[0], stack[1] = 1, 2
+			if err := add.CallWithStack(testCtx, stack); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func instantiateHostCallModule(b *testing.B, config wazero.RuntimeConfig) api.Module {
+	r := wazero.NewRuntimeWithConfig(config)
+
+	_, err := r.NewModuleBuilder("env").
+		ExportFunction("nanotime", func() uint64 { return 1 }).
+		ExportFunction("add", func(ctx context.Context, m api.Module, x, y uint32) uint32 { return x + y }).
+		Instantiate(testCtx, r)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	m, err := r.InstantiateModuleFromBinary(testCtx, hostCallWasm)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return m
+}
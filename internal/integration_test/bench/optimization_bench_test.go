@@ -0,0 +1,52 @@
+package bench
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+)
+
+// BenchmarkOptimizationLevel compares the time to compile caseWasm, then run "fibonacci" once, at each
+// wazero.OptimizationLevel. This is the cost a short-lived host, such as a CLI, pays on each invocation.
+func BenchmarkOptimizationLevel(b *testing.B) {
+	if runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64" {
+		b.Skip()
+	}
+
+	levels := []struct {
+		name  string
+		level wazero.OptimizationLevel
+	}{
+		{name: "minimal", level: wazero.OptimizationLevelMinimal},
+		{name: "full", level: wazero.OptimizationLevelFull},
+	}
+
+	for _, l := range levels {
+		level := l.level
+		b.Run(l.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				// A new runtime per iteration, as compilation is cached per runtime.
+				r := createRuntime(b, wazero.NewRuntimeConfigCompiler())
+
+				compiled, err := r.CompileModule(testCtx, caseWasm, wazero.NewCompileConfig().WithOptimizationLevel(level))
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				m, err := r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig())
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				if _, err = m.ExportedFunction("fibonacci").Call(testCtx, 20); err != nil {
+					b.Fatal(err)
+				}
+
+				if err = r.Close(testCtx); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
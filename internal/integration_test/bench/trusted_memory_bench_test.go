@@ -0,0 +1,121 @@
+package bench
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	binaryformat "github.com/tetratelabs/wazero/internal/wasm/binary"
+)
+
+// kernelN is the count of i32 values "kernel" stores then loads, which fits in one page of memory.
+const kernelN = 16000
+
+// kernelWasm exports "kernel", which stores i*3 for each i below its parameter n into memory, then returns the sum of
+// loading them back. This is mostly loads and stores, so dominated by their bounds checks.
+var kernelWasm = func() []byte {
+	i32, i64 := wasm.ValueTypeI32, wasm.ValueTypeI64
+	const n, i, sum = 0, 1, 2 // local indices
+
+	// incrementAndLoop is "i++; if i < n { continue }" of the enclosing loop.
+	incrementAndLoop := []byte{
+		wasm.OpcodeLocalGet, i, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Add, wasm.OpcodeLocalTee, i,
+		wasm.OpcodeLocalGet, n, wasm.OpcodeI32LtU, wasm.OpcodeBrIf, 0,
+	}
+
+	body := []byte{wasm.OpcodeLoop, 0x40}
+	// mem[4+i*4] = i*3
+	body = append(body, wasm.OpcodeLocalGet, i, wasm.OpcodeI32Const, 2, wasm.OpcodeI32Shl,
+		wasm.OpcodeLocalGet, i, wasm.OpcodeI32Const, 3, wasm.OpcodeI32Mul,
+		wasm.OpcodeI32Store, 2, 4) // align=2, offset=4
+	body = append(body, incrementAndLoop...)
+	body = append(body, wasm.OpcodeEnd, wasm.OpcodeI32Const, 0, wasm.OpcodeLocalSet, i, wasm.OpcodeLoop, 0x40)
+	// sum += mem[4+i*4]
+	body = append(body, wasm.OpcodeLocalGet, sum,
+		wasm.OpcodeLocalGet, i, wasm.OpcodeI32Const, 2, wasm.OpcodeI32Shl,
+		wasm.OpcodeI64Load32U, 2, 4, // align=2, offset=4
+		wasm.OpcodeI64Add, wasm.OpcodeLocalSet, sum)
+	body = append(body, incrementAndLoop...)
+	body = append(body, wasm.OpcodeEnd, wasm.OpcodeLocalGet, sum, wasm.OpcodeEnd)
+
+	return binaryformat.EncodeModule(&wasm.Module{
+		TypeSection:     []*wasm.FunctionType{{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i64}}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []*wasm.Code{{Body: body, LocalTypes: []wasm.ValueType{i32, i64}}},
+		MemorySection:   &wasm.Memory{Min: 1},
+		ExportSection:   []*wasm.Export{{Name: "kernel", Type: wasm.ExternTypeFunc, Index: 0}},
+	})
+}()
+
+// instantiateKernel compiles kernelWasm with or without wazero.CompileConfig WithTrustedMemory.
+func instantiateKernel(tb testing.TB, r wazero.Runtime, trusted bool) api.Function {
+	compiled, err := r.CompileModule(testCtx, kernelWasm, wazero.NewCompileConfig().WithTrustedMemory(trusted))
+	require.NoError(tb, err)
+
+	// Use a different name per mode, as both may be instantiated in the same runtime.
+	name := "untrusted"
+	if trusted {
+		name = "trusted"
+	}
+	m, err := r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig().WithName(name))
+	require.NoError(tb, err)
+	return m.ExportedFunction("kernel")
+}
+
+// TestTrustedMemory ensures in-bounds accesses have the same results whether or not bounds are checked.
+func TestTrustedMemory(t *testing.T) {
+	for _, config := range []wazero.RuntimeConfig{wazero.NewRuntimeConfigInterpreter(), wazero.NewRuntimeConfig()} {
+		r := wazero.NewRuntimeWithConfig(config)
+
+		trusted, untrusted := instantiateKernel(t, r, true), instantiateKernel(t, r, false)
+		for _, n := range []uint64{1, 2, 100, kernelN} {
+			expected := 3 * n * (n - 1) / 2
+
+			results, err := untrusted.Call(testCtx, n)
+			require.NoError(t, err)
+			require.Equal(t, expected, results[0])
+
+			results, err = trusted.Call(testCtx, n)
+			require.NoError(t, err)
+			require.Equal(t, expected, results[0])
+		}
+
+		// Bounds are still checked without trusted memory, even though the same binary was compiled with it.
+		_, err := untrusted.Call(testCtx, uint64(wasm.MemoryPageSize))
+		require.Error(t, err)
+
+		require.NoError(t, r.Close(testCtx))
+	}
+}
+
+// BenchmarkTrustedMemory compares the time to run "kernel" with and without wazero.CompileConfig WithTrustedMemory.
+func BenchmarkTrustedMemory(b *testing.B) {
+	if runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64" {
+		b.Skip()
+	}
+
+	for _, tc := range []struct {
+		name    string
+		trusted bool
+	}{
+		{name: "checked", trusted: false},
+		{name: "trusted", trusted: true},
+	} {
+		trusted := tc.trusted
+		b.Run(tc.name, func(b *testing.B) {
+			r := wazero.NewRuntimeWithConfig(wazero.NewRuntimeConfigCompiler())
+			defer r.Close(testCtx)
+
+			kernel := instantiateKernel(b, r, trusted)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := kernel.Call(testCtx, kernelN); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
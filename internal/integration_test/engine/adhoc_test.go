@@ -3,17 +3,22 @@ package adhoc
 import (
 	"context"
 	_ "embed"
+	"errors"
 	"fmt"
 	"math"
+	"math/bits"
 	"strconv"
+	"strings"
 	"testing"
 	"unsafe"
 
 	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/moremath"
 	"github.com/tetratelabs/wazero/internal/platform"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
+	binaryformat "github.com/tetratelabs/wazero/internal/wasm/binary"
 	"github.com/tetratelabs/wazero/internal/watzero"
 	"github.com/tetratelabs/wazero/sys"
 )
@@ -42,9 +47,26 @@ var tests = map[string]func(t *testing.T, r wazero.Runtime){
 	"host function with nested context":                 testNestedGoContext,
 	"host function with numeric parameter":              testHostFunctionNumericParameter,
 	"close module with in-flight calls":                 testCloseInFlight,
+	"close module with scheduled calls":                 testCloseScheduled,
 	"multiple instantiation from same source":           testMultipleInstantiation,
 	"exported function that grows memory":               testMemOps,
 	"import functions with reference type in signature": testReftypeImports,
+	"non-trapping float-to-int conversions":             testNonTrappingFloatToIntConversion,
+	"atomic instructions":                               testAtomics,
+	"bulk table instructions":                           testBulkTable,
+	"trap reasons":                                      testTrapReasons,
+	"imported global from host module":                  testImportedGlobal,
+	"imported mutable global from host module":          testImportedMutableGlobal,
+	"reinterpret preserves NaN bits":                    testReinterpretNaN,
+	"typed select between externrefs":                   testTypedSelect,
+	"rotate and bit counting edge cases":                testRotateAndCount,
+	"min and max of signed zeros and NaN":               testMinMax,
+	"nearest rounds half to even":                       testNearest,
+	"table grow with max table elements":                testTableGrow,
+	"host function results replace params":              testHostFunctionResults,
+	"ref.is_null on funcref and externref":              testRefIsNull,
+	"integer division and remainder edge cases":         testDivRem,
+	"unreachable only traps when reached":               testUnreachableDeadCode,
 }
 
 func TestEngineCompiler(t *testing.T) {
@@ -59,7 +81,8 @@ func TestEngineInterpreter(t *testing.T) {
 }
 
 func runAllTests(t *testing.T, tests map[string]func(t *testing.T, r wazero.Runtime), config wazero.RuntimeConfig) {
-	config = config.WithFeatureReferenceTypes(true)
+	config = config.WithFeatureReferenceTypes(true).WithFeatureNonTrappingFloatToIntConversion(true).
+		WithFeatureThreads(true).WithFeatureMultiValue(true)
 	for name, testf := range tests {
 		name := name   // pin
 		testf := testf // pin
@@ -108,6 +131,290 @@ func testReftypeImports(t *testing.T, r wazero.Runtime) {
 	require.Equal(t, uintptr(unsafe.Pointer(hostObj)), uintptr(actual[0]))
 }
 
+// testNonTrappingFloatToIntConversion ensures the saturating truncation instructions don't trap, rather return zero on
+// NaN and the min or max integer on overflow, including infinity.
+func testNonTrappingFloatToIntConversion(t *testing.T, r wazero.Runtime) {
+	var funcs []string
+	for _, in := range []string{"f32", "f64"} {
+		for _, out := range []string{"i32", "i64"} {
+			for _, sign := range []string{"s", "u"} {
+				name := fmt.Sprintf("%s.trunc_sat_%s_%s", out, in, sign)
+				funcs = append(funcs, fmt.Sprintf(`(func $%[1]s (param %[2]s) (result %[3]s) local.get 0 %[1]s)
+  (export "%[1]s" (func $%[1]s))`, name, in, out))
+			}
+		}
+	}
+	module, err := r.InstantiateModuleFromBinary(testCtx, wat2wasm("(module\n  "+strings.Join(funcs, "\n  ")+"\n)"))
+	require.NoError(t, err)
+	defer module.Close(testCtx)
+
+	nan32, inf32, big32 := api.EncodeF32(float32(math.NaN())), api.EncodeF32(float32(math.Inf(1))), api.EncodeF32(1e30)
+	ninf32, nbig32 := api.EncodeF32(float32(math.Inf(-1))), api.EncodeF32(-1e30)
+	nan64, inf64, big64 := api.EncodeF64(math.NaN()), api.EncodeF64(math.Inf(1)), api.EncodeF64(1e300)
+	ninf64, nbig64 := api.EncodeF64(math.Inf(-1)), api.EncodeF64(-1e300)
+
+	tests := []struct {
+		name     string
+		input    uint64
+		expected uint64
+	}{
+		{name: "i32.trunc_sat_f32_s", input: nan32, expected: 0},
+		{name: "i32.trunc_sat_f32_s", input: inf32, expected: math.MaxInt32},
+		{name: "i32.trunc_sat_f32_s", input: ninf32, expected: api.EncodeI32(math.MinInt32)},
+		{name: "i32.trunc_sat_f32_s", input: big32, expected: math.MaxInt32},
+		{name: "i32.trunc_sat_f32_s", input: nbig32, expected: api.EncodeI32(math.MinInt32)},
+		{name: "i32.trunc_sat_f32_u", input: nan32, expected: 0},
+		{name: "i32.trunc_sat_f32_u", input: inf32, expected: math.MaxUint32},
+		{name: "i32.trunc_sat_f32_u", input: ninf32, expected: 0},
+		{name: "i32.trunc_sat_f32_u", input: big32, expected: math.MaxUint32},
+		{name: "i32.trunc_sat_f64_s", input: nan64, expected: 0},
+		{name: "i32.trunc_sat_f64_s", input: inf64, expected: math.MaxInt32},
+		{name: "i32.trunc_sat_f64_s", input: ninf64, expected: api.EncodeI32(math.MinInt32)},
+		{name: "i32.trunc_sat_f64_s", input: big64, expected: math.MaxInt32},
+		{name: "i32.trunc_sat_f64_u", input: nan64, expected: 0},
+		{name: "i32.trunc_sat_f64_u", input: inf64, expected: math.MaxUint32},
+		{name: "i32.trunc_sat_f64_u", input: nbig64, expected: 0},
+		{name: "i64.trunc_sat_f32_s", input: nan32, expected: 0},
+		{name: "i64.trunc_sat_f32_s", input: inf32, expected: math.MaxInt64},
+		{name: "i64.trunc_sat_f32_s", input: ninf32, expected: api.EncodeI64(math.MinInt64)},
+		{name: "i64.trunc_sat_f32_s", input: big32, expected: math.MaxInt64},
+		{name: "i64.trunc_sat_f32_u", input: nan32, expected: 0},
+		{name: "i64.trunc_sat_f32_u", input: inf32, expected: math.MaxUint64},
+		{name: "i64.trunc_sat_f32_u", input: ninf32, expected: 0},
+		{name: "i64.trunc_sat_f64_s", input: nan64, expected: 0},
+		{name: "i64.trunc_sat_f64_s", input: inf64, expected: math.MaxInt64},
+		{name: "i64.trunc_sat_f64_s", input: ninf64, expected: api.EncodeI64(math.MinInt64)},
+		{name: "i64.trunc_sat_f64_s", input: nbig64, expected: api.EncodeI64(math.MinInt64)},
+		{name: "i64.trunc_sat_f64_u", input: nan64, expected: 0},
+		{name: "i64.trunc_sat_f64_u", input: inf64, expected: math.MaxUint64},
+		{name: "i64.trunc_sat_f64_u", input: big64, expected: math.MaxUint64},
+		{name: "i64.trunc_sat_f64_u", input: ninf64, expected: 0},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		results, err := module.ExportedFunction(tc.name).Call(testCtx, tc.input)
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.expected, results[0], "%s(%#x)", tc.name, tc.input)
+	}
+}
+
+// atomicsWasm exports functions that each run one atomic instruction against memory, which has no text format in
+// watzero, yet.
+var atomicsWasm = binaryformat.EncodeModule(&wasm.Module{
+	TypeSection: []*wasm.FunctionType{
+		{Params: []wasm.ValueType{wasm.ValueTypeI32, wasm.ValueTypeI32}, Results: []wasm.ValueType{wasm.ValueTypeI32}},
+		{Params: []wasm.ValueType{wasm.ValueTypeI32, wasm.ValueTypeI64}, Results: []wasm.ValueType{wasm.ValueTypeI64}},
+		{Params: []wasm.ValueType{wasm.ValueTypeI32, wasm.ValueTypeI32, wasm.ValueTypeI32}, Results: []wasm.ValueType{wasm.ValueTypeI32}},
+		{Params: []wasm.ValueType{wasm.ValueTypeI32}, Results: []wasm.ValueType{wasm.ValueTypeI32}},
+	},
+	FunctionSection: []wasm.Index{0, 1, 2, 3},
+	CodeSection: []*wasm.Code{
+		{Body: []byte{ // i32.atomic.rmw.add
+			wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeAtomicPrefix, wasm.OpcodeAtomicI32RmwAdd, 0x2, 0x0, // align=2, offset=0
+			wasm.OpcodeEnd,
+		}},
+		{Body: []byte{ // i64.atomic.rmw.xchg
+			wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeAtomicPrefix, wasm.OpcodeAtomicI64RmwXchg, 0x3, 0x0, // align=3, offset=0
+			wasm.OpcodeEnd,
+		}},
+		{Body: []byte{ // i32.atomic.rmw8.cmpxchg_u
+			wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 2,
+			wasm.OpcodeAtomicPrefix, wasm.OpcodeAtomicI32Rmw8CmpxchgU, 0x0, 0x0, // align=0, offset=0
+			wasm.OpcodeEnd,
+		}},
+		{Body: []byte{ // atomic.fence, then i32.atomic.load
+			wasm.OpcodeAtomicPrefix, wasm.OpcodeAtomicFence, 0x0,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeAtomicPrefix, wasm.OpcodeAtomicI32Load, 0x2, 0x0, // align=2, offset=0
+			wasm.OpcodeEnd,
+		}},
+	},
+	MemorySection: &wasm.Memory{Min: 1},
+	ExportSection: []*wasm.Export{
+		{Name: "i32.atomic.rmw.add", Type: wasm.ExternTypeFunc, Index: 0},
+		{Name: "i64.atomic.rmw.xchg", Type: wasm.ExternTypeFunc, Index: 1},
+		{Name: "i32.atomic.rmw8.cmpxchg_u", Type: wasm.ExternTypeFunc, Index: 2},
+		{Name: "i32.atomic.load", Type: wasm.ExternTypeFunc, Index: 3},
+		{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0},
+	},
+})
+
+func testAtomics(t *testing.T, r wazero.Runtime) {
+	module, err := r.InstantiateModuleFromBinary(testCtx, atomicsWasm)
+	require.NoError(t, err)
+	defer module.Close(testCtx)
+
+	mem := module.ExportedMemory("memory")
+
+	t.Run("i32.atomic.rmw.add", func(t *testing.T) {
+		require.True(t, mem.WriteUint32Le(testCtx, 8, 40))
+
+		results, err := module.ExportedFunction("i32.atomic.rmw.add").Call(testCtx, 8, 2)
+		require.NoError(t, err)
+		require.Equal(t, uint64(40), results[0]) // returns the value before the addition
+
+		v, ok := mem.ReadUint32Le(testCtx, 8)
+		require.True(t, ok)
+		require.Equal(t, uint32(42), v)
+
+		// Same as non-atomic memory instructions, out of bounds access traps.
+		_, err = module.ExportedFunction("i32.atomic.rmw.add").Call(testCtx, uint64(mem.Size(testCtx)), 2)
+		require.Error(t, err)
+	})
+
+	t.Run("i64.atomic.rmw.xchg", func(t *testing.T) {
+		require.True(t, mem.WriteUint64Le(testCtx, 16, math.MaxUint64))
+
+		results, err := module.ExportedFunction("i64.atomic.rmw.xchg").Call(testCtx, 16, 1)
+		require.NoError(t, err)
+		require.Equal(t, uint64(math.MaxUint64), results[0])
+
+		v, ok := mem.ReadUint64Le(testCtx, 16)
+		require.True(t, ok)
+		require.Equal(t, uint64(1), v)
+	})
+
+	t.Run("i32.atomic.rmw8.cmpxchg_u", func(t *testing.T) {
+		require.True(t, mem.WriteUint32Le(testCtx, 24, 0xaabbcc01))
+		cmpxchg := module.ExportedFunction("i32.atomic.rmw8.cmpxchg_u")
+
+		// The expected value doesn't match, so memory is unchanged.
+		results, err := cmpxchg.Call(testCtx, 24, 2, 0xff)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), results[0])
+		v, _ := mem.ReadUint32Le(testCtx, 24)
+		require.Equal(t, uint32(0xaabbcc01), v)
+
+		// The expected value is wrapped to 8 bits before comparison, so this matches.
+		results, err = cmpxchg.Call(testCtx, 24, 0xf01, 0xff)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), results[0])
+		v, _ = mem.ReadUint32Le(testCtx, 24)
+		require.Equal(t, uint32(0xaabbccff), v)
+	})
+
+	t.Run("atomic.fence", func(t *testing.T) {
+		require.True(t, mem.WriteUint32Le(testCtx, 32, 7))
+
+		results, err := module.ExportedFunction("i32.atomic.load").Call(testCtx, 32)
+		require.NoError(t, err)
+		require.Equal(t, uint64(7), results[0])
+	})
+}
+
+// bulkTableWasm has a table initialized only by "table.init" from a passive element segment, so calls through it
+// are possible only after that.
+var bulkTableWasm = binaryformat.EncodeModule(&wasm.Module{
+	TypeSection: []*wasm.FunctionType{
+		{Results: []wasm.ValueType{wasm.ValueTypeI32}},
+		{Params: []wasm.ValueType{wasm.ValueTypeI32, wasm.ValueTypeI32, wasm.ValueTypeI32}},
+		{},
+		{Params: []wasm.ValueType{wasm.ValueTypeI32}, Results: []wasm.ValueType{wasm.ValueTypeI32}},
+	},
+	FunctionSection: []wasm.Index{0, 0, 1, 1, 2, 3},
+	CodeSection: []*wasm.Code{
+		{Body: []byte{wasm.OpcodeI32Const, 10, wasm.OpcodeEnd}},
+		{Body: []byte{wasm.OpcodeI32Const, 20, wasm.OpcodeEnd}},
+		{Body: []byte{ // table.init (dst, src, len)
+			wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 2,
+			wasm.OpcodeMiscPrefix, wasm.OpcodeMiscTableInit, 0, 0, // element segment 0, table 0
+			wasm.OpcodeEnd,
+		}},
+		{Body: []byte{ // table.copy (dst, src, len)
+			wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 2,
+			wasm.OpcodeMiscPrefix, wasm.OpcodeMiscTableCopy, 0, 0, // dst table 0, src table 0
+			wasm.OpcodeEnd,
+		}},
+		{Body: []byte{ // elem.drop
+			wasm.OpcodeMiscPrefix, wasm.OpcodeMiscElemDrop, 0, // element segment 0
+			wasm.OpcodeEnd,
+		}},
+		{Body: []byte{ // call_indirect (i)
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeCallIndirect, 0, 0, // type 0, table 0
+			wasm.OpcodeEnd,
+		}},
+	},
+	TableSection: []*wasm.Table{{Min: 4, Type: wasm.RefTypeFuncref}},
+	ElementSection: []*wasm.ElementSegment{{
+		Init: []*wasm.Index{uint32Ptr(0), uint32Ptr(1)},
+		Type: wasm.RefTypeFuncref,
+		Mode: wasm.ElementModePassive,
+	}},
+	ExportSection: []*wasm.Export{
+		{Name: "table.init", Type: wasm.ExternTypeFunc, Index: 2},
+		{Name: "table.copy", Type: wasm.ExternTypeFunc, Index: 3},
+		{Name: "elem.drop", Type: wasm.ExternTypeFunc, Index: 4},
+		{Name: "call_indirect", Type: wasm.ExternTypeFunc, Index: 5},
+	},
+})
+
+func uint32Ptr(v uint32) *uint32 {
+	return &v
+}
+
+func testBulkTable(t *testing.T, r wazero.Runtime) {
+	module, err := r.InstantiateModuleFromBinary(testCtx, bulkTableWasm)
+	require.NoError(t, err)
+	defer module.Close(testCtx)
+
+	tableInit := module.ExportedFunction("table.init")
+	tableCopy := module.ExportedFunction("table.copy")
+	elemDrop := module.ExportedFunction("elem.drop")
+	callIndirect := module.ExportedFunction("call_indirect")
+
+	requireCall := func(t *testing.T, i, expected uint64) {
+		results, err := callIndirect.Call(testCtx, i)
+		require.NoError(t, err)
+		require.Equal(t, expected, results[0])
+	}
+
+	// The table is empty until initialized.
+	for i := uint64(0); i < 4; i++ {
+		_, err = callIndirect.Call(testCtx, i)
+		require.Error(t, err)
+	}
+
+	// table[1:3] = elem[0:2]
+	_, err = tableInit.Call(testCtx, 1, 0, 2)
+	require.NoError(t, err)
+	requireCall(t, 1, 10)
+	requireCall(t, 2, 20)
+
+	// table[0:1] = table[2:3]
+	_, err = tableCopy.Call(testCtx, 0, 2, 1)
+	require.NoError(t, err)
+	requireCall(t, 0, 20)
+
+	// Ranges that are even partially out of bounds trap without modifying the table.
+	_, err = tableInit.Call(testCtx, 3, 0, 2)
+	require.Error(t, err)
+	_, err = tableInit.Call(testCtx, 0, 1, 2)
+	require.Error(t, err)
+	_, err = tableCopy.Call(testCtx, 3, 0, 2)
+	require.Error(t, err)
+	_, err = tableCopy.Call(testCtx, 0, 3, 2)
+	require.Error(t, err)
+	_, err = callIndirect.Call(testCtx, 3)
+	require.Error(t, err)
+	requireCall(t, 0, 20)
+
+	// Once dropped, the element segment behaves as if it has no elements.
+	_, err = elemDrop.Call(testCtx)
+	require.NoError(t, err)
+	_, err = tableInit.Call(testCtx, 0, 0, 1)
+	require.Error(t, err)
+	_, err = tableInit.Call(testCtx, 0, 0, 0)
+	require.NoError(t, err)
+
+	// The table itself is unaffected by elem.drop.
+	requireCall(t, 1, 10)
+	requireCall(t, 2, 20)
+}
+
 func testHugeStack(t *testing.T, r wazero.Runtime) {
 	module, err := r.InstantiateModuleFromBinary(testCtx, hugestackWasm)
 	require.NoError(t, err)
@@ -213,11 +520,13 @@ func testNestedGoContext(t *testing.T, r wazero.Runtime) {
 	fns := map[string]interface{}{
 		"inner": func(ctx context.Context, p uint32) uint32 {
 			// We expect the initial context, testCtx, to be overwritten by "outer" when it called this.
-			require.Equal(t, nestedCtx, ctx)
+			require.Equal(t, "nested", ctx.Value(struct{}{}))
+			require.Equal(t, importing, wazero.ModuleFromContext(ctx))
 			return p + 1
 		},
 		"outer": func(ctx context.Context, module api.Module, p uint32) uint32 {
-			require.Equal(t, testCtx, ctx)
+			require.Equal(t, "arbitrary", ctx.Value(struct{}{}))
+			require.Equal(t, module, wazero.ModuleFromContext(ctx))
 			results, err := module.ExportedFunction("inner").Call(nestedCtx, uint64(p))
 			require.NoError(t, err)
 			return uint32(results[0]) + 1
@@ -257,7 +566,8 @@ func testHostFunctionContextParameter(t *testing.T, r wazero.Runtime) {
 			return p + 1
 		},
 		"go_context": func(ctx context.Context, p uint32) uint32 {
-			require.Equal(t, testCtx, ctx)
+			require.Equal(t, "arbitrary", ctx.Value(struct{}{}))
+			require.Equal(t, importing, wazero.ModuleFromContext(ctx))
 			return p + 1
 		},
 		"module_context": func(module api.Module, p uint32) uint32 {
@@ -471,6 +781,39 @@ func testCloseInFlight(t *testing.T, r wazero.Runtime) {
 	}
 }
 
+// testCloseScheduled ensures a call scheduled by the host, ex. a timer, doesn't run once the host closes the module.
+func testCloseScheduled(t *testing.T, r wazero.Runtime) {
+	var resumed int
+	host, err := r.NewModuleBuilder(t.Name()+"-host").
+		ExportFunction("resumed", func() { resumed++ }).
+		Instantiate(testCtx, r)
+	require.NoError(t, err)
+	defer host.Close(testCtx)
+
+	mod, err := r.InstantiateModuleFromBinary(testCtx, wat2wasm(fmt.Sprintf(`(module $%[2]s
+  (import "%[1]s" "resumed" (func $resumed))
+  (func $resume call $resumed)
+  (export "resume" (func $resume))
+)`, host.Name(), t.Name()+"-guest")))
+	require.NoError(t, err)
+
+	// Schedule an event, like a timer would, which calls back into the module.
+	resume := mod.ExportedFunction("resume")
+	scheduled := func() error {
+		_, err := resume.Call(testCtx)
+		return err
+	}
+	require.NoError(t, scheduled())
+	require.Equal(t, 1, resumed)
+
+	// The host closes the module directly while the event is still pending.
+	require.NoError(t, mod.CloseWithExitCode(testCtx, 2))
+
+	// When the event fires, the call fails without running any code.
+	require.Equal(t, sys.NewExitError(mod.Name(), 2), scheduled())
+	require.Equal(t, 1, resumed)
+}
+
 func testMemOps(t *testing.T, r wazero.Runtime) {
 	// Instantiate a module that manages its memory
 	memory, err := r.InstantiateModuleFromBinary(testCtx, wat2wasm(`(module $memory
@@ -534,6 +877,21 @@ func testMemOps(t *testing.T, r wazero.Runtime) {
 	// Now the store instruction at the memory capcity bound should succeed.
 	_, err = memory.ExportedFunction("store").Call(testCtx, wasm.MemoryPagesToBytesNum(memoryCapacityPages)-8) // i64.store needs 8 bytes from offset.
 	require.NoError(t, err)
+
+	// A slice leased by ReadSlice no longer shares writes once Wasm grows memory.
+	buf, release, ok := memory.Memory().ReadSlice(testCtx, 0, 8)
+	require.True(t, ok)
+	defer release()
+
+	results, err = memory.ExportedFunction("grow").Call(testCtx, 1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(memoryCapacityPages), results[0])
+
+	_, err = memory.ExportedFunction("store").Call(testCtx, 0)
+	require.NoError(t, err)
+	require.Equal(t, make([]byte, 8), buf)
+	v, _ := memory.Memory().ReadUint64Le(testCtx, 0)
+	require.Equal(t, uint64(1), v)
 }
 
 func testMultipleInstantiation(t *testing.T, r wazero.Runtime) {
@@ -573,3 +931,989 @@ func testMultipleInstantiation(t *testing.T, r wazero.Runtime) {
 		require.Equal(t, uint64(1000), after)
 	}
 }
+
+var trapReasonsWasm = binaryformat.EncodeModule(&wasm.Module{
+	TypeSection: []*wasm.FunctionType{
+		{Results: []wasm.ValueType{wasm.ValueTypeI32}},
+		{Params: []wasm.ValueType{wasm.ValueTypeI32}},
+		{},
+	},
+	FunctionSection: []wasm.Index{1, 0, 0, 0, 0, 0, 2, 0, 2, 0},
+	CodeSection: []*wasm.Code{
+		{Body: []byte{wasm.OpcodeEnd}}, // the only element in the table, whose type is never called indirectly.
+		{Body: []byte{ // i32.load (65536), which is the first byte after the memory
+			wasm.OpcodeI32Const, 0x80, 0x80, 0x04,
+			wasm.OpcodeI32Load, 0x2, 0x0, // alignment=2 (natural alignment) staticOffset=0
+			wasm.OpcodeEnd,
+		}},
+		{Body: []byte{ // call_indirect (2), which is past the end of the table
+			wasm.OpcodeI32Const, 2,
+			wasm.OpcodeCallIndirect, 0, 0, // type 0, table 0
+			wasm.OpcodeEnd,
+		}},
+		{Body: []byte{ // 1 / 0
+			wasm.OpcodeI32Const, 1, wasm.OpcodeI32Const, 0,
+			wasm.OpcodeI32DivU,
+			wasm.OpcodeEnd,
+		}},
+		{Body: []byte{ // math.MinInt32 / -1
+			wasm.OpcodeI32Const, 0x80, 0x80, 0x80, 0x80, 0x78, wasm.OpcodeI32Const, 0x7f,
+			wasm.OpcodeI32DivS,
+			wasm.OpcodeEnd,
+		}},
+		{Body: []byte{ // i32.trunc_f32_s (NaN)
+			wasm.OpcodeF32Const, 0x00, 0x00, 0xc0, 0x7f,
+			wasm.OpcodeI32TruncF32S,
+			wasm.OpcodeEnd,
+		}},
+		{Body: []byte{wasm.OpcodeUnreachable, wasm.OpcodeEnd}},
+		{Body: []byte{ // call_indirect (0), which has type 1, not type 0
+			wasm.OpcodeI32Const, 0,
+			wasm.OpcodeCallIndirect, 0, 0, // type 0, table 0
+			wasm.OpcodeEnd,
+		}},
+		{Body: []byte{wasm.OpcodeCall, 8, wasm.OpcodeEnd}}, // infinite recursion
+		{Body: []byte{ // call_indirect (1), which is in the table, but never initialized
+			wasm.OpcodeI32Const, 1,
+			wasm.OpcodeCallIndirect, 0, 0, // type 0, table 0
+			wasm.OpcodeEnd,
+		}},
+	},
+	MemorySection: &wasm.Memory{Min: 1, Cap: 1, Max: 1, IsMaxEncoded: true},
+	TableSection:  []*wasm.Table{{Min: 2, Type: wasm.RefTypeFuncref}},
+	ElementSection: []*wasm.ElementSegment{{
+		OffsetExpr: &wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}},
+		Init:       []*wasm.Index{uint32Ptr(0)},
+		Type:       wasm.RefTypeFuncref,
+		Mode:       wasm.ElementModeActive,
+	}},
+	ExportSection: []*wasm.Export{
+		{Name: "out_of_bounds_memory", Type: wasm.ExternTypeFunc, Index: 1},
+		{Name: "out_of_bounds_table", Type: wasm.ExternTypeFunc, Index: 2},
+		{Name: "integer_divide_by_zero", Type: wasm.ExternTypeFunc, Index: 3},
+		{Name: "integer_overflow", Type: wasm.ExternTypeFunc, Index: 4},
+		{Name: "invalid_conversion", Type: wasm.ExternTypeFunc, Index: 5},
+		{Name: "unreachable", Type: wasm.ExternTypeFunc, Index: 6},
+		{Name: "indirect_call_type_mismatch", Type: wasm.ExternTypeFunc, Index: 7},
+		{Name: "call_stack_exhausted", Type: wasm.ExternTypeFunc, Index: 8},
+		{Name: "indirect_call_to_null", Type: wasm.ExternTypeFunc, Index: 9},
+	},
+})
+
+// testTrapReasons ensures each trap is returned as an api.TrapError with its reason, regardless of the engine.
+func testTrapReasons(t *testing.T, r wazero.Runtime) {
+	mod, err := r.InstantiateModuleFromBinary(testCtx, trapReasonsWasm)
+	require.NoError(t, err)
+	defer mod.Close(testCtx)
+
+	tests := []struct {
+		function string
+		expected api.TrapReason
+	}{
+		{function: "out_of_bounds_memory", expected: api.TrapReasonOutOfBoundsMemory},
+		{function: "out_of_bounds_table", expected: api.TrapReasonOutOfBoundsTable},
+		{function: "integer_divide_by_zero", expected: api.TrapReasonIntegerDivideByZero},
+		{function: "integer_overflow", expected: api.TrapReasonIntegerOverflow},
+		{function: "invalid_conversion", expected: api.TrapReasonInvalidConversion},
+		{function: "unreachable", expected: api.TrapReasonUnreachable},
+		{function: "indirect_call_type_mismatch", expected: api.TrapReasonIndirectCallTypeMismatch},
+		{function: "call_stack_exhausted", expected: api.TrapReasonCallStackExhausted},
+		{function: "indirect_call_to_null", expected: api.TrapReasonIndirectCallToNull},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.function, func(t *testing.T) {
+			_, err := mod.ExportedFunction(tc.function).Call(testCtx)
+			require.Error(t, err)
+
+			var trap api.TrapError
+			require.True(t, errors.As(err, &trap), "expected a trap, but was %v", err)
+			require.Equal(t, tc.expected, trap.TrapReason(), "expected %s, but was %s", tc.expected, trap.TrapReason())
+			require.Contains(t, err.Error(), "wasm error: "+tc.expected.String())
+		})
+	}
+}
+
+// importGlobalWasm returns a module that imports the global "env.__memory_base" of the given type, and exports a
+// function "memory_base" that returns it.
+func importGlobalWasm(valType wasm.ValueType, mutable bool) []byte {
+	return binaryformat.EncodeModule(&wasm.Module{
+		TypeSection: []*wasm.FunctionType{{Results: []wasm.ValueType{valType}}},
+		ImportSection: []*wasm.Import{
+			{
+				Module: "env", Name: "__memory_base",
+				Type:       wasm.ExternTypeGlobal,
+				DescGlobal: &wasm.GlobalType{ValType: valType, Mutable: mutable},
+			},
+		},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []*wasm.Code{
+			{Body: []byte{wasm.OpcodeGlobalGet, 0, wasm.OpcodeEnd}},
+		},
+		ExportSection: []*wasm.Export{{Name: "memory_base", Type: wasm.ExternTypeFunc, Index: 0}},
+	})
+}
+
+// testImportedGlobal ensures a guest can import a global exported by a host module, as is common in dynamic linking.
+func testImportedGlobal(t *testing.T, r wazero.Runtime) {
+	host, err := r.NewModuleBuilder("env").ExportGlobalI32("__memory_base", 1024).Instantiate(testCtx, r)
+	require.NoError(t, err)
+	defer host.Close(testCtx)
+
+	t.Run("reads value", func(t *testing.T) {
+		mod, err := r.InstantiateModuleFromBinary(testCtx, importGlobalWasm(wasm.ValueTypeI32, false))
+		require.NoError(t, err)
+		defer mod.Close(testCtx)
+
+		results, err := mod.ExportedFunction("memory_base").Call(testCtx)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1024), results[0])
+	})
+
+	t.Run("value type mismatch", func(t *testing.T) {
+		_, err := r.InstantiateModuleFromBinary(testCtx, importGlobalWasm(wasm.ValueTypeI64, false))
+		require.EqualError(t, err, "import[0] global[env.__memory_base]: value type mismatch: i64 != i32")
+	})
+
+	t.Run("mutability mismatch", func(t *testing.T) {
+		_, err := r.InstantiateModuleFromBinary(testCtx, importGlobalWasm(wasm.ValueTypeI32, true))
+		require.EqualError(t, err, "import[0] global[env.__memory_base]: mutability mismatch: true != false")
+	})
+}
+
+// importMutableGlobalWasm imports the mutable i32 global "env.counter", and also defines its own mutable i32 global
+// which is initialized to 100. It exports these functions:
+//   - "get" returns the imported global.
+//   - "inc" adds one to the imported global.
+//   - "own" adds one to its own global and returns it.
+var importMutableGlobalWasm = func() []byte {
+	i32 := wasm.ValueTypeI32
+	return binaryformat.EncodeModule(&wasm.Module{
+		TypeSection: []*wasm.FunctionType{{Results: []wasm.ValueType{i32}}, {}},
+		ImportSection: []*wasm.Import{
+			{
+				Module: "env", Name: "counter",
+				Type:       wasm.ExternTypeGlobal,
+				DescGlobal: &wasm.GlobalType{ValType: i32, Mutable: true},
+			},
+		},
+		GlobalSection: []*wasm.Global{
+			{
+				Type: &wasm.GlobalType{ValType: i32, Mutable: true},
+				Init: &wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0xe4, 0x00}}, // 100
+			},
+		},
+		FunctionSection: []wasm.Index{0, 1, 0},
+		CodeSection: []*wasm.Code{
+			{Body: []byte{wasm.OpcodeGlobalGet, 0, wasm.OpcodeEnd}},
+			{Body: []byte{
+				wasm.OpcodeGlobalGet, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Add, wasm.OpcodeGlobalSet, 0,
+				wasm.OpcodeEnd,
+			}},
+			{Body: []byte{
+				wasm.OpcodeGlobalGet, 1, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Add, wasm.OpcodeGlobalSet, 1,
+				wasm.OpcodeGlobalGet, 1, wasm.OpcodeEnd,
+			}},
+		},
+		ExportSection: []*wasm.Export{
+			{Name: "get", Type: wasm.ExternTypeFunc, Index: 0},
+			{Name: "inc", Type: wasm.ExternTypeFunc, Index: 1},
+			{Name: "own", Type: wasm.ExternTypeFunc, Index: 2},
+		},
+	})
+}()
+
+// testImportedMutableGlobal ensures "global.get" and "global.set" of an imported mutable global act on the instance
+// exported by the host module, so that updates are visible to the host and to other importers.
+func testImportedMutableGlobal(t *testing.T, r wazero.Runtime) {
+	host, err := r.NewModuleBuilder("env").ExportMutableGlobalI32("counter", 1).Instantiate(testCtx, r)
+	require.NoError(t, err)
+	defer host.Close(testCtx)
+
+	counter, ok := host.ExportedGlobal("counter").(api.MutableGlobal)
+	require.True(t, ok)
+
+	compiled, err := r.CompileModule(testCtx, importMutableGlobalWasm, wazero.NewCompileConfig())
+	require.NoError(t, err)
+
+	mod1, err := r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig().WithName("mod1"))
+	require.NoError(t, err)
+	defer mod1.Close(testCtx)
+
+	mod2, err := r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig().WithName("mod2"))
+	require.NoError(t, err)
+	defer mod2.Close(testCtx)
+
+	get := func(mod api.Module) uint64 {
+		results, err := mod.ExportedFunction("get").Call(testCtx)
+		require.NoError(t, err)
+		return results[0]
+	}
+
+	// The guest reads the value exported by the host.
+	require.Equal(t, uint64(1), get(mod1))
+
+	// The host reads the value set by the guest.
+	_, err = mod1.ExportedFunction("inc").Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), counter.Get(testCtx))
+
+	// Another importer reads the value set by the first.
+	require.Equal(t, uint64(2), get(mod2))
+	_, err = mod2.ExportedFunction("inc").Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), get(mod1))
+	require.Equal(t, uint64(3), counter.Get(testCtx))
+
+	// Guests read the value set by the host.
+	counter.Set(testCtx, 42)
+	require.Equal(t, uint64(42), get(mod1))
+	require.Equal(t, uint64(42), get(mod2))
+
+	// Globals defined after the import are per instance.
+	results, err := mod1.ExportedFunction("own").Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(101), results[0])
+	results, err = mod2.ExportedFunction("own").Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(101), results[0])
+	require.Equal(t, uint64(42), counter.Get(testCtx))
+}
+
+// reinterpretWasm exports functions which reinterpret their parameter or a constant, and ones that round-trip through a
+// float local, so that any NaN canonicalization would be visible in the result bits.
+var reinterpretWasm = binaryformat.EncodeModule(&wasm.Module{
+	TypeSection: []*wasm.FunctionType{
+		{Params: []wasm.ValueType{wasm.ValueTypeF32}, Results: []wasm.ValueType{wasm.ValueTypeI32}},
+		{Params: []wasm.ValueType{wasm.ValueTypeI32}, Results: []wasm.ValueType{wasm.ValueTypeF32}},
+		{Params: []wasm.ValueType{wasm.ValueTypeF64}, Results: []wasm.ValueType{wasm.ValueTypeI64}},
+		{Params: []wasm.ValueType{wasm.ValueTypeI64}, Results: []wasm.ValueType{wasm.ValueTypeF64}},
+		{Params: []wasm.ValueType{wasm.ValueTypeI32}, Results: []wasm.ValueType{wasm.ValueTypeI32}},
+		{Params: []wasm.ValueType{wasm.ValueTypeI64}, Results: []wasm.ValueType{wasm.ValueTypeI64}},
+		{Results: []wasm.ValueType{wasm.ValueTypeI32}},
+		{Results: []wasm.ValueType{wasm.ValueTypeI64}},
+	},
+	FunctionSection: []wasm.Index{0, 1, 2, 3, 4, 5, 6, 7},
+	CodeSection: []*wasm.Code{
+		{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeI32ReinterpretF32, wasm.OpcodeEnd}},
+		{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeF32ReinterpretI32, wasm.OpcodeEnd}},
+		{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeI64ReinterpretF64, wasm.OpcodeEnd}},
+		{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeF64ReinterpretI64, wasm.OpcodeEnd}},
+		{
+			LocalTypes: []wasm.ValueType{wasm.ValueTypeF32},
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeF32ReinterpretI32,
+				wasm.OpcodeLocalSet, 1,
+				wasm.OpcodeLocalGet, 1, wasm.OpcodeI32ReinterpretF32,
+				wasm.OpcodeEnd,
+			},
+		},
+		{
+			LocalTypes: []wasm.ValueType{wasm.ValueTypeF64},
+			Body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeF64ReinterpretI64,
+				wasm.OpcodeLocalSet, 1,
+				wasm.OpcodeLocalGet, 1, wasm.OpcodeI64ReinterpretF64,
+				wasm.OpcodeEnd,
+			},
+		},
+		{Body: []byte{ // the same signaling NaN as the test below, encoded little-endian
+			wasm.OpcodeF32Const, 0x01, 0x00, 0xa0, 0x7f,
+			wasm.OpcodeI32ReinterpretF32,
+			wasm.OpcodeEnd,
+		}},
+		{Body: []byte{ // the same signaling NaN as the test below, encoded little-endian
+			wasm.OpcodeF64Const, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf4, 0xff,
+			wasm.OpcodeI64ReinterpretF64,
+			wasm.OpcodeEnd,
+		}},
+	},
+	ExportSection: []*wasm.Export{
+		{Name: "i32.reinterpret_f32", Type: wasm.ExternTypeFunc, Index: 0},
+		{Name: "f32.reinterpret_i32", Type: wasm.ExternTypeFunc, Index: 1},
+		{Name: "i64.reinterpret_f64", Type: wasm.ExternTypeFunc, Index: 2},
+		{Name: "f64.reinterpret_i64", Type: wasm.ExternTypeFunc, Index: 3},
+		{Name: "round_trip_f32", Type: wasm.ExternTypeFunc, Index: 4},
+		{Name: "round_trip_f64", Type: wasm.ExternTypeFunc, Index: 5},
+		{Name: "const_f32", Type: wasm.ExternTypeFunc, Index: 6},
+		{Name: "const_f64", Type: wasm.ExternTypeFunc, Index: 7},
+	},
+})
+
+// testReinterpretNaN ensures reinterpret instructions are pure bit casts, preserving signaling NaN payloads, which
+// would otherwise be quieted by a float conversion.
+func testReinterpretNaN(t *testing.T, r wazero.Runtime) {
+	mod, err := r.InstantiateModuleFromBinary(testCtx, reinterpretWasm)
+	require.NoError(t, err)
+	defer mod.Close(testCtx)
+
+	const (
+		signalingNaN32 = uint64(0x7fa00001)         // quiet bit (0x00400000) clear, non-zero payload
+		signalingNaN64 = uint64(0xfff4000000000001) // quiet bit (0x0008000000000000) clear, non-zero payload and sign
+	)
+
+	for _, fn := range []string{"i32.reinterpret_f32", "f32.reinterpret_i32", "round_trip_f32"} {
+		results, err := mod.ExportedFunction(fn).Call(testCtx, signalingNaN32)
+		require.NoError(t, err)
+		require.Equal(t, signalingNaN32, results[0], "%s: expected %#x, but was %#x", fn, signalingNaN32, results[0])
+	}
+
+	for _, fn := range []string{"i64.reinterpret_f64", "f64.reinterpret_i64", "round_trip_f64"} {
+		results, err := mod.ExportedFunction(fn).Call(testCtx, signalingNaN64)
+		require.NoError(t, err)
+		require.Equal(t, signalingNaN64, results[0], "%s: expected %#x, but was %#x", fn, signalingNaN64, results[0])
+	}
+
+	results, err := mod.ExportedFunction("const_f32").Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, signalingNaN32, results[0], "const_f32: expected %#x, but was %#x", signalingNaN32, results[0])
+
+	results, err = mod.ExportedFunction("const_f64").Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, signalingNaN64, results[0], "const_f64: expected %#x, but was %#x", signalingNaN64, results[0])
+}
+
+// typedSelectWasm exports "select_externref", which returns the first externref parameter when the i32 parameter is
+// non-zero, and the second otherwise.
+var typedSelectWasm = binaryformat.EncodeModule(&wasm.Module{
+	TypeSection: []*wasm.FunctionType{{
+		Params:            []wasm.ValueType{wasm.ValueTypeExternref, wasm.ValueTypeExternref, wasm.ValueTypeI32},
+		Results:           []wasm.ValueType{wasm.ValueTypeExternref},
+		ParamNumInUint64:  3,
+		ResultNumInUint64: 1,
+	}},
+	FunctionSection: []wasm.Index{0},
+	CodeSection: []*wasm.Code{{Body: []byte{
+		wasm.OpcodeLocalGet, 0,
+		wasm.OpcodeLocalGet, 1,
+		wasm.OpcodeLocalGet, 2,
+		wasm.OpcodeTypedSelect, 1, wasm.ValueTypeExternref,
+		wasm.OpcodeEnd,
+	}}},
+	ExportSection: []*wasm.Export{{Name: "select_externref", Type: wasm.ExternTypeFunc, Index: 0}},
+})
+
+func testTypedSelect(t *testing.T, r wazero.Runtime) {
+	mod, err := r.InstantiateModuleFromBinary(testCtx, typedSelectWasm)
+	require.NoError(t, err)
+	defer mod.Close(testCtx)
+
+	type dog struct {
+		name string
+	}
+	first, second := &dog{name: "first"}, &dog{name: "second"}
+	firstRef, secondRef := uint64(uintptr(unsafe.Pointer(first))), uint64(uintptr(unsafe.Pointer(second)))
+
+	selectExternref := mod.ExportedFunction("select_externref")
+	for _, tc := range []struct {
+		condition uint64
+		expected  uint64
+	}{
+		{condition: 1, expected: firstRef},
+		{condition: 0xffffffff, expected: firstRef},
+		{condition: 0, expected: secondRef},
+	} {
+		results, err := selectExternref.Call(testCtx, firstRef, secondRef, tc.condition)
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, results[0], "condition %d", tc.condition)
+	}
+}
+
+// rotateAndCountWasm exports a function per rotate and bit counting instruction, named after it. Each returns the
+// result of the instruction on its parameters.
+var rotateAndCountWasm = func() []byte {
+	i32, i64 := wasm.ValueTypeI32, wasm.ValueTypeI64
+	m := &wasm.Module{
+		TypeSection: []*wasm.FunctionType{
+			{Params: []wasm.ValueType{i32, i32}, Results: []wasm.ValueType{i32}, ParamNumInUint64: 2, ResultNumInUint64: 1},
+			{Params: []wasm.ValueType{i64, i64}, Results: []wasm.ValueType{i64}, ParamNumInUint64: 2, ResultNumInUint64: 1},
+			{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}, ParamNumInUint64: 1, ResultNumInUint64: 1},
+			{Params: []wasm.ValueType{i64}, Results: []wasm.ValueType{i64}, ParamNumInUint64: 1, ResultNumInUint64: 1},
+		},
+	}
+	for _, op := range []struct {
+		opcode wasm.Opcode
+		typeID wasm.Index
+	}{
+		{wasm.OpcodeI32Rotl, 0}, {wasm.OpcodeI32Rotr, 0},
+		{wasm.OpcodeI64Rotl, 1}, {wasm.OpcodeI64Rotr, 1},
+		{wasm.OpcodeI32Clz, 2}, {wasm.OpcodeI32Ctz, 2}, {wasm.OpcodeI32Popcnt, 2},
+		{wasm.OpcodeI64Clz, 3}, {wasm.OpcodeI64Ctz, 3}, {wasm.OpcodeI64Popcnt, 3},
+	} {
+		body := []byte{wasm.OpcodeLocalGet, 0}
+		if op.typeID < 2 { // binary
+			body = append(body, wasm.OpcodeLocalGet, 1)
+		}
+		body = append(body, op.opcode, wasm.OpcodeEnd)
+
+		idx := wasm.Index(len(m.FunctionSection))
+		m.FunctionSection = append(m.FunctionSection, op.typeID)
+		m.CodeSection = append(m.CodeSection, &wasm.Code{Body: body})
+		m.ExportSection = append(m.ExportSection, &wasm.Export{Name: wasm.InstructionName(op.opcode), Type: wasm.ExternTypeFunc, Index: idx})
+	}
+	return binaryformat.EncodeModule(m)
+}()
+
+func testRotateAndCount(t *testing.T, r wazero.Runtime) {
+	mod, err := r.InstantiateModuleFromBinary(testCtx, rotateAndCountWasm)
+	require.NoError(t, err)
+	defer mod.Close(testCtx)
+
+	const (
+		x32 = uint64(0x80000001)
+		x64 = uint64(0x8000000000000001)
+		// garbage32 has bits set above 32, which an i32 instruction must ignore.
+		garbage32 = uint64(0xffffffff00000000)
+	)
+
+	// The spec defines rotation counts modulo the bit width, as do bits.RotateLeft32 and bits.RotateLeft64.
+	// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#-hrefop-irotlmathrmirotl_n-i_1-i_2
+	for _, tc := range []struct {
+		fn       string
+		params   []uint64
+		expected uint64
+	}{
+		{fn: "i32.rotl", params: []uint64{x32, 0}, expected: x32},
+		{fn: "i32.rotl", params: []uint64{x32, 1}, expected: uint64(bits.RotateLeft32(uint32(x32), 1))},
+		{fn: "i32.rotl", params: []uint64{x32, 32}, expected: x32},
+		{fn: "i32.rotl", params: []uint64{x32, 33}, expected: uint64(bits.RotateLeft32(uint32(x32), 1))},
+		{fn: "i32.rotl", params: []uint64{x32, 0xffffffff}, expected: uint64(bits.RotateLeft32(uint32(x32), 31))},
+		{fn: "i32.rotl", params: []uint64{garbage32 | x32, 1}, expected: uint64(bits.RotateLeft32(uint32(x32), 1))},
+		{fn: "i32.rotr", params: []uint64{x32, 0}, expected: x32},
+		{fn: "i32.rotr", params: []uint64{x32, 1}, expected: uint64(bits.RotateLeft32(uint32(x32), -1))},
+		{fn: "i32.rotr", params: []uint64{x32, 32}, expected: x32},
+		{fn: "i32.rotr", params: []uint64{x32, 64}, expected: x32},
+		{fn: "i64.rotl", params: []uint64{x64, 0}, expected: x64},
+		{fn: "i64.rotl", params: []uint64{x64, 1}, expected: bits.RotateLeft64(x64, 1)},
+		{fn: "i64.rotl", params: []uint64{x64, 64}, expected: x64},
+		{fn: "i64.rotl", params: []uint64{x64, 65}, expected: bits.RotateLeft64(x64, 1)},
+		{fn: "i64.rotl", params: []uint64{x64, math.MaxUint64}, expected: bits.RotateLeft64(x64, 63)},
+		{fn: "i64.rotr", params: []uint64{x64, 0}, expected: x64},
+		{fn: "i64.rotr", params: []uint64{x64, 1}, expected: bits.RotateLeft64(x64, -1)},
+		{fn: "i64.rotr", params: []uint64{x64, 64}, expected: x64},
+		{fn: "i64.rotr", params: []uint64{x64, 128}, expected: x64},
+		{fn: "i32.clz", params: []uint64{0}, expected: 32},
+		{fn: "i32.clz", params: []uint64{math.MaxUint32}, expected: 0},
+		{fn: "i32.clz", params: []uint64{1}, expected: 31},
+		{fn: "i32.clz", params: []uint64{garbage32}, expected: 32},
+		{fn: "i32.ctz", params: []uint64{0}, expected: 32},
+		{fn: "i32.ctz", params: []uint64{math.MaxUint32}, expected: 0},
+		{fn: "i32.ctz", params: []uint64{0x80000000}, expected: 31},
+		{fn: "i32.ctz", params: []uint64{garbage32}, expected: 32},
+		{fn: "i32.popcnt", params: []uint64{0}, expected: 0},
+		{fn: "i32.popcnt", params: []uint64{math.MaxUint32}, expected: 32},
+		{fn: "i32.popcnt", params: []uint64{garbage32}, expected: 0},
+		{fn: "i64.clz", params: []uint64{0}, expected: 64},
+		{fn: "i64.clz", params: []uint64{math.MaxUint64}, expected: 0},
+		{fn: "i64.clz", params: []uint64{1}, expected: 63},
+		{fn: "i64.ctz", params: []uint64{0}, expected: 64},
+		{fn: "i64.ctz", params: []uint64{math.MaxUint64}, expected: 0},
+		{fn: "i64.ctz", params: []uint64{1 << 63}, expected: 63},
+		{fn: "i64.popcnt", params: []uint64{0}, expected: 0},
+		{fn: "i64.popcnt", params: []uint64{math.MaxUint64}, expected: 64},
+	} {
+		results, err := mod.ExportedFunction(tc.fn).Call(testCtx, tc.params...)
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, results[0], "%s%v: expected %#x, but was %#x", tc.fn, tc.params, tc.expected, results[0])
+	}
+}
+
+// minMaxWasm exports a function per float min and max instruction, named after it. Each returns the result of the
+// instruction on its parameters.
+var minMaxWasm = func() []byte {
+	f32, f64 := wasm.ValueTypeF32, wasm.ValueTypeF64
+	m := &wasm.Module{
+		TypeSection: []*wasm.FunctionType{
+			{Params: []wasm.ValueType{f32, f32}, Results: []wasm.ValueType{f32}, ParamNumInUint64: 2, ResultNumInUint64: 1},
+			{Params: []wasm.ValueType{f64, f64}, Results: []wasm.ValueType{f64}, ParamNumInUint64: 2, ResultNumInUint64: 1},
+		},
+	}
+	for _, op := range []struct {
+		opcode wasm.Opcode
+		typeID wasm.Index
+	}{
+		{wasm.OpcodeF32Min, 0}, {wasm.OpcodeF32Max, 0},
+		{wasm.OpcodeF64Min, 1}, {wasm.OpcodeF64Max, 1},
+	} {
+		idx := wasm.Index(len(m.FunctionSection))
+		m.FunctionSection = append(m.FunctionSection, op.typeID)
+		m.CodeSection = append(m.CodeSection, &wasm.Code{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, op.opcode, wasm.OpcodeEnd}})
+		m.ExportSection = append(m.ExportSection, &wasm.Export{Name: wasm.InstructionName(op.opcode), Type: wasm.ExternTypeFunc, Index: idx})
+	}
+	return binaryformat.EncodeModule(m)
+}()
+
+func testMinMax(t *testing.T, r wazero.Runtime) {
+	mod, err := r.InstantiateModuleFromBinary(testCtx, minMaxWasm)
+	require.NoError(t, err)
+	defer mod.Close(testCtx)
+
+	const (
+		posZero32, negZero32 = uint64(0), uint64(0x8000_0000)
+		one32                = uint64(0x3f80_0000)
+		negInf32             = uint64(0xff80_0000)
+		canonicalNaN32       = uint64(moremath.F32CanonicalNaNBits)
+		payloadNaN32         = uint64(0x7fc0_0001)
+		negNaN32             = uint64(0xffc0_0000)
+		posZero64, negZero64 = uint64(0), uint64(0x8000_0000_0000_0000)
+		one64                = uint64(0x3ff0_0000_0000_0000)
+		posInf64             = uint64(0x7ff0_0000_0000_0000)
+		canonicalNaN64       = moremath.F64CanonicalNaNBits
+		payloadNaN64         = uint64(0x7ff4_0000_0000_0000) // signaling
+	)
+
+	// The spec defines min(-0, +0) as -0 and max(-0, +0) as +0, regardless of the order of the operands. A NaN
+	// operand results in NaN. wazero returns the canonical NaN regardless of the payload of the NaN operands, so that
+	// all engines and platforms have the same result.
+	// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#-hrefop-fminmathrmfmin_n-z_1-z_2
+	for _, tc := range []struct {
+		fn       string
+		params   []uint64
+		expected uint64
+	}{
+		{fn: "f32.min", params: []uint64{negZero32, posZero32}, expected: negZero32},
+		{fn: "f32.min", params: []uint64{posZero32, negZero32}, expected: negZero32},
+		{fn: "f32.min", params: []uint64{posZero32, posZero32}, expected: posZero32},
+		{fn: "f32.min", params: []uint64{canonicalNaN32, one32}, expected: canonicalNaN32},
+		{fn: "f32.min", params: []uint64{one32, canonicalNaN32}, expected: canonicalNaN32},
+		{fn: "f32.min", params: []uint64{negInf32, canonicalNaN32}, expected: canonicalNaN32},
+		{fn: "f32.max", params: []uint64{negZero32, posZero32}, expected: posZero32},
+		{fn: "f32.max", params: []uint64{posZero32, negZero32}, expected: posZero32},
+		{fn: "f32.max", params: []uint64{negZero32, negZero32}, expected: negZero32},
+		{fn: "f32.max", params: []uint64{canonicalNaN32, one32}, expected: canonicalNaN32},
+		{fn: "f32.max", params: []uint64{one32, canonicalNaN32}, expected: canonicalNaN32},
+		{fn: "f32.min", params: []uint64{payloadNaN32, one32}, expected: canonicalNaN32},
+		{fn: "f32.min", params: []uint64{one32, negNaN32}, expected: canonicalNaN32},
+		{fn: "f32.max", params: []uint64{one32, payloadNaN32}, expected: canonicalNaN32},
+		{fn: "f32.max", params: []uint64{negNaN32, payloadNaN32}, expected: canonicalNaN32},
+		{fn: "f64.min", params: []uint64{negZero64, posZero64}, expected: negZero64},
+		{fn: "f64.min", params: []uint64{posZero64, negZero64}, expected: negZero64},
+		{fn: "f64.min", params: []uint64{posZero64, posZero64}, expected: posZero64},
+		{fn: "f64.min", params: []uint64{canonicalNaN64, one64}, expected: canonicalNaN64},
+		{fn: "f64.min", params: []uint64{one64, canonicalNaN64}, expected: canonicalNaN64},
+		{fn: "f64.max", params: []uint64{negZero64, posZero64}, expected: posZero64},
+		{fn: "f64.max", params: []uint64{posZero64, negZero64}, expected: posZero64},
+		{fn: "f64.max", params: []uint64{negZero64, negZero64}, expected: negZero64},
+		{fn: "f64.max", params: []uint64{canonicalNaN64, one64}, expected: canonicalNaN64},
+		{fn: "f64.max", params: []uint64{posInf64, canonicalNaN64}, expected: canonicalNaN64},
+		{fn: "f64.max", params: []uint64{canonicalNaN64, canonicalNaN64}, expected: canonicalNaN64},
+		{fn: "f64.min", params: []uint64{payloadNaN64, one64}, expected: canonicalNaN64},
+		{fn: "f64.min", params: []uint64{one64, payloadNaN64}, expected: canonicalNaN64},
+		{fn: "f64.max", params: []uint64{payloadNaN64, posInf64}, expected: canonicalNaN64},
+		{fn: "f64.max", params: []uint64{canonicalNaN64, payloadNaN64}, expected: canonicalNaN64},
+	} {
+		results, err := mod.ExportedFunction(tc.fn).Call(testCtx, tc.params...)
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, results[0], "%s%#x: expected %#x, but was %#x", tc.fn, tc.params, tc.expected, results[0])
+	}
+}
+
+// tableGrowWasm has a funcref table initially holding only the function "answer", which returns 42. It exports
+// "grow", which grows the table by the count in its parameter, filling new elements with "answer". It also exports
+// "size", which returns the table size, and "call", which calls the element at the index in its parameter.
+var tableGrowWasm = func() []byte {
+	i32 := wasm.ValueTypeI32
+	zero := wasm.Index(0)
+	m := &wasm.Module{
+		TypeSection: []*wasm.FunctionType{
+			{Results: []wasm.ValueType{i32}, ResultNumInUint64: 1},
+			{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}, ParamNumInUint64: 1, ResultNumInUint64: 1},
+		},
+		FunctionSection: []wasm.Index{0, 1, 0, 1},
+		CodeSection: []*wasm.Code{
+			{Body: []byte{wasm.OpcodeI32Const, 42, wasm.OpcodeEnd}},
+			{Body: []byte{
+				wasm.OpcodeRefFunc, 0, wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeMiscPrefix, wasm.OpcodeMiscTableGrow, 0,
+				wasm.OpcodeEnd,
+			}},
+			{Body: []byte{wasm.OpcodeMiscPrefix, wasm.OpcodeMiscTableSize, 0, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeCallIndirect, 0, 0, wasm.OpcodeEnd}},
+		},
+		TableSection: []*wasm.Table{{Min: 1, Type: wasm.RefTypeFuncref}},
+		ElementSection: []*wasm.ElementSegment{{
+			OffsetExpr: &wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}},
+			Init:       []*wasm.Index{&zero},
+			Type:       wasm.RefTypeFuncref,
+			Mode:       wasm.ElementModeActive,
+		}},
+		ExportSection: []*wasm.Export{
+			{Name: "grow", Type: wasm.ExternTypeFunc, Index: 1},
+			{Name: "size", Type: wasm.ExternTypeFunc, Index: 2},
+			{Name: "call", Type: wasm.ExternTypeFunc, Index: 3},
+		},
+	}
+	return binaryformat.EncodeModule(m)
+}()
+
+func testTableGrow(t *testing.T, r wazero.Runtime) {
+	compiled, err := r.CompileModule(testCtx, tableGrowWasm, wazero.NewCompileConfig())
+	require.NoError(t, err)
+
+	mod, err := r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig().WithMaxTableElements(4))
+	require.NoError(t, err)
+	defer mod.Close(testCtx)
+
+	grow, size, call := mod.ExportedFunction("grow"), mod.ExportedFunction("size"), mod.ExportedFunction("call")
+
+	results, err := size.Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), results[0])
+
+	// Calling beyond the table traps until it grows.
+	_, err = call.Call(testCtx, 2)
+	require.Error(t, err)
+
+	// Growing returns the previous size.
+	results, err = grow.Call(testCtx, 2)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), results[0])
+
+	results, err = size.Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), results[0])
+
+	// The newly grown slot holds the fill reference.
+	results, err = call.Call(testCtx, 2)
+	require.NoError(t, err)
+	require.Equal(t, uint64(42), results[0])
+
+	// Growing beyond the max table elements fails, leaving the table as it was.
+	results, err = grow.Call(testCtx, 2)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0xffffffff), results[0]) // -1 as signed i32.
+
+	results, err = size.Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), results[0])
+
+	// Growing up to the max table elements succeeds.
+	results, err = grow.Call(testCtx, 1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), results[0])
+}
+
+// testHostFunctionResults ensures host functions with more or fewer results than params leave the rest of the stack
+// intact, as results are written over the params.
+func testHostFunctionResults(t *testing.T, r wazero.Runtime) {
+	_, err := r.NewModuleBuilder("host").
+		ExportFunction("split", func(x uint32) (uint64, uint64, uint64) {
+			return uint64(x), uint64(x) * 10, uint64(x) * 100
+		}).
+		ExportFunction("sum", func(x, y, z uint64) uint64 {
+			return x + y + z
+		}).
+		Instantiate(testCtx, r)
+	require.NoError(t, err)
+
+	i32, i64 := wasm.ValueTypeI32, wasm.ValueTypeI64
+	bin := binaryformat.EncodeModule(&wasm.Module{
+		TypeSection: []*wasm.FunctionType{
+			{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i64, i64, i64}},
+			{Params: []wasm.ValueType{i64, i64, i64}, Results: []wasm.ValueType{i64}},
+			{Results: []wasm.ValueType{i32, i64}},
+		},
+		ImportSection: []*wasm.Import{
+			{Module: "host", Name: "split", Type: wasm.ExternTypeFunc, DescFunc: 0},
+			{Module: "host", Name: "sum", Type: wasm.ExternTypeFunc, DescFunc: 1},
+		},
+		FunctionSection: []wasm.Index{2},
+		CodeSection: []*wasm.Code{{Body: []byte{
+			wasm.OpcodeI32Const, 7, // sentinel below the host calls
+			wasm.OpcodeI32Const, 1,
+			wasm.OpcodeCall, 0, // split: 1 param -> 3 results
+			wasm.OpcodeCall, 1, // sum: 3 params -> 1 result
+			wasm.OpcodeEnd,
+		}}},
+		ExportSection: []*wasm.Export{{Name: "run", Type: wasm.ExternTypeFunc, Index: 2}},
+	})
+
+	mod, err := r.InstantiateModuleFromBinary(testCtx, bin)
+	require.NoError(t, err)
+	defer mod.Close(testCtx)
+
+	// Call more than once, as arguments of host functions are pooled.
+	for i := 0; i < 2; i++ {
+		results, err := mod.ExportedFunction("run").Call(testCtx)
+		require.NoError(t, err)
+		require.Equal(t, []uint64{7, 111}, results)
+	}
+}
+
+// refIsNullWasm exports functions which return the result of ref.is_null on:
+//   - "externref": the externref parameter.
+//   - "null_funcref": ref.null func.
+//   - "null_externref": ref.null extern.
+//   - "funcref": ref.func of "externref" itself.
+var refIsNullWasm = binaryformat.EncodeModule(&wasm.Module{
+	TypeSection: []*wasm.FunctionType{
+		{Params: []wasm.ValueType{wasm.ValueTypeExternref}, Results: []wasm.ValueType{wasm.ValueTypeI32}},
+		{Results: []wasm.ValueType{wasm.ValueTypeI32}},
+	},
+	FunctionSection: []wasm.Index{0, 1, 1, 1},
+	CodeSection: []*wasm.Code{
+		{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeRefIsNull, wasm.OpcodeEnd}},
+		{Body: []byte{wasm.OpcodeRefNull, wasm.RefTypeFuncref, wasm.OpcodeRefIsNull, wasm.OpcodeEnd}},
+		{Body: []byte{wasm.OpcodeRefNull, wasm.RefTypeExternref, wasm.OpcodeRefIsNull, wasm.OpcodeEnd}},
+		{Body: []byte{wasm.OpcodeRefFunc, 0, wasm.OpcodeRefIsNull, wasm.OpcodeEnd}},
+	},
+	ExportSection: []*wasm.Export{
+		{Name: "externref", Type: wasm.ExternTypeFunc, Index: 0},
+		{Name: "null_funcref", Type: wasm.ExternTypeFunc, Index: 1},
+		{Name: "null_externref", Type: wasm.ExternTypeFunc, Index: 2},
+		{Name: "funcref", Type: wasm.ExternTypeFunc, Index: 3},
+	},
+})
+
+func testRefIsNull(t *testing.T, r wazero.Runtime) {
+	mod, err := r.InstantiateModuleFromBinary(testCtx, refIsNullWasm)
+	require.NoError(t, err)
+	defer mod.Close(testCtx)
+
+	nonNull := uint64(uintptr(unsafe.Pointer(&struct{ name string }{name: "dog"})))
+
+	for _, tc := range []struct {
+		function string
+		params   []uint64
+		expected uint64
+	}{
+		{function: "externref", params: []uint64{0}, expected: 1},
+		{function: "externref", params: []uint64{nonNull}, expected: 0},
+		{function: "null_funcref", expected: 1},
+		{function: "null_externref", expected: 1},
+		{function: "funcref", expected: 0},
+	} {
+		results, err := mod.ExportedFunction(tc.function).Call(testCtx, tc.params...)
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, results[0], "%s%v", tc.function, tc.params)
+	}
+}
+
+// divRemWasm exports a function per integer division and remainder instruction, named after it. Each returns the
+// result of the instruction on its parameters.
+var divRemWasm = func() []byte {
+	i32, i64 := wasm.ValueTypeI32, wasm.ValueTypeI64
+	m := &wasm.Module{
+		TypeSection: []*wasm.FunctionType{
+			{Params: []wasm.ValueType{i32, i32}, Results: []wasm.ValueType{i32}, ParamNumInUint64: 2, ResultNumInUint64: 1},
+			{Params: []wasm.ValueType{i64, i64}, Results: []wasm.ValueType{i64}, ParamNumInUint64: 2, ResultNumInUint64: 1},
+		},
+	}
+	for _, op := range []struct {
+		opcode wasm.Opcode
+		typeID wasm.Index
+	}{
+		{wasm.OpcodeI32DivS, 0}, {wasm.OpcodeI32DivU, 0}, {wasm.OpcodeI32RemS, 0}, {wasm.OpcodeI32RemU, 0},
+		{wasm.OpcodeI64DivS, 1}, {wasm.OpcodeI64DivU, 1}, {wasm.OpcodeI64RemS, 1}, {wasm.OpcodeI64RemU, 1},
+	} {
+		idx := wasm.Index(len(m.FunctionSection))
+		m.FunctionSection = append(m.FunctionSection, op.typeID)
+		m.CodeSection = append(m.CodeSection, &wasm.Code{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, op.opcode, wasm.OpcodeEnd}})
+		m.ExportSection = append(m.ExportSection, &wasm.Export{Name: wasm.InstructionName(op.opcode), Type: wasm.ExternTypeFunc, Index: idx})
+	}
+	return binaryformat.EncodeModule(m)
+}()
+
+// testDivRem ensures integer division traps exactly as the spec defines: any division or remainder by zero traps, and
+// so does signed division of the minimum value by -1 as the quotient overflows. The remainder of the latter is zero.
+// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#-hrefop-idiv-smathrmidiv_s_n-i_1-i_2
+// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#-hrefop-irem-smathrmirem_s_n-i_1-i_2
+func testDivRem(t *testing.T, r wazero.Runtime) {
+	mod, err := r.InstantiateModuleFromBinary(testCtx, divRemWasm)
+	require.NoError(t, err)
+	defer mod.Close(testCtx)
+
+	const (
+		minInt32 = uint64(0x80000000)
+		minInt64 = uint64(0x8000000000000000)
+		minus1   = uint64(math.MaxUint32)
+		// garbage32 has bits set above 32, which an i32 instruction must ignore.
+		garbage32 = uint64(0xffffffff00000000)
+	)
+
+	for _, tc := range []struct {
+		fn       string
+		params   []uint64
+		expected uint64
+		trap     api.TrapReason // when non-zero, expected is ignored
+	}{
+		{fn: "i32.div_s", params: []uint64{7, 0}, trap: api.TrapReasonIntegerDivideByZero},
+		{fn: "i32.div_s", params: []uint64{7, garbage32}, trap: api.TrapReasonIntegerDivideByZero},
+		{fn: "i32.div_s", params: []uint64{minInt32, minus1}, trap: api.TrapReasonIntegerOverflow},
+		{fn: "i32.div_s", params: []uint64{minInt32, 1}, expected: minInt32},
+		{fn: "i32.div_s", params: []uint64{minInt32 + 1, minus1}, expected: uint64(math.MaxInt32)},
+		{fn: "i32.div_s", params: []uint64{minus1 - 6, 2}, expected: minus1 - 2}, // -7 / 2 = -3, truncating
+		{fn: "i32.div_u", params: []uint64{7, 0}, trap: api.TrapReasonIntegerDivideByZero},
+		{fn: "i32.div_u", params: []uint64{minInt32, minus1}, expected: 0},
+		{fn: "i32.div_u", params: []uint64{minus1, 2}, expected: uint64(math.MaxInt32)},
+		{fn: "i32.rem_s", params: []uint64{7, 0}, trap: api.TrapReasonIntegerDivideByZero},
+		{fn: "i32.rem_s", params: []uint64{minInt32, minus1}, expected: 0},
+		{fn: "i32.rem_s", params: []uint64{garbage32 | minInt32, garbage32 | minus1}, expected: 0},
+		{fn: "i32.rem_s", params: []uint64{minus1 - 6, 2}, expected: minus1}, // -7 % 2 = -1, the sign of the dividend
+		{fn: "i32.rem_u", params: []uint64{7, 0}, trap: api.TrapReasonIntegerDivideByZero},
+		{fn: "i32.rem_u", params: []uint64{minInt32, minus1}, expected: minInt32},
+		{fn: "i64.div_s", params: []uint64{7, 0}, trap: api.TrapReasonIntegerDivideByZero},
+		{fn: "i64.div_s", params: []uint64{minInt64, math.MaxUint64}, trap: api.TrapReasonIntegerOverflow},
+		{fn: "i64.div_s", params: []uint64{minInt64, 1}, expected: minInt64},
+		{fn: "i64.div_s", params: []uint64{minInt64 + 1, math.MaxUint64}, expected: math.MaxInt64},
+		{fn: "i64.div_u", params: []uint64{7, 0}, trap: api.TrapReasonIntegerDivideByZero},
+		{fn: "i64.div_u", params: []uint64{minInt64, math.MaxUint64}, expected: 0},
+		{fn: "i64.rem_s", params: []uint64{7, 0}, trap: api.TrapReasonIntegerDivideByZero},
+		{fn: "i64.rem_s", params: []uint64{minInt64, math.MaxUint64}, expected: 0},
+		{fn: "i64.rem_s", params: []uint64{math.MaxUint64 - 6, 2}, expected: math.MaxUint64},
+		{fn: "i64.rem_u", params: []uint64{7, 0}, trap: api.TrapReasonIntegerDivideByZero},
+		{fn: "i64.rem_u", params: []uint64{minInt64, math.MaxUint64}, expected: minInt64},
+	} {
+		results, err := mod.ExportedFunction(tc.fn).Call(testCtx, tc.params...)
+		if tc.trap != 0 {
+			var trap api.TrapError
+			require.True(t, errors.As(err, &trap), "%s%v: expected a trap, but was %v", tc.fn, tc.params, err)
+			require.Equal(t, tc.trap, trap.TrapReason(), "%s%v: expected %s, but was %s", tc.fn, tc.params, tc.trap, trap.TrapReason())
+			continue
+		}
+		require.NoError(t, err, "%s%v", tc.fn, tc.params)
+		require.Equal(t, tc.expected, results[0], "%s%v: expected %#x, but was %#x", tc.fn, tc.params, tc.expected, results[0])
+	}
+}
+
+// nearestWasm exports "f32.nearest" and "f64.nearest", which return the result of the instruction on their parameter.
+var nearestWasm = func() []byte {
+	f32, f64 := wasm.ValueTypeF32, wasm.ValueTypeF64
+	return binaryformat.EncodeModule(&wasm.Module{
+		TypeSection: []*wasm.FunctionType{
+			{Params: []wasm.ValueType{f32}, Results: []wasm.ValueType{f32}, ParamNumInUint64: 1, ResultNumInUint64: 1},
+			{Params: []wasm.ValueType{f64}, Results: []wasm.ValueType{f64}, ParamNumInUint64: 1, ResultNumInUint64: 1},
+		},
+		FunctionSection: []wasm.Index{0, 1},
+		CodeSection: []*wasm.Code{
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeF32Nearest, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeF64Nearest, wasm.OpcodeEnd}},
+		},
+		ExportSection: []*wasm.Export{
+			{Name: "f32.nearest", Type: wasm.ExternTypeFunc, Index: 0},
+			{Name: "f64.nearest", Type: wasm.ExternTypeFunc, Index: 1},
+		},
+	})
+}()
+
+// testNearest ensures f32.nearest and f64.nearest round halfway cases to the even neighbor, preserving the sign of
+// zero. As both engines run the same expectations, this also cross-checks the compiler, which uses native rounding
+// instructions, with the interpreter, which uses moremath.WasmCompatNearestF32 and moremath.WasmCompatNearestF64.
+// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#-hrefop-fnearestmathrmfnearest_n-z
+func testNearest(t *testing.T, r wazero.Runtime) {
+	mod, err := r.InstantiateModuleFromBinary(testCtx, nearestWasm)
+	require.NoError(t, err)
+	defer mod.Close(testCtx)
+
+	f32, f64 := mod.ExportedFunction("f32.nearest"), mod.ExportedFunction("f64.nearest")
+	negZero := math.Copysign(0, -1)
+
+	for _, tc := range []struct {
+		input, expected float64
+	}{
+		{input: 0.5, expected: 0},
+		{input: 1.5, expected: 2},
+		{input: 2.5, expected: 2},
+		{input: -0.5, expected: negZero},
+		{input: -2.5, expected: -2},
+		{input: -0.25, expected: negZero},
+		{input: negZero, expected: negZero},
+		{input: 8388607.5, expected: 8388608}, // 2^23-0.5: the largest f32 with a fraction
+		{input: math.Inf(-1), expected: math.Inf(-1)},
+	} {
+		results, err := f32.Call(testCtx, api.EncodeF32(float32(tc.input)))
+		require.NoError(t, err)
+		require.Equal(t, math.Float32bits(float32(tc.expected)), uint32(results[0]),
+			"f32.nearest(%v): expected %v, but was %v", tc.input, tc.expected, api.DecodeF32(results[0]))
+
+		results, err = f64.Call(testCtx, api.EncodeF64(tc.input))
+		require.NoError(t, err)
+		require.Equal(t, math.Float64bits(tc.expected), results[0],
+			"f64.nearest(%v): expected %v, but was %v", tc.input, tc.expected, api.DecodeF64(results[0]))
+	}
+
+	// Sweep quarters, which include every kind of halfway case, against the interpreter's implementation.
+	for i := -64; i <= 64; i++ {
+		v := float64(i) / 4
+		results, err := f32.Call(testCtx, api.EncodeF32(float32(v)))
+		require.NoError(t, err)
+		require.Equal(t, math.Float32bits(moremath.WasmCompatNearestF32(float32(v))), uint32(results[0]), "f32.nearest(%v)", v)
+
+		results, err = f64.Call(testCtx, api.EncodeF64(v))
+		require.NoError(t, err)
+		require.Equal(t, math.Float64bits(moremath.WasmCompatNearestF64(v)), results[0], "f64.nearest(%v)", v)
+	}
+
+	// NaN remains NaN.
+	results, err := f32.Call(testCtx, api.EncodeF32(float32(math.NaN())))
+	require.NoError(t, err)
+	require.True(t, math.IsNaN(float64(api.DecodeF32(results[0]))))
+	results, err = f64.Call(testCtx, api.EncodeF64(math.NaN()))
+	require.NoError(t, err)
+	require.True(t, math.IsNaN(api.DecodeF64(results[0])))
+}
+
+// deadCodeWasm exports functions with code after unreachable, br and return, which are stack-polymorphic, so only
+// validate as the operand stack is unconstrained until the end of the enclosing block.
+var deadCodeWasm = binaryformat.EncodeModule(&wasm.Module{
+	TypeSection: []*wasm.FunctionType{
+		{Params: []wasm.ValueType{wasm.ValueTypeI32}, Results: []wasm.ValueType{wasm.ValueTypeI32}},
+	},
+	FunctionSection: []wasm.Index{0, 0, 0},
+	CodeSection: []*wasm.Code{
+		{Body: []byte{ // i32.const 7; return; unreachable; i32.add
+			wasm.OpcodeI32Const, 7, wasm.OpcodeReturn,
+			wasm.OpcodeUnreachable, wasm.OpcodeI32Add,
+			wasm.OpcodeEnd,
+		}},
+		{Body: []byte{ // block (result i32) i32.const 8; br 0; unreachable; i32.add end
+			wasm.OpcodeBlock, wasm.ValueTypeI32,
+			wasm.OpcodeI32Const, 8, wasm.OpcodeBr, 0,
+			wasm.OpcodeUnreachable, wasm.OpcodeI32Add,
+			wasm.OpcodeEnd,
+			wasm.OpcodeEnd,
+		}},
+		{Body: []byte{ // if (result i32) (local.get 0) unreachable; drop else i32.const 9 end
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeIf, wasm.ValueTypeI32,
+			wasm.OpcodeUnreachable, wasm.OpcodeDrop,
+			wasm.OpcodeElse,
+			wasm.OpcodeI32Const, 9,
+			wasm.OpcodeEnd,
+			wasm.OpcodeEnd,
+		}},
+	},
+	ExportSection: []*wasm.Export{
+		{Name: "after_return", Type: wasm.ExternTypeFunc, Index: 0},
+		{Name: "after_br", Type: wasm.ExternTypeFunc, Index: 1},
+		{Name: "if_unreachable", Type: wasm.ExternTypeFunc, Index: 2},
+	},
+})
+
+// testUnreachableDeadCode ensures dead code after unreachable, br or return is never executed, and that unreachable
+// traps when it is reached.
+func testUnreachableDeadCode(t *testing.T, r wazero.Runtime) {
+	mod, err := r.InstantiateModuleFromBinary(testCtx, deadCodeWasm)
+	require.NoError(t, err)
+	defer mod.Close(testCtx)
+
+	for _, tc := range []struct {
+		function string
+		param    uint64
+		expected uint64
+	}{
+		{function: "after_return", expected: 7},
+		{function: "after_br", expected: 8},
+		{function: "if_unreachable", param: 0, expected: 9},
+	} {
+		results, err := mod.ExportedFunction(tc.function).Call(testCtx, tc.param)
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, results[0], tc.function)
+	}
+
+	_, err = mod.ExportedFunction("if_unreachable").Call(testCtx, 1)
+	var trap api.TrapError
+	require.True(t, errors.As(err, &trap), "expected a trap, but was %v", err)
+	require.Equal(t, api.TrapReasonUnreachable, trap.TrapReason())
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"os"
+	"path"
 	"testing"
 	"testing/fstest"
 	"testing/iotest"
@@ -54,8 +56,8 @@ func (fs *wasiFs) Open(name string) (fs.File, error) {
 	dirflags := uint32(0) // arbitrary dirflags
 	pathLen := len(pathBytes)
 	oflags := uint32(0) // arbitrary oflags
-	// rights are ignored per https://github.com/WebAssembly/WASI/issues/469#issuecomment-1045251844
-	fsRightsBase, fsRightsInheriting := uint64(1), uint64(2)
+	// RIGHTS_FD_READ|RIGHTS_FD_SEEK, as wasiFile only reads and seeks.
+	fsRightsBase, fsRightsInheriting := uint64(1<<1|1<<2), uint64(0)
 	fdflags := uint32(0) // arbitrary fdflags
 	res, err := fs.pathOpen.Call(
 		testCtx,
@@ -150,34 +152,36 @@ func TestReader(t *testing.T) {
 	r := wazero.NewRuntime()
 	defer r.Close(testCtx)
 
-	_, err := wasi_snapshot_preview1.Instantiate(testCtx, r)
-	require.NoError(t, err)
-
 	realFs := fstest.MapFS{"animals.txt": &fstest.MapFile{Data: animals}}
 	sys := wazero.NewModuleConfig().WithWorkDirFS(realFs)
 
-	// Create a module that just delegates to wasi functions.
-	compiled, err := r.CompileModule(testCtx, fsWasm, wazero.NewCompileConfig())
+	wasiFs := newWasiFs(t, r, sys, 3)
+
+	f, err := wasiFs.Open("animals.txt")
 	require.NoError(t, err)
+	defer f.Close()
 
-	mod, err := r.InstantiateModule(testCtx, compiled, sys)
+	err = iotest.TestReader(f, animals)
 	require.NoError(t, err)
+}
 
-	pathOpen := mod.ExportedFunction("path_open")
-	fdClose := mod.ExportedFunction("fd_close")
-	fdRead := mod.ExportedFunction("fd_read")
-	fdSeek := mod.ExportedFunction("fd_seek")
+func TestReader_WithOsWorkdir(t *testing.T) {
+	// Create the file in the working directory of the test, which the guest should see as its own.
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "animals.txt"), animals, 0o600))
 
-	wasiFs := &wasiFs{
-		t:         t,
-		wasm:      r,
-		memory:    mod.Memory(),
-		workdirFd: uint32(3),
-		pathOpen:  pathOpen,
-		fdClose:   fdClose,
-		fdRead:    fdRead,
-		fdSeek:    fdSeek,
-	}
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(tmpDir))
+	defer os.Chdir(wd) //nolint
+
+	r := wazero.NewRuntime()
+	defer r.Close(testCtx)
+
+	sys := wazero.NewModuleConfig().WithOsWorkdir()
+
+	// The working directory (".") is the second pre-open, after the root ("/").
+	wasiFs := newWasiFs(t, r, sys, 4)
 
 	f, err := wasiFs.Open("animals.txt")
 	require.NoError(t, err)
@@ -186,3 +190,27 @@ func TestReader(t *testing.T) {
 	err = iotest.TestReader(f, animals)
 	require.NoError(t, err)
 }
+
+// newWasiFs instantiates a module that just delegates to wasi functions, and returns a wasiFs which opens files in the
+// pre-opened directory workdirFd.
+func newWasiFs(t *testing.T, r wazero.Runtime, sys wazero.ModuleConfig, workdirFd uint32) *wasiFs {
+	_, err := wasi_snapshot_preview1.Instantiate(testCtx, r)
+	require.NoError(t, err)
+
+	compiled, err := r.CompileModule(testCtx, fsWasm, wazero.NewCompileConfig())
+	require.NoError(t, err)
+
+	mod, err := r.InstantiateModule(testCtx, compiled, sys)
+	require.NoError(t, err)
+
+	return &wasiFs{
+		t:         t,
+		wasm:      r,
+		memory:    mod.Memory(),
+		workdirFd: workdirFd,
+		pathOpen:  mod.ExportedFunction("path_open"),
+		fdClose:   mod.ExportedFunction("fd_close"),
+		fdRead:    mod.ExportedFunction("fd_read"),
+		fdSeek:    mod.ExportedFunction("fd_seek"),
+	}
+}
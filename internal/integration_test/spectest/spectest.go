@@ -255,7 +255,7 @@ func (c command) expectedError() (err error) {
 		err = wasmruntime.ErrRuntimeUnreachable
 	default:
 		if strings.HasPrefix(c.Text, "uninitialized") {
-			err = wasmruntime.ErrRuntimeInvalidTableAccess
+			err = wasmruntime.ErrRuntimeIndirectCallToNull
 		}
 	}
 	return
@@ -1,6 +1,7 @@
 package spectest
 
 import (
+	"context"
 	"embed"
 	"runtime"
 	"testing"
@@ -24,6 +25,29 @@ func TestCompiler(t *testing.T) {
 	spectest.Run(t, testcases, compiler.NewEngine, enabledFeatures, func(string) bool { return true })
 }
 
+// TestCompiler_FastCompile ensures skipping optional compilation passes doesn't change results.
+// See wazero.CompileConfig WithOptimizationLevel
+func TestCompiler_FastCompile(t *testing.T) {
+	if runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64" {
+		t.Skip()
+	}
+	newEngine := func(enabledFeatures wasm.Features) wasm.Engine {
+		return &fastCompileEngine{compiler.NewEngine(enabledFeatures)}
+	}
+	spectest.Run(t, testcases, newEngine, enabledFeatures, func(string) bool { return true })
+}
+
+// fastCompileEngine compiles modules as if wazero.CompileConfig WithOptimizationLevel was OptimizationLevelMinimal.
+type fastCompileEngine struct {
+	wasm.Engine
+}
+
+// CompileModule implements the same method as documented on wasm.Engine.
+func (e *fastCompileEngine) CompileModule(ctx context.Context, module *wasm.Module) error {
+	module.FastCompile = true
+	return e.Engine.CompileModule(ctx, module)
+}
+
 func TestInterpreter(t *testing.T) {
 	spectest.Run(t, testcases, interpreter.NewEngine, enabledFeatures, func(jsonname string) bool { return true })
 }
@@ -2,15 +2,27 @@ package moremath
 
 import "math"
 
+const (
+	// F32CanonicalNaNBits is the 32-bit float where all bits of the significand are zero except the most significant
+	// one, which is the canonical NaN that Wasm instructions produce when their operands are canonical NaN.
+	//
+	// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#nan%E2%91%A0
+	F32CanonicalNaNBits = uint32(0x7fc0_0000)
+	// F64CanonicalNaNBits is the 64-bit variant of F32CanonicalNaNBits.
+	F64CanonicalNaNBits = uint64(0x7ff8_0000_0000_0000)
+)
+
 // WasmCompatMin is the Wasm spec compatible variant of math.Min
 //
-// This returns math.NaN if either parameter is math.NaN, even if the other is -math.Inf.
+// This returns the canonical NaN if either parameter is NaN, even if the other is -math.Inf. Unlike math.Min, the
+// minimum of -0 and +0 is always -0.
 //
 // See https://github.com/golang/go/blob/1d20a362d0ca4898d77865e314ef6f73582daef0/src/math/dim.go#L74-L91
 func WasmCompatMin(x, y float64) float64 {
 	switch {
 	case math.IsNaN(x) || math.IsNaN(y): // NaN cannot be compared with themselves, so we have to use IsNaN
-		return math.NaN()
+		// Don't use math.NaN as it has a non-zero payload.
+		return math.Float64frombits(F64CanonicalNaNBits)
 	case math.IsInf(x, -1) || math.IsInf(y, -1):
 		return math.Inf(-1)
 	case x == 0 && x == y:
@@ -27,13 +39,15 @@ func WasmCompatMin(x, y float64) float64 {
 
 // WasmCompatMax is the Wasm spec compatible variant of math.Max
 //
-// This returns math.NaN if either parameter is math.NaN, even if the other is math.Inf.
+// This returns the canonical NaN if either parameter is NaN, even if the other is math.Inf. Unlike math.Max, the
+// maximum of -0 and +0 is always +0.
 //
 // See https://github.com/golang/go/blob/1d20a362d0ca4898d77865e314ef6f73582daef0/src/math/dim.go#L42-L59
 func WasmCompatMax(x, y float64) float64 {
 	switch {
 	case math.IsNaN(x) || math.IsNaN(y): // NaN cannot be compared with themselves, so we have to use IsNaN
-		return math.NaN()
+		// Don't use math.NaN as it has a non-zero payload.
+		return math.Float64frombits(F64CanonicalNaNBits)
 	case math.IsInf(x, 1) || math.IsInf(y, 1):
 		return math.Inf(1)
 
@@ -18,6 +18,19 @@ func TestWasmCompatMin(t *testing.T) {
 	require.True(t, math.IsNaN(WasmCompatMin(math.Inf(-1), math.NaN())))
 	require.True(t, math.IsNaN(WasmCompatMin(math.Inf(1), math.NaN())))
 	require.True(t, math.IsNaN(WasmCompatMin(math.NaN(), math.NaN())))
+
+	// The result is the canonical NaN, even if the NaN operand has a payload.
+	require.Equal(t, F64CanonicalNaNBits, math.Float64bits(WasmCompatMin(math.NaN(), 1.0)))
+	require.Equal(t, F64CanonicalNaNBits, math.Float64bits(WasmCompatMin(1.0, math.Float64frombits(F64CanonicalNaNBits))))
+
+	// Prevent constant folding by using two variables. -float64(0) is not actually negative.
+	// https://github.com/golang/go/issues/2196
+	zero := float64(0)
+	negZero := -zero
+
+	require.Equal(t, math.Float64bits(negZero), math.Float64bits(WasmCompatMin(negZero, zero)))
+	require.Equal(t, math.Float64bits(negZero), math.Float64bits(WasmCompatMin(zero, negZero)))
+	require.Equal(t, math.Float64bits(zero), math.Float64bits(WasmCompatMin(zero, zero)))
 }
 
 func TestWasmCompatMax(t *testing.T) {
@@ -31,6 +44,19 @@ func TestWasmCompatMax(t *testing.T) {
 	require.True(t, math.IsNaN(WasmCompatMax(math.Inf(-1), math.NaN())))
 	require.True(t, math.IsNaN(WasmCompatMax(math.Inf(1), math.NaN())))
 	require.True(t, math.IsNaN(WasmCompatMax(math.NaN(), math.NaN())))
+
+	// The result is the canonical NaN, even if the NaN operand has a payload.
+	require.Equal(t, F64CanonicalNaNBits, math.Float64bits(WasmCompatMax(math.NaN(), 1.0)))
+	require.Equal(t, F64CanonicalNaNBits, math.Float64bits(WasmCompatMax(1.0, math.Float64frombits(F64CanonicalNaNBits))))
+
+	// Prevent constant folding by using two variables. -float64(0) is not actually negative.
+	// https://github.com/golang/go/issues/2196
+	zero := float64(0)
+	negZero := -zero
+
+	require.Equal(t, math.Float64bits(zero), math.Float64bits(WasmCompatMax(negZero, zero)))
+	require.Equal(t, math.Float64bits(zero), math.Float64bits(WasmCompatMax(zero, negZero)))
+	require.Equal(t, math.Float64bits(negZero), math.Float64bits(WasmCompatMax(negZero, negZero)))
 }
 
 func TestWasmCompatNearestF32(t *testing.T) {
@@ -52,6 +78,38 @@ func TestWasmCompatNearestF32(t *testing.T) {
 	require.True(t, math.Signbit(float64(WasmCompatNearestF32(negZero))))
 }
 
+func TestWasmCompatNearest_HalfToEven(t *testing.T) {
+	// Halfway cases round to the even neighbor, unlike math.Round which rounds away from zero.
+	for _, tc := range []struct {
+		input, expected float64
+	}{
+		{input: 0.5, expected: 0},
+		{input: 1.5, expected: 2},
+		{input: 2.5, expected: 2},
+		{input: 3.5, expected: 4},
+		{input: -0.5, expected: math.Copysign(0, -1)},
+		{input: -1.5, expected: -2},
+		{input: -2.5, expected: -2},
+		{input: 0.49999999999999994, expected: 0},               // the largest f64 below 0.5
+		{input: 8388607.5, expected: 8388608},                   // 2^23-0.5: the largest f32 with a fraction
+		{input: 4503599627370495.5, expected: 4503599627370496}, // 2^52-0.5: the largest f64 with a fraction
+	} {
+		actual64 := WasmCompatNearestF64(tc.input)
+		require.Equal(t, math.Float64bits(tc.expected), math.Float64bits(actual64), "f64.nearest(%v) = %v", tc.input, actual64)
+
+		if f32 := float32(tc.input); float64(f32) == tc.input { // skip inputs f32 can't represent
+			actual32 := WasmCompatNearestF32(f32)
+			require.Equal(t, math.Float32bits(float32(tc.expected)), math.Float32bits(actual32), "f32.nearest(%v) = %v", f32, actual32)
+		}
+	}
+
+	// Non-finite values are unchanged.
+	require.True(t, math.IsNaN(WasmCompatNearestF64(math.NaN())))
+	require.True(t, math.IsNaN(float64(WasmCompatNearestF32(float32(math.NaN())))))
+	require.Equal(t, math.Inf(-1), WasmCompatNearestF64(math.Inf(-1)))
+	require.Equal(t, float32(math.Inf(1)), WasmCompatNearestF32(float32(math.Inf(1))))
+}
+
 func TestWasmCompatNearestF64(t *testing.T) {
 	require.Equal(t, WasmCompatNearestF64(-1.5), -2.0)
 
@@ -17,6 +17,9 @@ func FakeNanotime(context.Context) int64 {
 	return FakeEpochNanos
 }
 
+// FakeNanosleep implements sys.Nanosleep by returning immediately.
+func FakeNanosleep(context.Context, int64) {}
+
 // Walltime implements sys.Walltime with time.Now.
 //
 // Note: This is only notably less efficient than it could be is reading
@@ -46,3 +49,19 @@ func nanotimePortable() int64 {
 func Nanotime(context.Context) int64 {
 	return nanotime()
 }
+
+// Nanosleep implements sys.Nanosleep with time.Timer, returning early when the context is done.
+func Nanosleep(ctx context.Context, ns int64) {
+	if ns <= 0 {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	t := time.NewTimer(time.Duration(ns))
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
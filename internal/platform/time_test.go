@@ -45,3 +45,21 @@ func Test_Nanotime(t *testing.T) {
 		})
 	}
 }
+
+func Test_Nanosleep(t *testing.T) {
+	t.Run("sleeps", func(t *testing.T) {
+		ns := int64(time.Millisecond)
+		start := Nanotime(context.Background())
+		Nanosleep(context.Background(), ns)
+		require.True(t, Nanotime(context.Background())-start >= ns)
+	})
+
+	t.Run("returns when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		start := time.Now()
+		Nanosleep(ctx, int64(time.Hour))
+		require.True(t, time.Since(start) < time.Hour)
+	})
+}
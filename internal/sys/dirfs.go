@@ -0,0 +1,129 @@
+package sys
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// OpenFileFS is implemented by a fs.FS that can open files for writing. Mounts whose file system does not implement
+// this are read-only with regard to file contents.
+//
+// Note: This is matched structurally, so implementations needn't import this package.
+type OpenFileFS interface {
+	fs.FS
+
+	// OpenFile is like os.OpenFile, where flag is a combination of flags such as os.O_RDWR and os.O_CREATE, and perm
+	// are the permission bits used when creating the file.
+	//
+	// The name is a path accepted by fs.ValidPath. Errors should wrap fs.ErrExist or fs.ErrNotExist similar to Mkdir.
+	OpenFile(name string, flag int, perm fs.FileMode) (fs.File, error)
+}
+
+// NewDirFS returns a file system rooted at the host directory dir, which implements OpenFileFS, MkdirFS, RmdirFS,
+// ReadLinkFS and ChtimesFS.
+//
+// Like os.DirFS, names are validated by fs.ValidPath, so cannot escape dir with ".." elements. However, symbolic links
+// inside dir are followed, even if they point outside it.
+func NewDirFS(dir string) fs.FS {
+	return dirFS(dir)
+}
+
+// dirFS is similar to the type returned by os.DirFS, except it can also write.
+type dirFS string
+
+// Open implements fs.FS
+func (dir dirFS) Open(name string) (fs.File, error) {
+	return dir.OpenFile(name, os.O_RDONLY, 0)
+}
+
+// OpenFile implements OpenFileFS
+func (dir dirFS) OpenFile(name string, flag int, perm fs.FileMode) (fs.File, error) {
+	fullPath, err := dir.join("open", name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(fullPath, flag, perm)
+	if err != nil {
+		return nil, err // Don't return a typed nil as a non-nil fs.File.
+	}
+	return f, nil
+}
+
+// Mkdir implements MkdirFS
+func (dir dirFS) Mkdir(name string, perm fs.FileMode) error {
+	fullPath, err := dir.join("mkdir", name)
+	if err != nil {
+		return err
+	}
+	return os.Mkdir(fullPath, perm)
+}
+
+// Rmdir implements RmdirFS
+func (dir dirFS) Rmdir(name string) error {
+	fullPath, err := dir.join("rmdir", name)
+	if err != nil {
+		return err
+	} else if name == "." { // Don't remove dir itself, similar to rmdir(".") in POSIX.
+		return &fs.PathError{Op: "rmdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	// Check the directory first, as os.Remove also removes files, and its error for a non-empty directory varies by
+	// platform.
+	f, err := os.Open(fullPath)
+	if err != nil {
+		return err
+	}
+	stat, err := f.Stat()
+	var names []string
+	if err == nil && stat.IsDir() {
+		names, err = f.Readdirnames(1)
+	}
+	_ = f.Close() // Close before removing, as Windows can't remove an open directory.
+
+	switch {
+	case err != nil && err != io.EOF: // Readdirnames returns io.EOF when empty.
+		return err
+	case !stat.IsDir():
+		return &fs.PathError{Op: "rmdir", Path: name, Err: syscall.ENOTDIR}
+	case len(names) > 0:
+		return &fs.PathError{Op: "rmdir", Path: name, Err: syscall.ENOTEMPTY}
+	}
+	return os.Remove(fullPath)
+}
+
+// ReadLink implements ReadLinkFS
+func (dir dirFS) ReadLink(name string) (string, error) {
+	fullPath, err := dir.join("readlink", name)
+	if err != nil {
+		return "", err
+	}
+	target, err := os.Readlink(fullPath)
+	if errors.Is(err, syscall.EINVAL) { // not a symbolic link
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	return filepath.ToSlash(target), err
+}
+
+// Chtimes implements ChtimesFS
+func (dir dirFS) Chtimes(name string, atime, mtime time.Time) error {
+	fullPath, err := dir.join("chtimes", name)
+	if err != nil {
+		return err
+	}
+	return os.Chtimes(fullPath, atime, mtime)
+}
+
+// join returns the host path of name, or an error if it isn't valid or would escape dir.
+func (dir dirFS) join(op, name string) (string, error) {
+	if !fs.ValidPath(name) || runtime.GOOS == "windows" && strings.ContainsAny(name, `\:`) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return filepath.Join(string(dir), filepath.FromSlash(name)), nil
+}
@@ -0,0 +1,105 @@
+package sys
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestDirFS(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFS := NewDirFS(tmpDir)
+
+	// The result can both write files and create directories.
+	openFileFS, mkdirFS := testFS.(OpenFileFS), testFS.(MkdirFS)
+
+	t.Run("OpenFile creates and writes", func(t *testing.T) {
+		f, err := openFileFS.OpenFile("created", os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+		require.NoError(t, err)
+		_, err = f.(io.Writer).Write([]byte("wazero"))
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		b, err := os.ReadFile(path.Join(tmpDir, "created"))
+		require.NoError(t, err)
+		require.Equal(t, "wazero", string(b))
+
+		// Open reads what was written.
+		b, err = fs.ReadFile(testFS, "created")
+		require.NoError(t, err)
+		require.Equal(t, "wazero", string(b))
+	})
+
+	t.Run("OpenFile exclusive", func(t *testing.T) {
+		_, err := openFileFS.OpenFile("created", os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+		require.True(t, errors.Is(err, fs.ErrExist))
+	})
+
+	t.Run("Mkdir", func(t *testing.T) {
+		require.NoError(t, mkdirFS.Mkdir("dir", 0o700))
+		stat, err := os.Stat(path.Join(tmpDir, "dir"))
+		require.NoError(t, err)
+		require.True(t, stat.IsDir())
+
+		require.True(t, errors.Is(mkdirFS.Mkdir("dir", 0o700), fs.ErrExist))
+		require.True(t, errors.Is(mkdirFS.Mkdir("missing/dir", 0o700), fs.ErrNotExist))
+	})
+
+	t.Run("ReadLink", func(t *testing.T) {
+		readLinkFS := testFS.(ReadLinkFS)
+		if err := os.Symlink("created", path.Join(tmpDir, "link")); err != nil {
+			t.Skip("symbolic links are not supported:", err)
+		}
+
+		target, err := readLinkFS.ReadLink("link")
+		require.NoError(t, err)
+		require.Equal(t, "created", target)
+
+		_, err = readLinkFS.ReadLink("created")
+		require.True(t, errors.Is(err, fs.ErrInvalid))
+		_, err = readLinkFS.ReadLink("missing")
+		require.True(t, errors.Is(err, fs.ErrNotExist))
+	})
+
+	t.Run("Rmdir", func(t *testing.T) {
+		rmdirFS := testFS.(RmdirFS)
+		require.NoError(t, os.Mkdir(path.Join(tmpDir, "rmdir"), 0o700))
+		require.NoError(t, os.WriteFile(path.Join(tmpDir, "rmdir", "file"), []byte("wazero"), 0o600))
+
+		require.True(t, errors.Is(rmdirFS.Rmdir("rmdir"), syscall.ENOTEMPTY))
+		require.True(t, errors.Is(rmdirFS.Rmdir("rmdir/file"), syscall.ENOTDIR))
+		require.True(t, errors.Is(rmdirFS.Rmdir("missing"), fs.ErrNotExist))
+		require.True(t, errors.Is(rmdirFS.Rmdir("."), fs.ErrInvalid))
+
+		require.NoError(t, os.Remove(path.Join(tmpDir, "rmdir", "file")))
+		require.NoError(t, rmdirFS.Rmdir("rmdir"))
+		_, err := os.Stat(path.Join(tmpDir, "rmdir"))
+		require.True(t, errors.Is(err, fs.ErrNotExist))
+	})
+
+	t.Run("rejects paths that escape the directory", func(t *testing.T) {
+		for _, name := range []string{"../escaped", "/escaped", "dir/../../escaped"} {
+			_, err := testFS.Open(name)
+			require.True(t, errors.Is(err, fs.ErrInvalid), name)
+
+			_, err = openFileFS.OpenFile(name, os.O_RDWR|os.O_CREATE, 0o600)
+			require.True(t, errors.Is(err, fs.ErrInvalid), name)
+
+			require.True(t, errors.Is(mkdirFS.Mkdir(name, 0o700), fs.ErrInvalid), name)
+
+			_, err = testFS.(ReadLinkFS).ReadLink(name)
+			require.True(t, errors.Is(err, fs.ErrInvalid), name)
+
+			require.True(t, errors.Is(testFS.(RmdirFS).Rmdir(name), fs.ErrInvalid), name)
+		}
+
+		_, err := os.Stat(path.Join(path.Dir(tmpDir), "escaped"))
+		require.True(t, errors.Is(err, fs.ErrNotExist))
+	})
+}
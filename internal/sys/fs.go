@@ -4,8 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io/fs"
-	"math"
-	"sync/atomic"
+	"time"
 )
 
 // FSKey is a context.Context Value key. It allows overriding fs.FS for WASI.
@@ -19,42 +18,101 @@ type FileEntry struct {
 	FS   fs.FS
 	// File when nil this is a mount like "." or "/".
 	File fs.File
+
+	// Rights when nil means the file has all rights, as is the case for a mount. Otherwise, these are the rights
+	// granted to the guest when it opened the file.
+	Rights *Rights
+}
+
+// Rights are the WASI capabilities of a file descriptor, each a bit of the "rights" flags.
+//
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-rights-flagsu64
+type Rights struct {
+	// Base are the operations allowed on the file itself, ex. fd_write.
+	Base uint64
+	// Inheriting are the maximum rights of files opened relative to this one, when it is a directory.
+	Inheriting uint64
+}
+
+// MkdirFS is implemented by a fs.FS that can create directories. Mounts whose file system does not implement this
+// are read-only with regard to directory creation.
+//
+// Note: This is matched structurally, so implementations needn't import this package.
+type MkdirFS interface {
+	fs.FS
+
+	// Mkdir creates a new directory with the specified name and permission bits, similar to os.Mkdir.
+	//
+	// The name is a path accepted by fs.ValidPath. Errors should wrap fs.ErrExist when the name already exists and
+	// fs.ErrNotExist when its parent directory does not.
+	Mkdir(name string, perm fs.FileMode) error
+}
+
+// RmdirFS is implemented by a fs.FS that can remove directories. Mounts whose file system does not implement this
+// are read-only with regard to directory removal.
+//
+// Note: This is matched structurally, so implementations needn't import this package.
+type RmdirFS interface {
+	fs.FS
+
+	// Rmdir removes the named directory only if it is empty, similar to syscall.Rmdir.
+	//
+	// The name is a path accepted by fs.ValidPath. Errors should wrap fs.ErrNotExist when it does not exist,
+	// syscall.ENOTDIR when it is not a directory and syscall.ENOTEMPTY when it has entries.
+	Rmdir(name string) error
+}
+
+// ReadLinkFS is implemented by a fs.FS that can report the target of a symbolic link. Mounts whose file system does not
+// implement this don't support symbolic links.
+//
+// Note: This is matched structurally, so implementations needn't import this package.
+type ReadLinkFS interface {
+	fs.FS
+
+	// ReadLink returns the destination of the named symbolic link, similar to os.Readlink.
+	//
+	// The name is a path accepted by fs.ValidPath. Errors should wrap fs.ErrInvalid when the name is not a symbolic
+	// link and fs.ErrNotExist when it does not exist.
+	ReadLink(name string) (string, error)
+}
+
+// ChtimesFS is implemented by a fs.FS that can change the access and modification times of a file. Mounts whose file
+// system does not implement this are read-only with regard to file times.
+//
+// Note: This is matched structurally, so implementations needn't import this package.
+type ChtimesFS interface {
+	fs.FS
+
+	// Chtimes changes the access and modification times of the named file, similar to os.Chtimes. A zero time.Time
+	// leaves the corresponding time unchanged.
+	//
+	// The name is a path accepted by fs.ValidPath. Errors should wrap fs.ErrNotExist when it does not exist.
+	Chtimes(name string, atime, mtime time.Time) error
 }
 
 type FSContext struct {
 	// openedFiles is a map of file descriptor numbers (>=3) to open files (or directories) and defaults to empty.
 	// TODO: This is unguarded, so not goroutine-safe!
 	openedFiles map[uint32]*FileEntry
-
-	// lastFD is not meant to be read directly. Rather by nextFD.
-	lastFD uint32
 }
 
 func NewFSContext(openedFiles map[uint32]*FileEntry) *FSContext {
-	var fsCtx FSContext
 	if openedFiles == nil {
-		fsCtx.openedFiles = map[uint32]*FileEntry{}
-		fsCtx.lastFD = 2 // STDERR
-	} else {
-		fsCtx.openedFiles = openedFiles
-		fsCtx.lastFD = 2 // STDERR
-		for fd := range openedFiles {
-			if fd > fsCtx.lastFD {
-				fsCtx.lastFD = fd
-			}
-		}
+		openedFiles = map[uint32]*FileEntry{}
 	}
-	return &fsCtx
+	return &FSContext{openedFiles: openedFiles}
 }
 
-// nextFD gets the next file descriptor number in a goroutine safe way (monotonically) or zero if we ran out.
+// nextFD gets the lowest file descriptor number not in use, or zero if we ran out. This allows a guest to re-use the
+// number of a closed file, similar to POSIX.
 // TODO: opendFiles is still not goroutine safe!
-// TODO: This can return zero if we ran out of file descriptors. A future change can optimize by re-using an FD pool.
 func (c *FSContext) nextFD() uint32 {
-	if c.lastFD == math.MaxUint32 {
-		return 0
+	for fd := uint32(3); fd != 0; fd++ { // after STDERR, until overflow
+		if _, ok := c.openedFiles[fd]; !ok {
+			return fd
+		}
 	}
-	return atomic.AddUint32(&c.lastFD, 1)
+	return 0
 }
 
 // Close implements io.Closer
@@ -88,6 +146,27 @@ func (c *FSContext) CloseFile(fd uint32) (bool, error) {
 	return true, nil
 }
 
+// RenumberFile moves the file opened at fd to the file descriptor `to`, closing any file already opened there. This
+// returns false if fd was not opened.
+//
+// Note: Callers should check that neither file descriptor is pre-opened, as renumbering one revokes its capability.
+func (c *FSContext) RenumberFile(fd, to uint32) (bool, error) {
+	f, ok := c.openedFiles[fd]
+	if !ok {
+		return false, nil
+	} else if fd == to {
+		return true, nil
+	}
+
+	var err error
+	if existing, ok := c.openedFiles[to]; ok && existing.File != nil {
+		err = existing.File.Close()
+	}
+	delete(c.openedFiles, fd)
+	c.openedFiles[to] = f
+	return true, err
+}
+
 // OpenedFile returns a file and true if it was opened or nil and false, if not.
 func (c *FSContext) OpenedFile(fd uint32) (*FileEntry, bool) {
 	f, ok := c.openedFiles[fd]
@@ -146,12 +225,14 @@ func (c *FSConfig) WithWorkDirFS(fs fs.FS) *FSConfig {
 	return &ret
 }
 
+// Preopens returns a new map of the pre-opened file descriptors. Each call returns a copy, as the caller owns the
+// result: closing a module deletes its entries, which must not affect other modules using the same configuration.
 func (c *FSConfig) Preopens() (map[uint32]*FileEntry, error) {
 	// Ensure no-one set a nil FD. We do this here instead of at the call site to allow chaining as nil is unexpected.
 	rootFD := uint32(0) // zero is invalid
 	setWorkDirFS := false
-	preopens := c.preopens
-	for fd, entry := range preopens {
+	preopens := make(map[uint32]*FileEntry, len(c.preopens)+1)
+	for fd, entry := range c.preopens {
 		if entry.FS == nil {
 			return nil, fmt.Errorf("FS for %s is nil", entry.Path)
 		} else if entry.Path == "/" {
@@ -159,6 +240,8 @@ func (c *FSConfig) Preopens() (map[uint32]*FileEntry, error) {
 		} else if entry.Path == "." {
 			setWorkDirFS = true
 		}
+		e := *entry // copy
+		preopens[fd] = &e
 	}
 
 	// Default the working directory to the root FS if it exists.
@@ -48,6 +48,58 @@ func TestContext_Close_Error(t *testing.T) {
 	require.Zero(t, len(fsc.openedFiles), "expected no opened files")
 }
 
+func TestFSContext_OpenFile_ReusesClosedFD(t *testing.T) {
+	fsc := NewFSContext(map[uint32]*FileEntry{3: {Path: "."}})
+
+	fd1, ok := fsc.OpenFile(&FileEntry{Path: "a", File: &testFile{}})
+	require.True(t, ok)
+	require.Equal(t, uint32(4), fd1)
+	fd2, ok := fsc.OpenFile(&FileEntry{Path: "b", File: &testFile{}})
+	require.True(t, ok)
+	require.Equal(t, uint32(5), fd2)
+
+	closed, err := fsc.CloseFile(fd1)
+	require.NoError(t, err)
+	require.True(t, closed)
+
+	// The lowest closed file descriptor is used next.
+	fd3, ok := fsc.OpenFile(&FileEntry{Path: "c", File: &testFile{}})
+	require.True(t, ok)
+	require.Equal(t, fd1, fd3)
+}
+
+func TestFSContext_RenumberFile(t *testing.T) {
+	from, to := &FileEntry{Path: "a", File: &testFile{}}, &FileEntry{Path: "b", File: &testFile{errors.New("error closing")}}
+	fsc := NewFSContext(map[uint32]*FileEntry{4: from, 5: to})
+
+	// The error closing the replaced file is returned, but the file is still renumbered.
+	ok, err := fsc.RenumberFile(4, 5)
+	require.True(t, ok)
+	require.EqualError(t, err, "error closing")
+
+	_, ok = fsc.OpenedFile(4)
+	require.False(t, ok)
+	f, ok := fsc.OpenedFile(5)
+	require.True(t, ok)
+	require.Equal(t, from, f)
+
+	t.Run("to unused fd", func(t *testing.T) {
+		ok, err := fsc.RenumberFile(5, 42)
+		require.True(t, ok)
+		require.NoError(t, err)
+
+		f, ok := fsc.OpenedFile(42)
+		require.True(t, ok)
+		require.Equal(t, from, f)
+	})
+
+	t.Run("fd not opened", func(t *testing.T) {
+		ok, err := fsc.RenumberFile(4, 5)
+		require.False(t, ok)
+		require.NoError(t, err)
+	})
+}
+
 // compile-time check to ensure testFile implements fs.File
 var _ fs.File = &testFile{}
 
@@ -0,0 +1,42 @@
+package sys
+
+import (
+	"io/fs"
+	"net"
+	"time"
+)
+
+// Socket is an open file backed by a connected net.Conn, ex. added with ModuleConfig.WithSocket. Functions such as WASI
+// "sock_recv" only accept file descriptors of a Socket.
+type Socket struct {
+	net.Conn
+}
+
+// compile-time check to ensure Socket implements fs.File
+var _ fs.File = &Socket{}
+
+// Stat implements fs.File
+func (s *Socket) Stat() (fs.FileInfo, error) {
+	return socketInfo{}, nil
+}
+
+// socketInfo is the fs.FileInfo of a Socket.
+type socketInfo struct{}
+
+// Name implements fs.FileInfo
+func (socketInfo) Name() string { return "" }
+
+// Size implements fs.FileInfo
+func (socketInfo) Size() int64 { return 0 }
+
+// Mode implements fs.FileInfo
+func (socketInfo) Mode() fs.FileMode { return fs.ModeSocket }
+
+// ModTime implements fs.FileInfo
+func (socketInfo) ModTime() time.Time { return time.Time{} }
+
+// IsDir implements fs.FileInfo
+func (socketInfo) IsDir() bool { return false }
+
+// Sys implements fs.FileInfo
+func (socketInfo) Sys() interface{} { return nil }
@@ -0,0 +1,109 @@
+package sys
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// stderrTailSize is the maximum count of bytes written to stderr retained for StderrEnd.
+const stderrTailSize = 4096
+
+// StderrMark is the state of Stderr when a function call began, returned by StderrBegin.
+type StderrMark struct {
+	// written is the count of bytes written to Stderr when the call began.
+	written uint64
+	// started is the count of calls begun, including this one.
+	started uint64
+	// concurrent is true if another call was in progress when this one began.
+	concurrent bool
+}
+
+// stderrTail writes to stderr, retaining the last bytes written, so that they can be attached to errors. This allows
+// seeing a guest panic message even if stderr discards it.
+//
+// Note: This is goroutine-safe, as functions of the same module can be called concurrently, and a trap can read the
+// tail while another call writes.
+type stderrTail struct {
+	// written is the count of bytes written so far. started and active count the calls begun and in progress, so that
+	// what another call wrote isn't attributed to a failing one.
+	//
+	// Note: These are first to ensure 64-bit alignment. Exclusively reading and updating them with atomics guarantees
+	// cross-goroutine observations, without locking when a call begins.
+	written, started, active uint64
+
+	w io.Writer
+
+	// mux guards tail and updates to written. It is not held while writing to w, so that a writer which blocks doesn't
+	// block other callers. Hence, concurrent writes can be retained in a different order than w received them.
+	mux sync.Mutex
+	// tail is a ring buffer of the last bytes written, where written%stderrTailSize is the next position to write. It
+	// is allocated on the first write, as most modules never write to stderr.
+	tail []byte
+}
+
+// Write implements io.Writer
+func (s *stderrTail) Write(p []byte) (n int, err error) {
+	n, err = s.w.Write(p)
+	b := p[:n]
+	if len(b) == 0 {
+		return
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.tail == nil {
+		s.tail = make([]byte, stderrTailSize)
+	}
+	written := atomic.LoadUint64(&s.written)
+	if len(b) > stderrTailSize {
+		written += uint64(len(b) - stderrTailSize)
+		b = b[len(b)-stderrTailSize:]
+	}
+	for len(b) > 0 {
+		copied := copy(s.tail[written%stderrTailSize:], b)
+		written += uint64(copied)
+		b = b[copied:]
+	}
+	atomic.StoreUint64(&s.written, written)
+	return
+}
+
+// begin implements Context.StderrBegin
+func (s *stderrTail) begin() StderrMark {
+	started := atomic.AddUint64(&s.started, 1)
+	concurrent := atomic.AddUint64(&s.active, 1) > 1
+	return StderrMark{written: atomic.LoadUint64(&s.written), started: started, concurrent: concurrent}
+}
+
+// end implements Context.StderrEnd
+func (s *stderrTail) end(mark StderrMark, failed bool) (ret []byte) {
+	if failed && !mark.concurrent && atomic.LoadUint64(&s.started) == mark.started {
+		ret = s.since(mark.written)
+	}
+	atomic.AddUint64(&s.active, ^uint64(0)) // decrement
+	return
+}
+
+// since returns up to the last stderrTailSize bytes written after the given count of bytes written, or nil if none
+// were.
+func (s *stderrTail) since(mark uint64) []byte {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	written := atomic.LoadUint64(&s.written)
+	n := written - mark
+	if n == 0 {
+		return nil
+	} else if n > stderrTailSize {
+		n = stderrTailSize
+	}
+	ret := make([]byte, 0, n)
+	start := (written - n) % stderrTailSize
+	if end := start + n; end <= stderrTailSize {
+		return append(ret, s.tail[start:end]...)
+	}
+	ret = append(ret, s.tail[start:]...)
+	return append(ret, s.tail[:written%stderrTailSize]...)
+}
@@ -0,0 +1,139 @@
+package sys
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestContext_StderrEnd(t *testing.T) {
+	stderr := bytes.NewBuffer(nil)
+	sysCtx, err := NewContext(0, nil, nil, nil, nil, stderr, false, false, nil, nil, 0, nil, 0, nil, nil)
+	require.NoError(t, err)
+
+	// Nothing was written yet.
+	mark := sysCtx.StderrBegin()
+	require.Nil(t, sysCtx.StderrEnd(mark, true))
+
+	_, err = sysCtx.Stderr().Write([]byte("before"))
+	require.NoError(t, err)
+	mark = sysCtx.StderrBegin()
+	require.Equal(t, uint64(6), mark.written)
+
+	_, err = sysCtx.Stderr().Write([]byte("panic: "))
+	require.NoError(t, err)
+	_, err = sysCtx.Stderr().Write([]byte("boom\n"))
+	require.NoError(t, err)
+
+	// Only what was written after the mark is returned, but the writer sees everything.
+	require.Equal(t, []byte("panic: boom\n"), sysCtx.StderrEnd(mark, true))
+	require.Equal(t, "beforepanic: boom\n", stderr.String())
+
+	t.Run("not failed", func(t *testing.T) {
+		mark := sysCtx.StderrBegin()
+		_, err = sysCtx.Stderr().Write([]byte("ok\n"))
+		require.NoError(t, err)
+
+		require.Nil(t, sysCtx.StderrEnd(mark, false))
+	})
+
+	t.Run("wraps around", func(t *testing.T) {
+		mark := sysCtx.StderrBegin()
+		first := bytes.Repeat([]byte{'a'}, stderrTailSize-10)
+		second := bytes.Repeat([]byte{'b'}, 20)
+		_, err = sysCtx.Stderr().Write(first)
+		require.NoError(t, err)
+		_, err = sysCtx.Stderr().Write(second)
+		require.NoError(t, err)
+
+		// Only the last stderrTailSize bytes are retained.
+		expected := append(first[10:], second...)
+		require.Equal(t, expected, sysCtx.StderrEnd(mark, true))
+	})
+
+	t.Run("larger than the tail", func(t *testing.T) {
+		mark := sysCtx.StderrBegin()
+		b := bytes.Repeat([]byte("0123456789"), stderrTailSize/5)
+		_, err = sysCtx.Stderr().Write(b)
+		require.NoError(t, err)
+
+		require.Equal(t, b[len(b)-stderrTailSize:], sysCtx.StderrEnd(mark, true))
+	})
+
+	t.Run("concurrent calls", func(t *testing.T) {
+		// A call in progress when another begins.
+		outer := sysCtx.StderrBegin()
+		inner := sysCtx.StderrBegin()
+		_, err = sysCtx.Stderr().Write([]byte("panic: boom\n"))
+		require.NoError(t, err)
+
+		// Neither call can tell which wrote, so neither is blamed.
+		require.Nil(t, sysCtx.StderrEnd(inner, true))
+		require.Nil(t, sysCtx.StderrEnd(outer, true))
+
+		// Once both ended, a call is attributed what it writes again.
+		mark := sysCtx.StderrBegin()
+		_, err = sysCtx.Stderr().Write([]byte("panic: boom\n"))
+		require.NoError(t, err)
+		require.Equal(t, []byte("panic: boom\n"), sysCtx.StderrEnd(mark, true))
+	})
+}
+
+// blockingWriter blocks each Write until unblock is closed.
+type blockingWriter struct {
+	writing, unblock chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	w.writing <- struct{}{}
+	<-w.unblock
+	return len(p), nil
+}
+
+// TestContext_StderrBegin_BlockingWriter ensures a writer that blocks doesn't block calls that don't write.
+func TestContext_StderrBegin_BlockingWriter(t *testing.T) {
+	w := &blockingWriter{writing: make(chan struct{}), unblock: make(chan struct{})}
+	sysCtx, err := NewContext(0, nil, nil, nil, nil, w, false, false, nil, nil, 0, nil, 0, nil, nil)
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = sysCtx.Stderr().Write([]byte("boom\n"))
+	}()
+	<-w.writing
+
+	// The writer is blocked, but another call can begin and end.
+	mark := sysCtx.StderrBegin()
+	require.Nil(t, sysCtx.StderrEnd(mark, true))
+
+	close(w.unblock)
+	<-done
+}
+
+// TestContext_StderrEnd_Concurrent ensures writes and reads of the tail are goroutine-safe, when run with -race.
+func TestContext_StderrEnd_Concurrent(t *testing.T) {
+	sysCtx, err := NewContext(0, nil, nil, nil, nil, io.Discard, false, false, nil, nil, 0, nil, 0, nil, nil)
+	require.NoError(t, err)
+
+	goroutines, writes := 8, 100
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < writes; j++ {
+				mark := sysCtx.StderrBegin()
+				_, err := sysCtx.Stderr().Write([]byte("boom\n"))
+				require.NoError(t, err)
+				_ = sysCtx.StderrEnd(mark, true) // nil when another goroutine's call overlapped
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, uint64(goroutines*writes*5), sysCtx.StderrBegin().written)
+}
@@ -1,11 +1,15 @@
 package sys
 
 import (
+	"bufio"
 	"context"
 	"crypto/rand"
 	"errors"
 	"fmt"
 	"io"
+	"os"
+	"reflect"
+	"sync"
 	"time"
 
 	"github.com/tetratelabs/wazero/internal/platform"
@@ -19,6 +23,10 @@ type Context struct {
 	argsSize, environSize uint32
 	stdin                 io.Reader
 	stdout, stderr        io.Writer
+	stderrTail            *stderrTail
+	stdioWriteBoundaries  bool
+	// closeStdio is true when Close closes stdout and stderr, as the wazero.ModuleConfig which set them isn't shared.
+	closeStdio bool
 
 	// Note: Using function pointers here keeps them stable for tests.
 
@@ -26,6 +34,7 @@ type Context struct {
 	walltimeResolution sys.ClockResolution
 	nanotime           *sys.Nanotime
 	nanotimeResolution sys.ClockResolution
+	nanosleep          *sys.Nanosleep
 	randSource         io.Reader
 
 	fs *FSContext
@@ -78,9 +87,32 @@ func (c *Context) Stdout() io.Writer {
 }
 
 // Stderr is like exec.Cmd Stderr and defaults to io.Discard.
+//
+// Note: The result retains the last bytes written, for StderrEnd.
 // See wazero.ModuleConfig WithStderr
 func (c *Context) Stderr() io.Writer {
-	return c.stderr
+	return c.stderrTail
+}
+
+// StderrBegin is called when a function call begins, returning the mark to pass to StderrEnd when it ends.
+func (c *Context) StderrBegin() StderrMark {
+	return c.stderrTail.begin()
+}
+
+// StderrEnd is called when the function call begun at mark ends. If it failed, this returns up to the last 4KiB it
+// wrote to Stderr, or nil if none were. This allows attaching what a guest wrote before it failed, ex. a panic message,
+// to the error.
+//
+// Note: This also returns nil if another call ran concurrently, as then what was written can't be attributed to either.
+func (c *Context) StderrEnd(mark StderrMark, failed bool) []byte {
+	return c.stderrTail.end(mark, failed)
+}
+
+// StdioWriteBoundaries is true when each write to Stdout or Stderr should be
+// delivered as a single io.Writer.Write call, as opposed to one per buffer.
+// See wazero.ModuleConfig WithStdioWriteBoundaries
+func (c *Context) StdioWriteBoundaries() bool {
+	return c.stdioWriteBoundaries
 }
 
 // Walltime implements sys.Walltime.
@@ -103,17 +135,136 @@ func (c *Context) NanotimeResolution() sys.ClockResolution {
 	return c.nanotimeResolution
 }
 
+// Nanosleep implements sys.Nanosleep.
+func (c *Context) Nanosleep(ctx context.Context, ns int64) {
+	(*(c.nanosleep))(ctx, ns)
+}
+
 // FS returns the file system context.
 func (c *Context) FS() *FSContext {
 	return c.fs
 }
 
+// Close closes the FSContext and flushes stdout. When closeStdio was set on NewContext, this also closes stdout and
+// stderr if they implement io.Closer. A writer used for both is only closed once, and os.Stdout or os.Stderr are never
+// closed as they belong to the host process.
+//
+// Note: The error returned is the first non-nil error encountered.
+func (c *Context) Close(ctx context.Context) (err error) {
+	err = c.fs.Close(ctx)
+	if !c.closeStdio {
+		if e := c.Flush(); e != nil && err == nil {
+			err = e
+		}
+		return
+	}
+	if e := closeWriter(c.stdout); e != nil && err == nil {
+		err = e
+	}
+	if !sameWriter(unwrapWriter(c.stdout), c.stderr) {
+		if e := closeWriter(c.stderr); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
+
+// Flush writes any bytes buffered by stdout, ex. via NewBufferedWriter, without closing it.
+func (c *Context) Flush() error {
+	if b, ok := c.stdout.(*bufferedWriter); ok {
+		return b.Flush()
+	}
+	return nil
+}
+
+// closeWriter closes the writer if it implements io.Closer, unless it is os.Stdout or os.Stderr.
+func closeWriter(w io.Writer) error {
+	if f, ok := w.(*os.File); ok && (f == os.Stdout || f == os.Stderr) {
+		return nil
+	} else if closer, ok := w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// bufferedWriter buffers writes to an io.Writer, flushing when the buffer is full and on Close.
+//
+// Note: This is goroutine-safe, as a function of the module can write while a failed call on another goroutine, or a
+// reset, flushes.
+type bufferedWriter struct {
+	// mux guards buf.
+	mux sync.Mutex
+	buf *bufio.Writer
+	w   io.Writer
+}
+
+// NewBufferedWriter returns an io.Writer which buffers up to size bytes before writing to w. Buffered bytes are
+// flushed when the returned writer is closed, which also closes w as described on Context.Close.
+func NewBufferedWriter(w io.Writer, size int) io.Writer {
+	return &bufferedWriter{buf: bufio.NewWriterSize(w, size), w: w}
+}
+
+// Write implements io.Writer
+func (b *bufferedWriter) Write(p []byte) (int, error) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.buf.Write(p)
+}
+
+// Available returns how many bytes can be written without blocking on the underlying writer, ex. for "poll_oneoff".
+func (b *bufferedWriter) Available() int {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.buf.Available()
+}
+
+// Flush writes any buffered bytes to the underlying writer.
+func (b *bufferedWriter) Flush() error {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.buf.Flush()
+}
+
+// Close implements io.Closer
+func (b *bufferedWriter) Close() (err error) {
+	err = b.Flush()
+	if e := closeWriter(b.w); e != nil && err == nil {
+		err = e
+	}
+	return
+}
+
+// unwrapWriter returns the writer underlying a bufferedWriter, or w if it isn't one.
+func unwrapWriter(w io.Writer) io.Writer {
+	if b, ok := w.(*bufferedWriter); ok {
+		return b.w
+	}
+	return w
+}
+
+// sameWriter returns true if both writers are the same value, without panicking on types which are not comparable.
+func sameWriter(w1, w2 io.Writer) bool {
+	if t := reflect.TypeOf(w1); t == nil || t != reflect.TypeOf(w2) || !t.Comparable() {
+		return false
+	}
+	return w1 == w2
+}
+
 // RandSource is a source of random bytes and defaults to crypto/rand.Reader.
 // see wazero.ModuleConfig WithRandSource
 func (c *Context) RandSource() io.Reader {
 	return c.randSource
 }
 
+// ReadRand fills buf from RandSource, retrying short reads until it is full, like io.ReadFull.
+//
+// Note: Unlike getentropy, there is no limit on len(buf), and a source which returns fewer bytes than requested isn't
+// an error unless it also returns one, including io.EOF before buf is full.
+func (c *Context) ReadRand(buf []byte) error {
+	_, err := io.ReadFull(c.randSource, buf)
+	return err
+}
+
 // eofReader is safer than reading from os.DevNull as it can never overrun operating system file descriptors.
 type eofReader struct{}
 
@@ -128,7 +279,7 @@ func (eofReader) Read([]byte) (int, error) {
 // Note: This isn't a constant because Context.openedFiles is currently mutable even when empty.
 // TODO: Make it an error to open or close files when no FS was assigned.
 func DefaultContext() *Context {
-	if sysCtx, err := NewContext(0, nil, nil, nil, nil, nil, nil, nil, 0, nil, 0, nil); err != nil {
+	if sysCtx, err := NewContext(0, nil, nil, nil, nil, nil, false, false, nil, nil, 0, nil, 0, nil, nil); err != nil {
 		panic(fmt.Errorf("BUG: DefaultContext should never error: %w", err))
 	} else {
 		return sysCtx
@@ -138,6 +289,7 @@ func DefaultContext() *Context {
 var _ = DefaultContext() // Force panic on bug.
 var wt sys.Walltime = platform.FakeWalltime
 var nt sys.Nanotime = platform.FakeNanotime
+var ns sys.Nanosleep = platform.FakeNanosleep
 
 // NewContext is a factory function which helps avoid needing to know defaults or exporting all fields.
 // Note: max is exposed for testing. max is only used for env/args validation.
@@ -146,12 +298,15 @@ func NewContext(
 	args, environ []string,
 	stdin io.Reader,
 	stdout, stderr io.Writer,
+	stdioWriteBoundaries bool,
+	closeStdio bool,
 	randSource io.Reader,
 	walltime *sys.Walltime, walltimeResolution sys.ClockResolution,
 	nanotime *sys.Nanotime, nanotimeResolution sys.ClockResolution,
+	nanosleep *sys.Nanosleep,
 	openedFiles map[uint32]*FileEntry,
 ) (sysCtx *Context, err error) {
-	sysCtx = &Context{args: args, environ: environ}
+	sysCtx = &Context{args: args, environ: environ, stdioWriteBoundaries: stdioWriteBoundaries, closeStdio: closeStdio}
 
 	if sysCtx.argsSize, err = nullTerminatedByteCount(max, args); err != nil {
 		return nil, fmt.Errorf("args invalid: %w", err)
@@ -178,6 +333,7 @@ func NewContext(
 	} else {
 		sysCtx.stderr = stderr
 	}
+	sysCtx.stderrTail = &stderrTail{w: sysCtx.stderr}
 
 	if randSource == nil {
 		sysCtx.randSource = rand.Reader
@@ -207,6 +363,12 @@ func NewContext(
 		sysCtx.nanotimeResolution = sys.ClockResolution(time.Nanosecond)
 	}
 
+	if nanosleep != nil {
+		sysCtx.nanosleep = nanosleep
+	} else {
+		sysCtx.nanosleep = &ns
+	}
+
 	sysCtx.fs = NewFSContext(openedFiles)
 
 	return
@@ -229,9 +391,10 @@ func nullTerminatedByteCount(max uint32, elements []string) (uint32, error) {
 	// count with each value length. This works because in Go, the length of a string is the same as its byte count.
 	bufSize, maxSize := uint64(count), uint64(max) // uint64 to allow summing without overflow
 	for _, e := range elements {
-		// As this is null-terminated, We have to validate there are no null characters in the string.
-		for _, c := range e {
-			if c == 0 {
+		// As this is null-terminated, We have to validate there are no null characters in the string. This ranges
+		// over bytes, not runes, as the encoded size is in bytes and invalid UTF-8 must not hide a NUL.
+		for i := 0; i < len(e); i++ {
+			if e[i] == 0 {
 				return 0, errors.New("contains NUL character")
 			}
 		}
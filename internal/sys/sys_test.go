@@ -3,8 +3,12 @@ package sys
 import (
 	"bytes"
 	"crypto/rand"
+	"errors"
 	"io"
+	"os"
+	"sync"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"github.com/tetratelabs/wazero/internal/testing/require"
@@ -19,9 +23,12 @@ func TestDefaultSysContext(t *testing.T) {
 		nil,    // stdin
 		nil,    // stdout
 		nil,    // stderr
+		false,  // stdioWriteBoundaries
+		false,  // closeStdio
 		nil,    // randSource
 		nil, 0, // walltime, walltimeResolution
 		nil, 0, // nanotime, nanotimeResolution
+		nil, // nanosleep
 		nil, // openedFiles
 	)
 	require.NoError(t, err)
@@ -32,11 +39,12 @@ func TestDefaultSysContext(t *testing.T) {
 	require.Zero(t, sysCtx.EnvironSize())
 	require.Equal(t, eofReader{}, sysCtx.Stdin())
 	require.Equal(t, io.Discard, sysCtx.Stdout())
-	require.Equal(t, io.Discard, sysCtx.Stderr())
+	require.Equal(t, &stderrTail{w: io.Discard}, sysCtx.Stderr())
 	require.Equal(t, &wt, sysCtx.walltime) // To compare functions, we can only compare pointers.
 	require.Equal(t, sys.ClockResolution(1_000), sysCtx.WalltimeResolution())
 	require.Equal(t, &nt, sysCtx.nanotime) // To compare functions, we can only compare pointers.
 	require.Equal(t, sys.ClockResolution(1), sysCtx.NanotimeResolution())
+	require.Equal(t, &ns, sysCtx.nanosleep) // To compare functions, we can only compare pointers.
 	require.Equal(t, rand.Reader, sysCtx.RandSource())
 	require.Equal(t, NewFSContext(map[uint32]*FileEntry{}), sysCtx.FS())
 }
@@ -55,6 +63,18 @@ func TestNewContext_Args(t *testing.T) {
 			args:         []string{"a", "bc"},
 			expectedSize: 5,
 		},
+		{
+			name:         "empty",
+			maxSize:      10,
+			args:         []string{},
+			expectedSize: 0,
+		},
+		{
+			name:         "utf-8 multibyte counts bytes",
+			maxSize:      20,
+			args:         []string{"ä", "日本"},
+			expectedSize: 2 + 1 + 6 + 1, // bytes plus one null terminator per arg
+		},
 		{
 			name:        "exceeds max count",
 			maxSize:     1,
@@ -73,6 +93,12 @@ func TestNewContext_Args(t *testing.T) {
 			args:        []string{"a", string([]byte{'b', 0})},
 			expectedErr: "args invalid: contains NUL character",
 		},
+		{
+			name:        "null character after invalid utf-8",
+			maxSize:     10,
+			args:        []string{string([]byte{0xff, 0})},
+			expectedErr: "args invalid: contains NUL character",
+		},
 	}
 
 	for _, tt := range tests {
@@ -86,9 +112,12 @@ func TestNewContext_Args(t *testing.T) {
 				bytes.NewReader(make([]byte, 0)), // stdin
 				nil,                              // stdout
 				nil,                              // stderr
+				false,                            // stdioWriteBoundaries
+				false,                            // closeStdio
 				nil,                              // randSource
 				nil, 0,                           // walltime, walltimeResolution
 				nil, 0, // nanotime, nanotimeResolution
+				nil, // nanosleep
 				nil, // openedFiles
 			)
 			if tc.expectedErr == "" {
@@ -147,9 +176,12 @@ func TestNewContext_Environ(t *testing.T) {
 				bytes.NewReader(make([]byte, 0)), // stdin
 				nil,                              // stdout
 				nil,                              // stderr
+				false,                            // stdioWriteBoundaries
+				false,                            // closeStdio
 				nil,                              // randSource
 				nil, 0,                           // walltime, walltimeResolution
 				nil, 0, // nanotime, nanotimeResolution
+				nil, // nanosleep
 				nil, // openedFiles
 			)
 			if tc.expectedErr == "" {
@@ -194,9 +226,12 @@ func TestNewContext_Walltime(t *testing.T) {
 				nil,                    // stdin
 				nil,                    // stdout
 				nil,                    // stderr
+				false,                  // stdioWriteBoundaries
+				false,                  // closeStdio
 				nil,                    // randSource
 				tc.time, tc.resolution, // walltime, walltimeResolution
 				nil, 0, // nanotime, nanotimeResolution
+				nil, // nanosleep
 				nil, // openedFiles
 			)
 			if tc.expectedErr == "" {
@@ -241,9 +276,12 @@ func TestNewContext_Nanotime(t *testing.T) {
 				nil,    // stdin
 				nil,    // stdout
 				nil,    // stderr
+				false,  // stdioWriteBoundaries
+				false,  // closeStdio
 				nil,    // randSource
 				nil, 0, // nanotime, nanotimeResolution
 				tc.time, tc.resolution, // nanotime, nanotimeResolution
+				nil, // nanosleep
 				nil, // openedFiles
 			)
 			if tc.expectedErr == "" {
@@ -287,3 +325,148 @@ func Test_clockResolutionInvalid(t *testing.T) {
 		})
 	}
 }
+
+func TestContext_Close_Writers(t *testing.T) {
+	t.Run("closes stdout and stderr", func(t *testing.T) {
+		stdout, stderr := &closeTracker{}, &closeTracker{}
+		sysCtx, err := NewContext(0, nil, nil, nil, stdout, stderr, false, true, nil, nil, 0, nil, 0, nil, nil)
+		require.NoError(t, err)
+
+		require.NoError(t, sysCtx.Close(testCtx))
+		require.Equal(t, 1, stdout.closed)
+		require.Equal(t, 1, stderr.closed)
+	})
+
+	t.Run("closes shared writer once", func(t *testing.T) {
+		out := &closeTracker{}
+		sysCtx, err := NewContext(0, nil, nil, nil, out, out, false, true, nil, nil, 0, nil, 0, nil, nil)
+		require.NoError(t, err)
+
+		require.NoError(t, sysCtx.Close(testCtx))
+		require.Equal(t, 1, out.closed)
+	})
+
+	t.Run("closes shared writer once when stdout is buffered", func(t *testing.T) {
+		out := &closeTracker{}
+		sysCtx, err := NewContext(0, nil, nil, nil, NewBufferedWriter(out, 16), out, false, true, nil, nil, 0, nil, 0, nil, nil)
+		require.NoError(t, err)
+
+		require.NoError(t, sysCtx.Close(testCtx))
+		require.Equal(t, 1, out.closed)
+	})
+
+	t.Run("returns close error", func(t *testing.T) {
+		stdout := &closeTracker{err: errors.New("error closing")}
+		sysCtx, err := NewContext(0, nil, nil, nil, stdout, nil, false, true, nil, nil, 0, nil, 0, nil, nil)
+		require.NoError(t, err)
+
+		require.EqualError(t, sysCtx.Close(testCtx), "error closing")
+	})
+
+	t.Run("doesn't close os.Stdout or os.Stderr", func(t *testing.T) {
+		sysCtx, err := NewContext(0, nil, nil, nil, os.Stdout, os.Stderr, false, true, nil, nil, 0, nil, 0, nil, nil)
+		require.NoError(t, err)
+
+		require.NoError(t, sysCtx.Close(testCtx))
+		_, err = os.Stdout.Stat()
+		require.NoError(t, err)
+		_, err = os.Stderr.Stat()
+		require.NoError(t, err)
+	})
+
+	t.Run("only flushes unless closeStdio", func(t *testing.T) {
+		out := &closeTracker{}
+		sysCtx, err := NewContext(0, nil, nil, nil, NewBufferedWriter(out, 16), out, false, false, nil, nil, 0, nil, 0, nil, nil)
+		require.NoError(t, err)
+		_, err = sysCtx.Stdout().Write([]byte("hello"))
+		require.NoError(t, err)
+
+		require.NoError(t, sysCtx.Close(testCtx))
+		require.Zero(t, out.closed)
+		require.Equal(t, "hello", out.String())
+	})
+}
+
+// TestContext_Flush_Concurrent ensures writes and flushes of buffered stdout are goroutine-safe, when run with -race.
+func TestContext_Flush_Concurrent(t *testing.T) {
+	out := &bytes.Buffer{}
+	sysCtx, err := NewContext(0, nil, nil, nil, NewBufferedWriter(out, 16), nil, false, false, nil, nil, 0, nil, 0, nil, nil)
+	require.NoError(t, err)
+
+	goroutines, writes := 8, 100
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < writes; j++ {
+				_, err := sysCtx.Stdout().Write([]byte("boom\n"))
+				require.NoError(t, err)
+				require.NoError(t, sysCtx.Flush()) // ex. a failed call flushes while others write.
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, goroutines*writes*5, out.Len())
+}
+
+// closeTracker is an io.WriteCloser that counts how many times it was closed.
+type closeTracker struct {
+	bytes.Buffer
+	closed int
+	err    error
+}
+
+func (w *closeTracker) Close() error { w.closed++; return w.err }
+
+func TestContext_ReadRand(t *testing.T) {
+	data := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+
+	tests := []struct {
+		name        string
+		randSource  io.Reader
+		expectedErr error
+	}{
+		{
+			name:       "whole",
+			randSource: bytes.NewReader(data),
+		},
+		{
+			name:       "one byte at a time",
+			randSource: iotest.OneByteReader(bytes.NewReader(data)),
+		},
+		{
+			name:       "half at a time",
+			randSource: iotest.HalfReader(bytes.NewReader(data)),
+		},
+		{
+			name:        "incomplete",
+			randSource:  iotest.OneByteReader(bytes.NewReader(data[:3])),
+			expectedErr: io.ErrUnexpectedEOF,
+		},
+		{
+			name:        "error",
+			randSource:  iotest.ErrReader(errors.New("RandSource error")),
+			expectedErr: errors.New("RandSource error"),
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			sysCtx, err := NewContext(0, nil, nil, nil, nil, nil, false, false, tc.randSource, nil, 0, nil, 0, nil, nil)
+			require.NoError(t, err)
+
+			buf := make([]byte, len(data))
+			err = sysCtx.ReadRand(buf)
+			if tc.expectedErr != nil {
+				require.EqualError(t, err, tc.expectedErr.Error())
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, data, buf)
+			}
+		})
+	}
+}
@@ -165,6 +165,25 @@ func RunTestModuleEngine_Call(t *testing.T, et EngineTester) {
 		_, err := me.Call(testCtx, module.CallCtx, fn, 1, 2)
 		require.EqualError(t, err, "expected 1 params, but passed 2")
 	})
+
+	t.Run("CallWithStack matches Call", func(t *testing.T) {
+		stack := []uint64{3}
+		err := me.CallWithStack(testCtx, module.CallCtx, fn, stack)
+		require.NoError(t, err)
+		require.Equal(t, results, stack)
+	})
+
+	t.Run("CallWithStack allows a longer stack", func(t *testing.T) {
+		stack := []uint64{3, 42}
+		err := me.CallWithStack(testCtx, module.CallCtx, fn, stack)
+		require.NoError(t, err)
+		require.Equal(t, []uint64{3, 42}, stack)
+	})
+
+	t.Run("CallWithStack errs when stack is too short", func(t *testing.T) {
+		err := me.CallWithStack(testCtx, module.CallCtx, fn, nil)
+		require.EqualError(t, err, "need 1 stack slots, but passed 0")
+	})
 }
 
 func RunTestEngine_NewModuleEngine_InitTable(t *testing.T, et EngineTester) {
@@ -442,6 +461,11 @@ func RunTestModuleEngine_Call_HostFn(t *testing.T, et EngineTester) {
 			results, err := f.Module.Engine.Call(testCtx, m, f, 1)
 			require.NoError(t, err)
 			require.Equal(t, uint64(1), results[0])
+
+			stack := []uint64{1}
+			err = f.Module.Engine.CallWithStack(testCtx, m, f, stack)
+			require.NoError(t, err)
+			require.Equal(t, results, stack)
 		})
 	}
 }
@@ -570,6 +594,11 @@ wasm stack trace:
 			results, err := f.Module.Engine.Call(testCtx, m, f, 1)
 			require.NoError(t, err)
 			require.Equal(t, uint64(1), results[0])
+
+			stack := []uint64{1}
+			err = f.Module.Engine.CallWithStack(testCtx, m, f, stack)
+			require.NoError(t, err)
+			require.Equal(t, results, stack)
 		})
 	}
 }
@@ -15,7 +15,12 @@ func decodeCode(r *bytes.Reader) (*wasm.Code, error) {
 	if err != nil {
 		return nil, fmt.Errorf("get the size of code: %w", err)
 	}
-	remaining := int64(ss)
+	return decodeCodeContent(r, ss)
+}
+
+// decodeCodeContent decodes the locals and body of a function, whose encoding is size bytes, after its size prefix.
+func decodeCodeContent(r *bytes.Reader, size uint32) (*wasm.Code, error) {
+	remaining := int64(size)
 
 	// parse locals
 	ls, bytesRead, err := leb128.DecodeUint32(r)
@@ -79,7 +84,9 @@ func decodeCode(r *bytes.Reader) (*wasm.Code, error) {
 		return nil, fmt.Errorf("expr not end with OpcodeEnd")
 	}
 
-	return &wasm.Code{Body: body, LocalTypes: localTypes}, nil
+	// The body is the end of the content, so its offset is relative to the start of what r reads.
+	bodyOffset := uint64(r.Size()) - uint64(r.Len()) - uint64(len(body))
+	return &wasm.Code{Body: body, LocalTypes: localTypes, BodyOffset: bodyOffset}, nil
 }
 
 // encodeCode returns the wasm.Code encoded in WebAssembly 1.0 (20191205) Binary Format.
@@ -43,73 +43,11 @@ func DecodeModule(
 
 		sectionSize, _, err := leb128.DecodeUint32(r)
 		if err != nil {
-			return nil, fmt.Errorf("get size of section %s: %v", wasm.SectionIDName(sectionID), err)
+			return nil, sectionError(binary, r, sectionID, fmt.Errorf("get size of section: %v", err))
 		}
 
 		sectionContentStart := r.Len()
-		switch sectionID {
-		case wasm.SectionIDCustom:
-			// First, validate the section and determine if the section for this name has already been set
-			name, nameSize, decodeErr := decodeUTF8(r, "custom section name")
-			if decodeErr != nil {
-				err = decodeErr
-				break
-			} else if sectionSize < nameSize {
-				err = fmt.Errorf("malformed custom section %s", name)
-				break
-			} else if name == "name" && m.NameSection != nil {
-				err = fmt.Errorf("redundant custom section %s", name)
-				break
-			}
-
-			// Now, either decode the NameSection or skip an unsupported one
-			limit := sectionSize - nameSize
-			if name == "name" {
-				m.NameSection, err = decodeNameSection(r, uint64(limit))
-			} else {
-				// Note: Not Seek because it doesn't err when given an offset past EOF. Rather, it leads to undefined state.
-				if _, err = io.CopyN(io.Discard, r, int64(limit)); err != nil {
-					return nil, fmt.Errorf("failed to skip name[%s]: %w", name, err)
-				}
-			}
-
-		case wasm.SectionIDType:
-			m.TypeSection, err = decodeTypeSection(enabledFeatures, r)
-		case wasm.SectionIDImport:
-			if m.ImportSection, err = decodeImportSection(r, memorySizer, enabledFeatures); err != nil {
-				return nil, err // avoid re-wrapping the error.
-			}
-		case wasm.SectionIDFunction:
-			m.FunctionSection, err = decodeFunctionSection(r)
-		case wasm.SectionIDTable:
-			m.TableSection, err = decodeTableSection(r, enabledFeatures)
-		case wasm.SectionIDMemory:
-			m.MemorySection, err = decodeMemorySection(r, memorySizer)
-		case wasm.SectionIDGlobal:
-			if m.GlobalSection, err = decodeGlobalSection(r, enabledFeatures); err != nil {
-				return nil, err // avoid re-wrapping the error.
-			}
-		case wasm.SectionIDExport:
-			m.ExportSection, err = decodeExportSection(r)
-		case wasm.SectionIDStart:
-			if m.StartSection != nil {
-				return nil, errors.New("multiple start sections are invalid")
-			}
-			m.StartSection, err = decodeStartSection(r)
-		case wasm.SectionIDElement:
-			m.ElementSection, err = decodeElementSection(r, enabledFeatures)
-		case wasm.SectionIDCode:
-			m.CodeSection, err = decodeCodeSection(r)
-		case wasm.SectionIDData:
-			m.DataSection, err = decodeDataSection(r, enabledFeatures)
-		case wasm.SectionIDDataCount:
-			if err := enabledFeatures.Require(wasm.FeatureBulkMemoryOperations); err != nil {
-				return nil, fmt.Errorf("data count section not supported as %v", err)
-			}
-			m.DataCountSection, err = decodeDataCountSection(r)
-		default:
-			err = ErrInvalidSectionID
-		}
+		err = decodeSection(m, r, sectionID, sectionSize, enabledFeatures, memorySizer)
 
 		readBytes := sectionContentStart - r.Len()
 		if err == nil && int(sectionSize) != readBytes {
@@ -117,13 +55,106 @@ func DecodeModule(
 		}
 
 		if err != nil {
-			return nil, fmt.Errorf("section %s: %v", wasm.SectionIDName(sectionID), err)
+			return nil, sectionError(binary, r, sectionID, err)
 		}
 	}
 
+	if err := requireCodeForEachFunction(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// requireCodeForEachFunction returns an error unless the function and code sections have the same length.
+func requireCodeForEachFunction(m *wasm.Module) error {
 	functionCount, codeCount := m.SectionElementCount(wasm.SectionIDFunction), m.SectionElementCount(wasm.SectionIDCode)
 	if functionCount != codeCount {
-		return nil, fmt.Errorf("function and code section have inconsistent lengths: %d != %d", functionCount, codeCount)
+		return fmt.Errorf("function and code section have inconsistent lengths: %d != %d", functionCount, codeCount)
 	}
-	return m, nil
+	return nil
+}
+
+// decodeSection decodes the contents of the section with the given ID into the module, reading up to sectionSize
+// bytes from the reader.
+func decodeSection(
+	m *wasm.Module,
+	r *bytes.Reader,
+	sectionID wasm.SectionID,
+	sectionSize uint32,
+	enabledFeatures wasm.Features,
+	memorySizer func(minPages uint32, maxPages *uint32) (min, capacity, max uint32),
+) (err error) {
+	switch sectionID {
+	case wasm.SectionIDCustom:
+		// First, validate the section and determine if the section for this name has already been set
+		name, nameSize, decodeErr := decodeUTF8(r, "custom section name")
+		if decodeErr != nil {
+			return decodeErr
+		} else if sectionSize < nameSize {
+			return fmt.Errorf("malformed custom section %s", name)
+		} else if name == "name" && m.NameSection != nil {
+			return fmt.Errorf("redundant custom section %s", name)
+		}
+
+		// Now, either decode the NameSection or retain the raw data of any other
+		limit := sectionSize - nameSize
+		if name == "name" {
+			m.NameSection, err = decodeNameSection(r, uint64(limit))
+			return
+		}
+		// Note: Check the length first, so that a corrupt size doesn't allocate more than the binary.
+		if int64(limit) > int64(r.Len()) {
+			return fmt.Errorf("failed to read name[%s]: %w", name, io.ErrUnexpectedEOF)
+		}
+		data := make([]byte, limit)
+		if _, err = io.ReadFull(r, data); err != nil {
+			return fmt.Errorf("failed to read name[%s]: %w", name, err)
+		}
+		m.CustomSections = append(m.CustomSections, &wasm.CustomSection{Name: name, Data: data})
+	case wasm.SectionIDType:
+		m.TypeSection, err = decodeTypeSection(enabledFeatures, r)
+	case wasm.SectionIDImport:
+		m.ImportSection, err = decodeImportSection(r, memorySizer, enabledFeatures)
+	case wasm.SectionIDFunction:
+		m.FunctionSection, err = decodeFunctionSection(r)
+	case wasm.SectionIDTable:
+		m.TableSection, err = decodeTableSection(r, enabledFeatures)
+	case wasm.SectionIDMemory:
+		m.MemorySection, err = decodeMemorySection(r, memorySizer)
+	case wasm.SectionIDGlobal:
+		m.GlobalSection, err = decodeGlobalSection(r, enabledFeatures)
+	case wasm.SectionIDExport:
+		m.ExportSection, err = decodeExportSection(r)
+	case wasm.SectionIDStart:
+		if m.StartSection != nil {
+			return errors.New("multiple start sections are invalid")
+		}
+		m.StartSection, err = decodeStartSection(r)
+	case wasm.SectionIDElement:
+		m.ElementSection, err = decodeElementSection(r, enabledFeatures)
+	case wasm.SectionIDCode:
+		m.CodeSection, err = decodeCodeSection(r, m.ImportFuncCount())
+	case wasm.SectionIDData:
+		m.DataSection, err = decodeDataSection(r, enabledFeatures)
+	case wasm.SectionIDDataCount:
+		if err = enabledFeatures.Require(wasm.FeatureBulkMemoryOperations); err != nil {
+			return fmt.Errorf("data count section not supported as %v", err)
+		}
+		m.DataCountSection, err = decodeDataCountSection(r)
+	default:
+		err = ErrInvalidSectionID
+	}
+	return
+}
+
+// sectionError adds the section and the offset into the binary where decoding stopped to the error.
+//
+// Ex. "section 10 (code), offset 0x1a2: function[3]: read body: unexpected EOF"
+func sectionError(binary []byte, r *bytes.Reader, sectionID wasm.SectionID, err error) error {
+	return sectionErrorAt(int64(len(binary)-r.Len()), sectionID, err)
+}
+
+// sectionErrorAt is like sectionError, except the offset is given.
+func sectionErrorAt(offset int64, sectionID wasm.SectionID, err error) error {
+	return fmt.Errorf("section %d (%s), offset %#x: %v", sectionID, wasm.SectionIDName(sectionID), offset, err)
 }
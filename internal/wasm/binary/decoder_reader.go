@@ -0,0 +1,174 @@
+package binary
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// DecodeModuleReader is like DecodeModule, except it reads the binary in order from the reader, without buffering all
+// of it. Only one section is buffered at a time, except the code section, which is buffered one function at a time.
+//
+// Note: The reader is read until io.EOF, so it can be wrapped to compute a hash of the binary, ex. for
+// wasm.Module AssignModuleID.
+func DecodeModuleReader(
+	in io.Reader,
+	enabledFeatures wasm.Features,
+	memorySizer func(minPages uint32, maxPages *uint32) (min, capacity, max uint32),
+) (*wasm.Module, error) {
+	r := &offsetReader{r: bufio.NewReader(in)}
+
+	// Magic number.
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(r, buf); err != nil || !bytes.Equal(buf, Magic) {
+		return nil, ErrInvalidMagicNumber
+	}
+
+	// Version.
+	if _, err := io.ReadFull(r, buf); err != nil || !bytes.Equal(buf, version) {
+		return nil, ErrInvalidVersion
+	}
+
+	m := &wasm.Module{}
+	for {
+		sectionID, err := r.ReadByte()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("read section id: %w", err)
+		}
+
+		sectionSize, _, err := r.readUint32()
+		if err != nil {
+			return nil, sectionErrorAt(r.offset, sectionID, fmt.Errorf("get size of section: %v", err))
+		}
+
+		sectionStart := r.offset
+		if sectionID == wasm.SectionIDCode {
+			// Decode function by function, as the code section is typically most of the binary.
+			if m.CodeSection, err = r.decodeCodeSection(m.ImportFuncCount()); err != nil {
+				return nil, sectionErrorAt(r.offset, sectionID, err)
+			}
+			if readBytes := r.offset - sectionStart; int64(sectionSize) != readBytes {
+				err = fmt.Errorf("invalid section length: expected to be %d but got %d", sectionSize, readBytes)
+				return nil, sectionErrorAt(r.offset, sectionID, err)
+			}
+			continue
+		}
+
+		section, err := r.readSection(sectionSize)
+		if err != nil {
+			return nil, sectionErrorAt(r.offset, sectionID, err)
+		}
+
+		err = decodeSection(m, section, sectionID, sectionSize, enabledFeatures, memorySizer)
+		readBytes := int(sectionSize) - section.Len()
+		if err == nil && int(sectionSize) != readBytes {
+			err = fmt.Errorf("invalid section length: expected to be %d but got %d", sectionSize, readBytes)
+		}
+
+		if err != nil {
+			return nil, sectionErrorAt(sectionStart+int64(readBytes), sectionID, err)
+		}
+	}
+
+	if err := requireCodeForEachFunction(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// offsetReader tracks the offset into the binary, for errors and to verify section lengths.
+type offsetReader struct {
+	r      *bufio.Reader
+	offset int64
+}
+
+// Read implements io.Reader
+func (o *offsetReader) Read(p []byte) (n int, err error) {
+	n, err = o.r.Read(p)
+	o.offset += int64(n)
+	return
+}
+
+// ReadByte implements io.ByteReader
+func (o *offsetReader) ReadByte() (b byte, err error) {
+	if b, err = o.r.ReadByte(); err == nil {
+		o.offset++
+	}
+	return
+}
+
+// readUint32 reads a LEB128 encoded uint32, returning it and the count of bytes read.
+func (o *offsetReader) readUint32() (uint32, uint64, error) {
+	var buf [5]byte // maxVarintLen32
+	n := 0
+	for n < len(buf) {
+		b, err := o.ReadByte()
+		if err != nil {
+			return 0, 0, err
+		}
+		buf[n] = b
+		n++
+		if b < 0x80 {
+			break
+		}
+	}
+	return leb128.DecodeUint32(bytes.NewReader(buf[:n]))
+}
+
+// readSection reads the next size bytes.
+//
+// Note: This grows the buffer as data is read, so that a corrupt size doesn't allocate more than the binary.
+func (o *offsetReader) readSection(size uint32) (*bytes.Reader, error) {
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, o, int64(size)); err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(buf.Bytes()), nil
+}
+
+// decodeCodeSection is like the function of the same name, except it reads one function at a time.
+func (o *offsetReader) decodeCodeSection(importedFunctionCount uint32) ([]*wasm.Code, error) {
+	vs, _, err := o.readUint32()
+	if err != nil {
+		return nil, fmt.Errorf("get size of vector: %w", err)
+	}
+
+	var result []*wasm.Code
+	for i := uint32(0); i < vs; i++ {
+		code, err := o.decodeCode()
+		if err != nil {
+			return nil, fmt.Errorf("function[%d]: %v", importedFunctionCount+i, err)
+		}
+		result = append(result, code)
+	}
+	return result, nil
+}
+
+// decodeCode reads the size of the next function and buffers only its content to decode it.
+func (o *offsetReader) decodeCode() (*wasm.Code, error) {
+	size, _, err := o.readUint32()
+	if err != nil {
+		return nil, fmt.Errorf("get the size of code: %w", err)
+	}
+	content, err := o.readSection(size)
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+	code, err := decodeCodeContent(content, size)
+	if err != nil {
+		return nil, err
+	} else if content.Len() != 0 {
+		return nil, fmt.Errorf("invalid code length: %d bytes unread", content.Len())
+	}
+	// Only the content is buffered, so make the offset of the body relative to the binary.
+	code.BodyOffset += uint64(o.offset) - uint64(size)
+	return code, nil
+}
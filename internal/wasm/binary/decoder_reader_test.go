@@ -0,0 +1,146 @@
+package binary
+
+import (
+	"bytes"
+	"testing"
+	"testing/iotest"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// TestDecodeModuleReader ensures the result is the same as DecodeModule, when reading one byte at a time.
+func TestDecodeModuleReader(t *testing.T) {
+	i32 := wasm.ValueTypeI32
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{
+			name:  "empty",
+			input: EncodeModule(&wasm.Module{}),
+		},
+		{
+			name: "code section",
+			input: EncodeModule(&wasm.Module{
+				TypeSection:     []*wasm.FunctionType{{Params: []wasm.ValueType{i32}, ParamNumInUint64: 1}},
+				FunctionSection: []wasm.Index{0, 0},
+				CodeSection: []*wasm.Code{
+					{Body: []byte{wasm.OpcodeEnd}},
+					{LocalTypes: []wasm.ValueType{i32}, Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalSet, 1, wasm.OpcodeEnd}},
+				},
+				NameSection: &wasm.NameSection{ModuleName: "simple"},
+			}),
+		},
+		{
+			name: "custom section",
+			input: append(append(Magic, version...),
+				wasm.SectionIDCustom, 0xf, // 15 bytes in this section
+				0x04, 'm', 'e', 'm', 'e',
+				1, 2, 3, 4, 5, 6, 7, 8, 9, 0),
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			expected, err := DecodeModule(tc.input, wasm.Features20191205, wasm.MemorySizer)
+			require.NoError(t, err)
+
+			m, err := DecodeModuleReader(iotest.OneByteReader(bytes.NewReader(tc.input)), wasm.Features20191205, wasm.MemorySizer)
+			require.NoError(t, err)
+			require.Equal(t, expected, m)
+		})
+	}
+}
+
+// TestDecodeModule_BodyOffset ensures both decoders report the offset of each function body in the binary.
+func TestDecodeModule_BodyOffset(t *testing.T) {
+	i32 := wasm.ValueTypeI32
+	bin := EncodeModule(&wasm.Module{
+		TypeSection:     []*wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0, 0},
+		CodeSection: []*wasm.Code{
+			{Body: []byte{wasm.OpcodeNop, wasm.OpcodeEnd}},
+			{LocalTypes: []wasm.ValueType{i32, i32}, Body: []byte{wasm.OpcodeUnreachable, wasm.OpcodeEnd}},
+		},
+	})
+
+	m, err := DecodeModule(bin, wasm.Features20191205, wasm.MemorySizer)
+	require.NoError(t, err)
+	mr, err := DecodeModuleReader(iotest.OneByteReader(bytes.NewReader(bin)), wasm.Features20191205, wasm.MemorySizer)
+	require.NoError(t, err)
+
+	for _, mod := range []*wasm.Module{m, mr} {
+		for _, c := range mod.CodeSection {
+			require.NotEqual(t, uint64(0), c.BodyOffset)
+			require.Equal(t, c.Body, bin[c.BodyOffset:c.BodyOffset+uint64(len(c.Body))])
+		}
+	}
+	require.Equal(t, m.CodeSection, mr.CodeSection)
+}
+
+func TestDecodeModuleReader_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       []byte
+		expectedErr string
+	}{
+		{
+			name:        "wrong magic",
+			input:       []byte("wasm\x01\x00\x00\x00"),
+			expectedErr: "invalid magic number",
+		},
+		{
+			name:        "wrong version",
+			input:       []byte("\x00asm\x01\x00\x00\x01"),
+			expectedErr: "invalid version header",
+		},
+		{
+			name: "section larger than binary",
+			input: append(append(Magic, version...),
+				wasm.SectionIDCustom, 0xf, // 15 bytes in this section, but only 7 follow
+				0x04, 'm', 'e', 'm', 'e',
+				1, 2),
+			expectedErr: "section 0 (custom), offset 0x11: unexpected EOF",
+		},
+		{
+			name: "code body too short, reported by function index",
+			input: append(append(Magic, version...),
+				wasm.SectionIDType, 0x04, 0x01, 0x60, 0, 0, // 1 type: func() -> ()
+				wasm.SectionIDImport, 0x07, // 7 bytes in this section
+				0x01,                 // 1 import
+				0x01, 'a', 0x01, 'b', // module "a", name "b"
+				wasm.ExternTypeFunc, 0x00, // func of type 0
+				wasm.SectionIDFunction, 0x03, 0x02, 0x00, 0x00, // 2 functions of type 0
+				wasm.SectionIDCode, 0x07, // 7 bytes in this section
+				0x02,                       // 2 code entries
+				0x02, 0x00, wasm.OpcodeEnd, // function[1]: no locals and an empty body
+				0x04, 0x00, wasm.OpcodeEnd, // function[2]: claims 4 bytes, but only 2 remain
+			),
+			expectedErr: "section 10 (code), offset 0x25: function[2]: read body: unexpected EOF",
+		},
+		{
+			name: "code section length mismatch",
+			input: append(append(Magic, version...),
+				wasm.SectionIDType, 0x04, 0x01, 0x60, 0, 0, // 1 type: func() -> ()
+				wasm.SectionIDFunction, 0x02, 0x01, 0x00, // 1 function of type 0
+				wasm.SectionIDCode, 0x05, // claims 5 bytes in this section, but only has 4
+				0x01,                       // 1 code entry
+				0x02, 0x00, wasm.OpcodeEnd, // no locals and an empty body
+				wasm.SectionIDCustom, 0x01, 0x00, // an empty custom section
+			),
+			expectedErr: "section 10 (code), offset 0x18: invalid section length: expected to be 5 but got 4",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			_, e := DecodeModuleReader(bytes.NewReader(tc.input), wasm.Features20191205, wasm.MemorySizer)
+			require.EqualError(t, e, tc.expectedErr)
+		})
+	}
+}
@@ -98,17 +98,19 @@ func TestDecodeModule(t *testing.T) {
 		})
 	}
 
-	t.Run("skips custom section", func(t *testing.T) {
+	t.Run("retains custom section", func(t *testing.T) {
 		input := append(append(Magic, version...),
 			wasm.SectionIDCustom, 0xf, // 15 bytes in this section
 			0x04, 'm', 'e', 'm', 'e',
 			1, 2, 3, 4, 5, 6, 7, 8, 9, 0)
 		m, e := DecodeModule(input, wasm.Features20191205, wasm.MemorySizer)
 		require.NoError(t, e)
-		require.Equal(t, &wasm.Module{}, m)
+		require.Equal(t, &wasm.Module{CustomSections: []*wasm.CustomSection{
+			{Name: "meme", Data: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 0}},
+		}}, m)
 	})
 
-	t.Run("skips custom section, but not name", func(t *testing.T) {
+	t.Run("retains custom section and decodes name", func(t *testing.T) {
 		input := append(append(Magic, version...),
 			wasm.SectionIDCustom, 0xf, // 15 bytes in this section
 			0x04, 'm', 'e', 'm', 'e',
@@ -120,13 +122,26 @@ func TestDecodeModule(t *testing.T) {
 			's', 'i', 'm', 'p', 'l', 'e')
 		m, e := DecodeModule(input, wasm.Features20191205, wasm.MemorySizer)
 		require.NoError(t, e)
-		require.Equal(t, &wasm.Module{NameSection: &wasm.NameSection{ModuleName: "simple"}}, m)
+		require.Equal(t, &wasm.Module{
+			NameSection: &wasm.NameSection{ModuleName: "simple"},
+			CustomSections: []*wasm.CustomSection{
+				{Name: "meme", Data: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 0}},
+			},
+		}, m)
+	})
+	t.Run("custom section larger than binary", func(t *testing.T) {
+		input := append(append(Magic, version...),
+			wasm.SectionIDCustom, 0xf, // 15 bytes in this section, but only 7 follow
+			0x04, 'm', 'e', 'm', 'e',
+			1, 2)
+		_, e := DecodeModule(input, wasm.Features20191205, wasm.MemorySizer)
+		require.EqualError(t, e, "section 0 (custom), offset 0xf: failed to read name[meme]: unexpected EOF")
 	})
 	t.Run("data count section disabled", func(t *testing.T) {
 		input := append(append(Magic, version...),
 			wasm.SectionIDDataCount, 1, 0)
 		_, e := DecodeModule(input, wasm.Features20191205, wasm.MemorySizer)
-		require.EqualError(t, e, `data count section not supported as feature "bulk-memory-operations" is disabled`)
+		require.EqualError(t, e, `section 12 (data_count), offset 0xa: data count section not supported as feature "bulk-memory-operations" is disabled`)
 	})
 }
 
@@ -156,7 +171,7 @@ func TestDecodeModule_Errors(t *testing.T) {
 				wasm.SectionIDStart, 1, 0,
 				wasm.SectionIDStart, 1, 0,
 			),
-			expectedErr: `multiple start sections are invalid`,
+			expectedErr: `section 8 (start), offset 0x1d: multiple start sections are invalid`,
 		},
 		{
 			name: "redundant name section",
@@ -167,7 +182,39 @@ func TestDecodeModule_Errors(t *testing.T) {
 				wasm.SectionIDCustom, 0x09, // 9 bytes in this section
 				0x04, 'n', 'a', 'm', 'e',
 				subsectionIDModuleName, 0x02, 0x01, 'x'),
-			expectedErr: "section custom: redundant custom section name",
+			expectedErr: "section 0 (custom), offset 0x1a: redundant custom section name",
+		},
+		{
+			name: "truncated section size",
+			input: append(append(Magic, version...),
+				wasm.SectionIDType, 0x80), // continuation bit set, but no more bytes
+			expectedErr: "section 1 (type), offset 0xa: get size of section: EOF",
+		},
+		{
+			name: "invalid import",
+			input: append(append(Magic, version...),
+				wasm.SectionIDImport, 0x05, // 5 bytes in this section
+				0x01,                 // 1 import
+				0x01, 'a', 0x01, 'b', // module "a", name "b"
+				0x05, // invalid import kind
+			),
+			expectedErr: "section 2 (import), offset 0x10: import[0] 0x5[a.b]: invalid byte: invalid byte for importdesc: 0x5",
+		},
+		{
+			name: "code body too short, reported by function index",
+			input: append(append(Magic, version...),
+				wasm.SectionIDType, 0x04, 0x01, 0x60, 0, 0, // 1 type: func() -> ()
+				wasm.SectionIDImport, 0x07, // 7 bytes in this section
+				0x01,                 // 1 import
+				0x01, 'a', 0x01, 'b', // module "a", name "b"
+				wasm.ExternTypeFunc, 0x00, // func of type 0
+				wasm.SectionIDFunction, 0x03, 0x02, 0x00, 0x00, // 2 functions of type 0
+				wasm.SectionIDCode, 0x07, // 7 bytes in this section
+				0x02,                       // 2 code entries
+				0x02, 0x00, wasm.OpcodeEnd, // function[1]: no locals and an empty body
+				0x04, 0x00, wasm.OpcodeEnd, // function[2]: claims 4 bytes, but only 2 remain
+			),
+			expectedErr: "section 10 (code), offset 0x25: function[2]: read body: unexpected EOF",
 		},
 	}
 
@@ -279,8 +279,15 @@ func decodeElementSegment(r *bytes.Reader, enabledFeatures wasm.Features) (*wasm
 
 // encodeCode returns the wasm.ElementSegment encoded in WebAssembly 1.0 (20191205) Binary Format.
 //
+// Note: Segments with null entries are encoded as a vector of const expressions, as a vector of function indexes
+// cannot express `ref.null`. This requires FeatureBulkMemoryOperations to decode.
+//
 // https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#element-section%E2%91%A0
+// https://www.w3.org/TR/2022/WD-wasm-core-2-20220419/binary/modules.html#element-section
 func encodeElement(e *wasm.ElementSegment) (ret []byte) {
+	if hasNullElement(e.Init) {
+		return encodeElementConstExprVector(e)
+	}
 	if e.Mode == wasm.ElementModeActive {
 		ret = append(ret, leb128.EncodeInt32(int32(e.TableIndex))...)
 		ret = append(ret, encodeConstantExpression(e.OffsetExpr)...)
@@ -289,7 +296,59 @@ func encodeElement(e *wasm.ElementSegment) (ret []byte) {
 			ret = append(ret, leb128.EncodeInt32(int32(*idx))...)
 		}
 	} else {
-		panic("TODO: support encoding for non-active elements in bulk-memory-operations proposal")
+		switch e.Mode {
+		case wasm.ElementModePassive:
+			ret = append(ret, elementSegmentPrefixPassiveFuncrefValueVector)
+		case wasm.ElementModeDeclarative:
+			ret = append(ret, elementSegmentPrefixDeclarativeFuncrefValueVector)
+		}
+		ret = append(ret, 0x0) // ElemKind is fixed to 0x0 (funcref).
+		ret = append(ret, leb128.EncodeUint32(uint32(len(e.Init)))...)
+		for _, idx := range e.Init {
+			ret = append(ret, leb128.EncodeUint32(*idx)...)
+		}
+	}
+	return
+}
+
+// hasNullElement returns true if any entry is nil, which means `ref.null funcref`.
+func hasNullElement(init []*wasm.Index) bool {
+	for _, idx := range init {
+		if idx == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeElementConstExprVector encodes the segment with its entries as a vector of const expressions: `ref.func` for
+// a function index and `ref.null funcref` for a nil one.
+func encodeElementConstExprVector(e *wasm.ElementSegment) (ret []byte) {
+	switch e.Mode {
+	case wasm.ElementModeActive:
+		if e.TableIndex == 0 {
+			ret = append(ret, elementSegmentPrefixActiveFuncrefConstExprVector)
+			ret = append(ret, encodeConstantExpression(e.OffsetExpr)...)
+		} else {
+			ret = append(ret, elementSegmentPrefixActiveConstExprVector)
+			ret = append(ret, leb128.EncodeUint32(e.TableIndex)...)
+			ret = append(ret, encodeConstantExpression(e.OffsetExpr)...)
+			ret = append(ret, wasm.RefTypeFuncref)
+		}
+	case wasm.ElementModePassive:
+		ret = append(ret, elementSegmentPrefixPassiveConstExprVector, wasm.RefTypeFuncref)
+	case wasm.ElementModeDeclarative:
+		ret = append(ret, elementSegmentPrefixDeclarativeConstExprVector, wasm.RefTypeFuncref)
+	}
+	ret = append(ret, leb128.EncodeUint32(uint32(len(e.Init)))...)
+	for _, idx := range e.Init {
+		if idx == nil {
+			ret = append(ret, wasm.OpcodeRefNull, wasm.RefTypeFuncref, wasm.OpcodeEnd)
+		} else {
+			ret = append(ret, wasm.OpcodeRefFunc)
+			ret = append(ret, leb128.EncodeUint32(*idx)...)
+			ret = append(ret, wasm.OpcodeEnd)
+		}
 	}
 	return
 }
@@ -383,3 +383,106 @@ func TestDecodeElementSegment_errors(t *testing.T) {
 	_, err := decodeElementSegment(bytes.NewReader([]byte{1}), wasm.FeatureMultiValue)
 	require.EqualError(t, err, `non-zero prefix for element segment is invalid as feature "bulk-memory-operations" is disabled`)
 }
+
+func TestEncodeElement(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    *wasm.ElementSegment
+		expected []byte
+	}{
+		{
+			name: "active",
+			input: &wasm.ElementSegment{
+				OffsetExpr: &wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{1}},
+				Init:       []*wasm.Index{uint32Ptr(1), uint32Ptr(2)},
+				Type:       wasm.RefTypeFuncref,
+				Mode:       wasm.ElementModeActive,
+			},
+			expected: []byte{0x0, wasm.OpcodeI32Const, 1, wasm.OpcodeEnd, 2, 1, 2},
+		},
+		{
+			name: "passive",
+			input: &wasm.ElementSegment{
+				Init: []*wasm.Index{uint32Ptr(1), uint32Ptr(2)},
+				Type: wasm.RefTypeFuncref,
+				Mode: wasm.ElementModePassive,
+			},
+			expected: []byte{elementSegmentPrefixPassiveFuncrefValueVector, 0x0, 2, 1, 2},
+		},
+		{
+			name: "declarative",
+			input: &wasm.ElementSegment{
+				Init: []*wasm.Index{uint32Ptr(1)},
+				Type: wasm.RefTypeFuncref,
+				Mode: wasm.ElementModeDeclarative,
+			},
+			expected: []byte{elementSegmentPrefixDeclarativeFuncrefValueVector, 0x0, 1, 1},
+		},
+		{
+			name: "active with null",
+			input: &wasm.ElementSegment{
+				OffsetExpr: &wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{1}},
+				Init:       []*wasm.Index{uint32Ptr(1), nil},
+				Type:       wasm.RefTypeFuncref,
+				Mode:       wasm.ElementModeActive,
+			},
+			expected: []byte{
+				elementSegmentPrefixActiveFuncrefConstExprVector, wasm.OpcodeI32Const, 1, wasm.OpcodeEnd, 2,
+				wasm.OpcodeRefFunc, 1, wasm.OpcodeEnd,
+				wasm.OpcodeRefNull, wasm.RefTypeFuncref, wasm.OpcodeEnd,
+			},
+		},
+		{
+			name: "active table 1 with null",
+			input: &wasm.ElementSegment{
+				OffsetExpr: &wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{1}},
+				Init:       []*wasm.Index{nil},
+				Type:       wasm.RefTypeFuncref,
+				Mode:       wasm.ElementModeActive,
+				TableIndex: 1,
+			},
+			expected: []byte{
+				elementSegmentPrefixActiveConstExprVector, 1, wasm.OpcodeI32Const, 1, wasm.OpcodeEnd,
+				wasm.RefTypeFuncref, 1,
+				wasm.OpcodeRefNull, wasm.RefTypeFuncref, wasm.OpcodeEnd,
+			},
+		},
+		{
+			name: "passive with null",
+			input: &wasm.ElementSegment{
+				Init: []*wasm.Index{nil, uint32Ptr(2)},
+				Type: wasm.RefTypeFuncref,
+				Mode: wasm.ElementModePassive,
+			},
+			expected: []byte{
+				elementSegmentPrefixPassiveConstExprVector, wasm.RefTypeFuncref, 2,
+				wasm.OpcodeRefNull, wasm.RefTypeFuncref, wasm.OpcodeEnd,
+				wasm.OpcodeRefFunc, 2, wasm.OpcodeEnd,
+			},
+		},
+		{
+			name: "declarative with null",
+			input: &wasm.ElementSegment{
+				Init: []*wasm.Index{nil},
+				Type: wasm.RefTypeFuncref,
+				Mode: wasm.ElementModeDeclarative,
+			},
+			expected: []byte{
+				elementSegmentPrefixDeclarativeConstExprVector, wasm.RefTypeFuncref, 1,
+				wasm.OpcodeRefNull, wasm.RefTypeFuncref, wasm.OpcodeEnd,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			encoded := encodeElement(tc.input)
+			require.Equal(t, tc.expected, encoded)
+
+			decoded, err := decodeElementSegment(bytes.NewReader(encoded), wasm.FeatureBulkMemoryOperations|wasm.FeatureReferenceTypes)
+			require.NoError(t, err)
+			require.Equal(t, tc.input, decoded)
+		})
+	}
+}
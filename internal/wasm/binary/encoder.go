@@ -55,6 +55,9 @@ func EncodeModule(m *wasm.Module) (bytes []byte) {
 			nameSection := append(sizePrefixedName, encodeNameSectionData(m.NameSection)...)
 			bytes = append(bytes, encodeSection(wasm.SectionIDCustom, nameSection)...)
 		}
+		for _, c := range m.CustomSections {
+			bytes = append(bytes, encodeCustomSection(c)...)
+		}
 	}
 	return
 }
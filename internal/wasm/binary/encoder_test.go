@@ -33,6 +33,22 @@ func TestModule_Encode(t *testing.T) {
 				0x06, // the Module name simple is 6 bytes long
 				's', 'i', 'm', 'p', 'l', 'e'),
 		},
+		{
+			name: "name section and custom section",
+			input: &wasm.Module{
+				NameSection:    &wasm.NameSection{ModuleName: "simple"},
+				CustomSections: []*wasm.CustomSection{{Name: "meme", Data: []byte{1, 2, 3}}},
+			},
+			expected: append(append(Magic, version...),
+				wasm.SectionIDCustom, 0x0e, // 14 bytes in this section
+				0x04, 'n', 'a', 'm', 'e',
+				subsectionIDModuleName, 0x07, // 7 bytes in this subsection
+				0x06, // the Module name simple is 6 bytes long
+				's', 'i', 'm', 'p', 'l', 'e',
+				wasm.SectionIDCustom, 0x08, // 8 bytes in this section
+				0x04, 'm', 'e', 'm', 'e',
+				1, 2, 3),
+		},
 		{
 			name: "type section",
 			input: &wasm.Module{
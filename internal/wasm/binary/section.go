@@ -156,7 +156,9 @@ func decodeElementSection(r *bytes.Reader, enabledFeatures wasm.Features) ([]*wa
 	return result, nil
 }
 
-func decodeCodeSection(r *bytes.Reader) ([]*wasm.Code, error) {
+// decodeCodeSection decodes the code section. importedFunctionCount is used to report errors with the function index,
+// as that's what other tools, such as wasm-objdump, use.
+func decodeCodeSection(r *bytes.Reader, importedFunctionCount uint32) ([]*wasm.Code, error) {
 	vs, _, err := leb128.DecodeUint32(r)
 	if err != nil {
 		return nil, fmt.Errorf("get size of vector: %w", err)
@@ -165,7 +167,7 @@ func decodeCodeSection(r *bytes.Reader) ([]*wasm.Code, error) {
 	result := make([]*wasm.Code, vs)
 	for i := uint32(0); i < vs; i++ {
 		if result[i], err = decodeCode(r); err != nil {
-			return nil, fmt.Errorf("read %d-th code segment: %v", i, err)
+			return nil, fmt.Errorf("function[%d]: %v", importedFunctionCount+i, err)
 		}
 	}
 	return result, nil
@@ -201,6 +203,13 @@ func encodeSection(sectionID wasm.SectionID, contents []byte) []byte {
 	return append([]byte{sectionID}, encodeSizePrefixed(contents)...)
 }
 
+// encodeCustomSection encodes the name and opaque data of a wasm.SectionIDCustom.
+// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#custom-section%E2%91%A0
+func encodeCustomSection(c *wasm.CustomSection) []byte {
+	contents := append(encodeSizePrefixed([]byte(c.Name)), c.Data...)
+	return encodeSection(wasm.SectionIDCustom, contents)
+}
+
 // encodeTypeSection encodes a wasm.SectionIDType for the given imports in WebAssembly 1.0 (20191205) Binary
 // Format.
 //
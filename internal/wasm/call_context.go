@@ -2,6 +2,7 @@ package wasm
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync/atomic"
 
@@ -18,6 +19,17 @@ func NewCallContext(ns *Namespace, instance *ModuleInstance, Sys *internalsys.Co
 	return &CallContext{memory: instance.Memory, module: instance, ns: ns, Sys: Sys, closed: &zero}
 }
 
+// NewFakeCallContext returns a CallContext of a module that only exports the memory as "memory", if non-nil. This is
+// for testing host functions without instantiating WebAssembly, so the module isn't in any Store.
+func NewFakeCallContext(name string, memory *MemoryInstance, sys *internalsys.Context) *CallContext {
+	instance := &ModuleInstance{Name: name, Memory: memory, Exports: map[string]*ExportInstance{}}
+	if memory != nil {
+		instance.Exports["memory"] = &ExportInstance{Type: ExternTypeMemory, Memory: memory}
+	}
+	instance.CallCtx = NewCallContext(newNamespace(), instance, sys)
+	return instance.CallCtx
+}
+
 // CallContext is a function call context bound to a module. This is important as one module's functions can call
 // imported functions, but all need to effect the same memory.
 //
@@ -56,6 +68,25 @@ type CallContext struct {
 
 	// CodeCloser is non-nil when the code should be closed after this module.
 	CodeCloser api.Closer
+
+	// deleteImportStubs is non-nil when the code of functions stubbing missing imports should be deleted after this
+	// module. See Store.StubMissingImports
+	deleteImportStubs func()
+
+	// CloseNotifier is non-nil when the embedder should be notified after this module closes.
+	CloseNotifier func(ctx context.Context, exitCode uint32)
+
+	// TrapHandler is non-nil when the embedder should be notified when a function called via this module traps.
+	TrapHandler func(ctx context.Context, err error)
+
+	// initialize is the function set by RequireInitialize, exported as initializeName.
+	initialize     *FunctionInstance
+	initializeName string
+
+	// uninitialized is non-zero until initialize returns without error.
+	//
+	// Note: Exclusively reading and updating this with atomics guarantees cross-goroutine observations.
+	uninitialized uint32
 }
 
 // FailIfClosed returns a sys.ExitError if CloseWithExitCode was called.
@@ -66,6 +97,77 @@ func (m *CallContext) FailIfClosed() error {
 	return nil
 }
 
+// Reset restores this module to its state after instantiation, using the given system context from now on. The module
+// must be the one this was instantiated from. See ModuleInstance.Reset
+//
+// Note: Files opened via the previous system context are closed and any output it buffered is flushed, but its stdout
+// and stderr are not closed.
+func (m *CallContext) Reset(ctx context.Context, module *Module, sys *internalsys.Context) error {
+	if err := m.Sys.Flush(); err != nil {
+		return err
+	}
+	if err := m.Sys.FS().Close(ctx); err != nil {
+		return err
+	}
+	m.Sys = sys
+	return m.module.Reset(ctx, module)
+}
+
+// flush writes any output buffered by the system context. This is called when a function call fails, as a trap doesn't
+// close the module, so output written before it would otherwise be held until the module is closed.
+func (m *CallContext) flush() {
+	if sysCtx := m.Sys; sysCtx != nil { // ex nil if from ModuleBuilder
+		_ = sysCtx.Flush() // The call error is more important than one writing its output.
+	}
+}
+
+// notifyTrap calls TrapHandler, if set and err is a trap.
+func (m *CallContext) notifyTrap(ctx context.Context, err error) {
+	if m.TrapHandler == nil {
+		return
+	} else if trap := api.TrapError(nil); errors.As(err, &trap) {
+		m.TrapHandler(ctx, err)
+	}
+}
+
+// RequireInitialize fails calls to the functions of this module, until its exported function named initializeName is
+// called and returns without error. This is a no-op unless the module defines that function.
+//
+// This enforces the WASI ABI of a reactor, which exports "_initialize" to call before any other export.
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/design/application-abi.md#current-unstable-abi
+func (m *CallContext) RequireInitialize(initializeName string) {
+	exp, err := m.module.getExport(initializeName, ExternTypeFunc)
+	if err != nil || exp.Function.Module != m.module {
+		return
+	}
+	m.initialize, m.initializeName = exp.Function, initializeName
+	atomic.StoreUint32(&m.uninitialized, 1)
+}
+
+// failIfUninitialized returns an error if RequireInitialize was called, but not yet satisfied. Calling the initialize
+// function itself is allowed.
+func (m *CallContext) failIfUninitialized(f *FunctionInstance) error {
+	if atomic.LoadUint32(&m.uninitialized) == 0 || f == m.initialize {
+		return nil
+	}
+	return fmt.Errorf("module[%s] not initialized: call function[%s] first", m.module.Name, m.initializeName)
+}
+
+// markInitialized satisfies RequireInitialize if f is the initialize function.
+func (m *CallContext) markInitialized(f *FunctionInstance) {
+	if f == m.initialize {
+		atomic.StoreUint32(&m.uninitialized, 0)
+	}
+}
+
+// LimitTableGrowth sets the TableInstance.GrowLimit of the tables defined by the module, which must be the one this was
+// instantiated from. Imported tables are not limited, as they are shared with the module that exports them.
+func (m *CallContext) LimitTableGrowth(module *Module, maxElements uint32) {
+	for _, t := range m.module.Tables[module.ImportTableCount():] {
+		t.GrowLimit = &maxElements
+	}
+}
+
 // Name implements the same method as documented on api.Module
 func (m *CallContext) Name() string {
 	return m.module.Name
@@ -74,7 +176,8 @@ func (m *CallContext) Name() string {
 // WithMemory allows overriding memory without re-allocation when the result would be the same.
 func (m *CallContext) WithMemory(memory *MemoryInstance) *CallContext {
 	if memory != nil && memory != m.memory { // only re-allocate if it will change the effective memory
-		return &CallContext{module: m.module, memory: memory, Sys: m.Sys, closed: m.closed}
+		return &CallContext{module: m.module, memory: memory, Sys: m.Sys, closed: m.closed, CloseNotifier: m.CloseNotifier,
+			deleteImportStubs: m.deleteImportStubs}
 	}
 	return m
 }
@@ -116,9 +219,19 @@ func (m *CallContext) close(ctx context.Context, exitCode uint32) (c bool, err e
 		return false, nil
 	}
 	if sysCtx := m.Sys; sysCtx != nil { // ex nil if from ModuleBuilder
-		return true, sysCtx.FS().Close(ctx)
+		err = sysCtx.Close(ctx)
 	}
-	return true, nil
+	if m.deleteImportStubs != nil {
+		m.deleteImportStubs()
+	}
+	// Notify last, so that the embedder can rely on system resources, such as stdout, being closed.
+	if m.CloseNotifier != nil {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		m.CloseNotifier(ctx, exitCode)
+	}
+	return true, err
 }
 
 // Memory implements the same method as documented on api.Module.
@@ -126,6 +239,13 @@ func (m *CallContext) Memory() api.Memory {
 	return m.module.Memory
 }
 
+// DataSegments implements the same method as documented on api.Module.
+func (m *CallContext) DataSegments() []api.DataSegment {
+	ret := make([]api.DataSegment, len(m.module.DataSegments))
+	copy(ret, m.module.DataSegments)
+	return ret
+}
+
 // ExportedMemory implements the same method as documented on api.Module.
 func (m *CallContext) ExportedMemory(name string) api.Memory {
 	exp, err := m.module.getExport(name, ExternTypeMemory)
@@ -169,8 +289,50 @@ func (f *importedFn) Call(ctx context.Context, params ...uint64) (ret []uint64,
 	if ctx == nil {
 		ctx = context.Background()
 	}
+	ctx, end := beginCall(ctx)
+	if end != nil {
+		defer end()
+	}
 	mod := f.importingModule
-	return f.importedFn.Module.Engine.Call(ctx, mod, f.importedFn, params...)
+	if err = f.importedFn.takeInjectedTrap(); err != nil {
+		mod.notifyTrap(ctx, err)
+		return
+	} else if err = f.importedFn.Module.CallCtx.failIfUninitialized(f.importedFn); err != nil {
+		return
+	}
+	mark := mod.stderrBegin()
+	ret, err = f.importedFn.Module.Engine.Call(ctx, mod, f.importedFn, params...)
+	if err = mod.withGuestStderr(mark, err); err != nil {
+		mod.flush()
+		mod.notifyTrap(ctx, err)
+	}
+	return
+}
+
+// CallWithStack implements the same method as documented on api.Function.
+func (f *importedFn) CallWithStack(ctx context.Context, stack []uint64) (err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, end := beginCall(ctx)
+	if end != nil {
+		defer end()
+	}
+	mod := f.importingModule
+	if err = f.importedFn.takeInjectedTrap(); err != nil {
+		mod.notifyTrap(ctx, err)
+		return err
+	} else if err = f.importedFn.Module.CallCtx.failIfUninitialized(f.importedFn); err != nil {
+		return err
+	}
+	mark := mod.stderrBegin()
+	err = f.importedFn.Module.Engine.CallWithStack(ctx, mod, f.importedFn, stack)
+	if err = mod.withGuestStderr(mark, err); err != nil {
+		mod.flush()
+		mod.notifyTrap(ctx, err)
+		return err
+	}
+	return nil
 }
 
 // ParamTypes implements the same method as documented on api.Function.
@@ -188,11 +350,55 @@ func (f *FunctionInstance) Call(ctx context.Context, params ...uint64) (ret []ui
 	if ctx == nil {
 		ctx = context.Background()
 	}
+	ctx, end := beginCall(ctx)
+	if end != nil {
+		defer end()
+	}
 	mod := f.Module
+	if err = f.takeInjectedTrap(); err != nil {
+		mod.CallCtx.notifyTrap(ctx, err)
+		return
+	} else if err = mod.CallCtx.failIfUninitialized(f); err != nil {
+		return
+	}
+	mark := mod.CallCtx.stderrBegin()
 	ret, err = mod.Engine.Call(ctx, mod.CallCtx, f, params...)
+	if err = mod.CallCtx.withGuestStderr(mark, err); err != nil {
+		mod.CallCtx.flush()
+		mod.CallCtx.notifyTrap(ctx, err)
+	} else {
+		mod.CallCtx.markInitialized(f)
+	}
 	return
 }
 
+// CallWithStack implements the same method as documented on api.Function.
+func (f *FunctionInstance) CallWithStack(ctx context.Context, stack []uint64) (err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, end := beginCall(ctx)
+	if end != nil {
+		defer end()
+	}
+	mod := f.Module
+	if err = f.takeInjectedTrap(); err != nil {
+		mod.CallCtx.notifyTrap(ctx, err)
+		return err
+	} else if err = mod.CallCtx.failIfUninitialized(f); err != nil {
+		return err
+	}
+	mark := mod.CallCtx.stderrBegin()
+	err = mod.Engine.CallWithStack(ctx, mod.CallCtx, f, stack)
+	if err = mod.CallCtx.withGuestStderr(mark, err); err != nil {
+		mod.CallCtx.flush()
+		mod.CallCtx.notifyTrap(ctx, err)
+		return err
+	}
+	mod.CallCtx.markInitialized(f)
+	return nil
+}
+
 // ExportedGlobal implements the same method as documented on api.Module.
 func (m *CallContext) ExportedGlobal(name string) api.Global {
 	exp, err := m.module.getExport(name, ExternTypeGlobal)
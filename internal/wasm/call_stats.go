@@ -0,0 +1,52 @@
+package wasm
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	experimentalapi "github.com/tetratelabs/wazero/experimental"
+)
+
+// callInProgressKey marks a context of a call already counted in experimental.CallStats Total.
+type callInProgressKey struct{}
+
+// hostCallInProgressKey marks a context of a host function call already counted in experimental.CallStats Host.
+type hostCallInProgressKey struct{}
+
+// callsWithStats is the count of calls in progress which record experimental.CallStats. When zero, beginHostCall
+// returns without looking up the context, as host functions are called far more often than api.Function.
+//
+// Note: Exclusively reading and updating this with atomics guarantees cross-goroutine observations.
+var callsWithStats int64
+
+// beginCall returns a context to use for the call and a function to end it, which records the elapsed time in
+// experimental.CallStats Total. The function is nil when stats weren't requested or are already recorded by a caller.
+func beginCall(ctx context.Context) (context.Context, func()) {
+	stats, ok := ctx.Value(experimentalapi.CallStatsKey{}).(*experimentalapi.CallStats)
+	if !ok || ctx.Value(callInProgressKey{}) != nil {
+		return ctx, nil
+	}
+	atomic.AddInt64(&callsWithStats, 1)
+	start := time.Now()
+	return context.WithValue(ctx, callInProgressKey{}, struct{}{}), func() {
+		stats.Total += time.Since(start)
+		atomic.AddInt64(&callsWithStats, -1)
+	}
+}
+
+// beginHostCall is like beginCall, except it records the elapsed time of a host function in experimental.CallStats
+// Host.
+func beginHostCall(ctx context.Context) (context.Context, func()) {
+	if atomic.LoadInt64(&callsWithStats) == 0 {
+		return ctx, nil // No call records stats, so this one doesn't either.
+	}
+	stats, ok := ctx.Value(experimentalapi.CallStatsKey{}).(*experimentalapi.CallStats)
+	if !ok || ctx.Value(hostCallInProgressKey{}) != nil {
+		return ctx, nil
+	}
+	start := time.Now()
+	return context.WithValue(ctx, hostCallInProgressKey{}, struct{}{}), func() {
+		stats.Host += time.Since(start)
+	}
+}
@@ -219,6 +219,14 @@ func TestModule_SectionElementCount(t *testing.T) {
 			input:    &Module{NameSection: &NameSection{ModuleName: "simple"}},
 			expected: map[string]uint32{"custom": 1},
 		},
+		{
+			name: "NameSection and CustomSections",
+			input: &Module{
+				NameSection:    &NameSection{ModuleName: "simple"},
+				CustomSections: []*CustomSection{{Name: "producers"}, {Name: "target_features"}},
+			},
+			expected: map[string]uint32{"custom": 3},
+		},
 		{
 			name:     "HostFunctionSection",
 			input:    &Module{HostFunctionSection: []*reflect.Value{&fn}},
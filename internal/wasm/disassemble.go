@@ -0,0 +1,284 @@
+package wasm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/tetratelabs/wazero/internal/leb128"
+)
+
+// DisassembleFunction returns a listing of the instructions of the function at the given index in the function index
+// namespace, which is prefixed by imports. This decodes the opcodes in Code.Body, not any compiled machine code.
+//
+// Each line is the offset of the instruction in Code.Body, followed by its name and any immediates, indented by its
+// block nesting. For example:
+//
+//	0x0000: local.get 0
+//	0x0002: if (result i32)
+//	0x0004:   i32.const 1
+//	0x0006: else
+//	0x0007:   i32.const 2
+//	0x0009: end
+//	0x000a: end
+func (m *Module) DisassembleFunction(funcIdx Index) (string, error) {
+	importCount := m.ImportFuncCount()
+	if funcIdx < importCount {
+		return "", fmt.Errorf("function[%d] is imported", funcIdx)
+	}
+	codeIdx := funcIdx - importCount
+	if codeIdx >= uint32(len(m.CodeSection)) {
+		return "", fmt.Errorf("function[%d] out of range", funcIdx)
+	}
+
+	body := m.CodeSection[codeIdx].Body
+	r := bytes.NewReader(body)
+	var ret strings.Builder
+	depth := 0
+	for r.Len() > 0 {
+		offset := len(body) - r.Len()
+		op, _ := r.ReadByte()
+
+		name, immediates, err := disassembleInstruction(op, r)
+		if err != nil {
+			if name != "" { // The opcode was valid, but its immediates were not.
+				err = fmt.Errorf("%s: %v", name, err)
+			}
+			return "", fmt.Errorf("function[%d]: offset %#x: %v", funcIdx, offset, err)
+		}
+
+		indent := depth
+		switch op {
+		case OpcodeBlock, OpcodeLoop, OpcodeIf:
+			depth++
+		case OpcodeElse:
+			indent--
+		case OpcodeEnd:
+			if depth > 0 { // The last end terminates the function, not a block.
+				depth--
+			}
+			indent = depth
+		}
+
+		fmt.Fprintf(&ret, "0x%04x: %s%s%s\n", offset, strings.Repeat("  ", indent), name, immediates)
+	}
+	return ret.String(), nil
+}
+
+// disassembleInstruction returns the name of the instruction for the opcode, which was already read, and its
+// immediates read from r. The immediates are prefixed with a space unless there are none.
+func disassembleInstruction(op Opcode, r *bytes.Reader) (name, immediates string, err error) {
+	switch op {
+	case OpcodeMiscPrefix:
+		return disassembleMiscInstruction(r)
+	case OpcodeVecPrefix:
+		return disassembleVectorInstruction(r)
+	case OpcodeAtomicPrefix:
+		return disassembleAtomicInstruction(r)
+	}
+
+	if name = InstructionName(op); name == "" {
+		return "", "", fmt.Errorf("invalid opcode %#x", op)
+	}
+
+	switch {
+	case op == OpcodeBlock || op == OpcodeLoop || op == OpcodeIf:
+		immediates, err = disassembleBlockType(r)
+	case op == OpcodeBr || op == OpcodeBrIf || op == OpcodeCall || op == OpcodeRefFunc ||
+		(op >= OpcodeLocalGet && op <= OpcodeTableSet):
+		immediates, err = disassembleIndexes(r, 1)
+	case op == OpcodeCallIndirect: // type index, then table index
+		immediates, err = disassembleIndexes(r, 2)
+	case op == OpcodeBrTable:
+		var count uint32
+		if count, _, err = leb128.DecodeUint32(r); err != nil {
+			return
+		}
+		// The label indexes are followed by the default label index.
+		immediates, err = disassembleIndexes(r, int(count)+1)
+	case op == OpcodeTypedSelect:
+		var count uint32
+		if count, _, err = leb128.DecodeUint32(r); err != nil {
+			return
+		}
+		for i := uint32(0); i < count; i++ {
+			var t ValueType
+			if t, err = r.ReadByte(); err != nil {
+				return
+			}
+			immediates += " " + ValueTypeName(t)
+		}
+	case op >= OpcodeI32Load && op <= OpcodeI64Store32:
+		immediates, err = disassembleMemArg(r)
+	case op == OpcodeMemorySize || op == OpcodeMemoryGrow:
+		err = skipReservedBytes(r, 1)
+	case op == OpcodeI32Const:
+		var v int32
+		v, _, err = leb128.DecodeInt32(r)
+		immediates = fmt.Sprintf(" %d", v)
+	case op == OpcodeI64Const:
+		var v int64
+		v, _, err = leb128.DecodeInt64(r)
+		immediates = fmt.Sprintf(" %d", v)
+	case op == OpcodeF32Const:
+		var b []byte
+		if b, err = readBytes(r, 4); err == nil {
+			immediates = fmt.Sprintf(" %v", math.Float32frombits(binary.LittleEndian.Uint32(b)))
+		}
+	case op == OpcodeF64Const:
+		var b []byte
+		if b, err = readBytes(r, 8); err == nil {
+			immediates = fmt.Sprintf(" %v", math.Float64frombits(binary.LittleEndian.Uint64(b)))
+		}
+	case op == OpcodeRefNull:
+		var t RefType
+		if t, err = r.ReadByte(); err == nil {
+			immediates = " " + RefTypeName(t)
+		}
+	}
+	return
+}
+
+// disassembleMiscInstruction is like disassembleInstruction, except for instructions prefixed by OpcodeMiscPrefix.
+func disassembleMiscInstruction(r *bytes.Reader) (name, immediates string, err error) {
+	op, err := r.ReadByte()
+	if err != nil {
+		return "", "", err
+	}
+	if name = MiscInstructionName(op); name == "" {
+		return "", "", fmt.Errorf("invalid misc opcode %#x", op)
+	}
+
+	switch op {
+	case OpcodeMiscMemoryInit: // data index, then a reserved byte for the memory index
+		if immediates, err = disassembleIndexes(r, 1); err == nil {
+			err = skipReservedBytes(r, 1)
+		}
+	case OpcodeMiscDataDrop, OpcodeMiscElemDrop, OpcodeMiscTableGrow, OpcodeMiscTableSize, OpcodeMiscTableFill:
+		immediates, err = disassembleIndexes(r, 1)
+	case OpcodeMiscMemoryCopy: // reserved bytes for the destination and source memory indexes
+		err = skipReservedBytes(r, 2)
+	case OpcodeMiscMemoryFill:
+		err = skipReservedBytes(r, 1)
+	case OpcodeMiscTableInit, OpcodeMiscTableCopy: // element index then table index, or destination then source table
+		immediates, err = disassembleIndexes(r, 2)
+	}
+	return
+}
+
+// disassembleVectorInstruction is like disassembleInstruction, except for instructions prefixed by OpcodeVecPrefix.
+func disassembleVectorInstruction(r *bytes.Reader) (name, immediates string, err error) {
+	op, err := r.ReadByte()
+	if err != nil {
+		return "", "", err
+	}
+	if name = VectorInstructionName(op); name == "" {
+		return "", "", fmt.Errorf("invalid vector opcode %#x", op)
+	}
+
+	switch {
+	case op <= OpcodeVecV128Store || op == OpcodeVecV128Load32zero || op == OpcodeVecV128Load64zero:
+		immediates, err = disassembleMemArg(r)
+	case op >= OpcodeVecV128Load8Lane && op <= OpcodeVecV128Store64Lane:
+		if immediates, err = disassembleMemArg(r); err == nil {
+			var lane byte
+			lane, err = r.ReadByte()
+			immediates += fmt.Sprintf(" %d", lane)
+		}
+	case op == OpcodeVecV128Const || op == OpcodeVecV128i8x16Shuffle:
+		var b []byte
+		if b, err = readBytes(r, 16); err != nil {
+			return
+		}
+		if op == OpcodeVecV128Const {
+			immediates = fmt.Sprintf(" i64x2 %#x %#x", binary.LittleEndian.Uint64(b), binary.LittleEndian.Uint64(b[8:]))
+		} else {
+			for _, lane := range b {
+				immediates += fmt.Sprintf(" %d", lane)
+			}
+		}
+	case op >= OpcodeVecI8x16ExtractLaneS && op <= OpcodeVecF64x2ReplaceLane:
+		var lane byte
+		lane, err = r.ReadByte()
+		immediates = fmt.Sprintf(" %d", lane)
+	}
+	return
+}
+
+// disassembleAtomicInstruction is like disassembleInstruction, except for instructions prefixed by
+// OpcodeAtomicPrefix.
+func disassembleAtomicInstruction(r *bytes.Reader) (name, immediates string, err error) {
+	op, err := r.ReadByte()
+	if err != nil {
+		return "", "", err
+	}
+	if name = AtomicInstructionName(op); name == "" {
+		return "", "", fmt.Errorf("invalid atomic opcode %#x", op)
+	}
+
+	if op == OpcodeAtomicFence {
+		err = skipReservedBytes(r, 1)
+	} else {
+		immediates, err = disassembleMemArg(r)
+	}
+	return
+}
+
+// disassembleBlockType formats the block type similar to the WebAssembly Text Format, ex. " (result i32)".
+func disassembleBlockType(r *bytes.Reader) (string, error) {
+	raw, _, err := leb128.DecodeInt33AsInt64(r)
+	if err != nil {
+		return "", fmt.Errorf("read block type: %w", err)
+	}
+	switch {
+	case raw == -64: // 0x40 in original byte = nil
+		return "", nil
+	case raw < 0: // The original byte is a value type, ex. 0x7f for i32.
+		return fmt.Sprintf(" (result %s)", ValueTypeName(ValueType(raw&0x7f))), nil
+	default:
+		return fmt.Sprintf(" (type %d)", raw), nil
+	}
+}
+
+// disassembleIndexes reads count unsigned LEB128 encoded indexes, ex. a function index.
+func disassembleIndexes(r *bytes.Reader, count int) (string, error) {
+	var ret string
+	for i := 0; i < count; i++ {
+		idx, _, err := leb128.DecodeUint32(r)
+		if err != nil {
+			return "", fmt.Errorf("read index: %w", err)
+		}
+		ret += fmt.Sprintf(" %d", idx)
+	}
+	return ret, nil
+}
+
+// disassembleMemArg formats the memory argument similar to the WebAssembly Text Format, ex. " offset=8 align=4".
+func disassembleMemArg(r *bytes.Reader) (string, error) {
+	align, _, err := leb128.DecodeUint32(r)
+	if err != nil {
+		return "", fmt.Errorf("read memory align: %v", err)
+	}
+	offset, _, err := leb128.DecodeUint32(r)
+	if err != nil {
+		return "", fmt.Errorf("read memory offset: %v", err)
+	}
+	return fmt.Sprintf(" offset=%d align=%d", offset, uint64(1)<<align), nil
+}
+
+// skipReservedBytes skips bytes, such as a memory index, which are always zero in the supported features.
+func skipReservedBytes(r *bytes.Reader, count int) error {
+	_, err := readBytes(r, count)
+	return err
+}
+
+func readBytes(r *bytes.Reader, count int) ([]byte, error) {
+	b := make([]byte, count)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
@@ -0,0 +1,156 @@
+package wasm
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestModule_DisassembleFunction(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     []byte
+		expected string
+	}{
+		{
+			name:     "empty",
+			body:     []byte{OpcodeEnd},
+			expected: "0x0000: end\n",
+		},
+		{
+			name: "block nesting",
+			body: []byte{
+				OpcodeLocalGet, 0,
+				OpcodeIf, 0x7f, // (result i32)
+				OpcodeI32Const, 1,
+				OpcodeElse,
+				OpcodeLoop, 0x40, // empty block type
+				OpcodeBr, 0,
+				OpcodeEnd,
+				OpcodeI32Const, 0x7e, // -2
+				OpcodeEnd,
+				OpcodeEnd,
+			},
+			expected: `0x0000: local.get 0
+0x0002: if (result i32)
+0x0004:   i32.const 1
+0x0006: else
+0x0007:   loop
+0x0009:     br 0
+0x000b:   end
+0x000c:   i32.const -2
+0x000e: end
+0x000f: end
+`,
+		},
+		{
+			name: "immediates",
+			body: []byte{
+				OpcodeBlock, 0x01, // (type 1)
+				OpcodeI64Const, 0x80, 0x01, // 128
+				OpcodeF32Const, 0x00, 0x00, 0xc0, 0x3f, // 1.5
+				OpcodeF64Const, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x04, 0x40, // 2.5
+				OpcodeI32Load, 0x02, 0x08, // align=4 offset=8
+				OpcodeBrTable, 2, 0, 1, 0, // 2 labels, then the default
+				OpcodeCallIndirect, 1, 0,
+				OpcodeTypedSelect, 1, ValueTypeI64,
+				OpcodeMemoryGrow, 0,
+				OpcodeRefNull, RefTypeExternref,
+				OpcodeEnd,
+				OpcodeEnd,
+			},
+			expected: `0x0000: block (type 1)
+0x0002:   i64.const 128
+0x0005:   f32.const 1.5
+0x000a:   f64.const 2.5
+0x0013:   i32.load offset=8 align=4
+0x0016:   br_table 0 1 0
+0x001b:   call_indirect 1 0
+0x001e:   typed_select i64
+0x0021:   memory.grow
+0x0023:   ref.null externref
+0x0025: end
+0x0026: end
+`,
+		},
+		{
+			name: "prefixed",
+			body: []byte{
+				OpcodeMiscPrefix, OpcodeMiscMemoryInit, 3, 0,
+				OpcodeMiscPrefix, OpcodeMiscTableCopy, 1, 2,
+				OpcodeMiscPrefix, OpcodeMiscI32TruncSatF32S,
+				OpcodeVecPrefix, OpcodeVecV128Load8Lane, 0, 4, 15,
+				OpcodeVecPrefix, OpcodeVecI32x4ExtractLane, 3,
+				OpcodeAtomicPrefix, OpcodeAtomicFence, 0,
+				OpcodeAtomicPrefix, OpcodeAtomicI32Load, 2, 0,
+				OpcodeEnd,
+			},
+			expected: `0x0000: memory.init 3
+0x0004: table.copy 1 2
+0x0008: i32.trunc_sat_f32_s
+0x000a: v128.load8_lane offset=4 align=1 15
+0x000f: i32x4.extract_lane 3
+0x0012: atomic.fence
+0x0015: i32.atomic.load offset=0 align=4
+0x0019: end
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			m := &Module{CodeSection: []*Code{{Body: tc.body}}}
+			actual, err := m.DisassembleFunction(0)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestModule_DisassembleFunction_Errors(t *testing.T) {
+	m := &Module{
+		ImportSection: []*Import{{Type: ExternTypeFunc}},
+		CodeSection: []*Code{
+			{Body: []byte{OpcodeI32Const}},
+			{Body: []byte{0xff}},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		index       Index
+		expectedErr string
+	}{
+		{
+			name:        "imported",
+			index:       0,
+			expectedErr: "function[0] is imported",
+		},
+		{
+			name:        "truncated immediate",
+			index:       1,
+			expectedErr: "function[1]: offset 0x0: i32.const: readByte failed: EOF",
+		},
+		{
+			name:        "invalid opcode",
+			index:       2,
+			expectedErr: "function[2]: offset 0x0: invalid opcode 0xff",
+		},
+		{
+			name:        "out of range",
+			index:       3,
+			expectedErr: "function[3] out of range",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := m.DisassembleFunction(tc.index)
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
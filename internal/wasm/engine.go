@@ -47,6 +47,10 @@ type ModuleEngine interface {
 	// Call invokes a function instance f with given parameters.
 	Call(ctx context.Context, m *CallContext, f *FunctionInstance, params ...uint64) (results []uint64, err error)
 
+	// CallWithStack is like Call, except params are read from and results written to the caller-provided stack.
+	// This errs if the stack is shorter than FunctionType.StackLen.
+	CallWithStack(ctx context.Context, m *CallContext, f *FunctionInstance, stack []uint64) error
+
 	// CreateFuncElementInstance creates an ElementInstance whose references are engine-specific function pointers
 	// corresponding to the given `indexes`.
 	CreateFuncElementInstance(indexes []*Index) *ElementInstance
@@ -102,6 +102,15 @@ const (
 	//
 	// See https://github.com/WebAssembly/spec/blob/main/proposals/simd/SIMD.md
 	FeatureSIMD
+
+	// FeatureThreads enables the atomic memory instructions of the threads proposal, such as OpcodeAtomicI32RmwAdd.
+	//
+	// Note: Only the atomic instructions are supported, and they have single-threaded semantics: loads, stores and
+	// read-modify-write operations are plain memory accesses and OpcodeAtomicFence is a no-op. Shared memory,
+	// OpcodeAtomicMemoryWait32 and OpcodeAtomicMemoryWait64 are not supported.
+	//
+	// See https://github.com/WebAssembly/threads/blob/main/proposals/threads/Overview.md
+	FeatureThreads
 )
 
 // Set assigns the value for the given feature.
@@ -165,6 +174,9 @@ func featureName(f Features) string {
 	case FeatureSIMD:
 		// match https://github.com/WebAssembly/spec/blob/main/proposals/simd/SIMD.md
 		return "simd"
+	case FeatureThreads:
+		// match https://github.com/WebAssembly/threads/blob/main/proposals/threads/Overview.md
+		return "threads"
 	}
 	return ""
 }
@@ -61,12 +61,24 @@ func (m *Module) validateFunctionWithMaxStackValues(
 	tables []*Table,
 	maxStackValues int,
 	declaredFunctionIndexes map[Index]struct{},
-) error {
+) (err error) {
 	functionType := m.TypeSection[m.FunctionSection[idx]]
 	body := m.CodeSection[idx].Body
 	localTypes := m.CodeSection[idx].LocalTypes
 	types := m.TypeSection
 
+	// opPC is the position of the instruction being validated, or the end of the body, once all were.
+	var opPC uint64
+	if bodyOffset := m.CodeSection[idx].BodyOffset; bodyOffset != 0 {
+		// Add the offset into the binary, so that the instruction can be found with tools such as wasm-objdump.
+		// Ex. "section 10 (code), offset 0x1a2: invalid opcode 0xfc"
+		defer func() {
+			if err != nil {
+				err = fmt.Errorf("section %d (%s), offset %#x: %w", SectionIDCode, SectionIDName(SectionIDCode), bodyOffset+opPC, err)
+			}
+		}()
+	}
+
 	// We start with the outermost control block which is for function return if the code branches into it.
 	controlBlockStack := []*controlBlock{{blockType: functionType}}
 	// Create the valueTypeStack to track the state of Wasm value stacks at anypoint of execution.
@@ -75,6 +87,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 	// Now start walking through all the instructions in the body while tracking
 	// control blocks and value types to check the validity of all instructions.
 	for pc := uint64(0); pc < uint64(len(body)); pc++ {
+		opPC = pc
 		op := body[pc]
 		if OpcodeI32Load <= op && op <= OpcodeI64Store32 {
 			if memory == nil {
@@ -1320,6 +1333,73 @@ func (m *Module) validateFunctionWithMaxStackValues(
 			default:
 				return fmt.Errorf("TODO: SIMD instruction %s will be implemented in #506", vectorInstructionName[vecOpcode])
 			}
+		} else if op == OpcodeAtomicPrefix {
+			pc++
+			// Atomic instructions come with two bytes where the first byte is always OpcodeAtomicPrefix,
+			// and the second byte determines the actual instruction.
+			atomicOpcode := body[pc]
+			if err := enabledFeatures.Require(FeatureThreads); err != nil {
+				return fmt.Errorf("%s invalid as %v", atomicInstructionNames[atomicOpcode], err)
+			}
+
+			if atomicOpcode == OpcodeAtomicFence {
+				pc++
+				if int(pc) >= len(body) || body[pc] != 0 {
+					return fmt.Errorf("atomic.fence reserved byte must be zero")
+				}
+				continue
+			}
+
+			var t ValueType
+			var size uint32
+			switch atomicOpcode {
+			case OpcodeAtomicMemoryNotify:
+				t, size = ValueTypeI32, 4
+			case OpcodeAtomicMemoryWait32, OpcodeAtomicMemoryWait64:
+				return fmt.Errorf("%s is not supported as there is no shared memory", atomicInstructionNames[atomicOpcode])
+			default:
+				var ok bool
+				if t, size, ok = AtomicAccess(atomicOpcode); !ok {
+					return fmt.Errorf("invalid atomic opcode: %#x", atomicOpcode)
+				}
+			}
+
+			if memory == nil {
+				return fmt.Errorf("unknown memory access")
+			}
+			pc++
+			align, _, read, err := readMemArg(pc, body)
+			if err != nil {
+				return err
+			}
+			pc += read - 1
+			// Unlike non-atomic memory instructions, the alignment must be exactly the natural alignment.
+			if 1<<align != size {
+				return fmt.Errorf("invalid memory alignment")
+			}
+
+			var params []ValueType
+			var hasResult bool
+			switch {
+			case atomicOpcode == OpcodeAtomicMemoryNotify:
+				params, hasResult = []ValueType{ValueTypeI32, ValueTypeI32}, true
+			case atomicOpcode <= OpcodeAtomicI64Load32U:
+				params, hasResult = []ValueType{ValueTypeI32}, true
+			case atomicOpcode <= OpcodeAtomicI64Store32:
+				params = []ValueType{ValueTypeI32, t}
+			case atomicOpcode < OpcodeAtomicI32RmwCmpxchg:
+				params, hasResult = []ValueType{ValueTypeI32, t}, true
+			default:
+				params, hasResult = []ValueType{ValueTypeI32, t, t}, true
+			}
+			for i := len(params) - 1; i >= 0; i-- {
+				if err := valueTypeStack.popAndVerifyType(params[i]); err != nil {
+					return fmt.Errorf("cannot pop the operand for %s: %v", atomicInstructionNames[atomicOpcode], err)
+				}
+			}
+			if hasResult {
+				valueTypeStack.push(t)
+			}
 		} else if op == OpcodeBlock {
 			bt, num, err := DecodeBlockType(types, bytes.NewReader(body[pc+1:]), enabledFeatures)
 			if err != nil {
@@ -1451,14 +1531,6 @@ func (m *Module) validateFunctionWithMaxStackValues(
 			if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
 				return fmt.Errorf("type mismatch on 3rd select operand: %v", err)
 			}
-			v1, err := valueTypeStack.pop()
-			if err != nil {
-				return fmt.Errorf("invalid select: %v", err)
-			}
-			v2, err := valueTypeStack.pop()
-			if err != nil {
-				return fmt.Errorf("invalid select: %v", err)
-			}
 
 			if op == OpcodeTypedSelect {
 				if err := enabledFeatures.Require(FeatureReferenceTypes); err != nil {
@@ -1474,17 +1546,36 @@ func (m *Module) validateFunctionWithMaxStackValues(
 					tp != api.ValueTypeExternref && tp != ValueTypeFuncref {
 					return fmt.Errorf("invalid type %s for %s", ValueTypeName(tp), OpcodeTypedSelectName)
 				}
-			} else if isReferenceValueType(v1) || isReferenceValueType(v2) {
-				return fmt.Errorf("reference types cannot be used for non typed select instruction")
-			}
 
-			if v1 != v2 && v1 != valueTypeUnknown && v2 != valueTypeUnknown {
-				return fmt.Errorf("type mismatch on 1st and 2nd select operands")
-			}
-			if v1 == valueTypeUnknown {
-				valueTypeStack.push(v2)
+				// Unlike select, the operands are checked against the declared type instead of each other.
+				if err := valueTypeStack.popAndVerifyType(tp); err != nil {
+					return fmt.Errorf("type mismatch on 2nd %s operand: %v", OpcodeTypedSelectName, err)
+				}
+				if err := valueTypeStack.popAndVerifyType(tp); err != nil {
+					return fmt.Errorf("type mismatch on 1st %s operand: %v", OpcodeTypedSelectName, err)
+				}
+				valueTypeStack.push(tp)
 			} else {
-				valueTypeStack.push(v1)
+				v1, err := valueTypeStack.pop()
+				if err != nil {
+					return fmt.Errorf("invalid select: %v", err)
+				}
+				v2, err := valueTypeStack.pop()
+				if err != nil {
+					return fmt.Errorf("invalid select: %v", err)
+				}
+				if isReferenceValueType(v1) || isReferenceValueType(v2) {
+					return fmt.Errorf("reference types cannot be used for non typed select instruction")
+				}
+
+				if v1 != v2 && v1 != valueTypeUnknown && v2 != valueTypeUnknown {
+					return fmt.Errorf("type mismatch on 1st and 2nd select operands")
+				}
+				if v1 == valueTypeUnknown {
+					valueTypeStack.push(v2)
+				} else {
+					valueTypeStack.push(v1)
+				}
 			}
 		} else if op == OpcodeUnreachable {
 			// unreachable instruction is stack-polymorphic.
@@ -1495,6 +1586,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 		}
 	}
 
+	opPC = uint64(len(body))
 	if len(controlBlockStack) > 0 {
 		return fmt.Errorf("ill-nested block exists")
 	}
@@ -44,6 +44,27 @@ func TestModule_ValidateFunction_validateFunctionWithMaxStackValues(t *testing.T
 	})
 }
 
+func TestModule_ValidateFunction_BodyOffset(t *testing.T) {
+	// The body begins at 0x1a0 in the binary, and the invalid instruction is after i32.const 1 and drop.
+	m := &Module{
+		TypeSection:     []*FunctionType{v_v},
+		FunctionSection: []Index{0},
+		CodeSection:     []*Code{{Body: []byte{OpcodeI32Const, 1, OpcodeDrop, 0xff, OpcodeEnd}, BodyOffset: 0x1a0}},
+	}
+	err := m.validateFunction(Features20191205, 0, []Index{0}, nil, nil, nil, nil)
+	require.EqualError(t, err, "section 10 (code), offset 0x1a3: invalid instruction 0xff")
+
+	// Errors found after all instructions are at the end of the body.
+	m.CodeSection[0].Body = []byte{OpcodeBlock, 0x40, OpcodeEnd}
+	err = m.validateFunction(Features20191205, 0, []Index{0}, nil, nil, nil, nil)
+	require.EqualError(t, err, "section 10 (code), offset 0x1a3: ill-nested block exists")
+
+	// Without an offset, ex. the module wasn't decoded, the error isn't changed.
+	m.CodeSection[0].BodyOffset = 0
+	err = m.validateFunction(Features20191205, 0, []Index{0}, nil, nil, nil, nil)
+	require.EqualError(t, err, "ill-nested block exists")
+}
+
 func TestModule_ValidateFunction_SignExtensionOps(t *testing.T) {
 	tests := []struct {
 		input                Opcode
@@ -2614,6 +2635,99 @@ func TestModule_funcValidation_TableGetSet(t *testing.T) {
 	}
 }
 
+// TestModule_funcValidation_StackPolymorphic ensures the operand stack is unconstrained after unreachable, br and
+// return until the end of the enclosing block, but values pushed afterwards are still type checked.
+// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#polymorphism
+func TestModule_funcValidation_StackPolymorphic(t *testing.T) {
+	tests := []struct {
+		name        string
+		functype    *FunctionType
+		body        []byte
+		expectedErr string
+	}{
+		{
+			name:     "unreachable then i32.add",
+			functype: v_v,
+			body:     []byte{OpcodeUnreachable, OpcodeI32Add, OpcodeDrop, OpcodeEnd},
+		},
+		{
+			name:     "unreachable satisfies results",
+			functype: v_i32,
+			body:     []byte{OpcodeUnreachable, OpcodeEnd},
+		},
+		{
+			name:     "return then i32.add satisfies results",
+			functype: v_i32,
+			body:     []byte{OpcodeI32Const, 0, OpcodeReturn, OpcodeI32Add, OpcodeEnd},
+		},
+		{
+			name:     "br then br",
+			functype: v_i32,
+			body:     []byte{OpcodeUnreachable, OpcodeBr, 0, OpcodeBr, 0, OpcodeEnd},
+		},
+		{
+			name:     "unreachable then select",
+			functype: v_i32,
+			body:     []byte{OpcodeUnreachable, OpcodeSelect, OpcodeEnd},
+		},
+		{
+			name:     "unreachable then br_table",
+			functype: v_i32,
+			body:     []byte{OpcodeUnreachable, OpcodeBrTable, 0, 0, OpcodeEnd},
+		},
+		{
+			name:     "unreachable then if",
+			functype: v_i32,
+			body: []byte{
+				OpcodeUnreachable,
+				OpcodeIf, ValueTypeI32, OpcodeI32Const, 0, OpcodeElse, OpcodeI32Const, 1, OpcodeEnd,
+				OpcodeEnd,
+			},
+		},
+		{
+			name:        "return then type mismatch",
+			functype:    v_v,
+			body:        []byte{OpcodeReturn, OpcodeI32Const, 0, OpcodeI64Add, OpcodeDrop, OpcodeEnd},
+			expectedErr: "cannot pop the 1st i64 operand for i64.add: type mismatch: expected i64, but was i32",
+		},
+		{
+			name:        "return then too many results",
+			functype:    v_v,
+			body:        []byte{OpcodeReturn, OpcodeI32Const, 0, OpcodeEnd},
+			expectedErr: "too many results\n\thave (unknown, i32)\n\twant ()",
+		},
+		{
+			name:        "unreachable then wrong result",
+			functype:    v_i32,
+			body:        []byte{OpcodeUnreachable, OpcodeI64Const, 0, OpcodeReturn, OpcodeEnd},
+			expectedErr: "cannot use i64 as result[0] type i32",
+		},
+		{
+			name:        "nested block isn't polymorphic",
+			functype:    v_v,
+			body:        []byte{OpcodeUnreachable, OpcodeBlock, 0x40, OpcodeDrop, OpcodeEnd, OpcodeEnd},
+			expectedErr: "invalid drop: invalid operation: trying to pop at 1 with limit 1",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			m := &Module{
+				TypeSection:     []*FunctionType{tc.functype},
+				FunctionSection: []Index{0},
+				CodeSection:     []*Code{{Body: tc.body}},
+			}
+			err := m.validateFunction(Features20191205, 0, []Index{0}, nil, nil, nil, nil)
+			if tc.expectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
 func TestModule_funcValidation_Select_error(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -2651,6 +2765,39 @@ func TestModule_funcValidation_Select_error(t *testing.T) {
 			flag:        FeatureReferenceTypes,
 			expectedErr: `invalid type unknown for typed_select`,
 		},
+		{
+			name: "typed_select (2nd operand type mismatch)",
+			body: []byte{
+				OpcodeRefNull, RefTypeExternref, OpcodeI32Const, 0, OpcodeI32Const, 0,
+				OpcodeTypedSelect, 1, ValueTypeExternref,
+				OpcodeDrop,
+				OpcodeEnd,
+			},
+			flag:        FeatureReferenceTypes,
+			expectedErr: `type mismatch on 2nd typed_select operand: type mismatch: expected externref, but was i32`,
+		},
+		{
+			name: "typed_select (1st operand type mismatch)",
+			body: []byte{
+				OpcodeRefNull, RefTypeFuncref, OpcodeRefNull, RefTypeExternref, OpcodeI32Const, 0,
+				OpcodeTypedSelect, 1, ValueTypeExternref,
+				OpcodeDrop,
+				OpcodeEnd,
+			},
+			flag:        FeatureReferenceTypes,
+			expectedErr: `type mismatch on 1st typed_select operand: type mismatch: expected externref, but was funcref`,
+		},
+		{
+			name: "typed_select (condition type mismatch)",
+			body: []byte{
+				OpcodeRefNull, RefTypeExternref, OpcodeRefNull, RefTypeExternref, OpcodeI64Const, 0,
+				OpcodeTypedSelect, 1, ValueTypeExternref,
+				OpcodeDrop,
+				OpcodeEnd,
+			},
+			flag:        FeatureReferenceTypes,
+			expectedErr: `type mismatch on 3rd select operand: type mismatch: expected i32, but was i64`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -3190,3 +3337,114 @@ func TestModule_funcValidation_SIMD_error(t *testing.T) {
 		})
 	}
 }
+
+func TestModule_funcValidation_Atomic(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        []byte
+		flag        Features
+		expectedErr string
+	}{
+		{
+			name: "threads disabled",
+			body: []byte{
+				OpcodeI32Const, 0, OpcodeI32Const, 1,
+				OpcodeAtomicPrefix, OpcodeAtomicI32RmwAdd, 0x2, 0x0,
+				OpcodeDrop, OpcodeEnd,
+			},
+			flag:        Features20220419,
+			expectedErr: "i32.atomic.rmw.add invalid as feature \"threads\" is disabled",
+		},
+		{
+			name: "i32.atomic.rmw.add",
+			body: []byte{
+				OpcodeI32Const, 0, OpcodeI32Const, 1,
+				OpcodeAtomicPrefix, OpcodeAtomicI32RmwAdd, 0x2, 0x0,
+				OpcodeDrop, OpcodeEnd,
+			},
+			flag: FeatureThreads,
+		},
+		{
+			name: "i64.atomic.rmw32.cmpxchg_u",
+			body: []byte{
+				OpcodeI32Const, 0, OpcodeI64Const, 1, OpcodeI64Const, 2,
+				OpcodeAtomicPrefix, OpcodeAtomicI64Rmw32CmpxchgU, 0x2, 0x0,
+				OpcodeDrop, OpcodeEnd,
+			},
+			flag: FeatureThreads,
+		},
+		{
+			name: "i64.atomic.store8",
+			body: []byte{
+				OpcodeI32Const, 0, OpcodeI64Const, 1,
+				OpcodeAtomicPrefix, OpcodeAtomicI64Store8, 0x0, 0x0,
+				OpcodeEnd,
+			},
+			flag: FeatureThreads,
+		},
+		{
+			name: "atomic.fence",
+			body: []byte{
+				OpcodeAtomicPrefix, OpcodeAtomicFence, 0x0,
+				OpcodeEnd,
+			},
+			flag: FeatureThreads,
+		},
+		{
+			name: "atomic.fence reserved byte",
+			body: []byte{
+				OpcodeAtomicPrefix, OpcodeAtomicFence, 0x1,
+				OpcodeEnd,
+			},
+			flag:        FeatureThreads,
+			expectedErr: "atomic.fence reserved byte must be zero",
+		},
+		{
+			name: "alignment smaller than natural",
+			body: []byte{
+				OpcodeI32Const, 0,
+				OpcodeAtomicPrefix, OpcodeAtomicI32Load, 0x1, 0x0,
+				OpcodeDrop, OpcodeEnd,
+			},
+			flag:        FeatureThreads,
+			expectedErr: "invalid memory alignment",
+		},
+		{
+			name: "operand type mismatch",
+			body: []byte{
+				OpcodeI32Const, 0, OpcodeI32Const, 1,
+				OpcodeAtomicPrefix, OpcodeAtomicI64RmwAdd, 0x3, 0x0,
+				OpcodeDrop, OpcodeEnd,
+			},
+			flag:        FeatureThreads,
+			expectedErr: "cannot pop the operand for i64.atomic.rmw.add: type mismatch: expected i64, but was i32",
+		},
+		{
+			name: "memory.atomic.wait32",
+			body: []byte{
+				OpcodeI32Const, 0, OpcodeI32Const, 0, OpcodeI64Const, 0,
+				OpcodeAtomicPrefix, OpcodeAtomicMemoryWait32, 0x2, 0x0,
+				OpcodeDrop, OpcodeEnd,
+			},
+			flag:        FeatureThreads,
+			expectedErr: "memory.atomic.wait32 is not supported as there is no shared memory",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			m := &Module{
+				TypeSection:     []*FunctionType{v_v},
+				FunctionSection: []Index{0},
+				CodeSection:     []*Code{{Body: tc.body}},
+			}
+			err := m.validateFunction(tc.flag, 0, []Index{0}, nil, &Memory{}, nil, nil)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
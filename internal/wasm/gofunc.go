@@ -34,118 +34,120 @@ var moduleType = reflect.TypeOf((*api.Module)(nil)).Elem()
 var goContextType = reflect.TypeOf((*context.Context)(nil)).Elem()
 var errorType = reflect.TypeOf((*error)(nil)).Elem()
 
-// PopGoFuncParams pops the correct number of parameters off the stack into a parameter slice for use in CallGoFunc
-//
-// For example, if the host function F requires the (x1 uint32, x2 float32) parameters, and
-// the stack is [..., A, B], then the function is called as F(A, B) where A and B are interpreted
-// as uint32 and float32 respectively.
-func PopGoFuncParams(f *FunctionInstance, popParam func() uint64) []uint64 {
-	// First, determine how many values we need to pop
-	paramCount := f.GoFunc.Type().NumIn()
-	switch f.Kind {
-	case FunctionKindGoNoContext:
-	case FunctionKindGoContextModule:
-		paramCount -= 2
-	default:
-		paramCount--
-	}
-
-	return PopValues(paramCount, popParam)
-}
+// moduleKey is a context.Context Value key. Its associated value is the *CallContext calling a host function.
+type moduleKey struct{}
 
-// PopValues pops api.ValueType values from the stack and returns them in reverse order.
-//
-// Note: the popper intentionally doesn't return bool or error because the caller's stack depth is trusted.
-func PopValues(count int, popper func() uint64) []uint64 {
-	if count == 0 {
-		return nil
-	}
-	params := make([]uint64, count)
-	for i := count - 1; i >= 0; i-- {
-		params[i] = popper()
-	}
-	return params
+// ModuleFromContext returns the module calling the host function ctx was passed to, or nil if ctx wasn't passed to a
+// host function.
+func ModuleFromContext(ctx context.Context) *CallContext {
+	m, _ := ctx.Value(moduleKey{}).(*CallContext)
+	return m
 }
 
-// CallGoFunc executes the FunctionInstance.GoFunc by converting params to Go types. The results of the function call
-// are converted back to api.ValueType.
+// CallGoFunc executes the FunctionInstance.GoFunc by converting the params at the beginning of the stack to Go types.
+// The results of the function call are converted back to api.ValueType, and written to the beginning of the stack,
+// overwriting the params. The stack must be large enough to hold either.
 //
 // * callCtx is passed to the host function as a first argument.
 //
+// When the host function accepts a context.Context, callCtx is also added to it, so that helpers only passed ctx can
+// recover the module via ModuleFromContext. The caller's ctx is not modified, so the value is scoped to this call.
+//
 // Note: ctx must use the caller's memory, which might be different from the defining module on an imported function.
-func CallGoFunc(ctx context.Context, callCtx *CallContext, f *FunctionInstance, params []uint64) []uint64 {
+// Note: This doesn't allocate the params or results. Arguments of the reflective call are reused via a pool, so that
+// host functions called frequently, such as clocks, are cheap.
+func CallGoFunc(ctx context.Context, callCtx *CallContext, f *FunctionInstance, stack []uint64) {
+	ctx, end := beginHostCall(ctx)
+	if end != nil {
+		defer end()
+	}
+
 	tp := f.GoFunc.Type()
+	args := f.getGoFuncArgs(tp)
+	in := args.in
 
-	var in []reflect.Value
-	if tp.NumIn() != 0 {
-		in = make([]reflect.Value, tp.NumIn())
-
-		i := 0
-		switch f.Kind {
-		case FunctionKindGoContext:
-			in[0] = newContextVal(ctx)
-			i = 1
-		case FunctionKindGoModule:
-			in[0] = newModuleVal(callCtx)
-			i = 1
-		case FunctionKindGoContextModule:
-			in[0] = newContextVal(ctx)
-			in[1] = newModuleVal(callCtx)
-			i = 2
+	i := 0
+	switch f.Kind {
+	case FunctionKindGoContext, FunctionKindGoContextModule:
+		// Only add the module when it differs, to avoid allocating on nested calls from the same module.
+		if ModuleFromContext(ctx) != callCtx {
+			ctx = context.WithValue(ctx, moduleKey{}, callCtx)
 		}
+	}
 
-		for _, raw := range params {
-			val := reflect.New(tp.In(i)).Elem()
-			k := tp.In(i).Kind()
-			switch k {
-			case reflect.Float32:
-				val.SetFloat(float64(math.Float32frombits(uint32(raw))))
-			case reflect.Float64:
-				val.SetFloat(math.Float64frombits(raw))
-			case reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-				val.SetUint(raw)
-			case reflect.Int32, reflect.Int64:
-				val.SetInt(int64(raw))
-			default:
-				panic(fmt.Errorf("BUG: param[%d] has an invalid type: %v", i, k))
-			}
-			in[i] = val
-			i++
-		}
+	switch f.Kind {
+	case FunctionKindGoContext:
+		in[0].Set(reflect.ValueOf(ctx))
+		i = 1
+	case FunctionKindGoModule:
+		in[0].Set(reflect.ValueOf(callCtx))
+		i = 1
+	case FunctionKindGoContextModule:
+		in[0].Set(reflect.ValueOf(ctx))
+		in[1].Set(reflect.ValueOf(callCtx))
+		i = 2
 	}
 
-	// Execute the host function and push back the call result onto the stack.
-	var results []uint64
-	if tp.NumOut() > 0 {
-		results = make([]uint64, 0, tp.NumOut())
+	for p, raw := range stack[:len(in)-i] {
+		val := in[i+p]
+		switch k := val.Kind(); k {
+		case reflect.Float32:
+			val.SetFloat(float64(math.Float32frombits(uint32(raw))))
+		case reflect.Float64:
+			val.SetFloat(math.Float64frombits(raw))
+		case reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			val.SetUint(raw)
+		case reflect.Int32, reflect.Int64:
+			val.SetInt(int64(raw))
+		default:
+			panic(fmt.Errorf("BUG: param[%d] has an invalid type: %v", i+p, k))
+		}
 	}
-	for i, ret := range f.GoFunc.Call(in) {
+
+	// Execute the host function and write back the call result onto the stack.
+	results := f.GoFunc.Call(in)
+	f.putGoFuncArgs(args, i)
+	for i, ret := range results {
 		switch ret.Kind() {
 		case reflect.Float32:
-			results = append(results, uint64(math.Float32bits(float32(ret.Float()))))
+			stack[i] = uint64(math.Float32bits(float32(ret.Float())))
 		case reflect.Float64:
-			results = append(results, math.Float64bits(ret.Float()))
+			stack[i] = math.Float64bits(ret.Float())
 		case reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-			results = append(results, ret.Uint())
+			stack[i] = ret.Uint()
 		case reflect.Int32, reflect.Int64:
-			results = append(results, uint64(ret.Int()))
+			stack[i] = uint64(ret.Int())
 		default:
 			panic(fmt.Errorf("BUG: result[%d] has an invalid type: %v", i, ret.Kind()))
 		}
 	}
-	return results
 }
 
-func newContextVal(ctx context.Context) reflect.Value {
-	val := reflect.New(goContextType).Elem()
-	val.Set(reflect.ValueOf(ctx))
-	return val
+// goFuncArgs are the arguments of a reflective call to FunctionInstance.GoFunc. Each is addressable, so that it can be
+// set from the stack without allocating.
+type goFuncArgs struct {
+	in []reflect.Value
+}
+
+// getGoFuncArgs returns arguments of the signature tp from the pool of this function, or allocates them if it is empty.
+func (f *FunctionInstance) getGoFuncArgs(tp reflect.Type) *goFuncArgs {
+	if args, ok := f.goFuncArgs.Get().(*goFuncArgs); ok {
+		return args
+	}
+	in := make([]reflect.Value, tp.NumIn())
+	for i := range in {
+		in[i] = reflect.New(tp.In(i)).Elem()
+	}
+	return &goFuncArgs{in: in}
 }
 
-func newModuleVal(m api.Module) reflect.Value {
-	val := reflect.New(moduleType).Elem()
-	val.Set(reflect.ValueOf(m))
-	return val
+// putGoFuncArgs returns the arguments to the pool of this function after clearing the first contextCount ones, so that
+// the pool doesn't keep a context.Context or api.Module alive.
+func (f *FunctionInstance) putGoFuncArgs(args *goFuncArgs, contextCount int) {
+	for _, val := range args.in[:contextCount] {
+		val.Set(reflect.Zero(val.Type()))
+	}
+	f.goFuncArgs.Put(args)
 }
 
 // getFunctionType returns the function type corresponding to the function signature or errs if invalid.
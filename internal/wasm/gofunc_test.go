@@ -155,114 +155,6 @@ func TestGetFunctionTypeErrors(t *testing.T) {
 	}
 }
 
-// stack simulates the value stack in a way easy to be tested.
-type stack struct {
-	vals []uint64
-}
-
-func (s *stack) pop() (result uint64) {
-	stackTopIndex := len(s.vals) - 1
-	result = s.vals[stackTopIndex]
-	s.vals = s.vals[:stackTopIndex]
-	return
-}
-
-func TestPopValues(t *testing.T) {
-	stackVals := []uint64{1, 2, 3, 4, 5, 6, 7}
-	var tests = []struct {
-		name     string
-		count    int
-		expected []uint64
-	}{
-		{
-			name: "pop zero doesn't allocate a slice ",
-		},
-		{
-			name:     "pop 1",
-			count:    1,
-			expected: []uint64{7},
-		},
-		{
-			name:     "pop 2",
-			count:    2,
-			expected: []uint64{6, 7},
-		},
-		{
-			name:     "pop 3",
-			count:    3,
-			expected: []uint64{5, 6, 7},
-		},
-	}
-
-	for _, tt := range tests {
-		tc := tt
-
-		t.Run(tc.name, func(t *testing.T) {
-			vals := PopValues(tc.count, (&stack{stackVals}).pop)
-			require.Equal(t, tc.expected, vals)
-		})
-	}
-}
-
-func TestPopGoFuncParams(t *testing.T) {
-	stackVals := []uint64{1, 2, 3, 4, 5, 6, 7}
-	var tests = []struct {
-		name      string
-		inputFunc interface{}
-		expected  []uint64
-	}{
-		{
-			name:      "nullary",
-			inputFunc: func() {},
-		},
-		{
-			name:      "wasm.Module",
-			inputFunc: func(api.Module) {},
-		},
-		{
-			name:      "context.Context",
-			inputFunc: func(context.Context) {},
-		},
-		{
-			name:      "context.Context and api.Module",
-			inputFunc: func(context.Context, api.Module) {},
-		},
-		{
-			name:      "all supported params",
-			inputFunc: func(uint32, uint64, float32, float64, uintptr) {},
-			expected:  []uint64{3, 4, 5, 6, 7},
-		},
-		{
-			name:      "all supported params - wasm.Module",
-			inputFunc: func(api.Module, uint32, uint64, float32, float64, uintptr) {},
-			expected:  []uint64{3, 4, 5, 6, 7},
-		},
-		{
-			name:      "all supported params - context.Context",
-			inputFunc: func(context.Context, uint32, uint64, float32, float64, uintptr) {},
-			expected:  []uint64{3, 4, 5, 6, 7},
-		},
-		{
-			name:      "all supported params - context.Context and api.Module",
-			inputFunc: func(context.Context, api.Module, uint32, uint64, float32, float64, uintptr) {},
-			expected:  []uint64{3, 4, 5, 6, 7},
-		},
-	}
-
-	for _, tt := range tests {
-		tc := tt
-
-		t.Run(tc.name, func(t *testing.T) {
-			goFunc := reflect.ValueOf(tc.inputFunc)
-			fk, _, err := getFunctionType(&goFunc, Features20220419)
-			require.NoError(t, err)
-
-			vals := PopGoFuncParams(&FunctionInstance{Kind: fk, GoFunc: &goFunc}, (&stack{stackVals}).pop)
-			require.Equal(t, tc.expected, vals)
-		})
-	}
-}
-
 func TestCallGoFunc(t *testing.T) {
 	tPtr := uintptr(unsafe.Pointer(t))
 	callCtx := &CallContext{}
@@ -286,13 +178,15 @@ func TestCallGoFunc(t *testing.T) {
 		{
 			name: "context.Context void return",
 			inputFunc: func(ctx context.Context) {
-				require.Equal(t, testCtx, ctx)
+				require.Equal(t, "arbitrary", ctx.Value(struct{}{}))
+				require.Equal(t, callCtx, ModuleFromContext(ctx))
 			},
 		},
 		{
 			name: "context.Context and api.Module void return",
 			inputFunc: func(ctx context.Context, m api.Module) {
-				require.Equal(t, testCtx, ctx)
+				require.Equal(t, "arbitrary", ctx.Value(struct{}{}))
+				require.Equal(t, callCtx, ModuleFromContext(ctx))
 				require.Equal(t, callCtx, m)
 			},
 		},
@@ -363,7 +257,7 @@ func TestCallGoFunc(t *testing.T) {
 		{
 			name: "all supported params and i32 result - context.Context",
 			inputFunc: func(ctx context.Context, v uintptr, w uint32, x uint64, y float32, z float64) uint32 {
-				require.Equal(t, testCtx, ctx)
+				require.Equal(t, "arbitrary", ctx.Value(struct{}{}))
 				require.Equal(t, tPtr, v)
 				require.Equal(t, uint32(math.MaxUint32), w)
 				require.Equal(t, uint64(math.MaxUint64), x)
@@ -383,7 +277,7 @@ func TestCallGoFunc(t *testing.T) {
 		{
 			name: "all supported params and i32 result - context.Context and api.Module",
 			inputFunc: func(ctx context.Context, m api.Module, v uintptr, w uint32, x uint64, y float32, z float64) uint32 {
-				require.Equal(t, testCtx, ctx)
+				require.Equal(t, "arbitrary", ctx.Value(struct{}{}))
 				require.Equal(t, callCtx, m)
 				require.Equal(t, tPtr, v)
 				require.Equal(t, uint32(math.MaxUint32), w)
@@ -410,8 +304,73 @@ func TestCallGoFunc(t *testing.T) {
 			fk, _, err := getFunctionType(&goFunc, Features20220419)
 			require.NoError(t, err)
 
-			results := CallGoFunc(testCtx, callCtx, &FunctionInstance{Kind: fk, GoFunc: &goFunc}, tc.inputParams)
-			require.Equal(t, tc.expectedResults, results)
+			f := &FunctionInstance{Kind: fk, GoFunc: &goFunc}
+
+			// Call more than once, to ensure pooled arguments are reset.
+			for i := 0; i < 2; i++ {
+				stack := make([]uint64, len(tc.inputParams)+len(tc.expectedResults))
+				copy(stack, tc.inputParams)
+
+				CallGoFunc(testCtx, callCtx, f, stack)
+				results := append([]uint64(nil), stack[:len(tc.expectedResults)]...) // nil when void.
+				require.Equal(t, tc.expectedResults, results)
+			}
 		})
 	}
 }
+
+func TestCallGoFunc_Allocations(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector randomly drops sync.Pool items, so allocations vary")
+	}
+
+	goFunc := reflect.ValueOf(func(ctx context.Context, m api.Module, x, y uint32) uint32 { return x + y })
+	fk, _, err := getFunctionType(&goFunc, Features20220419)
+	require.NoError(t, err)
+	f := &FunctionInstance{Kind: fk, GoFunc: &goFunc}
+	callCtx := &CallContext{}
+
+	stack := make([]uint64, 2)
+	CallGoFunc(testCtx, callCtx, f, stack) // Fill the pool.
+	allocs := testing.AllocsPerRun(100, func() {
+		stack[0], stack[1] = 1, 2
+		CallGoFunc(testCtx, callCtx, f, stack)
+	})
+	require.Equal(t, uint64(3), stack[0])
+
+	// Only reflect.Value Call allocates, for its results, and adding the module to the context.
+	require.True(t, allocs <= 3, "expected at most 3 allocations, but was %v", allocs)
+
+	// A context that already carries the module isn't wrapped again, ex. on a nested call from the same module.
+	moduleCtx := context.WithValue(testCtx, moduleKey{}, callCtx)
+	allocs = testing.AllocsPerRun(100, func() {
+		CallGoFunc(moduleCtx, callCtx, f, stack)
+	})
+	require.True(t, allocs <= 2, "expected at most 2 allocations, but was %v", allocs)
+}
+
+func TestModuleFromContext(t *testing.T) {
+	require.Nil(t, ModuleFromContext(testCtx))
+
+	callCtx := &CallContext{}
+	var calls []*CallContext
+	helper := func(ctx context.Context) { // a helper that isn't passed the module
+		calls = append(calls, ModuleFromContext(ctx))
+	}
+
+	goFunc := reflect.ValueOf(func(ctx context.Context) { helper(ctx) })
+	fk, _, err := getFunctionType(&goFunc, Features20220419)
+	require.NoError(t, err)
+	f := &FunctionInstance{Kind: fk, GoFunc: &goFunc}
+
+	CallGoFunc(testCtx, callCtx, f, nil)
+	require.Equal(t, []*CallContext{callCtx}, calls)
+
+	// The module is scoped to the call, so the caller's context is unchanged.
+	require.Nil(t, ModuleFromContext(testCtx))
+
+	// A nested call from a different module sees that module.
+	other := &CallContext{}
+	CallGoFunc(context.WithValue(testCtx, moduleKey{}, callCtx), other, f, nil)
+	require.Equal(t, []*CallContext{callCtx, other}, calls)
+}
@@ -0,0 +1,34 @@
+package wasm
+
+import (
+	"errors"
+
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// stderrBegin returns how much the module wrote to stderr so far, so that withGuestStderr can attach what it writes
+// after to an error.
+func (m *CallContext) stderrBegin() (mark internalsys.StderrMark) {
+	if m.Sys != nil { // ex. nil if from ModuleBuilder
+		mark = m.Sys.StderrBegin()
+	}
+	return
+}
+
+// withGuestStderr ends the call begun at mark, returning err as a sys.GuestStderrError if the module wrote to stderr
+// since, ex. a panic message before a trap. A nil error or a sys.ExitError is returned as-is.
+func (m *CallContext) withGuestStderr(mark internalsys.StderrMark, err error) error {
+	if m.Sys == nil {
+		return err
+	} else if err == nil {
+		m.Sys.StderrEnd(mark, false)
+		return nil
+	} else if exitErr := (*sys.ExitError)(nil); errors.As(err, &exitErr) {
+		m.Sys.StderrEnd(mark, false)
+		return err
+	} else if stderr := m.Sys.StderrEnd(mark, true); stderr != nil {
+		return sys.NewGuestStderrError(err, stderr)
+	}
+	return err
+}
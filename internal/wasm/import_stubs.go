@@ -0,0 +1,114 @@
+package wasm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// instantiateImportStubs returns functions which panic with api.ErrUnimplementedImport, keyed by the index in the
+// ImportSection of each function import that the modules given don't export, and the module defining them. This returns
+// nil if there are none.
+//
+// Note: The caller must delete the compiled code of the returned module from the Engine when the importing module is
+// closed, or when instantiating it failed. Otherwise, it is retained until the Engine is, as each distinct set of stubbed
+// imports has its own ID.
+// Note: Imports with a signature not supported by host functions, ex. v128 params, aren't stubbed. Hence, resolving
+// them fails as if they weren't stubbed.
+func (s *Store) instantiateImportStubs(
+	ctx context.Context,
+	ns *Namespace,
+	module *Module,
+	modules map[string]*ModuleInstance,
+) (map[int]*FunctionInstance, *Module, error) {
+	nameToGoFunc := map[string]interface{}{}
+	var id strings.Builder // The ID of the stubs must change when any import they stub does.
+	for idx, i := range module.ImportSection {
+		if i.Type != ExternTypeFunc || int(i.DescFunc) >= len(module.TypeSection) {
+			continue
+		}
+		if m, ok := modules[i.Module]; ok {
+			if _, ok = m.Exports[i.Name]; ok {
+				continue // The import exists, so isn't stubbed, even if its type is wrong.
+			}
+		}
+
+		ft := module.TypeSection[i.DescFunc]
+		if fn, ok := newImportStub(i.Module, i.Name, ft); ok {
+			name := strconv.Itoa(idx)
+			nameToGoFunc[name] = fn
+			fmt.Fprintf(&id, "%s:%s.%s:%s\n", name, i.Module, i.Name, ft)
+		}
+	}
+	if len(nameToGoFunc) == 0 {
+		return nil, nil, nil
+	}
+
+	stubs, err := NewHostModule("", nameToGoFunc, nil, nil, s.EnabledFeatures)
+	if err != nil {
+		return nil, nil, err
+	}
+	stubs.AssignModuleID([]byte(id.String()))
+
+	if err = s.Engine.CompileModule(ctx, stubs); err != nil {
+		return nil, nil, err
+	}
+	// The stubs aren't added to the namespace, as they are only visible to the module that imports them.
+	callCtx, err := s.instantiate(ctx, ns, stubs, "", nil, nil, nil)
+	if err != nil {
+		s.Engine.DeleteCompiledModule(stubs)
+		return nil, nil, err
+	}
+
+	ret := make(map[int]*FunctionInstance, len(nameToGoFunc))
+	for name, exp := range callCtx.module.Exports {
+		idx, _ := strconv.Atoi(name)
+		ret[idx] = exp.Function
+	}
+	return ret, stubs, nil
+}
+
+// newImportStub returns a host function with the signature of the function type, which panics with
+// api.ErrUnimplementedImport. This returns false if the type has a value type not supported by host functions.
+func newImportStub(moduleName, name string, ft *FunctionType) (interface{}, bool) {
+	in, ok := goTypesOf(ft.Params)
+	if !ok {
+		return nil, false
+	}
+	out, ok := goTypesOf(ft.Results)
+	if !ok {
+		return nil, false
+	}
+
+	err := &api.ErrUnimplementedImport{Module: moduleName, Name: name}
+	fn := reflect.MakeFunc(reflect.FuncOf(in, out, false), func([]reflect.Value) []reflect.Value {
+		panic(err)
+	})
+	return fn.Interface(), true
+}
+
+// goTypesOf returns the types of host function parameters or results that correspond to the value types.
+func goTypesOf(valueTypes []ValueType) ([]reflect.Type, bool) {
+	ret := make([]reflect.Type, len(valueTypes))
+	for i, vt := range valueTypes {
+		switch vt {
+		case ValueTypeI32:
+			ret[i] = reflect.TypeOf(uint32(0))
+		case ValueTypeI64:
+			ret[i] = reflect.TypeOf(uint64(0))
+		case ValueTypeF32:
+			ret[i] = reflect.TypeOf(float32(0))
+		case ValueTypeF64:
+			ret[i] = reflect.TypeOf(float64(0))
+		case ValueTypeExternref:
+			ret[i] = reflect.TypeOf(uintptr(0))
+		default:
+			return nil, false
+		}
+	}
+	return ret, true
+}
@@ -0,0 +1,61 @@
+//go:build inject_trap
+
+package wasm
+
+import (
+	"sync"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/wasmdebug"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+)
+
+// InjectTrapEnabled is true when built with the tag "inject_trap", so engines check for traps injected by
+// CallContext.InjectTrap when the guest calls a function.
+const InjectTrapEnabled = true
+
+// injectedTraps are the traps injected by CallContext.InjectTrap, keyed by the function they fail the next call of.
+//
+// Note: This isn't a field of FunctionInstance, so that builds without the "inject_trap" tag have no cost.
+var injectedTraps = struct {
+	sync.Mutex
+	m map[*FunctionInstance]*wasmruntime.Error
+}{m: map[*FunctionInstance]*wasmruntime.Error{}}
+
+// InjectTrap makes the next call of the function exported under the given name fail with a trap of the given reason,
+// instead of calling it. This includes calls from the guest, which engines check with TakeInjectedTrap.
+//
+// Note: This is only for testing how the host handles traps, so only exists with the build tag "inject_trap".
+func (m *CallContext) InjectTrap(exportName string, reason api.TrapReason) error {
+	exp, err := m.module.getExport(exportName, ExternTypeFunc)
+	if err != nil {
+		return err
+	}
+	injectedTraps.Lock()
+	defer injectedTraps.Unlock()
+	injectedTraps.m[exp.Function] = wasmruntime.New(reason)
+	return nil
+}
+
+// TakeInjectedTrap clears and returns any trap injected by CallContext.InjectTrap. Engines panic with it when the
+// guest calls the function, so that the stack trace includes the caller.
+func (f *FunctionInstance) TakeInjectedTrap() *wasmruntime.Error {
+	injectedTraps.Lock()
+	defer injectedTraps.Unlock()
+	trap := injectedTraps.m[f]
+	delete(injectedTraps.m, f)
+	return trap
+}
+
+// takeInjectedTrap is like TakeInjectedTrap, except the trap is formatted as if the function trapped, for calls via
+// api.Function.
+func (f *FunctionInstance) takeInjectedTrap() error {
+	trap := f.TakeInjectedTrap()
+	if trap == nil {
+		return nil
+	}
+
+	builder := wasmdebug.NewErrorBuilder()
+	builder.AddFrame(f.DebugName, f.ParamTypes(), f.ResultTypes())
+	return builder.FromRecovered(trap)
+}
@@ -0,0 +1,18 @@
+//go:build !inject_trap
+
+package wasm
+
+import "github.com/tetratelabs/wazero/internal/wasmruntime"
+
+// InjectTrapEnabled is false, as CallContext.InjectTrap only exists with the build tag "inject_trap".
+const InjectTrapEnabled = false
+
+// TakeInjectedTrap returns nil, as CallContext.InjectTrap only exists with the build tag "inject_trap".
+func (f *FunctionInstance) TakeInjectedTrap() *wasmruntime.Error {
+	return nil
+}
+
+// takeInjectedTrap returns nil, as CallContext.InjectTrap only exists with the build tag "inject_trap".
+func (f *FunctionInstance) takeInjectedTrap() error {
+	return nil
+}
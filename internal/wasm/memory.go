@@ -4,9 +4,11 @@ import (
 	"context"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"math"
 	"reflect"
 	"sync"
+	"sync/atomic"
 	"unsafe"
 
 	"github.com/tetratelabs/wazero/api"
@@ -44,8 +46,12 @@ var _ api.Memory = &MemoryInstance{}
 type MemoryInstance struct {
 	Buffer        []byte
 	Min, Cap, Max uint32
+	// GrowHook is consulted by Grow before growing, when set. If it returns false, Grow fails as if Max was exceeded.
+	GrowHook func(ctx context.Context, currentPages, delta uint32) (allow bool)
 	// mux is used to prevent overlapping calls to Grow.
 	mux sync.RWMutex
+	// leases is the count of slices from ReadSlice that are not yet released. Grow moves Buffer when non-zero.
+	leases int32
 }
 
 // NewMemoryInstance creates a new instance based on the parameters in the SectionIDMemory.
@@ -67,6 +73,11 @@ func (m *MemoryInstance) Size(_ context.Context) uint32 {
 	return m.size()
 }
 
+// Pages implements the same method as documented on api.Memory.
+func (m *MemoryInstance) Pages(ctx context.Context) uint32 {
+	return m.PageSize(ctx)
+}
+
 // ReadByte implements the same method as documented on api.Memory.
 func (m *MemoryInstance) ReadByte(_ context.Context, offset uint32) (byte, bool) {
 	// Note: If you use the context.Context param, don't forget to coerce nil to context.Background()!
@@ -133,6 +144,23 @@ func (m *MemoryInstance) Read(_ context.Context, offset, byteCount uint32) ([]by
 	return m.Buffer[offset : offset+byteCount : offset+byteCount], true
 }
 
+// ReadSlice implements the same method as documented on api.Memory.
+func (m *MemoryInstance) ReadSlice(_ context.Context, offset, byteCount uint32) ([]byte, func(), bool) {
+	// Note: If you use the context.Context param, don't forget to coerce nil to context.Background()!
+
+	if !m.hasSize(offset, byteCount) {
+		return nil, nil, false
+	}
+	atomic.AddInt32(&m.leases, 1)
+	var released uint32
+	release := func() {
+		if atomic.CompareAndSwapUint32(&released, 0, 1) {
+			atomic.AddInt32(&m.leases, -1)
+		}
+	}
+	return m.Buffer[offset : offset+byteCount : offset+byteCount], release, true
+}
+
 // WriteByte implements the same method as documented on api.Memory.
 func (m *MemoryInstance) WriteByte(_ context.Context, offset uint32, v byte) bool {
 	// Note: If you use the context.Context param, don't forget to coerce nil to context.Background()!
@@ -193,14 +221,132 @@ func (m *MemoryInstance) Write(_ context.Context, offset uint32, val []byte) boo
 	return true
 }
 
+// ReaderAt implements the same method as documented on api.Memory.
+func (m *MemoryInstance) ReaderAt(_ context.Context, offset, byteCount uint32) (io.Reader, bool) {
+	// Note: If you use the context.Context param, don't forget to coerce nil to context.Background()!
+
+	if !m.hasSize(offset, byteCount) {
+		return nil, false
+	}
+	return &memoryRegion{m: m, offset: offset, limit: offset + byteCount}, true
+}
+
+// WriterAt implements the same method as documented on api.Memory.
+func (m *MemoryInstance) WriterAt(_ context.Context, offset, byteCount uint32) (io.Writer, bool) {
+	// Note: If you use the context.Context param, don't forget to coerce nil to context.Background()!
+
+	if !m.hasSize(offset, byteCount) {
+		return nil, false
+	}
+	return &memoryRegion{m: m, offset: offset, limit: offset + byteCount}, true
+}
+
+// memoryRegion implements the results of MemoryInstance.ReaderAt and WriterAt.
+//
+// Note: This slices Buffer on each call, as opposed to once, so that it remains valid after memory grows. Memory can
+// also shrink, ex. when ModuleInstance.Reset restores its minimum size, so the region ends early if it no longer fits.
+type memoryRegion struct {
+	m *MemoryInstance
+	// offset is the position of the next read or write, and advances towards limit.
+	offset, limit uint32
+}
+
+// remaining returns the rest of the region, clamped to the current size of the memory. Slicing up to limit isn't
+// enough, as after memory shrinks, limit can be past the length of Buffer, but within its capacity, which would expose
+// the zeroed tail that a later Grow reuses.
+func (r *memoryRegion) remaining() []byte {
+	limit := r.limit
+	if size := uint32(len(r.m.Buffer)); limit > size {
+		limit = size
+	}
+	if r.offset >= limit {
+		return nil
+	}
+	return r.m.Buffer[r.offset:limit]
+}
+
+// Read implements io.Reader
+func (r *memoryRegion) Read(p []byte) (n int, err error) {
+	b := r.remaining()
+	if len(b) == 0 {
+		return 0, io.EOF
+	}
+	n = copy(p, b)
+	r.offset += uint32(n)
+	return
+}
+
+// WriteTo implements io.WriterTo, which allows io.Copy to write directly from memory.
+func (r *memoryRegion) WriteTo(w io.Writer) (n int64, err error) {
+	written, err := w.Write(r.remaining())
+	r.offset += uint32(written)
+	return int64(written), err
+}
+
+// Write implements io.Writer
+func (r *memoryRegion) Write(p []byte) (n int, err error) {
+	n = copy(r.remaining(), p)
+	r.offset += uint32(n)
+	if n < len(p) {
+		err = io.ErrShortWrite
+	}
+	return
+}
+
+// ReadFrom implements io.ReaderFrom, which allows io.Copy to read directly into memory. This returns
+// io.ErrShortWrite if src has more data than fits in the region.
+func (r *memoryRegion) ReadFrom(src io.Reader) (n int64, err error) {
+	for b := r.remaining(); len(b) > 0; b = r.remaining() {
+		var read int
+		read, err = src.Read(b)
+		r.offset += uint32(read)
+		n += int64(read)
+		if err == io.EOF {
+			return n, nil
+		} else if err != nil {
+			return
+		}
+	}
+
+	// The region is full, or memory shrank, so check if there's anything left to copy.
+	var b [1]byte
+	for {
+		var read int
+		read, err = src.Read(b[:])
+		if read > 0 {
+			return n, io.ErrShortWrite
+		} else if err == io.EOF {
+			return n, nil
+		} else if err != nil {
+			return
+		}
+	}
+}
+
 // MemoryPagesToBytesNum converts the given pages into the number of bytes contained in these pages.
 func MemoryPagesToBytesNum(pages uint32) (bytesNum uint64) {
 	return uint64(pages) << MemoryPageSizeInBits
 }
 
+// resize reslices the memory to byteLen, which must be within its capacity. The caller must hold the write lock.
+//
+// Note: When shrinking, the bytes past byteLen are zeroed, as the capacity is kept and a later Grow would otherwise
+// expose them again.
+func (m *MemoryInstance) resize(byteLen uint64) {
+	if byteLen < uint64(len(m.Buffer)) {
+		tail := m.Buffer[byteLen:]
+		for i := range tail {
+			tail[i] = 0
+		}
+	}
+	m.Buffer = m.Buffer[:byteLen]
+}
+
 // Grow implements the same method as documented on api.Memory.
-func (m *MemoryInstance) Grow(_ context.Context, delta uint32) (result uint32, ok bool) {
-	// Note: If you use the context.Context param, don't forget to coerce nil to context.Background()!
+func (m *MemoryInstance) Grow(ctx context.Context, delta uint32) (result uint32, ok bool) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
 
 	// We take write-lock here as the following might result in a new slice
 	m.mux.Lock()
@@ -215,6 +361,16 @@ func (m *MemoryInstance) Grow(_ context.Context, delta uint32) (result uint32, o
 	newPages := currentPages + delta
 	if newPages > m.Max {
 		return 0, false
+	} else if m.GrowHook != nil && !m.GrowHook(ctx, currentPages, delta) {
+		return 0, false
+	} else if atomic.LoadInt32(&m.leases) > 0 { // Move the buffer, so that leased slices don't alias it anymore.
+		if newPages > m.Cap {
+			m.Cap = newPages
+		}
+		buf := make([]byte, MemoryPagesToBytesNum(newPages), MemoryPagesToBytesNum(m.Cap))
+		copy(buf, m.Buffer)
+		m.Buffer = buf
+		return currentPages, true
 	} else if newPages > m.Cap { // grow the memory.
 		m.Buffer = append(m.Buffer, make([]byte, MemoryPagesToBytesNum(delta))...)
 		m.Cap = newPages
@@ -261,12 +417,13 @@ func memoryBytesNumToPages(bytesNum uint64) (pages uint32) {
 
 // size returns the size in bytes of the buffer.
 func (m *MemoryInstance) size() uint32 {
-	return uint32(len(m.Buffer)) // We don't lock here because size can't become smaller.
+	return uint32(len(m.Buffer)) // We don't lock here because size only becomes smaller on reset, when not in use.
 }
 
 // hasSize returns true if Len is sufficient for byteCount at the given offset.
 //
-// Note: This is always fine, because memory can grow, but never shrink.
+// Note: The result remains true while memory grows, but not after it shrinks, which only happens when the module is
+// reset, ex. ModuleInstance.Reset or Snapshot.Restore. Hence, don't retain it across a reset.
 func (m *MemoryInstance) hasSize(offset uint32, byteCount uint32) bool {
 	return uint64(offset)+uint64(byteCount) <= uint64(len(m.Buffer)) // uint64 prevents overflow on add
 }
@@ -1,9 +1,13 @@
 package wasm
 
 import (
+	"bytes"
 	"context"
+	"io"
 	"math"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/tetratelabs/wazero/internal/testing/require"
 )
@@ -96,6 +100,8 @@ func TestMemoryInstance_Grow_Size(t *testing.T) {
 
 			// Ensure that the current page size equals the max.
 			require.Equal(t, max, m.PageSize(ctx))
+			require.Equal(t, max, m.Pages(ctx))
+			require.Equal(t, uint32(maxBytes), m.Size(ctx))
 
 			if tc.capEqualsMax { // Ensure the capacity isn't more than max.
 				require.Equal(t, maxBytes, uint64(cap(m.Buffer)))
@@ -495,6 +501,88 @@ func TestMemoryInstance_Read(t *testing.T) {
 	}
 }
 
+func TestMemoryInstance_ReadSlice(t *testing.T) {
+	for _, ctx := range []context.Context{nil, testCtx} { // Ensure it doesn't crash on nil!
+		mem := &MemoryInstance{Buffer: make([]byte, MemoryPagesToBytesNum(16)), Min: 16, Cap: 16, Max: 16}
+		mem.Buffer[1] = 1
+
+		// Read a large region without copying it.
+		buf, release, ok := mem.ReadSlice(ctx, 1, uint32(len(mem.Buffer))-1)
+		require.True(t, ok)
+		require.Equal(t, len(mem.Buffer)-1, len(buf))
+		require.Equal(t, &mem.Buffer[1], &buf[0])
+		require.Equal(t, byte(1), buf[0])
+
+		// Test write-through
+		buf[1] = 2
+		require.Equal(t, byte(2), mem.Buffer[2])
+
+		release()
+		release() // idempotent
+		require.Zero(t, mem.leases)
+
+		_, _, ok = mem.ReadSlice(ctx, 1, uint32(len(mem.Buffer)))
+		require.False(t, ok)
+		require.Zero(t, mem.leases)
+	}
+}
+
+func TestMemoryInstance_ReadSlice_Grow(t *testing.T) {
+	tests := []struct {
+		name string
+		mem  *MemoryInstance
+	}{
+		{
+			name: "within capacity",
+			mem:  &MemoryInstance{Buffer: make([]byte, MemoryPageSize, MemoryPagesToBytesNum(2)), Min: 1, Cap: 2, Max: 2},
+		},
+		{
+			name: "beyond capacity",
+			mem:  &MemoryInstance{Buffer: make([]byte, MemoryPageSize), Min: 1, Cap: 1, Max: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			mem := tc.mem
+			mem.Buffer[0] = 1
+
+			buf, release, ok := mem.ReadSlice(testCtx, 0, 2)
+			require.True(t, ok)
+			defer release()
+
+			_, ok = mem.Grow(testCtx, 1)
+			require.True(t, ok)
+			require.Equal(t, 2*MemoryPageSize, mem.Size(testCtx))
+			require.Equal(t, byte(1), mem.Buffer[0]) // contents are retained
+			require.Equal(t, uint32(2), mem.Cap)
+
+			// Writes are no longer shared in either direction.
+			buf[0] = 2
+			mem.Buffer[1] = 3
+			require.Equal(t, []byte{1, 3}, mem.Buffer[0:2])
+			require.Equal(t, []byte{2, 0}, buf)
+		})
+	}
+
+	t.Run("grows in place after release", func(t *testing.T) {
+		mem := &MemoryInstance{Buffer: make([]byte, MemoryPageSize, MemoryPagesToBytesNum(2)), Min: 1, Cap: 2, Max: 2}
+
+		_, release, ok := mem.ReadSlice(testCtx, 0, 2)
+		require.True(t, ok)
+		release()
+
+		buf, ok := mem.Read(testCtx, 0, 2)
+		require.True(t, ok)
+
+		_, ok = mem.Grow(testCtx, 1)
+		require.True(t, ok)
+		require.Equal(t, &mem.Buffer[0], &buf[0])
+	})
+}
+
 func TestMemoryInstance_WriteUint16Le(t *testing.T) {
 	memory := &MemoryInstance{Buffer: make([]byte, 100)}
 
@@ -778,3 +866,123 @@ func TestMemoryInstance_Write(t *testing.T) {
 		require.False(t, ok)
 	}
 }
+
+func TestMemoryInstance_ReaderAt(t *testing.T) {
+	for _, ctx := range []context.Context{nil, testCtx} { // Ensure it doesn't crash on nil!
+		mem := &MemoryInstance{Buffer: []byte("?wazero?"), Min: 1, Cap: 1, Max: 2}
+
+		r, ok := mem.ReaderAt(ctx, 1, 6)
+		require.True(t, ok)
+		require.NoError(t, iotest.TestReader(r, []byte("wazero")))
+
+		// io.Copy uses io.WriterTo to avoid an intermediate buffer.
+		r, ok = mem.ReaderAt(ctx, 1, 6)
+		require.True(t, ok)
+		var out bytes.Buffer
+		n, err := io.Copy(&out, r)
+		require.NoError(t, err)
+		require.Equal(t, int64(6), n)
+		require.Equal(t, "wazero", out.String())
+
+		// Reading is still possible after the memory grows.
+		r, ok = mem.ReaderAt(ctx, 1, 6)
+		require.True(t, ok)
+		_, ok = mem.Grow(ctx, 1)
+		require.True(t, ok)
+		mem.Buffer[1] = 'W' // write to the grown buffer
+		b, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, "Wazero", string(b))
+
+		_, ok = mem.ReaderAt(ctx, mem.size()-5, 6)
+		require.False(t, ok)
+
+		_, ok = mem.ReaderAt(ctx, mem.size()+1, 0)
+		require.False(t, ok)
+	}
+}
+
+func TestMemoryInstance_WriterAt(t *testing.T) {
+	for _, ctx := range []context.Context{nil, testCtx} { // Ensure it doesn't crash on nil!
+		mem := &MemoryInstance{Buffer: []byte("????????"), Min: 1}
+
+		t.Run("Write", func(t *testing.T) {
+			copy(mem.Buffer, "????????")
+			w, ok := mem.WriterAt(ctx, 1, 6)
+			require.True(t, ok)
+
+			n, err := w.Write([]byte("waz"))
+			require.NoError(t, err)
+			require.Equal(t, 3, n)
+
+			// A write past the region is short.
+			n, err = w.Write([]byte("ero!"))
+			require.Equal(t, io.ErrShortWrite, err)
+			require.Equal(t, 3, n)
+			require.Equal(t, "?wazero?", string(mem.Buffer))
+
+			n, err = w.Write([]byte("!"))
+			require.Equal(t, io.ErrShortWrite, err)
+			require.Zero(t, n)
+		})
+
+		// io.Copy uses io.ReaderFrom to avoid an intermediate buffer.
+		t.Run("io.Copy", func(t *testing.T) {
+			copy(mem.Buffer, "????????")
+			w, ok := mem.WriterAt(ctx, 1, 6)
+			require.True(t, ok)
+
+			n, err := io.Copy(w, iotest.OneByteReader(strings.NewReader("wazero")))
+			require.NoError(t, err)
+			require.Equal(t, int64(6), n)
+			require.Equal(t, "?wazero?", string(mem.Buffer))
+		})
+
+		t.Run("io.Copy past the region", func(t *testing.T) {
+			copy(mem.Buffer, "????????")
+			w, ok := mem.WriterAt(ctx, 1, 6)
+			require.True(t, ok)
+
+			n, err := io.Copy(w, strings.NewReader("wazero!"))
+			require.Equal(t, io.ErrShortWrite, err)
+			require.Equal(t, int64(6), n)
+			require.Equal(t, "?wazero?", string(mem.Buffer))
+		})
+
+		_, ok := mem.WriterAt(ctx, 3, 6)
+		require.False(t, ok)
+
+		_, ok = mem.WriterAt(ctx, 9, 0)
+		require.False(t, ok)
+	}
+}
+
+// TestMemoryInstance_ReaderAt_WriterAt_Shrink ensures regions end early after memory shrinks, ex. on reset, as
+// opposed to accessing the zeroed tail that a later Grow exposes.
+func TestMemoryInstance_ReaderAt_WriterAt_Shrink(t *testing.T) {
+	mem := &MemoryInstance{Buffer: []byte("????????wazero!!"), Min: 1}
+	r, ok := mem.ReaderAt(testCtx, 4, 8)
+	require.True(t, ok)
+	w, ok := mem.WriterAt(testCtx, 4, 8)
+	require.True(t, ok)
+	w2, ok := mem.WriterAt(testCtx, 4, 8)
+	require.True(t, ok)
+
+	mem.resize(8)
+	tail := mem.Buffer[8:16]
+
+	b, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, "????", string(b))
+
+	n, err := w.Write([]byte("abcdefgh"))
+	require.Equal(t, io.ErrShortWrite, err)
+	require.Equal(t, 4, n)
+
+	copied, err := io.Copy(w2, strings.NewReader("abcdefgh"))
+	require.Equal(t, io.ErrShortWrite, err)
+	require.Equal(t, int64(4), copied)
+
+	require.Equal(t, "????abcd", string(mem.Buffer))
+	require.Equal(t, make([]byte, 8), tail)
+}
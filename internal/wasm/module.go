@@ -154,6 +154,13 @@ type Module struct {
 	// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#custom-section%E2%91%A0
 	NameSection *NameSection
 
+	// CustomSections are any SectionIDCustom besides "name", in the order they were decoded from the binary format.
+	//
+	// Note: These are retained for tooling, such as DWARF readers, and otherwise unused in wazero.
+	//
+	// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#custom-section%E2%91%A0
+	CustomSections []*CustomSection
+
 	// HostFunctionSection is index-correlated with FunctionSection and contains a host function defined in Go.
 	// When present, the CodeSection must be nil.
 	//
@@ -177,6 +184,22 @@ type Module struct {
 
 	// ID is the sha256 value of the source wasm and is used for caching.
 	ID ModuleID
+
+	// EnabledFeatures when non-zero are the features this module was validated with, which override those of the
+	// Engine and Store. Ex. wazero.CompileConfig WithFeatures sets this.
+	EnabledFeatures Features
+
+	// FastCompile when true skips optional compilation passes, trading the speed of the compiled code for compile
+	// time. Ex. wazero.CompileConfig WithOptimizationLevel sets this.
+	FastCompile bool
+
+	// DeterministicNaN when true canonicalizes the NaN results of floating-point arithmetic when compiled.
+	// Ex. wazero.RuntimeConfig WithDeterministicNaN sets this.
+	DeterministicNaN bool
+
+	// TrustedMemory when true omits the bounds checks of memory loads and stores in compiled code. This is unsafe: an
+	// out-of-bounds access reads or writes host memory. Ex. wazero.CompileConfig WithTrustedMemory sets this.
+	TrustedMemory bool
 }
 
 // ModuleID represents sha256 hash value uniquely assigned to Module.
@@ -190,6 +213,14 @@ const (
 	MaximumTableIndex    = uint32(1 << 27)
 )
 
+// FeaturesOr returns EnabledFeatures, or the given features if they weren't set.
+func (m *Module) FeaturesOr(features Features) Features {
+	if m.EnabledFeatures != 0 {
+		return m.EnabledFeatures
+	}
+	return features
+}
+
 // AssignModuleID calculates a sha256 checksum on `wasm` and set Module.ID to the result.
 func (m *Module) AssignModuleID(wasm []byte) {
 	m.ID = sha256.Sum256(wasm)
@@ -698,6 +729,14 @@ func (f *FunctionType) CacheNumInUint64() {
 	}
 }
 
+// StackLen is the minimum length of a stack used for both params and results of a function with this signature.
+func (f *FunctionType) StackLen() int {
+	if f.ParamNumInUint64 > f.ResultNumInUint64 {
+		return f.ParamNumInUint64
+	}
+	return f.ResultNumInUint64
+}
+
 // EqualsSignature returns true if the function type has the same parameters and results.
 func (t *FunctionType) EqualsSignature(params []ValueType, results []ValueType) bool {
 	return bytes.Equal(t.Params, params) && bytes.Equal(t.Results, results)
@@ -817,6 +856,10 @@ type Code struct {
 	// Body is a sequence of expressions ending in OpcodeEnd
 	// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#binary-expr
 	Body []byte
+
+	// BodyOffset is the offset of Body in the binary it was decoded from, used to report where validation failed. This
+	// is zero when the Code wasn't decoded, ex. in tests.
+	BodyOffset uint64
 }
 
 type DataSegment struct {
@@ -833,6 +876,12 @@ func (d *DataSegment) IsPassive() bool {
 	return d.OffsetExpression == nil
 }
 
+// CustomSection is a SectionIDCustom other than "name", whose Data is opaque to wazero.
+type CustomSection struct {
+	Name string
+	Data []byte
+}
+
 // NameSection represent the known custom name subsections defined in the WebAssembly Binary Format
 //
 // Note: This can be nil if no names were decoded for any reason including configuration.
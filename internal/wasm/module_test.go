@@ -773,7 +773,7 @@ func TestModule_buildGlobals(t *testing.T) {
 }
 
 func TestModule_buildFunctions(t *testing.T) {
-	nopCode := &Code{nil, []byte{OpcodeEnd}}
+	nopCode := &Code{Body: []byte{OpcodeEnd}}
 	m := Module{
 		TypeSection:   []*FunctionType{{}},
 		ImportSection: []*Import{{Type: ExternTypeFunc}},
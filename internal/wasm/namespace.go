@@ -56,20 +56,18 @@ func (ns *Namespace) module(moduleName string) *ModuleInstance {
 	return ns.modules[moduleName]
 }
 
-// requireModules returns all instantiated modules whose names equal the keys in the input, or errs if any are missing.
-func (ns *Namespace) requireModules(moduleNames map[string]struct{}) (map[string]*ModuleInstance, error) {
+// findModules returns all instantiated modules whose names equal the keys in the input, skipping any missing.
+func (ns *Namespace) findModules(moduleNames map[string]struct{}) map[string]*ModuleInstance {
 	ns.mux.RLock()
 	defer ns.mux.RUnlock()
 
 	ret := make(map[string]*ModuleInstance, len(moduleNames))
 	for n := range moduleNames {
-		m, ok := ns.modules[n]
-		if !ok {
-			return nil, fmt.Errorf("module[%s] not instantiated", n)
+		if m, ok := ns.modules[n]; ok {
+			ret[n] = m
 		}
-		ret[n] = m
 	}
-	return ret, nil
+	return ret
 }
 
 // requireModuleName is a pre-flight check to reserve a module.
@@ -111,6 +109,16 @@ func (ns *Namespace) CloseWithExitCode(ctx context.Context, exitCode uint32) (er
 	return
 }
 
+// CloseModule implements the same method as documented on wazero.Namespace.
+func (ns *Namespace) CloseModule(ctx context.Context, moduleName string, exitCode uint32) error {
+	m := ns.module(moduleName)
+	if m == nil {
+		return fmt.Errorf("module[%s] not instantiated", moduleName)
+	}
+	// Close like api.Module does, which also releases the name and any compiled code closed with the module.
+	return m.CallCtx.CloseWithExitCode(ctx, exitCode)
+}
+
 // Module implements wazero.Namespace Module
 func (ns *Namespace) Module(moduleName string) api.Module {
 	if m := ns.module(moduleName); m != nil {
@@ -1,6 +1,7 @@
 package wasm
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -72,19 +73,18 @@ func TestNamespace_module(t *testing.T) {
 	})
 }
 
-func TestNamespace_requireModules(t *testing.T) {
+func TestNamespace_findModules(t *testing.T) {
 	t.Run("ok", func(t *testing.T) {
 		ns, m1, _ := newTestNamespace()
 
-		modules, err := ns.requireModules(map[string]struct{}{m1.Name: {}})
-		require.NoError(t, err)
+		modules := ns.findModules(map[string]struct{}{m1.Name: {}})
 		require.Equal(t, map[string]*ModuleInstance{m1.Name: m1}, modules)
 	})
 	t.Run("module not instantiated", func(t *testing.T) {
-		ns, _, _ := newTestNamespace()
+		ns, m1, _ := newTestNamespace()
 
-		_, err := ns.requireModules(map[string]struct{}{"unknown": {}})
-		require.EqualError(t, err, "module[unknown] not instantiated")
+		modules := ns.findModules(map[string]struct{}{m1.Name: {}, "unknown": {}})
+		require.Equal(t, map[string]*ModuleInstance{m1.Name: m1}, modules)
 	})
 }
 
@@ -181,6 +181,26 @@ func TestNamespace_CloseWithExitCode(t *testing.T) {
 	})
 }
 
+func TestNamespace_CloseModule(t *testing.T) {
+	ns, m1, m2 := newTestNamespace()
+
+	var notified *uint32
+	m1.CallCtx.CloseNotifier = func(ctx context.Context, exitCode uint32) { notified = &exitCode }
+
+	require.NoError(t, ns.CloseModule(testCtx, m1.Name, 2))
+
+	// Only the module named was closed, and its name is available again.
+	require.Equal(t, uint64(1)+uint64(2)<<32, *m1.CallCtx.closed)
+	require.Equal(t, uint32(2), *notified)
+	require.Zero(t, *m2.CallCtx.closed)
+	require.Nil(t, ns.Module(m1.Name))
+	require.Equal(t, []string{m2.Name}, ns.moduleNames)
+
+	t.Run("unknown", func(t *testing.T) {
+		require.EqualError(t, ns.CloseModule(testCtx, m1.Name, 0), "module[m1] not instantiated")
+	})
+}
+
 func TestNamespace_Module(t *testing.T) {
 	ns, m1, _ := newTestNamespace()
 
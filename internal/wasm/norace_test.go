@@ -0,0 +1,6 @@
+//go:build !race
+
+package wasm
+
+// raceEnabled is false when testing without -race. See race_test.go
+const raceEnabled = false
@@ -0,0 +1,6 @@
+//go:build race
+
+package wasm
+
+// raceEnabled is true when testing with -race, which randomly drops sync.Pool items, so allocations vary.
+const raceEnabled = true
@@ -0,0 +1,53 @@
+package wasm
+
+import (
+	"context"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// snapshot implements api.Snapshot
+type snapshot struct {
+	callCtx *CallContext
+	// memory is a copy of the memory buffer, or nil if the module has no memory or imports it. Imported memory is
+	// excluded, as restoring it would overwrite the memory of each module sharing it.
+	memory []byte
+	// globals holds the Val and ValHi of each global defined by the module, in pairs. Imported globals are excluded, as
+	// they are owned by the module that exports them.
+	globals []uint64
+}
+
+// Snapshot implements the same method as documented on api.Module.
+func (m *CallContext) Snapshot() api.Snapshot {
+	globals := m.module.Globals[m.module.importedGlobalCount:]
+	s := &snapshot{callCtx: m, globals: make([]uint64, 0, 2*len(globals))}
+	if mem := m.module.Memory; mem != nil && !m.module.memoryImported {
+		mem.mux.RLock()
+		s.memory = append(make([]byte, 0, len(mem.Buffer)), mem.Buffer...)
+		mem.mux.RUnlock()
+	}
+	for _, g := range globals {
+		s.globals = append(s.globals, g.Val, g.ValHi)
+	}
+	return s
+}
+
+// Restore implements the same method as documented on api.Snapshot.
+func (s *snapshot) Restore(context.Context) error {
+	if err := s.callCtx.FailIfClosed(); err != nil {
+		return err
+	}
+	m := s.callCtx.module
+	if mem := m.Memory; mem != nil && !m.memoryImported {
+		mem.mux.Lock()
+		// Shrink back if the memory grew since, zeroing what a later grow would otherwise expose.
+		mem.resize(uint64(len(s.memory)))
+		copy(mem.Buffer, s.memory)
+		mem.mux.Unlock()
+	}
+	for i, g := range m.Globals[m.importedGlobalCount:] {
+		// Update in place as engines reference the global instances.
+		g.Val, g.ValHi = s.globals[2*i], s.globals[2*i+1]
+	}
+	return nil
+}
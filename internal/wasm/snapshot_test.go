@@ -0,0 +1,103 @@
+package wasm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+func TestCallContext_Snapshot(t *testing.T) {
+	mem := NewMemoryInstance(&Memory{Min: 1, Cap: 1, Max: 3})
+	global := &GlobalInstance{Type: &GlobalType{ValType: ValueTypeV128, Mutable: true}, Val: 1, ValHi: 2}
+	m := &ModuleInstance{Name: "test", Memory: mem, Globals: []*GlobalInstance{global}}
+	m.CallCtx = NewCallContext(newNamespace(), m, nil)
+
+	require.True(t, mem.Write(testCtx, 10, []byte("wazero")))
+	snap := m.CallCtx.Snapshot()
+
+	// Overwrite the memory and the global, then grow the memory.
+	require.True(t, mem.Write(testCtx, 10, []byte("wasmer")))
+	global.Val, global.ValHi = 3, 4
+	_, ok := mem.Grow(testCtx, 2)
+	require.True(t, ok)
+	require.True(t, mem.WriteUint32Le(testCtx, 2*MemoryPageSize, 42))
+
+	require.NoError(t, snap.Restore(testCtx))
+	requireSnapshotState(t, m)
+
+	// Growing the memory again must not expose what was written past the snapshot.
+	_, ok = mem.Grow(testCtx, 2)
+	require.True(t, ok)
+	v, ok := mem.ReadUint32Le(testCtx, 2*MemoryPageSize)
+	require.True(t, ok)
+	require.Zero(t, v)
+	require.NoError(t, snap.Restore(testCtx))
+
+	// A snapshot can be restored more than once.
+	require.True(t, mem.Write(testCtx, 10, []byte("wasmer")))
+	require.NoError(t, snap.Restore(testCtx))
+	requireSnapshotState(t, m)
+
+	t.Run("closed", func(t *testing.T) {
+		require.NoError(t, m.CallCtx.CloseWithExitCode(testCtx, 2))
+		require.Equal(t, sys.NewExitError("test", 2), snap.Restore(testCtx))
+	})
+}
+
+func TestCallContext_Snapshot_NoMemory(t *testing.T) {
+	global := &GlobalInstance{Type: &GlobalType{ValType: ValueTypeI32, Mutable: true}, Val: 1}
+	m := &ModuleInstance{Name: "test", Globals: []*GlobalInstance{global}}
+	m.CallCtx = NewCallContext(newNamespace(), m, nil)
+
+	snap := m.CallCtx.Snapshot()
+	global.Val = 2
+	require.NoError(t, snap.Restore(context.Background()))
+	require.Equal(t, uint64(1), global.Val)
+}
+
+func TestCallContext_Snapshot_ImportedGlobal(t *testing.T) {
+	imported := &GlobalInstance{Type: &GlobalType{ValType: ValueTypeI32, Mutable: true}, Val: 1}
+	global := &GlobalInstance{Type: &GlobalType{ValType: ValueTypeI32, Mutable: true}, Val: 2}
+	m := &ModuleInstance{Name: "test", Globals: []*GlobalInstance{imported, global}, importedGlobalCount: 1}
+	m.CallCtx = NewCallContext(newNamespace(), m, nil)
+
+	snap := m.CallCtx.Snapshot()
+	imported.Val, global.Val = 3, 4
+	require.NoError(t, snap.Restore(testCtx))
+
+	// Only the global defined by the module is restored, as the imported one is owned by another module.
+	require.Equal(t, uint64(3), imported.Val)
+	require.Equal(t, uint64(2), global.Val)
+}
+
+func TestCallContext_Snapshot_ImportedMemory(t *testing.T) {
+	mem := NewMemoryInstance(&Memory{Min: 1, Cap: 1, Max: 3})
+	m := &ModuleInstance{Name: "test", Memory: mem, memoryImported: true}
+	m.CallCtx = NewCallContext(newNamespace(), m, nil)
+
+	require.True(t, mem.Write(testCtx, 10, []byte("wazero")))
+	snap := m.CallCtx.Snapshot()
+	require.True(t, mem.Write(testCtx, 10, []byte("wasmer")))
+	_, ok := mem.Grow(testCtx, 1)
+	require.True(t, ok)
+	require.NoError(t, snap.Restore(testCtx))
+
+	// The memory isn't restored, as it is shared with the module that exports it.
+	require.Equal(t, uint32(2*MemoryPageSize), mem.Size(testCtx))
+	buf, ok := mem.Read(testCtx, 10, 6)
+	require.True(t, ok)
+	require.Equal(t, "wasmer", string(buf))
+}
+
+func requireSnapshotState(t *testing.T, m *ModuleInstance) {
+	var mem api.Memory = m.Memory
+	require.Equal(t, uint32(MemoryPageSize), mem.Size(testCtx))
+	buf, ok := mem.Read(testCtx, 10, 6)
+	require.True(t, ok)
+	require.Equal(t, "wazero", string(buf))
+	require.Equal(t, uint64(1), m.Globals[0].Val)
+	require.Equal(t, uint64(2), m.Globals[0].ValHi)
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"sync"
 
 	"github.com/tetratelabs/wazero/api"
@@ -35,6 +36,10 @@ type (
 		// Engine is a global context for a Store which is in responsible for compilation and execution of Wasm modules.
 		Engine Engine
 
+		// StubMissingImports when true instantiates function imports no module exports as functions that panic with
+		// api.ErrUnimplementedImport when called, instead of failing instantiation.
+		StubMissingImports bool
+
 		// typeIDs maps each FunctionType.String() to a unique FunctionTypeID. This is used at runtime to
 		// do type-checks on indirect function calls.
 		typeIDs map[string]FunctionTypeID
@@ -84,6 +89,14 @@ type (
 		// ElementInstances holds the element instance, and each holds the references to either functions
 		// or external objects (unimplemented).
 		ElementInstances []ElementInstance
+
+		// DataSegments are the memory ranges the active data segments were copied to by applyData.
+		DataSegments []api.DataSegment
+
+		// importedGlobalCount is the count of Globals imported from other modules, which precede the ones it defines.
+		importedGlobalCount int
+		// memoryImported is true when Memory is imported from another module, so shared with it.
+		memoryImported bool
 	}
 
 	// DataInstance holds bytes corresponding to the data segment in a module.
@@ -154,6 +167,9 @@ type (
 
 		// FunctionListener holds a listener to notify when this function is called.
 		FunctionListener experimentalapi.FunctionListener
+
+		// goFuncArgs pools the arguments of calls to GoFunc. See CallGoFunc
+		goFuncArgs sync.Pool
 	}
 
 	// GlobalInstance represents a global instance in a store.
@@ -205,7 +221,7 @@ const (
 
 // addSections adds section elements to the ModuleInstance
 func (m *ModuleInstance) addSections(module *Module, importedFunctions, functions []*FunctionInstance,
-	importedGlobals, globals []*GlobalInstance, tables []*TableInstance, memory, importedMemory *MemoryInstance,
+	importedGlobals, globals []*GlobalInstance, tables []*TableInstance, importedMemory, memory *MemoryInstance,
 	types []*FunctionType, typeIDs []FunctionTypeID) {
 
 	m.Types = types
@@ -221,11 +237,13 @@ func (m *ModuleInstance) addSections(module *Module, importedFunctions, function
 
 	m.Globals = append(m.Globals, importedGlobals...)
 	m.Globals = append(m.Globals, globals...)
+	m.importedGlobalCount = len(importedGlobals)
 
 	m.Tables = tables
 
 	if importedMemory != nil {
 		m.Memory = importedMemory
+		m.memoryImported = true
 	} else {
 		m.Memory = memory
 	}
@@ -286,6 +304,7 @@ func (m *ModuleInstance) validateData(data []*DataSegment) (err error) {
 }
 
 func (m *ModuleInstance) applyData(data []*DataSegment) error {
+	m.DataSegments = m.DataSegments[:0]
 	for i, d := range data {
 		if !d.IsPassive() {
 			offset := executeConstExpression(m.Globals, d.OffsetExpression).(int32)
@@ -293,6 +312,54 @@ func (m *ModuleInstance) applyData(data []*DataSegment) error {
 				return fmt.Errorf("%s[%d] out of bounds memory access", SectionIDName(SectionIDElement), i)
 			}
 			copy(m.Memory.Buffer[offset:], d.Init)
+			m.DataSegments = append(m.DataSegments, api.DataSegment{Offset: uint32(offset), Size: uint32(len(d.Init))})
+		}
+	}
+	return nil
+}
+
+// Reset restores the state defined by the module to what it was after instantiation, then executes the start function
+// again, if there is one. The module must be the one this instance was instantiated from.
+//
+// Memory defined by the module is zeroed and shrunk to its minimum size, and then the active data segments are applied.
+// Globals defined by the module are set to their initial values, and any data segments dropped are restored.
+//
+// Note: Tables and element segments are not reset. Imported globals, tables and memory are not reset either as they are
+// shared with other modules, though data segments are applied to imported memory, like on instantiation.
+func (m *ModuleInstance) Reset(ctx context.Context, module *Module) error {
+	if module.MemorySection != nil {
+		mem := m.Memory
+		mem.mux.Lock()
+		mem.resize(MemoryPagesToBytesNum(mem.Min))
+		for i := range mem.Buffer {
+			mem.Buffer[i] = 0
+		}
+		mem.mux.Unlock()
+	}
+
+	importedGlobals, globals := m.Globals[:m.importedGlobalCount], m.Globals[m.importedGlobalCount:]
+	for i, g := range module.buildGlobals(importedGlobals) {
+		// Update in place as engines reference the global instances.
+		globals[i].Val, globals[i].ValHi = g.Val, g.ValHi
+	}
+	// Like on instantiation, funcref globals hold a function index until the engine lowers it.
+	m.Engine.InitializeFuncrefGlobals(globals)
+
+	for i, d := range module.DataSection {
+		m.DataInstances[i] = d.Init
+	}
+
+	if m.Memory != nil {
+		if err := m.applyData(module.DataSection); err != nil {
+			return err
+		}
+	}
+
+	if module.StartSection != nil {
+		funcIdx := *module.StartSection
+		f := m.Functions[funcIdx]
+		if _, err := f.Module.Engine.Call(ctx, m.CallCtx, f); err != nil {
+			return fmt.Errorf("start %s failed: %w", module.funcDesc(SectionIDFunction, funcIdx), err)
 		}
 	}
 	return nil
@@ -358,14 +425,12 @@ func (s *Store) Instantiate(
 		importedModuleNames[i.Module] = struct{}{}
 	}
 
-	// Read-Lock the namespace and ensure imports needed are present.
-	importedModules, err := ns.requireModules(importedModuleNames)
-	if err != nil {
-		return nil, err
-	}
+	// Read-Lock the namespace and collect the imported modules present. Any missing are reported when resolving
+	// imports, along with any other unresolved imports.
+	importedModules := ns.findModules(importedModuleNames)
 
 	// Write-Lock the namespace and claim the name of the current module.
-	if err = ns.requireModuleName(name); err != nil {
+	if err := ns.requireModuleName(name); err != nil {
 		return nil, err
 	}
 
@@ -389,20 +454,35 @@ func (s *Store) instantiate(
 	sys *sys.Context,
 	functionListenerFactory experimentalapi.FunctionListenerFactory,
 	modules map[string]*ModuleInstance,
-) (*CallContext, error) {
+) (_ *CallContext, err error) {
 	typeIDs, err := s.getFunctionTypeIDs(module.TypeSection)
 	if err != nil {
 		return nil, err
 	}
 
-	importedFunctions, importedGlobals, importedTables, importedMemory, err := resolveImports(module, modules)
+	var stubs map[int]*FunctionInstance
+	var stubsModule *Module
+	if s.StubMissingImports {
+		if stubs, stubsModule, err = s.instantiateImportStubs(ctx, ns, module, modules); err != nil {
+			return nil, err
+		}
+	}
+	if stubsModule != nil {
+		defer func() {
+			if err != nil { // Otherwise, the code is deleted when the module is closed.
+				s.Engine.DeleteCompiledModule(stubsModule)
+			}
+		}()
+	}
+
+	importedFunctions, importedGlobals, importedTables, importedMemory, err := resolveImportsWithStubs(module, modules, stubs)
 	if err != nil {
 		return nil, err
 	}
 
 	tables, tableInit, err := module.buildTables(importedTables, importedGlobals,
 		// As of reference-types proposal, boundary check must be done after instantiation.
-		s.EnabledFeatures.Get(FeatureReferenceTypes))
+		module.FeaturesOr(s.EnabledFeatures).Get(FeatureReferenceTypes))
 	if err != nil {
 		return nil, err
 	}
@@ -426,7 +506,7 @@ func (s *Store) instantiate(
 	// As of reference types proposal, data segment validation must happen after instantiation,
 	// and the side effect must persist even if there's out of bounds error after instantiation.
 	// https://github.com/WebAssembly/spec/blob/d39195773112a22b245ffbe864bab6d1182ccb06/test/core/linking.wast#L395-L405
-	if !s.EnabledFeatures.Get(FeatureReferenceTypes) {
+	if !module.FeaturesOr(s.EnabledFeatures).Get(FeatureReferenceTypes) {
 		if err = m.validateData(module.DataSection); err != nil {
 			return nil, err
 		}
@@ -452,6 +532,9 @@ func (s *Store) instantiate(
 
 	// Compile the default context for calls to this module.
 	m.CallCtx = NewCallContext(ns, m, sys)
+	if stubsModule != nil {
+		m.CallCtx.deleteImportStubs = func() { s.Engine.DeleteCompiledModule(stubsModule) }
+	}
 
 	// Execute the start function.
 	if module.StartSection != nil {
@@ -472,17 +555,36 @@ func resolveImports(module *Module, modules map[string]*ModuleInstance) (
 	importedMemory *MemoryInstance,
 	err error,
 ) {
+	return resolveImportsWithStubs(module, modules, nil)
+}
+
+// resolveImportsWithStubs is like resolveImports, except function imports with an index in stubs resolve to the
+// corresponding stub. See Store.StubMissingImports
+func resolveImportsWithStubs(module *Module, modules map[string]*ModuleInstance, stubs map[int]*FunctionInstance) (
+	importedFunctions []*FunctionInstance,
+	importedGlobals []*GlobalInstance,
+	importedTables []*TableInstance,
+	importedMemory *MemoryInstance,
+	err error,
+) {
+	// Collect errors instead of returning the first, so that all unresolved imports are reported at once.
+	var errs []error
 	for idx, i := range module.ImportSection {
+		if stub, ok := stubs[idx]; ok {
+			importedFunctions = append(importedFunctions, stub)
+			continue
+		}
+
 		m, ok := modules[i.Module]
 		if !ok {
-			err = fmt.Errorf("module[%s] not instantiated", i.Module)
-			return
+			errs = append(errs, errorInvalidImport(i, idx, fmt.Errorf("module[%s] not instantiated", i.Module)))
+			continue
 		}
 
-		var imported *ExportInstance
-		imported, err = m.getExport(i.Name, i.Type)
-		if err != nil {
-			return
+		imported, exportErr := m.getExport(i.Name, i.Type)
+		if exportErr != nil {
+			errs = append(errs, errorInvalidImport(i, idx, exportErr))
+			continue
 		}
 
 		switch i.Type {
@@ -490,16 +592,16 @@ func resolveImports(module *Module, modules map[string]*ModuleInstance) (
 			typeIndex := i.DescFunc
 			// TODO: this shouldn't be possible as invalid should fail validate
 			if int(typeIndex) >= len(module.TypeSection) {
-				err = errorInvalidImport(i, idx, fmt.Errorf("function type out of range"))
-				return
+				errs = append(errs, errorInvalidImport(i, idx, fmt.Errorf("function type out of range")))
+				continue
 			}
 			expectedType := module.TypeSection[i.DescFunc]
 			importedFunction := imported.Function
 
 			actualType := importedFunction.Type
 			if !expectedType.EqualsSignature(actualType.Params, actualType.Results) {
-				err = errorInvalidImport(i, idx, fmt.Errorf("signature mismatch: %s != %s", expectedType, actualType))
-				return
+				errs = append(errs, errorInvalidImport(i, idx, fmt.Errorf("signature mismatch: %s != %s", expectedType, actualType)))
+				continue
 			}
 
 			importedFunctions = append(importedFunctions, importedFunction)
@@ -507,23 +609,24 @@ func resolveImports(module *Module, modules map[string]*ModuleInstance) (
 			expected := i.DescTable
 			importedTable := imported.Table
 			if expected.Type != importedTable.Type {
-				err = errorInvalidImport(i, idx, fmt.Errorf("table type mismatch: %s != %s",
-					RefTypeName(expected.Type), RefTypeName(importedTable.Type)))
+				errs = append(errs, errorInvalidImport(i, idx, fmt.Errorf("table type mismatch: %s != %s",
+					RefTypeName(expected.Type), RefTypeName(importedTable.Type))))
+				continue
 			}
 
 			if expected.Min > importedTable.Min {
-				err = errorMinSizeMismatch(i, idx, expected.Min, importedTable.Min)
-				return
+				errs = append(errs, errorMinSizeMismatch(i, idx, expected.Min, importedTable.Min))
+				continue
 			}
 
 			if expected.Max != nil {
 				expectedMax := *expected.Max
 				if importedTable.Max == nil {
-					err = errorNoMax(i, idx, expectedMax)
-					return
+					errs = append(errs, errorNoMax(i, idx, expectedMax))
+					continue
 				} else if expectedMax < *importedTable.Max {
-					err = errorMaxSizeMismatch(i, idx, expectedMax, *importedTable.Max)
-					return
+					errs = append(errs, errorMaxSizeMismatch(i, idx, expectedMax, *importedTable.Max))
+					continue
 				}
 			}
 			importedTables = append(importedTables, importedTable)
@@ -532,35 +635,62 @@ func resolveImports(module *Module, modules map[string]*ModuleInstance) (
 			importedMemory = imported.Memory
 
 			if expected.Min > memoryBytesNumToPages(uint64(len(importedMemory.Buffer))) {
-				err = errorMinSizeMismatch(i, idx, expected.Min, importedMemory.Min)
-				return
+				errs = append(errs, errorMinSizeMismatch(i, idx, expected.Min, importedMemory.Min))
+				continue
 			}
 
 			if expected.Max < importedMemory.Max {
-				err = errorMaxSizeMismatch(i, idx, expected.Max, importedMemory.Max)
-				return
+				errs = append(errs, errorMaxSizeMismatch(i, idx, expected.Max, importedMemory.Max))
+				continue
 			}
 		case ExternTypeGlobal:
 			expected := i.DescGlobal
 			importedGlobal := imported.Global
 
 			if expected.Mutable != importedGlobal.Type.Mutable {
-				err = errorInvalidImport(i, idx, fmt.Errorf("mutability mismatch: %t != %t",
-					expected.Mutable, importedGlobal.Type.Mutable))
-				return
+				errs = append(errs, errorInvalidImport(i, idx, fmt.Errorf("mutability mismatch: %t != %t",
+					expected.Mutable, importedGlobal.Type.Mutable)))
+				continue
 			}
 
 			if expected.ValType != importedGlobal.Type.ValType {
-				err = errorInvalidImport(i, idx, fmt.Errorf("value type mismatch: %s != %s",
-					ValueTypeName(expected.ValType), ValueTypeName(importedGlobal.Type.ValType)))
-				return
+				errs = append(errs, errorInvalidImport(i, idx, fmt.Errorf("value type mismatch: %s != %s",
+					ValueTypeName(expected.ValType), ValueTypeName(importedGlobal.Type.ValType))))
+				continue
 			}
 			importedGlobals = append(importedGlobals, importedGlobal)
 		}
 	}
+
+	switch len(errs) {
+	case 0:
+	case 1:
+		err = errs[0]
+	default:
+		err = importErrors(errs)
+	}
 	return
 }
 
+// importErrors are the errors resolving more than one import of a module.
+type importErrors []error
+
+// Error implements error
+func (e importErrors) Error() string {
+	var ret strings.Builder
+	fmt.Fprintf(&ret, "%d imports unresolved:", len(e))
+	for _, err := range e {
+		ret.WriteString("\n\t")
+		ret.WriteString(err.Error())
+	}
+	return ret.String()
+}
+
+// Unwrap allows errors.Is and errors.As to reach each error.
+func (e importErrors) Unwrap() []error {
+	return e
+}
+
 func errorMinSizeMismatch(i *Import, idx int, expected, actual uint32) error {
 	return errorInvalidImport(i, idx, fmt.Errorf("minimum size mismatch: %d > %d", expected, actual))
 }
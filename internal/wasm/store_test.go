@@ -266,7 +266,7 @@ func TestStore_Instantiate_Errors(t *testing.T) {
 				{Type: ExternTypeFunc, Module: "non-exist", Name: "fn", DescFunc: 0},
 			},
 		}, importingModuleName, nil, nil)
-		require.EqualError(t, err, "module[non-exist] not instantiated")
+		require.EqualError(t, err, "import[1] func[non-exist.fn]: module[non-exist] not instantiated")
 	})
 
 	t.Run("compilation failed", func(t *testing.T) {
@@ -408,6 +408,12 @@ func (e *mockModuleEngine) Call(ctx context.Context, callCtx *CallContext, f *Fu
 	return
 }
 
+// CallWithStack implements the same method as documented on wasm.ModuleEngine.
+func (e *mockModuleEngine) CallWithStack(ctx context.Context, callCtx *CallContext, f *FunctionInstance, _ []uint64) error {
+	_, err := e.Call(ctx, callCtx, f)
+	return err
+}
+
 // Close implements the same method as documented on wasm.ModuleEngine.
 func (e *mockModuleEngine) Close(_ context.Context) {
 }
@@ -601,14 +607,14 @@ func Test_resolveImports(t *testing.T) {
 	t.Run("module not instantiated", func(t *testing.T) {
 		modules := map[string]*ModuleInstance{}
 		_, _, _, _, err := resolveImports(&Module{ImportSection: []*Import{{Module: "unknown", Name: "unknown"}}}, modules)
-		require.EqualError(t, err, "module[unknown] not instantiated")
+		require.EqualError(t, err, "import[0] func[unknown.unknown]: module[unknown] not instantiated")
 	})
 	t.Run("export instance not found", func(t *testing.T) {
 		modules := map[string]*ModuleInstance{
 			moduleName: {Exports: map[string]*ExportInstance{}, Name: moduleName},
 		}
 		_, _, _, _, err := resolveImports(&Module{ImportSection: []*Import{{Module: moduleName, Name: "unknown"}}}, modules)
-		require.EqualError(t, err, "\"unknown\" is not exported in module \"test\"")
+		require.EqualError(t, err, "import[0] func[test.unknown]: \"unknown\" is not exported in module \"test\"")
 	})
 	t.Run("func", func(t *testing.T) {
 		t.Run("ok", func(t *testing.T) {
@@ -727,6 +733,18 @@ func Test_resolveImports(t *testing.T) {
 	})
 }
 
+func Test_importErrors(t *testing.T) {
+	first, second := errors.New("first"), errors.New("second")
+	err := error(importErrors{fmt.Errorf("import[0]: %w", first), fmt.Errorf("import[1]: %w", second)})
+	require.EqualError(t, err, `2 imports unresolved:
+	import[0]: first
+	import[1]: second`)
+
+	// Each error is reachable, not just the first.
+	require.True(t, errors.Is(err, first))
+	require.True(t, errors.Is(err, second))
+}
+
 func TestModuleInstance_validateData(t *testing.T) {
 	m := &ModuleInstance{Memory: &MemoryInstance{Buffer: make([]byte, 5)}}
 	tests := []struct {
@@ -778,6 +796,14 @@ func TestModuleInstance_applyData(t *testing.T) {
 	})
 	require.NoError(t, err)
 	require.Equal(t, []byte{0xa, 0xf, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x1, 0x5}, m.Memory.Buffer)
+	require.Equal(t, []api.DataSegment{{Offset: 0, Size: 2}, {Offset: 8, Size: 2}}, m.DataSegments)
+
+	// Applying again, such as on Reset, doesn't duplicate the ranges.
+	require.NoError(t, m.applyData([]*DataSegment{
+		{OffsetExpression: &ConstantExpression{Opcode: OpcodeI32Const, Data: leb128.EncodeUint32(4)}, Init: []byte{0x1}},
+		{Init: []byte{0x2}}, // passive
+	}))
+	require.Equal(t, []api.DataSegment{{Offset: 4, Size: 1}}, m.DataSegments)
 }
 
 func globalsContain(globals []*GlobalInstance, want *GlobalInstance) bool {
@@ -102,6 +102,10 @@ type TableInstance struct {
 	// Type is either RefTypeFuncref or RefTypeExternRef.
 	Type RefType
 
+	// GrowLimit if present is the maximum elements Grow allows, even if Max allows more.
+	// See wazero.ModuleConfig WithMaxTableElements
+	GrowLimit *uint32
+
 	// mux is used to prevent overlapping calls to Grow.
 	mux sync.RWMutex
 }
@@ -322,7 +326,7 @@ func (t *TableInstance) Grow(_ context.Context, delta uint32, initialRef Referen
 	}
 
 	if newLen := int64(currentLen) + int64(delta); // adding as 64bit ints to avoid overflow.
-	newLen >= math.MaxUint32 || (t.Max != nil && newLen > int64(*t.Max)) {
+	newLen >= math.MaxUint32 || (t.Max != nil && newLen > int64(*t.Max)) || (t.GrowLimit != nil && newLen > int64(*t.GrowLimit)) {
 		return 0xffffffff // = -1 in signed 32-bit integer.
 	}
 	t.References = append(t.References, make([]uintptr, delta)...)
@@ -953,6 +953,7 @@ func TestTableInstance_Grow(t *testing.T) {
 		name       string
 		currentLen int
 		max        *uint32
+		growLimit  *uint32
 		delta, exp uint32
 	}{
 		{
@@ -994,12 +995,26 @@ func TestTableInstance_Grow(t *testing.T) {
 			max:        &max10,
 			exp:        expOnErr,
 		},
+		{
+			name:       "grow up to grow limit",
+			currentLen: 5,
+			delta:      5,
+			growLimit:  &max10,
+			exp:        5,
+		},
+		{
+			name:       "grow out of range beyond grow limit",
+			currentLen: 5,
+			delta:      6,
+			growLimit:  &max10,
+			exp:        expOnErr,
+		},
 	}
 
 	for _, tt := range tests {
 		tc := tt
 		t.Run(tc.name, func(t *testing.T) {
-			table := &TableInstance{References: make([]uintptr, tc.currentLen), Max: tc.max}
+			table := &TableInstance{References: make([]uintptr, tc.currentLen), Max: tc.max, GrowLimit: tc.growLimit}
 			actual := table.Grow(testCtx, tc.delta, 0)
 			require.Equal(t, tc.exp, actual)
 		})
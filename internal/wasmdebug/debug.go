@@ -4,6 +4,7 @@
 package wasmdebug
 
 import (
+	"errors"
 	"fmt"
 	"runtime"
 	"runtime/debug"
@@ -98,16 +99,64 @@ type ErrorBuilder interface {
 	// Note: paramTypes and resultTypes are present because signature misunderstanding, mismatch or overflow are common.
 	AddFrame(funcName string, paramTypes, resultTypes []api.ValueType)
 
+	// SetStackValues sets the values at the top of the operand stack when the error occurred, beginning with the top.
+	// At most MaxStackValues are retained. See StackValues
+	//
+	// Note: Engines which don't track the operand stack at the point of a trap, such as the compiler, don't call this.
+	SetStackValues(values []uint64)
+
 	// FromRecovered returns an error with the wasm stack trace appended to it.
 	FromRecovered(recovered interface{}) error
 }
 
+// MaxStackValues is the maximum count of values retained by ErrorBuilder.SetStackValues.
+const MaxStackValues = 8
+
 func NewErrorBuilder() ErrorBuilder {
 	return &stackTrace{}
 }
 
 type stackTrace struct {
-	frames []string
+	frames      []string
+	funcNames   []string
+	stackValues []uint64
+}
+
+// stackTraceError is the error of FromRecovered for a *wasmruntime.Error, retaining the function names of its stack
+// trace. See FunctionNames
+type stackTraceError struct {
+	cause       *wasmruntime.Error
+	stack       string
+	funcNames   []string
+	stackValues []uint64
+}
+
+// Error implements the error interface by appending the wasm stack trace to the cause.
+func (e *stackTraceError) Error() string {
+	return fmt.Sprintf("wasm error: %s\nwasm stack trace:\n\t%s", e.cause, e.stack)
+}
+
+// Unwrap allows use of errors.Is or errors.As on the cause.
+func (e *stackTraceError) Unwrap() error {
+	return e.cause
+}
+
+// FunctionNames returns the function names in the wasm stack trace of a trap, beginning with the function that
+// trapped, or nil if err isn't a trap. Each name is from FuncName.
+func FunctionNames(err error) []string {
+	if e := (*stackTraceError)(nil); errors.As(err, &e) {
+		return e.funcNames
+	}
+	return nil
+}
+
+// StackValues returns the values at the top of the operand stack when a trap occurred, beginning with the top, or nil
+// if err isn't a trap or the engine doesn't track them. See ErrorBuilder.SetStackValues
+func StackValues(err error) []uint64 {
+	if e := (*stackTraceError)(nil); errors.As(err, &e) {
+		return e.stackValues
+	}
+	return nil
 }
 
 func (s *stackTrace) FromRecovered(recovered interface{}) error {
@@ -119,7 +168,7 @@ func (s *stackTrace) FromRecovered(recovered interface{}) error {
 
 	// If the error was internal, don't mention it was recovered.
 	if wasmErr, ok := recovered.(*wasmruntime.Error); ok {
-		return fmt.Errorf("wasm error: %w\nwasm stack trace:\n\t%s", wasmErr, stack)
+		return &stackTraceError{cause: wasmErr, stack: stack, funcNames: s.funcNames, stackValues: s.stackValues}
 	}
 
 	// If we have a runtime.Error, something severe happened which should include the stack trace. This could be
@@ -142,4 +191,13 @@ func (s *stackTrace) AddFrame(funcName string, paramTypes, resultTypes []api.Val
 	// Format as best as we can, considering we don't yet have source and line numbers,
 	// TODO: include DWARF symbols. See #58
 	s.frames = append(s.frames, signature(funcName, paramTypes, resultTypes))
+	s.funcNames = append(s.funcNames, funcName)
+}
+
+// SetStackValues implements ErrorBuilder.SetStackValues
+func (s *stackTrace) SetStackValues(values []uint64) {
+	if len(values) > MaxStackValues {
+		values = values[:MaxStackValues]
+	}
+	s.stackValues = values
 }
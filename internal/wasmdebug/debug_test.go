@@ -2,6 +2,7 @@ package wasmdebug
 
 import (
 	"errors"
+	"fmt"
 	"runtime"
 	"testing"
 
@@ -138,6 +139,40 @@ wasm stack trace:
 	}
 }
 
+func TestFunctionNames(t *testing.T) {
+	builder := NewErrorBuilder()
+	builder.AddFrame("wasi_snapshot_preview1.fd_write", nil, []api.ValueType{api.ValueTypeI32})
+	builder.AddFrame("x.y", nil, nil)
+
+	err := builder.FromRecovered(wasmruntime.ErrRuntimeUnreachable)
+	require.Equal(t, []string{"wasi_snapshot_preview1.fd_write", "x.y"}, FunctionNames(err))
+	require.Equal(t, []string{"wasi_snapshot_preview1.fd_write", "x.y"}, FunctionNames(fmt.Errorf("wrapped: %w", err)))
+
+	// Only traps retain the function names.
+	require.Nil(t, FunctionNames(builder.FromRecovered(errors.New("whoops"))))
+	require.Nil(t, FunctionNames(wasmruntime.ErrRuntimeUnreachable))
+}
+
+func TestStackValues(t *testing.T) {
+	builder := NewErrorBuilder()
+	builder.AddFrame("x.y", nil, nil)
+
+	// Traps without stack values, ex. from the compiler, return nil.
+	require.Nil(t, StackValues(builder.FromRecovered(wasmruntime.ErrRuntimeUnreachable)))
+
+	builder.SetStackValues([]uint64{1, 2, 3})
+	err := builder.FromRecovered(wasmruntime.ErrRuntimeUnreachable)
+	require.Equal(t, []uint64{1, 2, 3}, StackValues(err))
+	require.Equal(t, []uint64{1, 2, 3}, StackValues(fmt.Errorf("wrapped: %w", err)))
+
+	// Only MaxStackValues are retained.
+	builder.SetStackValues(make([]uint64, MaxStackValues+1))
+	require.Equal(t, MaxStackValues, len(StackValues(builder.FromRecovered(wasmruntime.ErrRuntimeUnreachable))))
+
+	// Only traps retain the stack values.
+	require.Nil(t, StackValues(builder.FromRecovered(errors.New("whoops"))))
+}
+
 // compile-time check to ensure testRuntimeErr implements runtime.Error.
 var _ runtime.Error = testRuntimeErr("")
 
@@ -3,42 +3,54 @@
 // Note: This only imports "api" as importing "wasm" would create a cyclic dependency.
 package wasmruntime
 
+import "github.com/tetratelabs/wazero/api"
+
 var (
 	// ErrRuntimeCallStackOverflow indicates that there are too many function calls,
 	// and the Engine terminated the execution.
-	ErrRuntimeCallStackOverflow = New("callstack overflow")
+	ErrRuntimeCallStackOverflow = New(api.TrapReasonCallStackExhausted)
 	// ErrRuntimeInvalidConversionToInteger indicates the Wasm function tries to
 	// convert NaN floating point value to integers during trunc variant instructions.
-	ErrRuntimeInvalidConversionToInteger = New("invalid conversion to integer")
+	ErrRuntimeInvalidConversionToInteger = New(api.TrapReasonInvalidConversion)
 	// ErrRuntimeIntegerOverflow indicates that an integer arithmetic resulted in
 	// overflow value. For example, when the program tried to truncate a float value
 	// which doesn't fit in the range of target integer.
-	ErrRuntimeIntegerOverflow = New("integer overflow")
+	ErrRuntimeIntegerOverflow = New(api.TrapReasonIntegerOverflow)
 	// ErrRuntimeIntegerDivideByZero indicates that an integer div or rem instructions
 	// was executed with 0 as the divisor.
-	ErrRuntimeIntegerDivideByZero = New("integer divide by zero")
+	ErrRuntimeIntegerDivideByZero = New(api.TrapReasonIntegerDivideByZero)
 	// ErrRuntimeUnreachable means "unreachable" instruction was executed by the program.
-	ErrRuntimeUnreachable = New("unreachable")
+	ErrRuntimeUnreachable = New(api.TrapReasonUnreachable)
 	// ErrRuntimeOutOfBoundsMemoryAccess indicates that the program tried to access the
 	// region beyond the linear memory.
-	ErrRuntimeOutOfBoundsMemoryAccess = New("out of bounds memory access")
-	// ErrRuntimeInvalidTableAccess means either offset to the table was out of bounds of table, or
-	// the target element in the table was uninitialized during call_indirect instruction.
-	ErrRuntimeInvalidTableAccess = New("invalid table access")
+	ErrRuntimeOutOfBoundsMemoryAccess = New(api.TrapReasonOutOfBoundsMemory)
+	// ErrRuntimeInvalidTableAccess means the offset to the table was out of bounds of table.
+	ErrRuntimeInvalidTableAccess = New(api.TrapReasonOutOfBoundsTable)
+	// ErrRuntimeIndirectCallToNull means the target element in the table was null during call_indirect instruction.
+	ErrRuntimeIndirectCallToNull = New(api.TrapReasonIndirectCallToNull)
 	// ErrRuntimeIndirectCallTypeMismatch indicates that the type check failed during call_indirect.
-	ErrRuntimeIndirectCallTypeMismatch = New("indirect call type mismatch")
+	ErrRuntimeIndirectCallTypeMismatch = New(api.TrapReasonIndirectCallTypeMismatch)
 )
 
+// compile-time check to ensure Error implements api.TrapError
+var _ api.TrapError = &Error{}
+
 // Error is returned by a wasm.Engine during the execution of Wasm functions, and they indicate that the Wasm runtime
 // state is unrecoverable.
 type Error struct {
-	s string
+	reason api.TrapReason
 }
 
-func New(text string) *Error {
-	return &Error{s: text}
+func New(reason api.TrapReason) *Error {
+	return &Error{reason: reason}
 }
 
+// Error implements error
 func (e *Error) Error() string {
-	return e.s
+	return e.reason.String()
+}
+
+// TrapReason implements api.TrapError
+func (e *Error) TrapReason() api.TrapReason {
+	return e.reason
 }
@@ -11,6 +11,7 @@ import (
 
 	"github.com/tetratelabs/wazero/internal/buildoptions"
 	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/moremath"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
 
@@ -155,6 +156,9 @@ type compiler struct {
 	funcs []uint32
 	// globals holds the global types for all declard globas in the module where the targe function exists.
 	globals []*wasm.GlobalType
+	// deterministicNaN is true when NaN results of floating-point arithmetic are canonicalized.
+	// See wasm.Module DeterministicNaN
+	deterministicNaN bool
 }
 
 // For debugging only.
@@ -232,7 +236,7 @@ func CompileFunctions(_ context.Context, enabledFeatures wasm.Features, module *
 		typeID := module.FunctionSection[funcIndex]
 		sig := module.TypeSection[typeID]
 		code := module.CodeSection[funcIndex]
-		r, err := compile(enabledFeatures, sig, code.Body, code.LocalTypes, module.TypeSection, functions, globals)
+		r, err := compile(enabledFeatures, module.DeterministicNaN, sig, code.Body, code.LocalTypes, module.TypeSection, functions, globals)
 		if err != nil {
 			return nil, fmt.Errorf("failed to lower func[%d/%d] to wazeroir: %w", funcIndex, len(functions)-1, err)
 		}
@@ -252,6 +256,7 @@ func CompileFunctions(_ context.Context, enabledFeatures wasm.Features, module *
 // so that the resulting operations can be consumed by the interpreter
 // or the Compiler compilation engine.
 func compile(enabledFeatures wasm.Features,
+	deterministicNaN bool,
 	sig *wasm.FunctionType,
 	body []byte,
 	localTypes []wasm.ValueType,
@@ -259,15 +264,16 @@ func compile(enabledFeatures wasm.Features,
 	functions []uint32, globals []*wasm.GlobalType,
 ) (*CompilationResult, error) {
 	c := compiler{
-		enabledFeatures: enabledFeatures,
-		controlFrames:   &controlFrames{},
-		result:          CompilationResult{LabelCallers: map[string]uint32{}},
-		body:            body,
-		localTypes:      localTypes,
-		sig:             sig,
-		globals:         globals,
-		funcs:           functions,
-		types:           types,
+		enabledFeatures:  enabledFeatures,
+		deterministicNaN: deterministicNaN,
+		controlFrames:    &controlFrames{},
+		result:           CompilationResult{LabelCallers: map[string]uint32{}},
+		body:             body,
+		localTypes:       localTypes,
+		sig:              sig,
+		globals:          globals,
+		funcs:            functions,
+		types:            types,
 	}
 
 	c.calcLocalIndexToStackHeight()
@@ -1718,6 +1724,32 @@ operatorSwitch:
 		default:
 			return fmt.Errorf("unsupported misc instruction in wazeroir: 0x%x", op)
 		}
+	case wasm.OpcodeAtomicPrefix:
+		c.pc++
+		atomicOp := c.body[c.pc]
+		if atomicOp == wasm.OpcodeAtomicFence {
+			c.pc++ // Skip the reserved one byte.
+			// There is only one thread, so there's nothing to order.
+			break operatorSwitch
+		}
+		imm, err := c.readMemoryArg(wasm.AtomicInstructionName(atomicOp))
+		if err != nil {
+			return err
+		}
+		if atomicOp == wasm.OpcodeAtomicMemoryNotify {
+			// There are no waiters as there is only one thread. Only check the address is in bounds, then return
+			// zero as the count of woken waiters.
+			c.emit(
+				&OperationDrop{Depth: &InclusiveRange{Start: 0, End: 0}},
+				&OperationLoad{Type: UnsignedTypeI32, Arg: imm},
+				&OperationDrop{Depth: &InclusiveRange{Start: 0, End: 0}},
+				&OperationConstI32{Value: 0},
+			)
+			break operatorSwitch
+		}
+		if err = c.emitAtomic(atomicOp, imm); err != nil {
+			return err
+		}
 	case wasm.OpcodeVecPrefix:
 		c.pc++
 		switch vecOp := c.body[c.pc]; vecOp {
@@ -2379,11 +2411,48 @@ operatorSwitch:
 		return fmt.Errorf("unsupported instruction in wazeroir: 0x%x", op)
 	}
 
+	if c.deterministicNaN {
+		c.emitCanonicalNaN(op)
+	}
+
 	// Move the program counter to point to the next instruction.
 	c.pc++
 	return nil
 }
 
+// emitCanonicalNaN replaces the result of the floating-point arithmetic instruction with the canonical NaN when it
+// is any NaN, as the spec otherwise allows an arbitrary payload. Other instructions are ignored.
+//
+// No new operation is needed, as this is the same as the below, where x is the result on top of the stack:
+//
+//	(select (local.get x) (f32.const nan) (f32.eq (local.get x) (local.get x)))
+//
+// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#nan-propagation%E2%91%A0
+func (c *compiler) emitCanonicalNaN(op wasm.Opcode) {
+	var t UnsignedType
+	var nan Operation
+	switch op {
+	case wasm.OpcodeF32Add, wasm.OpcodeF32Sub, wasm.OpcodeF32Mul, wasm.OpcodeF32Div, wasm.OpcodeF32Sqrt,
+		wasm.OpcodeF32Min, wasm.OpcodeF32Max, wasm.OpcodeF32Ceil, wasm.OpcodeF32Floor, wasm.OpcodeF32Trunc,
+		wasm.OpcodeF32Nearest, wasm.OpcodeF32DemoteF64:
+		t, nan = UnsignedTypeF32, &OperationConstF32{Value: math.Float32frombits(moremath.F32CanonicalNaNBits)}
+	case wasm.OpcodeF64Add, wasm.OpcodeF64Sub, wasm.OpcodeF64Mul, wasm.OpcodeF64Div, wasm.OpcodeF64Sqrt,
+		wasm.OpcodeF64Min, wasm.OpcodeF64Max, wasm.OpcodeF64Ceil, wasm.OpcodeF64Floor, wasm.OpcodeF64Trunc,
+		wasm.OpcodeF64Nearest, wasm.OpcodeF64PromoteF32:
+		t, nan = UnsignedTypeF64, &OperationConstF64{Value: math.Float64frombits(moremath.F64CanonicalNaNBits)}
+	default:
+		return
+	}
+	// Depths are relative to the top of the stack, which grows from [x] to [x, nan, x, x], then shrinks back to [x].
+	c.emit(
+		nan,
+		&OperationPick{Depth: 1},
+		&OperationPick{Depth: 2},
+		&OperationEq{Type: t},
+		&OperationSelect{},
+	)
+}
+
 func (c *compiler) nextID() (id uint32) {
 	id = c.currentID + 1
 	c.currentID++
@@ -2579,6 +2648,115 @@ func (c *compiler) stackLenInUint64(ceil int) (ret int) {
 	return
 }
 
+// emitAtomic emits the operations for the given atomic load, store or read-modify-write instruction.
+//
+// Atomic instructions have single-threaded semantics as there is no shared memory, so they are lowered into plain
+// loads and stores. Notably, misaligned accesses don't trap, same as non-atomic memory instructions.
+func (c *compiler) emitAtomic(atomicOp wasm.OpcodeAtomic, imm *MemoryArg) error {
+	t, size, ok := wasm.AtomicAccess(atomicOp)
+	if !ok {
+		return fmt.Errorf("unsupported atomic instruction in wazeroir: %s", wasm.AtomicInstructionName(atomicOp))
+	}
+
+	var unsignedType UnsignedType
+	var unsignedInt UnsignedInt
+	var signedInt SignedInt
+	if t == wasm.ValueTypeI32 {
+		unsignedType, unsignedInt, signedInt = UnsignedTypeI32, UnsignedInt32, SignedUint32
+	} else {
+		unsignedType, unsignedInt, signedInt = UnsignedTypeI64, UnsignedInt64, SignedUint64
+	}
+
+	var load, store Operation
+	var mask uint64
+	narrow := true // true if the access size is smaller than the value type.
+	switch {
+	case size == 1:
+		load, store = &OperationLoad8{Type: signedInt, Arg: imm}, &OperationStore8{Type: unsignedInt, Arg: imm}
+		mask = 0xff
+	case size == 2:
+		load, store = &OperationLoad16{Type: signedInt, Arg: imm}, &OperationStore16{Type: unsignedInt, Arg: imm}
+		mask = 0xffff
+	case size == 4 && t == wasm.ValueTypeI64:
+		load, store = &OperationLoad32{Signed: false, Arg: imm}, &OperationStore32{Arg: imm}
+		mask = 0xffffffff
+	default:
+		narrow = false
+		load, store = &OperationLoad{Type: unsignedType, Arg: imm}, &OperationStore{Type: unsignedType, Arg: imm}
+	}
+
+	switch {
+	case atomicOp <= wasm.OpcodeAtomicI64Load32U:
+		c.emit(load)
+	case atomicOp <= wasm.OpcodeAtomicI64Store32:
+		c.emit(store)
+	case atomicOp >= wasm.OpcodeAtomicI32RmwCmpxchg:
+		// [addr, expected, replacement] -> [loaded]
+		c.emit(
+			&OperationPick{Depth: 2}, // [a e r a]
+			load,                     // [a e r old]
+			&OperationPick{Depth: 1}, // [a e r old r]
+			&OperationPick{Depth: 1}, // [a e r old r old]
+			&OperationPick{Depth: 2}, // [a e r old r old old]
+			&OperationPick{Depth: 5}, // [a e r old r old old e]
+		)
+		if narrow {
+			// The expected value is compared after wrapping it to the accessed size.
+			if t == wasm.ValueTypeI32 {
+				c.emit(&OperationConstI32{Value: uint32(mask)})
+			} else {
+				c.emit(&OperationConstI64{Value: mask})
+			}
+			c.emit(&OperationAnd{Type: unsignedInt})
+		}
+		c.emit(
+			&OperationEq{Type: unsignedType}, // [a e r old r old (old==e)]
+			&OperationSelect{},               // [a e r old new]
+			&OperationPick{Depth: 4},         // [a e r old new a]
+			&OperationSwap{Depth: 1},         // [a e r old a new]
+			store,                            // [a e r old]
+			&OperationDrop{Depth: &InclusiveRange{Start: 1, End: 3}}, // [old]
+		)
+	case atomicOp >= wasm.OpcodeAtomicI32RmwXchg:
+		// [addr, value] -> [loaded]
+		c.emit(
+			&OperationPick{Depth: 1}, // [a v a]
+			load,                     // [a v old]
+			&OperationPick{Depth: 2}, // [a v old a]
+			&OperationPick{Depth: 2}, // [a v old a v]
+			store,                    // [a v old]
+			&OperationDrop{Depth: &InclusiveRange{Start: 1, End: 2}}, // [old]
+		)
+	default:
+		// [addr, value] -> [loaded]
+		var binop Operation
+		switch {
+		case atomicOp >= wasm.OpcodeAtomicI32RmwXor:
+			binop = &OperationXor{Type: unsignedInt}
+		case atomicOp >= wasm.OpcodeAtomicI32RmwOr:
+			binop = &OperationOr{Type: unsignedInt}
+		case atomicOp >= wasm.OpcodeAtomicI32RmwAnd:
+			binop = &OperationAnd{Type: unsignedInt}
+		case atomicOp >= wasm.OpcodeAtomicI32RmwSub:
+			binop = &OperationSub{Type: unsignedType}
+		default:
+			binop = &OperationAdd{Type: unsignedType}
+		}
+		c.emit(
+			&OperationPick{Depth: 1}, // [a v a]
+			load,                     // [a v old]
+			&OperationPick{Depth: 0}, // [a v old old]
+			&OperationPick{Depth: 2}, // [a v old old v]
+			binop,                    // [a v old new]
+			&OperationPick{Depth: 3}, // [a v old new a]
+			&OperationSwap{Depth: 1}, // [a v old a new]
+			store,                    // [a v old]
+			&OperationDrop{Depth: &InclusiveRange{Start: 1, End: 2}}, // [old]
+		)
+	}
+	return nil
+}
+
 func (c *compiler) readMemoryArg(tag string) (*MemoryArg, error) {
 	r := bytes.NewReader(c.body[c.pc+1:])
 	alignment, num, err := leb128.DecodeUint32(r)
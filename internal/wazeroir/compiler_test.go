@@ -8,6 +8,7 @@ import (
 
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/moremath"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
 	binaryformat "github.com/tetratelabs/wazero/internal/wasm/binary"
@@ -571,6 +572,43 @@ func TestCompile_NonTrappingFloatToIntConversion(t *testing.T) {
 	require.Equal(t, expected, res[0])
 }
 
+func TestCompile_DeterministicNaN(t *testing.T) {
+	f32_f32 := &wasm.FunctionType{Params: []wasm.ValueType{f32}, Results: []wasm.ValueType{f32}, ParamNumInUint64: 1, ResultNumInUint64: 1}
+	module := &wasm.Module{
+		TypeSection:     []*wasm.FunctionType{f32_f32},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []*wasm.Code{{Body: []byte{
+			wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 0, wasm.OpcodeF32Add, wasm.OpcodeF32Neg, wasm.OpcodeEnd,
+		}}},
+		DeterministicNaN: true,
+	}
+
+	res, err := CompileFunctions(ctx, wasm.Features20191205, module)
+	require.NoError(t, err)
+
+	ops := res[0].Operations
+	require.Equal(t, 11, len(ops))
+	require.Equal(t, []Operation{
+		&OperationPick{Depth: 0},             // [$0, $0]
+		&OperationPick{Depth: 1},             // [$0, $0, $0]
+		&OperationAdd{Type: UnsignedTypeF32}, // [$0, $x]
+	}, ops[:3])
+
+	// The result of f32.add is replaced with the canonical NaN if it isn't equal to itself.
+	nan, ok := ops[3].(*OperationConstF32) // [$0, $x, nan]
+	require.True(t, ok)
+	require.Equal(t, moremath.F32CanonicalNaNBits, math.Float32bits(nan.Value))
+	require.Equal(t, []Operation{
+		&OperationPick{Depth: 1},                                 // [$0, $x, nan, $x]
+		&OperationPick{Depth: 2},                                 // [$0, $x, nan, $x, $x]
+		&OperationEq{Type: UnsignedTypeF32},                      // [$0, $x, nan, $x == $x]
+		&OperationSelect{},                                       // [$0, $x']
+		&OperationNeg{Type: Float32},                             // [$0, f32.neg($x')], which isn't arithmetic, so isn't canonicalized.
+		&OperationDrop{Depth: &InclusiveRange{Start: 1, End: 1}}, // [f32.neg($x')]
+	}, ops[4:10])
+	require.Equal(t, &OperationBr{Target: &BranchTarget{}}, ops[10])
+}
+
 // TestCompile_SignExtensionOps picks an arbitrary operator from "sign-extension-ops".
 func TestCompile_SignExtensionOps(t *testing.T) {
 	module := requireModuleText(t, `(module
@@ -470,8 +470,9 @@ const (
 	OperationKindV128Shr
 	OperationKindV128Cmp
 
-	// operationKindEnd is always placed at the bottom of this iota definition to be used in the test.
-	operationKindEnd
+	// OperationKindEnd is always placed at the bottom of this iota definition. It is used in the test and to size
+	// arrays indexed by OperationKind.
+	OperationKindEnd
 )
 
 type Label struct {
@@ -8,7 +8,7 @@ import (
 
 // TestInstructionName ensures that all the operation kind's stringer is well-defined.
 func TestOperationKind_String(t *testing.T) {
-	for k := OperationKind(0); k < operationKindEnd; k++ {
+	for k := OperationKind(0); k < OperationKindEnd; k++ {
 		require.NotEqual(t, "", k.String())
 	}
 }
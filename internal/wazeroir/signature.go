@@ -110,6 +110,10 @@ var (
 	signature_I32I64_None = &signature{
 		in: []UnsignedType{UnsignedTypeI32, UnsignedTypeI64},
 	}
+	signature_I32I64_I64 = &signature{
+		in:  []UnsignedType{UnsignedTypeI32, UnsignedTypeI64},
+		out: []UnsignedType{UnsignedTypeI64},
+	}
 	signature_I32F32_None = &signature{
 		in: []UnsignedType{UnsignedTypeI32, UnsignedTypeF32},
 	}
@@ -150,6 +154,14 @@ var (
 	signature_I32I64I32_None = &signature{
 		in: []UnsignedType{UnsignedTypeI32, UnsignedTypeI64, UnsignedTypeI32},
 	}
+	signature_I32I32I32_I32 = &signature{
+		in:  []UnsignedType{UnsignedTypeI32, UnsignedTypeI32, UnsignedTypeI32},
+		out: []UnsignedType{UnsignedTypeI32},
+	}
+	signature_I32I64I64_I64 = &signature{
+		in:  []UnsignedType{UnsignedTypeI32, UnsignedTypeI64, UnsignedTypeI64},
+		out: []UnsignedType{UnsignedTypeI64},
+	}
 	signature_UnknownUnknownI32_Unknown = &signature{
 		in:  []UnsignedType{UnsignedTypeUnknown, UnsignedTypeUnknown, UnsignedTypeI32},
 		out: []UnsignedType{UnsignedTypeUnknown},
@@ -550,6 +562,41 @@ func (c *compiler) wasmOpcodeSignature(op wasm.Opcode, index uint32) (*signature
 		default:
 			return nil, fmt.Errorf("unsupported vector instruction in wazeroir: %s", wasm.VectorInstructionName(vecOp))
 		}
+	case wasm.OpcodeAtomicPrefix:
+		switch atomicOp := c.body[c.pc+1]; atomicOp {
+		case wasm.OpcodeAtomicFence:
+			return signature_None_None, nil
+		case wasm.OpcodeAtomicMemoryNotify:
+			return signature_I32I32_I32, nil
+		default:
+			t, _, ok := wasm.AtomicAccess(atomicOp)
+			if !ok {
+				return nil, fmt.Errorf("unsupported atomic instruction in wazeroir: %s", wasm.AtomicInstructionName(atomicOp))
+			}
+			i64 := t == wasm.ValueTypeI64
+			switch {
+			case atomicOp <= wasm.OpcodeAtomicI64Load32U:
+				if i64 {
+					return signature_I32_I64, nil
+				}
+				return signature_I32_I32, nil
+			case atomicOp <= wasm.OpcodeAtomicI64Store32:
+				if i64 {
+					return signature_I32I64_None, nil
+				}
+				return signature_I32I32_None, nil
+			case atomicOp < wasm.OpcodeAtomicI32RmwCmpxchg:
+				if i64 {
+					return signature_I32I64_I64, nil
+				}
+				return signature_I32I32_I32, nil
+			default:
+				if i64 {
+					return signature_I32I64I64_I64, nil
+				}
+				return signature_I32I32I32_I32, nil
+			}
+		}
 	default:
 		return nil, fmt.Errorf("unsupported instruction in wazeroir: 0x%x", op)
 	}
@@ -11,6 +11,15 @@ import (
 	"github.com/tetratelabs/wazero/sys"
 )
 
+// Function names of the WASI application ABI.
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/design/application-abi.md#current-unstable-abi
+const (
+	// functionStart is exported by a command, which is instantiated to run once.
+	functionStart = "_start"
+	// functionInitialize is exported by a reactor, which is instantiated to call its other exports.
+	functionInitialize = "_initialize"
+)
+
 // Namespace contains instantiated modules, which cannot conflict until they are closed.
 type Namespace interface {
 	// Module returns exports from an instantiated module in this namespace or nil if there aren't any.
@@ -28,6 +37,37 @@ type Namespace interface {
 	//	* The module has a start function, and it failed to execute.
 	InstantiateModule(ctx context.Context, compiled CompiledModule, config ModuleConfig) (api.Module, error)
 
+	// NewSharedMemory instantiates a module named moduleName that only exports linear memory as memoryName. Any module
+	// instantiated afterwards in this namespace can import it, so that they all share the same memory.
+	// When the context is nil, it defaults to context.Background.
+	//
+	// Ex. This defines the "env" "memory" convention used by dynamically linked modules:
+	//	mem, _ := n.NewSharedMemory(ctx, "env", "memory", 1, 10)
+	//
+	//	// Both modules below import memory like `(import "env" "memory" (memory 1))`
+	//	main, _ := n.InstantiateModule(ctx, mainCompiled, wazero.NewModuleConfig())
+	//	side, _ := n.InstantiateModule(ctx, sideCompiled, wazero.NewModuleConfig())
+	//
+	// Notes
+	//
+	//	* This is a convenience for ModuleBuilder.ExportMemoryWithMax, so the same validation applies to the page counts.
+	//	* "memory.grow" in any importing module grows the result. Any slice previously returned by api.Memory Read
+	//	functions must be read again after growth, as it may no longer alias the underlying memory.
+	//	* Closing the Namespace closes the module that defines the memory.
+	NewSharedMemory(ctx context.Context, moduleName, memoryName string, minPages, maxPages uint32) (api.Memory, error)
+
+	// CloseModule closes only the module instantiated in this Namespace as moduleName, with the provided exit code, or
+	// errs if there isn't one. Other modules, including those importing it, are not closed.
+	// When the context is nil, it defaults to context.Background.
+	//
+	// Ex. to replace a module that misbehaved, while the others keep running:
+	//	_ = n.CloseModule(ctx, "plugin", 1)
+	//	plugin, _ := n.InstantiateModule(ctx, compiled, wazero.NewModuleConfig().WithName("plugin"))
+	//
+	// This is the same as calling api.Module CloseWithExitCode: any ModuleConfig.WithCloseNotifier is notified, and the
+	// name is available to instantiate again, so Module returns nil for it.
+	CloseModule(ctx context.Context, moduleName string, exitCode uint32) error
+
 	// CloseWithExitCode closes all modules initialized in this Namespace with the provided exit code.
 	// An error is returned if any module returns an error when closed.
 	//
@@ -52,6 +92,7 @@ type Namespace interface {
 
 // namespace allows decoupling of public interfaces from internal representation.
 type namespace struct {
+	r     *runtime
 	store *wasm.Store
 	ns    *wasm.Namespace
 }
@@ -109,22 +150,91 @@ func (ns *namespace) InstantiateModule(
 		mod.(*wasm.CallContext).CodeCloser = code
 	}
 
+	// Attach the notifier before any start functions, as they may close the module, ex. via "proc_exit".
+	if config.closeNotifier != nil {
+		mod.(*wasm.CallContext).CloseNotifier = config.closeNotifier
+	}
+
+	// Attach the trap handler before any start functions, as they may trap.
+	if h := ns.r.trapHandler; h != nil {
+		callCtx := mod.(*wasm.CallContext)
+		callCtx.TrapHandler = func(ctx context.Context, err error) {
+			h(newTrapInfo(ctx, callCtx, err))
+		}
+	}
+
+	// Only hook memory defined by this module, as an imported one is shared with the module that exports it.
+	if config.memoryGrowHook != nil && code.module.MemorySection != nil {
+		mod.Memory().(*wasm.MemoryInstance).GrowHook = config.memoryGrowHook
+	}
+
+	if config.maxTableElements != nil {
+		mod.(*wasm.CallContext).LimitTableGrowth(code.module, *config.maxTableElements)
+	}
+
+	if err = startModule(ctx, config, mod); err != nil {
+		_ = mod.Close(ctx) // Don't leak the module on error.
+	}
+	return
+}
+
+// NewSharedMemory implements Namespace.NewSharedMemory
+func (ns *namespace) NewSharedMemory(
+	ctx context.Context,
+	moduleName, memoryName string,
+	minPages, maxPages uint32,
+) (api.Memory, error) {
+	mod, err := ns.r.NewModuleBuilder(moduleName).
+		ExportMemoryWithMax(memoryName, minPages, maxPages).
+		Instantiate(ctx, ns)
+	if err != nil {
+		return nil, err
+	}
+	return mod.ExportedMemory(memoryName), nil
+}
+
+// startModule initializes memory with any ModuleConfig.WithMemoryInit, then invokes any start functions, failing at
+// first error.
+//
+// When ModuleConfig.WithStartFunctions wasn't set, the start function is chosen per the WASI application ABI: "_start"
+// for a command, or "_initialize" for a reactor. A reactor's other exports fail until "_initialize" returns.
+func startModule(ctx context.Context, config *moduleConfig, mod api.Module) error {
+	startFunctions := config.startFunctions
+	if mod.ExportedFunction(functionStart) == nil && mod.ExportedFunction(functionInitialize) != nil {
+		mod.(*wasm.CallContext).RequireInitialize(functionInitialize)
+		if startFunctions == nil {
+			startFunctions = []string{functionInitialize}
+		}
+	} else if startFunctions == nil {
+		startFunctions = []string{functionStart}
+	}
+
+	// Initialize memory before any start functions can read it.
+	if mem := mod.Memory(); config.memoryInit != nil && mem != nil {
+		if err := config.memoryInit(ctx, mem); err != nil {
+			return fmt.Errorf("module[%s] memory init failed: %w", mod.Name(), err)
+		}
+	}
+
 	// Now, invoke any start functions, failing at first error.
-	for _, fn := range config.startFunctions {
+	for _, fn := range startFunctions {
 		start := mod.ExportedFunction(fn)
 		if start == nil {
 			continue
 		}
-		if _, err = start.Call(ctx); err != nil {
-			_ = mod.Close(ctx) // Don't leak the module on error.
-			if _, ok = err.(*sys.ExitError); ok {
-				return // Don't wrap an exit error
+		if _, err := start.Call(ctx); err != nil {
+			if _, ok := err.(*sys.ExitError); ok {
+				return err // Don't wrap an exit error
 			}
-			err = fmt.Errorf("module[%s] function[%s] failed: %w", name, fn, err)
-			return
+			return fmt.Errorf("module[%s] function[%s] failed: %w", mod.Name(), fn, err)
 		}
 	}
-	return
+	return nil
+}
+
+// CloseModule implements Namespace.CloseModule
+func (ns *namespace) CloseModule(ctx context.Context, moduleName string, exitCode uint32) error {
+	return ns.ns.CloseModule(ctx, moduleName, exitCode)
 }
 
 // Close implements api.Closer embedded in Namespace.
@@ -1,10 +1,14 @@
 package wazero
 
 import (
+	"context"
 	_ "embed"
 	"testing"
 
 	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	binaryformat "github.com/tetratelabs/wazero/internal/wasm/binary"
+	"github.com/tetratelabs/wazero/sys"
 )
 
 // TestRuntime_Namespace ensures namespaces are independent.
@@ -59,3 +63,191 @@ func TestRuntime_Namespace(t *testing.T) {
 	require.Nil(t, r.Module("env"))
 	require.Nil(t, ns1.Module("env"))
 }
+
+// TestNamespace_CloseModule ensures only the module named is closed.
+func TestNamespace_CloseModule(t *testing.T) {
+	r := NewRuntime()
+	defer r.Close(testCtx)
+
+	// The module exports a function which returns 42.
+	compiled, err := r.CompileModule(testCtx, binaryformat.EncodeModule(&wasm.Module{
+		TypeSection:     []*wasm.FunctionType{{Results: []wasm.ValueType{wasm.ValueTypeI32}}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []*wasm.Code{{Body: []byte{wasm.OpcodeI32Const, 42, wasm.OpcodeEnd}}},
+		ExportSection:   []*wasm.Export{{Name: "answer", Type: wasm.ExternTypeFunc, Index: 0}},
+	}), NewCompileConfig())
+	require.NoError(t, err)
+
+	var notified *uint32
+	one, err := r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithName("one").
+		WithCloseNotifier(func(ctx context.Context, exitCode uint32) { notified = &exitCode }))
+	require.NoError(t, err)
+	two, err := r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithName("two"))
+	require.NoError(t, err)
+
+	require.NoError(t, r.CloseModule(testCtx, "one", 3))
+	require.Equal(t, uint32(3), *notified)
+
+	// The closed module is gone, and can't be called anymore.
+	require.Nil(t, r.Module("one"))
+	_, err = one.ExportedFunction("answer").Call(testCtx)
+	require.Equal(t, sys.NewExitError("one", 3), err)
+
+	// The other module still works.
+	require.Equal(t, two, r.Module("two"))
+	results, err := two.ExportedFunction("answer").Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{42}, results)
+
+	// A module can't be closed twice, but its name can be used again.
+	require.EqualError(t, r.CloseModule(testCtx, "one", 0), "module[one] not instantiated")
+	_, err = r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithName("one"))
+	require.NoError(t, err)
+
+	// Only modules in the same namespace are found.
+	ns := r.NewNamespace(testCtx)
+	require.EqualError(t, ns.CloseModule(testCtx, "two", 0), "module[two] not instantiated")
+	require.NotNil(t, r.Module("two"))
+}
+
+// TestNamespace_NewSharedMemory ensures modules importing the same memory see each other's writes and growth.
+func TestNamespace_NewSharedMemory(t *testing.T) {
+	i32 := wasm.ValueTypeI32
+	i32_i32 := &wasm.FunctionType{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}
+	importMemory := &wasm.Import{Type: wasm.ExternTypeMemory, Module: "env", Name: "memory", DescMem: &wasm.Memory{Min: 1}}
+
+	// writer exports functions to store an i32 and grow the memory it imports from "env".
+	writerWasm := binaryformat.EncodeModule(&wasm.Module{
+		TypeSection: []*wasm.FunctionType{
+			{Params: []wasm.ValueType{i32, i32}}, // store
+			i32_i32,                              // grow
+		},
+		ImportSection:   []*wasm.Import{importMemory},
+		FunctionSection: []wasm.Index{0, 1},
+		CodeSection: []*wasm.Code{
+			{Body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeI32Store, 0x2, 0x0, // alignment=2 (natural alignment) staticOffset=0
+				wasm.OpcodeEnd,
+			}},
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeMemoryGrow, 0, wasm.OpcodeEnd}},
+		},
+		ExportSection: []*wasm.Export{
+			{Name: "store", Type: wasm.ExternTypeFunc, Index: 0},
+			{Name: "grow", Type: wasm.ExternTypeFunc, Index: 1},
+		},
+		NameSection: &wasm.NameSection{ModuleName: "writer"},
+	})
+
+	// reader exports functions to load an i32 and the size of the memory it imports from "env". It also imports the
+	// writer's grow function to ensure growth during a call is visible to the caller.
+	readerWasm := binaryformat.EncodeModule(&wasm.Module{
+		TypeSection: []*wasm.FunctionType{
+			i32_i32,                          // load, grow_and_load and the imported grow
+			{Results: []wasm.ValueType{i32}}, // size
+		},
+		ImportSection: []*wasm.Import{
+			importMemory,
+			{Type: wasm.ExternTypeFunc, Module: "writer", Name: "grow", DescFunc: 0},
+		},
+		FunctionSection: []wasm.Index{0, 1, 0},
+		CodeSection: []*wasm.Code{
+			{Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI32Load, 0x2, 0x0, // alignment=2 (natural alignment) staticOffset=0
+				wasm.OpcodeEnd,
+			}},
+			{Body: []byte{wasm.OpcodeMemorySize, 0, wasm.OpcodeEnd}},
+			{Body: []byte{
+				wasm.OpcodeI32Const, 1, wasm.OpcodeCall, 0, wasm.OpcodeDrop,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeI32Load, 0x2, 0x0, // alignment=2 (natural alignment) staticOffset=0
+				wasm.OpcodeEnd,
+			}},
+		},
+		ExportSection: []*wasm.Export{
+			{Name: "load", Type: wasm.ExternTypeFunc, Index: 1},
+			{Name: "size", Type: wasm.ExternTypeFunc, Index: 2},
+			{Name: "grow_and_load", Type: wasm.ExternTypeFunc, Index: 3},
+		},
+		NameSection: &wasm.NameSection{ModuleName: "reader"},
+	})
+
+	for _, tt := range []struct {
+		name   string
+		config RuntimeConfig
+	}{
+		{name: "default", config: NewRuntimeConfig()},
+		{name: "interpreter", config: NewRuntimeConfigInterpreter()},
+	} {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRuntimeWithConfig(tc.config)
+			defer r.Close(testCtx)
+
+			ns := r.NewNamespace(testCtx)
+			mem, err := ns.NewSharedMemory(testCtx, "env", "memory", 1, 3)
+			require.NoError(t, err)
+			require.Equal(t, uint32(wasm.MemoryPageSize), mem.Size(testCtx))
+
+			// The memory can't be redefined in the same namespace.
+			_, err = ns.NewSharedMemory(testCtx, "env", "memory", 1, 3)
+			require.EqualError(t, err, "module[env] has already been instantiated")
+
+			writerCompiled, err := r.CompileModule(testCtx, writerWasm, NewCompileConfig())
+			require.NoError(t, err)
+			writer, err := ns.InstantiateModule(testCtx, writerCompiled, NewModuleConfig())
+			require.NoError(t, err)
+
+			readerCompiled, err := r.CompileModule(testCtx, readerWasm, NewCompileConfig())
+			require.NoError(t, err)
+			reader, err := ns.InstantiateModule(testCtx, readerCompiled, NewModuleConfig())
+			require.NoError(t, err)
+
+			// One module writes, and the other reads the same address.
+			_, err = writer.ExportedFunction("store").Call(testCtx, 8, 42)
+			require.NoError(t, err)
+			results, err := reader.ExportedFunction("load").Call(testCtx, 8)
+			require.NoError(t, err)
+			require.Equal(t, uint64(42), results[0])
+
+			// The host sees the same memory.
+			v, ok := mem.ReadUint32Le(testCtx, 8)
+			require.True(t, ok)
+			require.Equal(t, uint32(42), v)
+
+			// Growth by one module is visible to the other.
+			results, err = writer.ExportedFunction("grow").Call(testCtx, 1)
+			require.NoError(t, err)
+			require.Equal(t, uint64(1), results[0]) // previous page count
+			results, err = reader.ExportedFunction("size").Call(testCtx)
+			require.NoError(t, err)
+			require.Equal(t, uint64(2), results[0])
+			require.Equal(t, uint32(2*wasm.MemoryPageSize), mem.Size(testCtx))
+
+			// Writes to the new page are visible to the other module.
+			_, err = writer.ExportedFunction("store").Call(testCtx, uint64(wasm.MemoryPageSize+8), 43)
+			require.NoError(t, err)
+			results, err = reader.ExportedFunction("load").Call(testCtx, uint64(wasm.MemoryPageSize+8))
+			require.NoError(t, err)
+			require.Equal(t, uint64(43), results[0])
+
+			// Growth inside a call to another module is visible when it returns: this would trap if the reader used
+			// a cached length of its memory.
+			results, err = reader.ExportedFunction("grow_and_load").Call(testCtx, uint64(2*wasm.MemoryPageSize+8))
+			require.NoError(t, err)
+			require.Equal(t, uint64(0), results[0])
+			require.Equal(t, uint32(3*wasm.MemoryPageSize), mem.Size(testCtx))
+
+			// Restoring a snapshot of one module leaves the shared memory as is, as the other module uses it, too.
+			snap := writer.Snapshot()
+			_, err = writer.ExportedFunction("store").Call(testCtx, 8, 44)
+			require.NoError(t, err)
+			require.NoError(t, snap.Restore(testCtx))
+			results, err = reader.ExportedFunction("load").Call(testCtx, 8)
+			require.NoError(t, err)
+			require.Equal(t, uint64(44), results[0])
+			require.Equal(t, uint32(3*wasm.MemoryPageSize), mem.Size(testCtx))
+		})
+	}
+}
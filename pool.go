@@ -0,0 +1,201 @@
+package wazero
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// InstancePool reuses instances of the same CompiledModule, which avoids the cost of instantiating it each time.
+//
+// Ex.
+//	pool := wazero.NewInstancePool(r, compiled, wazero.NewModuleConfig())
+//	defer pool.Close(ctx)
+//
+//	mod, _ := pool.Get(ctx)
+//	defer pool.Put(ctx, mod)
+//
+// Notes
+//
+//	* Each instance is named after ModuleConfig.WithName, or the compiled module name, with a unique suffix, ex.
+//	  "env#1". Hence, instances are not meant to be imported by other modules.
+//	* Tables are not reset, so modules that modify tables, ex. via "table.set", should not be pooled.
+//	* ModuleConfig.WithCloseStdio is ignored, as all instances share the writers of the ModuleConfig.
+//	* ModuleConfig.WithSocket is not supported, as a connection can't be shared: Get returns an error.
+type InstancePool interface {
+	// Get returns an instance that is idle in the pool, or instantiates a new one if there are none.
+	//
+	// Either way, the instance has the state of a new one: memory and globals are initialized, and start functions
+	// configured with ModuleConfig.WithStartFunctions have run.
+	Get(ctx context.Context) (api.Module, error)
+
+	// Put returns an instance from Get to the pool, after resetting it to the state of a new instance:
+	//
+	//	* Memory is zeroed, shrunk to its minimum size, then the data segments are applied again.
+	//	* Globals are set to their initial values.
+	//	* Files opened by the instance are closed, output buffered by ModuleConfig.WithBufferedStdout is flushed, and
+	//	  the ModuleConfig is applied again, ex. a new file descriptor table.
+	//	* The start function, any ModuleConfig.WithMemoryInit and ModuleConfig.WithStartFunctions are run again.
+	//
+	// If the instance was closed, ex. by "proc_exit" in "wasi_snapshot_preview1", or the pool was closed, the
+	// instance is discarded. If resetting failed, the instance is closed and the error is returned. Putting an instance
+	// which was already put, and not returned by Get since, is an error.
+	Put(ctx context.Context, mod api.Module) error
+
+	// Closer closes all instances idle in the pool. Instances not yet returned with Put are closed when they are.
+	api.Closer
+}
+
+// NewInstancePool returns an InstancePool which instantiates the compiled module in the given Namespace, ex. a Runtime,
+// with the given ModuleConfig.
+func NewInstancePool(ns Namespace, compiled CompiledModule, config ModuleConfig) InstancePool {
+	code, ok := compiled.(*compiledModule)
+	if !ok {
+		panic(fmt.Errorf("unsupported wazero.CompiledModule implementation: %#v", compiled))
+	}
+
+	c, ok := config.(*moduleConfig)
+	if !ok {
+		panic(fmt.Errorf("unsupported wazero.ModuleConfig implementation: %#v", config))
+	}
+
+	// Instances share the config, so one closing would close the stdout and stderr of all others.
+	if c.closeStdio {
+		cfg := *c // copy
+		cfg.closeStdio = false
+		c = &cfg
+	}
+
+	name := c.name
+	if name == "" && code.module.NameSection != nil {
+		name = code.module.NameSection.ModuleName
+	}
+	return &instancePool{ns: ns, compiled: code, config: c, name: name, live: map[*wasm.CallContext]bool{}}
+}
+
+// instancePool implements InstancePool
+type instancePool struct {
+	ns       Namespace
+	compiled *compiledModule
+	config   *moduleConfig
+	// name is the prefix of the name of each instance.
+	name string
+
+	// mux guards the fields below.
+	mux sync.Mutex
+	// count is the number of instances created so far, used to name them uniquely.
+	count uint64
+	// idle are instances that can be returned by Get.
+	idle []*wasm.CallContext
+	// live are all instances that are not closed, including idle ones. The value is true while the instance is in use,
+	// ex. from Get until Put, so that putting it twice doesn't hand it to two callers of Get.
+	live   map[*wasm.CallContext]bool
+	closed bool
+}
+
+// Get implements InstancePool.Get
+func (p *instancePool) Get(ctx context.Context) (api.Module, error) {
+	p.mux.Lock()
+	if p.closed {
+		p.mux.Unlock()
+		return nil, errors.New("instance pool closed")
+	} else if len(p.config.sockets) > 0 {
+		// Each instance would close the same connection on reset, leaving it closed for the next user.
+		p.mux.Unlock()
+		return nil, errors.New("sockets can't be pooled: ModuleConfig.WithSocket shares one connection")
+	}
+	if n := len(p.idle); n > 0 {
+		mod := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.live[mod] = true
+		p.mux.Unlock()
+		return mod, nil
+	}
+	p.count++
+	name := fmt.Sprintf("%s#%d", p.name, p.count)
+	p.mux.Unlock()
+
+	mod, err := p.ns.InstantiateModule(ctx, p.compiled, p.config.WithName(name))
+	if err != nil {
+		return nil, err
+	}
+
+	p.mux.Lock()
+	p.live[mod.(*wasm.CallContext)] = true
+	p.mux.Unlock()
+	return mod, nil
+}
+
+// Put implements InstancePool.Put
+func (p *instancePool) Put(ctx context.Context, mod api.Module) error {
+	callCtx, _ := mod.(*wasm.CallContext)
+
+	p.mux.Lock()
+	inUse, ok := p.live[callCtx]
+	if !ok {
+		p.mux.Unlock()
+		return fmt.Errorf("module[%s] is not from this pool", mod.Name())
+	} else if !inUse {
+		p.mux.Unlock()
+		return fmt.Errorf("module[%s] was already put", mod.Name())
+	}
+	p.live[callCtx] = false // reject another Put, even while this one resets the instance.
+	if p.closed || callCtx.FailIfClosed() != nil {
+		delete(p.live, callCtx)
+		p.mux.Unlock()
+		return mod.Close(ctx) // no-op if already closed
+	}
+	p.mux.Unlock()
+
+	if err := p.reset(ctx, callCtx); err != nil {
+		p.mux.Lock()
+		delete(p.live, callCtx)
+		p.mux.Unlock()
+		_ = mod.Close(ctx) // Don't leak the module on error.
+		return err
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if p.closed { // closed while resetting
+		delete(p.live, callCtx)
+		return mod.Close(ctx)
+	}
+	p.idle = append(p.idle, callCtx)
+	return nil
+}
+
+// reset restores the instance to the state of a new one.
+func (p *instancePool) reset(ctx context.Context, callCtx *wasm.CallContext) error {
+	sysCtx, err := p.config.toSysContext()
+	if err != nil {
+		return err
+	}
+	if err = callCtx.Reset(ctx, p.compiled.module, sysCtx); err != nil {
+		return fmt.Errorf("module[%s] reset failed: %w", callCtx.Name(), err)
+	}
+	return startModule(ctx, p.config, callCtx)
+}
+
+// Close implements api.Closer embedded in InstancePool.
+func (p *instancePool) Close(ctx context.Context) (err error) {
+	p.mux.Lock()
+	p.closed = true
+	idle := p.idle
+	p.idle = nil
+	for _, mod := range idle {
+		delete(p.live, mod)
+	}
+	p.mux.Unlock()
+
+	for _, mod := range idle {
+		if e := mod.Close(ctx); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
@@ -0,0 +1,320 @@
+package wazero
+
+import (
+	"net"
+	"testing"
+	"testing/fstest"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	binaryformat "github.com/tetratelabs/wazero/internal/wasm/binary"
+)
+
+// poolTestWasm has a data segment and a global, which its function "mutate" changes along with the memory size.
+var poolTestWasm = binaryformat.EncodeModule(&wasm.Module{
+	TypeSection:     []*wasm.FunctionType{{}},
+	FunctionSection: []wasm.Index{0},
+	CodeSection: []*wasm.Code{{Body: []byte{
+		// counter++
+		wasm.OpcodeGlobalGet, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Add, wasm.OpcodeGlobalSet, 0,
+		// memory[0] = 42
+		wasm.OpcodeI32Const, 0, wasm.OpcodeI32Const, 42, wasm.OpcodeI32Store8, 0x0, 0x0,
+		// memory.grow 1
+		wasm.OpcodeI32Const, 1, wasm.OpcodeMemoryGrow, 0, wasm.OpcodeDrop,
+		wasm.OpcodeEnd,
+	}}},
+	MemorySection: &wasm.Memory{Min: 1, Max: 2, IsMaxEncoded: true},
+	GlobalSection: []*wasm.Global{{
+		Type: &wasm.GlobalType{ValType: wasm.ValueTypeI32, Mutable: true},
+		Init: &wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{10}},
+	}},
+	DataSection: []*wasm.DataSegment{{
+		OffsetExpression: &wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}},
+		Init:             []byte{1},
+	}},
+	ExportSection: []*wasm.Export{
+		{Name: "mutate", Type: wasm.ExternTypeFunc, Index: 0},
+		{Name: "counter", Type: wasm.ExternTypeGlobal, Index: 0},
+		{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0},
+	},
+	NameSection: &wasm.NameSection{ModuleName: "pooled"},
+})
+
+func TestInstancePool(t *testing.T) {
+	r := NewRuntime()
+	defer r.Close(testCtx)
+
+	compiled, err := r.CompileModule(testCtx, poolTestWasm, NewCompileConfig())
+	require.NoError(t, err)
+
+	requireState := func(t *testing.T, mod api.Module, counter uint64, mem0 byte, memSize uint32) {
+		require.Equal(t, counter, mod.ExportedGlobal("counter").Get(testCtx))
+		b, ok := mod.Memory().ReadByte(testCtx, 0)
+		require.True(t, ok)
+		require.Equal(t, mem0, b)
+		require.Equal(t, memSize, mod.Memory().Size(testCtx))
+	}
+
+	t.Run("reused instance has a clean state", func(t *testing.T) {
+		pool := NewInstancePool(r, compiled, NewModuleConfig())
+		defer pool.Close(testCtx)
+
+		mod, err := pool.Get(testCtx)
+		require.NoError(t, err)
+		require.Equal(t, "pooled#1", mod.Name())
+		requireState(t, mod, 10, 1, wasm.MemoryPageSize)
+
+		_, err = mod.ExportedFunction("mutate").Call(testCtx)
+		require.NoError(t, err)
+		requireState(t, mod, 11, 42, 2*wasm.MemoryPageSize)
+
+		// Write past the data segment, to ensure memory is zeroed, not just re-initialized.
+		require.True(t, mod.Memory().WriteByte(testCtx, 1, 1))
+
+		require.NoError(t, pool.Put(testCtx, mod))
+
+		reused, err := pool.Get(testCtx)
+		require.NoError(t, err)
+		require.Equal(t, mod, reused)
+		requireState(t, reused, 10, 1, wasm.MemoryPageSize)
+		b, _ := reused.Memory().ReadByte(testCtx, 1)
+		require.Zero(t, b)
+	})
+
+	t.Run("memory past the minimum is zeroed", func(t *testing.T) {
+		pool := NewInstancePool(r, compiled, NewModuleConfig().WithName("grown"))
+		defer pool.Close(testCtx)
+
+		mod, err := pool.Get(testCtx)
+		require.NoError(t, err)
+		_, err = mod.ExportedFunction("mutate").Call(testCtx)
+		require.NoError(t, err)
+		require.True(t, mod.Memory().WriteUint32Le(testCtx, wasm.MemoryPageSize, 12345))
+
+		require.NoError(t, pool.Put(testCtx, mod))
+
+		// Growing the memory again must not expose what the previous user wrote.
+		reused, err := pool.Get(testCtx)
+		require.NoError(t, err)
+		_, ok := reused.Memory().Grow(testCtx, 1)
+		require.True(t, ok)
+		v, ok := reused.Memory().ReadUint32Le(testCtx, wasm.MemoryPageSize)
+		require.True(t, ok)
+		require.Zero(t, v)
+	})
+
+	t.Run("start functions run again", func(t *testing.T) {
+		pool := NewInstancePool(r, compiled, NewModuleConfig().WithName("started").WithStartFunctions("mutate"))
+		defer pool.Close(testCtx)
+
+		mod, err := pool.Get(testCtx)
+		require.NoError(t, err)
+		requireState(t, mod, 11, 42, 2*wasm.MemoryPageSize)
+
+		require.NoError(t, pool.Put(testCtx, mod))
+
+		reused, err := pool.Get(testCtx)
+		require.NoError(t, err)
+		requireState(t, reused, 11, 42, 2*wasm.MemoryPageSize)
+	})
+
+	t.Run("pre-opens are not shared", func(t *testing.T) {
+		pool := NewInstancePool(r, compiled, NewModuleConfig().WithName("fs").WithFS(fstest.MapFS{}))
+		defer pool.Close(testCtx)
+
+		requirePreopen := func(t *testing.T, mod api.Module) {
+			entry, ok := mod.(*wasm.CallContext).Sys.FS().OpenedFile(3)
+			require.True(t, ok)
+			require.Equal(t, "/", entry.Path)
+		}
+
+		mod, err := pool.Get(testCtx)
+		require.NoError(t, err)
+		sibling, err := pool.Get(testCtx)
+		require.NoError(t, err)
+		defer pool.Put(testCtx, sibling)
+
+		// Resetting one instance closes its files, which must not close those of the other.
+		require.NoError(t, pool.Put(testCtx, mod))
+		requirePreopen(t, sibling)
+
+		reused, err := pool.Get(testCtx)
+		require.NoError(t, err)
+		require.Equal(t, mod, reused)
+		requirePreopen(t, reused)
+	})
+
+	t.Run("closed instance is discarded", func(t *testing.T) {
+		pool := NewInstancePool(r, compiled, NewModuleConfig().WithName("closed"))
+		defer pool.Close(testCtx)
+
+		mod, err := pool.Get(testCtx)
+		require.NoError(t, err)
+		require.NoError(t, mod.Close(testCtx))
+		require.NoError(t, pool.Put(testCtx, mod))
+
+		next, err := pool.Get(testCtx)
+		require.NoError(t, err)
+		require.Equal(t, "closed#2", next.Name())
+	})
+
+	t.Run("Put rejects modules not from the pool", func(t *testing.T) {
+		pool := NewInstancePool(r, compiled, NewModuleConfig().WithName("foreign"))
+		defer pool.Close(testCtx)
+
+		mod, err := r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithName("notpooled"))
+		require.NoError(t, err)
+		defer mod.Close(testCtx)
+
+		require.EqualError(t, pool.Put(testCtx, mod), "module[notpooled] is not from this pool")
+	})
+
+	t.Run("Put rejects an instance put twice", func(t *testing.T) {
+		pool := NewInstancePool(r, compiled, NewModuleConfig().WithName("twice"))
+		defer pool.Close(testCtx)
+
+		mod, err := pool.Get(testCtx)
+		require.NoError(t, err)
+		require.NoError(t, pool.Put(testCtx, mod))
+		require.EqualError(t, pool.Put(testCtx, mod), "module[twice#1] was already put")
+
+		// The instance is only idle once, so the next two callers get different instances.
+		first, err := pool.Get(testCtx)
+		require.NoError(t, err)
+		second, err := pool.Get(testCtx)
+		require.NoError(t, err)
+		require.Equal(t, mod, first)
+		require.Equal(t, "twice#2", second.Name())
+
+		// Once returned by Get again, the instance can be put.
+		require.NoError(t, pool.Put(testCtx, first))
+		require.NoError(t, pool.Put(testCtx, second))
+	})
+
+	t.Run("stdio isn't closed", func(t *testing.T) {
+		out := &closeTrackingWriter{}
+		pool := NewInstancePool(r, compiled, NewModuleConfig().WithName("stdio").WithStdout(out).WithCloseStdio())
+
+		mod, err := pool.Get(testCtx)
+		require.NoError(t, err)
+		sibling, err := pool.Get(testCtx)
+		require.NoError(t, err)
+
+		// Instances share the writer, so neither closing one nor the pool closes it.
+		require.NoError(t, mod.Close(testCtx))
+		require.NoError(t, pool.Put(testCtx, mod))
+		require.NoError(t, pool.Put(testCtx, sibling))
+		require.NoError(t, pool.Close(testCtx))
+		require.Zero(t, out.closed)
+	})
+
+	t.Run("sockets can't be pooled", func(t *testing.T) {
+		conn, peer := net.Pipe()
+		defer conn.Close()
+		defer peer.Close()
+
+		pool := NewInstancePool(r, compiled, NewModuleConfig().WithName("socket").WithSocket(3, conn))
+		defer pool.Close(testCtx)
+
+		_, err := pool.Get(testCtx)
+		require.EqualError(t, err, "sockets can't be pooled: ModuleConfig.WithSocket shares one connection")
+	})
+
+	t.Run("Close closes idle instances", func(t *testing.T) {
+		pool := NewInstancePool(r, compiled, NewModuleConfig().WithName("close"))
+
+		idle, err := pool.Get(testCtx)
+		require.NoError(t, err)
+		inUse, err := pool.Get(testCtx)
+		require.NoError(t, err)
+		require.NoError(t, pool.Put(testCtx, idle))
+
+		require.NoError(t, pool.Close(testCtx))
+		require.Nil(t, r.Module(idle.Name()))
+		require.NotNil(t, r.Module(inUse.Name()))
+
+		// Instances in use are closed when returned.
+		require.NoError(t, pool.Put(testCtx, inUse))
+		require.Nil(t, r.Module(inUse.Name()))
+
+		_, err = pool.Get(testCtx)
+		require.EqualError(t, err, "instance pool closed")
+	})
+}
+
+// poolFuncrefWasm has a funcref global initialized to function zero, which "is_null" checks is still set.
+var poolFuncrefWasm = binaryformat.EncodeModule(&wasm.Module{
+	TypeSection:     []*wasm.FunctionType{{Results: []wasm.ValueType{wasm.ValueTypeI32}, ResultNumInUint64: 1}},
+	FunctionSection: []wasm.Index{0},
+	CodeSection: []*wasm.Code{{Body: []byte{
+		wasm.OpcodeGlobalGet, 0, wasm.OpcodeRefIsNull, wasm.OpcodeEnd,
+	}}},
+	GlobalSection: []*wasm.Global{{
+		Type: &wasm.GlobalType{ValType: wasm.ValueTypeFuncref},
+		Init: &wasm.ConstantExpression{Opcode: wasm.OpcodeRefFunc, Data: []byte{0}},
+	}},
+	ExportSection: []*wasm.Export{{Name: "is_null", Type: wasm.ExternTypeFunc, Index: 0}},
+})
+
+func TestInstancePool_FuncrefGlobal(t *testing.T) {
+	r := NewRuntimeWithConfig(NewRuntimeConfig().WithFeatureReferenceTypes(true))
+	defer r.Close(testCtx)
+
+	compiled, err := r.CompileModule(testCtx, poolFuncrefWasm, NewCompileConfig())
+	require.NoError(t, err)
+
+	pool := NewInstancePool(r, compiled, NewModuleConfig().WithName("funcref"))
+	defer pool.Close(testCtx)
+
+	for i := 0; i < 2; i++ { // the second time, the instance was reset.
+		mod, err := pool.Get(testCtx)
+		require.NoError(t, err)
+
+		results, err := mod.ExportedFunction("is_null").Call(testCtx)
+		require.NoError(t, err)
+		require.Equal(t, uint64(0), results[0])
+
+		require.NoError(t, pool.Put(testCtx, mod))
+	}
+}
+
+func BenchmarkInstancePool(b *testing.B) {
+	r := NewRuntime()
+	defer r.Close(testCtx)
+
+	compiled, err := r.CompileModule(testCtx, poolTestWasm, NewCompileConfig())
+	if err != nil {
+		b.Fatal(err)
+	}
+	config := NewModuleConfig().WithStartFunctions("mutate")
+
+	b.Run("InstantiateModule", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			mod, err := r.InstantiateModule(testCtx, compiled, config)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if err = mod.Close(testCtx); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("InstancePool", func(b *testing.B) {
+		pool := NewInstancePool(r, compiled, config)
+		defer pool.Close(testCtx)
+
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			mod, err := pool.Get(testCtx)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if err = pool.Put(testCtx, mod); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -3,10 +3,14 @@ package wazero
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/engine/interpreter"
 	"github.com/tetratelabs/wazero/internal/wasm"
 	binaryformat "github.com/tetratelabs/wazero/internal/wasm/binary"
 )
@@ -46,6 +50,23 @@ type Runtime interface {
 	// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#name-section%E2%91%A0
 	CompileModule(ctx context.Context, binary []byte, config CompileConfig) (CompiledModule, error)
 
+	// CompileModuleReader is like CompileModule, except it decodes the WebAssembly binary (%.wasm) as it is read.
+	//
+	// This avoids holding the entire binary in memory while decoding, which is helpful for large modules, ex. when
+	// read from a file or a network stream. Only one section, or one function in the code section, is buffered at a
+	// time. The result is the same as calling CompileModule with all bytes in the reader.
+	//
+	// Note: The reader is read until io.EOF, but not closed.
+	CompileModuleReader(ctx context.Context, reader io.Reader, config CompileConfig) (CompiledModule, error)
+
+	// ValidateModule decodes and validates the WebAssembly binary (%.wasm), returning an error if it is invalid.
+	//
+	// Validation is the same as CompileModule, including features enabled in the RuntimeConfig, but no code is
+	// compiled. This is faster than CompileModule when only the validity of a binary is needed, ex. in CI.
+	//
+	// Note: This doesn't check if imports can be resolved, as that's only known on InstantiateModule.
+	ValidateModule(ctx context.Context, binary []byte) error
+
 	// InstantiateModuleFromBinary instantiates a module from the WebAssembly binary (%.wasm) or errs if invalid.
 	// When the context is nil, it defaults to context.Background.
 	//
@@ -129,25 +150,45 @@ func NewRuntimeWithConfig(rConfig RuntimeConfig) Runtime {
 	if !ok {
 		panic(fmt.Errorf("unsupported wazero.RuntimeConfig implementation: %#v", rConfig))
 	}
-	store, ns := wasm.NewStore(config.enabledFeatures, config.newEngine(config.enabledFeatures))
-	return &runtime{
-		store:           store,
-		ns:              &namespace{store: store, ns: ns},
-		enabledFeatures: config.enabledFeatures,
+	if config.guestProfile != nil {
+		c := *config // copy, as the profiler is per-runtime
+		c.interpreterProfiler = interpreter.NewProfiler()
+		config = &c
+	}
+	store, ns := wasm.NewStore(config.enabledFeatures, config.newEngine(config))
+	store.StubMissingImports = config.stubMissingImports
+	r := &runtime{
+		store:            store,
+		enabledFeatures:  config.enabledFeatures,
+		deterministicNaN: config.deterministicNaN,
+		guestProfile:     config.guestProfile,
+		guestProfiler:    config.interpreterProfiler,
+		trapHandler:      config.trapHandler,
 	}
+	r.ns = &namespace{r: r, store: store, ns: ns}
+	return r
 }
 
 // runtime allows decoupling of public interfaces from internal representation.
 type runtime struct {
-	store           *wasm.Store
-	ns              *namespace
-	enabledFeatures wasm.Features
-	compiledModules []*compiledModule
+	store            *wasm.Store
+	ns               *namespace
+	enabledFeatures  wasm.Features
+	deterministicNaN bool
+	compiledModules  []*compiledModule
+
+	// guestProfile is where guestProfiler is written on close, or nil when not profiling.
+	guestProfile  io.Writer
+	guestProfiler *interpreter.Profiler
+
+	// trapHandler is called when a function called via an instantiated module traps, or nil when not set.
+	trapHandler func(TrapInfo)
 }
 
 // NewNamespace implements Runtime.NewNamespace.
 func (r *runtime) NewNamespace(ctx context.Context) Namespace {
 	return &namespace{
+		r:     r,
 		store: r.store,
 		ns:    r.store.NewNamespace(ctx),
 	}
@@ -158,6 +199,20 @@ func (r *runtime) Module(moduleName string) api.Module {
 	return r.ns.Module(moduleName)
 }
 
+// NewSharedMemory implements Namespace.NewSharedMemory embedded by Runtime.
+func (r *runtime) NewSharedMemory(
+	ctx context.Context,
+	moduleName, memoryName string,
+	minPages, maxPages uint32,
+) (api.Memory, error) {
+	return r.ns.NewSharedMemory(ctx, moduleName, memoryName, minPages, maxPages)
+}
+
+// CloseModule implements Namespace.CloseModule embedded by Runtime.
+func (r *runtime) CloseModule(ctx context.Context, moduleName string, exitCode uint32) error {
+	return r.ns.CloseModule(ctx, moduleName, exitCode)
+}
+
 // CompileModule implements Runtime.CompileModule
 func (r *runtime) CompileModule(ctx context.Context, binary []byte, cConfig CompileConfig) (CompiledModule, error) {
 	if binary == nil {
@@ -169,35 +224,147 @@ func (r *runtime) CompileModule(ctx context.Context, binary []byte, cConfig Comp
 		panic(fmt.Errorf("unsupported wazero.CompileConfig implementation: %#v", cConfig))
 	}
 
+	internal, err := r.decodeModule(binary, config)
+	if err != nil {
+		return nil, err
+	}
+
+	internal.AssignModuleID(binary)
+
+	return r.compileModule(ctx, internal)
+}
+
+// CompileModuleReader implements Runtime.CompileModuleReader
+func (r *runtime) CompileModuleReader(ctx context.Context, reader io.Reader, cConfig CompileConfig) (CompiledModule, error) {
+	if reader == nil {
+		return nil, errors.New("reader == nil")
+	}
+
+	config, ok := cConfig.(*compileConfig)
+	if !ok {
+		panic(fmt.Errorf("unsupported wazero.CompileConfig implementation: %#v", cConfig))
+	}
+
+	// Hash while decoding, so that the ID is the same as if the binary was passed to CompileModule.
+	hash := sha256.New()
+	internal, err := binaryformat.DecodeModuleReader(io.TeeReader(reader, hash), r.featuresOf(config), config.memorySizer)
+	if errors.Is(err, binaryformat.ErrInvalidMagicNumber) {
+		return nil, errors.New("invalid binary")
+	} else if err != nil {
+		return nil, err
+	} else if err = r.prepareModule(internal, config); err != nil {
+		return nil, err
+	}
+
+	hash.Sum(internal.ID[:0])
+
+	return r.compileModule(ctx, internal)
+}
+
+// compileModule compiles the decoded module and tracks it, so that it is released on Close.
+func (r *runtime) compileModule(ctx context.Context, internal *wasm.Module) (CompiledModule, error) {
+	// Compiled code is cached by ID, so derive a different one for each setting that changes the code. Otherwise, a
+	// later compilation of the same binary with different settings would reuse it, ex. code that doesn't check bounds
+	// when compiled with CompileConfig.WithTrustedMemory.
+	if internal.TrustedMemory {
+		internal.ID = sha256.Sum256(append(internal.ID[:], "trusted-memory"...))
+	}
+	if internal.FastCompile {
+		internal.ID = sha256.Sum256(append(internal.ID[:], "fast-compile"...))
+	}
+	if internal.EnabledFeatures != r.enabledFeatures {
+		var features [8]byte
+		binary.LittleEndian.PutUint64(features[:], uint64(internal.EnabledFeatures))
+		internal.ID = sha256.Sum256(append(append(internal.ID[:], "features"...), features[:]...))
+	}
+
+	if err := r.store.Engine.CompileModule(ctx, internal); err != nil {
+		return nil, err
+	}
+
+	c := &compiledModule{module: internal, compiledEngine: r.store.Engine}
+	r.compiledModules = append(r.compiledModules, c)
+	return c, nil
+}
+
+// ValidateModule implements Runtime.ValidateModule
+func (r *runtime) ValidateModule(_ context.Context, binary []byte) error {
+	if binary == nil {
+		return errors.New("binary == nil")
+	}
+	_, err := r.decodeModule(binary, NewCompileConfig().(*compileConfig))
+	return err
+}
+
+// decodeModule decodes and validates the binary, applying any configuration that changes the resulting module.
+func (r *runtime) decodeModule(binary []byte, config *compileConfig) (*wasm.Module, error) {
 	if len(binary) < 4 || !bytes.Equal(binary[0:4], binaryformat.Magic) {
 		return nil, errors.New("invalid binary")
 	}
 
-	internal, err := binaryformat.DecodeModule(binary, r.enabledFeatures, config.memorySizer)
+	internal, err := binaryformat.DecodeModule(binary, r.featuresOf(config), config.memorySizer)
 	if err != nil {
 		return nil, err
-	} else if err = internal.Validate(r.enabledFeatures); err != nil {
+	} else if err = r.prepareModule(internal, config); err != nil {
+		return nil, err
+	}
+	return internal, nil
+}
+
+// featuresOf returns the features to decode and validate a module with, which CompileConfig.WithFeatures overrides.
+func (r *runtime) featuresOf(config *compileConfig) wasm.Features {
+	if config.enabledFeatures != nil {
+		return *config.enabledFeatures
+	}
+	return r.enabledFeatures
+}
+
+// prepareModule validates the decoded module, applying any configuration that changes it.
+func (r *runtime) prepareModule(internal *wasm.Module, config *compileConfig) error {
+	internal.EnabledFeatures = r.featuresOf(config)
+	internal.FastCompile = config.optimizationLevel == OptimizationLevelMinimal
+	internal.DeterministicNaN = r.deterministicNaN
+	internal.TrustedMemory = config.trustedMemory
+
+	// Handle custom sections first, so that a module built for an unsupported target fails with the handler's error.
+	if handler := config.customSectionHandler; handler != nil {
+		for _, s := range internal.CustomSections {
+			if err := handler(s.Name, s.Data); err != nil {
+				return fmt.Errorf("custom section[%s]: %w", s.Name, err)
+			}
+		}
+	}
+
+	if err := internal.Validate(internal.EnabledFeatures); err != nil {
 		// TODO: decoders should validate before returning, as that allows
 		// them to err with the correct position in the wasm binary.
-		return nil, err
+		return err
 	}
 
 	// Replace imports if any configuration exists to do so.
 	if importRenamer := config.importRenamer; importRenamer != nil {
-		for _, i := range internal.ImportSection {
-			i.Module, i.Name = importRenamer(i.Type, i.Module, i.Name)
+		if err := renameImports(internal.ImportSection, importRenamer); err != nil {
+			return err
 		}
 	}
+	return nil
+}
 
-	internal.AssignModuleID(binary)
-
-	if err = r.store.Engine.CompileModule(ctx, internal); err != nil {
-		return nil, err
+// renameImports applies the importRenamer to each import, failing if two distinct imports end up with the same name.
+func renameImports(imports []*wasm.Import, importRenamer api.ImportRenamer) error {
+	type importName struct{ module, name string }
+	renamedFrom := make(map[importName]importName, len(imports))
+	for idx, i := range imports {
+		oldName := importName{i.Module, i.Name}
+		i.Module, i.Name = importRenamer(i.Type, i.Module, i.Name)
+		newName := importName{i.Module, i.Name}
+		if existing, ok := renamedFrom[newName]; ok && existing != oldName {
+			return fmt.Errorf("import[%d] renamed from %s.%s to %s.%s collides with the import renamed from %s.%s",
+				idx, oldName.module, oldName.name, newName.module, newName.name, existing.module, existing.name)
+		}
+		renamedFrom[newName] = oldName
 	}
-
-	c := &compiledModule{module: internal, compiledEngine: r.store.Engine}
-	r.compiledModules = append(r.compiledModules, c)
-	return c, nil
+	return nil
 }
 
 // InstantiateModuleFromBinary implements Runtime.InstantiateModuleFromBinary
@@ -232,5 +399,11 @@ func (r *runtime) CloseWithExitCode(ctx context.Context, exitCode uint32) error
 			err = e
 		}
 	}
+	if w := r.guestProfile; w != nil {
+		r.guestProfile = nil // Only write the profile once.
+		if e := r.guestProfiler.WriteProfile(w); e != nil && err == nil {
+			err = fmt.Errorf("failed to write guest profile: %w", e)
+		}
+	}
 	return err
 }
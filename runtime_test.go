@@ -1,14 +1,21 @@
 package wazero
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	_ "embed"
 	"errors"
+	"fmt"
+	"io"
 	"math"
+	goruntime "runtime"
 	"testing"
+	"testing/iotest"
 
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/platform"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
 	binaryformat "github.com/tetratelabs/wazero/internal/wasm/binary"
@@ -33,6 +40,71 @@ func TestNewRuntimeWithConfig_PanicsOnWrongImpl(t *testing.T) {
 	require.EqualError(t, err, "unsupported wazero.RuntimeConfig implementation: <nil>")
 }
 
+func TestNewRuntimeWithConfig_Engine(t *testing.T) {
+	tests := []struct {
+		name           string
+		config         RuntimeConfig
+		expectedEngine string
+	}{
+		{name: "compiler", config: NewRuntimeConfigCompiler(), expectedEngine: "*compiler.engine"},
+		{name: "interpreter", config: NewRuntimeConfigInterpreter(), expectedEngine: "*interpreter.engine"},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.name == "compiler" && !platform.CompilerSupported() {
+				t.Skip("compiler is not supported on this platform")
+			}
+
+			r := NewRuntimeWithConfig(tc.config)
+			defer r.Close(testCtx)
+
+			require.Equal(t, tc.expectedEngine, fmt.Sprintf("%T", r.(*runtime).store.Engine))
+
+			// Ensure the selected engine can compile and run code.
+			mod, err := r.InstantiateModuleFromBinary(testCtx, binaryformat.EncodeModule(&wasm.Module{
+				TypeSection:     []*wasm.FunctionType{{Results: []wasm.ValueType{wasm.ValueTypeI32}}},
+				FunctionSection: []wasm.Index{0},
+				CodeSection:     []*wasm.Code{{Body: []byte{wasm.OpcodeI32Const, 42, wasm.OpcodeEnd}}},
+				ExportSection:   []*wasm.Export{{Name: "answer", Type: wasm.ExternTypeFunc, Index: 0}},
+			}))
+			require.NoError(t, err)
+
+			results, err := mod.ExportedFunction("answer").Call(testCtx)
+			require.NoError(t, err)
+			require.Equal(t, uint64(42), results[0])
+		})
+	}
+}
+
+func TestNewRuntimeWithConfig_PanicsOnUnsupportedCompiler(t *testing.T) {
+	defer func(f func() bool) { compilerSupported = f }(compilerSupported)
+	compilerSupported = func() bool { return false }
+
+	err := require.CapturePanic(func() {
+		NewRuntimeWithConfig(NewRuntimeConfigCompiler())
+	})
+	require.EqualError(t, err, fmt.Sprintf("compiler is not supported on %s/%s: use NewRuntimeConfigInterpreter instead",
+		goruntime.GOOS, goruntime.GOARCH))
+
+	// The interpreter is always supported.
+	r := NewRuntimeWithConfig(NewRuntimeConfigInterpreter())
+	require.NoError(t, r.Close(testCtx))
+}
+
+func TestNewRuntimeWithConfig_CompilerIgnoresGuestProfiler(t *testing.T) {
+	if !platform.CompilerSupported() {
+		t.Skip("compiler is not supported on this platform")
+	}
+
+	var profile bytes.Buffer
+	r := NewRuntimeWithConfig(NewRuntimeConfigCompiler().WithGuestProfiler(&profile))
+	require.NoError(t, r.Close(testCtx))
+	require.Zero(t, profile.Len())
+}
+
 func TestRuntime_CompileModule(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -89,6 +161,39 @@ func TestRuntime_CompileModule(t *testing.T) {
 		}, code.module.MemorySection)
 	})
 
+	t.Run("WithOptimizationLevel", func(t *testing.T) {
+		testWasm := binaryformat.EncodeModule(&wasm.Module{MemorySection: &wasm.Memory{Min: 1}})
+
+		m, err := r.CompileModule(testCtx, testWasm, NewCompileConfig())
+		require.NoError(t, err)
+		require.False(t, m.(*compiledModule).module.FastCompile)
+
+		optimized := m.(*compiledModule).module.ID
+
+		m, err = r.CompileModule(testCtx, testWasm, NewCompileConfig().WithOptimizationLevel(OptimizationLevelMinimal))
+		require.NoError(t, err)
+		require.True(t, m.(*compiledModule).module.FastCompile)
+		// The code is cached by ID, so it must differ from the optimized one.
+		require.NotEqual(t, optimized, m.(*compiledModule).module.ID)
+	})
+
+	t.Run("WithFeatures", func(t *testing.T) {
+		testWasm := binaryformat.EncodeModule(&wasm.Module{MemorySection: &wasm.Memory{Min: 1}})
+
+		m, err := r.CompileModule(testCtx, testWasm, NewCompileConfig())
+		require.NoError(t, err)
+		defaultID := m.(*compiledModule).module.ID
+
+		// Overriding with the same features as the runtime compiles the same code.
+		m, err = r.CompileModule(testCtx, testWasm, NewCompileConfig().WithFeatures(Features(r.(*runtime).enabledFeatures)))
+		require.NoError(t, err)
+		require.Equal(t, defaultID, m.(*compiledModule).module.ID)
+
+		m, err = r.CompileModule(testCtx, testWasm, NewCompileConfig().WithFeatures(FeaturesWasmCore2))
+		require.NoError(t, err)
+		require.NotEqual(t, defaultID, m.(*compiledModule).module.ID)
+	})
+
 	t.Run("WithImportReplacements", func(t *testing.T) {
 		testBin, err := watzero.Wat2Wasm(`(module
   (import "js" "increment" (func $increment (result i32)))
@@ -140,6 +245,221 @@ func TestRuntime_CompileModule(t *testing.T) {
 	})
 }
 
+func TestRuntime_CompileModule_CustomSections(t *testing.T) {
+	// Note: "producers" is a tool convention, so the data here is arbitrary rather than its real encoding.
+	producers := []byte{1, 'l', 'a', 'n', 'g'}
+	bin := append(binaryformat.EncodeModule(&wasm.Module{NameSection: &wasm.NameSection{ModuleName: "test"}}),
+		wasm.SectionIDCustom, 15, // 15 bytes in this section
+		9, 'p', 'r', 'o', 'd', 'u', 'c', 'e', 'r', 's')
+	bin = append(bin, producers...)
+
+	r := NewRuntime()
+	defer r.Close(testCtx)
+
+	m, err := r.CompileModule(testCtx, bin, NewCompileConfig())
+	require.NoError(t, err)
+
+	// The "name" section is decoded, so it is not in the result.
+	sections := m.CustomSections()
+	require.Equal(t, 1, len(sections))
+	require.Equal(t, "producers", sections[0].Name())
+	require.Equal(t, producers, sections[0].Data())
+
+	t.Run("none", func(t *testing.T) {
+		m, err := r.CompileModule(testCtx, binaryformat.EncodeModule(&wasm.Module{}), NewCompileConfig())
+		require.NoError(t, err)
+		require.Nil(t, m.CustomSections())
+	})
+
+	t.Run("WithCustomSectionHandler", func(t *testing.T) {
+		var names []string
+		var data [][]byte
+		config := NewCompileConfig().WithCustomSectionHandler(func(name string, d []byte) error {
+			names = append(names, name)
+			data = append(data, append([]byte(nil), d...))
+			return nil
+		})
+
+		_, err := r.CompileModule(testCtx, bin, config)
+		require.NoError(t, err)
+		require.Equal(t, []string{"producers"}, names)
+		require.Equal(t, [][]byte{producers}, data)
+	})
+
+	t.Run("WithCustomSectionHandler error", func(t *testing.T) {
+		config := NewCompileConfig().WithCustomSectionHandler(func(string, []byte) error {
+			return errors.New("unsupported")
+		})
+
+		_, err := r.CompileModule(testCtx, bin, config)
+		require.EqualError(t, err, "custom section[producers]: unsupported")
+
+		_, err = r.CompileModuleReader(testCtx, bytes.NewReader(bin), config)
+		require.EqualError(t, err, "custom section[producers]: unsupported")
+	})
+}
+
+func TestRuntime_CompileModule_DisassembleFunction(t *testing.T) {
+	r := NewRuntime()
+	defer r.Close(testCtx)
+
+	m, err := r.CompileModule(testCtx, addWasm, NewCompileConfig())
+	require.NoError(t, err)
+
+	listing, err := m.DisassembleFunction(0)
+	require.NoError(t, err)
+	require.Equal(t, `0x0000: local.get 0
+0x0002: local.get 1
+0x0004: i32.add
+0x0005: end
+`, listing)
+
+	_, err = m.DisassembleFunction(1)
+	require.EqualError(t, err, "function[1] out of range")
+}
+
+func TestRuntime_CompileModule_WithFeatures(t *testing.T) {
+	// "simd" returns the first lane of a v128 constant, so it requires FeatureSIMD.
+	lane := []byte{42, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	body := append([]byte{wasm.OpcodeVecPrefix, wasm.OpcodeVecV128Const}, lane...)
+	body = append(body, wasm.OpcodeVecPrefix, wasm.OpcodeVecI32x4ExtractLane, 0, wasm.OpcodeEnd)
+	binary := binaryformat.EncodeModule(&wasm.Module{
+		TypeSection:     []*wasm.FunctionType{{Results: []wasm.ValueType{wasm.ValueTypeI32}, ResultNumInUint64: 1}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []*wasm.Code{{Body: body}},
+		ExportSection:   []*wasm.Export{{Name: "simd", Type: wasm.ExternTypeFunc, Index: 0}},
+	})
+
+	for _, tt := range []struct {
+		name   string
+		config RuntimeConfig
+	}{
+		{name: "default", config: NewRuntimeConfig()},
+		{name: "interpreter", config: NewRuntimeConfigInterpreter()},
+	} {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			t.Run("disabled", func(t *testing.T) {
+				// The runtime allows SIMD, but the compile config doesn't.
+				r := NewRuntimeWithConfig(tc.config.WithWasmCore2())
+				defer r.Close(testCtx)
+
+				_, err := r.CompileModule(testCtx, binary, NewCompileConfig().
+					WithFeatures(FeaturesWasmCore2.Set(FeatureSIMD, false)))
+				require.EqualError(t, err, `invalid function[0] export["simd"]: section 10 (code), offset 0x22: v128.const invalid as feature "simd" is disabled`)
+			})
+
+			t.Run("enabled", func(t *testing.T) {
+				// The runtime doesn't allow SIMD, but the compile config does.
+				r := NewRuntimeWithConfig(tc.config)
+				defer r.Close(testCtx)
+
+				_, err := r.CompileModule(testCtx, binary, NewCompileConfig())
+				require.EqualError(t, err, `invalid function[0] export["simd"]: section 10 (code), offset 0x22: v128.const invalid as feature "simd" is disabled`)
+
+				compiled, err := r.CompileModule(testCtx, binary, NewCompileConfig().
+					WithFeatures(FeaturesWasmCore1.Set(FeatureSIMD, true)))
+				require.NoError(t, err)
+
+				mod, err := r.InstantiateModule(testCtx, compiled, NewModuleConfig())
+				require.NoError(t, err)
+
+				results, err := mod.ExportedFunction("simd").Call(testCtx)
+				require.NoError(t, err)
+				require.Equal(t, []uint64{42}, results)
+			})
+		})
+	}
+}
+
+func TestRuntime_CompileModuleReader(t *testing.T) {
+	tests := []struct {
+		name string
+		wasm []byte
+	}{
+		{
+			name: "empty",
+			wasm: binaryformat.EncodeModule(&wasm.Module{}),
+		},
+		{
+			name: "code and name section",
+			wasm: addWasm,
+		},
+		{
+			name: "custom section",
+			wasm: append(binaryformat.EncodeModule(&wasm.Module{NameSection: &wasm.NameSection{ModuleName: "test"}}),
+				wasm.SectionIDCustom, 15, // 15 bytes in this section
+				9, 'p', 'r', 'o', 'd', 'u', 'c', 'e', 'r', 's', 1, 'l', 'a', 'n', 'g'),
+		},
+	}
+
+	r := NewRuntime()
+	defer r.Close(testCtx)
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			expected, err := r.CompileModule(testCtx, tc.wasm, NewCompileConfig())
+			require.NoError(t, err)
+
+			// OneByteReader ensures decoding doesn't rely on reads returning whole sections.
+			m, err := r.CompileModuleReader(testCtx, iotest.OneByteReader(bytes.NewReader(tc.wasm)), NewCompileConfig())
+			require.NoError(t, err)
+
+			// The result, including the ID used for caching, is the same as CompileModule.
+			require.Equal(t, expected.(*compiledModule).module, m.(*compiledModule).module)
+			require.Equal(t, r.(*runtime).store.Engine, m.(*compiledModule).compiledEngine)
+		})
+	}
+}
+
+func TestRuntime_CompileModuleReader_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		wasm        []byte
+		expectedErr string
+	}{
+		{
+			name:        "invalid binary",
+			wasm:        []byte("yolo"),
+			expectedErr: "invalid binary",
+		},
+		{
+			name:        "invalid version",
+			wasm:        append(binaryformat.Magic, []byte("yolo")...),
+			expectedErr: "invalid version header",
+		},
+		{
+			name:        "truncated code section",
+			wasm:        addWasm[:len(addWasm)/2],
+			expectedErr: "section 10 (code), offset 0x23: function[0]: read body: unexpected EOF",
+		},
+		{
+			name:        "memory has too many pages",
+			wasm:        binaryformat.EncodeModule(&wasm.Module{MemorySection: &wasm.Memory{Min: 2, Cap: 2, Max: 70000, IsMaxEncoded: true}}),
+			expectedErr: "section 5 (memory), offset 0x10: max 70000 pages (4 Gi) over limit of 65536 pages (4 Gi)",
+		},
+	}
+
+	r := NewRuntime()
+	defer r.Close(testCtx)
+
+	t.Run("nil", func(t *testing.T) {
+		_, err := r.CompileModuleReader(testCtx, nil, NewCompileConfig())
+		require.EqualError(t, err, "reader == nil")
+	})
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := r.CompileModuleReader(testCtx, bytes.NewReader(tc.wasm), NewCompileConfig())
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
+
 func TestRuntime_CompileModule_Errors(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -164,7 +484,7 @@ func TestRuntime_CompileModule_Errors(t *testing.T) {
 			wasm: binaryformat.EncodeModule(&wasm.Module{
 				MemorySection: &wasm.Memory{Min: 3},
 			}),
-			expectedErr: "section memory: capacity 1 pages (64 Ki) less than minimum 3 pages (192 Ki)",
+			expectedErr: "section 5 (memory), offset 0xd: capacity 1 pages (64 Ki) less than minimum 3 pages (192 Ki)",
 		},
 		{
 			name: "memory cap < min exported", // only one test to avoid duplicating tests in module_test.go
@@ -177,12 +497,26 @@ func TestRuntime_CompileModule_Errors(t *testing.T) {
 					{Name: "memory", Type: api.ExternTypeMemory},
 				},
 			}),
-			expectedErr: "section memory: capacity 2 pages (128 Ki) less than minimum 3 pages (192 Ki)",
+			expectedErr: "section 5 (memory), offset 0xd: capacity 2 pages (128 Ki) less than minimum 3 pages (192 Ki)",
 		},
 		{
 			name:        "memory has too many pages",
 			wasm:        binaryformat.EncodeModule(&wasm.Module{MemorySection: &wasm.Memory{Min: 2, Cap: 2, Max: 70000, IsMaxEncoded: true}}),
-			expectedErr: "section memory: max 70000 pages (4 Gi) over limit of 65536 pages (4 Gi)",
+			expectedErr: "section 5 (memory), offset 0x10: max 70000 pages (4 Gi) over limit of 65536 pages (4 Gi)",
+		},
+		{
+			name: "import renamer collides distinct imports",
+			config: NewCompileConfig().WithImportRenamer(func(externType api.ExternType, oldModule, oldName string) (string, string) {
+				return "env", oldName
+			}),
+			wasm: binaryformat.EncodeModule(&wasm.Module{
+				TypeSection: []*wasm.FunctionType{{}},
+				ImportSection: []*wasm.Import{
+					{Module: "go", Name: "tick", Type: wasm.ExternTypeFunc, DescFunc: 0},
+					{Module: "wasm", Name: "tick", Type: wasm.ExternTypeFunc, DescFunc: 0},
+				},
+			}),
+			expectedErr: "import[1] renamed from wasm.tick to env.tick collides with the import renamed from go.tick",
 		},
 	}
 
@@ -203,6 +537,84 @@ func TestRuntime_CompileModule_Errors(t *testing.T) {
 	}
 }
 
+func TestRuntime_ValidateModule(t *testing.T) {
+	i32 := wasm.ValueTypeI32
+	tests := []struct {
+		name        string
+		wasm        []byte
+		expectedErr string
+	}{
+		{
+			name: "valid",
+			wasm: binaryformat.EncodeModule(&wasm.Module{
+				TypeSection:     []*wasm.FunctionType{{Results: []wasm.ValueType{i32}}},
+				FunctionSection: []wasm.Index{0},
+				CodeSection:     []*wasm.Code{{Body: []byte{wasm.OpcodeI32Const, 1, wasm.OpcodeEnd}}},
+				ExportSection:   []*wasm.Export{{Name: "one", Type: wasm.ExternTypeFunc, Index: 0}},
+			}),
+		},
+		{
+			name:        "nil",
+			expectedErr: "binary == nil",
+		},
+		{
+			name:        "invalid binary",
+			wasm:        append(binaryformat.Magic, []byte("yolo")...),
+			expectedErr: "invalid version header",
+		},
+		{
+			name: "function type mismatch",
+			wasm: binaryformat.EncodeModule(&wasm.Module{
+				TypeSection:     []*wasm.FunctionType{{Results: []wasm.ValueType{i32}}},
+				FunctionSection: []wasm.Index{0},
+				CodeSection:     []*wasm.Code{{Body: []byte{wasm.OpcodeEnd}}},
+			}),
+			expectedErr: "invalid function[0]: section 10 (code), offset 0x18: not enough results\n\thave ()\n\twant (i32)",
+		},
+		{
+			name: "feature not enabled",
+			wasm: binaryformat.EncodeModule(&wasm.Module{
+				TypeSection:     []*wasm.FunctionType{{Results: []wasm.ValueType{i32, i32}}},
+				FunctionSection: []wasm.Index{0},
+				CodeSection:     []*wasm.Code{{Body: []byte{wasm.OpcodeI32Const, 1, wasm.OpcodeI32Const, 1, wasm.OpcodeEnd}}},
+			}),
+			expectedErr: `section 1 (type), offset 0xe: read 0-th type: multiple result types invalid as feature "multi-value" is disabled`,
+		},
+		{
+			name: "export of unknown function",
+			wasm: binaryformat.EncodeModule(&wasm.Module{
+				ExportSection: []*wasm.Export{{Name: "one", Type: wasm.ExternTypeFunc, Index: 0}},
+			}),
+			expectedErr: `unknown function for export["one"]`,
+		},
+	}
+
+	engine := &mockEngine{name: "mock", cachedModules: map[*wasm.Module]struct{}{}}
+	conf := *engineLessConfig
+	conf.newEngine = func(*runtimeConfig) wasm.Engine {
+		return engine
+	}
+	r := NewRuntimeWithConfig(&conf)
+	defer r.Close(testCtx)
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			err := r.ValidateModule(testCtx, tc.wasm)
+			if tc.expectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.expectedErr)
+			}
+
+			// Validation should never compile code.
+			require.Zero(t, engine.CompiledModuleCount())
+			require.Zero(t, len(r.(*runtime).compiledModules))
+		})
+	}
+}
+
 // TestModule_Memory only covers a couple cases to avoid duplication of internal/wasm/runtime_test.go
 func TestModule_Memory(t *testing.T) {
 	tests := []struct {
@@ -339,19 +751,18 @@ func TestModule_Global(t *testing.T) {
 
 func TestModule_FunctionContext(t *testing.T) {
 	tests := []struct {
-		name     string
-		ctx      context.Context
-		expected context.Context
+		name          string
+		ctx           context.Context
+		expectedValue interface{} // of the testCtx key, as the host function's context also includes the module
 	}{
 		{
-			name:     "nil defaults to context.Background",
-			ctx:      nil,
-			expected: context.Background(),
+			name: "nil defaults to context.Background",
+			ctx:  nil,
 		},
 		{
-			name:     "set context",
-			ctx:      testCtx,
-			expected: testCtx,
+			name:          "set context",
+			ctx:           testCtx,
+			expectedValue: "arbitrary",
 		},
 	}
 
@@ -366,7 +777,8 @@ func TestModule_FunctionContext(t *testing.T) {
 			functionName := "fn"
 			expectedResult := uint64(math.MaxUint64)
 			hostFn := func(ctx context.Context) uint64 {
-				require.Equal(t, tc.expected, ctx)
+				require.Equal(t, tc.expectedValue, ctx.Value(struct{}{}))
+				require.NotNil(t, ModuleFromContext(ctx))
 				return expectedResult
 			}
 			source := requireImportAndExportFunction(t, r, hostFn, functionName)
@@ -383,6 +795,38 @@ func TestModule_FunctionContext(t *testing.T) {
 	}
 }
 
+func TestModuleFromContext(t *testing.T) {
+	require.Nil(t, ModuleFromContext(testCtx))
+
+	r := NewRuntime()
+	defer r.Close(testCtx)
+
+	// helper is nested inside the host function, and only has the context.
+	var fromHelper api.Module
+	helper := func(ctx context.Context) {
+		fromHelper = ModuleFromContext(ctx)
+	}
+	functionName := "fn"
+	hostFn := func(ctx context.Context) uint64 {
+		helper(ctx)
+		return 0
+	}
+	source := requireImportAndExportFunction(t, r, hostFn, functionName)
+
+	code, err := r.CompileModule(testCtx, source, NewCompileConfig())
+	require.NoError(t, err)
+	module, err := r.InstantiateModule(testCtx, code, NewModuleConfig().WithName("caller"))
+	require.NoError(t, err)
+
+	_, err = module.ExportedFunction(functionName).Call(testCtx)
+	require.NoError(t, err)
+	require.NotNil(t, fromHelper)
+	require.Equal(t, "caller", fromHelper.Name())
+
+	// The module is only added to the context passed to the host function.
+	require.Nil(t, ModuleFromContext(testCtx))
+}
+
 func TestRuntime_InstantiateModule_UsesContext(t *testing.T) {
 	r := NewRuntime()
 	defer r.Close(testCtx)
@@ -391,7 +835,7 @@ func TestRuntime_InstantiateModule_UsesContext(t *testing.T) {
 	var calledStart bool
 	start := func(ctx context.Context) {
 		calledStart = true
-		require.Equal(t, testCtx, ctx)
+		require.Equal(t, "arbitrary", ctx.Value(struct{}{}))
 	}
 
 	_, err := r.NewModuleBuilder("env").
@@ -419,6 +863,452 @@ func TestRuntime_InstantiateModule_UsesContext(t *testing.T) {
 	require.Equal(t, uint32(2), r.(*runtime).store.Engine.CompiledModuleCount())
 }
 
+func TestRuntime_InstantiateModule_DataSegments(t *testing.T) {
+	r := NewRuntime()
+	defer r.Close(testCtx)
+
+	binary := binaryformat.EncodeModule(&wasm.Module{
+		MemorySection: &wasm.Memory{Min: 2, Max: 3, IsMaxEncoded: true},
+		DataSection: []*wasm.DataSegment{
+			{
+				OffsetExpression: &wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: leb128.EncodeInt32(1024)},
+				Init:             []byte("wazero"),
+			},
+			{
+				OffsetExpression: &wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: leb128.EncodeInt32(16)},
+				Init:             []byte{1, 2},
+			},
+		},
+	})
+
+	mod, err := r.InstantiateModuleFromBinary(testCtx, binary)
+	require.NoError(t, err)
+	defer mod.Close(testCtx)
+
+	mem := mod.Memory()
+	require.Equal(t, uint32(2), mem.Pages(testCtx))
+	require.Equal(t, uint32(2*65536), mem.Size(testCtx))
+
+	// Segments are in the order of the data section, not by offset.
+	segments := mod.DataSegments()
+	require.Equal(t, []api.DataSegment{{Offset: 1024, Size: 6}, {Offset: 16, Size: 2}}, segments)
+
+	buf, ok := mem.Read(testCtx, segments[0].Offset, segments[0].Size)
+	require.True(t, ok)
+	require.Equal(t, "wazero", string(buf))
+
+	buf, ok = mem.Read(testCtx, segments[1].Offset, segments[1].Size)
+	require.True(t, ok)
+	require.Equal(t, []byte{1, 2}, buf)
+
+	// The result is a copy, so callers can't corrupt it.
+	segments[0].Offset = 0
+	require.Equal(t, uint32(1024), mod.DataSegments()[0].Offset)
+
+	// Pages reflects growth.
+	_, ok = mem.Grow(testCtx, 1)
+	require.True(t, ok)
+	require.Equal(t, uint32(3), mem.Pages(testCtx))
+}
+
+func TestRuntime_InstantiateModule_WithMemoryInit(t *testing.T) {
+	r := NewRuntime()
+	defer r.Close(testCtx)
+
+	// Define a function that records what "_start" read from memory.
+	var seen uint32
+	_, err := r.NewModuleBuilder("env").
+		ExportFunction("seen", func(v uint32) { seen = v }).
+		Instantiate(testCtx, r)
+	require.NoError(t, err)
+
+	// "_start" reads the uint32 at offset zero, then passes it to "seen". The data segment initializes the byte after.
+	binary := binaryformat.EncodeModule(&wasm.Module{
+		TypeSection:     []*wasm.FunctionType{{Params: []wasm.ValueType{wasm.ValueTypeI32}}, {}},
+		ImportSection:   []*wasm.Import{{Module: "env", Name: "seen", Type: wasm.ExternTypeFunc, DescFunc: 0}},
+		FunctionSection: []wasm.Index{1},
+		CodeSection: []*wasm.Code{{Body: []byte{
+			wasm.OpcodeI32Const, 0,
+			wasm.OpcodeI32Load, 0x2, 0x0, // align=2, offset=0
+			wasm.OpcodeCall, 0,
+			wasm.OpcodeEnd,
+		}}},
+		MemorySection: &wasm.Memory{Min: 1},
+		DataSection: []*wasm.DataSegment{{
+			OffsetExpression: &wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{4}},
+			Init:             []byte{1},
+		}},
+		ExportSection: []*wasm.Export{{Name: "_start", Type: wasm.ExternTypeFunc, Index: 1}},
+	})
+
+	code, err := r.CompileModule(testCtx, binary, NewCompileConfig())
+	require.NoError(t, err)
+
+	t.Run("seeds memory before _start", func(t *testing.T) {
+		config := NewModuleConfig().WithName(t.Name()).WithMemoryInit(func(ctx context.Context, mem api.Memory) error {
+			require.Equal(t, testCtx, ctx)
+
+			// Data segments are applied before this is called.
+			b, ok := mem.ReadByte(ctx, 4)
+			require.True(t, ok)
+			require.Equal(t, byte(1), b)
+
+			require.True(t, mem.WriteUint32Le(ctx, 0, 42))
+			return nil
+		})
+
+		mod, err := r.InstantiateModule(testCtx, code, config)
+		require.NoError(t, err)
+		defer mod.Close(testCtx)
+
+		require.Equal(t, uint32(42), seen)
+	})
+
+	t.Run("error aborts instantiation", func(t *testing.T) {
+		seen = 0
+		config := NewModuleConfig().WithName(t.Name()).WithMemoryInit(func(context.Context, api.Memory) error {
+			return errors.New("ice cream")
+		})
+
+		_, err := r.InstantiateModule(testCtx, code, config)
+		require.EqualError(t, err, "module[TestRuntime_InstantiateModule_WithMemoryInit/error_aborts_instantiation] memory init failed: ice cream")
+
+		// "_start" didn't run and the module name is free to use again.
+		require.Zero(t, seen)
+		require.Nil(t, r.Module(t.Name()))
+	})
+}
+
+func TestRuntime_InstantiateModule_WithCloseNotifier(t *testing.T) {
+	// "_start" calls "env.exit" with the exit code in its only parameter, similar to "proc_exit". "trap" traps.
+	binary := binaryformat.EncodeModule(&wasm.Module{
+		TypeSection:     []*wasm.FunctionType{{Params: []wasm.ValueType{wasm.ValueTypeI32}}, {}},
+		ImportSection:   []*wasm.Import{{Module: "env", Name: "exit", Type: wasm.ExternTypeFunc, DescFunc: 0}},
+		FunctionSection: []wasm.Index{1, 1},
+		CodeSection: []*wasm.Code{
+			{Body: []byte{
+				wasm.OpcodeI32Const, 3,
+				wasm.OpcodeCall, 0,
+				wasm.OpcodeEnd,
+			}},
+			{Body: []byte{wasm.OpcodeUnreachable, wasm.OpcodeEnd}},
+		},
+		ExportSection: []*wasm.Export{
+			{Name: "_start", Type: wasm.ExternTypeFunc, Index: 1},
+			{Name: "trap", Type: wasm.ExternTypeFunc, Index: 2},
+		},
+	})
+
+	tests := []struct {
+		name             string
+		startFunctions   []string
+		close            func(Runtime, Namespace, api.Module) error
+		expectedExitCode uint32
+	}{
+		{
+			name: "Close",
+			close: func(_ Runtime, _ Namespace, mod api.Module) error {
+				return mod.Close(testCtx)
+			},
+		},
+		{
+			name: "CloseWithExitCode",
+			close: func(_ Runtime, _ Namespace, mod api.Module) error {
+				return mod.CloseWithExitCode(testCtx, 2)
+			},
+			expectedExitCode: 2,
+		},
+		{
+			name:           "exit from _start",
+			startFunctions: []string{"_start"},
+			close: func(Runtime, Namespace, api.Module) error {
+				return nil // already closed
+			},
+			expectedExitCode: 3,
+		},
+		{
+			name: "Namespace.CloseWithExitCode",
+			close: func(_ Runtime, ns Namespace, _ api.Module) error {
+				return ns.CloseWithExitCode(testCtx, 4)
+			},
+			expectedExitCode: 4,
+		},
+		{
+			name: "Runtime.CloseWithExitCode",
+			close: func(r Runtime, _ Namespace, _ api.Module) error {
+				return r.CloseWithExitCode(testCtx, 5)
+			},
+			expectedExitCode: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRuntime()
+			defer r.Close(testCtx)
+
+			ns := r.NewNamespace(testCtx)
+			_, err := r.NewModuleBuilder("env").
+				ExportFunction("exit", func(ctx context.Context, m api.Module, exitCode uint32) {
+					_ = m.CloseWithExitCode(ctx, exitCode)
+				}).
+				Instantiate(testCtx, ns)
+			require.NoError(t, err)
+
+			var exitCodes []uint32
+			config := NewModuleConfig().WithStartFunctions(tc.startFunctions...).
+				WithCloseNotifier(func(ctx context.Context, exitCode uint32) {
+					require.Equal(t, "arbitrary", ctx.Value(struct{}{}))
+					exitCodes = append(exitCodes, exitCode)
+				})
+
+			code, err := r.CompileModule(testCtx, binary, NewCompileConfig())
+			require.NoError(t, err)
+
+			mod, err := ns.InstantiateModule(testCtx, code, config)
+			if len(tc.startFunctions) > 0 {
+				require.Equal(t, uint32(3), err.(*sys.ExitError).ExitCode())
+			} else {
+				require.NoError(t, err)
+				require.Zero(t, len(exitCodes)) // not yet closed
+
+				// A trap doesn't close the module, so doesn't notify. The host decides whether to close it.
+				_, err = mod.ExportedFunction("trap").Call(testCtx)
+				require.Error(t, err)
+				require.Zero(t, len(exitCodes))
+			}
+
+			require.NoError(t, tc.close(r, ns, mod))
+			require.Equal(t, []uint32{tc.expectedExitCode}, exitCodes)
+
+			// Closing again doesn't notify again.
+			if mod != nil {
+				require.NoError(t, mod.CloseWithExitCode(testCtx, 6))
+			}
+			require.NoError(t, r.CloseWithExitCode(testCtx, 7))
+			require.Equal(t, []uint32{tc.expectedExitCode}, exitCodes)
+		})
+	}
+}
+
+func TestRuntime_InstantiateModule_WithMemoryGrowHook(t *testing.T) {
+	// "grow" returns the result of "memory.grow", which is the previous size in pages, or -1 on failure.
+	binary := binaryformat.EncodeModule(&wasm.Module{
+		TypeSection:     []*wasm.FunctionType{{Params: []wasm.ValueType{wasm.ValueTypeI32}, Results: []wasm.ValueType{wasm.ValueTypeI32}}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []*wasm.Code{{Body: []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeMemoryGrow, 0,
+			wasm.OpcodeEnd,
+		}}},
+		MemorySection: &wasm.Memory{Min: 1, Max: 10, IsMaxEncoded: true},
+		ExportSection: []*wasm.Export{{Name: "grow", Type: wasm.ExternTypeFunc, Index: 0}},
+	})
+
+	for _, tt := range []struct {
+		name   string
+		config RuntimeConfig
+	}{
+		{name: "default", config: NewRuntimeConfig()},
+		{name: "interpreter", config: NewRuntimeConfigInterpreter()},
+	} {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRuntimeWithConfig(tc.config)
+			defer r.Close(testCtx)
+
+			// quotaPages can change while the module runs, unlike the memory maximum.
+			quotaPages := uint32(3)
+			var requests [][2]uint32
+			config := NewModuleConfig().WithMemoryGrowHook(func(ctx context.Context, currentPages, delta uint32) bool {
+				require.Equal(t, testCtx, ctx)
+				requests = append(requests, [2]uint32{currentPages, delta})
+				return currentPages+delta <= quotaPages
+			})
+
+			code, err := r.CompileModule(testCtx, binary, NewCompileConfig())
+			require.NoError(t, err)
+			defer code.Close(testCtx)
+
+			mod, err := r.InstantiateModule(testCtx, code, config)
+			require.NoError(t, err)
+			defer mod.Close(testCtx)
+			grow := mod.ExportedFunction("grow")
+
+			results, err := grow.Call(testCtx, 2)
+			require.NoError(t, err)
+			require.Equal(t, uint64(1), results[0]) // previous size
+
+			// The guest sees -1 when the hook denies growth, and memory isn't grown.
+			results, err = grow.Call(testCtx, 1)
+			require.NoError(t, err)
+			require.Equal(t, uint64(0xffffffff), results[0])
+			require.Equal(t, uint32(3*wasm.MemoryPageSize), mod.Memory().Size(testCtx))
+
+			quotaPages = 4
+			results, err = grow.Call(testCtx, 1)
+			require.NoError(t, err)
+			require.Equal(t, uint64(3), results[0])
+
+			// The hook isn't consulted when the request exceeds the memory maximum anyway, or doesn't grow.
+			results, err = grow.Call(testCtx, 10)
+			require.NoError(t, err)
+			require.Equal(t, uint64(0xffffffff), results[0])
+			_, err = grow.Call(testCtx, 0)
+			require.NoError(t, err)
+
+			require.Equal(t, [][2]uint32{{1, 2}, {3, 1}, {3, 1}}, requests)
+		})
+	}
+}
+
+func TestRuntime_InstantiateModule_ReportsAllUnresolvedImports(t *testing.T) {
+	r := NewRuntime()
+	defer r.Close(testCtx)
+
+	_, err := r.NewModuleBuilder("env").
+		ExportFunction("present", func(v uint32) uint32 { return v }).
+		ExportFunction("mismatched", func(v uint32) uint32 { return v }).
+		Instantiate(testCtx, r)
+	require.NoError(t, err)
+
+	binary := binaryformat.EncodeModule(&wasm.Module{
+		TypeSection: []*wasm.FunctionType{
+			{Params: []wasm.ValueType{wasm.ValueTypeI32}, Results: []wasm.ValueType{wasm.ValueTypeI32}},
+			{Params: []wasm.ValueType{wasm.ValueTypeI64}},
+		},
+		ImportSection: []*wasm.Import{
+			{Module: "env", Name: "present", Type: wasm.ExternTypeFunc, DescFunc: 0},
+			{Module: "env", Name: "missing", Type: wasm.ExternTypeFunc, DescFunc: 0},
+			{Module: "env", Name: "mismatched", Type: wasm.ExternTypeFunc, DescFunc: 1},
+			{Module: "wasi_snapshot_preview1", Name: "proc_exit", Type: wasm.ExternTypeFunc, DescFunc: 0},
+		},
+	})
+
+	// All unresolved imports are reported, not just the first.
+	_, err = r.InstantiateModuleFromBinary(testCtx, binary)
+	require.EqualError(t, err, `3 imports unresolved:
+	import[1] func[env.missing]: "missing" is not exported in module "env"
+	import[2] func[env.mismatched]: signature mismatch: i64_v != i32_i32
+	import[3] func[wasi_snapshot_preview1.proc_exit]: module[wasi_snapshot_preview1] not instantiated`)
+}
+
+func TestRuntime_InstantiateModule_WithStubMissingImports(t *testing.T) {
+	i32 := wasm.ValueTypeI32
+	// "call_missing" calls "env.missing", which isn't defined, and "call_present" calls "env.present", which is.
+	binary := binaryformat.EncodeModule(&wasm.Module{
+		TypeSection: []*wasm.FunctionType{
+			{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}, ParamNumInUint64: 1, ResultNumInUint64: 1},
+			{Results: []wasm.ValueType{i32}, ResultNumInUint64: 1},
+		},
+		ImportSection: []*wasm.Import{
+			{Module: "env", Name: "missing", Type: wasm.ExternTypeFunc, DescFunc: 0},
+			{Module: "env", Name: "present", Type: wasm.ExternTypeFunc, DescFunc: 0},
+		},
+		FunctionSection: []wasm.Index{1, 1},
+		CodeSection: []*wasm.Code{
+			{Body: []byte{wasm.OpcodeI32Const, 1, wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeI32Const, 1, wasm.OpcodeCall, 1, wasm.OpcodeEnd}},
+		},
+		ExportSection: []*wasm.Export{
+			{Name: "call_missing", Type: wasm.ExternTypeFunc, Index: 2},
+			{Name: "call_present", Type: wasm.ExternTypeFunc, Index: 3},
+		},
+	})
+
+	for _, tt := range []struct {
+		name   string
+		config RuntimeConfig
+	}{
+		{name: "default", config: NewRuntimeConfig()},
+		{name: "interpreter", config: NewRuntimeConfigInterpreter()},
+	} {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			t.Run("module missing", func(t *testing.T) {
+				r := NewRuntimeWithConfig(tc.config)
+				defer r.Close(testCtx)
+
+				_, err := r.InstantiateModuleFromBinary(testCtx, binary)
+				require.EqualError(t, err, `2 imports unresolved:
+	import[0] func[env.missing]: module[env] not instantiated
+	import[1] func[env.present]: module[env] not instantiated`)
+
+				r = NewRuntimeWithConfig(tc.config.WithStubMissingImports(true))
+				defer r.Close(testCtx)
+
+				mod, err := r.InstantiateModuleFromBinary(testCtx, binary)
+				require.NoError(t, err)
+				defer mod.Close(testCtx)
+
+				// Both imports are missing, so both trap when called.
+				for _, name := range []string{"call_missing", "call_present"} {
+					_, err = mod.ExportedFunction(name).Call(testCtx)
+					var unimplemented *api.ErrUnimplementedImport
+					require.True(t, errors.As(err, &unimplemented), "%s: %v", name, err)
+				}
+			})
+
+			t.Run("function missing", func(t *testing.T) {
+				r := NewRuntimeWithConfig(tc.config)
+				defer r.Close(testCtx)
+
+				_, err := r.NewModuleBuilder("env").
+					ExportFunction("present", func(v uint32) uint32 { return v + 1 }).
+					Instantiate(testCtx, r)
+				require.NoError(t, err)
+
+				_, err = r.InstantiateModuleFromBinary(testCtx, binary)
+				require.EqualError(t, err, "import[0] func[env.missing]: \"missing\" is not exported in module \"env\"")
+
+				r = NewRuntimeWithConfig(tc.config.WithStubMissingImports(true))
+				defer r.Close(testCtx)
+
+				_, err = r.NewModuleBuilder("env").
+					ExportFunction("present", func(v uint32) uint32 { return v + 1 }).
+					Instantiate(testCtx, r)
+				require.NoError(t, err)
+
+				mod, err := r.InstantiateModuleFromBinary(testCtx, binary)
+				require.NoError(t, err)
+				defer mod.Close(testCtx)
+
+				// The present import is called as usual.
+				results, err := mod.ExportedFunction("call_present").Call(testCtx)
+				require.NoError(t, err)
+				require.Equal(t, []uint64{2}, results)
+
+				// The missing import traps only when called.
+				_, err = mod.ExportedFunction("call_missing").Call(testCtx)
+				var unimplemented *api.ErrUnimplementedImport
+				require.True(t, errors.As(err, &unimplemented), err)
+				require.Equal(t, &api.ErrUnimplementedImport{Module: "env", Name: "missing"}, unimplemented)
+			})
+
+			t.Run("stubs deleted on close", func(t *testing.T) {
+				r := NewRuntimeWithConfig(tc.config.WithStubMissingImports(true))
+				defer r.Close(testCtx)
+				engine := r.(*runtime).store.Engine
+
+				compiled, err := r.CompileModule(testCtx, binary, NewCompileConfig())
+				require.NoError(t, err)
+				defer compiled.Close(testCtx)
+				require.Equal(t, uint32(1), engine.CompiledModuleCount())
+
+				// The stubs are compiled on instantiation.
+				mod, err := r.InstantiateModule(testCtx, compiled, NewModuleConfig())
+				require.NoError(t, err)
+				require.Equal(t, uint32(2), engine.CompiledModuleCount())
+
+				// Closing the module deletes the stubs, but not the module that imported them.
+				require.NoError(t, mod.Close(testCtx))
+				require.Equal(t, uint32(1), engine.CompiledModuleCount())
+			})
+		})
+	}
+}
+
 // TestRuntime_InstantiateModuleFromBinary_DoesntEnforce_Start ensures wapc-go work when modules import WASI, but don't
 // export "_start".
 func TestRuntime_InstantiateModuleFromBinary_DoesntEnforce_Start(t *testing.T) {
@@ -435,6 +1325,71 @@ func TestRuntime_InstantiateModuleFromBinary_DoesntEnforce_Start(t *testing.T) {
 	require.NoError(t, mod.Close(testCtx))
 }
 
+// TestRuntime_InstantiateModule_Reactor ensures a WASI reactor, which exports "_initialize" instead of "_start", is
+// initialized before any other export can be called.
+func TestRuntime_InstantiateModule_Reactor(t *testing.T) {
+	// "_initialize" calls "env.record" with 1 and "work" calls it with 2.
+	binary := binaryformat.EncodeModule(&wasm.Module{
+		TypeSection:     []*wasm.FunctionType{{Params: []wasm.ValueType{wasm.ValueTypeI32}}, {}},
+		ImportSection:   []*wasm.Import{{Module: "env", Name: "record", Type: wasm.ExternTypeFunc, DescFunc: 0}},
+		FunctionSection: []wasm.Index{1, 1},
+		CodeSection: []*wasm.Code{
+			{Body: []byte{wasm.OpcodeI32Const, 1, wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeI32Const, 2, wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+		},
+		ExportSection: []*wasm.Export{
+			{Name: "_initialize", Type: wasm.ExternTypeFunc, Index: 1},
+			{Name: "work", Type: wasm.ExternTypeFunc, Index: 2},
+		},
+	})
+
+	r := NewRuntime()
+	defer r.Close(testCtx)
+
+	var recorded []uint32
+	_, err := r.NewModuleBuilder("env").
+		ExportFunction("record", func(v uint32) {
+			recorded = append(recorded, v)
+		}).
+		Instantiate(testCtx, r)
+	require.NoError(t, err)
+
+	code, err := r.CompileModule(testCtx, binary, NewCompileConfig())
+	require.NoError(t, err)
+
+	t.Run("calls _initialize by default", func(t *testing.T) {
+		recorded = nil
+		mod, err := r.InstantiateModule(testCtx, code, NewModuleConfig().WithName("default"))
+		require.NoError(t, err)
+		defer mod.Close(testCtx)
+		require.Equal(t, []uint32{1}, recorded)
+
+		_, err = mod.ExportedFunction("work").Call(testCtx)
+		require.NoError(t, err)
+		require.Equal(t, []uint32{1, 2}, recorded)
+	})
+
+	t.Run("fails before _initialize", func(t *testing.T) {
+		recorded = nil
+		mod, err := r.InstantiateModule(testCtx, code, NewModuleConfig().WithName("override").WithStartFunctions())
+		require.NoError(t, err)
+		defer mod.Close(testCtx)
+		require.Zero(t, len(recorded))
+
+		work := mod.ExportedFunction("work")
+		_, err = work.Call(testCtx)
+		require.EqualError(t, err, "module[override] not initialized: call function[_initialize] first")
+		require.Zero(t, len(recorded))
+
+		// Calling "_initialize" directly satisfies the requirement.
+		_, err = mod.ExportedFunction("_initialize").Call(testCtx)
+		require.NoError(t, err)
+		_, err = work.Call(testCtx)
+		require.NoError(t, err)
+		require.Equal(t, []uint32{1, 2}, recorded)
+	})
+}
+
 func TestRuntime_InstantiateModuleFromBinary_UsesContext(t *testing.T) {
 	r := NewRuntime()
 	defer r.Close(testCtx)
@@ -443,7 +1398,7 @@ func TestRuntime_InstantiateModuleFromBinary_UsesContext(t *testing.T) {
 	var calledStart bool
 	start := func(ctx context.Context) {
 		calledStart = true
-		require.Equal(t, testCtx, ctx)
+		require.Equal(t, "arbitrary", ctx.Value(struct{}{}))
 	}
 
 	host, err := r.NewModuleBuilder("").
@@ -569,6 +1524,19 @@ func TestRuntime_InstantiateModule_WithName(t *testing.T) {
 
 	require.Nil(t, internal.Module("0"))
 	require.Equal(t, internal.Module("2"), m2)
+
+	// An empty name falls back to the name of the compiled module.
+	m0, err := r.InstantiateModule(testCtx, base, NewModuleConfig().WithName(""))
+	require.NoError(t, err)
+
+	require.Equal(t, "0", m0.Name())
+	require.Equal(t, internal.Module("0"), m0)
+
+	// Closing one instance doesn't affect the others, and the exit error uses the instance name.
+	require.NoError(t, m1.CloseWithExitCode(testCtx, 2))
+	require.Nil(t, internal.Module("1"))
+	require.Equal(t, internal.Module("2"), m2)
+	require.Equal(t, sys.NewExitError("1", 2), m1.(*wasm.CallContext).FailIfClosed())
 }
 
 func TestRuntime_InstantiateModule_ExitError(t *testing.T) {
@@ -644,7 +1612,7 @@ func TestRuntime_CloseWithExitCode(t *testing.T) {
 func TestRuntime_Close_ClosesCompiledModules(t *testing.T) {
 	engine := &mockEngine{name: "mock", cachedModules: map[*wasm.Module]struct{}{}}
 	conf := *engineLessConfig
-	conf.newEngine = func(wasm.Features) wasm.Engine {
+	conf.newEngine = func(*runtimeConfig) wasm.Engine {
 		return engine
 	}
 	r := NewRuntimeWithConfig(&conf)
@@ -662,6 +1630,75 @@ func TestRuntime_Close_ClosesCompiledModules(t *testing.T) {
 	require.Zero(t, engine.CompiledModuleCount())
 }
 
+func TestRuntime_Close_ClosesStdoutAndStderr(t *testing.T) {
+	r := NewRuntime()
+	defer r.Close(testCtx)
+
+	out := &closeTrackingWriter{}
+	code, err := r.CompileModule(testCtx, binaryNamedZero, NewCompileConfig())
+	require.NoError(t, err)
+	_, err = r.InstantiateModule(testCtx, code, NewModuleConfig().WithStdout(out).WithStderr(out).WithCloseStdio())
+	require.NoError(t, err)
+	require.Zero(t, out.closed)
+
+	require.NoError(t, r.Close(testCtx))
+	require.Equal(t, 1, out.closed)
+
+	// Closing again should not close the writer again.
+	require.NoError(t, r.Close(testCtx))
+	require.Equal(t, 1, out.closed)
+}
+
+// closeTrackingWriter is an io.WriteCloser that counts how many times it was closed.
+type closeTrackingWriter struct {
+	closed int
+}
+
+func (w *closeTrackingWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (w *closeTrackingWriter) Close() error                { w.closed++; return nil }
+
+func TestRuntime_HostFunctionPanicIsReturnedAsError(t *testing.T) {
+	r := NewRuntime()
+	defer r.Close(testCtx)
+
+	errPanic := errors.New("host panicked")
+	_, err := r.NewModuleBuilder("host").
+		ExportFunction("panic", func() { panic(errPanic) }).
+		ExportFunction("ok", func() uint32 { return 1 }).
+		Instantiate(testCtx, r)
+	require.NoError(t, err)
+
+	// Call the host functions from wasm, so that the panic unwinds through a guest frame.
+	vI32 := &wasm.FunctionType{Results: []wasm.ValueType{wasm.ValueTypeI32}}
+	m, err := r.InstantiateModuleFromBinary(testCtx, binaryformat.EncodeModule(&wasm.Module{
+		TypeSection: []*wasm.FunctionType{{}, vI32},
+		ImportSection: []*wasm.Import{
+			{Module: "host", Name: "panic", Type: wasm.ExternTypeFunc, DescFunc: 0},
+			{Module: "host", Name: "ok", Type: wasm.ExternTypeFunc, DescFunc: 1},
+		},
+		FunctionSection: []wasm.Index{0, 1},
+		CodeSection: []*wasm.Code{
+			{Body: []byte{wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeCall, 1, wasm.OpcodeEnd}},
+		},
+		ExportSection: []*wasm.Export{
+			{Name: "call_panic", Type: wasm.ExternTypeFunc, Index: 2},
+			{Name: "call_ok", Type: wasm.ExternTypeFunc, Index: 3},
+		},
+	}))
+	require.NoError(t, err)
+
+	_, err = m.ExportedFunction("call_panic").Call(testCtx)
+	require.Error(t, err)
+	require.True(t, errors.Is(err, errPanic))
+	require.Contains(t, err.Error(), "host panicked (recovered by wazero)")
+
+	// The module is still usable after recovering.
+	results, err := m.ExportedFunction("call_ok").Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1}, results)
+}
+
 // requireImportAndExportFunction re-exports a host function because only host functions can see the propagated context.
 func requireImportAndExportFunction(t *testing.T, r Runtime, hostFn func(ctx context.Context) uint64, functionName string) []byte {
 	_, err := r.NewModuleBuilder("host").ExportFunction(functionName, hostFn).Instantiate(testCtx, r)
@@ -699,3 +1736,257 @@ func (e *mockEngine) DeleteCompiledModule(module *wasm.Module) {
 func (e *mockEngine) NewModuleEngine(_ string, _ *wasm.Module, _, _ []*wasm.FunctionInstance, _ []*wasm.TableInstance, _ []wasm.TableInitEntry) (wasm.ModuleEngine, error) {
 	return nil, nil
 }
+
+func TestRuntime_InterpreterMetrics(t *testing.T) {
+	metrics := NewInterpreterMetrics()
+	r := NewRuntimeWithConfig(NewRuntimeConfigInterpreter().WithInterpreterMetrics(metrics))
+	defer r.Close(testCtx)
+
+	// sum adds 1..n to acc in a loop, so each operation in the loop body executes n times.
+	i32 := wasm.ValueTypeI32
+	bin := binaryformat.EncodeModule(&wasm.Module{
+		TypeSection:     []*wasm.FunctionType{{Params: []wasm.ValueType{i32, i32}, Results: []wasm.ValueType{i32}}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []*wasm.Code{{Body: []byte{
+			wasm.OpcodeLoop, 0x40, // loop with an empty block type
+			wasm.OpcodeLocalGet, 1, // acc
+			wasm.OpcodeLocalGet, 0, // n
+			wasm.OpcodeI32Add,
+			wasm.OpcodeLocalSet, 1, // acc = acc + n
+			wasm.OpcodeLocalGet, 0, // n
+			wasm.OpcodeI32Const, 1,
+			wasm.OpcodeI32Sub,
+			wasm.OpcodeLocalTee, 0, // n = n - 1
+			wasm.OpcodeBrIf, 0, // continue the loop while n != 0
+			wasm.OpcodeEnd,
+			wasm.OpcodeLocalGet, 1, // acc
+			wasm.OpcodeEnd,
+		}}},
+		ExportSection: []*wasm.Export{{Name: "sum", Type: wasm.ExternTypeFunc, Index: 0}},
+	})
+
+	m, err := r.InstantiateModuleFromBinary(testCtx, bin)
+	require.NoError(t, err)
+
+	results, err := m.ExportedFunction("sum").Call(testCtx, 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, uint64(55), results[0])
+
+	// Local access is lowered to stack operations (Pick, Swap and Drop), which are counted instead.
+	require.Equal(t, map[string]uint64{
+		"Add":      10,
+		"Br":       2,
+		"BrIf":     10,
+		"ConstI32": 10,
+		"Drop":     21,
+		"Pick":     41,
+		"Sub":      10,
+		"Swap":     20,
+	}, metrics.Counts())
+
+	metrics.Reset()
+	require.Equal(t, map[string]uint64{}, metrics.Counts())
+}
+
+func TestRuntime_WithGuestProfiler(t *testing.T) {
+	var profile bytes.Buffer
+	r := NewRuntimeWithConfig(NewRuntimeConfigInterpreter().WithGuestProfiler(&profile))
+
+	// "run" calls "hot", which adds 1..n in a loop, then "cold", which does nothing.
+	i32 := wasm.ValueTypeI32
+	bin := binaryformat.EncodeModule(&wasm.Module{
+		TypeSection: []*wasm.FunctionType{
+			{},
+			{Params: []wasm.ValueType{i32, i32}, Results: []wasm.ValueType{i32}},
+		},
+		FunctionSection: []wasm.Index{0, 1, 0},
+		CodeSection: []*wasm.Code{
+			{Body: append(append([]byte{wasm.OpcodeI32Const}, leb128.EncodeInt32(100_000)...),
+				wasm.OpcodeI32Const, 0, wasm.OpcodeCall, 1, wasm.OpcodeDrop,
+				wasm.OpcodeCall, 2, wasm.OpcodeEnd)},
+			{Body: []byte{
+				wasm.OpcodeLoop, 0x40, // loop with an empty block type
+				wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Add, wasm.OpcodeLocalSet, 1, // acc += n
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Sub, wasm.OpcodeLocalTee, 0, // n--
+				wasm.OpcodeBrIf, 0, // continue the loop while n != 0
+				wasm.OpcodeEnd,
+				wasm.OpcodeLocalGet, 1, // acc
+				wasm.OpcodeEnd,
+			}},
+			{Body: []byte{wasm.OpcodeEnd}},
+		},
+		ExportSection: []*wasm.Export{{Name: "run", Type: wasm.ExternTypeFunc, Index: 0}},
+		NameSection: &wasm.NameSection{
+			ModuleName:    "guest",
+			FunctionNames: wasm.NameMap{{Index: 0, Name: "run"}, {Index: 1, Name: "hot"}, {Index: 2, Name: "cold"}},
+		},
+	})
+
+	m, err := r.InstantiateModuleFromBinary(testCtx, bin)
+	require.NoError(t, err)
+
+	_, err = m.ExportedFunction("run").Call(testCtx)
+	require.NoError(t, err)
+
+	// The profile is written on close.
+	require.Zero(t, profile.Len())
+	require.NoError(t, r.Close(testCtx))
+
+	zr, err := gzip.NewReader(&profile)
+	require.NoError(t, err)
+	decoded, err := io.ReadAll(zr)
+	require.NoError(t, err)
+
+	// The function names are in the string table of the profile.
+	for _, name := range []string{"guest.run", "guest.hot", "guest.cold", "cpu", "nanoseconds"} {
+		require.True(t, bytes.Contains(decoded, []byte(name)), name)
+	}
+}
+
+func TestRuntime_Stepper(t *testing.T) {
+	stepper := NewInterpreterStepper()
+	r := NewRuntimeWithConfig(NewRuntimeConfigInterpreter().WithStepper(stepper))
+	defer r.Close(testCtx)
+
+	// double_plus_one returns x*2+1
+	i32 := wasm.ValueTypeI32
+	bin := binaryformat.EncodeModule(&wasm.Module{
+		TypeSection:     []*wasm.FunctionType{{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []*wasm.Code{{Body: []byte{
+			wasm.OpcodeLocalGet, 0, // x
+			wasm.OpcodeI32Const, 2,
+			wasm.OpcodeI32Mul,
+			wasm.OpcodeI32Const, 1,
+			wasm.OpcodeI32Add,
+			wasm.OpcodeEnd,
+		}}},
+		ExportSection: []*wasm.Export{{Name: "double_plus_one", Type: wasm.ExternTypeFunc, Index: 0}},
+	})
+
+	m, err := r.InstantiateModuleFromBinary(testCtx, bin)
+	require.NoError(t, err)
+	fn := m.ExportedFunction("double_plus_one")
+
+	// call runs fn in another goroutine, as it blocks until stepped. The results are nil on error.
+	call := func(x uint64) chan []uint64 {
+		results := make(chan []uint64, 1)
+		go func() {
+			res, _ := fn.Call(testCtx, x)
+			results <- res
+		}()
+		return results
+	}
+
+	t.Run("single step", func(t *testing.T) {
+		results := call(20)
+
+		// Zero steps pauses before the first operation, where the only value on the stack is the parameter x.
+		require.True(t, stepper.Step(0))
+		require.Equal(t, uint32(0), stepper.FunctionIndex())
+		require.Equal(t, uint64(0), stepper.PC())
+		require.Equal(t, []uint64{20}, stepper.Stack())
+
+		// Each operation after translation, noting the local x stays at the bottom of the stack until dropped.
+		for _, tc := range []struct {
+			pc  uint64
+			top uint64
+		}{
+			{pc: 1, top: 20}, // after Pick (local.get 0)
+			{pc: 2, top: 2},  // after ConstI32 2
+			{pc: 3, top: 40}, // after Mul
+			{pc: 4, top: 1},  // after ConstI32 1
+			{pc: 5, top: 41}, // after Add
+			{pc: 6, top: 41}, // after Drop (x)
+		} {
+			require.True(t, stepper.Step(1))
+			require.Equal(t, uint32(0), stepper.FunctionIndex())
+			require.Equal(t, tc.pc, stepper.PC())
+			stack := stepper.Stack()
+			require.Equal(t, tc.top, stack[len(stack)-1])
+		}
+
+		// Stepping past the end returns false.
+		require.False(t, stepper.Step(1))
+		require.Equal(t, []uint64{41}, <-results)
+	})
+
+	t.Run("multiple steps", func(t *testing.T) {
+		results := call(1)
+
+		require.True(t, stepper.Step(3))
+		require.Equal(t, uint64(3), stepper.PC())
+		require.Equal(t, []uint64{1, 2}, stepper.Stack())
+
+		// Remaining steps don't carry over to the next call.
+		require.False(t, stepper.Step(100))
+		require.Equal(t, []uint64{3}, <-results)
+	})
+}
+
+func TestRuntime_WithDeterministicNaN(t *testing.T) {
+	f32, f64 := wasm.ValueTypeF32, wasm.ValueTypeF64
+	binary := binaryformat.EncodeModule(&wasm.Module{
+		TypeSection: []*wasm.FunctionType{
+			{Params: []wasm.ValueType{f32, f32}, Results: []wasm.ValueType{f32}},
+			{Params: []wasm.ValueType{f64, f64}, Results: []wasm.ValueType{f64}},
+			{Params: []wasm.ValueType{f64}, Results: []wasm.ValueType{f32}},
+			{Params: []wasm.ValueType{f32}, Results: []wasm.ValueType{f32}},
+		},
+		FunctionSection: []wasm.Index{0, 1, 2, 3},
+		CodeSection: []*wasm.Code{
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeF32Add, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeF64Div, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeF32DemoteF64, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeF32Neg, wasm.OpcodeEnd}},
+		},
+		ExportSection: []*wasm.Export{
+			{Name: "f32.add", Type: wasm.ExternTypeFunc, Index: 0},
+			{Name: "f64.div", Type: wasm.ExternTypeFunc, Index: 1},
+			{Name: "f32.demote_f64", Type: wasm.ExternTypeFunc, Index: 2},
+			{Name: "f32.neg", Type: wasm.ExternTypeFunc, Index: 3},
+		},
+	})
+
+	const (
+		canonical32, canonical64 = uint64(0x7fc00000), uint64(0x7ff8000000000000)
+		// payload32 and payload64 are NaN with a payload, which arithmetic may propagate.
+		payload32, payload64 = uint64(0x7fa00001), uint64(0x7ff4000000000001)
+	)
+	tests := []struct {
+		fn       string
+		params   []uint64
+		expected uint64
+	}{
+		{fn: "f32.add", params: []uint64{payload32, api.EncodeF32(1)}, expected: canonical32},
+		{fn: "f32.add", params: []uint64{api.EncodeF32(1), api.EncodeF32(2)}, expected: api.EncodeF32(3)},
+		{fn: "f64.div", params: []uint64{api.EncodeF64(0), api.EncodeF64(0)}, expected: canonical64},
+		{fn: "f64.div", params: []uint64{payload64, api.EncodeF64(2)}, expected: canonical64},
+		{fn: "f32.demote_f64", params: []uint64{payload64}, expected: canonical32},
+		// neg only flips the sign bit, so the payload isn't canonicalized.
+		{fn: "f32.neg", params: []uint64{payload32}, expected: payload32 | 0x80000000},
+	}
+
+	for _, tt := range []struct {
+		name   string
+		config RuntimeConfig
+	}{
+		{name: "default", config: NewRuntimeConfig()},
+		{name: "interpreter", config: NewRuntimeConfigInterpreter()},
+	} {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRuntimeWithConfig(tc.config.WithDeterministicNaN(true))
+			defer r.Close(testCtx)
+
+			mod, err := r.InstantiateModuleFromBinary(testCtx, binary)
+			require.NoError(t, err)
+
+			for _, tc := range tests {
+				results, err := mod.ExportedFunction(tc.fn).Call(testCtx, tc.params...)
+				require.NoError(t, err)
+				require.Equal(t, tc.expected, results[0], "%s%v: expected %#x, but was %#x", tc.fn, tc.params, tc.expected, results[0])
+			}
+		})
+	}
+}
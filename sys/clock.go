@@ -19,3 +19,9 @@ type Walltime func(context.Context) (sec int64, nsec int32)
 // Note: There are no constraints on the value return except that it
 // increments. For example, -1 is a valid if the next value is >= 0.
 type Nanotime func(context.Context) int64
+
+// Nanosleep puts the current goroutine to sleep for at least ns nanoseconds, or returns early when the context is done.
+//
+// Note: This is used by functions that block on a timeout, such as "poll_oneoff" in "wasi_snapshot_preview1". An
+// implementation that controls time, ex. in tests, can advance its Nanotime instead of sleeping.
+type Nanosleep func(ctx context.Context, ns int64)
@@ -3,6 +3,7 @@ package sys
 
 import (
 	"fmt"
+	"strings"
 )
 
 // ExitError is returned to a caller of api.Function still running when api.Module CloseWithExitCode was invoked.
@@ -52,3 +53,40 @@ func (e *ExitError) Is(err error) bool {
 	}
 	return false
 }
+
+// GuestStderrError is returned by api.Function Call when the call failed, ex. on a trap, after the guest wrote to
+// stderr. This is commonly the message of a guest panic, such as TinyGo writes before "unreachable".
+//
+// Here's an example of how to get what the guest wrote:
+//	if _, err := main.Call(ctx); err != nil {
+//		var stderrErr *sys.GuestStderrError
+//		if errors.As(err, &stderrErr) {
+//			log.Printf("guest panicked: %s", stderrErr.GuestStderr())
+//		}
+//	}
+//
+// Note: Only up to the last 4KiB written to stderr during the call are retained, even if stderr was configured to
+// discard them. An ExitError is never wrapped, as exiting is not a failure of the guest.
+type GuestStderrError struct {
+	err    error
+	stderr []byte
+}
+
+func NewGuestStderrError(err error, stderr []byte) *GuestStderrError {
+	return &GuestStderrError{err: err, stderr: stderr}
+}
+
+// GuestStderr returns the last bytes the guest wrote to stderr before the call failed.
+func (e *GuestStderrError) GuestStderr() []byte {
+	return e.stderr
+}
+
+// Error implements the error interface by appending what the guest wrote to stderr to the cause.
+func (e *GuestStderrError) Error() string {
+	return fmt.Sprintf("%v\nguest stderr:\n\t%s", e.err, strings.ReplaceAll(strings.TrimRight(string(e.stderr), "\n"), "\n", "\n\t"))
+}
+
+// Unwrap allows use of errors.Is or errors.As on the cause, ex. a *wasmruntime.Error.
+func (e *GuestStderrError) Unwrap() error {
+	return e.err
+}
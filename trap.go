@@ -0,0 +1,64 @@
+package wazero
+
+import (
+	"context"
+	"errors"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasmdebug"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// TrapInfo is diagnostic state captured when a call to a guest function traps. See RuntimeConfig.WithTrapHandler
+type TrapInfo struct {
+	// Reason is the cause of the trap.
+	Reason api.TrapReason
+
+	// Module is the name of the module whose exported function was called.
+	Module string
+
+	// Function is the name of the function that trapped, in the same format as the wasm stack trace. This uses the
+	// custom name section, if present. Ex. "math.div" or "math.[3]" if the function has no name.
+	Function string
+
+	// StackTrace are the names of the functions in the call stack, beginning with Function.
+	StackTrace []string
+
+	// Stderr is up to the last 4KiB the guest wrote to stderr during the call, or nil if none were. This is commonly
+	// a panic message. See sys.GuestStderrError
+	Stderr []byte
+
+	// StackValues are up to the top 8 values of the operand stack when the trap occurred, beginning with the top. The
+	// operands of the instruction that trapped may already be popped. Values are uint64 encoded as with api.Function.
+	//
+	// Note: This is only captured by the interpreter, ex. NewRuntimeConfigInterpreter, so is nil when the runtime uses
+	// the compiler, which keeps operands in registers.
+	StackValues []uint64
+
+	// MemorySize is the size in bytes of the memory of the module, or zero if it has none.
+	MemorySize uint32
+
+	// Err is the error the call returns, which wraps an api.TrapError.
+	Err error
+}
+
+// newTrapInfo captures TrapInfo from the error of a call via the module.
+func newTrapInfo(ctx context.Context, mod *wasm.CallContext, err error) TrapInfo {
+	info := TrapInfo{Module: mod.Name(), StackTrace: wasmdebug.FunctionNames(err), Err: err}
+	if trap := api.TrapError(nil); errors.As(err, &trap) {
+		info.Reason = trap.TrapReason()
+	}
+	if len(info.StackTrace) > 0 {
+		info.Function = info.StackTrace[0]
+	}
+	info.StackValues = wasmdebug.StackValues(err)
+	if stderrErr := (*sys.GuestStderrError)(nil); errors.As(err, &stderrErr) {
+		info.Stderr = stderrErr.GuestStderr()
+	}
+	// mod.Memory is a nil *wasm.MemoryInstance, not a nil api.Memory, when the module has no memory.
+	if mem, ok := mod.Memory().(*wasm.MemoryInstance); ok && mem != nil {
+		info.MemorySize = mem.Size(ctx)
+	}
+	return info
+}
@@ -0,0 +1,38 @@
+//go:build inject_trap
+
+package wazero
+
+import (
+	"fmt"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// InjectTrap makes the next call to the function the module exports under the given name fail with a trap of the
+// given reason, instead of calling it. This returns an error if the module doesn't export a function by that name.
+//
+// This is for testing only! It avoids crafting a WebAssembly binary for each failure mode when testing how the host
+// handles traps. Ex. to test the host handles an out-of-bounds memory access:
+//
+//	_ = wazero.InjectTrap(mod, "malloc", api.TrapReasonOutOfBoundsMemory)
+//	_, err := mod.ExportedFunction("malloc").Call(ctx, 16)
+//	var trap api.TrapError
+//	if errors.As(err, &trap) && trap.TrapReason() == api.TrapReasonOutOfBoundsMemory {
+//		// the host code under test should handle this
+//	}
+//
+// Notes
+//
+//	* Calls from the guest trap as well, including indirect calls and calls to imported host functions.
+//	* The trap affects the function, not the export. Ex. if the module re-exports an imported function, calls via the
+//	  module that defined it also trap.
+//	* This only exists when built with the tag "inject_trap", ex. `go test -tags inject_trap`, so that production
+//	  builds have no cost checking for injected traps.
+func InjectTrap(mod api.Module, exportName string, reason api.TrapReason) error {
+	callCtx, ok := mod.(*wasm.CallContext)
+	if !ok {
+		return fmt.Errorf("unsupported api.Module implementation: %T", mod)
+	}
+	return callCtx.InjectTrap(exportName, reason)
+}
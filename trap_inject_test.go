@@ -0,0 +1,154 @@
+//go:build inject_trap
+
+package wazero
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	binaryformat "github.com/tetratelabs/wazero/internal/wasm/binary"
+)
+
+func TestInjectTrap(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		config RuntimeConfig
+	}{
+		{name: "default", config: NewRuntimeConfig()},
+		{name: "interpreter", config: NewRuntimeConfigInterpreter()},
+	} {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRuntimeWithConfig(tc.config)
+			defer r.Close(testCtx)
+
+			mod, err := r.InstantiateModuleFromBinary(testCtx, addWasm)
+			require.NoError(t, err)
+			add := mod.ExportedFunction("add")
+
+			require.NoError(t, InjectTrap(mod, "add", api.TrapReasonOutOfBoundsMemory))
+
+			_, err = add.Call(testCtx, 1, 2)
+			require.EqualError(t, err, `wasm error: out of bounds memory access
+wasm stack trace:
+	wasm/math.[0](i32,i32) i32`)
+			var trap api.TrapError
+			require.True(t, errors.As(err, &trap))
+			require.Equal(t, api.TrapReasonOutOfBoundsMemory, trap.TrapReason())
+
+			// Only the next call traps.
+			results, err := add.Call(testCtx, 1, 2)
+			require.NoError(t, err)
+			require.Equal(t, []uint64{3}, results)
+
+			// CallWithStack traps the same way.
+			require.NoError(t, InjectTrap(mod, "add", api.TrapReasonUnreachable))
+			err = add.CallWithStack(testCtx, []uint64{1, 2})
+			require.True(t, errors.As(err, &trap))
+			require.Equal(t, api.TrapReasonUnreachable, trap.TrapReason())
+		})
+	}
+}
+
+func TestInjectTrap_GuestCall(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		config RuntimeConfig
+	}{
+		{name: "default", config: NewRuntimeConfig()},
+		{name: "interpreter", config: NewRuntimeConfigInterpreter()},
+	} {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRuntimeWithConfig(tc.config)
+			defer r.Close(testCtx)
+
+			_, err := r.NewModuleBuilder("env").
+				ExportFunction("host", func() uint32 { return 1 }).
+				Instantiate(testCtx, r)
+			require.NoError(t, err)
+
+			one := uint32(1)
+			mod, err := r.InstantiateModuleFromBinary(testCtx, binaryformat.EncodeModule(&wasm.Module{
+				TypeSection:     []*wasm.FunctionType{{Results: []wasm.ValueType{wasm.ValueTypeI32}}},
+				ImportSection:   []*wasm.Import{{Module: "env", Name: "host", Type: wasm.ExternTypeFunc, DescFunc: 0}},
+				FunctionSection: []wasm.Index{0, 0, 0, 0},
+				CodeSection: []*wasm.Code{
+					{Body: []byte{wasm.OpcodeI32Const, 1, wasm.OpcodeEnd}},
+					{Body: []byte{wasm.OpcodeCall, 1, wasm.OpcodeEnd}},
+					{Body: []byte{wasm.OpcodeI32Const, 0, wasm.OpcodeCallIndirect, 0, 0, wasm.OpcodeEnd}},
+					{Body: []byte{wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+				},
+				TableSection: []*wasm.Table{{Min: 1, Type: wasm.RefTypeFuncref}},
+				ElementSection: []*wasm.ElementSegment{{
+					OffsetExpr: &wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}},
+					Init:       []*wasm.Index{&one},
+					Type:       wasm.RefTypeFuncref,
+					Mode:       wasm.ElementModeActive,
+				}},
+				ExportSection: []*wasm.Export{
+					{Name: "host", Type: wasm.ExternTypeFunc, Index: 0},
+					{Name: "callee", Type: wasm.ExternTypeFunc, Index: 1},
+					{Name: "call", Type: wasm.ExternTypeFunc, Index: 2},
+					{Name: "call_indirect", Type: wasm.ExternTypeFunc, Index: 3},
+					{Name: "call_host", Type: wasm.ExternTypeFunc, Index: 4},
+				},
+				NameSection: &wasm.NameSection{ModuleName: "guest"},
+			}))
+			require.NoError(t, err)
+
+			for _, c := range []struct {
+				caller, callee string
+			}{
+				{caller: "call", callee: "callee"},
+				{caller: "call_indirect", callee: "callee"},
+				{caller: "call_host", callee: "host"},
+			} {
+				require.NoError(t, InjectTrap(mod, c.callee, api.TrapReasonOutOfBoundsMemory))
+
+				_, err = mod.ExportedFunction(c.caller).Call(testCtx)
+				var trap api.TrapError
+				require.True(t, errors.As(err, &trap), c.caller)
+				require.Equal(t, api.TrapReasonOutOfBoundsMemory, trap.TrapReason(), c.caller)
+
+				// Only the next call traps.
+				results, err := mod.ExportedFunction(c.caller).Call(testCtx)
+				require.NoError(t, err, c.caller)
+				require.Equal(t, []uint64{1}, results, c.caller)
+			}
+		})
+	}
+}
+
+func TestInjectTrap_Errors(t *testing.T) {
+	r := NewRuntime()
+	defer r.Close(testCtx)
+
+	mod, err := r.InstantiateModuleFromBinary(testCtx, addWasm)
+	require.NoError(t, err)
+
+	err = InjectTrap(mod, "sub", api.TrapReasonOutOfBoundsMemory)
+	require.EqualError(t, err, `"sub" is not exported in module "wasm/math"`)
+}
+
+func TestInjectTrap_TrapHandler(t *testing.T) {
+	var infos []TrapInfo
+	r := NewRuntimeWithConfig(NewRuntimeConfig().WithTrapHandler(func(info TrapInfo) {
+		infos = append(infos, info)
+	}))
+	defer r.Close(testCtx)
+
+	mod, err := r.InstantiateModuleFromBinary(testCtx, addWasm)
+	require.NoError(t, err)
+
+	// Injected traps call the handler like any other.
+	require.NoError(t, InjectTrap(mod, "add", api.TrapReasonUnreachable))
+	err = mod.ExportedFunction("add").CallWithStack(testCtx, []uint64{1, 2})
+	require.Equal(t, 1, len(infos))
+	require.Equal(t, api.TrapReasonUnreachable, infos[0].Reason)
+	require.Equal(t, "wasm/math.[0]", infos[0].Function)
+	require.Equal(t, err, infos[0].Err)
+}
@@ -0,0 +1,98 @@
+package wazero
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	binaryformat "github.com/tetratelabs/wazero/internal/wasm/binary"
+)
+
+func TestRuntimeConfig_WithTrapHandler(t *testing.T) {
+	// "run" calls "divide", which divides 10 by its parameter, so traps on zero.
+	i32 := wasm.ValueTypeI32
+	bin := binaryformat.EncodeModule(&wasm.Module{
+		TypeSection:     []*wasm.FunctionType{{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}},
+		FunctionSection: []wasm.Index{0, 0},
+		CodeSection: []*wasm.Code{
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeCall, 1, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeI32Const, 10, wasm.OpcodeLocalGet, 0, wasm.OpcodeI32DivU, wasm.OpcodeEnd}},
+		},
+		MemorySection: &wasm.Memory{Min: 2},
+		ExportSection: []*wasm.Export{{Name: "run", Type: wasm.ExternTypeFunc, Index: 0}},
+		NameSection: &wasm.NameSection{
+			ModuleName:    "calc",
+			FunctionNames: wasm.NameMap{{Index: 0, Name: "run"}, {Index: 1, Name: "divide"}},
+		},
+	})
+
+	for _, tt := range []struct {
+		name   string
+		config RuntimeConfig
+	}{
+		{name: "default", config: NewRuntimeConfig()},
+		{name: "interpreter", config: NewRuntimeConfigInterpreter()},
+	} {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			var infos []TrapInfo
+			r := NewRuntimeWithConfig(tc.config.WithTrapHandler(func(info TrapInfo) {
+				infos = append(infos, info)
+			}))
+			defer r.Close(testCtx)
+
+			mod, err := r.InstantiateModuleFromBinary(testCtx, bin)
+			require.NoError(t, err)
+			run := mod.ExportedFunction("run")
+
+			// The handler isn't called unless the call traps.
+			results, err := run.Call(testCtx, 2)
+			require.NoError(t, err)
+			require.Equal(t, []uint64{5}, results)
+			require.Zero(t, len(infos))
+
+			// Stack values are only captured by the interpreter. The operands of i32.div_u are already popped, so the
+			// top is the parameter of "divide", then that of "run".
+			var expectedStackValues []uint64
+			if tc.config.(*runtimeConfig).engineKind == engineKindInterpreter {
+				expectedStackValues = []uint64{0, 0}
+			}
+
+			_, err = run.Call(testCtx, 0)
+			require.Error(t, err)
+			require.Equal(t, []TrapInfo{{
+				Reason:      api.TrapReasonIntegerDivideByZero,
+				Module:      "calc",
+				Function:    "calc.divide",
+				StackTrace:  []string{"calc.divide", "calc.run"},
+				StackValues: expectedStackValues,
+				MemorySize:  2 * wasm.MemoryPageSize,
+				Err:         err,
+			}}, infos)
+		})
+	}
+}
+
+func TestRuntimeConfig_WithTrapHandler_NoMemory(t *testing.T) {
+	var infos []TrapInfo
+	r := NewRuntimeWithConfig(NewRuntimeConfig().WithTrapHandler(func(info TrapInfo) {
+		infos = append(infos, info)
+	}))
+	defer r.Close(testCtx)
+
+	bin := binaryformat.EncodeModule(&wasm.Module{
+		TypeSection:     []*wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []*wasm.Code{{Body: []byte{wasm.OpcodeUnreachable, wasm.OpcodeEnd}}},
+		ExportSection:   []*wasm.Export{{Name: "run", Type: wasm.ExternTypeFunc, Index: 0}},
+	})
+	mod, err := r.InstantiateModuleFromBinary(testCtx, bin)
+	require.NoError(t, err)
+
+	_, err = mod.ExportedFunction("run").Call(testCtx)
+	require.Error(t, err)
+	require.Equal(t, 1, len(infos))
+	require.Equal(t, api.TrapReasonUnreachable, infos[0].Reason)
+	require.Zero(t, infos[0].MemorySize)
+}
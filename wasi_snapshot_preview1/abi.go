@@ -0,0 +1,236 @@
+package wasi_snapshot_preview1
+
+import (
+	"context"
+	"encoding/binary"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// This file defines the byte layout of structs in the WASI ABI. WebAssembly is little-endian, so each field is encoded
+// explicitly with binary.LittleEndian at its offset, regardless of the host byte order.
+//
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#types
+
+// https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-filetype-enumu8
+const (
+	filetypeUnknown = iota
+	filetypeBlockDevice
+	filetypeCharacterDevice
+	filetypeDirectory
+	filetypeRegularFile
+	filetypeSocketDgram
+	filetypeSocketStream
+	filetypeSymbolicLink
+)
+
+// https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-advice-enumu8
+const (
+	adviceNormal = iota
+	adviceSequential
+	adviceRandom
+	adviceWillNeed
+	adviceDontNeed
+	adviceNoReuse
+)
+
+// https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-riflags-flagsu16
+const (
+	riflagRecvPeek = 1 << iota
+	riflagRecvWaitall
+)
+
+// https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-sdflags-flagsu8
+const (
+	sdflagRd = 1 << iota
+	sdflagWr
+)
+
+// iovecSize is the size in bytes of iovec and ciovec.
+const iovecSize = 8
+
+// iovec is a region of memory for scatter/gather reads, ex. by "fd_read".
+//
+// Layout: buf uint32le at 0, buf_len uint32le at 4.
+//
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-iovec-struct
+type iovec struct {
+	buf, bufLen uint32
+}
+
+// ciovec is a region of memory for scatter/gather writes, ex. by "fd_write". It has the same layout as iovec.
+//
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-ciovec-struct
+type ciovec = iovec
+
+// encode writes the iovec to the first iovecSize bytes of b.
+func (v *iovec) encode(b []byte) {
+	binary.LittleEndian.PutUint32(b[0:], v.buf)
+	binary.LittleEndian.PutUint32(b[4:], v.bufLen)
+}
+
+// decode reads the iovec from the first iovecSize bytes of b.
+func (v *iovec) decode(b []byte) {
+	v.buf = binary.LittleEndian.Uint32(b[0:])
+	v.bufLen = binary.LittleEndian.Uint32(b[4:])
+}
+
+// readIovec reads the i-th iovec of the array at offset iovs, or returns false if it is out of range.
+func readIovec(ctx context.Context, mem api.Memory, iovs, i uint32) (v iovec, ok bool) {
+	b, ok := mem.Read(ctx, iovs+i*iovecSize, iovecSize)
+	if ok {
+		v.decode(b)
+	}
+	return
+}
+
+// fdstatSize is the size in bytes of fdstat.
+const fdstatSize = 24
+
+// fdstat is the result of "fd_fdstat_get".
+//
+// Layout: fs_filetype uint8 at 0, fs_flags uint16le at 2, fs_rights_base uint64le at 8 and fs_rights_inheriting
+// uint64le at 16. Other bytes are padding, written as zero.
+//
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-fdstat-struct
+type fdstat struct {
+	filetype                     uint8
+	flags                        uint16
+	rightsBase, rightsInheriting uint64
+}
+
+// encode writes the fdstat to the first fdstatSize bytes of b.
+func (s *fdstat) encode(b []byte) {
+	b[0] = s.filetype
+	b[1] = 0
+	binary.LittleEndian.PutUint16(b[2:], s.flags)
+	binary.LittleEndian.PutUint32(b[4:], 0)
+	binary.LittleEndian.PutUint64(b[8:], s.rightsBase)
+	binary.LittleEndian.PutUint64(b[16:], s.rightsInheriting)
+}
+
+// filestatSize is the size in bytes of filestat.
+const filestatSize = 64
+
+// filestat is the result of functions such as "fd_filestat_get".
+//
+// Layout: dev uint64le at 0, ino uint64le at 8, filetype uint8 at 16, nlink uint64le at 24, size uint64le at 32, atim
+// uint64le at 40, mtim uint64le at 48 and ctim uint64le at 56. Other bytes are padding, written as zero.
+//
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-filestat-struct
+type filestat struct {
+	dev, ino         uint64
+	filetype         uint8
+	nlink, size      uint64
+	atim, mtim, ctim uint64
+}
+
+// encode writes the filestat to the first filestatSize bytes of b.
+func (s *filestat) encode(b []byte) {
+	binary.LittleEndian.PutUint64(b[0:], s.dev)
+	binary.LittleEndian.PutUint64(b[8:], s.ino)
+	binary.LittleEndian.PutUint64(b[16:], uint64(s.filetype)) // the filetype byte, then padding
+	binary.LittleEndian.PutUint64(b[24:], s.nlink)
+	binary.LittleEndian.PutUint64(b[32:], s.size)
+	binary.LittleEndian.PutUint64(b[40:], s.atim)
+	binary.LittleEndian.PutUint64(b[48:], s.mtim)
+	binary.LittleEndian.PutUint64(b[56:], s.ctim)
+}
+
+// writeFdstat writes the fdstat to memory at offset, or returns false if it is out of range.
+func writeFdstat(ctx context.Context, mem api.Memory, offset uint32, s *fdstat) bool {
+	var b [fdstatSize]byte
+	s.encode(b[:])
+	return mem.Write(ctx, offset, b[:])
+}
+
+// writeFilestat writes the filestat to memory at offset, or returns false if it is out of range.
+func writeFilestat(ctx context.Context, mem api.Memory, offset uint32, s *filestat) bool {
+	var b [filestatSize]byte
+	s.encode(b[:])
+	return mem.Write(ctx, offset, b[:])
+}
+
+// https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-eventtype-enumu8
+const (
+	eventtypeClock = iota
+	eventtypeFdRead
+	eventtypeFdWrite
+)
+
+// subclockflagSubscriptionClockAbstime means the timeout of a subscriptionClock is an absolute time, instead of
+// relative to the current time of the clock.
+//
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-subclockflags-flagsu16
+const subclockflagSubscriptionClockAbstime = 1 << 0
+
+// subscriptionSize is the size in bytes of subscription.
+const subscriptionSize = 48
+
+// subscription is an event "poll_oneoff" waits for.
+//
+// Layout: userdata uint64le at 0, tag uint8 at 8, then the contents for the tag at 16:
+//	* eventtypeClock: id uint32le at 16, timeout uint64le at 24, precision uint64le at 32 and flags uint16le at 40.
+//	* eventtypeFdRead or eventtypeFdWrite: file_descriptor uint32le at 16.
+//
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-subscription-struct
+type subscription struct {
+	userdata uint64
+	tag      uint8
+	// clock is set when tag is eventtypeClock.
+	clock subscriptionClock
+	// fd is set when tag is eventtypeFdRead or eventtypeFdWrite.
+	fd uint32
+}
+
+// subscriptionClock is the contents of a subscription for eventtypeClock.
+//
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-subscription_clock-struct
+type subscriptionClock struct {
+	id                 uint32
+	timeout, precision uint64
+	flags              uint16
+}
+
+// decode reads the subscription from the first subscriptionSize bytes of b.
+func (s *subscription) decode(b []byte) {
+	s.userdata = binary.LittleEndian.Uint64(b[0:])
+	s.tag = b[8]
+	switch s.tag {
+	case eventtypeClock:
+		s.clock.id = binary.LittleEndian.Uint32(b[16:])
+		s.clock.timeout = binary.LittleEndian.Uint64(b[24:])
+		s.clock.precision = binary.LittleEndian.Uint64(b[32:])
+		s.clock.flags = binary.LittleEndian.Uint16(b[40:])
+	case eventtypeFdRead, eventtypeFdWrite:
+		s.fd = binary.LittleEndian.Uint32(b[16:])
+	}
+}
+
+// eventSize is the size in bytes of event.
+const eventSize = 32
+
+// event is the result of a subscription in "poll_oneoff".
+//
+// Layout: userdata uint64le at 0, error uint16le at 8, type uint8 at 10, then for eventtypeFdRead or
+// eventtypeFdWrite, nbytes uint64le at 16 and flags uint16le at 24. Other bytes are padding, written as zero.
+//
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-event-struct
+type event struct {
+	userdata  uint64
+	errno     Errno
+	eventtype uint8
+	// nbytes and flags are the fd_readwrite contents, only used for eventtypeFdRead or eventtypeFdWrite.
+	nbytes uint64
+	flags  uint16
+}
+
+// encode writes the event to the first eventSize bytes of b.
+func (e *event) encode(b []byte) {
+	binary.LittleEndian.PutUint64(b[0:], e.userdata)
+	binary.LittleEndian.PutUint16(b[8:], uint16(e.errno))
+	binary.LittleEndian.PutUint32(b[10:], uint32(e.eventtype)) // the type byte, then padding
+	binary.LittleEndian.PutUint16(b[14:], 0)
+	binary.LittleEndian.PutUint64(b[16:], e.nbytes)
+	binary.LittleEndian.PutUint64(b[24:], uint64(e.flags)) // flags, then padding
+}
@@ -0,0 +1,195 @@
+package wasi_snapshot_preview1
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// The expected bytes below are written per the WASI ABI, rather than derived from the encoders.
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#types
+
+func TestIovec(t *testing.T) {
+	v := &iovec{buf: 0x04030201, bufLen: 0x08070605}
+	expected := []byte{
+		1, 2, 3, 4, // buf uint32le
+		5, 6, 7, 8, // buf_len uint32le
+	}
+
+	b := make([]byte, iovecSize)
+	v.encode(b)
+	require.Equal(t, expected, b)
+
+	var decoded ciovec
+	decoded.decode(expected)
+	require.Equal(t, *v, decoded)
+}
+
+func TestReadIovec(t *testing.T) {
+	mem := &wasm.MemoryInstance{Buffer: []byte{
+		'?',        // iovs is after this
+		1, 0, 0, 0, // iovs[0].buf
+		2, 0, 0, 0, // iovs[0].buf_len
+		3, 0, 0, 0, // iovs[1].buf
+		4, 0, 0, 0, // iovs[1].buf_len
+	}, Min: 1}
+
+	v, ok := readIovec(testCtx, mem, 1, 1)
+	require.True(t, ok)
+	require.Equal(t, iovec{buf: 3, bufLen: 4}, v)
+
+	_, ok = readIovec(testCtx, mem, 1, 2)
+	require.False(t, ok)
+}
+
+func TestFdstat(t *testing.T) {
+	s := &fdstat{
+		filetype:         filetypeDirectory,
+		flags:            0x0201,
+		rightsBase:       0x0807060504030201,
+		rightsInheriting: 0x1817161514131211,
+	}
+	expected := []byte{
+		3,    // fs_filetype uint8
+		0,    // padding
+		1, 2, // fs_flags uint16le
+		0, 0, 0, 0, // padding
+		1, 2, 3, 4, 5, 6, 7, 8, // fs_rights_base uint64le
+		0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, // fs_rights_inheriting uint64le
+	}
+	require.Equal(t, fdstatSize, len(expected))
+
+	mem := &wasm.MemoryInstance{Buffer: make([]byte, 1+fdstatSize+1), Min: 1}
+	for i := range mem.Buffer {
+		mem.Buffer[i] = '?' // to ensure padding is written
+	}
+	require.True(t, writeFdstat(testCtx, mem, 1, s))
+	require.Equal(t, append(append([]byte{'?'}, expected...), '?'), mem.Buffer)
+
+	require.False(t, writeFdstat(testCtx, mem, 3, s))
+}
+
+func TestFilestat(t *testing.T) {
+	s := &filestat{
+		dev:      0x0107,
+		ino:      0x0207,
+		filetype: filetypeRegularFile,
+		nlink:    0x0307,
+		size:     0x0407,
+		atim:     0x0507,
+		mtim:     0x0607,
+		ctim:     0x0707,
+	}
+	expected := []byte{
+		7, 1, 0, 0, 0, 0, 0, 0, // dev uint64le
+		7, 2, 0, 0, 0, 0, 0, 0, // ino uint64le
+		4,                   // filetype uint8
+		0, 0, 0, 0, 0, 0, 0, // padding
+		7, 3, 0, 0, 0, 0, 0, 0, // nlink uint64le
+		7, 4, 0, 0, 0, 0, 0, 0, // size uint64le
+		7, 5, 0, 0, 0, 0, 0, 0, // atim uint64le
+		7, 6, 0, 0, 0, 0, 0, 0, // mtim uint64le
+		7, 7, 0, 0, 0, 0, 0, 0, // ctim uint64le
+	}
+	require.Equal(t, filestatSize, len(expected))
+
+	mem := &wasm.MemoryInstance{Buffer: make([]byte, 1+filestatSize+1), Min: 1}
+	for i := range mem.Buffer {
+		mem.Buffer[i] = '?' // to ensure padding is written
+	}
+	require.True(t, writeFilestat(testCtx, mem, 1, s))
+	require.Equal(t, append(append([]byte{'?'}, expected...), '?'), mem.Buffer)
+
+	require.False(t, writeFilestat(testCtx, mem, 3, s))
+}
+
+func TestFiletype(t *testing.T) {
+	// Values are from the "filetype" enum, in order.
+	require.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7}, []int{
+		filetypeUnknown, filetypeBlockDevice, filetypeCharacterDevice, filetypeDirectory,
+		filetypeRegularFile, filetypeSocketDgram, filetypeSocketStream, filetypeSymbolicLink,
+	})
+}
+
+func TestSubscription(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []byte
+		expected subscription
+	}{
+		{
+			name: "clock",
+			input: []byte{
+				1, 2, 3, 4, 5, 6, 7, 8, // userdata uint64le
+				eventtypeClock,      // tag uint8
+				0, 0, 0, 0, 0, 0, 0, // padding
+				1, 0, 0, 0, // id uint32le
+				0, 0, 0, 0, // padding
+				0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, // timeout uint64le
+				0x21, 0x22, 0x23, 0x24, 0x25, 0x26, 0x27, 0x28, // precision uint64le
+				1, 0, // flags uint16le
+				0, 0, 0, 0, 0, 0, // padding
+			},
+			expected: subscription{
+				userdata: 0x0807060504030201,
+				tag:      eventtypeClock,
+				clock: subscriptionClock{
+					id:        clockIDMonotonic,
+					timeout:   0x1817161514131211,
+					precision: 0x2827262524232221,
+					flags:     subclockflagSubscriptionClockAbstime,
+				},
+			},
+		},
+		{
+			name: "fd_read",
+			input: []byte{
+				1, 0, 0, 0, 0, 0, 0, 0, // userdata uint64le
+				eventtypeFdRead,     // tag uint8
+				0, 0, 0, 0, 0, 0, 0, // padding
+				3, 0, 0, 0, // file_descriptor uint32le
+				0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, // padding
+			},
+			expected: subscription{userdata: 1, tag: eventtypeFdRead, fd: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, subscriptionSize, len(tc.input))
+
+			var s subscription
+			s.decode(tc.input)
+			require.Equal(t, tc.expected, s)
+		})
+	}
+}
+
+func TestEvent(t *testing.T) {
+	e := &event{
+		userdata:  0x0807060504030201,
+		errno:     ErrnoNotsup,
+		eventtype: eventtypeFdWrite,
+		nbytes:    0x1817161514131211,
+		flags:     0x2221,
+	}
+	expected := []byte{
+		1, 2, 3, 4, 5, 6, 7, 8, // userdata uint64le
+		58, 0, // error uint16le
+		2,             // type uint8
+		0, 0, 0, 0, 0, // padding
+		0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, // nbytes uint64le
+		0x21, 0x22, // flags uint16le
+		0, 0, 0, 0, 0, 0, // padding
+	}
+	require.Equal(t, eventSize, len(expected))
+
+	b := make([]byte, eventSize)
+	for i := range b {
+		b[i] = '?' // to ensure padding is written
+	}
+	e.encode(b)
+	require.Equal(t, expected, b)
+}
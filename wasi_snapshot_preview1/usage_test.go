@@ -3,10 +3,16 @@ package wasi_snapshot_preview1
 import (
 	"bytes"
 	_ "embed"
+	"errors"
 	"testing"
 
 	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	binaryformat "github.com/tetratelabs/wazero/internal/wasm/binary"
+	"github.com/tetratelabs/wazero/internal/watzero"
+	"github.com/tetratelabs/wazero/sys"
 )
 
 // wasiArg was compiled from testdata/wasi_arg.wat
@@ -43,3 +49,280 @@ func TestInstantiateModule(t *testing.T) {
 		require.NoError(t, mod.Close(testCtx))
 	}
 }
+
+// TestInstantiateModule_ImportRenamer shows a module built against the older "wasi_unstable" ABI can be satisfied by
+// this host module by renaming its imports during compilation.
+func TestInstantiateModule_ImportRenamer(t *testing.T) {
+	r := wazero.NewRuntime()
+	defer r.Close(testCtx)
+
+	_, err := Instantiate(testCtx, r)
+	require.NoError(t, err)
+
+	bin, err := watzero.Wat2Wasm(`(module
+  (import "wasi_unstable" "args_sizes_get"
+    (func $args_sizes_get (param $result.argc i32) (param $result.argv_buf_size i32) (result (;errno;) i32)))
+  (memory 1)
+  (export "memory" (memory 0))
+  (export "args_sizes_get" (func $args_sizes_get))
+)`)
+	require.NoError(t, err)
+
+	compiled, err := r.CompileModule(testCtx, bin, wazero.NewCompileConfig().
+		WithImportRenamer(func(_ api.ExternType, oldModule, oldName string) (string, string) {
+			if oldModule == "wasi_unstable" {
+				return ModuleName, oldName
+			}
+			return oldModule, oldName
+		}))
+	require.NoError(t, err)
+
+	mod, err := r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig().WithArgs("a", "bc"))
+	require.NoError(t, err)
+
+	results, err := mod.ExportedFunction("args_sizes_get").Call(testCtx, 0, 4)
+	require.NoError(t, err)
+	require.Equal(t, ErrnoSuccess, Errno(results[0]))
+
+	argc, ok := mod.Memory().ReadUint32Le(testCtx, 0)
+	require.True(t, ok)
+	require.Equal(t, uint32(2), argc)
+	argvBufSize, ok := mod.Memory().ReadUint32Le(testCtx, 4)
+	require.True(t, ok)
+	require.Equal(t, uint32(5), argvBufSize) // "a\x00bc\x00"
+}
+
+// TestInstantiateModule_GuestStderr shows the message a guest writes to stderr before it traps, like TinyGo does on
+// panic, is retrievable from the error even though stderr discards it.
+func TestInstantiateModule_GuestStderr(t *testing.T) {
+	r := wazero.NewRuntime()
+	defer r.Close(testCtx)
+
+	_, err := Instantiate(testCtx, r)
+	require.NoError(t, err)
+
+	// "main" writes the panic message to stderr (fd 2), then hits "unreachable" as TinyGo's runtime.abort does.
+	message := "panic: boom\n"
+	i32 := wasm.ValueTypeI32
+	bin := binaryformat.EncodeModule(&wasm.Module{
+		TypeSection: []*wasm.FunctionType{
+			{Params: []wasm.ValueType{i32, i32, i32, i32}, Results: []wasm.ValueType{i32}},
+			{},
+		},
+		ImportSection:   []*wasm.Import{{Module: ModuleName, Name: functionFdWrite, Type: wasm.ExternTypeFunc, DescFunc: 0}},
+		FunctionSection: []wasm.Index{1},
+		CodeSection: []*wasm.Code{{Body: []byte{
+			wasm.OpcodeI32Const, 2, // fd
+			wasm.OpcodeI32Const, 0, // iovs
+			wasm.OpcodeI32Const, 1, // iovs_len
+			wasm.OpcodeI32Const, 0x20, // result.size
+			wasm.OpcodeCall, 0,
+			wasm.OpcodeDrop,
+			wasm.OpcodeUnreachable,
+			wasm.OpcodeEnd,
+		}}},
+		MemorySection: &wasm.Memory{Min: 1},
+		DataSection: []*wasm.DataSegment{{
+			OffsetExpression: &wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}},
+			// The iovec at offset zero points to the message after it.
+			Init: append([]byte{8, 0, 0, 0, byte(len(message)), 0, 0, 0}, message...),
+		}},
+		ExportSection: []*wasm.Export{{Name: "main", Type: wasm.ExternTypeFunc, Index: 1}},
+	})
+
+	mod, err := r.InstantiateModuleFromBinary(testCtx, bin)
+	require.NoError(t, err)
+
+	_, err = mod.ExportedFunction("main").Call(testCtx)
+	require.EqualError(t, err, `wasm error: unreachable
+wasm stack trace:
+	.[1]()
+guest stderr:
+	panic: boom`)
+
+	var stderrErr *sys.GuestStderrError
+	require.True(t, errors.As(err, &stderrErr))
+	require.Equal(t, []byte("panic: boom\n"), stderrErr.GuestStderr())
+}
+
+// TestInstantiateModule_BufferedStdout ensures stdout buffered with wazero.ModuleConfig WithBufferedStdout is flushed
+// when the guest exits via "proc_exit", even though it didn't write a trailing newline.
+func TestInstantiateModule_BufferedStdout(t *testing.T) {
+	r := wazero.NewRuntime()
+	defer r.Close(testCtx)
+
+	_, err := Instantiate(testCtx, r)
+	require.NoError(t, err)
+
+	// "_start" writes the message to stdout (fd 1) twice, then exits with code 0.
+	message := "wazero"
+	i32 := wasm.ValueTypeI32
+	writeMessage := []byte{
+		wasm.OpcodeI32Const, 1, // fd
+		wasm.OpcodeI32Const, 0, // iovs
+		wasm.OpcodeI32Const, 1, // iovs_len
+		wasm.OpcodeI32Const, 0x20, // result.size
+		wasm.OpcodeCall, 0,
+		wasm.OpcodeDrop,
+	}
+	body := append(append(writeMessage, writeMessage...), wasm.OpcodeI32Const, 0, wasm.OpcodeCall, 1, wasm.OpcodeEnd)
+	bin := binaryformat.EncodeModule(&wasm.Module{
+		TypeSection: []*wasm.FunctionType{
+			{Params: []wasm.ValueType{i32, i32, i32, i32}, Results: []wasm.ValueType{i32}},
+			{Params: []wasm.ValueType{i32}},
+			{},
+		},
+		ImportSection: []*wasm.Import{
+			{Module: ModuleName, Name: functionFdWrite, Type: wasm.ExternTypeFunc, DescFunc: 0},
+			{Module: ModuleName, Name: functionProcExit, Type: wasm.ExternTypeFunc, DescFunc: 1},
+		},
+		FunctionSection: []wasm.Index{2},
+		CodeSection:     []*wasm.Code{{Body: body}},
+		MemorySection:   &wasm.Memory{Min: 1},
+		DataSection: []*wasm.DataSegment{{
+			OffsetExpression: &wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}},
+			// The iovec at offset zero points to the message after it.
+			Init: append([]byte{8, 0, 0, 0, byte(len(message)), 0, 0, 0}, message...),
+		}},
+		ExportSection: []*wasm.Export{{Name: "_start", Type: wasm.ExternTypeFunc, Index: 2}},
+	})
+
+	compiled, err := r.CompileModule(testCtx, bin, wazero.NewCompileConfig())
+	require.NoError(t, err)
+	defer compiled.Close(testCtx)
+
+	stdout := &writeRecorder{}
+	_, err = r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig().
+		WithStdout(stdout).WithBufferedStdout(1024))
+	require.Equal(t, uint32(0), err.(*sys.ExitError).ExitCode())
+
+	// Both writes were held in the buffer, and written once on exit.
+	require.Equal(t, []string{"wazerowazero"}, stdout.writes)
+}
+
+// TestInstantiateModule_BufferedStdoutTrap ensures stdout buffered with wazero.ModuleConfig WithBufferedStdout is
+// flushed when a function call traps, even though the module isn't closed.
+func TestInstantiateModule_BufferedStdoutTrap(t *testing.T) {
+	r := wazero.NewRuntime()
+	defer r.Close(testCtx)
+
+	_, err := Instantiate(testCtx, r)
+	require.NoError(t, err)
+
+	// "run" writes the message to stdout (fd 1) twice, then traps.
+	message := "wazero"
+	i32 := wasm.ValueTypeI32
+	writeMessage := []byte{
+		wasm.OpcodeI32Const, 1, // fd
+		wasm.OpcodeI32Const, 0, // iovs
+		wasm.OpcodeI32Const, 1, // iovs_len
+		wasm.OpcodeI32Const, 0x20, // result.size
+		wasm.OpcodeCall, 0,
+		wasm.OpcodeDrop,
+	}
+	body := append(append(writeMessage, writeMessage...), wasm.OpcodeUnreachable, wasm.OpcodeEnd)
+	bin := binaryformat.EncodeModule(&wasm.Module{
+		TypeSection: []*wasm.FunctionType{
+			{Params: []wasm.ValueType{i32, i32, i32, i32}, Results: []wasm.ValueType{i32}},
+			{},
+		},
+		ImportSection: []*wasm.Import{
+			{Module: ModuleName, Name: functionFdWrite, Type: wasm.ExternTypeFunc, DescFunc: 0},
+		},
+		FunctionSection: []wasm.Index{1},
+		CodeSection:     []*wasm.Code{{Body: body}},
+		MemorySection:   &wasm.Memory{Min: 1},
+		DataSection: []*wasm.DataSegment{{
+			OffsetExpression: &wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}},
+			// The iovec at offset zero points to the message after it.
+			Init: append([]byte{8, 0, 0, 0, byte(len(message)), 0, 0, 0}, message...),
+		}},
+		ExportSection: []*wasm.Export{{Name: "run", Type: wasm.ExternTypeFunc, Index: 1}},
+	})
+
+	compiled, err := r.CompileModule(testCtx, bin, wazero.NewCompileConfig())
+	require.NoError(t, err)
+	defer compiled.Close(testCtx)
+
+	stdout := &writeRecorder{}
+	mod, err := r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig().
+		WithStdout(stdout).WithBufferedStdout(1024))
+	require.NoError(t, err)
+	defer mod.Close(testCtx)
+
+	_, err = mod.ExportedFunction("run").Call(testCtx)
+	require.Error(t, err)
+
+	// Both writes were held in the buffer, and written once on trap.
+	require.Equal(t, []string{"wazerowazero"}, stdout.writes)
+}
+
+// TestInstancePool_BufferedStdout ensures stdout buffered with wazero.ModuleConfig WithBufferedStdout is flushed when
+// an instance is returned to a wazero.InstancePool, as its system context is replaced without closing it.
+func TestInstancePool_BufferedStdout(t *testing.T) {
+	r := wazero.NewRuntime()
+	defer r.Close(testCtx)
+
+	_, err := Instantiate(testCtx, r)
+	require.NoError(t, err)
+
+	// "write" writes the message to stdout (fd 1).
+	message := "wazero"
+	i32 := wasm.ValueTypeI32
+	bin := binaryformat.EncodeModule(&wasm.Module{
+		TypeSection: []*wasm.FunctionType{
+			{Params: []wasm.ValueType{i32, i32, i32, i32}, Results: []wasm.ValueType{i32}},
+			{},
+		},
+		ImportSection: []*wasm.Import{
+			{Module: ModuleName, Name: functionFdWrite, Type: wasm.ExternTypeFunc, DescFunc: 0},
+		},
+		FunctionSection: []wasm.Index{1},
+		CodeSection: []*wasm.Code{{Body: []byte{
+			wasm.OpcodeI32Const, 1, // fd
+			wasm.OpcodeI32Const, 0, // iovs
+			wasm.OpcodeI32Const, 1, // iovs_len
+			wasm.OpcodeI32Const, 0x20, // result.size
+			wasm.OpcodeCall, 0,
+			wasm.OpcodeDrop,
+			wasm.OpcodeEnd,
+		}}},
+		MemorySection: &wasm.Memory{Min: 1},
+		DataSection: []*wasm.DataSegment{{
+			OffsetExpression: &wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}},
+			// The iovec at offset zero points to the message after it.
+			Init: append([]byte{8, 0, 0, 0, byte(len(message)), 0, 0, 0}, message...),
+		}},
+		ExportSection: []*wasm.Export{{Name: "write", Type: wasm.ExternTypeFunc, Index: 1}},
+	})
+
+	compiled, err := r.CompileModule(testCtx, bin, wazero.NewCompileConfig())
+	require.NoError(t, err)
+	defer compiled.Close(testCtx)
+
+	stdout := &writeRecorder{}
+	pool := wazero.NewInstancePool(r, compiled, wazero.NewModuleConfig().WithStdout(stdout).WithBufferedStdout(1024))
+	defer pool.Close(testCtx)
+
+	for i := 1; i <= 2; i++ {
+		mod, err := pool.Get(testCtx)
+		require.NoError(t, err)
+		_, err = mod.ExportedFunction("write").Call(testCtx)
+		require.NoError(t, err)
+		require.Equal(t, i-1, len(stdout.writes)) // still buffered
+
+		require.NoError(t, pool.Put(testCtx, mod))
+		require.Equal(t, i, len(stdout.writes))
+		require.Equal(t, message, stdout.writes[i-1])
+	}
+}
+
+// writeRecorder is an io.Writer that records each call to Write.
+type writeRecorder struct {
+	writes []string
+}
+
+func (w *writeRecorder) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, string(p))
+	return len(p), nil
+}
@@ -20,7 +20,11 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"math"
+	"os"
 	"path"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/tetratelabs/wazero"
@@ -135,7 +139,7 @@ const (
 
 	// importFdAdvise is the WebAssembly 1.0 (20191205) Text format import of functionFdAdvise.
 	importFdAdvise = `(import "wasi_snapshot_preview1" "fd_advise"
-    (func $wasi.fd_advise (param $fd i32) (param $offset i64) (param $len i64) (param $result.advice i32) (result (;errno;) i32)))`
+    (func $wasi.fd_advise (param $fd i32) (param $offset i64) (param $len i64) (param $advice i32) (result (;errno;) i32)))`
 
 	// functionFdAllocate forces the allocation of space in a file.
 	// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-fd_allocatefd-fd-offset-filesize-len-filesize---errno
@@ -377,7 +381,7 @@ const (
 	importPathUnlinkFile = `(import "wasi_snapshot_preview1" "path_unlink_file"
     (func $wasi.path_unlink_file (param $fd i32) (param $path i32) (param $path_len i32) (result (;errno;) i32)))`
 
-	// functionPollOneoff unlinks a file.
+	// functionPollOneoff concurrently polls for the occurrence of a set of events.
 	// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-poll_oneoffin-constpointersubscription-out-pointerevent-nsubscriptions-size---errno-size
 	functionPollOneoff = "poll_oneoff"
 
@@ -560,7 +564,7 @@ func wasiFunctions() map[string]interface{} {
 // See https://en.wikipedia.org/wiki/Null-terminated_string
 func (a *wasi) ArgsGet(ctx context.Context, mod api.Module, argv, argvBuf uint32) Errno {
 	sysCtx := getSysCtx(mod)
-	return writeOffsetsAndNullTerminatedValues(ctx, mod.Memory(), sysCtx.Args(), argv, argvBuf)
+	return writeOffsetsAndNullTerminatedValues(ctx, mod.Memory(), sysCtx.Args(), argv, argvBuf, sysCtx.ArgsSize())
 }
 
 // ArgsSizesGet is the WASI function named functionArgsSizesGet that reads command-line argument data (WithArgs)
@@ -629,8 +633,8 @@ func (a *wasi) ArgsSizesGet(ctx context.Context, mod api.Module, resultArgc, res
 // See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#environ_get
 // See https://en.wikipedia.org/wiki/Null-terminated_string
 func (a *wasi) EnvironGet(ctx context.Context, mod api.Module, environ uint32, environBuf uint32) Errno {
-	env := getSysCtx(mod).Environ()
-	return writeOffsetsAndNullTerminatedValues(ctx, mod.Memory(), env, environ, environBuf)
+	sysCtx := getSysCtx(mod)
+	return writeOffsetsAndNullTerminatedValues(ctx, mod.Memory(), sysCtx.Environ(), environ, environBuf, sysCtx.EnvironSize())
 }
 
 // EnvironSizesGet is the WASI function named functionEnvironSizesGet that reads environment variable
@@ -755,20 +759,103 @@ func (a *wasi) ClockTimeGet(ctx context.Context, mod api.Module, id uint32, prec
 	return ErrnoSuccess
 }
 
-// FdAdvise is the WASI function named functionFdAdvise and is stubbed for GrainLang per #271
-func (a *wasi) FdAdvise(ctx context.Context, mod api.Module, fd uint32, offset, len uint64, resultAdvice uint32) Errno {
-	return ErrnoNosys // stubbed for GrainLang per #271
+// FdAdvise is the WASI function to announce how a file will be accessed. As the advice is optional, this only
+// validates the parameters.
+//
+// * fd - the file descriptor the advice applies to
+// * offset - the offset of the region the advice applies to
+// * len - the length of the region the advice applies to
+// * advice - how the region will be accessed, ex. adviceSequential
+//
+// The wasi_snapshot_preview1.Errno returned is wasi_snapshot_preview1.ErrnoSuccess except the following error conditions:
+// * wasi_snapshot_preview1.ErrnoBadf - if `fd` is invalid
+// * wasi_snapshot_preview1.ErrnoInval - if `advice` is invalid
+//
+// Note: importFdAdvise shows this signature in the WebAssembly 1.0 (20191205) Text Format.
+// Note: This is similar to `posix_fadvise` in POSIX.
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-fd_advisefd-fd-offset-filesize-len-filesize-advice-advice---errno
+// See https://linux.die.net/man/2/posix_fadvise
+func (a *wasi) FdAdvise(ctx context.Context, mod api.Module, fd uint32, offset, len uint64, advice uint32) Errno {
+	if _, errno := openedFileEntryWithRights(ctx, mod, fd, rightFdAdvise); errno != ErrnoSuccess {
+		return errno
+	}
+	if advice > adviceNoReuse {
+		return ErrnoInval
+	}
+	return ErrnoSuccess
 }
 
-// FdAllocate is the WASI function named functionFdAllocate and is stubbed for GrainLang per #271
+// FdAllocate is the WASI function to ensure a file is at least large enough to hold a region, extending it with
+// zeros if not.
+//
+// * fd - the file descriptor of the file to extend
+// * offset - the offset of the region to allocate
+// * len - the length of the region to allocate
+//
+// The wasi_snapshot_preview1.Errno returned is wasi_snapshot_preview1.ErrnoSuccess except the following error conditions:
+// * wasi_snapshot_preview1.ErrnoBadf - if `fd` is invalid or a directory
+// * wasi_snapshot_preview1.ErrnoInval - if `len` is zero
+// * wasi_snapshot_preview1.ErrnoFbig - if `offset` plus `len` overflows the maximum file size
+// * wasi_snapshot_preview1.ErrnoRofs - if the file isn't writable, ex. from an fs.FS that only reads files
+// * wasi_snapshot_preview1.ErrnoNotsup - if the file is writable, but can't be extended, as it has no Truncate method
+// * wasi_snapshot_preview1.ErrnoIo - if the file failed to extend
+//
+// Note: importFdAllocate shows this signature in the WebAssembly 1.0 (20191205) Text Format.
+// Note: This is similar to `posix_fallocate` in POSIX, except the space may not be reserved on disk.
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-fd_allocatefd-fd-offset-filesize-len-filesize---errno
+// See https://linux.die.net/man/3/posix_fallocate
 func (a *wasi) FdAllocate(ctx context.Context, mod api.Module, fd uint32, offset, len uint64) Errno {
-	return ErrnoNosys // stubbed for GrainLang per #271
+	f, errno := openedFile(ctx, mod, fd, rightFdAllocate)
+	if errno != ErrnoSuccess {
+		return errno
+	}
+	if len == 0 {
+		return ErrnoInval
+	}
+	size := offset + len
+	if size < offset || size > math.MaxInt64 {
+		return ErrnoFbig
+	}
+
+	// fs.FS doesn't declare io.Writer or Truncate, but implementations such as os.File implement them.
+	if _, ok := f.(io.Writer); !ok {
+		return ErrnoRofs
+	}
+	t, ok := f.(truncater)
+	if !ok {
+		return ErrnoNotsup
+	}
+
+	st, err := f.Stat()
+	if err != nil {
+		return ErrnoIo
+	}
+	if st.IsDir() {
+		return ErrnoBadf
+	}
+	if st.Size() >= int64(size) {
+		return ErrnoSuccess // Never shrink the file.
+	}
+	if err = t.Truncate(int64(size)); err != nil {
+		return ErrnoIo
+	}
+	return ErrnoSuccess
+}
+
+// truncater is implemented by files that can change size, such as os.File.
+type truncater interface {
+	Truncate(size int64) error
 }
 
-// FdClose is the WASI function to close a file descriptor. This returns ErrnoBadf if the fd is invalid.
+// FdClose is the WASI function to close a file descriptor. Once closed, PathOpen can re-use the file descriptor number.
 //
 // * fd - the file descriptor to close
 //
+// The wasi_snapshot_preview1.Errno returned is wasi_snapshot_preview1.ErrnoSuccess except the following error conditions:
+// * wasi_snapshot_preview1.ErrnoBadf - if `fd` is invalid
+// * wasi_snapshot_preview1.ErrnoNotsup - if `fd` is a pre-opened directory, as closing it would revoke its capability
+// * wasi_snapshot_preview1.ErrnoIo - if the file failed to close
+//
 // Note: importFdClose shows this signature in the WebAssembly 1.0 (20191205) Text Format.
 // Note: This is similar to `close` in POSIX.
 // See https://github.com/WebAssembly/WASI/blob/main/phases/snapshot/docs.md#fd_close
@@ -776,6 +863,12 @@ func (a *wasi) FdAllocate(ctx context.Context, mod api.Module, fd uint32, offset
 func (a *wasi) FdClose(ctx context.Context, mod api.Module, fd uint32) Errno {
 	_, fsc := sysFSCtx(ctx, mod)
 
+	if f, errno := openedFileEntry(ctx, mod, fd); errno != ErrnoSuccess {
+		return errno
+	} else if f.File == nil { // File is nil for a pre-opened directory like "." or "/"
+		return ErrnoNotsup
+	}
+
 	if ok, err := fsc.CloseFile(fd); err != nil {
 		return ErrnoIo
 	} else if !ok {
@@ -785,9 +878,22 @@ func (a *wasi) FdClose(ctx context.Context, mod api.Module, fd uint32) Errno {
 	return ErrnoSuccess
 }
 
-// FdDatasync is the WASI function named functionFdDatasync and is stubbed for GrainLang per #271
+// FdDatasync is the WASI function to synchronize the data of a file to disk. This is implemented the same as FdSync,
+// as Go has no portable means to synchronize data without also synchronizing metadata.
+//
+// * fd - the file descriptor to synchronize
+//
+// The wasi_snapshot_preview1.Errno returned is wasi_snapshot_preview1.ErrnoSuccess except the following error conditions:
+// * wasi_snapshot_preview1.ErrnoBadf - if `fd` is invalid
+// * wasi_snapshot_preview1.ErrnoIo - if the file failed to synchronize
+//
+// Note: importFdDatasync shows this signature in the WebAssembly 1.0 (20191205) Text Format.
+// Note: This is similar to `fdatasync` in POSIX.
+// See FdSync
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#fd_datasync
+// See https://linux.die.net/man/2/fdatasync
 func (a *wasi) FdDatasync(ctx context.Context, mod api.Module, fd uint32) Errno {
-	return ErrnoNosys // stubbed for GrainLang per #271
+	return syncFD(ctx, mod, fd, rightFdDatasync)
 }
 
 // FdFdstatGet is the WASI function to return the attributes of a file descriptor.
@@ -798,6 +904,7 @@ func (a *wasi) FdDatasync(ctx context.Context, mod api.Module, fd uint32) Errno
 // The wasi_snapshot_preview1.Errno returned is wasi_snapshot_preview1.ErrnoSuccess except the following error conditions:
 // * wasi_snapshot_preview1.ErrnoBadf - if `fd` is invalid
 // * wasi_snapshot_preview1.ErrnoFault - if `resultFdstat` contains an invalid offset due to the memory constraint
+// * wasi_snapshot_preview1.ErrnoIo - if the file couldn't be stat'ed
 //
 // fdstat byte layout is 24-byte size, which as the following elements in order
 // * fs_filetype 1 byte, to indicate the file type
@@ -819,14 +926,27 @@ func (a *wasi) FdDatasync(ctx context.Context, mod api.Module, fd uint32) Errno
 //
 // Note: importFdFdstatGet shows this signature in the WebAssembly 1.0 (20191205) Text Format.
 // Note: FdFdstatGet returns similar flags to `fsync(fd, F_GETFL)` in POSIX, as well as additional fields.
+// Note: The rights are those granted by PathOpen, or all rights for a file descriptor it didn't open, such as a
+// pre-opened directory. `fs_flags` is always zero, as they aren't tracked.
 // See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#fdstat
 // See https://github.com/WebAssembly/WASI/blob/main/phases/snapshot/docs.md#fd_fdstat_get
 // See https://linux.die.net/man/3/fsync
 func (a *wasi) FdFdstatGet(ctx context.Context, mod api.Module, fd uint32, resultStat uint32) Errno {
-	_, fsc := sysFSCtx(ctx, mod)
+	f, errno := openedFileEntry(ctx, mod, fd)
+	if errno != ErrnoSuccess {
+		return errno
+	}
 
-	if _, ok := fsc.OpenedFile(fd); !ok {
-		return ErrnoBadf
+	stat := &fdstat{rightsBase: rightsBase(f), rightsInheriting: rightsInheriting(f)}
+	if f.File == nil { // a mount like "." or "/"
+		stat.filetype = filetypeDirectory
+	} else if info, err := f.File.Stat(); err != nil {
+		return ErrnoIo
+	} else {
+		stat.filetype = filetypeOf(info.Mode())
+	}
+	if !writeFdstat(ctx, mod.Memory(), resultStat, stat) {
+		return ErrnoFault
 	}
 	return ErrnoSuccess
 }
@@ -859,11 +979,9 @@ func (a *wasi) FdFdstatGet(ctx context.Context, mod api.Module, fd uint32, resul
 // See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#prestat
 // See https://github.com/WebAssembly/WASI/blob/main/phases/snapshot/docs.md#fd_prestat_get
 func (a *wasi) FdPrestatGet(ctx context.Context, mod api.Module, fd uint32, resultPrestat uint32) Errno {
-	_, fsc := sysFSCtx(ctx, mod)
-
-	entry, ok := fsc.OpenedFile(fd)
-	if !ok {
-		return ErrnoBadf
+	entry, errno := preopenedDir(ctx, mod, fd)
+	if errno != ErrnoSuccess {
+		return errno
 	}
 
 	// Zero-value 8-bit tag, and 3-byte zero-value paddings, which is uint32le(0) in short.
@@ -889,9 +1007,53 @@ func (a *wasi) FdFdstatSetRights(ctx context.Context, mod api.Module, fd uint32,
 	return ErrnoNosys // stubbed for GrainLang per #271
 }
 
-// FdFilestatGet is the WASI function named functionFdFilestatGet
+// FdFilestatGet is the WASI function to return the attributes of an open file.
+//
+// * fd - the file descriptor to get the filestat attributes of
+// * resultBuf - the offset to write the result filestat data
+//
+// The wasi_snapshot_preview1.Errno returned is wasi_snapshot_preview1.ErrnoSuccess except the following error conditions:
+// * wasi_snapshot_preview1.ErrnoBadf - if `fd` is invalid
+// * wasi_snapshot_preview1.ErrnoFault - if `resultBuf` contains an invalid offset due to the memory constraint
+// * wasi_snapshot_preview1.ErrnoIo - if the file couldn't be stat'ed
+//
+// filestat byte layout is 64-byte size. See filestat for the offset of each field.
+//
+// Note: importFdFilestatGet shows this signature in the WebAssembly 1.0 (20191205) Text Format.
+// Note: fs.FileInfo doesn't portably expose the access or status change time, so `atim` and `ctim` are the same as
+// `mtim`. Likewise, `dev` and `ino` are zero and `nlink` is one.
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-fd_filestat_getfd-fd---errno-filestat
+// See https://linux.die.net/man/3/fstat
 func (a *wasi) FdFilestatGet(ctx context.Context, mod api.Module, fd uint32, resultBuf uint32) Errno {
-	return ErrnoNosys // stubbed for GrainLang per #271
+	entry, errno := openedFileEntryWithRights(ctx, mod, fd, rightFdFilestatGet)
+	if errno != ErrnoSuccess {
+		return errno
+	}
+
+	var info fs.FileInfo
+	var err error
+	if entry.File != nil {
+		info, err = entry.File.Stat()
+	} else { // a mount like "." or "/"
+		info, err = fs.Stat(entry.FS, ".")
+	}
+	if err != nil {
+		return ErrnoIo
+	}
+
+	mtim := uint64(info.ModTime().UnixNano())
+	stat := &filestat{
+		filetype: filetypeOf(info.Mode()),
+		nlink:    1,
+		size:     uint64(info.Size()),
+		atim:     mtim,
+		mtim:     mtim,
+		ctim:     mtim,
+	}
+	if !writeFilestat(ctx, mod.Memory(), resultBuf, stat) {
+		return ErrnoFault
+	}
+	return ErrnoSuccess
 }
 
 // FdFilestatSetSize is the WASI function named functionFdFilestatSetSize
@@ -899,9 +1061,43 @@ func (a *wasi) FdFilestatSetSize(ctx context.Context, mod api.Module, fd uint32,
 	return ErrnoNosys // stubbed for GrainLang per #271
 }
 
-// FdFilestatSetTimes is the WASI function named functionFdFilestatSetTimes
+// FdFilestatSetTimes is the WASI function to adjust the access and modification times of an open file.
+//
+// * fd - the file descriptor of the file to adjust
+// * atim - the access time in nanoseconds since the epoch, used when `fstFlags` includes FSTFLAGS_ATIM
+// * mtim - the modification time in nanoseconds since the epoch, used when `fstFlags` includes FSTFLAGS_MTIM
+// * fstFlags - which times to set. FSTFLAGS_ATIM_NOW or FSTFLAGS_MTIM_NOW set the time to sys.Walltime
+//
+// The wasi_snapshot_preview1.Errno returned is wasi_snapshot_preview1.ErrnoSuccess except the following error conditions:
+// * wasi_snapshot_preview1.ErrnoBadf - if `fd` is invalid
+// * wasi_snapshot_preview1.ErrnoInval - if `fstFlags` has unknown bits or both an explicit and _NOW flag for one time
+// * wasi_snapshot_preview1.ErrnoNotsup - if `fd` isn't in a file system, ex. a socket
+// * wasi_snapshot_preview1.ErrnoRofs - if the file system of `fd` cannot change file times
+// * wasi_snapshot_preview1.ErrnoIo - if other error happens during the operation of the underying file system.
+//
+// Note: importFdFilestatSetTimes shows this signature in the WebAssembly 1.0 (20191205) Text Format.
+// Note: This is similar to `futimens` in POSIX.
+// Note: File times can only be changed when the file system implements sys.ChtimesFS.
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-fd_filestat_set_timesfd-fd-atim-timestamp-mtim-timestamp-fst_flags-fstflags---errno
+// See https://linux.die.net/man/3/futimens
 func (a *wasi) FdFilestatSetTimes(ctx context.Context, mod api.Module, fd uint32, atim, mtim uint64, fstFlags uint32) Errno {
-	return ErrnoNosys // stubbed for GrainLang per #271
+	entry, errno := openedFileEntryWithRights(ctx, mod, fd, rightFdFilestatSetTimes)
+	if errno != ErrnoSuccess {
+		return errno
+	} else if entry.FS == nil {
+		return ErrnoNotsup
+	}
+
+	atime, mtime, errno := filestatTimes(ctx, getSysCtx(mod), atim, mtim, fstFlags)
+	if errno != ErrnoSuccess {
+		return errno
+	}
+
+	pathName, errno := resolvePath(entry.Path, ".")
+	if errno != ErrnoSuccess {
+		return errno
+	}
+	return chtimes(entry.FS, pathName, atime, mtime)
 }
 
 // FdPread is the WASI function named functionFdPread
@@ -917,7 +1113,7 @@ func (a *wasi) FdPread(ctx context.Context, mod api.Module, fd, iovs, iovsCount
 //   * This should match the uint32le FdPrestatGet writes to offset `resultPrestat`+4
 //
 // The wasi_snapshot_preview1.Errno returned is wasi_snapshot_preview1.ErrnoSuccess except the following error conditions:
-// * wasi_snapshot_preview1.ErrnoBadf - if `fd` is invalid
+// * wasi_snapshot_preview1.ErrnoBadf - if `fd` is invalid or the `fd` is not a pre-opened directory.
 // * wasi_snapshot_preview1.ErrnoFault - if `path` is an invalid offset due to the memory constraint
 // * wasi_snapshot_preview1.ErrnoNametoolong - if `pathLen` is longer than the actual length of the result path
 //
@@ -934,11 +1130,9 @@ func (a *wasi) FdPread(ctx context.Context, mod api.Module, fd, iovs, iovsCount
 // See FdPrestatGet
 // See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#fd_prestat_dir_name
 func (a *wasi) FdPrestatDirName(ctx context.Context, mod api.Module, fd uint32, pathPtr uint32, pathLen uint32) Errno {
-	_, fsc := sysFSCtx(ctx, mod)
-
-	f, ok := fsc.OpenedFile(fd)
-	if !ok {
-		return ErrnoBadf
+	f, errno := preopenedDir(ctx, mod, fd)
+	if errno != ErrnoSuccess {
+		return errno
 	}
 
 	// Some runtimes may have another semantics. See /RATIONALE.md
@@ -968,6 +1162,7 @@ func (a *wasi) FdPwrite(ctx context.Context, mod api.Module, fd, iovs, iovsCount
 //
 // The wasi_snapshot_preview1.Errno returned is wasi_snapshot_preview1.ErrnoSuccess except the following error conditions:
 // * wasi_snapshot_preview1.ErrnoBadf - if `fd` is invalid
+// * wasi_snapshot_preview1.ErrnoNotcapable - if `fd` was opened without the right to read, ex. write-only
 // * wasi_snapshot_preview1.ErrnoFault - if `iovs` or `resultSize` contain an invalid offset due to the memory constraint
 // * wasi_snapshot_preview1.ErrnoIo - if an IO related error happens during the operation
 //
@@ -1002,34 +1197,27 @@ func (a *wasi) FdPwrite(ctx context.Context, mod api.Module, fd, iovs, iovsCount
 // See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#iovec
 // See https://linux.die.net/man/3/readv
 func (a *wasi) FdRead(ctx context.Context, mod api.Module, fd, iovs, iovsCount, resultSize uint32) Errno {
-	sysCtx, fsCtx := sysFSCtx(ctx, mod)
-
 	var reader io.Reader
 
 	if fd == fdStdin {
-		reader = sysCtx.Stdin()
-	} else if f, ok := fsCtx.OpenedFile(fd); !ok || f.File == nil {
-		return ErrnoBadf
+		reader = getSysCtx(mod).Stdin()
+	} else if f, errno := openedFile(ctx, mod, fd, rightFdRead); errno != ErrnoSuccess {
+		return errno
 	} else {
-		reader = f.File
+		reader = f
 	}
 
 	var nread uint32
 	for i := uint32(0); i < iovsCount; i++ {
-		iovPtr := iovs + i*8
-		offset, ok := mod.Memory().ReadUint32Le(ctx, iovPtr)
-		if !ok {
-			return ErrnoFault
-		}
-		l, ok := mod.Memory().ReadUint32Le(ctx, iovPtr+4)
+		iov, ok := readIovec(ctx, mod.Memory(), iovs, i)
 		if !ok {
 			return ErrnoFault
 		}
-		b, ok := mod.Memory().Read(ctx, offset, l)
+		b, ok := mod.Memory().Read(ctx, iov.buf, iov.bufLen)
 		if !ok {
 			return ErrnoFault
 		}
-		n, err := reader.Read(b) // Note: n <= l
+		n, err := reader.Read(b) // Note: n <= iov.bufLen
 		nread += uint32(n)
 		if errors.Is(err, io.EOF) {
 			break
@@ -1048,9 +1236,37 @@ func (a *wasi) FdReaddir(ctx context.Context, mod api.Module, fd, buf, bufLen ui
 	return ErrnoNosys // stubbed for GrainLang per #271
 }
 
-// FdRenumber is the WASI function named functionFdRenumber
+// FdRenumber is the WASI function to atomically replace a file descriptor by renumbering another file descriptor.
+// Any file already opened at `to` is closed, and `fd` is invalid after this returns.
+//
+// * fd - the file descriptor to renumber
+// * to - the file descriptor to replace with `fd`
+//
+// The wasi_snapshot_preview1.Errno returned is wasi_snapshot_preview1.ErrnoSuccess except the following error conditions:
+// * wasi_snapshot_preview1.ErrnoBadf - if `fd` is invalid or `to` is a standard stream (stdin, stdout or stderr)
+// * wasi_snapshot_preview1.ErrnoNotsup - if `fd` or `to` is a pre-opened directory, as that would revoke its capability
+// * wasi_snapshot_preview1.ErrnoIo - if the file previously opened at `to` failed to close
+//
+// Note: importFdRenumber shows this signature in the WebAssembly 1.0 (20191205) Text Format.
+// Note: This is similar to `dup2` in POSIX, except `fd` is closed.
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-fd_renumberfd-fd-to-fd---errno
+// See https://linux.die.net/man/3/dup2
 func (a *wasi) FdRenumber(ctx context.Context, mod api.Module, fd, to uint32) Errno {
-	return ErrnoNosys // stubbed for GrainLang per #271
+	_, fsc := sysFSCtx(ctx, mod)
+
+	f, errno := openedFileEntry(ctx, mod, fd)
+	if errno != ErrnoSuccess {
+		return errno
+	} else if to <= fdStderr { // Standard streams aren't in the file table, so can't be replaced.
+		return ErrnoBadf
+	} else if existing, ok := fsc.OpenedFile(to); f.File == nil || (ok && existing.File == nil) {
+		return ErrnoNotsup // File is nil for a pre-opened directory like "." or "/"
+	}
+
+	if _, err := fsc.RenumberFile(fd, to); err != nil {
+		return ErrnoIo
+	}
+	return ErrnoSuccess
 }
 
 // FdSeek is the WASI function to move the offset of a file descriptor.
@@ -1065,6 +1281,7 @@ func (a *wasi) FdRenumber(ctx context.Context, mod api.Module, fd, to uint32) Er
 //
 // The wasi_snapshot_preview1.Errno returned is wasi_snapshot_preview1.ErrnoSuccess except the following error conditions:
 // * wasi_snapshot_preview1.ErrnoBadf - if `fd` is invalid
+// * wasi_snapshot_preview1.ErrnoNotcapable - if `fd` was opened without the right to seek
 // * wasi_snapshot_preview1.ErrnoFault - if `resultNewoffset` is an invalid offset in `mod.Memory` due to the memory constraint
 // * wasi_snapshot_preview1.ErrnoInval - if `whence` is an invalid value
 // * wasi_snapshot_preview1.ErrnoIo - if other error happens during the operation of the underying file system
@@ -1085,14 +1302,14 @@ func (a *wasi) FdRenumber(ctx context.Context, mod api.Module, fd, to uint32) Er
 // See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#fd_seek
 // See https://linux.die.net/man/3/lseek
 func (a *wasi) FdSeek(ctx context.Context, mod api.Module, fd uint32, offset uint64, whence uint32, resultNewoffset uint32) Errno {
-	_, fsc := sysFSCtx(ctx, mod)
-
-	var seeker io.Seeker
 	// Check to see if the file descriptor is available
-	if f, ok := fsc.OpenedFile(fd); !ok || f.File == nil {
-		return ErrnoBadf
-		// fs.FS doesn't declare io.Seeker, but implementations such as os.File implement it.
-	} else if seeker, ok = f.File.(io.Seeker); !ok {
+	f, errno := openedFile(ctx, mod, fd, rightFdSeek)
+	if errno != ErrnoSuccess {
+		return errno
+	}
+	// fs.FS doesn't declare io.Seeker, but implementations such as os.File implement it.
+	seeker, ok := f.(io.Seeker)
+	if !ok {
 		return ErrnoBadf
 	}
 
@@ -1104,21 +1321,107 @@ func (a *wasi) FdSeek(ctx context.Context, mod api.Module, fd uint32, offset uin
 		return ErrnoIo
 	}
 
-	if !mod.Memory().WriteUint32Le(ctx, resultNewoffset, uint32(newOffset)) {
+	if !mod.Memory().WriteUint64Le(ctx, resultNewoffset, uint64(newOffset)) {
 		return ErrnoFault
 	}
 
 	return ErrnoSuccess
 }
 
-// FdSync is the WASI function named functionFdSync
+// FdSync is the WASI function to synchronize the data and metadata of a file to disk.
+//
+// * fd - the file descriptor to synchronize
+//
+// The wasi_snapshot_preview1.Errno returned is wasi_snapshot_preview1.ErrnoSuccess except the following error conditions:
+// * wasi_snapshot_preview1.ErrnoBadf - if `fd` is invalid
+// * wasi_snapshot_preview1.ErrnoIo - if the file failed to synchronize
+//
+// The file is synchronized when it implements `Sync() error`, such as os.File. Otherwise, this is a no-op that returns
+// wasi_snapshot_preview1.ErrnoSuccess, which includes stdio and pre-opened directories.
+//
+// Note: importFdSync shows this signature in the WebAssembly 1.0 (20191205) Text Format.
+// Note: This is similar to `fsync` in POSIX.
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#fd_sync
+// See https://linux.die.net/man/2/fsync
 func (a *wasi) FdSync(ctx context.Context, mod api.Module, fd uint32) Errno {
-	return ErrnoNosys // stubbed for GrainLang per #271
+	return syncFD(ctx, mod, fd, rightFdSync)
 }
 
-// FdTell is the WASI function named functionFdTell
+// syncer is implemented by files that can be synchronized to disk, such as os.File.
+type syncer interface {
+	Sync() error
+}
+
+// syncFD implements FdSync and FdDatasync, which require the given right.
+func syncFD(ctx context.Context, mod api.Module, fd uint32, right uint64) Errno {
+	switch fd {
+	case fdStdin, fdStdout, fdStderr:
+		return ErrnoSuccess // stdio are streams, which have nothing to synchronize.
+	}
+
+	f, errno := openedFileEntryWithRights(ctx, mod, fd, right)
+	if errno != ErrnoSuccess {
+		return errno
+	}
+	if s, ok := f.File.(syncer); ok { // false when File is nil, as is the case for a pre-opened directory.
+		if err := s.Sync(); err != nil {
+			return ErrnoIo
+		}
+	}
+	return ErrnoSuccess
+}
+
+// FdTell is the WASI function to return the current offset of a file descriptor, without changing it.
+//
+// * fd: the file descriptor to return the offset of
+// * resultOffset: the offset in `mod.Memory` to write the current offset to, relative to start of the file
+//
+// The wasi_snapshot_preview1.Errno returned is wasi_snapshot_preview1.ErrnoSuccess except the following error conditions:
+// * wasi_snapshot_preview1.ErrnoBadf - if `fd` is invalid
+// * wasi_snapshot_preview1.ErrnoSpipe - if `fd` is not seekable, such as stdio
+// * wasi_snapshot_preview1.ErrnoFault - if `resultOffset` is an invalid offset in `mod.Memory` due to the memory constraint
+// * wasi_snapshot_preview1.ErrnoIo - if other error happens during the operation of the underying file system
+//
+// For example, if fd 3 is a file with offset 4, and parameter resultOffset=1, this function writes the below to
+// `mod.Memory`:
+//
+//                        uint64le
+//                 +--------------------+
+//                 |                    |
+//       []byte{?, 4, 0, 0, 0, 0, 0, 0, 0, ? }
+//  resultOffset --^
+//
+// The offset is the same one read and updated by FdRead and FdSeek, as it is tracked by the opened file.
+//
+// Note: importFdTell shows this signature in the WebAssembly 1.0 (20191205) Text Format.
+// Note: This is similar to `lseek(fd, 0, SEEK_CUR)` in POSIX.
+// See FdSeek
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-fd_tellfd-fd---errno-filesize
 func (a *wasi) FdTell(ctx context.Context, mod api.Module, fd, resultOffset uint32) Errno {
-	return ErrnoNosys // stubbed for GrainLang per #271
+	switch fd {
+	case fdStdin, fdStdout, fdStderr:
+		return ErrnoSpipe // stdio are streams, which have no offset.
+	}
+
+	f, errno := openedFile(ctx, mod, fd, rightFdTell)
+	if errno != ErrnoSuccess {
+		return errno
+	}
+	// fs.FS doesn't declare io.Seeker, but implementations such as os.File implement it.
+	seeker, ok := f.(io.Seeker)
+	if !ok {
+		return ErrnoSpipe
+	}
+
+	offset, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return ErrnoIo
+	}
+
+	if !mod.Memory().WriteUint64Le(ctx, resultOffset, uint64(offset)) {
+		return ErrnoFault
+	}
+	return ErrnoSuccess
 }
 
 // FdWrite is the WASI function to write to a file descriptor.
@@ -1130,8 +1433,10 @@ func (a *wasi) FdTell(ctx context.Context, mod api.Module, fd, resultOffset uint
 // * resultSize - the offset in `mod.Memory` to write the number of bytes written
 //
 // The wasi_snapshot_preview1.Errno returned is wasi_snapshot_preview1.ErrnoSuccess except the following error conditions:
-// * wasi_snapshot_preview1.ErrnoBadf - if `fd` is invalid
+// * wasi_snapshot_preview1.ErrnoBadf - if `fd` is invalid or not writable
+// * wasi_snapshot_preview1.ErrnoNotcapable - if `fd` was opened without the right to write, ex. read-only
 // * wasi_snapshot_preview1.ErrnoFault - if `iovs` or `resultSize` contain an invalid offset due to the memory constraint
+// * wasi_snapshot_preview1.ErrnoPipe - if the reading end of the writer was closed, ex. a pipe to a process that exited
 // * wasi_snapshot_preview1.ErrnoIo - if an IO related error happens during the operation
 //
 // For example, this function needs to first read `iovs` to determine what to write to `fd`. If
@@ -1166,100 +1471,88 @@ func (a *wasi) FdTell(ctx context.Context, mod api.Module, fd, resultOffset uint
 //
 // Note: importFdWrite shows this signature in the WebAssembly 1.0 (20191205) Text Format.
 // Note: This is similar to `writev` in POSIX.
+// Note: When wazero.ModuleConfig WithStdioWriteBoundaries is set, writes to stdout or stderr are gathered into a
+// single io.Writer.Write call.
 // See FdRead
 // See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#ciovec
 // See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#fd_write
 // See https://linux.die.net/man/3/writev
 func (a *wasi) FdWrite(ctx context.Context, mod api.Module, fd, iovs, iovsCount, resultSize uint32) Errno {
-	sysCtx, fsCtx := sysFSCtx(ctx, mod)
+	sysCtx := getSysCtx(mod)
 
 	var writer io.Writer
+	var gather bool
 
 	switch fd {
 	case fdStdout:
-		writer = sysCtx.Stdout()
+		writer, gather = sysCtx.Stdout(), sysCtx.StdioWriteBoundaries()
 	case fdStderr:
-		writer = sysCtx.Stderr()
+		writer, gather = sysCtx.Stderr(), sysCtx.StdioWriteBoundaries()
 	default:
 		// Check to see if the file descriptor is available
-		if f, ok := fsCtx.OpenedFile(fd); !ok || f.File == nil {
-			return ErrnoBadf
-			// fs.FS doesn't declare io.Writer, but implementations such as os.File implement it.
-		} else if writer, ok = f.File.(io.Writer); !ok {
+		f, errno := openedFile(ctx, mod, fd, rightFdWrite)
+		if errno != ErrnoSuccess {
+			return errno
+		}
+		// fs.FS doesn't declare io.Writer, but implementations such as os.File implement it.
+		var ok bool
+		if writer, ok = f.(io.Writer); !ok {
 			return ErrnoBadf
 		}
 	}
 
 	var nwritten uint32
+	var gathered []byte
 	for i := uint32(0); i < iovsCount; i++ {
-		iovPtr := iovs + i*8
-		offset, ok := mod.Memory().ReadUint32Le(ctx, iovPtr)
+		iov, ok := readIovec(ctx, mod.Memory(), iovs, i) // a ciovec
 		if !ok {
 			return ErrnoFault
 		}
-		l, ok := mod.Memory().ReadUint32Le(ctx, iovPtr+4)
+		b, ok := mod.Memory().Read(ctx, iov.buf, iov.bufLen)
 		if !ok {
 			return ErrnoFault
 		}
-		b, ok := mod.Memory().Read(ctx, offset, l)
-		if !ok {
-			return ErrnoFault
+		if gather {
+			// Defer the write until all buffers are read, so the writer sees one call.
+			gathered = append(gathered, b...)
+			continue
 		}
 		n, err := writer.Write(b)
 		if err != nil {
-			return ErrnoIo
+			return writeErrno(err)
 		}
 		nwritten += uint32(n)
 	}
+	if gather && len(gathered) > 0 {
+		n, err := writer.Write(gathered)
+		if err != nil {
+			return writeErrno(err)
+		}
+		nwritten = uint32(n)
+	}
 	if !mod.Memory().WriteUint32Le(ctx, resultSize, nwritten) {
 		return ErrnoFault
 	}
 	return ErrnoSuccess
 }
 
-// PathCreateDirectory is the WASI function named functionPathCreateDirectory
-func (a *wasi) PathCreateDirectory(ctx context.Context, mod api.Module, fd, path, pathLen uint32) Errno {
-	return ErrnoNosys // stubbed for GrainLang per #271
-}
-
-// PathFilestatGet is the WASI function named functionPathFilestatGet
-func (a *wasi) PathFilestatGet(ctx context.Context, mod api.Module, fd, flags, path, pathLen, resultBuf uint32) Errno {
-	return ErrnoNosys // stubbed for GrainLang per #271
-}
-
-// PathFilestatSetTimes is the WASI function named functionPathFilestatSetTimes
-func (a *wasi) PathFilestatSetTimes(ctx context.Context, mod api.Module, fd, flags, path, pathLen uint32, atim, mtime uint64, fstFlags uint32) Errno {
-	return ErrnoNosys // stubbed for GrainLang per #271
-}
-
-// PathLink is the WASI function named functionPathLink
-func (a *wasi) PathLink(ctx context.Context, mod api.Module, oldFd, oldFlags, oldPath, oldPathLen, newFd, newPath, newPathLen uint32) Errno {
-	return ErrnoNosys // stubbed for GrainLang per #271
-}
-
-// PathOpen is the WASI function to open a file or directory. This returns ErrnoBadf if the fd is invalid.
+// PathCreateDirectory is the WASI function to create a directory relative to a directory file descriptor.
 //
 // * fd - the file descriptor of a directory that `path` is relative to
-// * dirflags - flags to indicate how to resolve `path`
 // * path - the offset in `mod.Memory` to read the path string from
 // * pathLen - the length of `path`
-// * oFlags - the open flags to indicate the method by which to open the file
-// * fsRightsBase - the rights of the newly created file descriptor for `path`
-// * fsRightsInheriting - the rights of the file descriptors derived from the newly created file descriptor for `path`
-// * fdFlags - the file descriptor flags
-// * resultOpenedFd - the offset in `mod.Memory` to write the newly created file descriptor to.
-//     * The result FD value is guaranteed to be less than 2**31
 //
 // The wasi_snapshot_preview1.Errno returned is wasi_snapshot_preview1.ErrnoSuccess except the following error conditions:
 // * wasi_snapshot_preview1.ErrnoBadf - if `fd` is invalid
-// * wasi_snapshot_preview1.ErrnoFault - if `resultOpenedFd` contains an invalid offset due to the memory constraint
-// * wasi_snapshot_preview1.ErrnoNoent - if `path` does not exist.
-// * wasi_snapshot_preview1.ErrnoExist - if `path` exists, while `oFlags` requires that it must not.
-// * wasi_snapshot_preview1.ErrnoNotdir - if `path` is not a directory, while `oFlags` requires that it must be.
+// * wasi_snapshot_preview1.ErrnoFault - if `path` or `pathLen` point to an invalid offset due to the memory constraint
+// * wasi_snapshot_preview1.ErrnoNotcapable - if `path` escapes the directory of `fd`, ex. "../foo"
+// * wasi_snapshot_preview1.ErrnoRofs - if the file system of `fd` cannot create directories
+// * wasi_snapshot_preview1.ErrnoExist - if `path` already exists
+// * wasi_snapshot_preview1.ErrnoNoent - if the parent of `path` does not exist
 // * wasi_snapshot_preview1.ErrnoIo - if other error happens during the operation of the underying file system.
 //
-// For example, this function needs to first read `path` to determine the file to open.
-//    If parameters `path` = 1, `pathLen` = 6, and the path is "wazero", PathOpen reads the path from `mod.Memory`:
+// For example, if parameters `path` = 1, `pathLen` = 6, and the path is "wazero", PathCreateDirectory reads the path
+// from `mod.Memory`:
 //
 //                   pathLen
 //               +------------------------+
@@ -1267,63 +1560,370 @@ func (a *wasi) PathLink(ctx context.Context, mod api.Module, oldFd, oldFlags, ol
 //   []byte{ ?, 'w', 'a', 'z', 'e', 'r', 'o', ?... }
 //        path --^
 //
-// Then, if parameters resultOpenedFd = 8, and this function opened a new file descriptor 5 with the given flags,
-// this function writes the below to `mod.Memory`:
-//
-//                          uint32le
-//                         +--------+
-//                         |        |
-//        []byte{ 0..6, ?, 5, 0, 0, 0, ?}
-//        resultOpenedFd --^
-//
-// Note: importPathOpen shows this signature in the WebAssembly 1.0 (20191205) Text Format.
-// Note: This is similar to `openat` in POSIX.
-// Note: The returned file descriptor is not guaranteed to be the lowest-numbered file
-// Note: Rights will never be implemented per https://github.com/WebAssembly/WASI/issues/469#issuecomment-1045251844
-// See https://github.com/WebAssembly/WASI/blob/main/phases/snapshot/docs.md#path_open
-// See https://linux.die.net/man/3/openat
-func (a *wasi) PathOpen(ctx context.Context, mod api.Module, fd, dirflags, pathPtr, pathLen, oflags uint32, fsRightsBase,
-	fsRightsInheriting uint64, fdflags, resultOpenedFd uint32) (errno Errno) {
-	_, fsc := sysFSCtx(ctx, mod)
-
-	dir, ok := fsc.OpenedFile(fd)
-	if !ok || dir.FS == nil {
+// Note: importPathCreateDirectory shows this signature in the WebAssembly 1.0 (20191205) Text Format.
+// Note: This is similar to `mkdirat` in POSIX.
+// Note: Directories can only be created when the file system implements sys.MkdirFS.
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#path_create_directory
+// See https://linux.die.net/man/2/mkdirat
+func (a *wasi) PathCreateDirectory(ctx context.Context, mod api.Module, fd, path, pathLen uint32) Errno {
+	dir, errno := openedFileEntryWithRights(ctx, mod, fd, rightPathCreateDirectory)
+	if errno != ErrnoSuccess {
+		return errno
+	} else if dir.FS == nil {
 		return ErrnoBadf
 	}
 
-	b, ok := mod.Memory().Read(ctx, pathPtr, pathLen)
+	b, ok := mod.Memory().Read(ctx, path, pathLen)
 	if !ok {
 		return ErrnoFault
 	}
 
-	// TODO: Consider dirflags and oflags. Also, allow non-read-only open based on config about the mount.
-	// Ex. allow os.O_RDONLY, os.O_WRONLY, or os.O_RDWR either by config flag or pattern on filename
-	// See #390
-	entry, errno := openFileEntry(dir.FS, path.Join(dir.Path, string(b)))
+	pathName, errno := resolvePath(dir.Path, string(b))
 	if errno != ErrnoSuccess {
 		return errno
 	}
 
-	if newFD, ok := fsc.OpenFile(entry); !ok {
-		_ = entry.File.Close()
-		return ErrnoIo
-	} else if !mod.Memory().WriteUint32Le(ctx, resultOpenedFd, newFD) {
-		_ = entry.File.Close()
-		return ErrnoFault
+	mkdirFS, ok := dir.FS.(sys.MkdirFS)
+	if !ok {
+		return ErrnoRofs
+	}
+
+	if err := mkdirFS.Mkdir(pathName, 0o755); err != nil {
+		switch {
+		case errors.Is(err, fs.ErrExist):
+			return ErrnoExist
+		case errors.Is(err, fs.ErrNotExist):
+			return ErrnoNoent
+		case errors.Is(err, fs.ErrPermission):
+			return ErrnoAcces
+		default:
+			return ErrnoIo
+		}
 	}
 	return ErrnoSuccess
 }
 
-// PathReadlink is the WASI function named functionPathReadlink
-func (a *wasi) PathReadlink(ctx context.Context, mod api.Module, fd, path, pathLen, buf, bufLen, resultBufused uint32) Errno {
+// PathFilestatGet is the WASI function named functionPathFilestatGet
+func (a *wasi) PathFilestatGet(ctx context.Context, mod api.Module, fd, flags, path, pathLen, resultBuf uint32) Errno {
 	return ErrnoNosys // stubbed for GrainLang per #271
 }
 
-// PathRemoveDirectory is the WASI function named functionPathRemoveDirectory
-func (a *wasi) PathRemoveDirectory(ctx context.Context, mod api.Module, fd, path, pathLen uint32) Errno {
+// PathFilestatSetTimes is the WASI function to adjust the access and modification times of a file or directory,
+// relative to a directory file descriptor.
+//
+// * fd - the file descriptor of a directory that `path` is relative to
+// * flags - lookupflags, which are ignored as symbolic links are always followed
+// * path - the offset in `mod.Memory` to read the path string from
+// * pathLen - the length of `path`
+// * atim - the access time in nanoseconds since the epoch, used when `fstFlags` includes FSTFLAGS_ATIM
+// * mtim - the modification time in nanoseconds since the epoch, used when `fstFlags` includes FSTFLAGS_MTIM
+// * fstFlags - which times to set. FSTFLAGS_ATIM_NOW or FSTFLAGS_MTIM_NOW set the time to sys.Walltime
+//
+// The wasi_snapshot_preview1.Errno returned is wasi_snapshot_preview1.ErrnoSuccess except the following error conditions:
+// * wasi_snapshot_preview1.ErrnoBadf - if `fd` is invalid
+// * wasi_snapshot_preview1.ErrnoFault - if `path` or `pathLen` point to an invalid offset due to the memory constraint
+// * wasi_snapshot_preview1.ErrnoInval - if `fstFlags` has unknown bits or both an explicit and _NOW flag for one time
+// * wasi_snapshot_preview1.ErrnoNotcapable - if `path` escapes the directory of `fd`, ex. "../foo"
+// * wasi_snapshot_preview1.ErrnoNotsup - if `fd` isn't in a file system, ex. a socket
+// * wasi_snapshot_preview1.ErrnoRofs - if the file system of `fd` cannot change file times
+// * wasi_snapshot_preview1.ErrnoNoent - if `path` does not exist
+// * wasi_snapshot_preview1.ErrnoIo - if other error happens during the operation of the underying file system.
+//
+// Note: importPathFilestatSetTimes shows this signature in the WebAssembly 1.0 (20191205) Text Format.
+// Note: This is similar to `utimensat` in POSIX.
+// Note: File times can only be changed when the file system implements sys.ChtimesFS.
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-path_filestat_set_timesfd-fd-flags-lookupflags-path-string-atim-timestamp-mtim-timestamp-fst_flags-fstflags---errno
+// See https://linux.die.net/man/3/utimensat
+func (a *wasi) PathFilestatSetTimes(ctx context.Context, mod api.Module, fd, flags, path, pathLen uint32, atim, mtim uint64, fstFlags uint32) Errno {
+	dir, errno := openedFileEntryWithRights(ctx, mod, fd, rightPathFilestatSetTimes)
+	if errno != ErrnoSuccess {
+		return errno
+	} else if dir.FS == nil {
+		return ErrnoNotsup
+	}
+
+	b, ok := mod.Memory().Read(ctx, path, pathLen)
+	if !ok {
+		return ErrnoFault
+	}
+
+	atime, mtime, errno := filestatTimes(ctx, getSysCtx(mod), atim, mtim, fstFlags)
+	if errno != ErrnoSuccess {
+		return errno
+	}
+
+	pathName, errno := resolvePath(dir.Path, string(b))
+	if errno != ErrnoSuccess {
+		return errno
+	}
+	return chtimes(dir.FS, pathName, atime, mtime)
+}
+
+// PathLink is the WASI function named functionPathLink
+func (a *wasi) PathLink(ctx context.Context, mod api.Module, oldFd, oldFlags, oldPath, oldPathLen, newFd, newPath, newPathLen uint32) Errno {
 	return ErrnoNosys // stubbed for GrainLang per #271
 }
 
+// PathOpen is the WASI function to open a file or directory. This returns ErrnoBadf if the fd is invalid.
+//
+// * fd - the file descriptor of a directory that `path` is relative to
+// * dirflags - flags to indicate how to resolve `path`
+// * path - the offset in `mod.Memory` to read the path string from
+// * pathLen - the length of `path`
+// * oFlags - the open flags to indicate the method by which to open the file
+// * fsRightsBase - the rights of the newly created file descriptor for `path`
+// * fsRightsInheriting - the rights of the file descriptors derived from the newly created file descriptor for `path`
+// * fdFlags - the file descriptor flags
+// * resultOpenedFd - the offset in `mod.Memory` to write the newly created file descriptor to.
+//     * The result FD value is guaranteed to be less than 2**31
+//
+// The wasi_snapshot_preview1.Errno returned is wasi_snapshot_preview1.ErrnoSuccess except the following error conditions:
+// * wasi_snapshot_preview1.ErrnoBadf - if `fd` is invalid
+// * wasi_snapshot_preview1.ErrnoFault - if `resultOpenedFd` contains an invalid offset due to the memory constraint
+// * wasi_snapshot_preview1.ErrnoNotcapable - if `fd` doesn't have the right to open files, RIGHTS_PATH_OPEN
+// * wasi_snapshot_preview1.ErrnoInval - if `oFlags` includes both O_CREAT and O_DIRECTORY.
+// * wasi_snapshot_preview1.ErrnoNoent - if `path` does not exist.
+// * wasi_snapshot_preview1.ErrnoExist - if `path` exists, while `oFlags` requires that it must not.
+// * wasi_snapshot_preview1.ErrnoNotdir - if `path` is not a directory, while `oFlags` requires that it must be.
+// * wasi_snapshot_preview1.ErrnoIsdir - if `path` is a directory, while `fsRightsBase` requires writing.
+// * wasi_snapshot_preview1.ErrnoRofs - if the file system of `fd` is read-only, while `oFlags` or `fsRightsBase`
+//   require writing, ex. O_TRUNC.
+// * wasi_snapshot_preview1.ErrnoIo - if other error happens during the operation of the underying file system.
+//
+// For example, this function needs to first read `path` to determine the file to open.
+//    If parameters `path` = 1, `pathLen` = 6, and the path is "wazero", PathOpen reads the path from `mod.Memory`:
+//
+//                   pathLen
+//               +------------------------+
+//               |                        |
+//   []byte{ ?, 'w', 'a', 'z', 'e', 'r', 'o', ?... }
+//        path --^
+//
+// Then, if parameters resultOpenedFd = 8, and this function opened a new file descriptor 5 with the given flags,
+// this function writes the below to `mod.Memory`:
+//
+//                          uint32le
+//                         +--------+
+//                         |        |
+//        []byte{ 0..6, ?, 5, 0, 0, 0, ?}
+//        resultOpenedFd --^
+//
+// Note: importPathOpen shows this signature in the WebAssembly 1.0 (20191205) Text Format.
+// Note: This is similar to `openat` in POSIX.
+// Note: The returned file descriptor is not guaranteed to be the lowest-numbered file
+// Note: Files are opened for writing when the file system implements sys.OpenFileFS and `fsRightsBase` includes the
+// right to write. Otherwise, the file system is read-only.
+// Note: The rights of the new file descriptor are `fsRightsBase` and `fsRightsInheriting`, each limited to the
+// inheriting rights of `fd`. Functions that use the new file descriptor without the corresponding right, ex. FdWrite
+// without RIGHTS_FD_WRITE, return wasi_snapshot_preview1.ErrnoNotcapable.
+// See https://github.com/WebAssembly/WASI/blob/main/phases/snapshot/docs.md#path_open
+// See https://linux.die.net/man/3/openat
+func (a *wasi) PathOpen(ctx context.Context, mod api.Module, fd, dirflags, pathPtr, pathLen, oflags uint32, fsRightsBase,
+	fsRightsInheriting uint64, fdflags, resultOpenedFd uint32) (errno Errno) {
+	_, fsc := sysFSCtx(ctx, mod)
+
+	dir, errno := openedFileEntryWithRights(ctx, mod, fd, rightPathOpen)
+	if errno != ErrnoSuccess {
+		return errno
+	} else if dir.FS == nil {
+		return ErrnoBadf
+	}
+
+	b, ok := mod.Memory().Read(ctx, pathPtr, pathLen)
+	if !ok {
+		return ErrnoFault
+	}
+
+	pathName, errno := resolvePath(dir.Path, string(b))
+	if errno != ErrnoSuccess {
+		return errno
+	}
+
+	if oflags&oflagDirectory != 0 && oflags&oflagCreat != 0 {
+		return ErrnoInval // only regular files can be created.
+	}
+
+	// The new file descriptor can't have more rights than the directory allows it to inherit.
+	rights := &sys.Rights{
+		Base:       fsRightsBase & rightsInheriting(dir),
+		Inheriting: fsRightsInheriting & rightsInheriting(dir),
+	}
+
+	// TODO: Consider dirflags.
+	entry, errno := openFileEntry(dir.FS, pathName, openFlag(oflags, rights.Base, fdflags))
+	if errno != ErrnoSuccess {
+		return errno
+	}
+	entry.Rights = rights
+
+	if oflags&oflagDirectory != 0 {
+		if st, err := entry.File.Stat(); err != nil {
+			_ = entry.File.Close()
+			return ErrnoIo
+		} else if !st.IsDir() {
+			_ = entry.File.Close()
+			return ErrnoNotdir
+		}
+	}
+
+	if newFD, ok := fsc.OpenFile(entry); !ok {
+		_ = entry.File.Close()
+		return ErrnoIo
+	} else if !mod.Memory().WriteUint32Le(ctx, resultOpenedFd, newFD) {
+		_ = entry.File.Close()
+		return ErrnoFault
+	}
+	return ErrnoSuccess
+}
+
+// PathReadlink is the WASI function to read the target of a symbolic link relative to a directory file descriptor.
+//
+// * fd - the file descriptor of a directory that `path` is relative to
+// * path - the offset in `mod.Memory` to read the path string from
+// * pathLen - the length of `path`
+// * buf - the offset in `mod.Memory` to write the target of the symbolic link
+// * bufLen - the maximum count of bytes to write to `buf`
+// * resultBufused - the offset in `mod.Memory` to write the count of bytes written to `buf`
+//
+// The wasi_snapshot_preview1.Errno returned is wasi_snapshot_preview1.ErrnoSuccess except the following error conditions:
+// * wasi_snapshot_preview1.ErrnoBadf - if `fd` is invalid
+// * wasi_snapshot_preview1.ErrnoFault - if `path`, `buf` or `resultBufused` point to an invalid offset due to the
+//   memory constraint
+// * wasi_snapshot_preview1.ErrnoNotcapable - if `path` escapes the directory of `fd`, ex. "../foo"
+// * wasi_snapshot_preview1.ErrnoNotsup - if the file system of `fd` doesn't support symbolic links
+// * wasi_snapshot_preview1.ErrnoInval - if `path` is not a symbolic link
+// * wasi_snapshot_preview1.ErrnoNoent - if `path` does not exist
+// * wasi_snapshot_preview1.ErrnoIo - if other error happens during the operation of the underying file system.
+//
+// For example, if the symbolic link "link" points to "wazero", and parameters `buf` = 1, `bufLen` = 4 and
+// `resultBufused` = 8, this function writes the below to `mod.Memory`:
+//
+//                   bufLen                  uint32le
+//              +----------------+           +--------+
+//              |                |           |        |
+//   []byte{ ?, 'w', 'a', 'z', 'e', ?, ?, ?, 4, 0, 0, 0 }
+//        buf --^           resultBufused --^
+//
+// Note: importPathReadlink shows this signature in the WebAssembly 1.0 (20191205) Text Format.
+// Note: Like readlink in POSIX, the target is truncated to `bufLen` and not null-terminated.
+// Note: Symbolic links are only supported when the file system implements sys.ReadLinkFS.
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#path_readlink
+// See https://linux.die.net/man/2/readlinkat
+func (a *wasi) PathReadlink(ctx context.Context, mod api.Module, fd, path, pathLen, buf, bufLen, resultBufused uint32) Errno {
+	dir, errno := openedFileEntryWithRights(ctx, mod, fd, rightPathReadlink)
+	if errno != ErrnoSuccess {
+		return errno
+	} else if dir.FS == nil {
+		return ErrnoBadf
+	}
+
+	b, ok := mod.Memory().Read(ctx, path, pathLen)
+	if !ok {
+		return ErrnoFault
+	}
+
+	pathName, errno := resolvePath(dir.Path, string(b))
+	if errno != ErrnoSuccess {
+		return errno
+	}
+
+	readLinkFS, ok := dir.FS.(sys.ReadLinkFS)
+	if !ok {
+		return ErrnoNotsup
+	}
+
+	target, err := readLinkFS.ReadLink(pathName)
+	if err != nil {
+		switch {
+		case errors.Is(err, fs.ErrInvalid):
+			return ErrnoInval
+		case errors.Is(err, fs.ErrNotExist):
+			return ErrnoNoent
+		case errors.Is(err, fs.ErrPermission):
+			return ErrnoAcces
+		default:
+			return ErrnoIo
+		}
+	}
+
+	if uint32(len(target)) > bufLen {
+		target = target[:bufLen]
+	}
+	if !mod.Memory().Write(ctx, buf, []byte(target)) {
+		return ErrnoFault
+	}
+	if !mod.Memory().WriteUint32Le(ctx, resultBufused, uint32(len(target))) {
+		return ErrnoFault
+	}
+	return ErrnoSuccess
+}
+
+// PathRemoveDirectory is the WASI function to remove an empty directory relative to a directory file descriptor.
+//
+// * fd - the file descriptor of a directory that `path` is relative to
+// * path - the offset in `mod.Memory` to read the path string from
+// * pathLen - the length of `path`
+//
+// The wasi_snapshot_preview1.Errno returned is wasi_snapshot_preview1.ErrnoSuccess except the following error conditions:
+// * wasi_snapshot_preview1.ErrnoBadf - if `fd` is invalid
+// * wasi_snapshot_preview1.ErrnoFault - if `path` or `pathLen` point to an invalid offset due to the memory constraint
+// * wasi_snapshot_preview1.ErrnoNotcapable - if `path` escapes the directory of `fd`, ex. "../foo"
+// * wasi_snapshot_preview1.ErrnoInval - if `path` is the directory of `fd` itself, ex. "."
+// * wasi_snapshot_preview1.ErrnoRofs - if the file system of `fd` cannot remove directories
+// * wasi_snapshot_preview1.ErrnoNoent - if `path` does not exist
+// * wasi_snapshot_preview1.ErrnoNotdir - if `path` is not a directory
+// * wasi_snapshot_preview1.ErrnoNotempty - if `path` has entries
+// * wasi_snapshot_preview1.ErrnoIo - if other error happens during the operation of the underying file system.
+//
+// Note: importPathRemoveDirectory shows this signature in the WebAssembly 1.0 (20191205) Text Format.
+// Note: This is similar to `unlinkat` with `AT_REMOVEDIR` in POSIX.
+// Note: Directories can only be removed when the file system implements sys.RmdirFS.
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#path_remove_directory
+// See https://linux.die.net/man/2/unlinkat
+func (a *wasi) PathRemoveDirectory(ctx context.Context, mod api.Module, fd, path, pathLen uint32) Errno {
+	dir, errno := openedFileEntryWithRights(ctx, mod, fd, rightPathRemoveDirectory)
+	if errno != ErrnoSuccess {
+		return errno
+	} else if dir.FS == nil {
+		return ErrnoBadf
+	}
+
+	b, ok := mod.Memory().Read(ctx, path, pathLen)
+	if !ok {
+		return ErrnoFault
+	}
+
+	pathName, errno := resolvePath(dir.Path, string(b))
+	if errno != ErrnoSuccess {
+		return errno
+	} else if dirName, _ := resolvePath(dir.Path, "."); pathName == dirName {
+		return ErrnoInval // Similar to rmdir(".") in POSIX, which would remove the directory of `fd`.
+	}
+
+	rmdirFS, ok := dir.FS.(sys.RmdirFS)
+	if !ok {
+		return ErrnoRofs
+	}
+
+	if err := rmdirFS.Rmdir(pathName); err != nil {
+		switch {
+		case errors.Is(err, syscall.ENOTEMPTY):
+			return ErrnoNotempty
+		case errors.Is(err, syscall.ENOTDIR):
+			return ErrnoNotdir
+		case errors.Is(err, fs.ErrNotExist):
+			return ErrnoNoent
+		case errors.Is(err, fs.ErrInvalid):
+			return ErrnoInval
+		case errors.Is(err, fs.ErrPermission):
+			return ErrnoAcces
+		default:
+			return ErrnoIo
+		}
+	}
+	return ErrnoSuccess
+}
+
 // PathRename is the WASI function named functionPathRename
 func (a *wasi) PathRename(ctx context.Context, mod api.Module, fd, oldPath, oldPathLen, newFd, newPath, newPathLen uint32) Errno {
 	return ErrnoNosys // stubbed for GrainLang per #271
@@ -1339,9 +1939,223 @@ func (a *wasi) PathUnlinkFile(ctx context.Context, mod api.Module, fd, path, pat
 	return ErrnoNosys // stubbed for GrainLang per #271
 }
 
-// PollOneoff is the WASI function named functionPollOneoff
+// PollOneoff is the WASI function named functionPollOneoff that concurrently polls for the occurrence of a set of
+// events.
+//
+// * in - the offset in `mod.Memory` to read `nsubscriptions` subscriptions from, each 48 bytes
+// * out - the offset in `mod.Memory` to write the resulting events to, each 32 bytes
+// * nsubscriptions - the count of subscriptions, which must be at least one
+// * resultNevents - the offset in `mod.Memory` to write the count of events written to `out`
+//
+// The wasi_snapshot_preview1.Errno returned is wasi_snapshot_preview1.ErrnoSuccess except the following error conditions:
+// * wasi_snapshot_preview1.ErrnoInval - if `nsubscriptions` is zero or a subscription has an invalid tag
+// * wasi_snapshot_preview1.ErrnoFault - if `in`, `out` or `resultNevents` point to an invalid offset due to the
+//   memory constraint
+// * wasi_snapshot_preview1.ErrnoIntr - if the context is done while waiting for a clock subscription
+//
+// Errors specific to a subscription, such as an unsupported clock id, are instead written to the `error` field of
+// its event, which is returned without waiting.
+//
+// For example, if a single clock subscription has `userdata` = 1, and parameters `out` = 1 and `resultNevents` = 33,
+// this function waits until its timeout, then writes the below to `mod.Memory`. The remaining bytes of the event,
+// including `error` and `type`, are zero:
+//
+//                    userdata                        uint32le
+//           +------------------------+            +------------+
+//           |                        |            |            |
+//  []byte{ ?, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, ..., 1, 0, 0, 0 }
+//       out --^                     resultNevents --^
+//
+// Note: importPollOneoff shows this signature in the WebAssembly 1.0 (20191205) Text Format.
+// Note: Clock timeouts are measured with sys.Context Nanotime, and waited for with Nanosleep. Hence, a module
+// configured with wazero.ModuleConfig WithNanotime and WithNanosleep can control time, ex. in tests.
+// Note: Subscriptions for writing to stdout, stderr, a socket or a writable file are ready immediately, with the
+// `nbytes` that can be written. Reading subscriptions are not yet supported, so their events are written with the error
+// wasi_snapshot_preview1.ErrnoNotsup. Either is written with wasi_snapshot_preview1.ErrnoBadf if the file descriptor
+// is invalid.
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-poll_oneoffin-constpointersubscription-out-pointerevent-nsubscriptions-size---errno-size
+// See https://linux.die.net/man/3/poll
 func (a *wasi) PollOneoff(ctx context.Context, mod api.Module, in, out, nsubscriptions, resultNevents uint32) Errno {
-	return ErrnoNosys // stubbed for GrainLang per #271
+	if nsubscriptions == 0 {
+		return ErrnoInval
+	}
+
+	mem := mod.Memory()
+	// Multiply in uint64, as the size can overflow uint32.
+	if uint64(nsubscriptions)*subscriptionSize > math.MaxUint32 {
+		return ErrnoFault
+	}
+	inBuf, ok := mem.Read(ctx, in, nsubscriptions*subscriptionSize)
+	if !ok {
+		return ErrnoFault
+	}
+	outBuf, ok := mem.Read(ctx, out, nsubscriptions*eventSize)
+	if !ok {
+		return ErrnoFault
+	}
+	if _, ok = mem.ReadUint32Le(ctx, resultNevents); !ok {
+		return ErrnoFault
+	}
+
+	sysCtx := getSysCtx(mod)
+
+	// Decode all subscriptions before writing any event, as in and out may overlap.
+	subs := make([]subscription, nsubscriptions)
+	for i := range subs {
+		subs[i].decode(inBuf[i*subscriptionSize:])
+		if subs[i].tag > eventtypeFdWrite {
+			return ErrnoInval
+		}
+	}
+
+	var events []event
+	// deadlines are the Nanotime each subscription fires, or math.MinInt64 if its event was already written.
+	deadlines := make([]int64, nsubscriptions)
+	earliest := int64(math.MaxInt64)
+	for i := range subs {
+		sub := &subs[i]
+		var errno Errno
+		var nbytes uint64
+		switch sub.tag {
+		case eventtypeClock:
+			deadlines[i], errno = clockDeadline(ctx, sysCtx, &sub.clock)
+		case eventtypeFdWrite:
+			if nbytes, errno = fdWriteNbytes(ctx, mod, sysCtx, sub.fd); errno == ErrnoSuccess {
+				events = append(events, event{userdata: sub.userdata, eventtype: sub.tag, nbytes: nbytes})
+				deadlines[i] = math.MinInt64
+				continue
+			}
+		default:
+			if _, errno = openedFileEntry(ctx, mod, sub.fd); errno == ErrnoSuccess || sub.fd <= fdStderr {
+				errno = ErrnoNotsup // TODO: fd_read subscriptions
+			}
+		}
+
+		if errno != ErrnoSuccess {
+			events = append(events, event{userdata: sub.userdata, errno: errno, eventtype: sub.tag})
+			deadlines[i] = math.MinInt64
+		} else if deadlines[i] < earliest {
+			earliest = deadlines[i]
+		}
+	}
+
+	// Wait for the earliest clock subscription, unless there is an event already.
+	if len(events) == 0 {
+		if !nanosleepUntil(ctx, sysCtx, earliest) {
+			return ErrnoIntr
+		}
+	} else {
+		earliest = sysCtx.Nanotime(ctx)
+	}
+
+	// Clock subscriptions fire in order of subscription, once their deadline has passed.
+	for i := range subs {
+		if d := deadlines[i]; d != math.MinInt64 && d <= earliest {
+			events = append(events, event{userdata: subs[i].userdata, eventtype: eventtypeClock})
+		}
+	}
+
+	for i := range events {
+		events[i].encode(outBuf[i*eventSize:])
+	}
+	if !mem.WriteUint32Le(ctx, resultNevents, uint32(len(events))) {
+		return ErrnoFault
+	}
+	return ErrnoSuccess
+}
+
+// pipeBufSize is the nbytes of a ready eventtypeFdWrite event when the writer doesn't know how much it can accept. This
+// is PIPE_BUF on Linux, the count of bytes a pipe accepts in a single write.
+const pipeBufSize = 4096
+
+// fdWriteNbytes returns the nbytes of the eventtypeFdWrite event for fd, or the error of the event if fd can't be
+// written. Writable file descriptors are ready immediately, as fd_write and sock_send block until all bytes are written
+// instead of failing with wasi_snapshot_preview1.ErrnoAgain.
+//
+// nbytes is the free space of a writer that buffers, such as stdout configured with wazero.ModuleConfig
+// WithBufferedStdout, as more bytes can't be written without flushing. Otherwise, it is pipeBufSize.
+func fdWriteNbytes(ctx context.Context, mod api.Module, sysCtx *sys.Context, fd uint32) (uint64, Errno) {
+	var writer io.Writer
+	switch fd {
+	case fdStdin:
+		return 0, ErrnoBadf
+	case fdStdout:
+		writer = sysCtx.Stdout()
+	case fdStderr:
+		writer = sysCtx.Stderr()
+	default:
+		f, errno := openedFile(ctx, mod, fd, rightPollFdReadwrite)
+		if errno != ErrnoSuccess {
+			return 0, errno
+		}
+		// Like fd_write, only files which implement io.Writer can be written, which includes a sys.Socket.
+		var ok bool
+		if writer, ok = f.(io.Writer); !ok {
+			return 0, ErrnoBadf
+		}
+	}
+
+	if b, ok := writer.(interface{ Available() int }); ok && b.Available() > 0 {
+		return uint64(b.Available()), ErrnoSuccess
+	}
+	return pipeBufSize, ErrnoSuccess
+}
+
+// clockDeadline returns the value of sys.Context Nanotime when the clock subscription fires.
+//
+// Relative timeouts are measured from the current Nanotime, as it is monotonic, regardless of the clock id. Absolute
+// timeouts are converted from the clock id, so that the deadline follows any clock configured in wazero.ModuleConfig.
+func clockDeadline(ctx context.Context, sysCtx *sys.Context, c *subscriptionClock) (int64, Errno) {
+	timeout := int64(math.MaxInt64) // Saturate, as no deadline that far will be reached anyway.
+	if c.timeout < math.MaxInt64 {
+		timeout = int64(c.timeout)
+	}
+
+	var clockNow int64
+	switch c.id {
+	case clockIDRealtime:
+		if c.flags&subclockflagSubscriptionClockAbstime != 0 {
+			sec, nsec := sysCtx.Walltime(ctx)
+			clockNow = sec*time.Second.Nanoseconds() + int64(nsec)
+		}
+	case clockIDMonotonic:
+		if c.flags&subclockflagSubscriptionClockAbstime != 0 {
+			clockNow = sysCtx.Nanotime(ctx)
+		}
+	default:
+		// Similar to clock_time_get, we only support realtime and monotonic clocks.
+		return 0, ErrnoInval
+	}
+
+	now := sysCtx.Nanotime(ctx)
+	if timeout != math.MaxInt64 {
+		timeout -= clockNow // negative when an absolute timeout already passed
+	}
+	if timeout > math.MaxInt64-now {
+		return math.MaxInt64, ErrnoSuccess
+	}
+	return now + timeout, ErrnoSuccess
+}
+
+// nanosleepUntil blocks with sys.Context Nanosleep until the deadline in Nanotime. This returns false if the
+// context was done before then.
+//
+// If Nanosleep returns early, this sleeps again for the time remaining. As the remaining time is always measured from
+// the deadline, early or late wake-ups don't accumulate. This stops waiting when Nanosleep doesn't advance the clock,
+// ex. the default that returns immediately with a fake clock, as sleeping again wouldn't either.
+func nanosleepUntil(ctx context.Context, sysCtx *sys.Context, deadline int64) bool {
+	now := sysCtx.Nanotime(ctx)
+	for timeout := deadline - now; timeout > 0; timeout = deadline - now {
+		sysCtx.Nanosleep(ctx, timeout)
+		if ctx.Err() != nil {
+			break
+		} else if next := sysCtx.Nanotime(ctx); next == now {
+			break
+		} else {
+			now = next
+		}
+	}
+	return ctx.Err() == nil
 }
 
 // ProcExit is the WASI function that terminates the execution of the module with an exit code.
@@ -1383,34 +2197,267 @@ func (a *wasi) SchedYield(mod api.Module) Errno {
 // Note: importRandomGet shows this signature in the WebAssembly 1.0 (20191205) Text Format.
 // See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-random_getbuf-pointeru8-bufLen-size---errno
 func (a *wasi) RandomGet(ctx context.Context, mod api.Module, buf uint32, bufLen uint32) (errno Errno) {
-	randSource := getSysCtx(mod).RandSource()
-
 	randomBytes, ok := mod.Memory().Read(ctx, buf, bufLen)
 	if !ok { // out-of-range
 		return ErrnoFault
 	}
 
-	// We can ignore the returned n as it only != byteCount on error
-	if _, err := io.ReadAtLeast(randSource, randomBytes, int(bufLen)); err != nil {
+	// Short reads are retried, so only a real error, such as the source ending early, results in ErrnoIo.
+	if err := getSysCtx(mod).ReadRand(randomBytes); err != nil {
 		return ErrnoIo
 	}
 
 	return ErrnoSuccess
 }
 
-// SockRecv is the WASI function named functionSockRecv
+// SockRecv is the WASI function to receive a message from a socket, scattering it into the `riDataCount` iovecs at
+// offset `riData`, similar to fd_read.
+//
+// * fd - the file descriptor of a socket, ex. opened by wazero.ModuleConfig WithSocket
+// * riData - the offset in `mod.Memory` to read the iovec array from
+// * riDataCount - the count of iovecs to read from `riData`
+// * riFlags - flags which must be zero, as neither RECV_PEEK nor RECV_WAITALL are supported
+// * resultRoDataLen - the offset in `mod.Memory` to write the number of bytes received
+// * resultRoFlags - the offset in `mod.Memory` to write the roflags, which are always zero
+//
+// The wasi_snapshot_preview1.Errno returned is wasi_snapshot_preview1.ErrnoSuccess except the following error conditions:
+// * wasi_snapshot_preview1.ErrnoBadf - if `fd` is invalid
+// * wasi_snapshot_preview1.ErrnoNotsock - if `fd` is not a socket
+// * wasi_snapshot_preview1.ErrnoNotsup - if `riFlags` are set
+// * wasi_snapshot_preview1.ErrnoFault - if `riData`, `resultRoDataLen` or `resultRoFlags` point to an invalid offset
+//   due to the memory constraint
+// * wasi_snapshot_preview1.ErrnoIo - if reading from the socket failed
+//
+// Note: importSockRecv shows this signature in the WebAssembly 1.0 (20191205) Text Format.
+// Note: Like fd_read, zero bytes are received without error once the peer closed the connection.
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-sock_recvfd-fd-ri_data-iovec_array-ri_flags-riflags---errno-size-roflags
+// See https://linux.die.net/man/2/recv
 func (a *wasi) SockRecv(ctx context.Context, mod api.Module, fd, riData, riDataCount, riFlags, resultRoDataLen, resultRoFlags uint32) Errno {
-	return ErrnoNosys // stubbed for GrainLang per #271
+	socket, errno := openedSocket(ctx, mod, fd)
+	if errno != ErrnoSuccess {
+		return errno
+	}
+	if riFlags&(riflagRecvPeek|riflagRecvWaitall) != 0 {
+		return ErrnoNotsup
+	}
+
+	var nread uint32
+	for i := uint32(0); i < riDataCount; i++ {
+		iov, ok := readIovec(ctx, mod.Memory(), riData, i)
+		if !ok {
+			return ErrnoFault
+		}
+		b, ok := mod.Memory().Read(ctx, iov.buf, iov.bufLen)
+		if !ok {
+			return ErrnoFault
+		}
+		n, err := socket.Read(b) // Note: n <= iov.bufLen
+		nread += uint32(n)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return ErrnoIo
+		} else if n < len(b) {
+			break // Don't block for more data than the peer has sent so far.
+		}
+	}
+	if !mod.Memory().WriteUint32Le(ctx, resultRoDataLen, nread) {
+		return ErrnoFault
+	}
+	if !mod.Memory().WriteUint16Le(ctx, resultRoFlags, 0) {
+		return ErrnoFault
+	}
+	return ErrnoSuccess
 }
 
-// SockSend is the WASI function named functionSockSend
+// SockSend is the WASI function to send a message on a socket, gathering it from the `siDataCount` ciovecs at offset
+// `siData`, similar to fd_write.
+//
+// * fd - the file descriptor of a socket, ex. opened by wazero.ModuleConfig WithSocket
+// * siData - the offset in `mod.Memory` to read the ciovec array from
+// * siDataCount - the count of ciovecs to read from `siData`
+// * siFlags - flags which must be zero, as none are defined
+// * resultSoDataLen - the offset in `mod.Memory` to write the number of bytes sent
+//
+// The wasi_snapshot_preview1.Errno returned is wasi_snapshot_preview1.ErrnoSuccess except the following error conditions:
+// * wasi_snapshot_preview1.ErrnoBadf - if `fd` is invalid
+// * wasi_snapshot_preview1.ErrnoNotsock - if `fd` is not a socket
+// * wasi_snapshot_preview1.ErrnoInval - if `siFlags` are set
+// * wasi_snapshot_preview1.ErrnoFault - if `siData` or `resultSoDataLen` point to an invalid offset due to the memory
+//   constraint
+// * wasi_snapshot_preview1.ErrnoIo - if writing to the socket failed
+//
+// Note: importSockSend shows this signature in the WebAssembly 1.0 (20191205) Text Format.
+// Note: The ciovecs are gathered into one write, so the peer sees one message.
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-sock_sendfd-fd-si_data-ciovec_array-si_flags-siflags---errno-size
+// See https://linux.die.net/man/2/send
 func (a *wasi) SockSend(ctx context.Context, mod api.Module, fd, siData, siDataCount, siFlags, resultSoDataLen uint32) Errno {
-	return ErrnoNosys // stubbed for GrainLang per #271
+	socket, errno := openedSocket(ctx, mod, fd)
+	if errno != ErrnoSuccess {
+		return errno
+	}
+	if siFlags != 0 {
+		return ErrnoInval
+	}
+
+	var gathered []byte
+	for i := uint32(0); i < siDataCount; i++ {
+		iov, ok := readIovec(ctx, mod.Memory(), siData, i) // a ciovec
+		if !ok {
+			return ErrnoFault
+		}
+		b, ok := mod.Memory().Read(ctx, iov.buf, iov.bufLen)
+		if !ok {
+			return ErrnoFault
+		}
+		gathered = append(gathered, b...)
+	}
+
+	var nwritten uint32
+	if len(gathered) > 0 {
+		n, err := socket.Write(gathered)
+		if err != nil {
+			return writeErrno(err)
+		}
+		nwritten = uint32(n)
+	}
+	if !mod.Memory().WriteUint32Le(ctx, resultSoDataLen, nwritten) {
+		return ErrnoFault
+	}
+	return ErrnoSuccess
 }
 
-// SockShutdown is the WASI function named functionSockShutdown
+// SockShutdown is the WASI function to shut down the receive or send channel of a socket, or both. The file descriptor
+// stays open until fd_close.
+//
+// * fd - the file descriptor of a socket, ex. opened by wazero.ModuleConfig WithSocket
+// * how - sdflags of the channels to shut down: 1 (RD) for receive, 2 (WR) for send, or both
+//
+// The wasi_snapshot_preview1.Errno returned is wasi_snapshot_preview1.ErrnoSuccess except the following error conditions:
+// * wasi_snapshot_preview1.ErrnoBadf - if `fd` is invalid
+// * wasi_snapshot_preview1.ErrnoNotsock - if `fd` is not a socket
+// * wasi_snapshot_preview1.ErrnoInval - if `how` has no channel or an unknown flag
+// * wasi_snapshot_preview1.ErrnoNotsup - if the connection can't half-close, ex. it has no CloseWrite method
+// * wasi_snapshot_preview1.ErrnoNotconn - if shutting down failed, ex. as the connection was already closed
+//
+// Note: importSockShutdown shows this signature in the WebAssembly 1.0 (20191205) Text Format.
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-sock_shutdownfd-fd-how-sdflags---errno
+// See https://linux.die.net/man/2/shutdown
 func (a *wasi) SockShutdown(ctx context.Context, mod api.Module, fd, how uint32) Errno {
-	return ErrnoNosys // stubbed for GrainLang per #271
+	socket, errno := openedSocket(ctx, mod, fd)
+	if errno != ErrnoSuccess {
+		return errno
+	}
+	if how == 0 || how&^(sdflagRd|sdflagWr) != 0 {
+		return ErrnoInval
+	}
+
+	// net.Conn doesn't declare half-close, but implementations such as net.TCPConn implement it.
+	var closers []func() error
+	if how&sdflagRd != 0 {
+		r, ok := socket.Conn.(interface{ CloseRead() error })
+		if !ok {
+			return ErrnoNotsup
+		}
+		closers = append(closers, r.CloseRead)
+	}
+	if how&sdflagWr != 0 {
+		w, ok := socket.Conn.(interface{ CloseWrite() error })
+		if !ok {
+			return ErrnoNotsup
+		}
+		closers = append(closers, w.CloseWrite)
+	}
+	for _, c := range closers {
+		if err := c(); err != nil {
+			return ErrnoNotconn
+		}
+	}
+	return ErrnoSuccess
+}
+
+// openedFileEntry returns the entry opened at fd, or ErrnoBadf if fd isn't open, ex. it was closed or is a garbage
+// value from the guest. All functions that accept a file descriptor look it up with this or a function that calls it,
+// so that an invalid one can never cause a panic on the host.
+//
+// Note: stdio isn't in the file table, so functions that accept it must check for it before calling this.
+func openedFileEntry(ctx context.Context, mod api.Module, fd uint32) (*sys.FileEntry, Errno) {
+	_, fsc := sysFSCtx(ctx, mod)
+
+	if f, ok := fsc.OpenedFile(fd); ok {
+		return f, ErrnoSuccess
+	}
+	return nil, ErrnoBadf
+}
+
+// openedFileEntryWithRights is like openedFileEntry, except it also returns ErrnoNotcapable unless fd has all the
+// given rights, ex. rightFdWrite.
+func openedFileEntryWithRights(ctx context.Context, mod api.Module, fd uint32, rights uint64) (*sys.FileEntry, Errno) {
+	f, errno := openedFileEntry(ctx, mod, fd)
+	if errno != ErrnoSuccess {
+		return nil, errno
+	} else if rightsBase(f)&rights != rights {
+		return nil, ErrnoNotcapable
+	}
+	return f, ErrnoSuccess
+}
+
+// rightsBase returns the rights of the file descriptor, which are rightsAll unless it was opened by PathOpen.
+func rightsBase(f *sys.FileEntry) uint64 {
+	if f.Rights == nil {
+		return rightsAll
+	}
+	return f.Rights.Base
+}
+
+// rightsInheriting returns the maximum rights of files opened relative to the file descriptor, which are rightsAll
+// unless it was opened by PathOpen.
+func rightsInheriting(f *sys.FileEntry) uint64 {
+	if f.Rights == nil {
+		return rightsAll
+	}
+	return f.Rights.Inheriting
+}
+
+// openedFile is like openedFileEntryWithRights, except it also returns ErrnoBadf for a pre-opened directory like "."
+// or "/", as it has no file to read, write or seek.
+func openedFile(ctx context.Context, mod api.Module, fd uint32, rights uint64) (fs.File, Errno) {
+	f, errno := openedFileEntryWithRights(ctx, mod, fd, rights)
+	if errno != ErrnoSuccess {
+		return nil, errno
+	} else if f.File == nil {
+		return nil, ErrnoBadf
+	}
+	return f.File, ErrnoSuccess
+}
+
+// preopenedDir is like openedFileEntry, except it returns ErrnoBadf unless fd is a pre-opened directory like "." or
+// "/", as opposed to a file opened by the guest.
+func preopenedDir(ctx context.Context, mod api.Module, fd uint32) (*sys.FileEntry, Errno) {
+	f, errno := openedFileEntry(ctx, mod, fd)
+	if errno != ErrnoSuccess {
+		return nil, errno
+	} else if f.File != nil {
+		return nil, ErrnoBadf
+	}
+	return f, ErrnoSuccess
+}
+
+// openedSocket returns the socket opened at fd, or ErrnoBadf if fd isn't open and ErrnoNotsock if it isn't a socket.
+func openedSocket(ctx context.Context, mod api.Module, fd uint32) (*sys.Socket, Errno) {
+	if fd <= fdStderr {
+		return nil, ErrnoNotsock // stdio is always open, but never a socket.
+	}
+
+	f, errno := openedFileEntry(ctx, mod, fd)
+	if errno != ErrnoSuccess {
+		return nil, errno
+	}
+	socket, ok := f.File.(*sys.Socket)
+	if !ok {
+		return nil, ErrnoNotsock
+	}
+	return socket, ErrnoSuccess
 }
 
 const (
@@ -1419,6 +2466,63 @@ const (
 	fdStderr = 2
 )
 
+// https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-oflags-flagsu16
+const (
+	oflagCreat = 1 << iota
+	oflagDirectory
+	oflagExcl
+	oflagTrunc
+)
+
+// https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-fdflags-flagsu16
+const (
+	fdflagAppend = 1 << iota
+)
+
+// https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-fstflags-flagsu16
+const (
+	fstflagAtim = 1 << iota
+	fstflagAtimNow
+	fstflagMtim
+	fstflagMtimNow
+)
+
+// https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-rights-flagsu64
+const (
+	rightFdDatasync = 1 << iota
+	rightFdRead
+	rightFdSeek
+	rightFdFdstatSetFlags
+	rightFdSync
+	rightFdTell
+	rightFdWrite
+	rightFdAdvise
+	rightFdAllocate
+	rightPathCreateDirectory
+	rightPathCreateFile
+	rightPathLinkSource
+	rightPathLinkTarget
+	rightPathOpen
+	rightFdReaddir
+	rightPathReadlink
+	rightPathRenameSource
+	rightPathRenameTarget
+	rightPathFilestatGet
+	rightPathFilestatSetSize
+	rightPathFilestatSetTimes
+	rightFdFilestatGet
+	rightFdFilestatSetSize
+	rightFdFilestatSetTimes
+	rightPathSymlink
+	rightPathRemoveDirectory
+	rightPathUnlinkFile
+	rightPollFdReadwrite
+	rightSockShutdown
+
+	// rightsAll are the rights of a file descriptor not opened by PathOpen, such as a pre-opened directory.
+	rightsAll = rightSockShutdown<<1 - 1
+)
+
 // https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-clockid-enumu32
 const (
 	clockIDRealtime  = 0
@@ -1449,26 +2553,188 @@ func sysFSCtx(ctx context.Context, mod api.Module) (*sys.Context, *sys.FSContext
 	}
 }
 
-func openFileEntry(rootFS fs.FS, pathName string) (*sys.FileEntry, Errno) {
-	f, err := rootFS.Open(pathName)
+// resolvePath joins the path read from memory to the path of its directory, returning a name valid for fs.FS or
+// ErrnoNotcapable if the result escapes the directory.
+func resolvePath(dirPath, pathName string) (string, Errno) {
+	// Check the path relative to its directory before joining, as otherwise cleaning "../foo" under "/" is "/foo".
+	if rel := path.Clean(pathName); rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", ErrnoNotcapable
+	}
+	name := path.Join(dirPath, pathName)
+	if name == "/" {
+		name = "."
+	} else if name[0] == '/' {
+		name = name[1:]
+	}
+	if !fs.ValidPath(name) {
+		return "", ErrnoNotcapable
+	}
+	return name, ErrnoSuccess
+}
+
+// filetypeOf returns the WASI filetype of the mode, or filetypeUnknown if it has no equivalent.
+func filetypeOf(mode fs.FileMode) uint8 {
+	switch {
+	case mode.IsRegular():
+		return filetypeRegularFile
+	case mode.IsDir():
+		return filetypeDirectory
+	case mode&fs.ModeSymlink != 0:
+		return filetypeSymbolicLink
+	case mode&fs.ModeSocket != 0:
+		return filetypeSocketStream
+	case mode&fs.ModeCharDevice != 0:
+		return filetypeCharacterDevice
+	case mode&fs.ModeDevice != 0:
+		return filetypeBlockDevice
+	}
+	return filetypeUnknown
+}
+
+// filestatTimes returns the access and modification times to set for the fstflags of "fd_filestat_set_times" or
+// "path_filestat_set_times". A zero time.Time means the corresponding time should be left unchanged.
+func filestatTimes(ctx context.Context, sysCtx *sys.Context, atim, mtim uint64, fstFlags uint32) (atime, mtime time.Time, errno Errno) {
+	if fstFlags&^(fstflagAtim|fstflagAtimNow|fstflagMtim|fstflagMtimNow) != 0 ||
+		fstFlags&(fstflagAtim|fstflagAtimNow) == fstflagAtim|fstflagAtimNow ||
+		fstFlags&(fstflagMtim|fstflagMtimNow) == fstflagMtim|fstflagMtimNow {
+		return atime, mtime, ErrnoInval
+	}
+
+	var now time.Time
+	if fstFlags&(fstflagAtimNow|fstflagMtimNow) != 0 {
+		sec, nsec := sysCtx.Walltime(ctx)
+		now = time.Unix(sec, int64(nsec))
+	}
+
+	switch {
+	case fstFlags&fstflagAtim != 0:
+		atime = time.Unix(0, int64(atim))
+	case fstFlags&fstflagAtimNow != 0:
+		atime = now
+	}
+	switch {
+	case fstFlags&fstflagMtim != 0:
+		mtime = time.Unix(0, int64(mtim))
+	case fstFlags&fstflagMtimNow != 0:
+		mtime = now
+	}
+	return atime, mtime, ErrnoSuccess
+}
+
+// chtimes changes the times of the path in rootFS, or returns ErrnoRofs if it doesn't implement sys.ChtimesFS.
+func chtimes(rootFS fs.FS, pathName string, atime, mtime time.Time) Errno {
+	chtimesFS, ok := rootFS.(sys.ChtimesFS)
+	if !ok {
+		return ErrnoRofs
+	}
+
+	if err := chtimesFS.Chtimes(pathName, atime, mtime); err != nil {
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			return ErrnoNoent
+		case errors.Is(err, fs.ErrPermission):
+			return ErrnoAcces
+		default:
+			return ErrnoIo
+		}
+	}
+	return ErrnoSuccess
+}
+
+// openFlag returns the os.OpenFile flag corresponding to the parameters of PathOpen. Write access is inferred from
+// fsRightsBase, as that's how libraries such as wasi-libc encode os.O_WRONLY or os.O_RDWR.
+func openFlag(oflags uint32, fsRightsBase uint64, fdflags uint32) (flag int) {
+	if fsRightsBase&rightFdWrite != 0 {
+		if fsRightsBase&rightFdRead != 0 {
+			flag = os.O_RDWR
+		} else {
+			flag = os.O_WRONLY
+		}
+	} // else os.O_RDONLY, which is zero.
+	if oflags&oflagCreat != 0 {
+		flag |= os.O_CREATE
+	}
+	if oflags&oflagExcl != 0 {
+		flag |= os.O_EXCL
+	}
+	if oflags&oflagTrunc != 0 {
+		flag |= os.O_TRUNC
+	}
+	if fdflags&fdflagAppend != 0 {
+		flag |= os.O_APPEND
+	}
+	return
+}
+
+// openFileEntry opens the path in rootFS. When rootFS implements sys.OpenFileFS, the flag is used to open the file,
+// ex. os.O_RDWR|os.O_CREATE. Otherwise, rootFS is read-only, so any flag that requires writing fails with ErrnoRofs.
+func openFileEntry(rootFS fs.FS, pathName string, flag int) (*sys.FileEntry, Errno) {
+	var f fs.File
+	var err error
+	openFileFS, ok := rootFS.(sys.OpenFileFS)
+	if ok && flag != os.O_RDONLY {
+		f, err = openFileFS.OpenFile(pathName, flag, 0o644)
+	} else {
+		f, err = rootFS.Open(pathName)
+	}
 	if err != nil {
 		switch {
 		case errors.Is(err, fs.ErrNotExist):
+			if !ok && flag&os.O_CREATE != 0 {
+				return nil, ErrnoRofs // the file doesn't exist, and can't be created.
+			}
 			return nil, ErrnoNoent
 		case errors.Is(err, fs.ErrExist):
 			return nil, ErrnoExist
+		case errors.Is(err, syscall.EISDIR):
+			return nil, ErrnoIsdir
+		case errors.Is(err, syscall.ENOTDIR):
+			return nil, ErrnoNotdir
 		default:
 			return nil, ErrnoIo
 		}
 	}
 
-	// TODO: verify if oflags is a directory and fail with ErrnoNotdir if not
-	// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-oflags-flagsu16
-
+	if !ok { // rootFS is read-only, so it ignored the flag.
+		if errno := readOnlyErrno(flag); errno != ErrnoSuccess {
+			_ = f.Close()
+			return nil, errno
+		}
+	}
 	return &sys.FileEntry{Path: pathName, FS: rootFS, File: f}, ErrnoSuccess
 }
 
-func writeOffsetsAndNullTerminatedValues(ctx context.Context, mem api.Memory, values []string, offsets, bytes uint32) Errno {
+// writeErrno returns the Errno of a failed write: ErrnoPipe if the reading end was closed, ex. a pipe to `head` which
+// exited early, or ErrnoIo otherwise.
+func writeErrno(err error) Errno {
+	if errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrClosedPipe) {
+		return ErrnoPipe
+	}
+	return ErrnoIo
+}
+
+// readOnlyErrno returns the Errno of opening an existing file on a read-only file system with the given flag.
+func readOnlyErrno(flag int) Errno {
+	switch {
+	case flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL:
+		return ErrnoExist
+	case flag&(os.O_TRUNC|os.O_WRONLY|os.O_RDWR) != 0:
+		return ErrnoRofs
+	}
+	return ErrnoSuccess
+}
+
+// writeOffsetsAndNullTerminatedValues writes a uint32le array of absolute offsets to `offsets`, each pointing to the
+// corresponding value written with a NUL terminator contiguously from `bytes`. bytesLen is the sum of the length of
+// each value plus one for its NUL terminator.
+//
+// Note: Both regions are checked before writing, so memory is not partially written on ErrnoFault.
+func writeOffsetsAndNullTerminatedValues(ctx context.Context, mem api.Memory, values []string, offsets, bytes, bytesLen uint32) Errno {
+	memSize := uint64(mem.Size(ctx))
+	if uint64(offsets)+4*uint64(len(values)) > memSize || uint64(bytes)+uint64(bytesLen) > memSize {
+		return ErrnoFault
+	}
+
 	for _, value := range values {
 		// Write current offset and advance it.
 		if !mem.WriteUint32Le(ctx, offsets, bytes) {
@@ -4,17 +4,21 @@ import (
 	"bytes"
 	"context"
 	_ "embed"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"math"
 	"math/rand"
+	"net"
 	"os"
 	"path"
+	"syscall"
 	"testing"
 	"testing/fstest"
 	"testing/iotest"
+	"time"
 
 	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/api"
@@ -82,6 +86,37 @@ func TestSnapshotPreview1_ArgsGet(t *testing.T) {
 	})
 }
 
+func TestSnapshotPreview1_ArgsGet_UTF8(t *testing.T) {
+	sysCtx, err := newSysContext([]string{"ä", "日本"}, nil, nil)
+	require.NoError(t, err)
+
+	argv := uint32(11)   // arbitrary offset
+	argvBuf := uint32(1) // arbitrary offset
+	expectedMemory := []byte{
+		'?',           // argvBuf is after this
+		0xc3, 0xa4, 0, // null terminated "ä"
+		0xe6, 0x97, 0xa5, 0xe6, 0x9c, 0xac, 0, // null terminated "日本"
+		1, 0, 0, 0, // little endian-encoded offset of "ä"
+		4, 0, 0, 0, // little endian-encoded offset of "日本"
+		'?', // stopped after encoding
+	}
+
+	mod, _ := instantiateModule(testCtx, t, functionArgsGet, importArgsGet, sysCtx)
+	defer mod.Close(testCtx)
+
+	maskMemory(t, testCtx, mod, len(expectedMemory))
+
+	errno := a.ArgsGet(testCtx, mod, argv, argvBuf)
+	require.Zero(t, errno, ErrnoName(errno))
+
+	actual, ok := mod.Memory().Read(testCtx, 0, uint32(len(expectedMemory)))
+	require.True(t, ok)
+	require.Equal(t, expectedMemory, actual)
+
+	// The size reported by args_sizes_get must match the bytes written, not the rune count.
+	require.Equal(t, uint32(len(expectedMemory)-2-8), sysCtx.ArgsSize())
+}
+
 func TestSnapshotPreview1_ArgsGet_Errors(t *testing.T) {
 	sysCtx, err := newSysContext([]string{"a", "bc"}, nil, nil)
 	require.NoError(t, err)
@@ -125,13 +160,55 @@ func TestSnapshotPreview1_ArgsGet_Errors(t *testing.T) {
 		tc := tt
 
 		t.Run(tc.name, func(t *testing.T) {
+			maskMemory(t, testCtx, mod, int(memorySize))
+
 			errno := a.ArgsGet(testCtx, mod, tc.argv, tc.argvBuf)
 			require.NoError(t, err)
 			require.Equal(t, ErrnoFault, errno, ErrnoName(errno))
+
+			// Ensure neither region was partially written before the fault.
+			actual, ok := mod.Memory().Read(testCtx, 0, memorySize)
+			require.True(t, ok)
+			require.True(t, bytes.Count(actual, []byte{'?'}) == len(actual), "memory was partially written")
 		})
 	}
 }
 
+// TestSnapshotPreview1_ArgsGet_Layout verifies the exact layout libc implementations such as wasi-libc (used by
+// TinyGo and Rust) expect: argv is an array of absolute offsets into argv_buf, which holds the arguments back to back,
+// each NUL-terminated.
+func TestSnapshotPreview1_ArgsGet_Layout(t *testing.T) {
+	sysCtx, err := newSysContext([]string{"wasi", "./test.txt"}, nil, nil)
+	require.NoError(t, err)
+
+	mod, _ := instantiateModule(testCtx, t, functionArgsGet, importArgsGet, sysCtx)
+	defer mod.Close(testCtx)
+
+	// args_sizes_get: argc=2 and argv_buf_size=len("wasi\x00./test.txt\x00")
+	require.Equal(t, 2, len(sysCtx.Args()))
+	require.Equal(t, uint32(16), sysCtx.ArgsSize())
+
+	argv := uint32(1)    // arbitrary offset
+	argvBuf := uint32(9) // immediately after argv, as wasi-libc allocates argc pointers
+	expectedMemory := []byte{
+		'?',        // argv is after this
+		9, 0, 0, 0, // little endian-encoded absolute offset of "wasi"
+		14, 0, 0, 0, // little endian-encoded absolute offset of "./test.txt"
+		'w', 'a', 's', 'i', 0, // null terminated "wasi"
+		'.', '/', 't', 'e', 's', 't', '.', 't', 'x', 't', 0, // null terminated "./test.txt"
+		'?', // stopped after encoding
+	}
+
+	maskMemory(t, testCtx, mod, len(expectedMemory))
+
+	errno := a.ArgsGet(testCtx, mod, argv, argvBuf)
+	require.Zero(t, errno, ErrnoName(errno))
+
+	actual, ok := mod.Memory().Read(testCtx, 0, uint32(len(expectedMemory)))
+	require.True(t, ok)
+	require.Equal(t, expectedMemory, actual)
+}
+
 func TestSnapshotPreview1_ArgsSizesGet(t *testing.T) {
 	sysCtx, err := newSysContext([]string{"a", "bc"}, nil, nil)
 	require.NoError(t, err)
@@ -175,6 +252,33 @@ func TestSnapshotPreview1_ArgsSizesGet(t *testing.T) {
 	})
 }
 
+func TestSnapshotPreview1_ArgsSizesGet_NoArgs(t *testing.T) {
+	sysCtx, err := newSysContext(nil, nil, nil)
+	require.NoError(t, err)
+
+	resultArgc := uint32(1)        // arbitrary offset
+	resultArgvBufSize := uint32(6) // arbitrary offset
+	expectedMemory := []byte{
+		'?',                // resultArgc is after this
+		0x0, 0x0, 0x0, 0x0, // little endian-encoded arg count
+		'?',                // resultArgvBufSize is after this
+		0x0, 0x0, 0x0, 0x0, // little endian-encoded size of null terminated strings
+		'?', // stopped after encoding
+	}
+
+	mod, _ := instantiateModule(testCtx, t, functionArgsSizesGet, importArgsSizesGet, sysCtx)
+	defer mod.Close(testCtx)
+
+	maskMemory(t, testCtx, mod, len(expectedMemory))
+
+	errno := a.ArgsSizesGet(testCtx, mod, resultArgc, resultArgvBufSize)
+	require.Zero(t, errno, ErrnoName(errno))
+
+	actual, ok := mod.Memory().Read(testCtx, 0, uint32(len(expectedMemory)))
+	require.True(t, ok)
+	require.Equal(t, expectedMemory, actual)
+}
+
 func TestSnapshotPreview1_ArgsSizesGet_Errors(t *testing.T) {
 	sysCtx, err := newSysContext([]string{"a", "bc"}, nil, nil)
 	require.NoError(t, err)
@@ -359,6 +463,42 @@ func TestSnapshotPreview1_EnvironSizesGet(t *testing.T) {
 	})
 }
 
+// TestSnapshotPreview1_Environ_DuplicateKeys ensures duplicate and case-varying keys are passed through in order, and
+// that environ_sizes_get reports exactly what environ_get writes.
+func TestSnapshotPreview1_Environ_DuplicateKeys(t *testing.T) {
+	environ := []string{"a=b", "A=c", "a=d"}
+	sysCtx, err := newSysContext(nil, environ, nil)
+	require.NoError(t, err)
+
+	mod, _ := instantiateModule(testCtx, t, functionEnvironSizesGet, importEnvironSizesGet, sysCtx)
+	defer mod.Close(testCtx)
+
+	resultEnvironc, resultEnvironBufSize := uint32(0), uint32(4) // arbitrary offsets
+	errno := a.EnvironSizesGet(testCtx, mod, resultEnvironc, resultEnvironBufSize)
+	require.Zero(t, errno, ErrnoName(errno))
+
+	environc, ok := mod.Memory().ReadUint32Le(testCtx, resultEnvironc)
+	require.True(t, ok)
+	require.Equal(t, uint32(len(environ)), environc)
+	environBufSize, ok := mod.Memory().ReadUint32Le(testCtx, resultEnvironBufSize)
+	require.True(t, ok)
+	require.Equal(t, uint32(12), environBufSize) // 3 null terminated strings of 3 bytes each
+
+	resultEnviron, resultEnvironBuf := uint32(8), uint32(8+4*environc) // the buffer follows the offsets
+	errno = a.EnvironGet(testCtx, mod, resultEnviron, resultEnvironBuf)
+	require.Zero(t, errno, ErrnoName(errno))
+
+	buf, ok := mod.Memory().Read(testCtx, resultEnvironBuf, environBufSize)
+	require.True(t, ok)
+	require.Equal(t, "a=b\x00A=c\x00a=d\x00", string(buf))
+
+	for i := uint32(0); i < environc; i++ {
+		offset, ok := mod.Memory().ReadUint32Le(testCtx, resultEnviron+4*i)
+		require.True(t, ok)
+		require.Equal(t, resultEnvironBuf+4*i, offset)
+	}
+}
+
 func TestSnapshotPreview1_EnvironSizesGet_Errors(t *testing.T) {
 	sysCtx, err := newSysContext(nil, []string{"a=b", "b=cd"}, nil)
 	require.NoError(t, err)
@@ -677,42 +817,206 @@ func TestSnapshotPreview1_ClockTimeGet_Errors(t *testing.T) {
 	}
 }
 
-// TestSnapshotPreview1_FdAdvise only tests it is stubbed for GrainLang per #271
 func TestSnapshotPreview1_FdAdvise(t *testing.T) {
-	mod, fn := instantiateModule(testCtx, t, functionFdAdvise, importFdAdvise, nil)
+	fd := uint32(3) // arbitrary fd after 0, 1, and 2, that are stdin/out/err
+	file, testFS := createFile(t, "test_path", []byte("wazero"))
+	sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
+		fd: {Path: "test_path", FS: testFS, File: file},
+	})
+	require.NoError(t, err)
+
+	mod, fn := instantiateModule(testCtx, t, functionFdAdvise, importFdAdvise, sysCtx)
 	defer mod.Close(testCtx)
 
 	t.Run("wasi.FdAdvise", func(t *testing.T) {
-		errno := a.FdAdvise(testCtx, mod, 0, 0, 0, 0)
-		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
+		errno := a.FdAdvise(testCtx, mod, fd, 0, 6, adviceSequential)
+		require.Zero(t, errno, ErrnoName(errno))
 	})
 
 	t.Run(functionFdAdvise, func(t *testing.T) {
-		results, err := fn.Call(testCtx, 0, 0, 0, 0)
+		results, err := fn.Call(testCtx, uint64(fd), 0, 6, adviceDontNeed)
 		require.NoError(t, err)
 		errno := Errno(results[0]) // results[0] is the errno
-		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
+		require.Zero(t, errno, ErrnoName(errno))
 	})
 }
 
-// TestSnapshotPreview1_FdAllocate only tests it is stubbed for GrainLang per #271
-func TestSnapshotPreview1_FdAllocate(t *testing.T) {
-	mod, fn := instantiateModule(testCtx, t, functionFdAllocate, importFdAllocate, nil)
+func TestSnapshotPreview1_FdAdvise_Errors(t *testing.T) {
+	fd := uint32(3) // arbitrary fd after 0, 1, and 2, that are stdin/out/err
+	file, testFS := createFile(t, "test_path", []byte("wazero"))
+	sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
+		fd: {Path: "test_path", FS: testFS, File: file},
+	})
+	require.NoError(t, err)
+
+	mod, _ := instantiateModule(testCtx, t, functionFdAdvise, importFdAdvise, sysCtx)
 	defer mod.Close(testCtx)
 
+	tests := []struct {
+		name          string
+		fd, advice    uint32
+		expectedErrno Errno
+	}{
+		{
+			name:          "invalid fd",
+			fd:            42, // arbitrary invalid fd
+			advice:        adviceNormal,
+			expectedErrno: ErrnoBadf,
+		},
+		{
+			name:          "invalid advice",
+			fd:            fd,
+			advice:        adviceNoReuse + 1,
+			expectedErrno: ErrnoInval,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			errno := a.FdAdvise(testCtx, mod, tc.fd, 0, 0, tc.advice)
+			require.Equal(t, tc.expectedErrno, errno, ErrnoName(errno))
+		})
+	}
+}
+
+func TestSnapshotPreview1_FdAllocate(t *testing.T) {
+	fd := uint32(3) // arbitrary fd after 0, 1, and 2, that are stdin/out/err
+	pathName := "test_path"
+	tmpDir := t.TempDir()
+
+	setup := func() (api.Module, api.Function) {
+		file, testFS := createWriteableFile(t, tmpDir, pathName, []byte("wazero"))
+		sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
+			fd: {Path: pathName, FS: testFS, File: file},
+		})
+		require.NoError(t, err)
+		return instantiateModule(testCtx, t, functionFdAllocate, importFdAllocate, sysCtx)
+	}
+
+	requireSize := func(expected int64) {
+		st, err := os.Stat(path.Join(tmpDir, pathName))
+		require.NoError(t, err)
+		require.Equal(t, expected, st.Size())
+	}
+
 	t.Run("wasi.FdAllocate", func(t *testing.T) {
-		errno := a.FdAllocate(testCtx, mod, 0, 0, 0)
-		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
+		mod, _ := setup()
+		defer mod.Close(testCtx)
+
+		errno := a.FdAllocate(testCtx, mod, fd, 4, 12)
+		require.Zero(t, errno, ErrnoName(errno))
+		requireSize(16)
+
+		// A region within the file doesn't shrink it.
+		errno = a.FdAllocate(testCtx, mod, fd, 0, 1)
+		require.Zero(t, errno, ErrnoName(errno))
+		requireSize(16)
+
+		// The original contents are retained, and the rest is zero.
+		buf, err := os.ReadFile(path.Join(tmpDir, pathName))
+		require.NoError(t, err)
+		require.Equal(t, append([]byte("wazero"), make([]byte, 10)...), buf)
 	})
 
 	t.Run(functionFdAllocate, func(t *testing.T) {
-		results, err := fn.Call(testCtx, 0, 0, 0)
+		mod, fn := setup()
+		defer mod.Close(testCtx)
+
+		results, err := fn.Call(testCtx, uint64(fd), 100, 28)
 		require.NoError(t, err)
 		errno := Errno(results[0]) // results[0] is the errno
-		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
+		require.Zero(t, errno, ErrnoName(errno))
+		requireSize(128)
 	})
 }
 
+func TestSnapshotPreview1_FdAllocate_Errors(t *testing.T) {
+	fileFD, readOnlyFD, noTruncateFD, dirFD := uint32(3), uint32(4), uint32(5), uint32(6)
+
+	file, testFS := createWriteableFile(t, t.TempDir(), "test_path", []byte("wazero"))
+	readOnlyFile, readOnlyFS := createFile(t, "test_path", []byte("wazero"))
+	sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
+		fileFD:       {Path: "test_path", FS: testFS, File: file},
+		readOnlyFD:   {Path: "test_path", FS: readOnlyFS, File: readOnlyFile}, // fstest.MapFS files don't implement Write
+		noTruncateFD: {Path: "test_path", FS: readOnlyFS, File: &writeOnlyFile{File: readOnlyFile}},
+		dirFD:        {Path: ".", FS: testFS},
+	})
+	require.NoError(t, err)
+
+	mod, _ := instantiateModule(testCtx, t, functionFdAllocate, importFdAllocate, sysCtx)
+	defer mod.Close(testCtx)
+
+	tests := []struct {
+		name          string
+		fd            uint32
+		offset, len   uint64
+		expectedErrno Errno
+	}{
+		{
+			name:          "invalid fd",
+			fd:            42, // arbitrary invalid fd
+			len:           1,
+			expectedErrno: ErrnoBadf,
+		},
+		{
+			name:          "pre-opened directory",
+			fd:            dirFD,
+			len:           1,
+			expectedErrno: ErrnoBadf,
+		},
+		{
+			name:          "zero len",
+			fd:            fileFD,
+			expectedErrno: ErrnoInval,
+		},
+		{
+			name:          "size overflows",
+			fd:            fileFD,
+			offset:        math.MaxUint64,
+			len:           1,
+			expectedErrno: ErrnoFbig,
+		},
+		{
+			name:          "size exceeds max file size",
+			fd:            fileFD,
+			offset:        math.MaxInt64,
+			len:           1,
+			expectedErrno: ErrnoFbig,
+		},
+		{
+			name:          "file not writable",
+			fd:            readOnlyFD,
+			len:           1,
+			expectedErrno: ErrnoRofs,
+		},
+		{
+			name:          "file without Truncate",
+			fd:            noTruncateFD,
+			len:           1,
+			expectedErrno: ErrnoNotsup,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			errno := a.FdAllocate(testCtx, mod, tc.fd, tc.offset, tc.len)
+			require.Equal(t, tc.expectedErrno, errno, ErrnoName(errno))
+		})
+	}
+}
+
+// writeOnlyFile is a fs.File that implements io.Writer, but not Truncate.
+type writeOnlyFile struct {
+	fs.File
+}
+
+// Write implements io.Writer
+func (f *writeOnlyFile) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
 func TestSnapshotPreview1_FdClose(t *testing.T) {
 	fdToClose := uint32(3) // arbitrary fd
 	fdToKeep := uint32(4)  // another arbitrary fd
@@ -721,9 +1025,9 @@ func TestSnapshotPreview1_FdClose(t *testing.T) {
 		// fd_close needs to close an open file descriptor. Open two files so that we can tell which is closed.
 		path1, path2 := "a", "b"
 		testFs := fstest.MapFS{path1: {Data: make([]byte, 0)}, path2: {Data: make([]byte, 0)}}
-		entry1, errno := openFileEntry(testFs, path1)
+		entry1, errno := openFileEntry(testFs, path1, os.O_RDONLY)
 		require.Zero(t, errno, ErrnoName(errno))
-		entry2, errno := openFileEntry(testFs, path2)
+		entry2, errno := openFileEntry(testFs, path2, os.O_RDONLY)
 		require.Zero(t, errno, ErrnoName(errno))
 
 		sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
@@ -774,30 +1078,127 @@ func TestSnapshotPreview1_FdClose(t *testing.T) {
 		errno := api.FdClose(testCtx, mod, 42) // 42 is an arbitrary invalid FD
 		require.Equal(t, ErrnoBadf, errno)
 	})
+	t.Run("ErrnoNotsup for a pre-opened directory", func(t *testing.T) {
+		preopenFD := uint32(3) // arbitrary fd after 0, 1, and 2, that are stdin/out/err
+		sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
+			preopenFD: {Path: ".", FS: fstest.MapFS{}},
+		})
+		require.NoError(t, err)
+		mod, _ := instantiateModule(testCtx, t, functionFdClose, importFdClose, sysCtx)
+		defer mod.Close(testCtx)
+
+		errno := a.FdClose(testCtx, mod, preopenFD)
+		require.Equal(t, ErrnoNotsup, errno, ErrnoName(errno))
+
+		// Verify the pre-opened directory is still usable.
+		_, fsc := sysFSCtx(testCtx, mod)
+		_, ok := fsc.OpenedFile(preopenFD)
+		require.True(t, ok)
+	})
+	t.Run("closed FD is re-used", func(t *testing.T) {
+		mod, _, api := setupFD()
+		defer mod.Close(testCtx)
+
+		errno := api.FdClose(testCtx, mod, fdToClose)
+		require.Zero(t, errno, ErrnoName(errno))
+
+		_, fsc := sysFSCtx(testCtx, mod)
+		fd, ok := fsc.OpenFile(&internalsys.FileEntry{Path: "c"})
+		require.True(t, ok)
+		require.Equal(t, fdToClose, fd)
+	})
 }
 
-// TestSnapshotPreview1_FdDatasync only tests it is stubbed for GrainLang per #271
 func TestSnapshotPreview1_FdDatasync(t *testing.T) {
-	mod, fn := instantiateModule(testCtx, t, functionFdDatasync, importFdDatasync, nil)
-	defer mod.Close(testCtx)
+	fd := uint32(3) // arbitrary fd after 0, 1, and 2, that are stdin/out/err
+
+	setup := func() (api.Module, api.Function, *syncTrackingFile) {
+		file, testFS := createFile(t, "test_path", []byte("wazero"))
+		syncFile := &syncTrackingFile{File: file}
+		sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
+			fd: {Path: "test_path", FS: testFS, File: syncFile},
+		})
+		require.NoError(t, err)
+		mod, fn := instantiateModule(testCtx, t, functionFdDatasync, importFdDatasync, sysCtx)
+		return mod, fn, syncFile
+	}
 
 	t.Run("wasi.FdDatasync", func(t *testing.T) {
-		errno := a.FdDatasync(testCtx, mod, 0)
-		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
+		mod, _, syncFile := setup()
+		defer mod.Close(testCtx)
+
+		errno := a.FdDatasync(testCtx, mod, fd)
+		require.Zero(t, errno, ErrnoName(errno))
+		require.Equal(t, 1, syncFile.syncCount)
 	})
 
 	t.Run(functionFdDatasync, func(t *testing.T) {
-		results, err := fn.Call(testCtx, 0)
+		mod, fn, syncFile := setup()
+		defer mod.Close(testCtx)
+
+		results, err := fn.Call(testCtx, uint64(fd))
 		require.NoError(t, err)
 		errno := Errno(results[0]) // results[0] is the errno
-		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
+		require.Zero(t, errno, ErrnoName(errno))
+		require.Equal(t, 1, syncFile.syncCount)
 	})
 }
 
-// TODO: TestSnapshotPreview1_FdFdstatGet TestSnapshotPreview1_FdFdstatGet_Errors
 func TestSnapshotPreview1_FdFdstatGet(t *testing.T) {
-	t.Skip("TODO")
-	_ = importFdFdstatGet // stop linter complaint until we implement this
+	preopenFD, fileFD := uint32(3), uint32(4) // arbitrary fds after 0, 1, and 2, that are stdin/out/err
+	testFS := fstest.MapFS{"file": &fstest.MapFile{Data: []byte("wazero")}}
+	file, err := testFS.Open("file")
+	require.NoError(t, err)
+
+	sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
+		preopenFD: {Path: ".", FS: testFS},
+		fileFD:    {Path: "file", FS: testFS, File: file, Rights: &internalsys.Rights{Base: rightFdRead | rightFdSeek}},
+	})
+	require.NoError(t, err)
+
+	mod, fn := instantiateModule(testCtx, t, functionFdFdstatGet, importFdFdstatGet, sysCtx)
+	defer mod.Close(testCtx)
+
+	tests := []struct {
+		name     string
+		fd       uint32
+		expected fdstat
+	}{
+		{
+			name:     "pre-opened directory",
+			fd:       preopenFD,
+			expected: fdstat{filetype: filetypeDirectory, rightsBase: rightsAll, rightsInheriting: rightsAll},
+		},
+		{
+			name:     "file opened by the guest",
+			fd:       fileFD,
+			expected: fdstat{filetype: filetypeRegularFile, rightsBase: rightFdRead | rightFdSeek},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			resultStat := uint32(1)
+			maskMemory(t, testCtx, mod, int(resultStat)+fdstatSize+1)
+
+			results, err := fn.Call(testCtx, uint64(tc.fd), uint64(resultStat))
+			require.NoError(t, err)
+			errno := Errno(results[0]) // results[0] is the errno
+			require.Zero(t, errno, ErrnoName(errno))
+
+			expected := make([]byte, fdstatSize)
+			tc.expected.encode(expected)
+			actual, ok := mod.Memory().Read(testCtx, resultStat, fdstatSize)
+			require.True(t, ok)
+			require.Equal(t, expected, actual)
+		})
+	}
+
+	t.Run("invalid resultStat", func(t *testing.T) {
+		errno := a.FdFdstatGet(testCtx, mod, preopenFD, mod.Memory().Size(testCtx))
+		require.Equal(t, ErrnoFault, errno, ErrnoName(errno))
+	})
 }
 
 // TestSnapshotPreview1_FdFdstatSetFlags only tests it is stubbed for GrainLang per #271
@@ -836,21 +1237,76 @@ func TestSnapshotPreview1_FdFdstatSetRights(t *testing.T) {
 	})
 }
 
-// TestSnapshotPreview1_FdFilestatGet only tests it is stubbed for GrainLang per #271
 func TestSnapshotPreview1_FdFilestatGet(t *testing.T) {
-	mod, fn := instantiateModule(testCtx, t, functionFdFilestatGet, importFdFilestatGet, nil)
+	fileFD, dirFD := uint32(3), uint32(4) // arbitrary fds after 0, 1, and 2, that are stdin/out/err
+	mapFS := fstest.MapFS{"file": &fstest.MapFile{Data: []byte("wazero"), ModTime: time.Unix(0, platform.FakeEpochNanos)}}
+	f, err := mapFS.Open("file")
+	require.NoError(t, err)
+
+	sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
+		fileFD: {Path: "file", FS: mapFS, File: f},
+		dirFD:  {Path: "/", FS: mapFS},
+	})
+	require.NoError(t, err)
+
+	mod, fn := instantiateModule(testCtx, t, functionFdFilestatGet, importFdFilestatGet, sysCtx)
 	defer mod.Close(testCtx)
 
+	resultBuf := uint32(1) // arbitrary offset
+	expectedMemory := []byte{
+		'?',                    // resultBuf is after this
+		0, 0, 0, 0, 0, 0, 0, 0, // dev
+		0, 0, 0, 0, 0, 0, 0, 0, // ino
+		filetypeRegularFile, 0, 0, 0, 0, 0, 0, 0, // filetype + padding
+		1, 0, 0, 0, 0, 0, 0, 0, // nlink
+		6, 0, 0, 0, 0, 0, 0, 0, // size
+		0, 0, 31, 166, 112, 252, 197, 22, // atim
+		0, 0, 31, 166, 112, 252, 197, 22, // mtim
+		0, 0, 31, 166, 112, 252, 197, 22, // ctim
+		'?',
+	}
+
 	t.Run("wasi.FdFilestatGet", func(t *testing.T) {
-		errno := a.FdFilestatGet(testCtx, mod, 0, 0)
-		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
+		maskMemory(t, testCtx, mod, len(expectedMemory))
+
+		errno := a.FdFilestatGet(testCtx, mod, fileFD, resultBuf)
+		require.Zero(t, errno, ErrnoName(errno))
+
+		actual, ok := mod.Memory().Read(testCtx, 0, uint32(len(expectedMemory)))
+		require.True(t, ok)
+		require.Equal(t, expectedMemory, actual)
 	})
 
 	t.Run(functionFdFilestatGet, func(t *testing.T) {
-		results, err := fn.Call(testCtx, 0, 0)
+		maskMemory(t, testCtx, mod, len(expectedMemory))
+
+		results, err := fn.Call(testCtx, uint64(fileFD), uint64(resultBuf))
 		require.NoError(t, err)
 		errno := Errno(results[0]) // results[0] is the errno
-		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
+		require.Zero(t, errno, ErrnoName(errno))
+
+		actual, ok := mod.Memory().Read(testCtx, 0, uint32(len(expectedMemory)))
+		require.True(t, ok)
+		require.Equal(t, expectedMemory, actual)
+	})
+
+	t.Run("directory mount", func(t *testing.T) {
+		errno := a.FdFilestatGet(testCtx, mod, dirFD, resultBuf)
+		require.Zero(t, errno, ErrnoName(errno))
+
+		filetype, ok := mod.Memory().ReadByte(testCtx, resultBuf+16)
+		require.True(t, ok)
+		require.Equal(t, byte(filetypeDirectory), filetype)
+	})
+
+	t.Run("invalid fd", func(t *testing.T) {
+		errno := a.FdFilestatGet(testCtx, mod, 42, resultBuf)
+		require.Equal(t, ErrnoBadf, errno, ErrnoName(errno))
+	})
+
+	t.Run("resultBuf exceeds the maximum valid address by 1", func(t *testing.T) {
+		errno := a.FdFilestatGet(testCtx, mod, fileFD, mod.Memory().Size(testCtx)-filestatSize+1)
+		require.Equal(t, ErrnoFault, errno, ErrnoName(errno))
 	})
 }
 
@@ -872,22 +1328,71 @@ func TestSnapshotPreview1_FdFilestatSetSize(t *testing.T) {
 	})
 }
 
-// TestSnapshotPreview1_FdFilestatSetTimes only tests it is stubbed for GrainLang per #271
 func TestSnapshotPreview1_FdFilestatSetTimes(t *testing.T) {
-	mod, fn := instantiateModule(testCtx, t, functionFdFilestatSetTimes, importFdFilestatSetTimes, nil)
+	fileFD, readOnlyFD, socketFD := uint32(3), uint32(4), uint32(5) // arbitrary fds after 0, 1, and 2
+	tmpDir := t.TempDir()
+	f, _ := createWriteableFile(t, tmpDir, "file", []byte("wazero"))
+	readOnlyFile, readOnlyFS := createFile(t, "file", []byte("wazero"))
+	conn, peer := net.Pipe()
+	defer peer.Close()
+
+	sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
+		fileFD:     {Path: "file", FS: wazero.NewWritableDirFS(tmpDir), File: f},
+		readOnlyFD: {Path: "file", FS: readOnlyFS, File: readOnlyFile},
+		socketFD:   {File: &internalsys.Socket{Conn: conn}},
+	})
+	require.NoError(t, err)
+
+	mod, fn := instantiateModule(testCtx, t, functionFdFilestatSetTimes, importFdFilestatSetTimes, sysCtx)
 	defer mod.Close(testCtx)
 
 	t.Run("wasi.FdFilestatSetTimes", func(t *testing.T) {
-		errno := a.FdFilestatSetTimes(testCtx, mod, 0, 0, 0, 0)
-		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
+		mtim := uint64(platform.FakeEpochNanos + 123456789)
+		errno := a.FdFilestatSetTimes(testCtx, mod, fileFD, 0, mtim, fstflagMtim)
+		require.Zero(t, errno, ErrnoName(errno))
+
+		// Read the modification time back, via "fd_filestat_get".
+		resultBuf := uint32(0)
+		errno = a.FdFilestatGet(testCtx, mod, fileFD, resultBuf)
+		require.Zero(t, errno, ErrnoName(errno))
+		actual, ok := mod.Memory().ReadUint64Le(testCtx, resultBuf+48)
+		require.True(t, ok)
+		require.Equal(t, mtim, actual)
 	})
 
 	t.Run(functionFdFilestatSetTimes, func(t *testing.T) {
-		results, err := fn.Call(testCtx, 0, 0, 0, 0)
+		results, err := fn.Call(testCtx, uint64(fileFD), 0, 0, fstflagAtimNow|fstflagMtimNow)
 		require.NoError(t, err)
 		errno := Errno(results[0]) // results[0] is the errno
-		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
+		require.Zero(t, errno, ErrnoName(errno))
+
+		// The _NOW flags use sys.Walltime, which is fake in tests.
+		stat, err := os.Stat(path.Join(tmpDir, "file"))
+		require.NoError(t, err)
+		require.Equal(t, platform.FakeEpochNanos, stat.ModTime().UnixNano())
 	})
+
+	tests := []struct {
+		name          string
+		fd, fstFlags  uint32
+		expectedErrno Errno
+	}{
+		{name: "invalid fd", fd: 42, fstFlags: fstflagMtimNow, expectedErrno: ErrnoBadf},
+		{name: "stdio", fd: fdStdout, fstFlags: fstflagMtimNow, expectedErrno: ErrnoBadf},
+		{name: "socket", fd: socketFD, fstFlags: fstflagMtimNow, expectedErrno: ErrnoNotsup},
+		{name: "read-only file system", fd: readOnlyFD, fstFlags: fstflagMtimNow, expectedErrno: ErrnoRofs},
+		{name: "atim and atim_now", fd: fileFD, fstFlags: fstflagAtim | fstflagAtimNow, expectedErrno: ErrnoInval},
+		{name: "mtim and mtim_now", fd: fileFD, fstFlags: fstflagMtim | fstflagMtimNow, expectedErrno: ErrnoInval},
+		{name: "unknown flag", fd: fileFD, fstFlags: fstflagMtimNow << 1, expectedErrno: ErrnoInval},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			errno := a.FdFilestatSetTimes(testCtx, mod, tc.fd, 0, 0, tc.fstFlags)
+			require.Equal(t, tc.expectedErrno, errno, ErrnoName(errno))
+		})
+	}
 }
 
 // TestSnapshotPreview1_FdPread only tests it is stubbed for GrainLang per #271
@@ -955,9 +1460,14 @@ func TestSnapshotPreview1_FdPrestatGet(t *testing.T) {
 
 func TestSnapshotPreview1_FdPrestatGet_Errors(t *testing.T) {
 	fd := uint32(3)           // fd 3 will be opened for the "/tmp" directory after 0, 1, and 2, that are stdin/out/err
+	fileFD := uint32(4)       // fd 4 will be a file opened by the guest
 	validAddress := uint32(0) // Arbitrary valid address as arguments to fd_prestat_get. We chose 0 here.
 
-	sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{fd: {Path: "/tmp"}})
+	file, testFS := createFile(t, "file", []byte("wazero"))
+	sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
+		fd:     {Path: "/tmp"},
+		fileFD: {Path: "file", FS: testFS, File: file},
+	})
 	require.NoError(t, err)
 
 	mod, _ := instantiateModule(testCtx, t, functionFdPrestatGet, importFdPrestatGet, sysCtx)
@@ -983,7 +1493,12 @@ func TestSnapshotPreview1_FdPrestatGet_Errors(t *testing.T) {
 			resultPrestat: memorySize,
 			expectedErrno: ErrnoFault,
 		},
-		// TODO: non pre-opened file == api.ErrnoBadf
+		{
+			name:          "non pre-opened file",
+			fd:            fileFD,
+			resultPrestat: validAddress,
+			expectedErrno: ErrnoBadf,
+		},
 	}
 
 	for _, tt := range tests {
@@ -1039,8 +1554,13 @@ func TestSnapshotPreview1_FdPrestatDirName(t *testing.T) {
 }
 
 func TestSnapshotPreview1_FdPrestatDirName_Errors(t *testing.T) {
-	fd := uint32(3) // arbitrary fd after 0, 1, and 2, that are stdin/out/err
-	sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{fd: {Path: "/tmp"}})
+	fd := uint32(3)     // arbitrary fd after 0, 1, and 2, that are stdin/out/err
+	fileFD := uint32(4) // a file opened by the guest
+	file, testFS := createFile(t, "file", []byte("wazero"))
+	sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
+		fd:     {Path: "/tmp"},
+		fileFD: {Path: "file", FS: testFS, File: file},
+	})
 	require.NoError(t, err)
 
 	mod, _ := instantiateModule(testCtx, t, functionFdPrestatDirName, importFdPrestatDirName, sysCtx)
@@ -1085,7 +1605,13 @@ func TestSnapshotPreview1_FdPrestatDirName_Errors(t *testing.T) {
 			pathLen:       pathLen,
 			expectedErrno: ErrnoBadf,
 		},
-		// TODO: non pre-opened file == ErrnoBadf
+		{
+			name:          "non pre-opened file",
+			fd:            fileFD,
+			path:          validAddress,
+			pathLen:       uint32(len("file")),
+			expectedErrno: ErrnoBadf,
+		},
 	}
 
 	for _, tt := range tests {
@@ -1295,24 +1821,110 @@ func TestSnapshotPreview1_FdReaddir(t *testing.T) {
 	})
 }
 
-// TestSnapshotPreview1_FdRenumber only tests it is stubbed for GrainLang per #271
 func TestSnapshotPreview1_FdRenumber(t *testing.T) {
-	mod, fn := instantiateModule(testCtx, t, functionFdRenumber, importFdRenumber, nil)
-	defer mod.Close(testCtx)
+	preopenFD, fd, to := uint32(3), uint32(4), uint32(5) // arbitrary fds after 0, 1, and 2, that are stdin/out/err
+
+	setup := func() (api.Module, api.Function, fs.File, *closeTrackingFile) {
+		fromFile, testFS := createFile(t, "from", []byte("wazero"))
+		f, err := testFS.Open("from")
+		require.NoError(t, err)
+		toFile := &closeTrackingFile{File: f}
+		sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
+			preopenFD: {Path: ".", FS: testFS},
+			fd:        {Path: "from", FS: testFS, File: fromFile},
+			to:        {Path: "to", FS: testFS, File: toFile},
+		})
+		require.NoError(t, err)
+		mod, fn := instantiateModule(testCtx, t, functionFdRenumber, importFdRenumber, sysCtx)
+		return mod, fn, fromFile, toFile
+	}
+
+	verify := func(mod api.Module, fromFile fs.File, toFile *closeTrackingFile) {
+		_, fsc := sysFSCtx(testCtx, mod)
+
+		// Verify the old fd is invalid.
+		_, ok := fsc.OpenedFile(fd)
+		require.False(t, ok)
+
+		// Verify the file is now at the new fd.
+		f, ok := fsc.OpenedFile(to)
+		require.True(t, ok)
+		require.Equal(t, "from", f.Path)
+		require.Equal(t, fromFile, f.File)
+
+		// Verify the file replaced was closed.
+		require.Equal(t, 1, toFile.closeCount)
+	}
 
 	t.Run("wasi.FdRenumber", func(t *testing.T) {
-		errno := a.FdRenumber(testCtx, mod, 0, 0)
-		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
+		mod, _, fromFile, toFile := setup()
+		defer mod.Close(testCtx)
+
+		errno := a.FdRenumber(testCtx, mod, fd, to)
+		require.Zero(t, errno, ErrnoName(errno))
+
+		verify(mod, fromFile, toFile)
+
+		// Verify the old fd can no longer be used.
+		errno = a.FdClose(testCtx, mod, fd)
+		require.Equal(t, ErrnoBadf, errno, ErrnoName(errno))
 	})
 
 	t.Run(functionFdRenumber, func(t *testing.T) {
-		results, err := fn.Call(testCtx, 0, 0)
+		mod, fn, fromFile, toFile := setup()
+		defer mod.Close(testCtx)
+
+		results, err := fn.Call(testCtx, uint64(fd), uint64(to))
 		require.NoError(t, err)
 		errno := Errno(results[0]) // results[0] is the errno
-		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
+		require.Zero(t, errno, ErrnoName(errno))
+
+		verify(mod, fromFile, toFile)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		mod, _, _, _ := setup()
+		defer mod.Close(testCtx)
+
+		tests := []struct {
+			name          string
+			fd, to        uint32
+			expectedErrno Errno
+		}{
+			{name: "invalid fd", fd: 42, to: to, expectedErrno: ErrnoBadf},
+			{name: "to stdout", fd: fd, to: 1, expectedErrno: ErrnoBadf},
+			{name: "fd pre-opened", fd: preopenFD, to: to, expectedErrno: ErrnoNotsup},
+			{name: "to pre-opened", fd: fd, to: preopenFD, expectedErrno: ErrnoNotsup},
+		}
+
+		for _, tt := range tests {
+			tc := tt
+			t.Run(tc.name, func(t *testing.T) {
+				errno := a.FdRenumber(testCtx, mod, tc.fd, tc.to)
+				require.Equal(t, tc.expectedErrno, errno, ErrnoName(errno))
+			})
+		}
+
+		// Verify nothing changed.
+		_, fsc := sysFSCtx(testCtx, mod)
+		for _, fd := range []uint32{preopenFD, fd, to} {
+			_, ok := fsc.OpenedFile(fd)
+			require.True(t, ok)
+		}
 	})
 }
 
+type closeTrackingFile struct {
+	fs.File
+	closeCount int
+}
+
+// Close implements io.Closer
+func (f *closeTrackingFile) Close() error {
+	f.closeCount++
+	return f.File.Close()
+}
+
 func TestSnapshotPreview1_FdSeek(t *testing.T) {
 	fd := uint32(3)                                              // arbitrary fd after 0, 1, and 2, that are stdin/out/err
 	resultNewoffset := uint32(1)                                 // arbitrary offset in `ctx.Memory` for the new offset value
@@ -1359,8 +1971,8 @@ func TestSnapshotPreview1_FdSeek(t *testing.T) {
 			whence:         io.SeekStart,
 			expectedOffset: 4, // = offset
 			expectedMemory: []byte{
-				'?',        // resultNewoffset is after this
-				4, 0, 0, 0, // = expectedOffset
+				'?',                    // resultNewoffset is after this
+				4, 0, 0, 0, 0, 0, 0, 0, // = expectedOffset
 				'?',
 			},
 		},
@@ -1370,8 +1982,8 @@ func TestSnapshotPreview1_FdSeek(t *testing.T) {
 			whence:         io.SeekCurrent,
 			expectedOffset: 2, // = 1 (the initial offset of the test file) + 1 (offset)
 			expectedMemory: []byte{
-				'?',        // resultNewoffset is after this
-				2, 0, 0, 0, // = expectedOffset
+				'?',                    // resultNewoffset is after this
+				2, 0, 0, 0, 0, 0, 0, 0, // = expectedOffset
 				'?',
 			},
 		},
@@ -1381,8 +1993,8 @@ func TestSnapshotPreview1_FdSeek(t *testing.T) {
 			whence:         io.SeekEnd,
 			expectedOffset: 5, // = 6 (the size of the test file with content "wazero") + -1 (offset)
 			expectedMemory: []byte{
-				'?',        // resultNewoffset is after this
-				5, 0, 0, 0, // = expectedOffset
+				'?',                    // resultNewoffset is after this
+				5, 0, 0, 0, 0, 0, 0, 0, // = expectedOffset
 				'?',
 			},
 		},
@@ -1422,6 +2034,62 @@ func TestSnapshotPreview1_FdSeek(t *testing.T) {
 	}
 }
 
+// largeFile is a file whose offset is beyond 4GiB, which would be truncated if written as a uint32.
+type largeFile struct {
+	fs.File
+	offset int64
+}
+
+// Seek implements io.Seeker
+func (f *largeFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+		f.offset = offset
+	case io.SeekCurrent:
+		f.offset += offset
+	default:
+		return 0, errors.New("unsupported whence")
+	}
+	return f.offset, nil
+}
+
+func TestSnapshotPreview1_FdSeek_LargeOffset(t *testing.T) {
+	fd := uint32(3)              // arbitrary fd after 0, 1, and 2, that are stdin/out/err
+	resultNewoffset := uint32(1) // arbitrary offset in `ctx.Memory` for the new offset value
+	file, testFS := createFile(t, "test_path", []byte("wazero"))
+
+	sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
+		fd: {Path: "test_path", FS: testFS, File: &largeFile{File: file}},
+	})
+	require.NoError(t, err)
+
+	mod, _ := instantiateModule(testCtx, t, functionFdSeek, importFdSeek, sysCtx)
+	defer mod.Close(testCtx)
+
+	expectedMemory := []byte{
+		'?',                    // resultNewoffset is after this
+		1, 0, 0, 0, 1, 0, 0, 0, // = 4GiB + 1
+		'?',
+	}
+	maskMemory(t, testCtx, mod, len(expectedMemory))
+
+	errno := a.FdSeek(testCtx, mod, fd, 1<<32+1, io.SeekStart, resultNewoffset)
+	require.Zero(t, errno, ErrnoName(errno))
+
+	actual, ok := mod.Memory().Read(testCtx, 0, uint32(len(expectedMemory)))
+	require.True(t, ok)
+	require.Equal(t, expectedMemory, actual)
+
+	// fd_tell reads back the same offset.
+	maskMemory(t, testCtx, mod, len(expectedMemory))
+	errno = a.FdTell(testCtx, mod, fd, resultNewoffset)
+	require.Zero(t, errno, ErrnoName(errno))
+
+	actual, ok = mod.Memory().Read(testCtx, 0, uint32(len(expectedMemory)))
+	require.True(t, ok)
+	require.Equal(t, expectedMemory, actual)
+}
+
 func TestSnapshotPreview1_FdSeek_Errors(t *testing.T) {
 	validFD := uint32(3)                                         // arbitrary valid fd after 0, 1, and 2, that are stdin/out/err
 	file, testFS := createFile(t, "test_path", []byte("wazero")) // arbitrary valid file with non-empty contents
@@ -1460,6 +2128,12 @@ func TestSnapshotPreview1_FdSeek_Errors(t *testing.T) {
 			resultNewoffset: memorySize,
 			expectedErrno:   ErrnoFault,
 		},
+		{
+			name:            "resultNewoffset exceeds the maximum valid address by 1",
+			fd:              validFD,
+			resultNewoffset: memorySize - 8 + 1, // 8 bytes for the uint64 offset
+			expectedErrno:   ErrnoFault,
+		},
 	}
 
 	for _, tt := range tests {
@@ -1472,111 +2146,317 @@ func TestSnapshotPreview1_FdSeek_Errors(t *testing.T) {
 
 }
 
-// TestSnapshotPreview1_FdSync only tests it is stubbed for GrainLang per #271
 func TestSnapshotPreview1_FdSync(t *testing.T) {
-	mod, fn := instantiateModule(testCtx, t, functionFdSync, importFdSync, nil)
-	defer mod.Close(testCtx)
+	fd := uint32(3) // arbitrary fd after 0, 1, and 2, that are stdin/out/err
+
+	setup := func() (api.Module, api.Function, *syncTrackingFile) {
+		file, testFS := createFile(t, "test_path", []byte("wazero"))
+		syncFile := &syncTrackingFile{File: file}
+		sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
+			fd: {Path: "test_path", FS: testFS, File: syncFile},
+		})
+		require.NoError(t, err)
+		mod, fn := instantiateModule(testCtx, t, functionFdSync, importFdSync, sysCtx)
+		return mod, fn, syncFile
+	}
 
 	t.Run("wasi.FdSync", func(t *testing.T) {
-		errno := a.FdSync(testCtx, mod, 0)
-		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
+		mod, _, syncFile := setup()
+		defer mod.Close(testCtx)
+
+		errno := a.FdSync(testCtx, mod, fd)
+		require.Zero(t, errno, ErrnoName(errno))
+		require.Equal(t, 1, syncFile.syncCount)
 	})
 
 	t.Run(functionFdSync, func(t *testing.T) {
-		results, err := fn.Call(testCtx, 0)
+		mod, fn, syncFile := setup()
+		defer mod.Close(testCtx)
+
+		results, err := fn.Call(testCtx, uint64(fd))
 		require.NoError(t, err)
 		errno := Errno(results[0]) // results[0] is the errno
-		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
+		require.Zero(t, errno, ErrnoName(errno))
+		require.Equal(t, 1, syncFile.syncCount)
 	})
 }
 
-// TestSnapshotPreview1_FdTell only tests it is stubbed for GrainLang per #271
-func TestSnapshotPreview1_FdTell(t *testing.T) {
-	mod, fn := instantiateModule(testCtx, t, functionFdTell, importFdTell, nil)
-	defer mod.Close(testCtx)
+func TestSnapshotPreview1_FdSync_Errors(t *testing.T) {
+	fileFD, errFD, dirFD := uint32(3), uint32(4), uint32(5)
 
-	t.Run("wasi.FdTell", func(t *testing.T) {
-		errno := a.FdTell(testCtx, mod, 0, 0)
-		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
+	file, testFS := createFile(t, "test_path", []byte("wazero"))
+	sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
+		fileFD: {Path: "test_path", FS: testFS, File: file}, // fstest.MapFS files don't implement Sync
+		errFD:  {Path: "test_path", FS: testFS, File: &syncTrackingFile{File: file, syncErr: errors.New("sync failed")}},
+		dirFD:  {Path: ".", FS: testFS},
 	})
+	require.NoError(t, err)
 
-	t.Run(functionFdTell, func(t *testing.T) {
-		results, err := fn.Call(testCtx, 0, 0)
-		require.NoError(t, err)
-		errno := Errno(results[0]) // results[0] is the errno
-		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
-	})
+	mod, _ := instantiateModule(testCtx, t, functionFdSync, importFdSync, sysCtx)
+	defer mod.Close(testCtx)
+
+	tests := []struct {
+		name          string
+		fd            uint32
+		expectedErrno Errno
+	}{
+		{
+			name:          "invalid fd",
+			fd:            42, // arbitrary invalid fd
+			expectedErrno: ErrnoBadf,
+		},
+		{
+			name:          "sync error",
+			fd:            errFD,
+			expectedErrno: ErrnoIo,
+		},
+		{
+			name:          "file without Sync is a no-op",
+			fd:            fileFD,
+			expectedErrno: ErrnoSuccess,
+		},
+		{
+			name:          "pre-opened directory is a no-op",
+			fd:            dirFD,
+			expectedErrno: ErrnoSuccess,
+		},
+		{
+			name:          "stdout is a no-op",
+			fd:            fdStdout,
+			expectedErrno: ErrnoSuccess,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			errno := a.FdSync(testCtx, mod, tc.fd)
+			require.Equal(t, tc.expectedErrno, errno, ErrnoName(errno))
+
+			errno = a.FdDatasync(testCtx, mod, tc.fd)
+			require.Equal(t, tc.expectedErrno, errno, ErrnoName(errno))
+		})
+	}
 }
 
-func TestSnapshotPreview1_FdWrite(t *testing.T) {
-	fd := uint32(3)   // arbitrary fd after 0, 1, and 2, that are stdin/out/err
-	iovs := uint32(1) // arbitrary offset
+// syncTrackingFile is a fs.File that counts calls to Sync, as os.File would synchronize to disk.
+type syncTrackingFile struct {
+	fs.File
+	syncCount int
+	syncErr   error
+}
+
+// Sync implements the same method as documented on os.File
+func (f *syncTrackingFile) Sync() error {
+	f.syncCount++
+	return f.syncErr
+}
+
+func TestSnapshotPreview1_FdTell(t *testing.T) {
+	fd := uint32(3)                                              // arbitrary fd after 0, 1, and 2, that are stdin/out/err
+	iovs := uint32(1)                                            // arbitrary offset
+	resultOffset := uint32(32)                                   // arbitrary offset after the iovec and what it reads
+	file, testFS := createFile(t, "test_path", []byte("wazero")) // arbitrary non-empty contents
+
+	sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
+		fd: {Path: "test_path", FS: testFS, File: file},
+	})
+	require.NoError(t, err)
+
+	mod, fn := instantiateModule(testCtx, t, functionFdTell, importFdTell, sysCtx)
+	defer mod.Close(testCtx)
+
 	initialMemory := []byte{
 		'?',         // `iovs` is after this
 		18, 0, 0, 0, // = iovs[0].offset
 		4, 0, 0, 0, // = iovs[0].length
-		23, 0, 0, 0, // = iovs[1].offset
-		2, 0, 0, 0, // = iovs[1].length
-		'?',                // iovs[0].offset is after this
-		'w', 'a', 'z', 'e', // iovs[0].length bytes
-		'?',      // iovs[1].offset is after this
-		'r', 'o', // iovs[1].length bytes
-		'?',
 	}
-	iovsCount := uint32(2)   // The count of iovs
-	resultSize := uint32(26) // arbitrary offset
-	expectedMemory := append(
-		initialMemory,
-		6, 0, 0, 0, // sum(iovs[...].length) == length of "wazero"
+	expectedOffset := []byte{
+		'?',                    // resultOffset is after this
+		4, 0, 0, 0, 0, 0, 0, 0, // = 4 bytes read by FdRead
 		'?',
-	)
+	}
 
-	// TestSnapshotPreview1_FdWrite uses a matrix because setting up test files is complicated and has to be clean each time.
-	type fdWriteFn func(ctx context.Context, mod api.Module, fd, iovs, iovsCount, resultSize uint32) Errno
 	tests := []struct {
-		name    string
-		fdWrite func(api.Module, api.Function) fdWriteFn
+		name   string
+		fdTell func() Errno
 	}{
-		{"wasi.FdWrite", func(_ api.Module, _ api.Function) fdWriteFn {
-			return a.FdWrite
-		}},
-		{functionFdWrite, func(mod api.Module, fn api.Function) fdWriteFn {
-			return func(ctx context.Context, mod api.Module, fd, iovs, iovsCount, resultSize uint32) Errno {
-				results, err := fn.Call(ctx, uint64(fd), uint64(iovs), uint64(iovsCount), uint64(resultSize))
+		{
+			name: "wasi.FdTell",
+			fdTell: func() Errno {
+				return a.FdTell(testCtx, mod, fd, resultOffset)
+			},
+		},
+		{
+			name: functionFdTell,
+			fdTell: func() Errno {
+				results, err := fn.Call(testCtx, uint64(fd), uint64(resultOffset))
 				require.NoError(t, err)
-				return Errno(results[0])
-			}
-		}},
+				return Errno(results[0]) // results[0] is the errno
+			},
+		},
 	}
 
-	tmpDir := t.TempDir() // open before loop to ensure no locking problems.
-
 	for _, tt := range tests {
 		tc := tt
 		t.Run(tc.name, func(t *testing.T) {
-			// Create a fresh file to write the contents to
-			pathName := "test_path"
-			file, testFS := createWriteableFile(t, tmpDir, pathName, []byte{})
-			sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
-				fd: {Path: pathName, FS: testFS, File: file},
-			})
+			// Rewind the file, then read from it to advance the offset.
+			f, ok := sysCtx.FS().OpenedFile(fd)
+			require.True(t, ok)
+			_, err := f.File.(io.Seeker).Seek(0, io.SeekStart)
 			require.NoError(t, err)
 
-			mod, fn := instantiateModule(testCtx, t, functionFdWrite, importFdWrite, sysCtx)
-			defer mod.Close(testCtx)
+			maskMemory(t, testCtx, mod, int(resultOffset)+len(expectedOffset))
+			require.True(t, mod.Memory().Write(testCtx, 0, initialMemory))
 
-			maskMemory(t, testCtx, mod, len(expectedMemory))
-			ok := mod.Memory().Write(testCtx, 0, initialMemory)
-			require.True(t, ok)
+			errno := a.FdRead(testCtx, mod, fd, iovs, 1, 24)
+			require.Zero(t, errno, ErrnoName(errno))
 
-			errno := tc.fdWrite(mod, fn)(testCtx, mod, fd, iovs, iovsCount, resultSize)
+			errno = tc.fdTell()
 			require.Zero(t, errno, ErrnoName(errno))
 
-			actual, ok := mod.Memory().Read(testCtx, 0, uint32(len(expectedMemory)))
+			actual, ok := mod.Memory().Read(testCtx, resultOffset-1, uint32(len(expectedOffset)))
 			require.True(t, ok)
-			require.Equal(t, expectedMemory, actual)
+			require.Equal(t, expectedOffset, actual)
 
-			// Since we initialized this file, we know we can read it by path
+			// The offset of the file should not have moved.
+			errno = a.FdTell(testCtx, mod, fd, resultOffset)
+			require.Zero(t, errno, ErrnoName(errno))
+			offset, ok := mod.Memory().ReadUint64Le(testCtx, resultOffset)
+			require.True(t, ok)
+			require.Equal(t, uint64(4), offset)
+		})
+	}
+}
+
+func TestSnapshotPreview1_FdTell_Errors(t *testing.T) {
+	validFD, unseekableFD, dirFD := uint32(3), uint32(4), uint32(5)
+	file, testFS := createFile(t, "test_path", []byte("wazero")) // arbitrary valid file with non-empty contents
+
+	sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
+		validFD:      {Path: "test_path", FS: testFS, File: file},
+		unseekableFD: {Path: "test_path", FS: testFS, File: struct{ fs.File }{file}}, // hides io.Seeker
+		dirFD:        {Path: ".", FS: testFS},
+	})
+	require.NoError(t, err)
+
+	mod, _ := instantiateModule(testCtx, t, functionFdTell, importFdTell, sysCtx)
+	defer mod.Close(testCtx)
+
+	memorySize := mod.Memory().Size(testCtx)
+
+	tests := []struct {
+		name          string
+		fd            uint32
+		resultOffset  uint32
+		expectedErrno Errno
+	}{
+		{
+			name:          "invalid fd",
+			fd:            42, // arbitrary invalid fd
+			expectedErrno: ErrnoBadf,
+		},
+		{
+			name:          "pre-opened directory",
+			fd:            dirFD,
+			expectedErrno: ErrnoBadf,
+		},
+		{
+			name:          "stdin",
+			fd:            fdStdin,
+			expectedErrno: ErrnoSpipe,
+		},
+		{
+			name:          "unseekable file",
+			fd:            unseekableFD,
+			expectedErrno: ErrnoSpipe,
+		},
+		{
+			name:          "out-of-memory writing resultOffset",
+			fd:            validFD,
+			resultOffset:  memorySize - 7, // one byte short of uint64le
+			expectedErrno: ErrnoFault,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			errno := a.FdTell(testCtx, mod, tc.fd, tc.resultOffset)
+			require.Equal(t, tc.expectedErrno, errno, ErrnoName(errno))
+		})
+	}
+}
+
+func TestSnapshotPreview1_FdWrite(t *testing.T) {
+	fd := uint32(3)   // arbitrary fd after 0, 1, and 2, that are stdin/out/err
+	iovs := uint32(1) // arbitrary offset
+	initialMemory := []byte{
+		'?',         // `iovs` is after this
+		18, 0, 0, 0, // = iovs[0].offset
+		4, 0, 0, 0, // = iovs[0].length
+		23, 0, 0, 0, // = iovs[1].offset
+		2, 0, 0, 0, // = iovs[1].length
+		'?',                // iovs[0].offset is after this
+		'w', 'a', 'z', 'e', // iovs[0].length bytes
+		'?',      // iovs[1].offset is after this
+		'r', 'o', // iovs[1].length bytes
+		'?',
+	}
+	iovsCount := uint32(2)   // The count of iovs
+	resultSize := uint32(26) // arbitrary offset
+	expectedMemory := append(
+		initialMemory,
+		6, 0, 0, 0, // sum(iovs[...].length) == length of "wazero"
+		'?',
+	)
+
+	// TestSnapshotPreview1_FdWrite uses a matrix because setting up test files is complicated and has to be clean each time.
+	type fdWriteFn func(ctx context.Context, mod api.Module, fd, iovs, iovsCount, resultSize uint32) Errno
+	tests := []struct {
+		name    string
+		fdWrite func(api.Module, api.Function) fdWriteFn
+	}{
+		{"wasi.FdWrite", func(_ api.Module, _ api.Function) fdWriteFn {
+			return a.FdWrite
+		}},
+		{functionFdWrite, func(mod api.Module, fn api.Function) fdWriteFn {
+			return func(ctx context.Context, mod api.Module, fd, iovs, iovsCount, resultSize uint32) Errno {
+				results, err := fn.Call(ctx, uint64(fd), uint64(iovs), uint64(iovsCount), uint64(resultSize))
+				require.NoError(t, err)
+				return Errno(results[0])
+			}
+		}},
+	}
+
+	tmpDir := t.TempDir() // open before loop to ensure no locking problems.
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			// Create a fresh file to write the contents to
+			pathName := "test_path"
+			file, testFS := createWriteableFile(t, tmpDir, pathName, []byte{})
+			sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
+				fd: {Path: pathName, FS: testFS, File: file},
+			})
+			require.NoError(t, err)
+
+			mod, fn := instantiateModule(testCtx, t, functionFdWrite, importFdWrite, sysCtx)
+			defer mod.Close(testCtx)
+
+			maskMemory(t, testCtx, mod, len(expectedMemory))
+			ok := mod.Memory().Write(testCtx, 0, initialMemory)
+			require.True(t, ok)
+
+			errno := tc.fdWrite(mod, fn)(testCtx, mod, fd, iovs, iovsCount, resultSize)
+			require.Zero(t, errno, ErrnoName(errno))
+
+			actual, ok := mod.Memory().Read(testCtx, 0, uint32(len(expectedMemory)))
+			require.True(t, ok)
+			require.Equal(t, expectedMemory, actual)
+
+			// Since we initialized this file, we know we can read it by path
 			buf, err := os.ReadFile(path.Join(tmpDir, pathName))
 			require.NoError(t, err)
 
@@ -1585,6 +2465,166 @@ func TestSnapshotPreview1_FdWrite(t *testing.T) {
 	}
 }
 
+// recordingWriter records the bytes passed to each Write call.
+type recordingWriter struct {
+	writes [][]byte
+}
+
+// Write implements io.Writer
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+func TestSnapshotPreview1_FdWrite_StdioWriteBoundaries(t *testing.T) {
+	iovs := uint32(1) // arbitrary offset
+	initialMemory := []byte{
+		'?',         // `iovs` is after this
+		18, 0, 0, 0, // = iovs[0].offset
+		4, 0, 0, 0, // = iovs[0].length
+		23, 0, 0, 0, // = iovs[1].offset
+		2, 0, 0, 0, // = iovs[1].length
+		'?',                // iovs[0].offset is after this
+		'w', 'a', 'z', 'e', // iovs[0].length bytes
+		'?',      // iovs[1].offset is after this
+		'r', 'o', // iovs[1].length bytes
+		'?',
+	}
+	iovsCount := uint32(2)   // The count of iovs
+	resultSize := uint32(26) // arbitrary offset
+
+	tests := []struct {
+		name            string
+		fd              uint32
+		writeBoundaries bool
+		expectedWrites  [][]byte
+	}{
+		{name: "stdout", fd: fdStdout, expectedWrites: [][]byte{[]byte("waze"), []byte("ro")}},
+		{name: "stderr", fd: fdStderr, expectedWrites: [][]byte{[]byte("waze"), []byte("ro")}},
+		{name: "stdout write boundaries", fd: fdStdout, writeBoundaries: true, expectedWrites: [][]byte{[]byte("wazero")}},
+		{name: "stderr write boundaries", fd: fdStderr, writeBoundaries: true, expectedWrites: [][]byte{[]byte("wazero")}},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			stdout, stderr := &recordingWriter{}, &recordingWriter{}
+			sysCtx, err := internalsys.NewContext(math.MaxUint32, nil, nil, nil, stdout, stderr, tc.writeBoundaries, false, nil,
+				nil, 0, nil, 0, nil, nil)
+			require.NoError(t, err)
+
+			mod, fn := instantiateModule(testCtx, t, functionFdWrite, importFdWrite, sysCtx)
+			defer mod.Close(testCtx)
+
+			ok := mod.Memory().Write(testCtx, 0, initialMemory)
+			require.True(t, ok)
+
+			results, err := fn.Call(testCtx, uint64(tc.fd), uint64(iovs), uint64(iovsCount), uint64(resultSize))
+			require.NoError(t, err)
+			require.Equal(t, ErrnoSuccess, Errno(results[0]))
+
+			nwritten, ok := mod.Memory().ReadUint32Le(testCtx, resultSize)
+			require.True(t, ok)
+			require.Equal(t, uint32(6), nwritten)
+
+			writer, other := stdout, stderr
+			if tc.fd == fdStderr {
+				writer, other = stderr, stdout
+			}
+			require.Equal(t, tc.expectedWrites, writer.writes)
+			require.Zero(t, len(other.writes))
+		})
+	}
+}
+
+// TestSnapshotPreview1_FdWrite_Stdout calls FdWrite directly with a wazero.NewFakeModule, to capture stdout without
+// instantiating WebAssembly.
+func TestSnapshotPreview1_FdWrite_Stdout(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	mod, err := wazero.NewFakeModule(wazero.NewModuleConfig().WithStdout(stdout), 1)
+	require.NoError(t, err)
+	defer mod.Close(testCtx)
+
+	iovs, resultSize := uint32(0), uint32(32) // arbitrary offsets
+	require.True(t, mod.Memory().Write(testCtx, iovs, []byte{
+		16, 0, 0, 0, // = iovs[0].offset
+		6, 0, 0, 0, // = iovs[0].length
+	}))
+	require.True(t, mod.Memory().Write(testCtx, 16, []byte("wazero")))
+
+	errno := a.FdWrite(testCtx, mod, fdStdout, iovs, 1, resultSize)
+	require.Zero(t, errno, ErrnoName(errno))
+	require.Equal(t, "wazero", stdout.String())
+
+	nwritten, ok := mod.Memory().ReadUint32Le(testCtx, resultSize)
+	require.True(t, ok)
+	require.Equal(t, uint32(6), nwritten)
+}
+
+// TestSnapshotPreview1_FdWrite_WriterErrors ensures a guest can tell a closed pipe, ex. to `head`, from other errors.
+func TestSnapshotPreview1_FdWrite_WriterErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		stdout        func(t *testing.T) io.Writer
+		expectedErrno Errno
+	}{
+		{
+			name: "os.Pipe with closed reader",
+			stdout: func(t *testing.T) io.Writer {
+				r, w, err := os.Pipe()
+				require.NoError(t, err)
+				require.NoError(t, r.Close())
+				return w
+			},
+			expectedErrno: ErrnoPipe,
+		},
+		{
+			name: "io.Pipe with closed reader",
+			stdout: func(t *testing.T) io.Writer {
+				r, w := io.Pipe()
+				require.NoError(t, r.Close())
+				return w
+			},
+			expectedErrno: ErrnoPipe,
+		},
+		{
+			name: "other error",
+			stdout: func(t *testing.T) io.Writer {
+				return &errWriter{err: errors.New("disk full")}
+			},
+			expectedErrno: ErrnoIo,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			mod, err := wazero.NewFakeModule(wazero.NewModuleConfig().WithStdout(tc.stdout(t)), 1)
+			require.NoError(t, err)
+			defer mod.Close(testCtx)
+
+			iovs, resultSize := uint32(0), uint32(32) // arbitrary offsets
+			require.True(t, mod.Memory().Write(testCtx, iovs, []byte{
+				16, 0, 0, 0, // = iovs[0].offset
+				6, 0, 0, 0, // = iovs[0].length
+			}))
+			require.True(t, mod.Memory().Write(testCtx, 16, []byte("wazero")))
+
+			errno := a.FdWrite(testCtx, mod, fdStdout, iovs, 1, resultSize)
+			require.Equal(t, tc.expectedErrno, errno, ErrnoName(errno))
+		})
+	}
+}
+
+// errWriter is an io.Writer which always returns err.
+type errWriter struct {
+	err error
+}
+
+func (w *errWriter) Write([]byte) (int, error) {
+	return 0, w.err
+}
+
 func TestSnapshotPreview1_FdWrite_Errors(t *testing.T) {
 	validFD := uint32(3) // arbitrary valid fd after 0, 1, and 2, that are stdin/out/err
 
@@ -1663,24 +2703,187 @@ func TestSnapshotPreview1_FdWrite_Errors(t *testing.T) {
 	}
 }
 
-// TestSnapshotPreview1_PathCreateDirectory only tests it is stubbed for GrainLang per #271
 func TestSnapshotPreview1_PathCreateDirectory(t *testing.T) {
-	mod, fn := instantiateModule(testCtx, t, functionPathCreateDirectory, importPathCreateDirectory, nil)
-	defer mod.Close(testCtx)
+	workdirFD := uint32(3) // arbitrary fd after 0, 1, and 2, that are stdin/out/err
+	pathName := "wazero"
+
+	setup := func() (api.Module, api.Function, writableMapFS) {
+		testFS := writableMapFS{fstest.MapFS{}}
+		sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
+			workdirFD: {Path: ".", FS: testFS},
+		})
+		require.NoError(t, err)
+		mod, fn := instantiateModule(testCtx, t, functionPathCreateDirectory, importPathCreateDirectory, sysCtx)
+		ok := mod.Memory().Write(testCtx, 1, []byte(pathName))
+		require.True(t, ok)
+		return mod, fn, testFS
+	}
+
+	verify := func(errno Errno, testFS writableMapFS) {
+		require.Zero(t, errno, ErrnoName(errno))
+
+		// verify the directory was actually created
+		stat, err := fs.Stat(testFS, pathName)
+		require.NoError(t, err)
+		require.True(t, stat.IsDir())
+	}
 
 	t.Run("wasi.PathCreateDirectory", func(t *testing.T) {
-		errno := a.PathCreateDirectory(testCtx, mod, 0, 0, 0)
-		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
+		mod, _, testFS := setup()
+		defer mod.Close(testCtx)
+
+		errno := a.PathCreateDirectory(testCtx, mod, workdirFD, 1, uint32(len(pathName)))
+		verify(errno, testFS)
 	})
 
 	t.Run(functionPathCreateDirectory, func(t *testing.T) {
-		results, err := fn.Call(testCtx, 0, 0, 0)
+		mod, fn, testFS := setup()
+		defer mod.Close(testCtx)
+
+		results, err := fn.Call(testCtx, uint64(workdirFD), 1, uint64(len(pathName)))
 		require.NoError(t, err)
 		errno := Errno(results[0]) // results[0] is the errno
-		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
+		verify(errno, testFS)
 	})
 }
 
+func TestSnapshotPreview1_PathCreateDirectory_Errors(t *testing.T) {
+	validFD := uint32(3)    // arbitrary valid fd after 0, 1, and 2, that are stdin/out/err
+	readOnlyFD := uint32(4) // a mount whose fs.FS cannot create directories
+	rootFD := uint32(5)     // a mount at "/", where cleaning alone cannot detect an escape
+	testFS := writableMapFS{fstest.MapFS{"existing": &fstest.MapFile{Mode: fs.ModeDir}}}
+
+	sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
+		validFD:    {Path: ".", FS: testFS},
+		readOnlyFD: {Path: ".", FS: fstest.MapFS{}},
+		rootFD:     {Path: "/", FS: testFS},
+	})
+	require.NoError(t, err)
+
+	mod, _ := instantiateModule(testCtx, t, functionPathCreateDirectory, importPathCreateDirectory, sysCtx)
+	defer mod.Close(testCtx)
+
+	writePath := func(pathName string) (uint32, uint32) {
+		ok := mod.Memory().Write(testCtx, 0, []byte(pathName))
+		require.True(t, ok)
+		return 0, uint32(len(pathName))
+	}
+
+	tests := []struct {
+		name          string
+		fd            uint32
+		pathName      string
+		path, pathLen uint32 // used when pathName is empty
+		expectedErrno Errno
+	}{
+		{
+			name:          "invalid fd",
+			fd:            42, // arbitrary invalid fd
+			pathName:      "wazero",
+			expectedErrno: ErrnoBadf,
+		},
+		{
+			name:          "out-of-memory reading path",
+			fd:            validFD,
+			path:          mod.Memory().Size(testCtx),
+			pathLen:       1,
+			expectedErrno: ErrnoFault,
+		},
+		{
+			name:          "out-of-memory reading pathLen",
+			fd:            validFD,
+			path:          0,
+			pathLen:       mod.Memory().Size(testCtx) + 1,
+			expectedErrno: ErrnoFault,
+		},
+		{
+			name:          "path escapes the directory",
+			fd:            validFD,
+			pathName:      "../wazero",
+			expectedErrno: ErrnoNotcapable,
+		},
+		{
+			name:          "path escapes the root directory",
+			fd:            rootFD,
+			pathName:      "../wazero",
+			expectedErrno: ErrnoNotcapable,
+		},
+		{
+			name:          "path escapes the directory after a subdirectory",
+			fd:            validFD,
+			pathName:      "existing/../../wazero",
+			expectedErrno: ErrnoNotcapable,
+		},
+		{
+			name:          "read-only file system",
+			fd:            readOnlyFD,
+			pathName:      "wazero",
+			expectedErrno: ErrnoRofs,
+		},
+		{
+			name:          "directory exists",
+			fd:            validFD,
+			pathName:      "existing",
+			expectedErrno: ErrnoExist,
+		},
+		{
+			name:          "parent does not exist",
+			fd:            validFD,
+			pathName:      "missing/wazero",
+			expectedErrno: ErrnoNoent,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			path, pathLen := tc.path, tc.pathLen
+			if tc.pathName != "" {
+				path, pathLen = writePath(tc.pathName)
+			}
+			errno := a.PathCreateDirectory(testCtx, mod, tc.fd, path, pathLen)
+			require.Equal(t, tc.expectedErrno, errno, ErrnoName(errno))
+		})
+	}
+}
+
+// writableMapFS is a fstest.MapFS which implements sys.MkdirFS and sys.RmdirFS, for testing functions that create or
+// remove directories.
+type writableMapFS struct {
+	fstest.MapFS
+}
+
+// Mkdir implements sys.MkdirFS
+func (m writableMapFS) Mkdir(name string, perm fs.FileMode) error {
+	if _, err := fs.Stat(m.MapFS, name); err == nil {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrExist}
+	}
+	if parent := path.Dir(name); parent != "." {
+		if stat, err := fs.Stat(m.MapFS, parent); err != nil || !stat.IsDir() {
+			return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrNotExist}
+		}
+	}
+	m.MapFS[name] = &fstest.MapFile{Mode: fs.ModeDir | perm}
+	return nil
+}
+
+// Rmdir implements sys.RmdirFS
+func (m writableMapFS) Rmdir(name string) error {
+	stat, err := fs.Stat(m.MapFS, name)
+	if err != nil {
+		return err
+	} else if !stat.IsDir() {
+		return &fs.PathError{Op: "rmdir", Path: name, Err: syscall.ENOTDIR}
+	}
+	if entries, err := fs.ReadDir(m.MapFS, name); err != nil {
+		return err
+	} else if len(entries) > 0 {
+		return &fs.PathError{Op: "rmdir", Path: name, Err: syscall.ENOTEMPTY}
+	}
+	delete(m.MapFS, name)
+	return nil
+}
+
 // TestSnapshotPreview1_PathFilestatGet only tests it is stubbed for GrainLang per #271
 func TestSnapshotPreview1_PathFilestatGet(t *testing.T) {
 	mod, fn := instantiateModule(testCtx, t, functionPathFilestatGet, importPathFilestatGet, nil)
@@ -1699,21 +2902,76 @@ func TestSnapshotPreview1_PathFilestatGet(t *testing.T) {
 	})
 }
 
-// TestSnapshotPreview1_PathFilestatSetTimes only tests it is stubbed for GrainLang per #271
 func TestSnapshotPreview1_PathFilestatSetTimes(t *testing.T) {
-	mod, fn := instantiateModule(testCtx, t, functionPathFilestatSetTimes, importPathFilestatSetTimes, nil)
+	workdirFD, readOnlyFD := uint32(3), uint32(4) // arbitrary fds after 0, 1, and 2, that are stdin/out/err
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "file"), []byte("wazero"), 0o600))
+
+	sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
+		workdirFD:  {Path: ".", FS: wazero.NewWritableDirFS(tmpDir)},
+		readOnlyFD: {Path: ".", FS: fstest.MapFS{"file": &fstest.MapFile{Data: []byte("wazero")}}},
+	})
+	require.NoError(t, err)
+
+	mod, fn := instantiateModule(testCtx, t, functionPathFilestatSetTimes, importPathFilestatSetTimes, sysCtx)
 	defer mod.Close(testCtx)
 
+	pathName := "file"
+	ok := mod.Memory().Write(testCtx, 0, []byte(pathName))
+	require.True(t, ok)
+	atim := uint64(platform.FakeEpochNanos)
+	mtim := uint64(platform.FakeEpochNanos + 123456789)
+
 	t.Run("wasi.PathFilestatSetTimes", func(t *testing.T) {
-		errno := a.PathFilestatSetTimes(testCtx, mod, 0, 0, 0, 0, 0, 0, 0)
-		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
+		errno := a.PathFilestatSetTimes(testCtx, mod, workdirFD, 0, 0, uint32(len(pathName)), atim, mtim,
+			fstflagAtim|fstflagMtim)
+		require.Zero(t, errno, ErrnoName(errno))
+
+		stat, err := os.Stat(path.Join(tmpDir, pathName))
+		require.NoError(t, err)
+		require.Equal(t, int64(mtim), stat.ModTime().UnixNano())
 	})
 
 	t.Run(functionPathFilestatSetTimes, func(t *testing.T) {
-		results, err := fn.Call(testCtx, 0, 0, 0, 0, 0, 0, 0)
+		// Only set the access time, which leaves the modification time unchanged.
+		results, err := fn.Call(testCtx, uint64(workdirFD), 0, 0, uint64(len(pathName)), atim, 0, fstflagAtimNow)
 		require.NoError(t, err)
 		errno := Errno(results[0]) // results[0] is the errno
-		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
+		require.Zero(t, errno, ErrnoName(errno))
+
+		stat, err := os.Stat(path.Join(tmpDir, pathName))
+		require.NoError(t, err)
+		require.Equal(t, int64(mtim), stat.ModTime().UnixNano())
+	})
+
+	tests := []struct {
+		name              string
+		fd, path, pathLen uint32
+		fstFlags          uint32
+		expectedErrno     Errno
+	}{
+		{name: "invalid fd", fd: 42, pathLen: uint32(len(pathName)), fstFlags: fstflagMtimNow, expectedErrno: ErrnoBadf},
+		{name: "read-only file system", fd: readOnlyFD, pathLen: uint32(len(pathName)), fstFlags: fstflagMtimNow, expectedErrno: ErrnoRofs},
+		{name: "mtim and mtim_now", fd: workdirFD, pathLen: uint32(len(pathName)), fstFlags: fstflagMtim | fstflagMtimNow, expectedErrno: ErrnoInval},
+		{name: "path doesn't exist", fd: workdirFD, pathLen: 3 /* "fil" */, fstFlags: fstflagMtimNow, expectedErrno: ErrnoNoent},
+		{name: "path exceeds memory", fd: workdirFD, path: mod.Memory().Size(testCtx), pathLen: 1, fstFlags: fstflagMtimNow, expectedErrno: ErrnoFault},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			errno := a.PathFilestatSetTimes(testCtx, mod, tc.fd, 0, tc.path, tc.pathLen, 0, 0, tc.fstFlags)
+			require.Equal(t, tc.expectedErrno, errno, ErrnoName(errno))
+		})
+	}
+
+	t.Run("rejects paths that escape the directory", func(t *testing.T) {
+		escape := "../" + pathName
+		ok := mod.Memory().Write(testCtx, 0, []byte(escape))
+		require.True(t, ok)
+
+		errno := a.PathFilestatSetTimes(testCtx, mod, workdirFD, 0, 0, uint32(len(escape)), 0, 0, fstflagMtimNow)
+		require.Equal(t, ErrnoNotcapable, errno, ErrnoName(errno))
 	})
 }
 
@@ -1766,7 +3024,7 @@ func TestSnapshotPreview1_PathOpen(t *testing.T) {
 			pathPtr:            1,
 			pathLen:            uint32(len(pathName)),
 			oflags:             0,
-			fsRightsBase:       1, // rights are ignored per https://github.com/WebAssembly/WASI/issues/469#issuecomment-1045251844
+			fsRightsBase:       1, // arbitrary, as rights are only checked when the opened file is used
 			fsRightsInheriting: 2,
 			fdflags:            0,
 			resultOpenedFd:     uint32(len(initialMemory) + 1),
@@ -1834,13 +3092,244 @@ func TestSnapshotPreview1_PathOpen(t *testing.T) {
 		testFS := fstest.MapFS{pathName: &fstest.MapFile{Mode: os.ModeDir}}
 		ctx, closer, err := experimental.WithFS(testCtx, testFS)
 		require.NoError(t, err)
-		defer closer.Close(ctx)
+		defer closer.Close(ctx)
+
+		errno := a.PathOpen(ctx, mod, workdirFD, args.dirflags, args.pathPtr, args.pathLen, args.oflags,
+			args.fsRightsBase, args.fsRightsInheriting, args.fdflags, args.resultOpenedFd)
+		require.Zero(t, errno, ErrnoName(errno))
+
+		verify(ctx, errno, mod, pathName, expectedMemory, expectedFD)
+	})
+}
+
+func TestSnapshotPreview1_PathOpen_WritableDirFS(t *testing.T) {
+	workdirFD := uint32(3) // arbitrary fd after 0, 1, and 2, that are stdin/out/err
+	tmpDir := t.TempDir()
+
+	sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
+		workdirFD: {Path: ".", FS: wazero.NewWritableDirFS(tmpDir)},
+	})
+	require.NoError(t, err)
+	mod, _ := instantiateModule(testCtx, t, functionPathOpen, importPathOpen, sysCtx)
+	defer mod.Close(testCtx)
+
+	pathName := "wazero.txt"
+	initialMemory := []byte(pathName)
+	resultOpenedFd := uint32(len(initialMemory))
+	ok := mod.Memory().Write(testCtx, 0, initialMemory)
+	require.True(t, ok)
+
+	t.Run("creates and writes a file", func(t *testing.T) {
+		errno := a.PathOpen(testCtx, mod, workdirFD, 0, 0, uint32(len(pathName)), oflagCreat|oflagTrunc,
+			rightFdRead|rightFdWrite, 0, 0, resultOpenedFd)
+		require.Zero(t, errno, ErrnoName(errno))
+
+		fd, ok := mod.Memory().ReadUint32Le(testCtx, resultOpenedFd)
+		require.True(t, ok)
+
+		// Write "wazero" to the opened file, using iovs after the result.
+		iovs := resultOpenedFd + 4
+		data := iovs + 8
+		resultSize := data + 6
+		require.True(t, mod.Memory().WriteUint32Le(testCtx, iovs, data))
+		require.True(t, mod.Memory().WriteUint32Le(testCtx, iovs+4, 6))
+		require.True(t, mod.Memory().Write(testCtx, data, []byte("wazero")))
+		errno = a.FdWrite(testCtx, mod, fd, iovs, 1, resultSize)
+		require.Zero(t, errno, ErrnoName(errno))
+		require.Zero(t, a.FdClose(testCtx, mod, fd))
+
+		// The file should exist on the host.
+		b, err := os.ReadFile(path.Join(tmpDir, pathName))
+		require.NoError(t, err)
+		require.Equal(t, "wazero", string(b))
+	})
+
+	t.Run("rejects paths that escape the directory", func(t *testing.T) {
+		escape := "../" + pathName
+		ok := mod.Memory().Write(testCtx, 0, []byte(escape))
+		require.True(t, ok)
+
+		errno := a.PathOpen(testCtx, mod, workdirFD, 0, 0, uint32(len(escape)), oflagCreat,
+			rightFdRead|rightFdWrite, 0, 0, resultOpenedFd)
+		require.Equal(t, ErrnoNotcapable, errno, ErrnoName(errno))
+
+		_, err := os.Stat(path.Join(path.Dir(tmpDir), pathName))
+		require.True(t, errors.Is(err, fs.ErrNotExist))
+	})
+}
+
+func TestSnapshotPreview1_PathOpen_Oflags(t *testing.T) {
+	workdirFD := uint32(3) // arbitrary fd after 0, 1, and 2, that are stdin/out/err
+	resultOpenedFd := uint32(16)
+
+	readOnlyFS := func(t *testing.T) fs.FS {
+		return fstest.MapFS{
+			"file": &fstest.MapFile{Data: []byte("wazero")},
+			"dir":  &fstest.MapFile{Mode: os.ModeDir},
+		}
+	}
+	writableFS := func(t *testing.T) fs.FS {
+		tmpDir := t.TempDir()
+		require.NoError(t, os.WriteFile(path.Join(tmpDir, "file"), []byte("wazero"), 0o600))
+		require.NoError(t, os.Mkdir(path.Join(tmpDir, "dir"), 0o700))
+		return wazero.NewWritableDirFS(tmpDir)
+	}
+
+	tests := []struct {
+		name          string
+		fs            func(t *testing.T) fs.FS
+		pathName      string
+		oflags        uint32
+		fsRightsBase  uint64
+		expectedErrno Errno
+		expectedSize  int64 // of the opened file, when expectedErrno is ErrnoSuccess and it isn't a directory
+	}{
+		{name: "read-only: file", fs: readOnlyFS, pathName: "file", expectedSize: 6},
+		{name: "read-only: missing", fs: readOnlyFS, pathName: "missing", expectedErrno: ErrnoNoent},
+		{name: "read-only: O_DIRECTORY dir", fs: readOnlyFS, pathName: "dir", oflags: oflagDirectory},
+		{name: "read-only: O_DIRECTORY file", fs: readOnlyFS, pathName: "file", oflags: oflagDirectory, expectedErrno: ErrnoNotdir},
+		{name: "read-only: O_CREAT|O_DIRECTORY", fs: readOnlyFS, pathName: "dir", oflags: oflagCreat | oflagDirectory, expectedErrno: ErrnoInval},
+		{name: "read-only: O_CREAT existing", fs: readOnlyFS, pathName: "file", oflags: oflagCreat, expectedSize: 6},
+		{name: "read-only: O_CREAT missing", fs: readOnlyFS, pathName: "missing", oflags: oflagCreat, expectedErrno: ErrnoRofs},
+		{name: "read-only: O_CREAT|O_EXCL existing", fs: readOnlyFS, pathName: "file", oflags: oflagCreat | oflagExcl, expectedErrno: ErrnoExist},
+		{name: "read-only: O_TRUNC", fs: readOnlyFS, pathName: "file", oflags: oflagTrunc, expectedErrno: ErrnoRofs},
+		{name: "read-only: write rights", fs: readOnlyFS, pathName: "file", fsRightsBase: rightFdWrite, expectedErrno: ErrnoRofs},
+		{name: "writable: file", fs: writableFS, pathName: "file", fsRightsBase: rightFdRead | rightFdWrite, expectedSize: 6},
+		{name: "writable: missing", fs: writableFS, pathName: "missing", fsRightsBase: rightFdWrite, expectedErrno: ErrnoNoent},
+		{name: "writable: O_DIRECTORY dir", fs: writableFS, pathName: "dir", oflags: oflagDirectory},
+		{name: "writable: O_DIRECTORY file", fs: writableFS, pathName: "file", oflags: oflagDirectory, expectedErrno: ErrnoNotdir},
+		{name: "writable: O_CREAT|O_DIRECTORY", fs: writableFS, pathName: "missing", oflags: oflagCreat | oflagDirectory, expectedErrno: ErrnoInval},
+		{name: "writable: O_CREAT existing", fs: writableFS, pathName: "file", oflags: oflagCreat, fsRightsBase: rightFdWrite, expectedSize: 6},
+		{name: "writable: O_CREAT missing", fs: writableFS, pathName: "missing", oflags: oflagCreat, fsRightsBase: rightFdWrite},
+		{name: "writable: O_CREAT|O_EXCL existing", fs: writableFS, pathName: "file", oflags: oflagCreat | oflagExcl, fsRightsBase: rightFdWrite, expectedErrno: ErrnoExist},
+		{name: "writable: O_CREAT|O_EXCL missing", fs: writableFS, pathName: "missing", oflags: oflagCreat | oflagExcl, fsRightsBase: rightFdWrite},
+		{name: "writable: O_TRUNC", fs: writableFS, pathName: "file", oflags: oflagTrunc, fsRightsBase: rightFdWrite},
+		{name: "writable: write rights dir", fs: writableFS, pathName: "dir", fsRightsBase: rightFdWrite, expectedErrno: ErrnoIsdir},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
+				workdirFD: {Path: ".", FS: tc.fs(t)},
+			})
+			require.NoError(t, err)
+
+			mod, _ := instantiateModule(testCtx, t, functionPathOpen, importPathOpen, sysCtx)
+			defer mod.Close(testCtx)
+			require.True(t, mod.Memory().Write(testCtx, 0, []byte(tc.pathName)))
+
+			errno := a.PathOpen(testCtx, mod, workdirFD, 0, 0, uint32(len(tc.pathName)), tc.oflags, tc.fsRightsBase, 0, 0, resultOpenedFd)
+			require.Equal(t, tc.expectedErrno, errno, ErrnoName(errno))
+			if errno != ErrnoSuccess {
+				_, ok := sysCtx.FS().OpenedFile(workdirFD + 1)
+				require.False(t, ok) // no file descriptor was allocated
+				return
+			}
+
+			// The new file descriptor is the next one after the directory and tracked under the opened path.
+			fd, ok := mod.Memory().ReadUint32Le(testCtx, resultOpenedFd)
+			require.True(t, ok)
+			require.Equal(t, workdirFD+1, fd)
+
+			f, ok := sysCtx.FS().OpenedFile(fd)
+			require.True(t, ok)
+			require.Equal(t, tc.pathName, f.Path)
+
+			st, err := f.File.Stat()
+			require.NoError(t, err)
+			if tc.pathName == "dir" {
+				require.True(t, st.IsDir())
+			} else {
+				require.Equal(t, tc.expectedSize, st.Size())
+			}
+		})
+	}
+}
+
+func TestSnapshotPreview1_PathOpen_Rights(t *testing.T) {
+	workdirFD := uint32(3) // arbitrary fd after 0, 1, and 2, that are stdin/out/err
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "file"), []byte("wazero"), 0o600))
+	require.NoError(t, os.Mkdir(path.Join(tmpDir, "dir"), 0o700))
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "dir", "file"), []byte("wazero"), 0o600))
+
+	sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
+		workdirFD: {Path: ".", FS: wazero.NewWritableDirFS(tmpDir)},
+	})
+	require.NoError(t, err)
+	mod, _ := instantiateModule(testCtx, t, functionPathOpen, importPathOpen, sysCtx)
+	defer mod.Close(testCtx)
+
+	resultOpenedFd := uint32(16)
+	pathOpen := func(t *testing.T, fd uint32, pathName string, oflags uint32, fsRightsBase, fsRightsInheriting uint64) uint32 {
+		require.True(t, mod.Memory().Write(testCtx, 0, []byte(pathName)))
+		errno := a.PathOpen(testCtx, mod, fd, 0, 0, uint32(len(pathName)), oflags, fsRightsBase, fsRightsInheriting, 0, resultOpenedFd)
+		require.Zero(t, errno, ErrnoName(errno))
+		newFD, ok := mod.Memory().ReadUint32Le(testCtx, resultOpenedFd)
+		require.True(t, ok)
+		return newFD
+	}
+
+	// Write "wazero" to fd, using iovs after the result.
+	fdWrite := func(fd uint32) Errno {
+		iovs := resultOpenedFd + 4
+		data := iovs + 8
+		resultSize := data + 6
+		require.True(t, mod.Memory().WriteUint32Le(testCtx, iovs, data))
+		require.True(t, mod.Memory().WriteUint32Le(testCtx, iovs+4, 6))
+		require.True(t, mod.Memory().Write(testCtx, data, []byte("wazero")))
+		return a.FdWrite(testCtx, mod, fd, iovs, 1, resultSize)
+	}
+
+	t.Run("fd_write read-only file", func(t *testing.T) {
+		fd := pathOpen(t, workdirFD, "file", 0, rightFdRead, 0)
+		defer a.FdClose(testCtx, mod, fd)
+
+		errno := fdWrite(fd)
+		require.Equal(t, ErrnoNotcapable, errno, ErrnoName(errno))
+
+		// The file is unchanged.
+		b, err := os.ReadFile(path.Join(tmpDir, "file"))
+		require.NoError(t, err)
+		require.Equal(t, "wazero", string(b))
+	})
 
-		errno := a.PathOpen(ctx, mod, workdirFD, args.dirflags, args.pathPtr, args.pathLen, args.oflags,
-			args.fsRightsBase, args.fsRightsInheriting, args.fdflags, args.resultOpenedFd)
+	t.Run("fd_seek without the right to seek", func(t *testing.T) {
+		fd := pathOpen(t, workdirFD, "file", 0, rightFdRead|rightFdWrite, 0)
+		defer a.FdClose(testCtx, mod, fd)
+
+		errno := a.FdSeek(testCtx, mod, fd, 0, io.SeekStart, 0)
+		require.Equal(t, ErrnoNotcapable, errno, ErrnoName(errno))
+		errno = a.FdTell(testCtx, mod, fd, 0)
+		require.Equal(t, ErrnoNotcapable, errno, ErrnoName(errno))
+
+		errno = fdWrite(fd)
 		require.Zero(t, errno, ErrnoName(errno))
+	})
 
-		verify(ctx, errno, mod, pathName, expectedMemory, expectedFD)
+	t.Run("rights are limited to the inheriting rights of the directory", func(t *testing.T) {
+		dirFD := pathOpen(t, workdirFD, "dir", oflagDirectory, rightPathOpen, rightFdRead)
+		defer a.FdClose(testCtx, mod, dirFD)
+
+		fd := pathOpen(t, dirFD, "file", 0, rightFdRead|rightFdWrite, rightFdRead|rightFdWrite)
+		defer a.FdClose(testCtx, mod, fd)
+
+		f, ok := sysCtx.FS().OpenedFile(fd)
+		require.True(t, ok)
+		require.Equal(t, &internalsys.Rights{Base: rightFdRead, Inheriting: rightFdRead}, f.Rights)
+
+		errno := fdWrite(fd)
+		require.Equal(t, ErrnoNotcapable, errno, ErrnoName(errno))
+	})
+
+	t.Run("path_open without the right to open", func(t *testing.T) {
+		dirFD := pathOpen(t, workdirFD, "dir", oflagDirectory, rightFdReaddir, rightsAll)
+		defer a.FdClose(testCtx, mod, dirFD)
+
+		require.True(t, mod.Memory().Write(testCtx, 0, []byte("file")))
+		errno := a.PathOpen(testCtx, mod, dirFD, 0, 0, 4, 0, rightFdRead, 0, 0, resultOpenedFd)
+		require.Equal(t, ErrnoNotcapable, errno, ErrnoName(errno))
 	})
 }
 
@@ -1913,38 +3402,332 @@ func TestSnapshotPreview1_PathOpen_Errors(t *testing.T) {
 
 // TestSnapshotPreview1_PathReadlink only tests it is stubbed for GrainLang per #271
 func TestSnapshotPreview1_PathReadlink(t *testing.T) {
-	mod, fn := instantiateModule(testCtx, t, functionPathReadlink, importPathReadlink, nil)
-	defer mod.Close(testCtx)
+	workdirFD := uint32(3) // arbitrary fd after 0, 1, and 2, that are stdin/out/err
+	pathName := "link"
+	target := "wazero.txt"
 
-	t.Run("wasi.PathLink", func(t *testing.T) {
-		errno := a.PathReadlink(testCtx, mod, 0, 0, 0, 0, 0, 0)
-		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
+	setup := func() (api.Module, api.Function) {
+		testFS := symlinkMapFS{fstest.MapFS{target: {}}, map[string]string{pathName: target}}
+		sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
+			workdirFD: {Path: ".", FS: testFS},
+		})
+		require.NoError(t, err)
+		mod, fn := instantiateModule(testCtx, t, functionPathReadlink, importPathReadlink, sysCtx)
+		ok := mod.Memory().Write(testCtx, 0, []byte(pathName))
+		require.True(t, ok)
+		return mod, fn
+	}
+
+	path, pathLen := uint32(0), uint32(len(pathName))
+	buf, bufLen := uint32(16), uint32(32)
+	resultBufused := uint32(8)
+
+	verify := func(mod api.Module, errno Errno) {
+		require.Zero(t, errno, ErrnoName(errno))
+
+		bufused, ok := mod.Memory().ReadUint32Le(testCtx, resultBufused)
+		require.True(t, ok)
+		require.Equal(t, uint32(len(target)), bufused)
+
+		b, ok := mod.Memory().Read(testCtx, buf, bufused)
+		require.True(t, ok)
+		require.Equal(t, target, string(b))
+	}
+
+	t.Run("wasi.PathReadlink", func(t *testing.T) {
+		mod, _ := setup()
+		defer mod.Close(testCtx)
+
+		errno := a.PathReadlink(testCtx, mod, workdirFD, path, pathLen, buf, bufLen, resultBufused)
+		verify(mod, errno)
 	})
 
 	t.Run(functionPathReadlink, func(t *testing.T) {
-		results, err := fn.Call(testCtx, 0, 0, 0, 0, 0, 0)
+		mod, fn := setup()
+		defer mod.Close(testCtx)
+
+		results, err := fn.Call(testCtx, uint64(workdirFD), uint64(path), uint64(pathLen), uint64(buf), uint64(bufLen), uint64(resultBufused))
 		require.NoError(t, err)
 		errno := Errno(results[0]) // results[0] is the errno
-		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
+		verify(mod, errno)
+	})
+
+	t.Run("truncates to bufLen", func(t *testing.T) {
+		mod, _ := setup()
+		defer mod.Close(testCtx)
+
+		errno := a.PathReadlink(testCtx, mod, workdirFD, path, pathLen, buf, 6, resultBufused)
+		require.Zero(t, errno, ErrnoName(errno))
+
+		bufused, ok := mod.Memory().ReadUint32Le(testCtx, resultBufused)
+		require.True(t, ok)
+		require.Equal(t, uint32(6), bufused)
+
+		b, ok := mod.Memory().Read(testCtx, buf, 7)
+		require.True(t, ok)
+		require.Equal(t, "wazero\x00", string(b)) // not null-terminated, so the next byte is untouched
 	})
 }
 
-// TestSnapshotPreview1_PathRemoveDirectory only tests it is stubbed for GrainLang per #271
-func TestSnapshotPreview1_PathRemoveDirectory(t *testing.T) {
-	mod, fn := instantiateModule(testCtx, t, functionPathRemoveDirectory, importPathRemoveDirectory, nil)
+func TestSnapshotPreview1_PathReadlink_Errors(t *testing.T) {
+	validFD := uint32(3)     // arbitrary valid fd after 0, 1, and 2, that are stdin/out/err
+	noSymlinkFD := uint32(4) // a mount whose fs.FS doesn't support symbolic links
+	testFS := symlinkMapFS{fstest.MapFS{"file": {}}, map[string]string{"link": "file"}}
+
+	sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
+		validFD:     {Path: ".", FS: testFS},
+		noSymlinkFD: {Path: ".", FS: struct{ fs.FS }{fstest.MapFS{}}}, // hides any ReadLink method
+	})
+	require.NoError(t, err)
+
+	mod, _ := instantiateModule(testCtx, t, functionPathReadlink, importPathReadlink, sysCtx)
 	defer mod.Close(testCtx)
 
+	memorySize := mod.Memory().Size(testCtx)
+	writePath := func(pathName string) (uint32, uint32) {
+		ok := mod.Memory().Write(testCtx, 0, []byte(pathName))
+		require.True(t, ok)
+		return 0, uint32(len(pathName))
+	}
+
+	tests := []struct {
+		name                       string
+		fd                         uint32
+		pathName                   string
+		path, pathLen              uint32 // used when pathName is empty
+		buf, bufLen, resultBufused uint32
+		expectedErrno              Errno
+	}{
+		{
+			name:          "invalid fd",
+			fd:            42, // arbitrary invalid fd
+			pathName:      "link",
+			expectedErrno: ErrnoBadf,
+		},
+		{
+			name:          "out-of-memory reading path",
+			fd:            validFD,
+			path:          memorySize,
+			pathLen:       1,
+			expectedErrno: ErrnoFault,
+		},
+		{
+			name:          "path escapes the directory",
+			fd:            validFD,
+			pathName:      "../link",
+			expectedErrno: ErrnoNotcapable,
+		},
+		{
+			name:          "file system doesn't support symbolic links",
+			fd:            noSymlinkFD,
+			pathName:      "link",
+			expectedErrno: ErrnoNotsup,
+		},
+		{
+			name:          "not a symbolic link",
+			fd:            validFD,
+			pathName:      "file",
+			expectedErrno: ErrnoInval,
+		},
+		{
+			name:          "not found",
+			fd:            validFD,
+			pathName:      "missing",
+			expectedErrno: ErrnoNoent,
+		},
+		{
+			name:          "out-of-memory writing buf",
+			fd:            validFD,
+			pathName:      "link",
+			buf:           memorySize,
+			bufLen:        4,
+			expectedErrno: ErrnoFault,
+		},
+		{
+			name:          "out-of-memory writing resultBufused",
+			fd:            validFD,
+			pathName:      "link",
+			buf:           16,
+			bufLen:        4,
+			resultBufused: memorySize,
+			expectedErrno: ErrnoFault,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			path, pathLen := tc.path, tc.pathLen
+			if tc.pathName != "" {
+				path, pathLen = writePath(tc.pathName)
+			}
+			errno := a.PathReadlink(testCtx, mod, tc.fd, path, pathLen, tc.buf, tc.bufLen, tc.resultBufused)
+			require.Equal(t, tc.expectedErrno, errno, ErrnoName(errno))
+		})
+	}
+}
+
+// symlinkMapFS is a fstest.MapFS which implements sys.ReadLinkFS, for testing functions that read symbolic links.
+type symlinkMapFS struct {
+	fstest.MapFS
+	// links are the targets of symbolic links by name.
+	links map[string]string
+}
+
+// ReadLink implements sys.ReadLinkFS
+func (m symlinkMapFS) ReadLink(name string) (string, error) {
+	if target, ok := m.links[name]; ok {
+		return target, nil
+	}
+	if _, err := fs.Stat(m.MapFS, name); err != nil {
+		return "", err
+	}
+	return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+}
+
+func TestSnapshotPreview1_PathRemoveDirectory(t *testing.T) {
+	workdirFD := uint32(3) // arbitrary fd after 0, 1, and 2, that are stdin/out/err
+	pathName := "wazero"
+
+	setup := func() (api.Module, api.Function, writableMapFS) {
+		testFS := writableMapFS{fstest.MapFS{pathName: &fstest.MapFile{Mode: fs.ModeDir}}}
+		sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
+			workdirFD: {Path: ".", FS: testFS},
+		})
+		require.NoError(t, err)
+		mod, fn := instantiateModule(testCtx, t, functionPathRemoveDirectory, importPathRemoveDirectory, sysCtx)
+		ok := mod.Memory().Write(testCtx, 1, []byte(pathName))
+		require.True(t, ok)
+		return mod, fn, testFS
+	}
+
+	verify := func(errno Errno, testFS writableMapFS) {
+		require.Zero(t, errno, ErrnoName(errno))
+
+		// verify the directory was actually removed
+		_, err := fs.Stat(testFS, pathName)
+		require.ErrorIs(t, err, fs.ErrNotExist)
+	}
+
 	t.Run("wasi.PathRemoveDirectory", func(t *testing.T) {
-		errno := a.PathRemoveDirectory(testCtx, mod, 0, 0, 0)
-		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
+		mod, _, testFS := setup()
+		defer mod.Close(testCtx)
+
+		errno := a.PathRemoveDirectory(testCtx, mod, workdirFD, 1, uint32(len(pathName)))
+		verify(errno, testFS)
 	})
 
 	t.Run(functionPathRemoveDirectory, func(t *testing.T) {
-		results, err := fn.Call(testCtx, 0, 0, 0)
+		mod, fn, testFS := setup()
+		defer mod.Close(testCtx)
+
+		results, err := fn.Call(testCtx, uint64(workdirFD), 1, uint64(len(pathName)))
 		require.NoError(t, err)
 		errno := Errno(results[0]) // results[0] is the errno
-		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
+		verify(errno, testFS)
+	})
+}
+
+func TestSnapshotPreview1_PathRemoveDirectory_Errors(t *testing.T) {
+	validFD := uint32(3)    // arbitrary valid fd after 0, 1, and 2, that are stdin/out/err
+	readOnlyFD := uint32(4) // a mount whose fs.FS cannot remove directories
+	testFS := writableMapFS{fstest.MapFS{
+		"empty":           &fstest.MapFile{Mode: fs.ModeDir},
+		"notempty/wazero": &fstest.MapFile{Data: []byte("wazero")},
+		"file":            &fstest.MapFile{Data: []byte("wazero")},
+	}}
+
+	sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
+		validFD:    {Path: ".", FS: testFS},
+		readOnlyFD: {Path: ".", FS: fstest.MapFS{"empty": &fstest.MapFile{Mode: fs.ModeDir}}},
 	})
+	require.NoError(t, err)
+
+	mod, _ := instantiateModule(testCtx, t, functionPathRemoveDirectory, importPathRemoveDirectory, sysCtx)
+	defer mod.Close(testCtx)
+
+	writePath := func(pathName string) (uint32, uint32) {
+		ok := mod.Memory().Write(testCtx, 0, []byte(pathName))
+		require.True(t, ok)
+		return 0, uint32(len(pathName))
+	}
+
+	tests := []struct {
+		name          string
+		fd            uint32
+		pathName      string
+		path, pathLen uint32 // used when pathName is empty
+		expectedErrno Errno
+	}{
+		{
+			name:          "invalid fd",
+			fd:            42, // arbitrary invalid fd
+			pathName:      "empty",
+			expectedErrno: ErrnoBadf,
+		},
+		{
+			name:          "out-of-memory reading path",
+			fd:            validFD,
+			path:          mod.Memory().Size(testCtx),
+			pathLen:       1,
+			expectedErrno: ErrnoFault,
+		},
+		{
+			name:          "out-of-memory reading pathLen",
+			fd:            validFD,
+			path:          0,
+			pathLen:       mod.Memory().Size(testCtx) + 1,
+			expectedErrno: ErrnoFault,
+		},
+		{
+			name:          "path escapes the directory",
+			fd:            validFD,
+			pathName:      "../empty",
+			expectedErrno: ErrnoNotcapable,
+		},
+		{
+			name:          "directory of fd",
+			fd:            validFD,
+			pathName:      ".",
+			expectedErrno: ErrnoInval,
+		},
+		{
+			name:          "read-only file system",
+			fd:            readOnlyFD,
+			pathName:      "empty",
+			expectedErrno: ErrnoRofs,
+		},
+		{
+			name:          "directory does not exist",
+			fd:            validFD,
+			pathName:      "missing",
+			expectedErrno: ErrnoNoent,
+		},
+		{
+			name:          "not a directory",
+			fd:            validFD,
+			pathName:      "file",
+			expectedErrno: ErrnoNotdir,
+		},
+		{
+			name:          "directory not empty",
+			fd:            validFD,
+			pathName:      "notempty",
+			expectedErrno: ErrnoNotempty,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			path, pathLen := tc.path, tc.pathLen
+			if tc.pathName != "" {
+				path, pathLen = writePath(tc.pathName)
+			}
+			errno := a.PathRemoveDirectory(testCtx, mod, tc.fd, path, pathLen)
+			require.Equal(t, tc.expectedErrno, errno, ErrnoName(errno))
+		})
+	}
 }
 
 // TestSnapshotPreview1_PathRename only tests it is stubbed for GrainLang per #271
@@ -1993,30 +3776,394 @@ func TestSnapshotPreview1_PathUnlinkFile(t *testing.T) {
 		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
 	})
 
-	t.Run(functionPathUnlinkFile, func(t *testing.T) {
-		results, err := fn.Call(testCtx, 0, 0, 0)
-		require.NoError(t, err)
-		errno := Errno(results[0]) // results[0] is the errno
-		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
-	})
+	t.Run(functionPathUnlinkFile, func(t *testing.T) {
+		results, err := fn.Call(testCtx, 0, 0, 0)
+		require.NoError(t, err)
+		errno := Errno(results[0]) // results[0] is the errno
+		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
+	})
+}
+
+func TestSnapshotPreview1_PollOneoff(t *testing.T) {
+	// The clock starts at an arbitrary time, and only advances when nanosleep is called, so that the test doesn't
+	// sleep for real.
+	now := int64(1000)
+	var nanotime sys.Nanotime = func(context.Context) int64 { return now }
+	var nanosleep sys.Nanosleep = func(ctx context.Context, ns int64) { now += ns }
+	sysCtx, err := internalsys.NewContext(math.MaxUint32, nil, nil, nil, nil, nil, false, false, nil,
+		nil, 0, &nanotime, 1, &nanosleep, nil)
+	require.NoError(t, err)
+
+	mod, fn := instantiateModule(testCtx, t, functionPollOneoff, importPollOneoff, sysCtx)
+	defer mod.Close(testCtx)
+
+	in := uint32(1) // arbitrary offset, that's not aligned
+	out := in + 2*subscriptionSize
+	resultNevents := out + 2*eventSize
+
+	// The second subscription fires first.
+	subscriptions := []byte{
+		'?',                    // in is after this
+		1, 0, 0, 0, 0, 0, 0, 0, // userdata
+		eventtypeClock, 0, 0, 0, 0, 0, 0, 0, // tag and padding
+		clockIDMonotonic, 0, 0, 0, 0, 0, 0, 0, // id and padding
+		0x00, 0x70, 0xc9, 0xb2, 0x8b, 0x00, 0x00, 0x00, // timeout relative (10 minutes)
+		0, 0, 0, 0, 0, 0, 0, 0, // precision
+		0, 0, 0, 0, 0, 0, 0, 0, // flags and padding
+
+		2, 0, 0, 0, 0, 0, 0, 0, // userdata
+		eventtypeClock, 0, 0, 0, 0, 0, 0, 0, // tag and padding
+		clockIDMonotonic, 0, 0, 0, 0, 0, 0, 0, // id and padding
+		0, 0, 0, 0, 0, 0, 0, 0, // timeout absolute (50 seconds from now), set below
+		0, 0, 0, 0, 0, 0, 0, 0, // precision
+		subclockflagSubscriptionClockAbstime, 0, 0, 0, 0, 0, 0, 0, // flags and padding
+	}
+	expectedEvent := []byte{
+		2, 0, 0, 0, 0, 0, 0, 0, // userdata
+		0, 0, // error
+		eventtypeClock, 0, 0, 0, 0, 0, // type and padding
+		0, 0, 0, 0, 0, 0, 0, 0, // nbytes
+		0, 0, 0, 0, 0, 0, 0, 0, // flags and padding
+	}
+
+	tests := []struct {
+		name       string
+		pollOneoff func() Errno
+	}{
+		{
+			name: "wasi.PollOneoff",
+			pollOneoff: func() Errno {
+				return a.PollOneoff(testCtx, mod, in, out, 2, resultNevents)
+			},
+		},
+		{
+			name: functionPollOneoff,
+			pollOneoff: func() Errno {
+				results, err := fn.Call(testCtx, uint64(in), uint64(out), 2, uint64(resultNevents))
+				require.NoError(t, err)
+				return Errno(results[0]) // results[0] is the errno
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			now = 1000
+			binary.LittleEndian.PutUint64(subscriptions[1+subscriptionSize+24:], uint64(now+int64(50*time.Second)))
+			maskMemory(t, testCtx, mod, int(resultNevents+4))
+			require.True(t, mod.Memory().Write(testCtx, 0, subscriptions))
+
+			start := time.Now()
+			errno := tc.pollOneoff()
+			require.Equal(t, ErrnoSuccess, errno, ErrnoName(errno))
+
+			// The injected clock advanced to the earliest deadline, without waiting in real time.
+			require.Equal(t, int64(1000)+int64(50*time.Second), now)
+			require.True(t, time.Since(start) < 50*time.Second)
+
+			actual, ok := mod.Memory().Read(testCtx, out, eventSize)
+			require.True(t, ok)
+			require.Equal(t, expectedEvent, actual)
+
+			nevents, ok := mod.Memory().ReadUint32Le(testCtx, resultNevents)
+			require.True(t, ok)
+			require.Equal(t, uint32(1), nevents)
+		})
+	}
+}
+
+// TestSnapshotPreview1_PollOneoff_RelativeSleep ensures a relative clock timeout, such as used by Rust's
+// std::thread::sleep, waits the whole duration even when Nanosleep wakes early, and without drifting across calls.
+func TestSnapshotPreview1_PollOneoff_RelativeSleep(t *testing.T) {
+	// The injected clock only advances when nanosleep is called, and sleeps at most a second at a time, similar to a
+	// timer that wakes before its deadline.
+	now := int64(1000)
+	var sleeps int
+	var nanotime sys.Nanotime = func(context.Context) int64 { return now }
+	var nanosleep sys.Nanosleep = func(ctx context.Context, ns int64) {
+		sleeps++
+		if ns > int64(time.Second) {
+			ns = int64(time.Second)
+		}
+		now += ns
+	}
+	sysCtx, err := internalsys.NewContext(math.MaxUint32, nil, nil, nil, nil, nil, false, false, nil,
+		nil, 0, &nanotime, 1, &nanosleep, nil)
+	require.NoError(t, err)
+
+	mod, _ := instantiateModule(testCtx, t, functionPollOneoff, importPollOneoff, sysCtx)
+	defer mod.Close(testCtx)
+
+	in := uint32(0) // arbitrary offset
+	out := in + subscriptionSize
+	resultNevents := out + eventSize
+
+	duration := 2500 * time.Millisecond
+	subscription := make([]byte, subscriptionSize)
+	subscription[0] = 1 // userdata
+	subscription[8] = eventtypeClock
+	binary.LittleEndian.PutUint32(subscription[16:], clockIDMonotonic)
+	binary.LittleEndian.PutUint64(subscription[24:], uint64(duration)) // SUBSCRIPTION_CLOCK_ABSTIME is unset
+	require.True(t, mod.Memory().Write(testCtx, in, subscription))
+
+	for i := int64(1); i <= 3; i++ {
+		sleeps = 0
+		errno := a.PollOneoff(testCtx, mod, in, out, 1, resultNevents)
+		require.Zero(t, errno, ErrnoName(errno))
+
+		// The clock advanced by exactly the duration each call, so the deadlines didn't drift.
+		require.Equal(t, 1000+i*int64(duration), now)
+		require.Equal(t, 3, sleeps) // 1s, 1s, then the remaining 500ms
+
+		nevents, ok := mod.Memory().ReadUint32Le(testCtx, resultNevents)
+		require.True(t, ok)
+		require.Equal(t, uint32(1), nevents)
+	}
+}
+
+func TestSnapshotPreview1_PollOneoff_FdWrite(t *testing.T) {
+	var slept int64
+	var nanosleep sys.Nanosleep = func(ctx context.Context, ns int64) { slept += ns }
+	conn, peer := net.Pipe()
+	defer peer.Close()
+	stdout := internalsys.NewBufferedWriter(new(bytes.Buffer), 64)
+	_, err := stdout.Write([]byte("wa")) // buffered, so there's less space left
+	require.NoError(t, err)
+	sysCtx, err := internalsys.NewContext(math.MaxUint32, nil, nil, nil, stdout, nil, false, false, nil,
+		nil, 0, nil, 0, &nanosleep, map[uint32]*internalsys.FileEntry{3: {File: &internalsys.Socket{Conn: conn}}})
+	require.NoError(t, err)
+
+	mod, fn := instantiateModule(testCtx, t, functionPollOneoff, importPollOneoff, sysCtx)
+	defer mod.Close(testCtx)
+
+	subscription := func(userdata byte, tag uint8, fd uint32) []byte {
+		b := make([]byte, subscriptionSize)
+		b[0] = userdata
+		b[8] = tag
+		binary.LittleEndian.PutUint32(b[16:], fd) // or the clock id
+		return b
+	}
+	clock := subscription(1, eventtypeClock, clockIDMonotonic)
+	binary.LittleEndian.PutUint64(clock[24:], uint64(time.Minute)) // doesn't fire, as write events are ready
+	var subscriptions []byte
+	subscriptions = append(subscriptions, clock...)
+	subscriptions = append(subscriptions, subscription(2, eventtypeFdWrite, fdStdout)...)
+	subscriptions = append(subscriptions, subscription(3, eventtypeFdWrite, fdStderr)...)
+	subscriptions = append(subscriptions, subscription(4, eventtypeFdWrite, 3)...)
+	subscriptions = append(subscriptions, subscription(5, eventtypeFdWrite, fdStdin)...)
+
+	in := uint32(0)
+	out := uint32(len(subscriptions))
+	resultNevents := out + 5*eventSize
+	require.True(t, mod.Memory().Write(testCtx, in, subscriptions))
+
+	results, err := fn.Call(testCtx, uint64(in), uint64(out), 5, uint64(resultNevents))
+	require.NoError(t, err)
+	require.Equal(t, ErrnoSuccess, Errno(results[0]), ErrnoName(Errno(results[0])))
+	require.Zero(t, slept)
+
+	expectedEvents := []byte{
+		2, 0, 0, 0, 0, 0, 0, 0, // userdata
+		0, 0, // error
+		eventtypeFdWrite, 0, 0, 0, 0, 0, // type and padding
+		62, 0, 0, 0, 0, 0, 0, 0, // nbytes: the space left in the stdout buffer
+		0, 0, 0, 0, 0, 0, 0, 0, // flags and padding
+
+		3, 0, 0, 0, 0, 0, 0, 0, // userdata
+		0, 0, // error
+		eventtypeFdWrite, 0, 0, 0, 0, 0, // type and padding
+		0x00, 0x10, 0, 0, 0, 0, 0, 0, // nbytes: pipeBufSize
+		0, 0, 0, 0, 0, 0, 0, 0, // flags and padding
+
+		4, 0, 0, 0, 0, 0, 0, 0, // userdata
+		0, 0, // error
+		eventtypeFdWrite, 0, 0, 0, 0, 0, // type and padding
+		0x00, 0x10, 0, 0, 0, 0, 0, 0, // nbytes: pipeBufSize
+		0, 0, 0, 0, 0, 0, 0, 0, // flags and padding
+
+		5, 0, 0, 0, 0, 0, 0, 0, // userdata
+		byte(ErrnoBadf), 0, // error: stdin can't be written
+		eventtypeFdWrite, 0, 0, 0, 0, 0, // type and padding
+		0, 0, 0, 0, 0, 0, 0, 0, // nbytes
+		0, 0, 0, 0, 0, 0, 0, 0, // flags and padding
+	}
+	actual, ok := mod.Memory().Read(testCtx, out, uint32(len(expectedEvents)))
+	require.True(t, ok)
+	require.Equal(t, expectedEvents, actual)
+
+	nevents, ok := mod.Memory().ReadUint32Le(testCtx, resultNevents)
+	require.True(t, ok)
+	require.Equal(t, uint32(4), nevents)
 }
 
-// TestSnapshotPreview1_PollOneoff only tests it is stubbed for GrainLang per #271
-func TestSnapshotPreview1_PollOneoff(t *testing.T) {
-	mod, fn := instantiateModule(testCtx, t, functionPollOneoff, importPollOneoff, nil)
+func TestSnapshotPreview1_PollOneoff_Errors(t *testing.T) {
+	var slept int64
+	var nanosleep sys.Nanosleep = func(ctx context.Context, ns int64) { slept += ns }
+	sysCtx, err := internalsys.NewContext(math.MaxUint32, nil, nil, nil, nil, nil, false, false, nil,
+		nil, 0, nil, 0, &nanosleep, nil)
+	require.NoError(t, err)
+
+	mod, _ := instantiateModule(testCtx, t, functionPollOneoff, importPollOneoff, sysCtx)
 	defer mod.Close(testCtx)
+	memorySize := mod.Memory().Size(testCtx)
 
-	t.Run("wasi.PollOneoff", func(t *testing.T) {
-		errno := a.PollOneoff(testCtx, mod, 0, 0, 0, 0)
-		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
-	})
+	clockSubscription := func(id uint32, timeout uint64) []byte {
+		b := make([]byte, subscriptionSize)
+		b[0] = 1 // userdata
+		b[8] = eventtypeClock
+		binary.LittleEndian.PutUint32(b[16:], id)
+		binary.LittleEndian.PutUint64(b[24:], timeout)
+		return b
+	}
+	fdReadSubscription := make([]byte, subscriptionSize)
+	fdReadSubscription[0] = 2 // userdata
+	fdReadSubscription[8] = eventtypeFdRead
+	badFdWriteSubscription := make([]byte, subscriptionSize)
+	badFdWriteSubscription[0] = 3 // userdata
+	badFdWriteSubscription[8] = eventtypeFdWrite
+	binary.LittleEndian.PutUint32(badFdWriteSubscription[16:], 999)
 
-	t.Run(functionPollOneoff, func(t *testing.T) {
-		results, err := fn.Call(testCtx, 0, 0, 0, 0)
-		require.NoError(t, err)
-		errno := Errno(results[0]) // results[0] is the errno
-		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
-	})
+	tests := []struct {
+		name                                   string
+		ctx                                    context.Context
+		in, out, nsubscriptions, resultNevents uint32
+		subscriptions                          []byte
+		expectedErrno                          Errno
+		expectedEvents                         []byte // when ErrnoSuccess
+		expectedSlept                          int64
+	}{
+		{
+			name:           "zero subscriptions",
+			nsubscriptions: 0,
+			expectedErrno:  ErrnoInval,
+		},
+		{
+			name:           "in out of range",
+			in:             memorySize - subscriptionSize + 1,
+			out:            0,
+			nsubscriptions: 1,
+			resultNevents:  eventSize,
+			expectedErrno:  ErrnoFault,
+		},
+		{
+			name:           "out out of range",
+			out:            memorySize - eventSize + 1,
+			nsubscriptions: 1,
+			expectedErrno:  ErrnoFault,
+		},
+		{
+			name:           "resultNevents out of range",
+			out:            subscriptionSize,
+			nsubscriptions: 1,
+			resultNevents:  memorySize - 3,
+			expectedErrno:  ErrnoFault,
+		},
+		{
+			name:           "nsubscriptions overflows",
+			nsubscriptions: math.MaxUint32,
+			expectedErrno:  ErrnoFault,
+		},
+		{
+			name:           "invalid tag",
+			out:            subscriptionSize,
+			nsubscriptions: 1,
+			resultNevents:  subscriptionSize + eventSize,
+			subscriptions:  []byte{1, 0, 0, 0, 0, 0, 0, 0, eventtypeFdWrite + 1},
+			expectedErrno:  ErrnoInval,
+		},
+		{
+			name:           "unsupported clock",
+			out:            subscriptionSize,
+			nsubscriptions: 1,
+			resultNevents:  subscriptionSize + eventSize,
+			subscriptions:  clockSubscription(2 /* PROCESS_CPUTIME_ID */, 1),
+			expectedErrno:  ErrnoSuccess,
+			expectedEvents: []byte{
+				1, 0, 0, 0, 0, 0, 0, 0, // userdata
+				byte(ErrnoInval), 0, // error
+				eventtypeClock, 0, 0, 0, 0, 0, // type and padding
+				0, 0, 0, 0, 0, 0, 0, 0, // nbytes
+				0, 0, 0, 0, 0, 0, 0, 0, // flags and padding
+			},
+		},
+		{
+			name:           "fd subscriptions are not supported and don't wait",
+			out:            2 * subscriptionSize,
+			nsubscriptions: 2,
+			resultNevents:  2*subscriptionSize + 2*eventSize,
+			subscriptions:  append(clockSubscription(clockIDMonotonic, 1), fdReadSubscription...),
+			expectedErrno:  ErrnoSuccess,
+			expectedEvents: []byte{
+				2, 0, 0, 0, 0, 0, 0, 0, // userdata
+				byte(ErrnoNotsup), 0, // error
+				eventtypeFdRead, 0, 0, 0, 0, 0, // type and padding
+				0, 0, 0, 0, 0, 0, 0, 0, // nbytes
+				0, 0, 0, 0, 0, 0, 0, 0, // flags and padding
+			},
+		},
+		{
+			name:           "fd subscription with an invalid fd",
+			out:            subscriptionSize,
+			nsubscriptions: 1,
+			resultNevents:  subscriptionSize + eventSize,
+			subscriptions:  badFdWriteSubscription,
+			expectedErrno:  ErrnoSuccess,
+			expectedEvents: []byte{
+				3, 0, 0, 0, 0, 0, 0, 0, // userdata
+				byte(ErrnoBadf), 0, // error
+				eventtypeFdWrite, 0, 0, 0, 0, 0, // type and padding
+				0, 0, 0, 0, 0, 0, 0, 0, // nbytes
+				0, 0, 0, 0, 0, 0, 0, 0, // flags and padding
+			},
+		},
+		{
+			name:           "context done while waiting",
+			ctx:            canceledContext(),
+			out:            subscriptionSize,
+			nsubscriptions: 1,
+			resultNevents:  subscriptionSize + eventSize,
+			subscriptions:  clockSubscription(clockIDRealtime, 5),
+			expectedErrno:  ErrnoIntr,
+			expectedSlept:  5,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := tc.ctx
+			if ctx == nil {
+				ctx = testCtx
+			}
+			slept = 0
+			maskMemory(t, testCtx, mod, int(memorySize))
+			if tc.subscriptions != nil {
+				require.True(t, mod.Memory().Write(testCtx, tc.in, tc.subscriptions))
+			}
+
+			errno := a.PollOneoff(ctx, mod, tc.in, tc.out, tc.nsubscriptions, tc.resultNevents)
+			require.Equal(t, tc.expectedErrno, errno, ErrnoName(errno))
+			require.Equal(t, tc.expectedSlept, slept)
+
+			if tc.expectedEvents != nil {
+				actual, ok := mod.Memory().Read(testCtx, tc.out, uint32(len(tc.expectedEvents)))
+				require.True(t, ok)
+				require.Equal(t, tc.expectedEvents, actual)
+
+				nevents, ok := mod.Memory().ReadUint32Le(testCtx, tc.resultNevents)
+				require.True(t, ok)
+				require.Equal(t, uint32(len(tc.expectedEvents)/eventSize), nevents)
+			}
+		})
+	}
+}
+
+func canceledContext() context.Context {
+	ctx, cancel := context.WithCancel(testCtx)
+	cancel()
+	return ctx
 }
 
 func TestSnapshotPreview1_ProcExit(t *testing.T) {
@@ -2164,6 +4311,48 @@ func TestSnapshotPreview1_RandomGet_Errors(t *testing.T) {
 	}
 }
 
+// TestSnapshotPreview1_RandomGet_ShortReads ensures a source returning fewer bytes than requested is read again until
+// the buffer is full, as opposed to failing with ErrnoIo.
+func TestSnapshotPreview1_RandomGet_ShortReads(t *testing.T) {
+	expectedMemory := []byte{
+		'?',           // `offset` is after this
+		1, 2, 3, 4, 5, // random data read one byte at a time
+		'?', // stopped after encoding
+	}
+
+	length := uint32(5) // arbitrary length,
+	offset := uint32(1) // offset,
+
+	sysCtx, err := internalsys.NewContext(
+		math.MaxUint32,
+		nil,
+		nil,
+		new(bytes.Buffer),
+		nil,
+		nil,
+		false,
+		false,
+		iotest.OneByteReader(bytes.NewReader([]byte{1, 2, 3, 4, 5})),
+		nil, 0,
+		nil, 0,
+		nil,
+		nil,
+	)
+	require.NoError(t, err)
+
+	mod, _ := instantiateModule(testCtx, t, functionRandomGet, importRandomGet, sysCtx)
+	defer mod.Close(testCtx)
+
+	maskMemory(t, testCtx, mod, len(expectedMemory))
+
+	errno := a.RandomGet(testCtx, mod, offset, length)
+	require.Zero(t, errno, ErrnoName(errno))
+
+	actual, ok := mod.Memory().Read(testCtx, 0, offset+length+1)
+	require.True(t, ok)
+	require.Equal(t, expectedMemory, actual)
+}
+
 func TestSnapshotPreview1_RandomGet_SourceError(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -2189,10 +4378,13 @@ func TestSnapshotPreview1_RandomGet_SourceError(t *testing.T) {
 				new(bytes.Buffer),
 				nil,
 				nil,
+				false,
+				false,
 				tc.randSource,
 				nil, 0,
 				nil, 0,
 				nil,
+				nil,
 			)
 			require.NoError(t, err)
 
@@ -2205,58 +4397,385 @@ func TestSnapshotPreview1_RandomGet_SourceError(t *testing.T) {
 	}
 }
 
-// TestSnapshotPreview1_SockRecv only tests it is stubbed for GrainLang per #271
-func TestSnapshotPreview1_SockRecv(t *testing.T) {
-	mod, fn := instantiateModule(testCtx, t, functionSockRecv, importSockRecv, nil)
-	defer mod.Close(testCtx)
+func TestSnapshotPreview1_SockSend(t *testing.T) {
+	fd := uint32(3)     // arbitrary fd after 0, 1, and 2, that are stdin/out/err
+	siData := uint32(1) // arbitrary offset
+	initialMemory := []byte{
+		'?',         // `siData` is after this
+		18, 0, 0, 0, // = siData[0].offset
+		4, 0, 0, 0, // = siData[0].length
+		23, 0, 0, 0, // = siData[1].offset
+		2, 0, 0, 0, // = siData[1].length
+		'?',                // siData[0].offset is after this
+		'w', 'a', 'z', 'e', // siData[0].length bytes
+		'?',      // siData[1].offset is after this
+		'r', 'o', // siData[1].length bytes
+		'?',
+	}
+	siDataCount := uint32(2)      // The count of siData
+	resultSoDataLen := uint32(26) // arbitrary offset
+	expectedMemory := append(
+		initialMemory,
+		6, 0, 0, 0, // sum(siData[...].length) == length of "wazero"
+		'?',
+	)
 
-	t.Run("wasi.SockRecv", func(t *testing.T) {
-		errno := a.SockRecv(testCtx, mod, 0, 0, 0, 0, 0, 0)
-		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
+	conn, peer := net.Pipe()
+	defer peer.Close()
+	sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
+		fd: {File: &internalsys.Socket{Conn: conn}},
 	})
+	require.NoError(t, err)
 
-	t.Run(functionSockRecv, func(t *testing.T) {
-		results, err := fn.Call(testCtx, 0, 0, 0, 0, 0, 0)
-		require.NoError(t, err)
-		errno := Errno(results[0]) // results[0] is the errno
-		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
-	})
+	mod, fn := instantiateModule(testCtx, t, functionSockSend, importSockSend, sysCtx)
+	defer mod.Close(testCtx)
+
+	maskMemory(t, testCtx, mod, len(expectedMemory))
+	ok := mod.Memory().Write(testCtx, 0, initialMemory)
+	require.True(t, ok)
+
+	// net.Pipe is synchronous, so the peer must read concurrently.
+	received := make(chan []byte)
+	go func() {
+		b := make([]byte, 6)
+		_, _ = io.ReadFull(peer, b)
+		received <- b
+	}()
+
+	results, err := fn.Call(testCtx, uint64(fd), uint64(siData), uint64(siDataCount), 0, uint64(resultSoDataLen))
+	require.NoError(t, err)
+	errno := Errno(results[0]) // results[0] is the errno
+	require.Zero(t, errno, ErrnoName(errno))
+
+	actual, ok := mod.Memory().Read(testCtx, 0, uint32(len(expectedMemory)))
+	require.True(t, ok)
+	require.Equal(t, expectedMemory, actual)
+	require.Equal(t, []byte("wazero"), <-received)
 }
 
-// TestSnapshotPreview1_SockSend only tests it is stubbed for GrainLang per #271
-func TestSnapshotPreview1_SockSend(t *testing.T) {
-	mod, fn := instantiateModule(testCtx, t, functionSockSend, importSockSend, nil)
-	defer mod.Close(testCtx)
+func TestSnapshotPreview1_SockRecv(t *testing.T) {
+	fd := uint32(3)     // arbitrary fd after 0, 1, and 2, that are stdin/out/err
+	riData := uint32(1) // arbitrary offset
+	initialMemory := []byte{
+		'?',         // `riData` is after this
+		18, 0, 0, 0, // = riData[0].offset
+		2, 0, 0, 0, // = riData[0].length
+		21, 0, 0, 0, // = riData[1].offset
+		8, 0, 0, 0, // = riData[1].length
+	}
+	riDataCount := uint32(2)      // The count of riData
+	resultRoDataLen := uint32(30) // arbitrary offset
+	resultRoFlags := uint32(34)   // arbitrary offset
+	expectedMemory := append(
+		initialMemory,
+		'?',      // riData[0].offset is after this
+		'h', 'e', // riData[0].length bytes
+		'?',           // riData[1].offset is after this
+		'l', 'l', 'o', // the rest of "hello", which is shorter than riData[1].length
+		'?', '?', '?', '?', '?',
+		'?',
+		5, 0, 0, 0, // = ro_datalen, the length of "hello"
+		0, 0, // = ro_flags
+		'?',
+	)
 
-	t.Run("wasi.SockSend", func(t *testing.T) {
-		errno := a.SockSend(testCtx, mod, 0, 0, 0, 0, 0)
-		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
+	conn, peer := net.Pipe()
+	defer peer.Close()
+	sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
+		fd: {File: &internalsys.Socket{Conn: conn}},
 	})
+	require.NoError(t, err)
 
-	t.Run(functionSockSend, func(t *testing.T) {
-		results, err := fn.Call(testCtx, 0, 0, 0, 0, 0)
-		require.NoError(t, err)
-		errno := Errno(results[0]) // results[0] is the errno
-		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
+	mod, fn := instantiateModule(testCtx, t, functionSockRecv, importSockRecv, sysCtx)
+	defer mod.Close(testCtx)
+
+	maskMemory(t, testCtx, mod, len(expectedMemory))
+	ok := mod.Memory().Write(testCtx, 0, initialMemory)
+	require.True(t, ok)
+
+	// net.Pipe is synchronous, so the peer must write concurrently.
+	go func() {
+		_, _ = peer.Write([]byte("hello"))
+	}()
+
+	results, err := fn.Call(testCtx, uint64(fd), uint64(riData), uint64(riDataCount), 0, uint64(resultRoDataLen), uint64(resultRoFlags))
+	require.NoError(t, err)
+	errno := Errno(results[0]) // results[0] is the errno
+	require.Zero(t, errno, ErrnoName(errno))
+
+	actual, ok := mod.Memory().Read(testCtx, 0, uint32(len(expectedMemory)))
+	require.True(t, ok)
+	require.Equal(t, expectedMemory, actual)
+}
+
+func TestSnapshotPreview1_SockSend_SockRecv(t *testing.T) {
+	conn1, conn2 := net.Pipe()
+	sysCtx1, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{3: {File: &internalsys.Socket{Conn: conn1}}})
+	require.NoError(t, err)
+	sysCtx2, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{3: {File: &internalsys.Socket{Conn: conn2}}})
+	require.NoError(t, err)
+
+	sender, _ := instantiateModule(testCtx, t, functionSockSend, importSockSend, sysCtx1)
+	defer sender.Close(testCtx)
+	receiver, _ := instantiateModule(testCtx, t, functionSockRecv, importSockRecv, sysCtx2)
+	defer receiver.Close(testCtx)
+
+	// Both modules use the same layout: one (c)iovec at 0, pointing to 8 bytes at 8, and the result length at 16.
+	iovs, resultLen, resultFlags := uint32(0), uint32(16), uint32(20)
+	require.True(t, sender.Memory().Write(testCtx, iovs, []byte{8, 0, 0, 0, 8, 0, 0, 0, 'w', 'a', 'z', 'e', 'r', 'o', '!', '!'}))
+	require.True(t, receiver.Memory().Write(testCtx, iovs, []byte{8, 0, 0, 0, 8, 0, 0, 0}))
+
+	sent := make(chan Errno)
+	go func() {
+		sent <- a.SockSend(testCtx, sender, 3, iovs, 1, 0, resultLen)
+	}()
+
+	errno := a.SockRecv(testCtx, receiver, 3, iovs, 1, 0, resultLen, resultFlags)
+	require.Equal(t, ErrnoSuccess, errno, ErrnoName(errno))
+	errno = <-sent
+	require.Equal(t, ErrnoSuccess, errno, ErrnoName(errno))
+
+	nwritten, ok := sender.Memory().ReadUint32Le(testCtx, resultLen)
+	require.True(t, ok)
+	require.Equal(t, uint32(8), nwritten)
+	nread, ok := receiver.Memory().ReadUint32Le(testCtx, resultLen)
+	require.True(t, ok)
+	require.Equal(t, uint32(8), nread)
+	received, ok := receiver.Memory().Read(testCtx, 8, nread)
+	require.True(t, ok)
+	require.Equal(t, []byte("wazero!!"), received)
+}
+
+func TestSnapshotPreview1_Sock_Errors(t *testing.T) {
+	socketFD, fileFD := uint32(3), uint32(4)
+
+	conn, peer := net.Pipe()
+	defer peer.Close()
+	file, testFS := createFile(t, "test_path", []byte("wazero"))
+	sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
+		socketFD: {File: &internalsys.Socket{Conn: conn}},
+		fileFD:   {Path: "test_path", FS: testFS, File: file},
 	})
+	require.NoError(t, err)
+
+	mod, _ := instantiateModule(testCtx, t, functionSockShutdown, importSockShutdown, sysCtx)
+	defer mod.Close(testCtx)
+
+	maxOffset := uint32(testMemoryPageSize * wasm.MemoryPageSize)
+	tests := []struct {
+		name          string
+		call          func() Errno
+		expectedErrno Errno
+	}{
+		{
+			name:          "sock_recv invalid fd",
+			call:          func() Errno { return a.SockRecv(testCtx, mod, 42, 0, 0, 0, 0, 0) },
+			expectedErrno: ErrnoBadf,
+		},
+		{
+			name:          "sock_recv file",
+			call:          func() Errno { return a.SockRecv(testCtx, mod, fileFD, 0, 0, 0, 0, 0) },
+			expectedErrno: ErrnoNotsock,
+		},
+		{
+			name:          "sock_recv stdin",
+			call:          func() Errno { return a.SockRecv(testCtx, mod, fdStdin, 0, 0, 0, 0, 0) },
+			expectedErrno: ErrnoNotsock,
+		},
+		{
+			name:          "sock_recv RECV_PEEK",
+			call:          func() Errno { return a.SockRecv(testCtx, mod, socketFD, 0, 0, riflagRecvPeek, 0, 0) },
+			expectedErrno: ErrnoNotsup,
+		},
+		{
+			name:          "sock_recv riData out of range",
+			call:          func() Errno { return a.SockRecv(testCtx, mod, socketFD, maxOffset, 1, 0, 0, 0) },
+			expectedErrno: ErrnoFault,
+		},
+		{
+			name:          "sock_recv resultRoDataLen out of range",
+			call:          func() Errno { return a.SockRecv(testCtx, mod, socketFD, 0, 0, 0, maxOffset, 0) },
+			expectedErrno: ErrnoFault,
+		},
+		{
+			name:          "sock_send invalid fd",
+			call:          func() Errno { return a.SockSend(testCtx, mod, 42, 0, 0, 0, 0) },
+			expectedErrno: ErrnoBadf,
+		},
+		{
+			name:          "sock_send file",
+			call:          func() Errno { return a.SockSend(testCtx, mod, fileFD, 0, 0, 0, 0) },
+			expectedErrno: ErrnoNotsock,
+		},
+		{
+			name:          "sock_send stdout",
+			call:          func() Errno { return a.SockSend(testCtx, mod, fdStdout, 0, 0, 0, 0) },
+			expectedErrno: ErrnoNotsock,
+		},
+		{
+			name:          "sock_send siFlags",
+			call:          func() Errno { return a.SockSend(testCtx, mod, socketFD, 0, 0, 1, 0) },
+			expectedErrno: ErrnoInval,
+		},
+		{
+			name:          "sock_send siData out of range",
+			call:          func() Errno { return a.SockSend(testCtx, mod, socketFD, maxOffset, 1, 0, 0) },
+			expectedErrno: ErrnoFault,
+		},
+		{
+			name:          "sock_send resultSoDataLen out of range",
+			call:          func() Errno { return a.SockSend(testCtx, mod, socketFD, 0, 0, 0, maxOffset) },
+			expectedErrno: ErrnoFault,
+		},
+		{
+			name:          "sock_shutdown invalid fd",
+			call:          func() Errno { return a.SockShutdown(testCtx, mod, 42, sdflagWr) },
+			expectedErrno: ErrnoBadf,
+		},
+		{
+			name:          "sock_shutdown file",
+			call:          func() Errno { return a.SockShutdown(testCtx, mod, fileFD, sdflagWr) },
+			expectedErrno: ErrnoNotsock,
+		},
+		{
+			name:          "sock_shutdown no flags",
+			call:          func() Errno { return a.SockShutdown(testCtx, mod, socketFD, 0) },
+			expectedErrno: ErrnoInval,
+		},
+		{
+			name:          "sock_shutdown unknown flag",
+			call:          func() Errno { return a.SockShutdown(testCtx, mod, socketFD, sdflagWr<<1) },
+			expectedErrno: ErrnoInval,
+		},
+		{
+			name:          "sock_shutdown can't half-close",
+			call:          func() Errno { return a.SockShutdown(testCtx, mod, socketFD, sdflagWr) },
+			expectedErrno: ErrnoNotsup,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			errno := tc.call()
+			require.Equal(t, tc.expectedErrno, errno, ErrnoName(errno))
+		})
+	}
 }
 
-// TestSnapshotPreview1_SockShutdown only tests it is stubbed for GrainLang per #271
 func TestSnapshotPreview1_SockShutdown(t *testing.T) {
-	mod, fn := instantiateModule(testCtx, t, functionSockShutdown, importSockShutdown, nil)
-	defer mod.Close(testCtx)
+	tests := []struct {
+		name                                    string
+		how                                     uint32
+		expectedClosedRead, expectedClosedWrite bool
+	}{
+		{name: "RD", how: sdflagRd, expectedClosedRead: true},
+		{name: "WR", how: sdflagWr, expectedClosedWrite: true},
+		{name: "RD|WR", how: sdflagRd | sdflagWr, expectedClosedRead: true, expectedClosedWrite: true},
+	}
 
-	t.Run("wasi.SockShutdown", func(t *testing.T) {
-		errno := a.SockShutdown(testCtx, mod, 0, 0)
-		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
-	})
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			conn, peer := net.Pipe()
+			defer peer.Close()
+			halfClose := &halfCloseConn{Conn: conn}
+			sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
+				3: {File: &internalsys.Socket{Conn: halfClose}},
+			})
+			require.NoError(t, err)
 
-	t.Run(functionSockShutdown, func(t *testing.T) {
-		results, err := fn.Call(testCtx, 0, 0)
-		require.NoError(t, err)
-		errno := Errno(results[0]) // results[0] is the errno
-		require.Equal(t, ErrnoNosys, errno, ErrnoName(errno))
+			mod, fn := instantiateModule(testCtx, t, functionSockShutdown, importSockShutdown, sysCtx)
+			defer mod.Close(testCtx)
+
+			results, err := fn.Call(testCtx, 3, uint64(tc.how))
+			require.NoError(t, err)
+			errno := Errno(results[0]) // results[0] is the errno
+			require.Zero(t, errno, ErrnoName(errno))
+			require.Equal(t, tc.expectedClosedRead, halfClose.closedRead)
+			require.Equal(t, tc.expectedClosedWrite, halfClose.closedWrite)
+
+			// The socket is still open.
+			_, ok := mod.(*wasm.CallContext).Sys.FS().OpenedFile(3)
+			require.True(t, ok)
+		})
+	}
+}
+
+// halfCloseConn implements the half-close methods of net.TCPConn, which net.Pipe doesn't.
+type halfCloseConn struct {
+	net.Conn
+	closedRead, closedWrite bool
+}
+
+func (c *halfCloseConn) CloseRead() error {
+	c.closedRead = true
+	return nil
+}
+
+func (c *halfCloseConn) CloseWrite() error {
+	c.closedWrite = true
+	return nil
+}
+
+// TestSnapshotPreview1_InvalidFd ensures each function that accepts a file descriptor returns ErrnoBadf for one that
+// was never opened, instead of panicking on the host. Stubbed functions are excluded, as they always return
+// ErrnoNosys.
+func TestSnapshotPreview1_InvalidFd(t *testing.T) {
+	// Open a directory, so that functions which might fall back to it have something to misuse.
+	sysCtx, err := newSysContext(nil, nil, map[uint32]*internalsys.FileEntry{
+		3: {Path: "/", FS: fstest.MapFS{}},
 	})
+	require.NoError(t, err)
+
+	mod, _ := instantiateModule(testCtx, t, functionFdClose, importFdClose, sysCtx)
+	defer mod.Close(testCtx)
+
+	fd := uint32(999) // garbage from the guest
+	tests := []struct {
+		name string
+		call func() Errno
+	}{
+		{functionFdAdvise, func() Errno { return a.FdAdvise(testCtx, mod, fd, 0, 0, adviceNormal) }},
+		{functionFdAllocate, func() Errno { return a.FdAllocate(testCtx, mod, fd, 0, 1) }},
+		{functionFdClose, func() Errno { return a.FdClose(testCtx, mod, fd) }},
+		{functionFdDatasync, func() Errno { return a.FdDatasync(testCtx, mod, fd) }},
+		{functionFdFdstatGet, func() Errno { return a.FdFdstatGet(testCtx, mod, fd, 0) }},
+		{functionFdFilestatGet, func() Errno { return a.FdFilestatGet(testCtx, mod, fd, 0) }},
+		{functionFdFilestatSetTimes, func() Errno { return a.FdFilestatSetTimes(testCtx, mod, fd, 0, 0, fstflagMtimNow) }},
+		{functionFdPrestatGet, func() Errno { return a.FdPrestatGet(testCtx, mod, fd, 0) }},
+		{functionFdPrestatDirName, func() Errno { return a.FdPrestatDirName(testCtx, mod, fd, 0, 0) }},
+		{functionFdRead, func() Errno { return a.FdRead(testCtx, mod, fd, 0, 0, 0) }},
+		{functionFdRenumber, func() Errno { return a.FdRenumber(testCtx, mod, fd, 4) }},
+		{functionFdSeek, func() Errno { return a.FdSeek(testCtx, mod, fd, 0, io.SeekStart, 0) }},
+		{functionFdSync, func() Errno { return a.FdSync(testCtx, mod, fd) }},
+		{functionFdTell, func() Errno { return a.FdTell(testCtx, mod, fd, 0) }},
+		{functionFdWrite, func() Errno { return a.FdWrite(testCtx, mod, fd, 0, 0, 0) }},
+		{functionPathCreateDirectory, func() Errno { return a.PathCreateDirectory(testCtx, mod, fd, 0, 1) }},
+		{functionPathFilestatSetTimes, func() Errno {
+			return a.PathFilestatSetTimes(testCtx, mod, fd, 0, 0, 1, 0, 0, fstflagMtimNow)
+		}},
+		{functionPathOpen, func() Errno { return a.PathOpen(testCtx, mod, fd, 0, 0, 1, 0, 0, 0, 0, 0) }},
+		{functionPathReadlink, func() Errno { return a.PathReadlink(testCtx, mod, fd, 0, 1, 0, 0, 0) }},
+		{functionPathRemoveDirectory, func() Errno { return a.PathRemoveDirectory(testCtx, mod, fd, 0, 1) }},
+		{functionSockRecv, func() Errno { return a.SockRecv(testCtx, mod, fd, 0, 0, 0, 0, 0) }},
+		{functionSockSend, func() Errno { return a.SockSend(testCtx, mod, fd, 0, 0, 0, 0) }},
+		{functionSockShutdown, func() Errno { return a.SockShutdown(testCtx, mod, fd, sdflagRd) }},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			errno := tc.call()
+			require.Equal(t, ErrnoBadf, errno, ErrnoName(errno))
+		})
+	}
+
+	// The file table is unchanged.
+	_, ok := mod.(*wasm.CallContext).Sys.FS().OpenedFile(fd)
+	require.False(t, ok)
+	_, ok = mod.(*wasm.CallContext).Sys.FS().OpenedFile(3)
+	require.True(t, ok)
 }
 
 const testMemoryPageSize = 1
@@ -2309,9 +4828,12 @@ func newSysContext(args, environ []string, openedFiles map[uint32]*internalsys.F
 		new(bytes.Buffer),
 		nil,
 		nil,
+		false,
+		false,
 		deterministicRandomSource(),
 		nil, 0,
 		nil, 0,
+		nil,
 		openedFiles,
 	)
 }